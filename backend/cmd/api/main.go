@@ -24,6 +24,9 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 	log.Println("shutting down gracefully, press Ctrl+C again to force")
 	stop()
 
+	// Event streams stay open until the client leaves, so end them first
+	fiberServer.CloseStreams()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := fiberServer.ShutdownWithContext(ctx); err != nil {
@@ -45,6 +48,10 @@ func main() {
 	server.SeedDevUsers()
 	server.RegisterFiberRoutes()
 
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	server.StartBackgroundJobs(jobs)
+
 	done := make(chan bool, 1)
 
 	go func() {
@@ -0,0 +1,217 @@
+// Package alerts turns monitoring alerts into incident fields. It reads
+// Prometheus Alertmanager webhook payloads and a simpler generic JSON schema
+// into one Alert shape, and maps alert labels to priority, affected service
+// and incident category according to an alert source's mapping.
+package alerts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// MaxFingerprintLength matches the incident_alerts.fingerprint column
+const MaxFingerprintLength = 255
+
+// ErrNoAlerts is returned for a payload that carries no alerts
+var ErrNoAlerts = errors.New("payload contains no alerts")
+
+// Alert is one alert from either payload format
+type Alert struct {
+	Fingerprint string
+	Status      models.AlertStatus
+	Title       string
+	Description string
+	Labels      map[string]string
+	Annotations map[string]string
+	StartsAt    time.Time
+	EndsAt      *time.Time
+}
+
+// alertmanagerPayload is the body Alertmanager posts to webhook receivers
+// (version 4 of its webhook format)
+type alertmanagerPayload struct {
+	Alerts []struct {
+		Status       string            `json:"status"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+		StartsAt     time.Time         `json:"startsAt"`
+		EndsAt       time.Time         `json:"endsAt"`
+		GeneratorURL string            `json:"generatorURL"`
+		Fingerprint  string            `json:"fingerprint"`
+	} `json:"alerts"`
+}
+
+// ParseAlertmanager reads an Alertmanager webhook body. The title comes from
+// the summary annotation or the alertname label, and alerts without a
+// fingerprint (Alertmanager before 0.19) get one computed from their labels.
+func ParseAlertmanager(body []byte) ([]Alert, error) {
+	var payload alertmanagerPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid Alertmanager payload: %w", err)
+	}
+	if len(payload.Alerts) == 0 {
+		return nil, ErrNoAlerts
+	}
+
+	alerts := make([]Alert, 0, len(payload.Alerts))
+	for i, a := range payload.Alerts {
+		alert := Alert{
+			Fingerprint: a.Fingerprint,
+			Status:      models.AlertStatus(a.Status),
+			Title:       firstNonEmpty(a.Annotations["summary"], a.Annotations["title"], a.Labels["alertname"]),
+			Description: firstNonEmpty(a.Annotations["description"], a.Annotations["message"]),
+			Labels:      a.Labels,
+			Annotations: a.Annotations,
+			StartsAt:    a.StartsAt,
+		}
+		// Alertmanager sends the zero time as endsAt while an alert fires
+		if !a.EndsAt.IsZero() {
+			endsAt := a.EndsAt
+			alert.EndsAt = &endsAt
+		}
+		if a.GeneratorURL != "" {
+			alert.Description = strings.TrimSpace(alert.Description + "\n\nSource: " + a.GeneratorURL)
+		}
+		if err := normalize(&alert); err != nil {
+			return nil, fmt.Errorf("alerts[%d]: %w", i, err)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// genericAlert is the schema for monitoring tools without Alertmanager
+// support. Only title is required; status defaults to firing.
+type genericAlert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      string            `json:"status"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Labels      map[string]string `json:"labels"`
+	StartedAt   *time.Time        `json:"started_at"`
+	EndedAt     *time.Time        `json:"ended_at"`
+}
+
+// ParseGeneric reads a generic alert body: a single alert object, an array
+// of them, or an object with an "alerts" array.
+func ParseGeneric(body []byte) ([]Alert, error) {
+	var raw []genericAlert
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &raw); err != nil {
+			return nil, fmt.Errorf("invalid alert payload: %w", err)
+		}
+	} else {
+		var payload struct {
+			genericAlert
+			Alerts []genericAlert `json:"alerts"`
+		}
+		if err := json.Unmarshal(trimmed, &payload); err != nil {
+			return nil, fmt.Errorf("invalid alert payload: %w", err)
+		}
+		raw = payload.Alerts
+		if raw == nil {
+			raw = []genericAlert{payload.genericAlert}
+		}
+	}
+	if len(raw) == 0 {
+		return nil, ErrNoAlerts
+	}
+
+	alerts := make([]Alert, 0, len(raw))
+	for i, a := range raw {
+		alert := Alert{
+			Fingerprint: a.Fingerprint,
+			Status:      models.AlertStatus(a.Status),
+			Title:       a.Title,
+			Description: a.Description,
+			Labels:      a.Labels,
+			Annotations: map[string]string{"summary": a.Title},
+			EndsAt:      a.EndedAt,
+		}
+		if a.Description != "" {
+			alert.Annotations["description"] = a.Description
+		}
+		if alert.Status == "" {
+			alert.Status = models.AlertStatusFiring
+		}
+		if a.StartedAt != nil {
+			alert.StartsAt = *a.StartedAt
+		}
+		if alert.Fingerprint == "" && len(alert.Labels) == 0 {
+			alert.Fingerprint = Fingerprint(map[string]string{"title": strings.TrimSpace(alert.Title)})
+		}
+		if err := normalize(&alert); err != nil {
+			return nil, fmt.Errorf("alerts[%d]: %w", i, err)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// normalize validates an alert and fills in what the payload left out
+func normalize(a *Alert) error {
+	if a.Status != models.AlertStatusFiring && a.Status != models.AlertStatusResolved {
+		return fmt.Errorf("status must be firing or resolved, got %q", a.Status)
+	}
+	a.Title = strings.TrimSpace(a.Title)
+	if a.Title == "" {
+		return errors.New("title is required")
+	}
+	if title := []rune(a.Title); len(title) > 255 {
+		a.Title = string(title[:254]) + "…"
+	}
+	if a.Labels == nil {
+		a.Labels = map[string]string{}
+	}
+	if a.Annotations == nil {
+		a.Annotations = map[string]string{}
+	}
+	if a.Fingerprint == "" {
+		a.Fingerprint = Fingerprint(a.Labels)
+	}
+	if len(a.Fingerprint) > MaxFingerprintLength {
+		return fmt.Errorf("fingerprint must be at most %d characters", MaxFingerprintLength)
+	}
+	if a.StartsAt.IsZero() {
+		a.StartsAt = time.Now()
+	}
+	return nil
+}
+
+// Fingerprint identifies an alert by its label set, independent of label
+// order, the way Alertmanager does
+func Fingerprint(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write([]byte(labels[k]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package alerts
+
+import (
+	"strings"
+	"testing"
+
+	"backend/internal/models"
+)
+
+const alertmanagerBody = `{
+	"version": "4",
+	"status": "firing",
+	"receiver": "risk-register",
+	"alerts": [
+		{
+			"status": "firing",
+			"labels": {"alertname": "HighErrorRate", "severity": "critical", "service": "checkout"},
+			"annotations": {"summary": "Checkout error rate above 5%", "description": "5xx ratio is 7%"},
+			"startsAt": "2026-03-02T10:00:00Z",
+			"endsAt": "0001-01-01T00:00:00Z",
+			"generatorURL": "http://prometheus/graph?g0.expr=rate",
+			"fingerprint": "c0ffee"
+		},
+		{
+			"status": "resolved",
+			"labels": {"alertname": "DiskFull", "severity": "warning"},
+			"annotations": {},
+			"startsAt": "2026-03-02T09:00:00Z",
+			"endsAt": "2026-03-02T09:30:00Z"
+		}
+	]
+}`
+
+func TestParseAlertmanager(t *testing.T) {
+	alerts, err := ParseAlertmanager([]byte(alertmanagerBody))
+	if err != nil {
+		t.Fatalf("ParseAlertmanager failed: %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(alerts))
+	}
+
+	firing := alerts[0]
+	if firing.Fingerprint != "c0ffee" || firing.Status != models.AlertStatusFiring {
+		t.Errorf("unexpected firing alert: %+v", firing)
+	}
+	if firing.Title != "Checkout error rate above 5%" {
+		t.Errorf("title should come from the summary annotation, got %q", firing.Title)
+	}
+	if !strings.HasPrefix(firing.Description, "5xx ratio is 7%") || !strings.Contains(firing.Description, "http://prometheus/graph") {
+		t.Errorf("description should include the generator URL, got %q", firing.Description)
+	}
+	if firing.EndsAt != nil {
+		t.Errorf("the zero endsAt of a firing alert should be dropped, got %v", firing.EndsAt)
+	}
+
+	resolved := alerts[1]
+	if resolved.Title != "DiskFull" {
+		t.Errorf("title should fall back to alertname, got %q", resolved.Title)
+	}
+	if resolved.EndsAt == nil || resolved.EndsAt.Minute() != 30 {
+		t.Errorf("expected endsAt to be kept, got %v", resolved.EndsAt)
+	}
+	if resolved.Fingerprint != Fingerprint(resolved.Labels) {
+		t.Errorf("missing fingerprint should be computed from labels, got %q", resolved.Fingerprint)
+	}
+}
+
+func TestParseAlertmanager_Invalid(t *testing.T) {
+	if _, err := ParseAlertmanager([]byte(`{"alerts": []}`)); err != ErrNoAlerts {
+		t.Errorf("expected ErrNoAlerts, got %v", err)
+	}
+	if _, err := ParseAlertmanager([]byte(`{"alerts": [{"status": "pending", "labels": {"alertname": "X"}}]}`)); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+	if _, err := ParseAlertmanager([]byte(`not json`)); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestParseGeneric(t *testing.T) {
+	t.Run("single object defaults to firing", func(t *testing.T) {
+		alerts, err := ParseGeneric([]byte(`{"title": "Backup failed", "description": "nightly job", "labels": {"severity": "high"}}`))
+		if err != nil {
+			t.Fatalf("ParseGeneric failed: %v", err)
+		}
+		if len(alerts) != 1 || alerts[0].Status != models.AlertStatusFiring || alerts[0].Title != "Backup failed" {
+			t.Fatalf("unexpected alerts: %+v", alerts)
+		}
+		if alerts[0].Annotations["description"] != "nightly job" {
+			t.Errorf("expected the description to be kept as an annotation, got %v", alerts[0].Annotations)
+		}
+		if alerts[0].StartsAt.IsZero() {
+			t.Error("a missing start time should default to now")
+		}
+	})
+
+	t.Run("array and alerts wrapper", func(t *testing.T) {
+		for _, body := range []string{
+			`[{"title": "A", "fingerprint": "a"}, {"title": "B", "status": "resolved", "fingerprint": "b"}]`,
+			`{"alerts": [{"title": "A", "fingerprint": "a"}, {"title": "B", "status": "resolved", "fingerprint": "b"}]}`,
+		} {
+			alerts, err := ParseGeneric([]byte(body))
+			if err != nil {
+				t.Fatalf("ParseGeneric(%s) failed: %v", body, err)
+			}
+			if len(alerts) != 2 || alerts[1].Status != models.AlertStatusResolved || alerts[1].Fingerprint != "b" {
+				t.Errorf("unexpected alerts for %s: %+v", body, alerts)
+			}
+		}
+	})
+
+	t.Run("fingerprint without labels comes from the title", func(t *testing.T) {
+		first, _ := ParseGeneric([]byte(`{"title": "Backup failed"}`))
+		second, _ := ParseGeneric([]byte(`{"title": "Backup failed", "description": "again"}`))
+		other, _ := ParseGeneric([]byte(`{"title": "Restore failed"}`))
+		if first[0].Fingerprint != second[0].Fingerprint || first[0].Fingerprint == other[0].Fingerprint {
+			t.Errorf("unexpected fingerprints %q %q %q", first[0].Fingerprint, second[0].Fingerprint, other[0].Fingerprint)
+		}
+	})
+
+	t.Run("title is required", func(t *testing.T) {
+		if _, err := ParseGeneric([]byte(`[{"title": "A"}, {"labels": {"x": "y"}}]`)); err == nil || !strings.Contains(err.Error(), "alerts[1]") {
+			t.Errorf("expected an error naming alerts[1], got %v", err)
+		}
+	})
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(map[string]string{"alertname": "X", "instance": "a"})
+	b := Fingerprint(map[string]string{"instance": "a", "alertname": "X"})
+	c := Fingerprint(map[string]string{"alertname": "X", "instance": "b"})
+	if a != b {
+		t.Error("fingerprint should not depend on label order")
+	}
+	if a == c {
+		t.Error("different label sets should have different fingerprints")
+	}
+}
+
+func TestMap(t *testing.T) {
+	network := &models.IncidentCategory{ID: "cat-network", Name: "Network"}
+	security := &models.IncidentCategory{ID: "cat-security", Name: "Security"}
+	categories := []*models.IncidentCategory{network, security}
+	fallback := "cat-default"
+
+	t.Run("defaults", func(t *testing.T) {
+		alert := &Alert{Labels: map[string]string{"severity": "Critical", "service": "checkout", "category": "network"}}
+		fields := Map(models.AlertMapping{}, alert, categories)
+		if fields.Priority != models.PriorityP1 {
+			t.Errorf("expected p1, got %s", fields.Priority)
+		}
+		if fields.ServiceAffected != "checkout" {
+			t.Errorf("expected service checkout, got %q", fields.ServiceAffected)
+		}
+		if fields.CategoryID == nil || *fields.CategoryID != network.ID {
+			t.Errorf("expected the category to be matched by name, got %v", fields.CategoryID)
+		}
+	})
+
+	t.Run("custom labels and maps", func(t *testing.T) {
+		mapping := models.AlertMapping{
+			PriorityLabel:     "urgency",
+			PriorityMap:       map[string]models.IncidentPriority{"sev2": models.PriorityP2},
+			DefaultPriority:   models.PriorityP4,
+			ServiceLabel:      "app",
+			CategoryLabel:     "team",
+			CategoryMap:       map[string]string{"SecOps": security.ID},
+			DefaultCategoryID: &fallback,
+		}
+		alert := &Alert{Labels: map[string]string{"urgency": "SEV2", "app": "billing", "team": "secops", "severity": "critical"}}
+		fields := Map(mapping, alert, categories)
+		if fields.Priority != models.PriorityP2 || fields.ServiceAffected != "billing" {
+			t.Errorf("unexpected fields: %+v", fields)
+		}
+		if fields.CategoryID == nil || *fields.CategoryID != security.ID {
+			t.Errorf("expected the category map to apply, got %v", fields.CategoryID)
+		}
+
+		unknown := Map(mapping, &Alert{Labels: map[string]string{"urgency": "whenever", "team": "nobody"}}, categories)
+		if unknown.Priority != models.PriorityP4 {
+			t.Errorf("unknown values should use the default priority, got %s", unknown.Priority)
+		}
+		if unknown.CategoryID == nil || *unknown.CategoryID != fallback {
+			t.Errorf("unknown values should use the default category, got %v", unknown.CategoryID)
+		}
+	})
+}
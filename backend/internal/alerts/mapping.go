@@ -0,0 +1,94 @@
+package alerts
+
+import (
+	"strings"
+
+	"backend/internal/models"
+)
+
+// Label names used when a source's mapping does not name its own
+const (
+	DefaultPriorityLabel = "severity"
+	DefaultServiceLabel  = "service"
+	DefaultCategoryLabel = "category"
+)
+
+// defaultPriorities covers the severities Prometheus rules commonly use
+var defaultPriorities = map[string]models.IncidentPriority{
+	"critical": models.PriorityP1,
+	"page":     models.PriorityP1,
+	"high":     models.PriorityP2,
+	"error":    models.PriorityP2,
+	"major":    models.PriorityP2,
+	"warning":  models.PriorityP3,
+	"medium":   models.PriorityP3,
+	"minor":    models.PriorityP3,
+	"low":      models.PriorityP4,
+	"info":     models.PriorityP4,
+	"none":     models.PriorityP4,
+	"p1":       models.PriorityP1,
+	"p2":       models.PriorityP2,
+	"p3":       models.PriorityP3,
+	"p4":       models.PriorityP4,
+}
+
+// Fields are the incident fields derived from an alert's labels
+type Fields struct {
+	Priority        models.IncidentPriority
+	ServiceAffected string
+	CategoryID      *string
+}
+
+// Map derives incident fields from an alert. The category label is looked
+// up in the mapping's category map first and then against the names of
+// categories; unmatched values fall back to the mapping's default.
+func Map(m models.AlertMapping, a *Alert, categories []*models.IncidentCategory) Fields {
+	fields := Fields{
+		Priority:        m.DefaultPriority,
+		ServiceAffected: labelValue(a.Labels, m.ServiceLabel, DefaultServiceLabel),
+		CategoryID:      m.DefaultCategoryID,
+	}
+	if fields.Priority == "" {
+		fields.Priority = models.PriorityP3
+	}
+
+	if severity := strings.ToLower(labelValue(a.Labels, m.PriorityLabel, DefaultPriorityLabel)); severity != "" {
+		if p, ok := lookupFold(m.PriorityMap, severity); ok {
+			fields.Priority = p
+		} else if p, ok := defaultPriorities[severity]; ok {
+			fields.Priority = p
+		}
+	}
+
+	if category := labelValue(a.Labels, m.CategoryLabel, DefaultCategoryLabel); category != "" {
+		if id, ok := lookupFold(m.CategoryMap, category); ok {
+			fields.CategoryID = &id
+		} else {
+			for _, c := range categories {
+				if strings.EqualFold(c.Name, category) {
+					id := c.ID
+					fields.CategoryID = &id
+					break
+				}
+			}
+		}
+	}
+	return fields
+}
+
+func labelValue(labels map[string]string, name, fallback string) string {
+	if name == "" {
+		name = fallback
+	}
+	return strings.TrimSpace(labels[name])
+}
+
+func lookupFold[V any](m map[string]V, key string) (V, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	var zero V
+	return zero, false
+}
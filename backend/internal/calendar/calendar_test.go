@@ -0,0 +1,125 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encode(t *testing.T, c *Calendar) string {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, c.Write(&buf))
+	return buf.String()
+}
+
+// unfold joins folded lines back up, as a client would
+func unfold(s string) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestWrite(t *testing.T) {
+	modified := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	out := encode(t, &Calendar{
+		Name:            "Deadlines",
+		RefreshInterval: time.Hour,
+		Events: []*Event{{
+			UID:          "risk-review-1@risk-register",
+			Date:         time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+			Summary:      "Review; backups, offsite",
+			Description:  "line one\nline two \\ end",
+			URL:          "https://risk.example.com/app/risks/1",
+			Categories:   []string{"Risk review"},
+			Sequence:     3,
+			LastModified: modified,
+		}},
+	})
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.NotContains(t, strings.ReplaceAll(out, "\r\n", ""), "\n", "every line ends in CRLF")
+
+	lines := unfold(out)
+	for _, want := range []string{
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:Deadlines",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+		"X-PUBLISHED-TTL:PT1H",
+		"UID:risk-review-1@risk-register",
+		"DTSTAMP:20261001T093000Z",
+		"SEQUENCE:3",
+		"DTSTART;VALUE=DATE:20261031",
+		"DTEND;VALUE=DATE:20261101",
+		`SUMMARY:Review\; backups\, offsite`,
+		`DESCRIPTION:line one\nline two \\ end`,
+		"URL:https://risk.example.com/app/risks/1",
+		"CATEGORIES:Risk review",
+		"TRANSP:TRANSPARENT",
+	} {
+		assert.Contains(t, lines, want)
+	}
+}
+
+func TestWrite_FoldsLongLines(t *testing.T) {
+	summary := strings.Repeat("ä", 100) + strings.Repeat("x", 100)
+	out := encode(t, &Calendar{Events: []*Event{{UID: "1", Summary: summary}}})
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "line %q is too long", line)
+		assert.True(t, strings.ToValidUTF8(line, "?") == line, "line %q splits a character", line)
+	}
+	assert.Contains(t, unfold(out), "SUMMARY:"+summary)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "PT1H", formatDuration(time.Hour))
+	assert.Equal(t, "PT1H30M", formatDuration(90*time.Minute))
+	assert.Equal(t, "PT45S", formatDuration(45*time.Second))
+	assert.Equal(t, "P1D", formatDuration(24*time.Hour))
+}
+
+func TestFeed(t *testing.T) {
+	due := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	entries := []*models.CalendarEntry{
+		{Kind: models.CalendarEntryReview, EntityID: "r1", RiskID: "r1", RiskTitle: "Backups untested",
+			Date: due, Severity: models.SeverityHigh, Status: "open", Owner: "Alice", Version: 4},
+		{Kind: models.CalendarEntryMitigationDue, EntityID: "m1", RiskID: "r1", RiskTitle: "Backups untested",
+			Description: "Run a restore drill\nand write it up", Date: due, Severity: models.SeverityHigh,
+			Status: "in_progress", Owner: "bob@example.com", Version: 2},
+	}
+
+	c := Feed("Mine", entries, "https://risk.example.com/", time.Hour)
+	require.Len(t, c.Events, 2)
+
+	review := c.Events[0]
+	assert.Equal(t, "risk-review-r1@risk-register", review.UID)
+	assert.Equal(t, "Risk review: Backups untested", review.Summary)
+	assert.Equal(t, "https://risk.example.com/app/risks/r1", review.URL)
+	assert.Equal(t, 4, review.Sequence)
+	assert.Contains(t, review.Description, "Owner: Alice")
+
+	mitigation := c.Events[1]
+	assert.Equal(t, "mitigation-due-m1@risk-register", mitigation.UID)
+	assert.Equal(t, "Mitigation due: Run a restore drill", mitigation.Summary)
+	assert.Contains(t, mitigation.Description, "Risk: Backups untested")
+	assert.Contains(t, mitigation.Description, "Status: in progress")
+
+	t.Run("UIDs don't depend on the date", func(t *testing.T) {
+		moved := *entries[0]
+		moved.Date = due.AddDate(0, 1, 0)
+		moved.Version++
+		assert.Equal(t, UID(entries[0]), UID(&moved))
+	})
+
+	t.Run("no links without an app URL", func(t *testing.T) {
+		c := Feed("Mine", entries, "", time.Hour)
+		assert.Empty(t, c.Events[0].URL)
+		assert.NotContains(t, c.Events[0].Description, "http")
+	})
+}
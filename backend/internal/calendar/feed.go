@@ -0,0 +1,83 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+// uidDomain makes event UIDs globally unique, as RFC 5545 asks
+const uidDomain = "risk-register"
+
+// summaryLength is how much of a mitigation's description goes in the
+// event title
+const summaryLength = 80
+
+// Feed returns the calendar for entries. Links to the web app are only
+// added when appURL is set.
+func Feed(name string, entries []*models.CalendarEntry, appURL string, refresh time.Duration) *Calendar {
+	c := &Calendar{Name: name, RefreshInterval: refresh, Events: make([]*Event, 0, len(entries))}
+	for _, entry := range entries {
+		c.Events = append(c.Events, event(entry, strings.TrimRight(appURL, "/")))
+	}
+	return c
+}
+
+// UID identifies the event for an entry. It depends only on what the
+// deadline belongs to, so moving a date updates the event in place.
+func UID(entry *models.CalendarEntry) string {
+	switch entry.Kind {
+	case models.CalendarEntryMitigationDue:
+		return fmt.Sprintf("mitigation-due-%s@%s", entry.EntityID, uidDomain)
+	default:
+		return fmt.Sprintf("risk-review-%s@%s", entry.EntityID, uidDomain)
+	}
+}
+
+func event(entry *models.CalendarEntry, appURL string) *Event {
+	e := &Event{
+		UID:          UID(entry),
+		Date:         entry.Date,
+		Sequence:     entry.Version,
+		LastModified: entry.UpdatedAt,
+	}
+
+	var details []string
+	switch entry.Kind {
+	case models.CalendarEntryMitigationDue:
+		e.Summary = "Mitigation due: " + truncate(firstLine(entry.Description), summaryLength)
+		e.Categories = []string{"Mitigation"}
+		details = append(details, entry.Description, "", "Risk: "+entry.RiskTitle)
+	default:
+		e.Summary = "Risk review: " + entry.RiskTitle
+		e.Categories = []string{"Risk review"}
+	}
+	details = append(details,
+		"Severity: "+string(entry.Severity),
+		"Status: "+strings.ReplaceAll(entry.Status, "_", " "),
+	)
+	if entry.Owner != "" {
+		details = append(details, "Owner: "+entry.Owner)
+	}
+	if appURL != "" {
+		e.URL = appURL + "/app/risks/" + entry.RiskID
+		details = append(details, "", e.URL)
+	}
+	e.Description = strings.Join(details, "\n")
+	return e
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,136 @@
+// Package calendar publishes risk review dates and mitigation due dates as
+// iCalendar (RFC 5545) feeds that Outlook, Google Calendar and other clients
+// can subscribe to.
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	prodID = "-//Risk Register//Calendar Feed//EN"
+	// maxLineOctets is the longest a content line may be before it is folded
+	maxLineOctets = 75
+)
+
+// Calendar is a published calendar of all-day events
+type Calendar struct {
+	Name string
+	// RefreshInterval is how often clients are asked to poll the feed
+	RefreshInterval time.Duration
+	Events          []*Event
+}
+
+// Event is an all-day event. UID must stay the same across versions of the
+// event so that clients update it rather than adding a copy.
+type Event struct {
+	UID          string
+	Date         time.Time
+	Summary      string
+	Description  string
+	URL          string
+	Categories   []string
+	Sequence     int
+	LastModified time.Time
+}
+
+// Write encodes the calendar, folding long lines and using CRLF line endings
+// as the format requires
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(bw, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		line("REFRESH-INTERVAL;VALUE=DURATION", formatDuration(c.RefreshInterval))
+		line("X-PUBLISHED-TTL", formatDuration(c.RefreshInterval))
+	}
+	for _, e := range c.Events {
+		stamp := e.LastModified.UTC().Format("20060102T150405Z")
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		line("LAST-MODIFIED", stamp)
+		line("SEQUENCE", fmt.Sprint(e.Sequence))
+		line("DTSTART;VALUE=DATE", e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE", e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION", escapeText(e.Description))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		if len(e.Categories) > 0 {
+			categories := make([]string, len(e.Categories))
+			for i, category := range e.Categories {
+				categories[i] = escapeText(category)
+			}
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		// Deadlines shouldn't show the day as busy
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeLine writes a content line folded into lines of at most 75 octets,
+// each continuation starting with a space. Multi-byte characters are never
+// split.
+func writeLine(w *bufio.Writer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// The leading space counts towards the next line's length
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// escapeText escapes a TEXT property value
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// formatDuration writes d as an RFC 5545 duration, e.g. PT1H or P1D
+func formatDuration(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("P%dD", d/(24*time.Hour))
+	}
+	s := "PT"
+	if h := d / time.Hour; h > 0 {
+		s += fmt.Sprintf("%dH", h)
+	}
+	if m := d % time.Hour / time.Minute; m > 0 {
+		s += fmt.Sprintf("%dM", m)
+	}
+	if sec := d % time.Minute / time.Second; sec > 0 || s == "PT" {
+		s += fmt.Sprintf("%dS", sec)
+	}
+	return s
+}
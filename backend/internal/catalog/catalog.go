@@ -0,0 +1,106 @@
+// Package catalog reads framework control catalogs, from NIST OSCAL catalog
+// JSON or from spreadsheets, and works out how a framework's controls must
+// change to match one.
+package catalog
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"backend/internal/models"
+)
+
+const (
+	// maxRefLength and maxTitleLength are the sizes of the control_ref and
+	// title columns
+	maxRefLength   = 100
+	maxTitleLength = 255
+)
+
+// Control is one node of a catalog. Groups such as families or domains are
+// controls too, so that they can be parents.
+type Control struct {
+	Ref         string
+	Title       string
+	Description string
+	ParentRef   string
+	// Row is the spreadsheet row the control came from, or 0
+	Row int
+	// Position is the control's place in the catalog, set by Validate
+	Position int
+}
+
+// Catalog is a list of controls in catalog order
+type Catalog struct {
+	Format   string
+	Title    string
+	Version  string
+	Controls []*Control
+}
+
+// Validate checks that refs are unique and every parent exists, and puts
+// the controls in an order where parents come before their children,
+// otherwise keeping the catalog's order
+func (c *Catalog) Validate() []*models.ControlImportError {
+	errs := []*models.ControlImportError{}
+	fail := func(control *Control, format string, args ...any) {
+		errs = append(errs, &models.ControlImportError{Row: control.Row, ControlRef: control.Ref, Error: fmt.Sprintf(format, args...)})
+	}
+
+	byRef := make(map[string]*Control, len(c.Controls))
+	for i, control := range c.Controls {
+		control.Position = i + 1
+		switch {
+		case control.Ref == "":
+			fail(control, "control_ref is required")
+			continue
+		case utf8.RuneCountInString(control.Ref) > maxRefLength:
+			fail(control, "control_ref must be at most %d characters", maxRefLength)
+		case byRef[control.Ref] != nil:
+			fail(control, "control_ref %q appears more than once", control.Ref)
+			continue
+		}
+		if control.Title == "" {
+			fail(control, "title is required")
+		} else if utf8.RuneCountInString(control.Title) > maxTitleLength {
+			fail(control, "title must be at most %d characters", maxTitleLength)
+		}
+		byRef[control.Ref] = control
+	}
+
+	for _, control := range c.Controls {
+		if control.ParentRef != "" && byRef[control.ParentRef] == nil {
+			fail(control, "parent %q is not in the catalog", control.ParentRef)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	ordered := make([]*Control, 0, len(c.Controls))
+	state := make(map[string]int, len(c.Controls)) // 1 visiting, 2 done
+	var visit func(control *Control) bool
+	visit = func(control *Control) bool {
+		switch state[control.Ref] {
+		case 1:
+			return false
+		case 2:
+			return true
+		}
+		state[control.Ref] = 1
+		if control.ParentRef != "" && !visit(byRef[control.ParentRef]) {
+			return false
+		}
+		state[control.Ref] = 2
+		ordered = append(ordered, control)
+		return true
+	}
+	for _, control := range c.Controls {
+		if !visit(control) {
+			fail(control, "control is its own ancestor")
+			return errs
+		}
+	}
+	c.Controls = ordered
+	return errs
+}
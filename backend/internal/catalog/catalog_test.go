@@ -0,0 +1,170 @@
+package catalog
+
+import (
+	"strings"
+	"testing"
+
+	"backend/internal/models"
+)
+
+const sampleOSCAL = `{
+  "catalog": {
+    "uuid": "74c8ba1e-5cd4-4ad1-bbfd-d888e2f6c724",
+    "metadata": {"title": "Security and Privacy Controls", "version": "5.1.1"},
+    "groups": [
+      {
+        "id": "ac",
+        "class": "family",
+        "title": "Access Control",
+        "controls": [
+          {
+            "id": "ac-2",
+            "class": "SP800-53",
+            "title": "Account Management",
+            "params": [
+              {"id": "ac-02_odp.01", "label": "prerequisites and criteria"},
+              {"id": "ac-02_odp.02", "select": {"how-many": "one-or-more", "choice": ["disable", "remove"]}}
+            ],
+            "props": [
+              {"name": "label", "value": "AC-2"},
+              {"name": "label", "class": "zero-padded", "value": "AC-02"}
+            ],
+            "parts": [
+              {
+                "id": "ac-2_smt",
+                "name": "statement",
+                "parts": [
+                  {
+                    "name": "item",
+                    "props": [{"name": "label", "value": "a."}],
+                    "prose": "Specify {{ insert: param, ac-02_odp.01 }} for group membership;",
+                    "parts": [
+                      {"name": "item", "props": [{"name": "label", "value": "1."}], "prose": "Then {{ insert: param, ac-02_odp.02 }} accounts."}
+                    ]
+                  }
+                ]
+              },
+              {"name": "guidance", "prose": "Not part of the description."}
+            ],
+            "controls": [
+              {
+                "id": "ac-2.1",
+                "title": "Automated System Account Management",
+                "props": [{"name": "label", "value": "AC-2(1)"}]
+              },
+              {
+                "id": "ac-2.10",
+                "title": "Shared and Group Account Credential Change",
+                "props": [{"name": "label", "value": "AC-2(10)"}, {"name": "status", "value": "withdrawn"}]
+              }
+            ]
+          }
+        ]
+      }
+    ]
+  }
+}`
+
+func TestParseOSCAL(t *testing.T) {
+	cat, err := ParseOSCAL(strings.NewReader(sampleOSCAL))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cat.Format != "oscal" || cat.Title != "Security and Privacy Controls" || cat.Version != "5.1.1" {
+		t.Errorf("unexpected catalog metadata: %+v", cat)
+	}
+
+	refs := []string{}
+	for _, control := range cat.Controls {
+		refs = append(refs, control.Ref+"<"+control.ParentRef)
+	}
+	if got := strings.Join(refs, " "); got != "ac< AC-2<ac AC-2(1)<AC-2" {
+		t.Errorf("unexpected controls: %s", got)
+	}
+
+	want := "a. Specify [Assignment: prerequisites and criteria] for group membership;\n" +
+		"  1. Then [Selection (one or more): disable; remove] accounts."
+	if got := cat.Controls[1].Description; got != want {
+		t.Errorf("unexpected description:\n%s\nwant:\n%s", got, want)
+	}
+
+	if _, err := ParseOSCAL(strings.NewReader(`{"profile": {}}`)); err == nil {
+		t.Error("expected an error for a document that is not a catalog")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("orders parents before children", func(t *testing.T) {
+		cat := &Catalog{Controls: []*Control{
+			{Ref: "A.5.1", Title: "Policies", ParentRef: "A.5"},
+			{Ref: "A.5", Title: "Organizational controls"},
+		}}
+		if errs := cat.Validate(); len(errs) != 0 {
+			t.Fatalf("unexpected errors: %+v", errs)
+		}
+		if cat.Controls[0].Ref != "A.5" || cat.Controls[1].Position != 1 {
+			t.Errorf("expected A.5 first and A.5.1 to keep position 1, got %+v %+v", cat.Controls[0], cat.Controls[1])
+		}
+	})
+
+	t.Run("reports invalid controls", func(t *testing.T) {
+		cat := &Catalog{Controls: []*Control{
+			{Ref: "A.1", Title: "One", Row: 2},
+			{Ref: "A.1", Title: "Again", Row: 3},
+			{Ref: "", Title: "No ref", Row: 4},
+			{Ref: "A.2", Title: "", Row: 5},
+			{Ref: "A.3", Title: "Orphan", ParentRef: "A.9", Row: 6},
+		}}
+		errs := cat.Validate()
+		rows := []int{}
+		for _, err := range errs {
+			rows = append(rows, err.Row)
+		}
+		if len(rows) != 4 || rows[0] != 3 || rows[1] != 4 || rows[2] != 5 || rows[3] != 6 {
+			t.Errorf("expected errors for rows 3-6, got %+v", errs)
+		}
+	})
+
+	t.Run("reports cycles", func(t *testing.T) {
+		cat := &Catalog{Controls: []*Control{
+			{Ref: "A", Title: "A", ParentRef: "B"},
+			{Ref: "B", Title: "B", ParentRef: "A"},
+		}}
+		if errs := cat.Validate(); len(errs) != 1 || !strings.Contains(errs[0].Error, "ancestor") {
+			t.Errorf("expected a cycle error, got %+v", errs)
+		}
+	})
+}
+
+func TestDiff(t *testing.T) {
+	parentID := "id-a5"
+	existing := []*models.FrameworkControl{
+		{ID: parentID, ControlRef: "A.5", Title: "Organizational controls", SortOrder: 1},
+		{ID: "id-a51", ControlRef: "A.5.1", Title: "Policies", ParentID: &parentID, SortOrder: 2},
+		{ID: "id-a52", ControlRef: "A.5.2", Title: "Roles", ParentID: &parentID, SortOrder: 3},
+	}
+	cat := &Catalog{Controls: []*Control{
+		{Ref: "A.5", Title: "Organizational controls"},
+		{Ref: "A.5.1", Title: "Policies for information security", ParentRef: "A.5"},
+		{Ref: "A.5.3", Title: "Segregation of duties", ParentRef: "A.5"},
+	}}
+	if errs := cat.Validate(); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+
+	result := &models.ControlImportResult{}
+	Diff(existing, cat, result)
+
+	if result.Total != 3 || result.Unchanged != 1 {
+		t.Errorf("expected 3 controls with 1 unchanged, got %+v", result)
+	}
+	if len(result.Added) != 1 || result.Added[0].ControlRef != "A.5.3" {
+		t.Errorf("expected A.5.3 to be added, got %+v", result.Added)
+	}
+	if len(result.Changed) != 1 || result.Changed[0].ControlID != "id-a51" || strings.Join(result.Changed[0].Fields, ",") != "title" {
+		t.Errorf("expected the title of A.5.1 to change, got %+v", result.Changed)
+	}
+	if len(result.Removed) != 1 || result.Removed[0].ControlID != "id-a52" || result.Removed[0].ParentRef != "A.5" {
+		t.Errorf("expected A.5.2 to be removed, got %+v", result.Removed)
+	}
+}
@@ -0,0 +1,67 @@
+package catalog
+
+import "backend/internal/models"
+
+// Diff compares a framework's controls with a validated catalog, matching
+// them by ref. It fills in the added, changed and removed controls and the
+// number left unchanged; changed and removed items carry the existing
+// control's ID.
+func Diff(existing []*models.FrameworkControl, c *Catalog, result *models.ControlImportResult) {
+	byRef := make(map[string]*models.FrameworkControl, len(existing))
+	refByID := make(map[string]string, len(existing))
+	for _, control := range existing {
+		byRef[control.ControlRef] = control
+		refByID[control.ID] = control.ControlRef
+	}
+
+	result.Added = []*models.ControlImportItem{}
+	result.Changed = []*models.ControlImportItem{}
+	result.Removed = []*models.ControlImportItem{}
+	result.Unchanged = 0
+	result.Total = len(c.Controls)
+
+	inCatalog := make(map[string]bool, len(c.Controls))
+	for _, incoming := range c.Controls {
+		inCatalog[incoming.Ref] = true
+		item := &models.ControlImportItem{ControlRef: incoming.Ref, Title: incoming.Title, ParentRef: incoming.ParentRef}
+
+		current, ok := byRef[incoming.Ref]
+		if !ok {
+			result.Added = append(result.Added, item)
+			continue
+		}
+		item.ControlID = current.ID
+		parentRef := ""
+		if current.ParentID != nil {
+			parentRef = refByID[*current.ParentID]
+		}
+		if current.Title != incoming.Title {
+			item.Fields = append(item.Fields, "title")
+		}
+		if current.Description != incoming.Description {
+			item.Fields = append(item.Fields, "description")
+		}
+		if parentRef != incoming.ParentRef {
+			item.Fields = append(item.Fields, "parent")
+		}
+		if current.SortOrder != incoming.Position {
+			item.Fields = append(item.Fields, "position")
+		}
+		if len(item.Fields) == 0 {
+			result.Unchanged++
+			continue
+		}
+		result.Changed = append(result.Changed, item)
+	}
+
+	for _, control := range existing {
+		if inCatalog[control.ControlRef] {
+			continue
+		}
+		item := &models.ControlImportItem{ControlRef: control.ControlRef, Title: control.Title, ControlID: control.ID}
+		if control.ParentID != nil {
+			item.ParentRef = refByID[*control.ParentID]
+		}
+		result.Removed = append(result.Removed, item)
+	}
+}
@@ -0,0 +1,254 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// The subset of the OSCAL catalog model (https://pages.nist.gov/OSCAL/)
+// needed to list controls
+
+type oscalDocument struct {
+	Catalog *oscalCatalog `json:"catalog"`
+}
+
+type oscalCatalog struct {
+	Metadata struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"metadata"`
+	Params   []oscalParam   `json:"params"`
+	Groups   []oscalGroup   `json:"groups"`
+	Controls []oscalControl `json:"controls"`
+}
+
+type oscalGroup struct {
+	ID       string         `json:"id"`
+	Title    string         `json:"title"`
+	Props    []oscalProp    `json:"props"`
+	Params   []oscalParam   `json:"params"`
+	Parts    []oscalPart    `json:"parts"`
+	Groups   []oscalGroup   `json:"groups"`
+	Controls []oscalControl `json:"controls"`
+}
+
+type oscalControl struct {
+	ID       string         `json:"id"`
+	Title    string         `json:"title"`
+	Props    []oscalProp    `json:"props"`
+	Params   []oscalParam   `json:"params"`
+	Parts    []oscalPart    `json:"parts"`
+	Controls []oscalControl `json:"controls"`
+}
+
+type oscalParam struct {
+	ID     string   `json:"id"`
+	Label  string   `json:"label"`
+	Values []string `json:"values"`
+	Select *struct {
+		HowMany string   `json:"how-many"`
+		Choice  []string `json:"choice"`
+	} `json:"select"`
+}
+
+type oscalPart struct {
+	Name  string      `json:"name"`
+	Props []oscalProp `json:"props"`
+	Prose string      `json:"prose"`
+	Parts []oscalPart `json:"parts"`
+}
+
+type oscalProp struct {
+	Name  string `json:"name"`
+	Class string `json:"class"`
+	Value string `json:"value"`
+}
+
+var paramInsert = regexp.MustCompile(`\{\{\s*insert:\s*param,\s*([^\s}]+)\s*\}\}`)
+
+// ParseOSCAL reads an OSCAL catalog in JSON. Groups become parents of the
+// controls in them and control enhancements become children of their
+// control. Controls are referred to by their label (e.g. "AC-2(1)") or, if
+// they have none, their id; withdrawn controls are left out.
+func ParseOSCAL(r io.Reader) (*Catalog, error) {
+	var doc oscalDocument
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid OSCAL JSON: %v", err)
+	}
+	if doc.Catalog == nil {
+		return nil, errors.New("file is not an OSCAL catalog")
+	}
+
+	p := &oscalParser{
+		catalog: &Catalog{Format: "oscal", Title: doc.Catalog.Metadata.Title, Version: doc.Catalog.Metadata.Version},
+		params:  map[string]oscalParam{},
+	}
+	p.addParams(doc.Catalog.Params)
+	for _, group := range doc.Catalog.Groups {
+		p.group(group, "")
+	}
+	for _, control := range doc.Catalog.Controls {
+		p.control(control, "")
+	}
+	return p.catalog, nil
+}
+
+type oscalParser struct {
+	catalog *Catalog
+	params  map[string]oscalParam
+}
+
+func (p *oscalParser) addParams(params []oscalParam) {
+	for _, param := range params {
+		p.params[param.ID] = param
+	}
+}
+
+func (p *oscalParser) group(g oscalGroup, parentRef string) {
+	p.addParams(g.Params)
+	// A group without an id can't be referred to, so its contents go to
+	// the enclosing group instead
+	ref := parentRef
+	if id := label(g.Props, g.ID); id != "" {
+		ref = id
+		p.catalog.Controls = append(p.catalog.Controls, &Control{
+			Ref:         ref,
+			Title:       truncate(g.Title, maxTitleLength),
+			Description: p.prose(g.Parts, "overview"),
+			ParentRef:   parentRef,
+		})
+	}
+	for _, child := range g.Groups {
+		p.group(child, ref)
+	}
+	for _, control := range g.Controls {
+		p.control(control, ref)
+	}
+}
+
+func (p *oscalParser) control(c oscalControl, parentRef string) {
+	if withdrawn(c.Props) {
+		return
+	}
+	p.addParams(c.Params)
+	ref := label(c.Props, c.ID)
+	p.catalog.Controls = append(p.catalog.Controls, &Control{
+		Ref:         ref,
+		Title:       truncate(c.Title, maxTitleLength),
+		Description: p.prose(c.Parts, "statement"),
+		ParentRef:   parentRef,
+	})
+	for _, child := range c.Controls {
+		p.control(child, ref)
+	}
+}
+
+// prose renders the parts with the given name as text, one line per item
+// with its label, e.g. "a. Develop ...", and parameters filled in
+func (p *oscalParser) prose(parts []oscalPart, name string) string {
+	var lines []string
+	var render func(part oscalPart, depth int)
+	render = func(part oscalPart, depth int) {
+		text := strings.TrimSpace(part.Prose)
+		if l := label(part.Props, ""); l != "" {
+			text = strings.TrimSpace(l + " " + text)
+		}
+		if text != "" {
+			lines = append(lines, strings.Repeat("  ", depth)+p.insertParams(text))
+		}
+		for _, child := range part.Parts {
+			render(child, depth+1)
+		}
+	}
+	for _, part := range parts {
+		if part.Name != name {
+			continue
+		}
+		text := strings.TrimSpace(part.Prose)
+		if text != "" {
+			lines = append(lines, p.insertParams(text))
+		}
+		for _, child := range part.Parts {
+			render(child, 0)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// insertParams replaces parameter references with their values, or with an
+// "[Assignment: ...]" or "[Selection: ...]" placeholder as NIST prints them
+func (p *oscalParser) insertParams(text string) string {
+	return p.insertParamsDepth(text, 0)
+}
+
+// maxParamDepth stops choices that refer to parameters from recursing
+// forever
+const maxParamDepth = 3
+
+func (p *oscalParser) insertParamsDepth(text string, depth int) string {
+	return paramInsert.ReplaceAllStringFunc(text, func(match string) string {
+		id := paramInsert.FindStringSubmatch(match)[1]
+		param, ok := p.params[id]
+		switch {
+		case !ok || depth >= maxParamDepth:
+			return "[Assignment: " + id + "]"
+		case len(param.Values) > 0:
+			return strings.Join(param.Values, ", ")
+		case param.Select != nil:
+			choices := make([]string, len(param.Select.Choice))
+			for i, choice := range param.Select.Choice {
+				choices[i] = p.insertParamsDepth(choice, depth+1)
+			}
+			if param.Select.HowMany == "one-or-more" {
+				return "[Selection (one or more): " + strings.Join(choices, "; ") + "]"
+			}
+			return "[Selection: " + strings.Join(choices, "; ") + "]"
+		case param.Label != "":
+			return "[Assignment: " + param.Label + "]"
+		default:
+			return "[Assignment: " + id + "]"
+		}
+	})
+}
+
+// label returns the label prop, preferring one without a class, or fallback
+func label(props []oscalProp, fallback string) string {
+	found := ""
+	for _, prop := range props {
+		if prop.Name != "label" {
+			continue
+		}
+		if prop.Class == "" {
+			return strings.TrimSpace(prop.Value)
+		}
+		if found == "" {
+			found = strings.TrimSpace(prop.Value)
+		}
+	}
+	if found != "" {
+		return found
+	}
+	return strings.TrimSpace(fallback)
+}
+
+func withdrawn(props []oscalProp) bool {
+	for _, prop := range props {
+		if prop.Name == "status" && strings.EqualFold(prop.Value, "withdrawn") {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
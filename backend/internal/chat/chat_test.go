@@ -0,0 +1,345 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+func TestTriggers(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  models.AuditAction
+		changes map[string]any
+		want    []models.ChatTrigger
+	}{
+		{"created", models.AuditActionCreated, map[string]any{"priority": "p3"}, []models.ChatTrigger{models.ChatTriggerCreated}},
+		{"escalated", models.AuditActionUpdated, map[string]any{"priority": map[string]any{"from": models.PriorityP3, "to": models.PriorityP1}}, []models.ChatTrigger{models.ChatTriggerEscalated}},
+		{"already p1", models.AuditActionUpdated, map[string]any{"priority": map[string]any{"from": "p1", "to": "p1"}}, nil},
+		{"lowered", models.AuditActionUpdated, map[string]any{"priority": map[string]any{"from": "p1", "to": "p2"}}, nil},
+		{"assigned", models.AuditActionUpdated, map[string]any{"assignee_id": map[string]any{"from": nil, "to": "user-1"}}, []models.ChatTrigger{models.ChatTriggerAssigned}},
+		{"unassigned", models.AuditActionUpdated, map[string]any{"assignee_id": map[string]any{"from": "user-1", "to": nil}}, nil},
+		{"resolved", models.AuditActionUpdated, map[string]any{"status": map[string]any{"from": "in_progress", "to": models.IncidentStatusResolved}}, []models.ChatTrigger{models.ChatTriggerResolved}},
+		{"closed", models.AuditActionUpdated, map[string]any{"status": map[string]any{"from": "resolved", "to": "closed"}}, nil},
+		{"several", models.AuditActionUpdated, map[string]any{
+			"priority":    map[string]any{"from": "p2", "to": "p1"},
+			"assignee_id": map[string]any{"from": nil, "to": "user-1"},
+		}, []models.ChatTrigger{models.ChatTriggerEscalated, models.ChatTriggerAssigned}},
+		{"deleted", models.AuditActionDeleted, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Triggers(tt.action, tt.changes)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Triggers() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Triggers() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	network := "cat-network"
+	incident := &models.Incident{Priority: models.PriorityP2, CategoryID: &network}
+
+	if !Routes(&models.ChatChannel{}, incident) {
+		t.Error("a channel without rules should match every incident")
+	}
+	if !Routes(&models.ChatChannel{Priorities: []models.IncidentPriority{models.PriorityP1, models.PriorityP2}, CategoryIDs: []string{network}}, incident) {
+		t.Error("expected matching priority and category to route")
+	}
+	if Routes(&models.ChatChannel{Priorities: []models.IncidentPriority{models.PriorityP1}}, incident) {
+		t.Error("expected a p1-only channel to skip a p2 incident")
+	}
+	if Routes(&models.ChatChannel{CategoryIDs: []string{"cat-security"}}, incident) {
+		t.Error("expected a category rule to skip other categories")
+	}
+	if Routes(&models.ChatChannel{CategoryIDs: []string{network}}, &models.Incident{Priority: models.PriorityP2}) {
+		t.Error("expected a category rule to skip uncategorised incidents")
+	}
+}
+
+func sampleData() *MessageData {
+	return &MessageData{
+		Trigger: models.ChatTriggerCreated,
+		Incident: &models.Incident{
+			ID:              "inc-1",
+			Title:           "Checkout <down>",
+			Priority:        models.PriorityP1,
+			Status:          models.IncidentStatusInProgress,
+			ServiceAffected: "checkout",
+			Category:        &models.IncidentCategory{Name: "Availability"},
+		},
+		Actor:    "Jane Doe",
+		Assignee: "John Doe",
+		URL:      "https://risk.example.com/app/incidents/inc-1",
+	}
+}
+
+func TestRender_Slack(t *testing.T) {
+	channel := &models.ChatChannel{Type: models.ChatChannelSlack}
+	payload, err := Render(channel, models.ChatTriggerCreated, sampleData())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	var msg struct {
+		Text   string           `json:"text"`
+		Blocks []map[string]any `json:"blocks"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if msg.Text != "New P1 incident: Checkout <down>" {
+		t.Errorf("unexpected fallback text %q", msg.Text)
+	}
+	types := []string{}
+	for _, b := range msg.Blocks {
+		types = append(types, b["type"].(string))
+	}
+	if strings.Join(types, ",") != "header,section,section,actions" {
+		t.Errorf("unexpected blocks %v", types)
+	}
+	body := string(payload)
+	for _, want := range []string{`*Priority*\nP1`, `*Status*\nIn progress`, `*Category*\nAvailability`, `*Assignee*\nJohn Doe`, "Jane Doe opened an incident affecting checkout.", "https://risk.example.com/app/incidents/inc-1"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected payload to contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestRender_Teams(t *testing.T) {
+	channel := &models.ChatChannel{Type: models.ChatChannelTeams}
+	payload, err := Render(channel, models.ChatTriggerResolved, sampleData())
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	var msg struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type    string           `json:"type"`
+				Body    []map[string]any `json:"body"`
+				Actions []map[string]any `json:"actions"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if msg.Type != "message" || len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "application/vnd.microsoft.card.adaptive" {
+		t.Fatalf("unexpected envelope: %s", payload)
+	}
+	card := msg.Attachments[0].Content
+	if card.Type != "AdaptiveCard" || card.Body[0]["text"] != "Resolved: Checkout <down>" || card.Body[0]["color"] != "Attention" {
+		t.Errorf("unexpected card heading: %v", card.Body[0])
+	}
+	if card.Body[len(card.Body)-1]["type"] != "FactSet" || len(card.Actions) != 1 {
+		t.Errorf("expected facts and a link: %s", payload)
+	}
+}
+
+func TestRender_Templates(t *testing.T) {
+	channel := &models.ChatChannel{
+		Type: models.ChatChannelSlack,
+		Templates: map[models.ChatTrigger]models.ChatTemplate{
+			models.ChatTriggerCreated: {Title: ":rotating_light: {{.Incident.Title}} ({{upper .Incident.Priority}})"},
+			// Fails at render time, so the default wording is used instead
+			models.ChatTriggerResolved: {Title: "{{.Incident.Missing}}"},
+		},
+	}
+	payload, _ := Render(channel, models.ChatTriggerCreated, sampleData())
+	if !strings.Contains(string(payload), `":rotating_light: Checkout \u003cdown\u003e (P1)"`) {
+		t.Errorf("expected the custom title to be used: %s", payload)
+	}
+	if !strings.Contains(string(payload), "Jane Doe opened an incident") {
+		t.Errorf("expected the default body when only the title is customised: %s", payload)
+	}
+
+	payload, _ = Render(channel, models.ChatTriggerResolved, sampleData())
+	if !strings.Contains(string(payload), "Resolved: Checkout") {
+		t.Errorf("expected the default title for a broken template: %s", payload)
+	}
+
+	if err := ValidateTemplate("{{.Incident.Title"); err == nil {
+		t.Error("expected a parse error")
+	}
+	if err := ValidateTemplate("{{.Incident.Missing}}"); err == nil {
+		t.Error("expected an execution error for an unknown field")
+	}
+	if err := ValidateTemplate("{{.Incident.Title}} for {{.Assignee}}"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type stubAudit struct {
+	database.AuditLogRepository
+	entries int
+}
+
+func (s *stubAudit) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	s.entries++
+	return nil
+}
+
+func (s *stubAudit) WithTx(tx *sql.Tx) database.AuditLogRepository { return s }
+
+type stubIncidents struct {
+	database.IncidentRepository
+	incident *models.Incident
+}
+
+func (s *stubIncidents) FindByID(ctx context.Context, id string) (*models.Incident, error) {
+	return s.incident, nil
+}
+
+func (s *stubIncidents) WithTx(tx *sql.Tx) database.IncidentRepository { return s }
+
+type stubUsers struct {
+	database.UserRepository
+}
+
+func (s *stubUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	if id == "user-1" {
+		return &models.User{ID: id, Name: "Jane Doe", Email: "jane@example.com"}, nil
+	}
+	return nil, database.ErrUserNotFound
+}
+
+type stubChannels struct {
+	database.ChatChannelRepository
+	channels   []*models.ChatChannel
+	messages   []*models.ChatMessage
+	dispatches []*models.ChatDispatch
+	attempts   map[string]*models.ChatAttempt
+}
+
+func (s *stubChannels) ListActive(ctx context.Context, trigger models.ChatTrigger) ([]*models.ChatChannel, error) {
+	var list []*models.ChatChannel
+	for _, ch := range s.channels {
+		for _, t := range ch.Triggers {
+			if t == trigger {
+				list = append(list, ch)
+			}
+		}
+	}
+	return list, nil
+}
+
+func (s *stubChannels) Enqueue(ctx context.Context, m *models.ChatMessage) error {
+	s.messages = append(s.messages, m)
+	return nil
+}
+
+func (s *stubChannels) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.ChatDispatch, error) {
+	claimed := s.dispatches
+	s.dispatches = nil
+	return claimed, nil
+}
+
+func (s *stubChannels) RecordAttempt(ctx context.Context, id string, a *models.ChatAttempt) error {
+	s.attempts[id] = a
+	return nil
+}
+
+func (s *stubChannels) WithTx(tx *sql.Tx) database.ChatChannelRepository { return s }
+
+func TestAuditNotifier(t *testing.T) {
+	audit := &stubAudit{}
+	incident := &models.Incident{ID: "inc-1", Title: "Checkout down", Priority: models.PriorityP1, Status: models.IncidentStatusNew}
+	channels := &stubChannels{channels: []*models.ChatChannel{
+		{ID: "all", Type: models.ChatChannelSlack, Triggers: []models.ChatTrigger{models.ChatTriggerCreated, models.ChatTriggerResolved}},
+		{ID: "p2-only", Type: models.ChatChannelTeams, Triggers: []models.ChatTrigger{models.ChatTriggerCreated}, Priorities: []models.IncidentPriority{models.PriorityP2}},
+	}}
+	notifier := NewAuditNotifier(audit, channels, &stubIncidents{incident: incident}, &stubUsers{}, "https://risk.example.com/").WithTx(nil)
+
+	if err := notifier.Create(context.Background(), "incident", "inc-1", models.AuditActionCreated, map[string]any{}, "user-1"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if audit.entries != 1 {
+		t.Errorf("expected the audit entry to be written, got %d", audit.entries)
+	}
+	if len(channels.messages) != 1 || channels.messages[0].ChannelID != "all" || channels.messages[0].Trigger != models.ChatTriggerCreated {
+		t.Fatalf("expected one message for the routed channel, got %+v", channels.messages)
+	}
+	payload := string(channels.messages[0].Payload)
+	if !strings.Contains(payload, "Jane Doe opened") || !strings.Contains(payload, "https://risk.example.com/app/incidents/inc-1") {
+		t.Errorf("unexpected payload: %s", payload)
+	}
+
+	// Risk entries and changes that raise no trigger queue nothing
+	notifier.Create(context.Background(), "risk", "risk-1", models.AuditActionCreated, map[string]any{}, "user-1")
+	notifier.Create(context.Background(), "incident", "inc-1", models.AuditActionUpdated, map[string]any{"title": map[string]any{"from": "a", "to": "b"}}, "user-1")
+	if len(channels.messages) != 1 {
+		t.Errorf("expected no further messages, got %d", len(channels.messages))
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	var bodies []string
+	status := http.StatusOK
+	retryAfter := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(status)
+		if status >= 400 {
+			w.Write([]byte("invalid_blocks"))
+		}
+	}))
+	defer server.Close()
+
+	channels := &stubChannels{attempts: map[string]*models.ChatAttempt{}}
+	dispatcher := NewDispatcher(channels, &http.Client{Timeout: 5 * time.Second}, time.Minute)
+	dispatch := func(attempts int) *models.ChatAttempt {
+		channels.dispatches = []*models.ChatDispatch{{
+			Message:    &models.ChatMessage{ID: "msg-1", Payload: json.RawMessage(`{"text":"hi"}`), Attempts: attempts},
+			WebhookURL: server.URL,
+		}}
+		if n, err := dispatcher.DispatchDue(context.Background(), time.Now()); err != nil || n != 1 {
+			t.Fatalf("DispatchDue() = %d, %v", n, err)
+		}
+		return channels.attempts["msg-1"]
+	}
+
+	if a := dispatch(0); a.Status != models.ChatMessageSent || *a.ResponseStatus != 200 || bodies[0] != `{"text":"hi"}` {
+		t.Errorf("expected the message to be sent, got %+v", a)
+	}
+
+	status = http.StatusBadRequest
+	a := dispatch(0)
+	if a.Status != models.ChatMessagePending || a.NextAttemptAt == nil || a.Error == nil || !strings.Contains(*a.Error, "invalid_blocks") {
+		t.Errorf("expected a retry with the channel's reason, got %+v", a)
+	}
+
+	status, retryAfter = http.StatusTooManyRequests, "120"
+	if a := dispatch(0); a.NextAttemptAt == nil || time.Until(*a.NextAttemptAt) < 100*time.Second {
+		t.Errorf("expected Retry-After to push the next attempt out, got %v", a.NextAttemptAt)
+	}
+
+	status, retryAfter = http.StatusInternalServerError, ""
+	if a := dispatch(MaxAttempts - 1); a.Status != models.ChatMessageFailed || a.NextAttemptAt != nil {
+		t.Errorf("expected the last attempt to fail the message, got %+v", a)
+	}
+}
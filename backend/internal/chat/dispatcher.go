@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/webhooks"
+)
+
+const (
+	// MaxAttempts is lower than for webhooks: a chat message that is hours
+	// late is no longer worth posting
+	MaxAttempts = 5
+
+	claimBatchSize = 50
+	// errorBodyLimit is how much of a rejected request's response is kept
+	// in the error
+	errorBodyLimit = 256
+)
+
+// Dispatcher posts queued chat messages to their channels
+type Dispatcher struct {
+	channels database.ChatChannelRepository
+	client   *http.Client
+	interval time.Duration
+}
+
+func NewDispatcher(channels database.ChatChannelRepository, client *http.Client, interval time.Duration) *Dispatcher {
+	return &Dispatcher{channels: channels, client: client, interval: interval}
+}
+
+// Run sends due messages every interval until ctx is cancelled. A zero
+// interval disables the dispatcher.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchDue(ctx, time.Now()); err != nil {
+			log.Printf("chat dispatcher: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue posts every message due at now and returns how many were
+// attempted
+func (d *Dispatcher) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	lease := now.Add(2*d.client.Timeout + time.Minute)
+	dispatches, err := d.channels.ClaimDue(ctx, now, lease, claimBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claiming messages: %w", err)
+	}
+
+	for _, dispatch := range dispatches {
+		attempt := d.attempt(ctx, dispatch)
+		if err := d.channels.RecordAttempt(ctx, dispatch.Message.ID, attempt); err != nil {
+			return 0, fmt.Errorf("recording message %s: %w", dispatch.Message.ID, err)
+		}
+	}
+	return len(dispatches), nil
+}
+
+// attempt posts one message and works out what happens to it next
+func (d *Dispatcher) attempt(ctx context.Context, dispatch *models.ChatDispatch) *models.ChatAttempt {
+	message := dispatch.Message
+	now := time.Now()
+	attempt := &models.ChatAttempt{Status: models.ChatMessageSent, AttemptedAt: now}
+
+	status, retryAfter, err := d.Send(ctx, dispatch.WebhookURL, message.Payload)
+	if status != 0 {
+		attempt.ResponseStatus = &status
+	}
+	if err == nil {
+		return attempt
+	}
+
+	msg := err.Error()
+	attempt.Error = &msg
+	if message.Attempts+1 >= MaxAttempts {
+		attempt.Status = models.ChatMessageFailed
+		return attempt
+	}
+	delay := webhooks.Backoff(message.Attempts + 1)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	next := now.Add(delay)
+	attempt.Status = models.ChatMessagePending
+	attempt.NextAttemptAt = &next
+	return attempt
+}
+
+// Send posts payload to an incoming-webhook URL. It returns the response
+// status, if there was one, and how long the receiver asked to wait before
+// retrying when it rate limited the request.
+func (d *Dispatcher) Send(ctx context.Context, url string, payload []byte) (status int, retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "risk-register-chat")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Slack explains rejections in the body, e.g. "invalid_blocks"
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, errorBodyLimit))
+		if reason := strings.TrimSpace(strings.ReplaceAll(strings.ToValidUTF8(string(body), ""), "\x00", "")); reason != "" {
+			return resp.StatusCode, retryAfter, fmt.Errorf("channel responded with %s: %s", resp.Status, reason)
+		}
+		return resp.StatusCode, retryAfter, fmt.Errorf("channel responded with %s", resp.Status)
+	}
+	return resp.StatusCode, 0, nil
+}
@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+// auditNotifier records audit entries and queues the chat messages that
+// incident entries raise. Like the webhook emitter it writes inside the
+// caller's transaction, so rolled back changes post nothing.
+type auditNotifier struct {
+	database.AuditLogRepository
+	channels  database.ChatChannelRepository
+	incidents database.IncidentRepository
+	users     database.UserRepository
+	appURL    string
+}
+
+// NewAuditNotifier wraps audit so that incident entries also queue messages
+// for the chat channels they route to. appURL is the web app's base URL used
+// for incident links; leave it empty to send messages without links.
+func NewAuditNotifier(audit database.AuditLogRepository, channels database.ChatChannelRepository, incidents database.IncidentRepository, users database.UserRepository, appURL string) database.AuditLogRepository {
+	return &auditNotifier{
+		AuditLogRepository: audit,
+		channels:           channels,
+		incidents:          incidents,
+		users:              users,
+		appURL:             strings.TrimRight(appURL, "/"),
+	}
+}
+
+func (n *auditNotifier) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	if err := n.AuditLogRepository.Create(ctx, entityType, entityID, action, changes, userID); err != nil {
+		return err
+	}
+	if entityType != "incident" {
+		return nil
+	}
+	triggers := Triggers(action, changes)
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	incident, err := n.incidents.FindByID(ctx, entityID)
+	if err != nil {
+		return fmt.Errorf("loading incident for chat notification: %w", err)
+	}
+	data := &MessageData{Incident: incident, Actor: n.displayName(ctx, &userID)}
+	if data.Actor == "" {
+		data.Actor = "Someone"
+	}
+	data.Assignee = n.displayName(ctx, incident.AssigneeID)
+	if n.appURL != "" {
+		data.URL = n.appURL + "/app/incidents/" + incident.ID
+	}
+
+	for _, trigger := range triggers {
+		channels, err := n.channels.ListActive(ctx, trigger)
+		if err != nil {
+			return fmt.Errorf("listing chat channels: %w", err)
+		}
+		data.Trigger = trigger
+		for _, channel := range channels {
+			if !Routes(channel, incident) {
+				continue
+			}
+			payload, err := Render(channel, trigger, data)
+			if err != nil {
+				return fmt.Errorf("rendering %s message for channel %s: %w", trigger, channel.ID, err)
+			}
+			message := &models.ChatMessage{ChannelID: channel.ID, Trigger: trigger, IncidentID: incident.ID, Payload: payload}
+			if err := n.channels.Enqueue(ctx, message); err != nil {
+				return fmt.Errorf("queueing %s message for channel %s: %w", trigger, channel.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// displayName is a user's name, or their email if they have none. Unknown
+// users are shown as an empty string.
+func (n *auditNotifier) displayName(ctx context.Context, userID *string) string {
+	if userID == nil || *userID == "" {
+		return ""
+	}
+	user, err := n.users.FindByID(ctx, *userID)
+	if err != nil || user == nil {
+		return ""
+	}
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.Email
+}
+
+func (n *auditNotifier) WithTx(tx *sql.Tx) database.AuditLogRepository {
+	return &auditNotifier{
+		AuditLogRepository: n.AuditLogRepository.WithTx(tx),
+		channels:           n.channels.WithTx(tx),
+		incidents:          n.incidents.WithTx(tx),
+		users:              n.users,
+		appURL:             n.appURL,
+	}
+}
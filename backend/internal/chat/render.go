@@ -0,0 +1,215 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"backend/internal/models"
+)
+
+// Slack limits: header text is plain text up to 150 characters and a
+// section's text up to 3000
+const (
+	slackHeaderLimit  = 150
+	slackSectionLimit = 3000
+)
+
+// MessageData is what templates render. Actor and Assignee are display
+// names; URL links to the incident in the web app when APP_URL is set.
+type MessageData struct {
+	Trigger  models.ChatTrigger
+	Incident *models.Incident
+	Actor    string
+	Assignee string
+	URL      string
+}
+
+// DefaultTemplates are used for triggers a channel has no template for
+var DefaultTemplates = map[models.ChatTrigger]models.ChatTemplate{
+	models.ChatTriggerCreated: {
+		Title: "New {{upper .Incident.Priority}} incident: {{.Incident.Title}}",
+		Body:  "{{.Actor}} opened an incident{{with .Incident.ServiceAffected}} affecting {{.}}{{end}}.{{with .Incident.Description}}\n{{.}}{{end}}",
+	},
+	models.ChatTriggerEscalated: {
+		Title: "Escalated to P1: {{.Incident.Title}}",
+		Body:  "{{.Actor}} raised the incident to P1.",
+	},
+	models.ChatTriggerAssigned: {
+		Title: "Incident assigned: {{.Incident.Title}}",
+		Body:  "{{.Actor}} assigned the incident to {{or .Assignee \"someone\"}}.",
+	},
+	models.ChatTriggerResolved: {
+		Title: "Resolved: {{.Incident.Title}}",
+		Body:  "{{.Actor}} resolved the incident.{{with .Incident.ResolutionNotes}}\n{{.}}{{end}}",
+	},
+}
+
+var templateFuncs = template.FuncMap{
+	"upper": func(v any) string { return strings.ToUpper(fmt.Sprint(v)) },
+}
+
+// ValidateTemplate parses text and renders it against a sample incident, so
+// mistakes are caught when a channel is saved rather than when it fires
+func ValidateTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+	category := "cat-sample"
+	sample := MessageData{
+		Trigger:  models.ChatTriggerCreated,
+		Incident: &models.Incident{Title: "Sample", Priority: models.PriorityP1, Status: models.IncidentStatusNew, CategoryID: &category},
+		Actor:    "Jane Doe",
+		Assignee: "John Doe",
+		URL:      "https://example.com/app/incidents/1",
+	}
+	_, err := execute(text, &sample)
+	return err
+}
+
+func execute(text string, data *MessageData) (string, error) {
+	tmpl, err := template.New("message").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// renderText renders one template field, falling back to the default
+// wording if the channel's template fails on this incident
+func renderText(custom, fallback string, data *MessageData) string {
+	if custom != "" {
+		if text, err := execute(custom, data); err == nil {
+			return text
+		}
+	}
+	text, _ := execute(fallback, data)
+	return text
+}
+
+// Render builds the JSON body to post to a channel for trigger
+func Render(channel *models.ChatChannel, trigger models.ChatTrigger, data *MessageData) (json.RawMessage, error) {
+	custom := channel.Templates[trigger]
+	fallback := DefaultTemplates[trigger]
+	title := renderText(custom.Title, fallback.Title, data)
+	body := renderText(custom.Body, fallback.Body, data)
+
+	var payload any
+	switch channel.Type {
+	case models.ChatChannelSlack:
+		payload = slackMessage(title, body, facts(data), data.URL)
+	case models.ChatChannelTeams:
+		payload = teamsMessage(title, body, facts(data), data.URL, data.Incident.Priority == models.PriorityP1)
+	default:
+		return nil, fmt.Errorf("unknown channel type %q", channel.Type)
+	}
+	return json.Marshal(payload)
+}
+
+type fact struct {
+	Title string
+	Value string
+}
+
+// facts are the incident fields shown under every message
+func facts(data *MessageData) []fact {
+	incident := data.Incident
+	list := []fact{
+		{"Priority", strings.ToUpper(string(incident.Priority))},
+		{"Status", humanize(string(incident.Status))},
+	}
+	if incident.Category != nil {
+		list = append(list, fact{"Category", incident.Category.Name})
+	}
+	if incident.ServiceAffected != "" {
+		list = append(list, fact{"Service", incident.ServiceAffected})
+	}
+	if data.Assignee != "" {
+		list = append(list, fact{"Assignee", data.Assignee})
+	}
+	return list
+}
+
+// humanize turns a status such as in_progress into "In progress"
+func humanize(s string) string {
+	s = strings.ReplaceAll(s, "_", " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// slackMessage is a Block Kit message. Text is the fallback shown in
+// notifications and by clients that cannot render blocks.
+func slackMessage(title, body string, facts []fact, url string) map[string]any {
+	blocks := []any{
+		map[string]any{"type": "header", "text": map[string]any{"type": "plain_text", "text": truncate(title, slackHeaderLimit), "emoji": true}},
+	}
+	if body != "" {
+		blocks = append(blocks, map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": truncate(slackEscape(body), slackSectionLimit)}})
+	}
+	fields := []any{}
+	for _, f := range facts {
+		fields = append(fields, map[string]any{"type": "mrkdwn", "text": "*" + f.Title + "*\n" + slackEscape(f.Value)})
+	}
+	blocks = append(blocks, map[string]any{"type": "section", "fields": fields})
+	if url != "" {
+		blocks = append(blocks, map[string]any{"type": "actions", "elements": []any{
+			map[string]any{"type": "button", "text": map[string]any{"type": "plain_text", "text": "View incident"}, "url": url},
+		}})
+	}
+	return map[string]any{"text": title, "blocks": blocks}
+}
+
+// slackEscape escapes the characters mrkdwn treats as control sequences
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// teamsMessage wraps an Adaptive Card the way Teams incoming webhooks and
+// workflow webhooks expect. P1 titles are shown in the attention colour.
+func teamsMessage(title, body string, facts []fact, url string, urgent bool) map[string]any {
+	heading := map[string]any{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "wrap": true}
+	if urgent {
+		heading["color"] = "Attention"
+	}
+	cardBody := []any{heading}
+	if body != "" {
+		cardBody = append(cardBody, map[string]any{"type": "TextBlock", "text": body, "wrap": true})
+	}
+	factSet := []any{}
+	for _, f := range facts {
+		factSet = append(factSet, map[string]any{"title": f.Title, "value": f.Value})
+	}
+	cardBody = append(cardBody, map[string]any{"type": "FactSet", "facts": factSet})
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    cardBody,
+	}
+	if url != "" {
+		card["actions"] = []any{map[string]any{"type": "Action.OpenUrl", "title": "View incident", "url": url}}
+	}
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{
+			map[string]any{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
+
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
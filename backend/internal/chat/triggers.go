@@ -0,0 +1,60 @@
+// Package chat posts incident lifecycle notifications to Slack and Microsoft
+// Teams incoming webhooks. Messages are rendered from per-channel templates
+// when the audit entry that raises them is written, queued in the same
+// transaction, and posted by a background dispatcher that retries failures.
+package chat
+
+import (
+	"fmt"
+	"slices"
+
+	"backend/internal/models"
+)
+
+// Triggers returns the chat triggers raised by an incident audit entry
+func Triggers(action models.AuditAction, changes map[string]any) []models.ChatTrigger {
+	switch action {
+	case models.AuditActionCreated:
+		return []models.ChatTrigger{models.ChatTriggerCreated}
+	case models.AuditActionUpdated:
+		var triggers []models.ChatTrigger
+		if from, to, ok := change(changes, "priority"); ok && to == string(models.PriorityP1) && from != to {
+			triggers = append(triggers, models.ChatTriggerEscalated)
+		}
+		if from, to, ok := change(changes, "assignee_id"); ok && to != "" && from != to {
+			triggers = append(triggers, models.ChatTriggerAssigned)
+		}
+		if from, to, ok := change(changes, "status"); ok && to == string(models.IncidentStatusResolved) && from != to {
+			triggers = append(triggers, models.ChatTriggerResolved)
+		}
+		return triggers
+	}
+	return nil
+}
+
+// change reads a from/to pair from audit changes, with nil values as ""
+func change(changes map[string]any, field string) (from, to string, ok bool) {
+	c, ok := changes[field].(map[string]any)
+	if !ok {
+		return "", "", false
+	}
+	text := func(v any) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	return text(c["from"]), text(c["to"]), true
+}
+
+// Routes reports whether a channel's priority and category rules match the
+// incident. An empty rule matches everything.
+func Routes(channel *models.ChatChannel, incident *models.Incident) bool {
+	if len(channel.Priorities) > 0 && !slices.Contains(channel.Priorities, incident.Priority) {
+		return false
+	}
+	if len(channel.CategoryIDs) > 0 && (incident.CategoryID == nil || !slices.Contains(channel.CategoryIDs, *incident.CategoryID)) {
+		return false
+	}
+	return true
+}
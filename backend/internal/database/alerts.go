@@ -0,0 +1,259 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var (
+	ErrAlertSourceNotFound   = errors.New("alert source not found")
+	ErrIncidentAlertNotFound = errors.New("incident alert not found")
+)
+
+type AlertRepository interface {
+	CreateSource(ctx context.Context, source *models.AlertSource, tokenHash string) error
+	FindSource(ctx context.Context, id string) (*models.AlertSource, error)
+	// FindSourceByToken returns the source whose token hashes to tokenHash
+	FindSourceByToken(ctx context.Context, tokenHash string) (*models.AlertSource, error)
+	ListSources(ctx context.Context) ([]*models.AlertSource, error)
+	// UpdateSource saves a source; a non-empty tokenHash replaces its token
+	UpdateSource(ctx context.Context, source *models.AlertSource, tokenHash string) error
+	DeleteSource(ctx context.Context, id string) error
+	MarkReceived(ctx context.Context, sourceID string, at time.Time) error
+	// FindOpen returns the alert row for fingerprint whose incident is still
+	// open. It first takes a transaction-scoped lock on the fingerprint, so
+	// concurrent deliveries of the same alert are handled one at a time and
+	// cannot both open an incident; call it inside a transaction.
+	FindOpen(ctx context.Context, sourceID, fingerprint string) (*models.IncidentAlert, error)
+	CreateAlert(ctx context.Context, alert *models.IncidentAlert) error
+	UpdateAlert(ctx context.Context, alert *models.IncidentAlert) error
+	ListByIncident(ctx context.Context, incidentID string) ([]*models.IncidentAlert, error)
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) AlertRepository
+}
+
+type alertRepository struct {
+	db dbtx
+}
+
+func NewAlertRepository(db *sql.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+func (r *alertRepository) WithTx(tx *sql.Tx) AlertRepository {
+	return &alertRepository{db: tx}
+}
+
+const alertSourceColumns = `id, name, mapping, active, created_by, last_received_at, created_at, updated_at`
+
+func scanAlertSource(row interface{ Scan(...any) error }) (*models.AlertSource, error) {
+	s := &models.AlertSource{}
+	var mapping []byte
+	err := row.Scan(&s.ID, &s.Name, &mapping, &s.Active, &s.CreatedBy, &s.LastReceivedAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(mapping, &s.Mapping); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *alertRepository) CreateSource(ctx context.Context, s *models.AlertSource, tokenHash string) error {
+	mapping, err := json.Marshal(s.Mapping)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO alert_sources (name, token_hash, mapping, active, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRowContext(ctx, query, s.Name, tokenHash, string(mapping), s.Active, s.CreatedBy).
+		Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt)
+}
+
+func (r *alertRepository) FindSource(ctx context.Context, id string) (*models.AlertSource, error) {
+	s, err := scanAlertSource(r.db.QueryRowContext(ctx, `SELECT `+alertSourceColumns+` FROM alert_sources WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAlertSourceNotFound
+	}
+	return s, err
+}
+
+func (r *alertRepository) FindSourceByToken(ctx context.Context, tokenHash string) (*models.AlertSource, error) {
+	s, err := scanAlertSource(r.db.QueryRowContext(ctx, `SELECT `+alertSourceColumns+` FROM alert_sources WHERE token_hash = $1`, tokenHash))
+	if err == sql.ErrNoRows {
+		return nil, ErrAlertSourceNotFound
+	}
+	return s, err
+}
+
+func (r *alertRepository) ListSources(ctx context.Context) ([]*models.AlertSource, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+alertSourceColumns+` FROM alert_sources ORDER BY name, created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []*models.AlertSource{}
+	for rows.Next() {
+		s, err := scanAlertSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
+
+func (r *alertRepository) UpdateSource(ctx context.Context, s *models.AlertSource, tokenHash string) error {
+	mapping, err := json.Marshal(s.Mapping)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE alert_sources
+		SET name = $2, mapping = $3, active = $4, token_hash = COALESCE(NULLIF($5, ''), token_hash), updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err = r.db.QueryRowContext(ctx, query, s.ID, s.Name, string(mapping), s.Active, tokenHash).Scan(&s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrAlertSourceNotFound
+	}
+	return err
+}
+
+func (r *alertRepository) DeleteSource(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM alert_sources WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrAlertSourceNotFound
+	}
+	return nil
+}
+
+func (r *alertRepository) MarkReceived(ctx context.Context, sourceID string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE alert_sources SET last_received_at = $2 WHERE id = $1`, sourceID, at)
+	return err
+}
+
+const incidentAlertColumns = `a.id, a.source_id, a.fingerprint, a.incident_id, a.status, a.labels, a.annotations,
+	a.starts_at, a.ends_at, a.occurrences, a.created_at, a.last_received_at`
+
+func scanIncidentAlert(row interface{ Scan(...any) error }) (*models.IncidentAlert, error) {
+	a := &models.IncidentAlert{}
+	var labels, annotations []byte
+	err := row.Scan(&a.ID, &a.SourceID, &a.Fingerprint, &a.IncidentID, &a.Status, &labels, &annotations,
+		&a.StartsAt, &a.EndsAt, &a.Occurrences, &a.CreatedAt, &a.LastReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(labels, &a.Labels); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(annotations, &a.Annotations); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r *alertRepository) FindOpen(ctx context.Context, sourceID, fingerprint string) (*models.IncidentAlert, error) {
+	if _, err := r.db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2))`, sourceID, fingerprint); err != nil {
+		return nil, err
+	}
+	query := `
+		SELECT ` + incidentAlertColumns + `
+		FROM incident_alerts a
+		JOIN incidents i ON i.id = a.incident_id
+		WHERE a.source_id = $1 AND a.fingerprint = $2
+			AND i.deleted_at IS NULL AND i.status NOT IN ('resolved', 'closed')
+		ORDER BY a.created_at DESC
+		LIMIT 1
+	`
+	a, err := scanIncidentAlert(r.db.QueryRowContext(ctx, query, sourceID, fingerprint))
+	if err == sql.ErrNoRows {
+		return nil, ErrIncidentAlertNotFound
+	}
+	return a, err
+}
+
+func (r *alertRepository) CreateAlert(ctx context.Context, a *models.IncidentAlert) error {
+	labels, err := json.Marshal(a.Labels)
+	if err != nil {
+		return err
+	}
+	annotations, err := json.Marshal(a.Annotations)
+	if err != nil {
+		return err
+	}
+	if a.Occurrences == 0 {
+		a.Occurrences = 1
+	}
+	query := `
+		INSERT INTO incident_alerts (source_id, fingerprint, incident_id, status, labels, annotations, starts_at, ends_at, occurrences, last_received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRowContext(ctx, query, a.SourceID, a.Fingerprint, a.IncidentID, a.Status, string(labels), string(annotations),
+		a.StartsAt, a.EndsAt, a.Occurrences, a.LastReceivedAt).Scan(&a.ID, &a.CreatedAt)
+}
+
+func (r *alertRepository) UpdateAlert(ctx context.Context, a *models.IncidentAlert) error {
+	labels, err := json.Marshal(a.Labels)
+	if err != nil {
+		return err
+	}
+	annotations, err := json.Marshal(a.Annotations)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE incident_alerts
+		SET status = $2, labels = $3, annotations = $4, ends_at = $5, occurrences = $6, last_received_at = $7
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, a.ID, a.Status, string(labels), string(annotations), a.EndsAt, a.Occurrences, a.LastReceivedAt)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrIncidentAlertNotFound
+	}
+	return nil
+}
+
+func (r *alertRepository) ListByIncident(ctx context.Context, incidentID string) ([]*models.IncidentAlert, error) {
+	query := `SELECT ` + incidentAlertColumns + ` FROM incident_alerts a WHERE a.incident_id = $1 ORDER BY a.created_at`
+	rows, err := r.db.QueryContext(ctx, query, incidentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []*models.IncidentAlert{}
+	for rows.Next() {
+		a, err := scanIncidentAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewAlertRepository(s.db)
+	incidents := NewIncidentRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "test-alerts-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Alert Admin",
+		Role:         models.RoleAdmin,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+
+	source := &models.AlertSource{
+		Name:      "Prometheus",
+		Mapping:   models.AlertMapping{PriorityLabel: "level"},
+		Active:    true,
+		CreatedBy: user.ID,
+	}
+	tokenHash := uuid.New().String() + uuid.New().String()[:28]
+	require.NoError(t, repo.CreateSource(ctx, source, tokenHash))
+	defer repo.DeleteSource(ctx, source.ID)
+
+	t.Run("find by token and rotate", func(t *testing.T) {
+		found, err := repo.FindSourceByToken(ctx, tokenHash)
+		require.NoError(t, err)
+		assert.Equal(t, source.ID, found.ID)
+		assert.Equal(t, "level", found.Mapping.PriorityLabel)
+
+		rotated := uuid.New().String() + uuid.New().String()[:28]
+		require.NoError(t, repo.UpdateSource(ctx, found, rotated))
+		_, err = repo.FindSourceByToken(ctx, tokenHash)
+		assert.ErrorIs(t, err, ErrAlertSourceNotFound)
+		_, err = repo.FindSourceByToken(ctx, rotated)
+		assert.NoError(t, err)
+
+		// An empty hash keeps the current token
+		require.NoError(t, repo.UpdateSource(ctx, found, ""))
+		_, err = repo.FindSourceByToken(ctx, rotated)
+		assert.NoError(t, err)
+	})
+
+	t.Run("open alert follows the incident status", func(t *testing.T) {
+		incident := &models.Incident{Title: "Checkout errors", ReporterID: user.ID, CreatedBy: user.ID, UpdatedBy: user.ID}
+		require.NoError(t, incidents.Create(ctx, incident))
+
+		fingerprint := uuid.New().String()
+		tx, err := s.db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		_, err = repo.WithTx(tx).FindOpen(ctx, source.ID, fingerprint)
+		assert.ErrorIs(t, err, ErrIncidentAlertNotFound)
+
+		link := &models.IncidentAlert{
+			SourceID:       source.ID,
+			Fingerprint:    fingerprint,
+			IncidentID:     incident.ID,
+			Status:         models.AlertStatusFiring,
+			Labels:         map[string]string{"alertname": "HighErrorRate"},
+			Annotations:    map[string]string{"summary": "Checkout errors"},
+			StartsAt:       time.Now().Add(-time.Minute),
+			LastReceivedAt: time.Now(),
+		}
+		require.NoError(t, repo.WithTx(tx).CreateAlert(ctx, link))
+		require.NoError(t, tx.Commit())
+
+		tx, err = s.db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		open, err := repo.WithTx(tx).FindOpen(ctx, source.ID, fingerprint)
+		require.NoError(t, err)
+		assert.Equal(t, link.ID, open.ID)
+		assert.Equal(t, "HighErrorRate", open.Labels["alertname"])
+
+		open.Occurrences++
+		require.NoError(t, repo.WithTx(tx).UpdateAlert(ctx, open))
+		require.NoError(t, tx.Commit())
+
+		incident.Status = models.IncidentStatusResolved
+		require.NoError(t, incidents.Update(ctx, incident))
+
+		tx, err = s.db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		_, err = repo.WithTx(tx).FindOpen(ctx, source.ID, fingerprint)
+		assert.ErrorIs(t, err, ErrIncidentAlertNotFound, "a resolved incident is no longer open")
+
+		list, err := repo.ListByIncident(ctx, incident.ID)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, 2, list[0].Occurrences)
+	})
+}
@@ -31,7 +31,7 @@ func (r *analyticsRepository) GetAnalytics(ctx context.Context, granularity mode
 	}
 
 	// Get total count
-	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM risks").Scan(&response.TotalRisks); err != nil {
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM risks WHERE deleted_at IS NULL").Scan(&response.TotalRisks); err != nil {
 		return nil, fmt.Errorf("failed to get total risks: %w", err)
 	}
 
@@ -68,9 +68,9 @@ func (r *analyticsRepository) populateByField(ctx context.Context, field string,
 	var query string
 	switch field {
 	case "severity":
-		query = "SELECT severity, COUNT(*) FROM risks GROUP BY severity"
+		query = "SELECT severity, COUNT(*) FROM risks WHERE deleted_at IS NULL GROUP BY severity"
 	case "status":
-		query = "SELECT status, COUNT(*) FROM risks GROUP BY status"
+		query = "SELECT status, COUNT(*) FROM risks WHERE deleted_at IS NULL GROUP BY status"
 	default:
 		return fmt.Errorf("invalid field for grouping: %s", field)
 	}
@@ -96,7 +96,7 @@ func (r *analyticsRepository) populateByCategory(ctx context.Context, target *[]
 	query := `
 		SELECT c.id, c.name, COUNT(r.id)
 		FROM categories c
-		LEFT JOIN risks r ON r.category_id = c.id
+		LEFT JOIN risks r ON r.category_id = c.id AND r.deleted_at IS NULL
 		GROUP BY c.id, c.name
 		ORDER BY COUNT(r.id) DESC
 	`
@@ -127,7 +127,8 @@ func (r *analyticsRepository) populateCreatedOverTime(ctx context.Context, granu
 	query := fmt.Sprintf(`
 		SELECT TO_CHAR(created_at, '%s') as period, COUNT(*) as count
 		FROM risks
-		WHERE created_at >= NOW() - INTERVAL '12 months'
+		WHERE deleted_at IS NULL
+		  AND created_at >= NOW() - INTERVAL '12 months'
 		GROUP BY period
 		ORDER BY period ASC
 	`, dateFormat)
@@ -160,7 +161,8 @@ func (r *analyticsRepository) populateStatusOverTime(ctx context.Context, granul
 	openedQuery := fmt.Sprintf(`
 		SELECT TO_CHAR(created_at, '%s') as period, COUNT(*) as count
 		FROM risks
-		WHERE created_at >= NOW() - INTERVAL '12 months'
+		WHERE deleted_at IS NULL
+		  AND created_at >= NOW() - INTERVAL '12 months'
 		GROUP BY period
 	`, dateFormat)
 
@@ -184,7 +186,8 @@ func (r *analyticsRepository) populateStatusOverTime(ctx context.Context, granul
 	closedQuery := fmt.Sprintf(`
 		SELECT TO_CHAR(updated_at, '%s') as period, COUNT(*) as count
 		FROM risks
-		WHERE status IN ('resolved', 'accepted')
+		WHERE deleted_at IS NULL
+		  AND status IN ('resolved', 'accepted')
 		  AND updated_at >= NOW() - INTERVAL '12 months'
 		GROUP BY period
 	`, dateFormat)
@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrAttachmentNotFound       = errors.New("attachment not found")
+	ErrAttachmentParentNotFound = errors.New("attachment parent not found")
+	ErrAttachmentInUse          = errors.New("attachment is the document of a policy version")
+)
+
+type AttachmentRepository interface {
+	// ListByEntity returns the attachments of one record, newest first
+	ListByEntity(ctx context.Context, entityType models.AttachmentEntity, entityID string) ([]*models.Attachment, error)
+	GetByID(ctx context.Context, id string) (*models.Attachment, error)
+	// Create stores the attachment's metadata and sets its ID and CreatedAt
+	Create(ctx context.Context, attachment *models.Attachment) error
+	// Delete removes the attachment's metadata. ErrAttachmentInUse means a
+	// policy version's document still refers to it.
+	Delete(ctx context.Context, id string) error
+	// DeleteOrphaned removes the attachments of the given types whose parent
+	// record no longer exists and returns their storage keys. Run it in the
+	// transaction that deletes the parents.
+	DeleteOrphaned(ctx context.Context, entityTypes ...models.AttachmentEntity) ([]string, error)
+	// ParentRiskID checks that the record an attachment belongs to exists and
+	// isn't in the trash. For mitigations it returns the risk they belong
+	// to; otherwise it returns an empty string.
+	ParentRiskID(ctx context.Context, entityType models.AttachmentEntity, entityID string) (string, error)
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) AttachmentRepository
+}
+
+type attachmentRepository struct {
+	db dbtx
+}
+
+func NewAttachmentRepository(db *sql.DB) AttachmentRepository {
+	return &attachmentRepository{db: db}
+}
+
+func (r *attachmentRepository) WithTx(tx *sql.Tx) AttachmentRepository {
+	return &attachmentRepository{db: tx}
+}
+
+const attachmentSelect = `
+	SELECT a.id, a.entity_type, a.entity_id, a.filename, a.content_type, a.size_bytes, a.sha256, a.storage_key,
+		a.uploaded_by, COALESCE(u.name, ''), a.created_at
+	FROM attachments a
+	LEFT JOIN users u ON u.id = a.uploaded_by
+`
+
+func scanAttachment(row interface{ Scan(...any) error }) (*models.Attachment, error) {
+	a := &models.Attachment{}
+	err := row.Scan(&a.ID, &a.EntityType, &a.EntityID, &a.Filename, &a.ContentType, &a.SizeBytes, &a.SHA256, &a.StorageKey,
+		&a.UploadedBy, &a.UploadedByName, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r *attachmentRepository) ListByEntity(ctx context.Context, entityType models.AttachmentEntity, entityID string) ([]*models.Attachment, error) {
+	rows, err := r.db.QueryContext(ctx, attachmentSelect+`
+		WHERE a.entity_type = $1 AND a.entity_id = $2
+		ORDER BY a.created_at DESC
+	`, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func (r *attachmentRepository) GetByID(ctx context.Context, id string) (*models.Attachment, error) {
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, attachmentSelect+` WHERE a.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttachmentNotFound
+	}
+	return attachment, err
+}
+
+func (r *attachmentRepository) Create(ctx context.Context, attachment *models.Attachment) error {
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO attachments (entity_type, entity_id, filename, content_type, size_bytes, sha256, storage_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, attachment.EntityType, attachment.EntityID, attachment.Filename, attachment.ContentType, attachment.SizeBytes,
+		attachment.SHA256, attachment.StorageKey, attachment.UploadedBy).
+		Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+func (r *attachmentRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = $1`, id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrAttachmentInUse
+	}
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
+
+// attachmentParentTables hold the parent records of each attachment type
+var attachmentParentTables = map[models.AttachmentEntity]string{
+	models.AttachmentRisk:             "risks",
+	models.AttachmentMitigation:       "mitigations",
+	models.AttachmentIncident:         "incidents",
+	models.AttachmentFrameworkControl: "framework_controls",
+	models.AttachmentPolicy:           "policies",
+}
+
+// DeleteOrphaned leaves the attachments of trashed risks and incidents alone,
+// since those can still be restored
+func (r *attachmentRepository) DeleteOrphaned(ctx context.Context, entityTypes ...models.AttachmentEntity) ([]string, error) {
+	keys := []string{}
+	for _, entityType := range entityTypes {
+		table, ok := attachmentParentTables[entityType]
+		if !ok {
+			return nil, fmt.Errorf("unknown attachment type %q", entityType)
+		}
+		rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+			DELETE FROM attachments a
+			WHERE a.entity_type = $1 AND NOT EXISTS (SELECT 1 FROM %s p WHERE p.id = a.entity_id)
+			RETURNING a.storage_key
+		`, table), entityType)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// attachmentParentQueries select a live parent record, and for mitigations
+// the risk they belong to
+var attachmentParentQueries = map[models.AttachmentEntity]string{
+	models.AttachmentRisk:             `SELECT '' FROM risks WHERE id = $1 AND deleted_at IS NULL`,
+	models.AttachmentMitigation:       `SELECT m.risk_id::text FROM mitigations m JOIN risks r ON r.id = m.risk_id WHERE m.id = $1 AND r.deleted_at IS NULL`,
+	models.AttachmentIncident:         `SELECT '' FROM incidents WHERE id = $1 AND deleted_at IS NULL`,
+	models.AttachmentFrameworkControl: `SELECT '' FROM framework_controls WHERE id = $1`,
+	models.AttachmentPolicy:           `SELECT '' FROM policies WHERE id = $1`,
+}
+
+func (r *attachmentRepository) ParentRiskID(ctx context.Context, entityType models.AttachmentEntity, entityID string) (string, error) {
+	query, ok := attachmentParentQueries[entityType]
+	if !ok {
+		return "", ErrAttachmentParentNotFound
+	}
+	var riskID string
+	err := r.db.QueryRowContext(ctx, query, entityID).Scan(&riskID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrAttachmentParentNotFound
+	}
+	return riskID, err
+}
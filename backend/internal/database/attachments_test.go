@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachmentRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewAttachmentRepository(s.db)
+	riskRepo := NewRiskRepository(s.db)
+	mitigationRepo := NewMitigationRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "attachments-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Evidence Uploader",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+	risk := &models.Risk{Title: "Stale accounts", OwnerID: user.ID, Status: models.StatusOpen, Severity: models.SeverityMedium,
+		CreatedBy: user.ID, UpdatedBy: user.ID}
+	require.NoError(t, riskRepo.Create(ctx, risk))
+	mitigation, err := mitigationRepo.Create(ctx, &models.CreateMitigationInput{RiskID: risk.ID, Description: "Quarterly review", Owner: "IT"}, user.ID)
+	require.NoError(t, err)
+
+	riskID, err := repo.ParentRiskID(ctx, models.AttachmentMitigation, mitigation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, risk.ID, riskID)
+	riskID, err = repo.ParentRiskID(ctx, models.AttachmentRisk, risk.ID)
+	require.NoError(t, err)
+	assert.Empty(t, riskID)
+	_, err = repo.ParentRiskID(ctx, models.AttachmentIncident, uuid.New().String())
+	assert.ErrorIs(t, err, ErrAttachmentParentNotFound)
+
+	attachment := &models.Attachment{
+		EntityType:  models.AttachmentMitigation,
+		EntityID:    mitigation.ID,
+		Filename:    "review.csv",
+		ContentType: "text/csv",
+		SizeBytes:   42,
+		SHA256:      "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		StorageKey:  "attachments/mitigation/" + uuid.New().String(),
+		UploadedBy:  &user.ID,
+	}
+	require.NoError(t, repo.Create(ctx, attachment))
+	assert.NotEmpty(t, attachment.ID)
+
+	list, err := repo.ListByEntity(ctx, models.AttachmentMitigation, mitigation.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "Evidence Uploader", list[0].UploadedByName)
+	assert.Equal(t, attachment.StorageKey, list[0].StorageKey)
+
+	require.NoError(t, riskRepo.Delete(ctx, risk.ID, risk.Version))
+	_, err = repo.ParentRiskID(ctx, models.AttachmentMitigation, mitigation.ID)
+	assert.ErrorIs(t, err, ErrAttachmentParentNotFound, "mitigations of a trashed risk are hidden")
+
+	require.NoError(t, repo.Delete(ctx, attachment.ID))
+	_, err = repo.GetByID(ctx, attachment.ID)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound)
+	assert.ErrorIs(t, repo.Delete(ctx, attachment.ID), ErrAttachmentNotFound)
+
+	// Purging the risk leaves its and its mitigations' attachments orphaned
+	evidence := func(entityType models.AttachmentEntity, entityID string) *models.Attachment {
+		a := &models.Attachment{EntityType: entityType, EntityID: entityID, Filename: "evidence.pdf", ContentType: "application/pdf",
+			SHA256: attachment.SHA256, StorageKey: "attachments/" + string(entityType) + "/" + uuid.New().String()}
+		require.NoError(t, repo.Create(ctx, a))
+		return a
+	}
+	onRisk, onMitigation := evidence(models.AttachmentRisk, risk.ID), evidence(models.AttachmentMitigation, mitigation.ID)
+	keys, err := repo.DeleteOrphaned(ctx, models.AttachmentRisk, models.AttachmentMitigation)
+	require.NoError(t, err)
+	assert.NotContains(t, keys, onRisk.StorageKey, "a trashed risk can still be restored")
+
+	err = NewTransactor(s.db).InTx(ctx, func(tx *sql.Tx) error {
+		if err := riskRepo.WithTx(tx).Purge(ctx, risk.ID, time.Now()); err != nil {
+			return err
+		}
+		keys, err = repo.WithTx(tx).DeleteOrphaned(ctx, models.AttachmentRisk, models.AttachmentMitigation)
+		return err
+	})
+	require.NoError(t, err)
+	assert.Subset(t, keys, []string{onRisk.StorageKey, onMitigation.StorageKey})
+	_, err = repo.GetByID(ctx, onRisk.ID)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound)
+	_, err = repo.GetByID(ctx, onMitigation.ID)
+	assert.ErrorIs(t, err, ErrAttachmentNotFound)
+}
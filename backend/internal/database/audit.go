@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"time"
 
 	"backend/internal/models"
 
@@ -13,16 +14,23 @@ import (
 type AuditLogRepository interface {
 	Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error
 	ListByEntity(ctx context.Context, entityType, entityID string, limit int) ([]*models.AuditLog, error)
+	ListHistory(ctx context.Context, entityType, entityID string, until time.Time) ([]*models.AuditLog, error)
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) AuditLogRepository
 }
 
 type auditLogRepo struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewAuditLogRepository(db *sql.DB) AuditLogRepository {
 	return &auditLogRepo{db: db}
 }
 
+func (r *auditLogRepo) WithTx(tx *sql.Tx) AuditLogRepository {
+	return &auditLogRepo{db: tx}
+}
+
 func (r *auditLogRepo) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
 	var changesJSON []byte
 	var err error
@@ -60,14 +68,41 @@ func (r *auditLogRepo) ListByEntity(ctx context.Context, entityType, entityID st
 	}
 	defer rows.Close()
 
+	return scanAuditLogs(rows)
+}
+
+// ListHistory returns every audit entry for an entity up to and including until,
+// oldest first, so the entries can be replayed to rebuild the entity's state.
+func (r *auditLogRepo) ListHistory(ctx context.Context, entityType, entityID string, until time.Time) ([]*models.AuditLog, error) {
+	query := `
+		SELECT al.id, al.entity_type, al.entity_id, al.action, al.changes, al.user_id, u.name, al.created_at
+		FROM audit_logs al
+		LEFT JOIN users u ON u.id = al.user_id
+		WHERE al.entity_type = $1 AND al.entity_id = $2 AND al.created_at <= $3
+		ORDER BY al.created_at ASC, al.id ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, entityType, entityID, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAuditLogs(rows)
+}
+
+func scanAuditLogs(rows *sql.Rows) ([]*models.AuditLog, error) {
 	var logs []*models.AuditLog
 	for rows.Next() {
 		var log models.AuditLog
 		var changesJSON []byte
-		var userName sql.NullString
-		if err := rows.Scan(&log.ID, &log.EntityType, &log.EntityID, &log.Action, &changesJSON, &log.UserID, &userName, &log.CreatedAt); err != nil {
+		var userID, userName sql.NullString
+		if err := rows.Scan(&log.ID, &log.EntityType, &log.EntityID, &log.Action, &changesJSON, &userID, &userName, &log.CreatedAt); err != nil {
 			return nil, err
 		}
+		if userID.Valid {
+			log.UserID = userID.String
+		}
 		if userName.Valid {
 			log.UserName = userName.String
 		}
@@ -76,5 +111,5 @@ func (r *auditLogRepo) ListByEntity(ctx context.Context, entityType, entityID st
 		}
 		logs = append(logs, &log)
 	}
-	return logs, nil
+	return logs, rows.Err()
 }
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+type CalendarRepository interface {
+	// SetFeedToken creates the user's feed, or replaces its token so the
+	// old feed URLs stop working
+	SetFeedToken(ctx context.Context, userID, tokenHash string) (*models.CalendarFeed, error)
+	FindFeed(ctx context.Context, userID string) (*models.CalendarFeed, error)
+	// FindFeedByToken returns the feed whose token hashes to tokenHash
+	FindFeedByToken(ctx context.Context, tokenHash string) (*models.CalendarFeed, error)
+	DeleteFeed(ctx context.Context, userID string) error
+	// MarkFetched records when a calendar client last read the feed
+	MarkFetched(ctx context.Context, userID string, at time.Time) error
+	// Entries returns the review dates of open risks and the due dates of
+	// planned and in-progress mitigations in scope for the user
+	Entries(ctx context.Context, userID string, scope models.CalendarScope) ([]*models.CalendarEntry, error)
+}
+
+type calendarRepository struct {
+	db dbtx
+}
+
+func NewCalendarRepository(db *sql.DB) CalendarRepository {
+	return &calendarRepository{db: db}
+}
+
+const calendarFeedColumns = `user_id, created_at, last_fetched_at`
+
+func scanCalendarFeed(row *sql.Row) (*models.CalendarFeed, error) {
+	feed := &models.CalendarFeed{}
+	err := row.Scan(&feed.UserID, &feed.CreatedAt, &feed.LastFetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return feed, nil
+}
+
+func (r *calendarRepository) SetFeedToken(ctx context.Context, userID, tokenHash string) (*models.CalendarFeed, error) {
+	return scanCalendarFeed(r.db.QueryRowContext(ctx, `
+		INSERT INTO calendar_feeds (user_id, token_hash) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW(), last_fetched_at = NULL
+		RETURNING `+calendarFeedColumns, userID, tokenHash))
+}
+
+func (r *calendarRepository) FindFeed(ctx context.Context, userID string) (*models.CalendarFeed, error) {
+	return scanCalendarFeed(r.db.QueryRowContext(ctx, `SELECT `+calendarFeedColumns+` FROM calendar_feeds WHERE user_id = $1`, userID))
+}
+
+func (r *calendarRepository) FindFeedByToken(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	return scanCalendarFeed(r.db.QueryRowContext(ctx, `SELECT `+calendarFeedColumns+` FROM calendar_feeds WHERE token_hash = $1`, tokenHash))
+}
+
+func (r *calendarRepository) DeleteFeed(ctx context.Context, userID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM calendar_feeds WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCalendarFeedNotFound
+	}
+	return nil
+}
+
+func (r *calendarRepository) MarkFetched(ctx context.Context, userID string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE calendar_feeds SET last_fetched_at = $2 WHERE user_id = $1`, userID, at)
+	return err
+}
+
+func (r *calendarRepository) Entries(ctx context.Context, userID string, scope models.CalendarScope) ([]*models.CalendarEntry, error) {
+	// people is the user, plus everyone sharing a team with them for the
+	// team scope. A mitigation's owner is free text, so it counts as theirs
+	// when it is one of their email addresses, as for due date notifications.
+	query := `
+		WITH people AS (
+			SELECT id, LOWER(email) AS email FROM users WHERE id = $1
+			UNION
+			SELECT u.id, LOWER(u.email) FROM team_members mine
+			JOIN team_members tm ON tm.team_id = mine.team_id
+			JOIN users u ON u.id = tm.user_id
+			WHERE mine.user_id = $1 AND $2::text = 'team'
+		)
+		SELECT 'review', r.id, r.id, r.title, '', r.review_date, r.severity, r.status::text, u.name, r.version, r.updated_at
+		FROM risks r
+		JOIN users u ON u.id = r.owner_id
+		WHERE r.deleted_at IS NULL AND r.status IN ('open', 'mitigating') AND r.review_date IS NOT NULL
+		  AND r.owner_id IN (SELECT id FROM people)
+		UNION ALL
+		SELECT 'mitigation_due', m.id, r.id, r.title, m.description, (m.due_date AT TIME ZONE 'UTC')::date, r.severity,
+			m.status::text, COALESCE(m.owner, ''), m.version, m.updated_at
+		FROM mitigations m
+		JOIN risks r ON r.id = m.risk_id
+		WHERE r.deleted_at IS NULL AND m.status IN ('planned', 'in_progress') AND m.due_date IS NOT NULL
+		  AND (r.owner_id IN (SELECT id FROM people) OR LOWER(m.owner) IN (SELECT email FROM people))
+		ORDER BY 6, 1 DESC, 2
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, string(scope))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*models.CalendarEntry{}
+	for rows.Next() {
+		e := &models.CalendarEntry{}
+		if err := rows.Scan(&e.Kind, &e.EntityID, &e.RiskID, &e.RiskTitle, &e.Description, &e.Date, &e.Severity,
+			&e.Status, &e.Owner, &e.Version, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
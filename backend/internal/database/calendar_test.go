@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewCalendarRepository(s.db)
+	teamRepo := NewTeamRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	riskRepo := NewRiskRepository(s.db)
+	mitigationRepo := NewMitigationRepository(s.db)
+	ctx := context.Background()
+
+	newUser := func(name string) *models.User {
+		user := &models.User{
+			ID:           uuid.New().String(),
+			Email:        "test-calendar-" + uuid.New().String() + "@example.com",
+			PasswordHash: "hash",
+			Name:         name,
+			Role:         models.RoleMember,
+		}
+		require.NoError(t, userRepo.Create(ctx, user))
+		return user
+	}
+	me, teammate, outsider := newUser("Me"), newUser("Teammate"), newUser("Outsider")
+
+	team := &models.Team{Name: "Calendar team " + uuid.New().String()}
+	require.NoError(t, teamRepo.Create(ctx, team))
+	require.NoError(t, teamRepo.SetMembers(ctx, team.ID, []string{me.ID, teammate.ID}))
+
+	t.Run("teams", func(t *testing.T) {
+		found, err := teamRepo.FindByID(ctx, team.ID)
+		require.NoError(t, err)
+		require.Len(t, found.Members, 2)
+		assert.ErrorIs(t, teamRepo.Create(ctx, &models.Team{Name: team.Name}), ErrTeamExists)
+		_, err = teamRepo.FindByID(ctx, uuid.New().String())
+		assert.ErrorIs(t, err, ErrTeamNotFound)
+	})
+
+	t.Run("feed tokens", func(t *testing.T) {
+		_, err := repo.SetFeedToken(ctx, me.ID, "aaaa")
+		require.NoError(t, err)
+		_, err = repo.SetFeedToken(ctx, me.ID, "bbbb")
+		require.NoError(t, err)
+
+		_, err = repo.FindFeedByToken(ctx, "aaaa")
+		assert.ErrorIs(t, err, ErrCalendarFeedNotFound, "rotating replaces the old token")
+		feed, err := repo.FindFeedByToken(ctx, "bbbb")
+		require.NoError(t, err)
+		assert.Equal(t, me.ID, feed.UserID)
+
+		require.NoError(t, repo.MarkFetched(ctx, me.ID, time.Now()))
+		feed, err = repo.FindFeed(ctx, me.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, feed.LastFetchedAt)
+
+		require.NoError(t, repo.DeleteFeed(ctx, me.ID))
+		assert.ErrorIs(t, repo.DeleteFeed(ctx, me.ID), ErrCalendarFeedNotFound)
+	})
+
+	t.Run("entries by scope", func(t *testing.T) {
+		newRisk := func(owner *models.User, status models.RiskStatus) *models.Risk {
+			reviewDate := time.Now().AddDate(0, 1, 0)
+			risk := &models.Risk{Title: "Calendar risk", OwnerID: owner.ID, Status: status, Severity: models.SeverityHigh,
+				ReviewDate: &reviewDate, CreatedBy: owner.ID, UpdatedBy: owner.ID}
+			require.NoError(t, riskRepo.Create(ctx, risk))
+			return risk
+		}
+		mine := newRisk(me, models.StatusOpen)
+		teammates := newRisk(teammate, models.StatusMitigating)
+		resolved := newRisk(me, models.StatusResolved)
+		outsiders := newRisk(outsider, models.StatusOpen)
+
+		due := time.Now().AddDate(0, 0, 14).Format(time.RFC3339)
+		assigned, err := mitigationRepo.Create(ctx, &models.CreateMitigationInput{RiskID: outsiders.ID, Description: "Assigned to me",
+			Owner: me.Email, Status: models.MitigationStatusInProgress, DueDate: &due}, outsider.ID)
+		require.NoError(t, err)
+		done, err := mitigationRepo.Create(ctx, &models.CreateMitigationInput{RiskID: mine.ID, Description: "Already done",
+			Owner: "someone", Status: models.MitigationStatusCompleted, DueDate: &due}, me.ID)
+		require.NoError(t, err)
+
+		ids := func(scope models.CalendarScope) []string {
+			entries, err := repo.Entries(ctx, me.ID, scope)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, e := range entries {
+				ids = append(ids, e.EntityID)
+			}
+			return ids
+		}
+
+		mineIDs := ids(models.CalendarScopeMine)
+		assert.Contains(t, mineIDs, mine.ID)
+		assert.Contains(t, mineIDs, assigned.ID, "mitigations owned by my email are mine")
+		assert.NotContains(t, mineIDs, teammates.ID)
+		assert.NotContains(t, mineIDs, resolved.ID, "closed risks have no review to do")
+		assert.NotContains(t, mineIDs, done.ID, "finished mitigations aren't due")
+		assert.NotContains(t, mineIDs, outsiders.ID)
+
+		teamIDs := ids(models.CalendarScopeTeam)
+		assert.Contains(t, teamIDs, mine.ID)
+		assert.Contains(t, teamIDs, teammates.ID)
+		assert.NotContains(t, teamIDs, outsiders.ID)
+	})
+}
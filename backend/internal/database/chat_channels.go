@@ -0,0 +1,275 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var (
+	ErrChatChannelNotFound = errors.New("chat channel not found")
+	ErrChatMessageNotFound = errors.New("chat message not found")
+)
+
+type ChatChannelRepository interface {
+	Create(ctx context.Context, channel *models.ChatChannel) error
+	FindByID(ctx context.Context, id string) (*models.ChatChannel, error)
+	List(ctx context.Context) ([]*models.ChatChannel, error)
+	// ListActive returns the active channels subscribed to trigger
+	ListActive(ctx context.Context, trigger models.ChatTrigger) ([]*models.ChatChannel, error)
+	Update(ctx context.Context, channel *models.ChatChannel) error
+	Delete(ctx context.Context, id string) error
+	Enqueue(ctx context.Context, message *models.ChatMessage) error
+	// ClaimDue returns up to limit pending messages due at now and pushes
+	// their next attempt out to leaseUntil so other workers skip them
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.ChatDispatch, error)
+	RecordAttempt(ctx context.Context, messageID string, attempt *models.ChatAttempt) error
+	ListMessages(ctx context.Context, channelID string, limit int) ([]*models.ChatMessage, error)
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) ChatChannelRepository
+}
+
+type chatChannelRepository struct {
+	db dbtx
+}
+
+func NewChatChannelRepository(db *sql.DB) ChatChannelRepository {
+	return &chatChannelRepository{db: db}
+}
+
+func (r *chatChannelRepository) WithTx(tx *sql.Tx) ChatChannelRepository {
+	return &chatChannelRepository{db: tx}
+}
+
+const chatChannelColumns = `id, name, type, webhook_url, triggers, priorities, category_ids, templates, active, created_by, created_at, updated_at`
+
+func scanChatChannel(row interface{ Scan(...any) error }) (*models.ChatChannel, error) {
+	ch := &models.ChatChannel{}
+	var triggers, priorities, templates []byte
+	err := row.Scan(&ch.ID, &ch.Name, &ch.Type, &ch.WebhookURL, &triggers, &priorities, tagsColumn{&ch.CategoryIDs},
+		&templates, &ch.Active, &ch.CreatedBy, &ch.CreatedAt, &ch.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(triggers, &ch.Triggers); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(priorities, &ch.Priorities); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(templates, &ch.Templates); err != nil {
+		return nil, err
+	}
+	return ch, nil
+}
+
+// chatChannelJSON encodes the channel's JSONB columns, writing empty
+// lists and maps rather than null
+func chatChannelJSON(ch *models.ChatChannel) (triggers, priorities, templates string, err error) {
+	encode := func(v any, empty string) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil || string(b) == "null" {
+			return empty, err
+		}
+		return string(b), nil
+	}
+	if triggers, err = encode(ch.Triggers, "[]"); err != nil {
+		return
+	}
+	if priorities, err = encode(ch.Priorities, "[]"); err != nil {
+		return
+	}
+	templates, err = encode(ch.Templates, "{}")
+	return
+}
+
+func (r *chatChannelRepository) Create(ctx context.Context, ch *models.ChatChannel) error {
+	triggers, priorities, templates, err := chatChannelJSON(ch)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO chat_channels (name, type, webhook_url, triggers, priorities, category_ids, templates, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRowContext(ctx, query, ch.Name, ch.Type, ch.WebhookURL, triggers, priorities, tagsValue(ch.CategoryIDs),
+		templates, ch.Active, ch.CreatedBy).Scan(&ch.ID, &ch.CreatedAt, &ch.UpdatedAt)
+}
+
+func (r *chatChannelRepository) FindByID(ctx context.Context, id string) (*models.ChatChannel, error) {
+	ch, err := scanChatChannel(r.db.QueryRowContext(ctx, `SELECT `+chatChannelColumns+` FROM chat_channels WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrChatChannelNotFound
+	}
+	return ch, err
+}
+
+func (r *chatChannelRepository) List(ctx context.Context) ([]*models.ChatChannel, error) {
+	return r.list(ctx, `SELECT `+chatChannelColumns+` FROM chat_channels ORDER BY name, created_at`)
+}
+
+func (r *chatChannelRepository) ListActive(ctx context.Context, trigger models.ChatTrigger) ([]*models.ChatChannel, error) {
+	return r.list(ctx, `SELECT `+chatChannelColumns+` FROM chat_channels WHERE active AND triggers ? $1 ORDER BY name`, trigger)
+}
+
+func (r *chatChannelRepository) list(ctx context.Context, query string, args ...any) ([]*models.ChatChannel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []*models.ChatChannel{}
+	for rows.Next() {
+		ch, err := scanChatChannel(rows)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, ch)
+	}
+	return channels, rows.Err()
+}
+
+func (r *chatChannelRepository) Update(ctx context.Context, ch *models.ChatChannel) error {
+	triggers, priorities, templates, err := chatChannelJSON(ch)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE chat_channels
+		SET name = $2, type = $3, webhook_url = $4, triggers = $5, priorities = $6, category_ids = $7,
+		    templates = $8, active = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err = r.db.QueryRowContext(ctx, query, ch.ID, ch.Name, ch.Type, ch.WebhookURL, triggers, priorities,
+		tagsValue(ch.CategoryIDs), templates, ch.Active).Scan(&ch.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrChatChannelNotFound
+	}
+	return err
+}
+
+func (r *chatChannelRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM chat_channels WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrChatChannelNotFound
+	}
+	return nil
+}
+
+func (r *chatChannelRepository) Enqueue(ctx context.Context, m *models.ChatMessage) error {
+	query := `
+		INSERT INTO chat_messages (channel_id, trigger, incident_id, payload, next_attempt_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, status, created_at, next_attempt_at
+	`
+	return r.db.QueryRowContext(ctx, query, m.ChannelID, m.Trigger, m.IncidentID, string(m.Payload)).
+		Scan(&m.ID, &m.Status, &m.CreatedAt, &m.NextAttemptAt)
+}
+
+const chatMessageColumns = `m.id, m.channel_id, m.trigger, m.incident_id, m.payload, m.status, m.attempts, m.next_attempt_at,
+	m.last_attempt_at, m.response_status, m.error, m.created_at, m.sent_at`
+
+func scanChatMessage(row interface{ Scan(...any) error }, extra ...any) (*models.ChatMessage, error) {
+	m := &models.ChatMessage{}
+	dest := append([]any{&m.ID, &m.ChannelID, &m.Trigger, &m.IncidentID, &m.Payload, &m.Status, &m.Attempts, &m.NextAttemptAt,
+		&m.LastAttemptAt, &m.ResponseStatus, &m.Error, &m.CreatedAt, &m.SentAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *chatChannelRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.ChatDispatch, error) {
+	query := `
+		UPDATE chat_messages m
+		SET next_attempt_at = $2
+		FROM chat_channels c
+		WHERE c.id = m.channel_id AND m.id IN (
+			SELECT q.id
+			FROM chat_messages q
+			JOIN chat_channels qc ON qc.id = q.channel_id
+			WHERE q.status = 'pending' AND q.next_attempt_at <= $1 AND qc.active
+			ORDER BY q.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF q SKIP LOCKED
+		)
+		RETURNING ` + chatMessageColumns + `, c.webhook_url
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dispatches := []*models.ChatDispatch{}
+	for rows.Next() {
+		dispatch := &models.ChatDispatch{}
+		dispatch.Message, err = scanChatMessage(rows, &dispatch.WebhookURL)
+		if err != nil {
+			return nil, err
+		}
+		dispatches = append(dispatches, dispatch)
+	}
+	return dispatches, rows.Err()
+}
+
+func (r *chatChannelRepository) RecordAttempt(ctx context.Context, messageID string, a *models.ChatAttempt) error {
+	var sentAt *time.Time
+	if a.Status == models.ChatMessageSent {
+		sentAt = &a.AttemptedAt
+	}
+	query := `
+		UPDATE chat_messages
+		SET status = $2, attempts = attempts + 1, last_attempt_at = $3, next_attempt_at = $4,
+		    response_status = $5, error = $6, sent_at = $7
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, messageID, a.Status, a.AttemptedAt, a.NextAttemptAt, a.ResponseStatus, a.Error, sentAt)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrChatMessageNotFound
+	}
+	return nil
+}
+
+func (r *chatChannelRepository) ListMessages(ctx context.Context, channelID string, limit int) ([]*models.ChatMessage, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := `SELECT ` + chatMessageColumns + ` FROM chat_messages m WHERE m.channel_id = $1 ORDER BY m.created_at DESC LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, channelID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []*models.ChatMessage{}
+	for rows.Next() {
+		m, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
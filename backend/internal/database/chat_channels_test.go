@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatChannelRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewChatChannelRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "test-chat-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Chat Admin",
+		Role:         models.RoleAdmin,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+
+	channel := &models.ChatChannel{
+		Name:       "Incidents",
+		Type:       models.ChatChannelSlack,
+		WebhookURL: "https://hooks.slack.com/services/T0/B0/x",
+		Triggers:   []models.ChatTrigger{models.ChatTriggerCreated},
+		Priorities: []models.IncidentPriority{models.PriorityP1},
+		Templates:  map[models.ChatTrigger]models.ChatTemplate{models.ChatTriggerCreated: {Title: "{{.Incident.Title}}"}},
+		Active:     true,
+		CreatedBy:  user.ID,
+	}
+	require.NoError(t, repo.Create(ctx, channel))
+	defer repo.Delete(ctx, channel.ID)
+
+	t.Run("FindByID round-trips the JSON columns", func(t *testing.T) {
+		found, err := repo.FindByID(ctx, channel.ID)
+		require.NoError(t, err)
+		assert.Equal(t, channel.Triggers, found.Triggers)
+		assert.Equal(t, channel.Priorities, found.Priorities)
+		assert.Empty(t, found.CategoryIDs)
+		assert.Equal(t, "{{.Incident.Title}}", found.Templates[models.ChatTriggerCreated].Title)
+
+		_, err = repo.FindByID(ctx, uuid.New().String())
+		assert.ErrorIs(t, err, ErrChatChannelNotFound)
+	})
+
+	t.Run("ListActive filters by trigger", func(t *testing.T) {
+		ids := func(trigger models.ChatTrigger) []string {
+			list, err := repo.ListActive(ctx, trigger)
+			require.NoError(t, err)
+			var ids []string
+			for _, ch := range list {
+				ids = append(ids, ch.ID)
+			}
+			return ids
+		}
+		assert.Contains(t, ids(models.ChatTriggerCreated), channel.ID)
+		assert.NotContains(t, ids(models.ChatTriggerResolved), channel.ID)
+	})
+
+	t.Run("Enqueue in a rolled back transaction queues nothing", func(t *testing.T) {
+		tx, err := s.db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		msg := &models.ChatMessage{ChannelID: channel.ID, Trigger: models.ChatTriggerCreated, IncidentID: uuid.New().String(), Payload: json.RawMessage(`{"text":"x"}`)}
+		require.NoError(t, repo.WithTx(tx).Enqueue(ctx, msg))
+		require.NoError(t, tx.Rollback())
+
+		messages, err := repo.ListMessages(ctx, channel.ID, 10)
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("claim and record attempts", func(t *testing.T) {
+		msg := &models.ChatMessage{ChannelID: channel.ID, Trigger: models.ChatTriggerCreated, IncidentID: uuid.New().String(), Payload: json.RawMessage(`{"text":"x"}`)}
+		require.NoError(t, repo.Enqueue(ctx, msg))
+		assert.Equal(t, models.ChatMessagePending, msg.Status)
+
+		now := time.Now().Add(time.Minute)
+		claim := func() *models.ChatDispatch {
+			dispatches, err := repo.ClaimDue(ctx, now, now.Add(time.Minute), 1000)
+			require.NoError(t, err)
+			for _, d := range dispatches {
+				if d.Message.ID == msg.ID {
+					return d
+				}
+			}
+			return nil
+		}
+		dispatch := claim()
+		require.NotNil(t, dispatch)
+		assert.Equal(t, channel.WebhookURL, dispatch.WebhookURL)
+		assert.Nil(t, claim(), "a leased message must not be claimed again")
+
+		status := 200
+		require.NoError(t, repo.RecordAttempt(ctx, msg.ID, &models.ChatAttempt{Status: models.ChatMessageSent, AttemptedAt: time.Now(), ResponseStatus: &status}))
+		messages, err := repo.ListMessages(ctx, channel.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, models.ChatMessageSent, messages[0].Status)
+		assert.Equal(t, 1, messages[0].Attempts)
+		assert.NotNil(t, messages[0].SentAt)
+
+		assert.ErrorIs(t, repo.RecordAttempt(ctx, uuid.New().String(), &models.ChatAttempt{Status: models.ChatMessageSent, AttemptedAt: time.Now()}), ErrChatMessageNotFound)
+	})
+}
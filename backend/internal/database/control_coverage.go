@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"backend/internal/models"
+)
+
+func (r *frameworkControlRepository) Coverage(ctx context.Context, frameworkID string) (*models.FrameworkCoverage, error) {
+	name, err := frameworkName(ctx, r.db, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	coverage := &models.FrameworkCoverage{FrameworkID: frameworkID, FrameworkName: name, GeneratedAt: time.Now().UTC()}
+
+	controls, err := r.frameworkControls(ctx, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := r.riskLinkStatuses(ctx, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	buildCoverage(coverage, controls, statuses)
+	return coverage, nil
+}
+
+// riskLinkStatuses returns the statuses of the live risks linked to each of
+// the framework's controls
+func (r *frameworkControlRepository) riskLinkStatuses(ctx context.Context, frameworkID string) (map[string][]models.RiskStatus, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rfc.framework_control_id, r.status
+		FROM risk_framework_controls rfc
+		JOIN framework_controls fc ON fc.id = rfc.framework_control_id
+		JOIN risks r ON r.id = rfc.risk_id AND r.deleted_at IS NULL
+		WHERE fc.framework_id = $1
+	`, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := map[string][]models.RiskStatus{}
+	for rows.Next() {
+		var controlID string
+		var status models.RiskStatus
+		if err := rows.Scan(&controlID, &status); err != nil {
+			return nil, err
+		}
+		statuses[controlID] = append(statuses[controlID], status)
+	}
+	return statuses, rows.Err()
+}
+
+// buildCoverage fills in the counts and lists of coverage from the
+// framework's controls and the statuses of the risks linked to each. Controls
+// are grouped into families and listed in the order of walkControls. A group
+// of controls below a family heading is only counted when risks are linked to
+// it directly; otherwise its controls stand for it.
+func buildCoverage(coverage *models.FrameworkCoverage, controls []*models.FrameworkControl, statuses map[string][]models.RiskStatus) {
+	coverage.Families = []*models.CoverageFamily{}
+	coverage.Unlinked = []*models.CoverageControl{}
+	coverage.InactiveOnly = []*models.CoverageControl{}
+	coverage.Controls = []*models.CoverageControl{}
+
+	families := map[string]*models.CoverageFamily{}
+	family := func(ref, title string) *models.CoverageFamily {
+		f, ok := families[ref]
+		if !ok {
+			f = &models.CoverageFamily{Family: ref}
+			families[ref] = f
+			coverage.Families = append(coverage.Families, f)
+		}
+		if f.Title == "" {
+			f.Title = title
+		}
+		return f
+	}
+
+	add := func(f *models.CoverageFamily, control *models.FrameworkControl) {
+		entry := &models.CoverageControl{
+			ID:              control.ID,
+			ControlRef:      control.ControlRef,
+			Title:           control.Title,
+			Family:          f.Family,
+			Status:          models.ControlUnlinked,
+			LinkedRiskCount: len(statuses[control.ID]),
+		}
+		for _, status := range statuses[control.ID] {
+			if status == models.StatusOpen || status == models.StatusMitigating {
+				entry.ActiveRiskCount++
+			}
+		}
+
+		f.Total++
+		switch {
+		case entry.ActiveRiskCount > 0:
+			entry.Status = models.ControlCovered
+			f.Linked++
+		case entry.LinkedRiskCount > 0:
+			entry.Status = models.ControlInactiveOnly
+			f.Linked++
+			f.InactiveOnly++
+			coverage.InactiveOnly = append(coverage.InactiveOnly, entry)
+		default:
+			f.Unlinked++
+			coverage.Unlinked = append(coverage.Unlinked, entry)
+		}
+		coverage.Controls = append(coverage.Controls, entry)
+	}
+
+	walkControls(controls, func(control *models.FrameworkControl, familyRef, familyTitle string, group bool) {
+		if group && len(statuses[control.ID]) == 0 {
+			return
+		}
+		add(family(familyRef, familyTitle), control)
+	})
+
+	for _, f := range coverage.Families {
+		f.CoveragePercent = coveragePercent(f.Linked, f.Total)
+		coverage.TotalControls += f.Total
+		coverage.LinkedControls += f.Linked
+		coverage.UnlinkedControls += f.Unlinked
+		coverage.InactiveOnlyControls += f.InactiveOnly
+	}
+	coverage.CoveragePercent = coveragePercent(coverage.LinkedControls, coverage.TotalControls)
+}
+
+// coveragePercent is part of total as a percentage to one decimal place
+func coveragePercent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
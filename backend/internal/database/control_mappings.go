@@ -0,0 +1,241 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrControlMappingNotFound = errors.New("control mapping not found")
+	ErrControlMappingExists   = errors.New("controls are already mapped")
+)
+
+type ControlMappingRepository interface {
+	List(ctx context.Context, filter models.ControlMappingFilter) ([]*models.ControlMapping, error)
+	GetByID(ctx context.Context, id string) (*models.ControlMapping, error)
+	// Create stores a mapping between mapping.Source.ID and mapping.Target.ID
+	// and sets its ID
+	Create(ctx context.Context, mapping *models.ControlMapping) error
+	// Update writes the mapping's controls, strength and notes
+	Update(ctx context.Context, mapping *models.ControlMapping) error
+	Delete(ctx context.Context, id string) error
+	// Mapped lists the controls mapped to controlID, optionally only those
+	// of one framework
+	Mapped(ctx context.Context, controlID, frameworkID string) ([]*models.MappedControl, error)
+	// MappedForRisk lists the controls mapped to the risk's linked controls
+	// that the risk isn't linked to itself, with the linked control as Via
+	MappedForRisk(ctx context.Context, riskID string) ([]*models.MappedControl, error)
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) ControlMappingRepository
+}
+
+type controlMappingRepository struct {
+	db dbtx
+}
+
+func NewControlMappingRepository(db *sql.DB) ControlMappingRepository {
+	return &controlMappingRepository{db: db}
+}
+
+func (r *controlMappingRepository) WithTx(tx *sql.Tx) ControlMappingRepository {
+	return &controlMappingRepository{db: tx}
+}
+
+const controlMappingSelect = `
+	SELECT m.id, m.strength, m.notes, m.created_by, m.created_at, m.updated_at,
+		s.id, s.framework_id, sf.name, s.control_ref, s.title,
+		t.id, t.framework_id, tf.name, t.control_ref, t.title
+	FROM control_mappings m
+	JOIN framework_controls s ON s.id = m.source_control_id
+	JOIN frameworks sf ON sf.id = s.framework_id
+	JOIN framework_controls t ON t.id = m.target_control_id
+	JOIN frameworks tf ON tf.id = t.framework_id
+`
+
+func (r *controlMappingRepository) List(ctx context.Context, filter models.ControlMappingFilter) ([]*models.ControlMapping, error) {
+	query := controlMappingSelect + `
+		WHERE ($1 = '' OR m.source_control_id::text = $1 OR m.target_control_id::text = $1)
+		  AND ($2 = '' OR s.framework_id::text = $2 OR t.framework_id::text = $2)
+		ORDER BY sf.name, s.control_ref, tf.name, t.control_ref
+	`
+	rows, err := r.db.QueryContext(ctx, query, filter.ControlID, filter.FrameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mappings := []*models.ControlMapping{}
+	for rows.Next() {
+		mapping, err := scanControlMapping(rows)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, rows.Err()
+}
+
+func (r *controlMappingRepository) GetByID(ctx context.Context, id string) (*models.ControlMapping, error) {
+	mapping, err := scanControlMapping(r.db.QueryRowContext(ctx, controlMappingSelect+` WHERE m.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrControlMappingNotFound
+	}
+	return mapping, err
+}
+
+func scanControlMapping(row interface{ Scan(...any) error }) (*models.ControlMapping, error) {
+	m := &models.ControlMapping{}
+	err := row.Scan(&m.ID, &m.Strength, &m.Notes, &m.CreatedBy, &m.CreatedAt, &m.UpdatedAt,
+		&m.Source.ID, &m.Source.FrameworkID, &m.Source.FrameworkName, &m.Source.ControlRef, &m.Source.Title,
+		&m.Target.ID, &m.Target.FrameworkID, &m.Target.FrameworkName, &m.Target.ControlRef, &m.Target.Title)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *controlMappingRepository) Create(ctx context.Context, mapping *models.ControlMapping) error {
+	query := `
+		INSERT INTO control_mappings (source_control_id, target_control_id, strength, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, mapping.Source.ID, mapping.Target.ID, mapping.Strength, mapping.Notes, mapping.CreatedBy).
+		Scan(&mapping.ID, &mapping.CreatedAt, &mapping.UpdatedAt)
+	return controlMappingError(err)
+}
+
+func (r *controlMappingRepository) Update(ctx context.Context, mapping *models.ControlMapping) error {
+	query := `
+		UPDATE control_mappings
+		SET source_control_id = $2, target_control_id = $3, strength = $4, notes = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, mapping.ID, mapping.Source.ID, mapping.Target.ID, mapping.Strength, mapping.Notes).
+		Scan(&mapping.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrControlMappingNotFound
+	}
+	return controlMappingError(err)
+}
+
+// controlMappingError maps constraint violations to the repository's errors
+func controlMappingError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return ErrControlMappingExists
+		case "23503":
+			return ErrFrameworkControlNotFound
+		}
+	}
+	return err
+}
+
+func (r *controlMappingRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM control_mappings WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrControlMappingNotFound
+	}
+	return nil
+}
+
+func (r *controlMappingRepository) Mapped(ctx context.Context, controlID, frameworkID string) ([]*models.MappedControl, error) {
+	query := `
+		SELECT m.id, m.strength, m.source_control_id = $1 AS forward,
+			o.id, o.framework_id, f.name, o.control_ref, o.title
+		FROM control_mappings m
+		JOIN framework_controls o ON o.id = CASE WHEN m.source_control_id = $1 THEN m.target_control_id ELSE m.source_control_id END
+		JOIN frameworks f ON f.id = o.framework_id
+		WHERE (m.source_control_id = $1 OR m.target_control_id = $1)
+		  AND ($2 = '' OR o.framework_id::text = $2)
+		ORDER BY f.name, o.control_ref
+	`
+	rows, err := r.db.QueryContext(ctx, query, controlID, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mapped := []*models.MappedControl{}
+	for rows.Next() {
+		m := &models.MappedControl{}
+		var forward bool
+		if err := rows.Scan(&m.MappingID, &m.Strength, &forward,
+			&m.Control.ID, &m.Control.FrameworkID, &m.Control.FrameworkName, &m.Control.ControlRef, &m.Control.Title); err != nil {
+			return nil, err
+		}
+		m.Relation, m.Coverage = mappingRelation(m.Strength, forward)
+		mapped = append(mapped, m)
+	}
+	return mapped, rows.Err()
+}
+
+func (r *controlMappingRepository) MappedForRisk(ctx context.Context, riskID string) ([]*models.MappedControl, error) {
+	query := `
+		SELECT m.id, m.strength, m.source_control_id = v.id AS forward,
+			o.id, o.framework_id, f.name, o.control_ref, o.title,
+			v.id, v.framework_id, vf.name, v.control_ref, v.title
+		FROM risk_framework_controls rfc
+		JOIN framework_controls v ON v.id = rfc.framework_control_id
+		JOIN frameworks vf ON vf.id = v.framework_id
+		JOIN control_mappings m ON m.source_control_id = v.id OR m.target_control_id = v.id
+		JOIN framework_controls o ON o.id = CASE WHEN m.source_control_id = v.id THEN m.target_control_id ELSE m.source_control_id END
+		JOIN frameworks f ON f.id = o.framework_id
+		WHERE rfc.risk_id = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM risk_framework_controls linked
+			WHERE linked.risk_id = rfc.risk_id AND linked.framework_control_id = o.id
+		  )
+		ORDER BY f.name, o.control_ref, vf.name, v.control_ref
+	`
+	rows, err := r.db.QueryContext(ctx, query, riskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mapped := []*models.MappedControl{}
+	for rows.Next() {
+		m := &models.MappedControl{Via: &models.ControlSummary{}}
+		var forward bool
+		if err := rows.Scan(&m.MappingID, &m.Strength, &forward,
+			&m.Control.ID, &m.Control.FrameworkID, &m.Control.FrameworkName, &m.Control.ControlRef, &m.Control.Title,
+			&m.Via.ID, &m.Via.FrameworkID, &m.Via.FrameworkName, &m.Via.ControlRef, &m.Via.Title); err != nil {
+			return nil, err
+		}
+		m.Relation, m.Coverage = mappingRelation(m.Strength, forward)
+		mapped = append(mapped, m)
+	}
+	return mapped, rows.Err()
+}
+
+// mappingRelation reads a mapping from the side it was reached from: forward
+// when that is the mapping's source. What covers a control fully covers
+// another that is equal to it or a subset of it.
+func mappingRelation(strength models.MappingStrength, forward bool) (models.MappingRelation, models.MappingCoverage) {
+	switch {
+	case strength == models.MappingEqual:
+		return models.RelationEqual, models.CoverageFull
+	case strength == models.MappingSubset && forward:
+		return models.RelationSubset, models.CoveragePartial
+	case strength == models.MappingSubset:
+		return models.RelationSuperset, models.CoverageFull
+	default:
+		return models.RelationRelated, models.CoveragePartial
+	}
+}
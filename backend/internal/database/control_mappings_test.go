@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingRelation(t *testing.T) {
+	tests := []struct {
+		strength models.MappingStrength
+		forward  bool
+		relation models.MappingRelation
+		coverage models.MappingCoverage
+	}{
+		{models.MappingEqual, true, models.RelationEqual, models.CoverageFull},
+		{models.MappingEqual, false, models.RelationEqual, models.CoverageFull},
+		{models.MappingSubset, true, models.RelationSubset, models.CoveragePartial},
+		{models.MappingSubset, false, models.RelationSuperset, models.CoverageFull},
+		{models.MappingRelated, false, models.RelationRelated, models.CoveragePartial},
+	}
+	for _, tt := range tests {
+		relation, coverage := mappingRelation(tt.strength, tt.forward)
+		assert.Equal(t, tt.relation, relation, "%s forward=%v", tt.strength, tt.forward)
+		assert.Equal(t, tt.coverage, coverage, "%s forward=%v", tt.strength, tt.forward)
+	}
+}
+
+func TestControlMappingRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewControlMappingRepository(s.db)
+	frameworkRepo := NewFrameworkRepository(s.db)
+	controlRepo := NewFrameworkControlRepository(s.db)
+	riskControlRepo := NewRiskFrameworkControlRepository(s.db)
+	riskRepo := NewRiskRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	newControl := func(framework *models.Framework, ref string) *models.FrameworkControl {
+		control, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{FrameworkID: framework.ID, ControlRef: ref, Title: ref})
+		require.NoError(t, err)
+		return control
+	}
+	iso, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "ISO crosswalk " + uuid.New().String()})
+	require.NoError(t, err)
+	soc2, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "SOC 2 crosswalk " + uuid.New().String()})
+	require.NoError(t, err)
+	a81, a82 := newControl(iso, "A.8.1"), newControl(iso, "A.8.2")
+	cc61, cc62 := newControl(soc2, "CC6.1"), newControl(soc2, "CC6.2")
+
+	equal := &models.ControlMapping{Source: models.ControlSummary{ID: a81.ID}, Target: models.ControlSummary{ID: cc61.ID}, Strength: models.MappingEqual}
+	require.NoError(t, repo.Create(ctx, equal))
+	subset := &models.ControlMapping{Source: models.ControlSummary{ID: cc62.ID}, Target: models.ControlSummary{ID: a81.ID}, Strength: models.MappingSubset}
+	require.NoError(t, repo.Create(ctx, subset))
+
+	reversed := &models.ControlMapping{Source: models.ControlSummary{ID: cc61.ID}, Target: models.ControlSummary{ID: a81.ID}, Strength: models.MappingRelated}
+	assert.ErrorIs(t, repo.Create(ctx, reversed), ErrControlMappingExists)
+	unknown := &models.ControlMapping{Source: models.ControlSummary{ID: uuid.New().String()}, Target: models.ControlSummary{ID: a81.ID}, Strength: models.MappingRelated}
+	assert.ErrorIs(t, repo.Create(ctx, unknown), ErrFrameworkControlNotFound)
+
+	fetched, err := repo.GetByID(ctx, equal.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "CC6.1", fetched.Target.ControlRef)
+	assert.Equal(t, soc2.Name, fetched.Target.FrameworkName)
+
+	list, err := repo.List(ctx, models.ControlMappingFilter{FrameworkID: soc2.ID})
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	mapped, err := repo.Mapped(ctx, a81.ID, soc2.ID)
+	require.NoError(t, err)
+	require.Len(t, mapped, 2)
+	assert.Equal(t, "CC6.1", mapped[0].Control.ControlRef)
+	assert.Equal(t, models.RelationEqual, mapped[0].Relation)
+	assert.Equal(t, models.RelationSuperset, mapped[1].Relation)
+	assert.Equal(t, models.CoverageFull, mapped[1].Coverage)
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "crosswalk-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Crosswalk Tester",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+	risk := &models.Risk{Title: "Crosswalk risk", OwnerID: user.ID, Status: models.StatusOpen, Severity: models.SeverityHigh,
+		CreatedBy: user.ID, UpdatedBy: user.ID}
+	require.NoError(t, riskRepo.Create(ctx, risk))
+	_, err = riskControlRepo.LinkControl(ctx, risk.ID, &models.LinkControlInput{FrameworkControlID: a81.ID}, user.ID)
+	require.NoError(t, err)
+	_, err = riskControlRepo.LinkControl(ctx, risk.ID, &models.LinkControlInput{FrameworkControlID: cc61.ID}, user.ID)
+	require.NoError(t, err)
+
+	mapped, err = repo.MappedForRisk(ctx, risk.ID)
+	require.NoError(t, err)
+	require.Len(t, mapped, 1, "controls the risk is linked to directly are left out")
+	assert.Equal(t, cc62.ID, mapped[0].Control.ID)
+	assert.Equal(t, a81.ID, mapped[0].Via.ID)
+
+	subset.Strength = models.MappingRelated
+	subset.Source.ID, subset.Target.ID = a82.ID, cc62.ID
+	require.NoError(t, repo.Update(ctx, subset))
+	fetched, err = repo.GetByID(ctx, subset.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "A.8.2", fetched.Source.ControlRef)
+
+	require.NoError(t, repo.Delete(ctx, subset.ID))
+	assert.ErrorIs(t, repo.Delete(ctx, subset.ID), ErrControlMappingNotFound)
+}
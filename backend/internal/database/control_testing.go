@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrControlTestNotFound       = errors.New("control test not found")
+	ErrControlTestTesterNotFound = errors.New("tester not found")
+)
+
+type ControlTestRepository interface {
+	// List returns tests ordered by next due date, each with its latest
+	// result
+	List(ctx context.Context, filter models.ControlTestFilter) ([]*models.ControlTest, error)
+	GetByID(ctx context.Context, id string) (*models.ControlTest, error)
+	// Create stores the test and sets its ID and timestamps
+	Create(ctx context.Context, test *models.ControlTest) error
+	// Update saves the editable fields of the test
+	Update(ctx context.Context, test *models.ControlTest) error
+	Delete(ctx context.Context, id string) error
+	// ListResults returns a test's results, most recent first
+	ListResults(ctx context.Context, testID string) ([]*models.ControlTestResult, error)
+	// RecordResult stores the result and sets its ID and CreatedAt. Unless a
+	// later result was already recorded, the test becomes due again on next.
+	RecordResult(ctx context.Context, result *models.ControlTestResult, next time.Time) error
+	// FlagRisksForReview brings the review date of every live risk linked to
+	// the control forward to today, leaving those already due alone, and
+	// returns the risks it changed
+	FlagRisksForReview(ctx context.Context, controlID, userID string) ([]models.FlaggedRisk, error)
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) ControlTestRepository
+}
+
+type controlTestRepository struct {
+	db dbtx
+}
+
+func NewControlTestRepository(db *sql.DB) ControlTestRepository {
+	return &controlTestRepository{db: db}
+}
+
+func (r *controlTestRepository) WithTx(tx *sql.Tx) ControlTestRepository {
+	return &controlTestRepository{db: tx}
+}
+
+const controlTestSelect = `
+	SELECT t.id, t.framework_control_id, fc.control_ref, fc.title, fc.framework_id,
+		t.name, t.procedure, t.frequency, t.tester_id, COALESCE(tu.name, ''),
+		t.next_due_date, t.active, t.active AND t.next_due_date < CURRENT_DATE,
+		t.created_by, t.created_at, t.updated_at,
+		lr.id, lr.result, lr.notes, lr.tested_at, lr.tested_by, lr.tested_by_name, lr.flagged_risks, lr.created_at
+	FROM control_tests t
+	JOIN framework_controls fc ON fc.id = t.framework_control_id
+	LEFT JOIN users tu ON tu.id = t.tester_id
+	LEFT JOIN LATERAL (
+		SELECT res.id, res.result, res.notes, res.tested_at, res.tested_by,
+			COALESCE(ru.name, '') AS tested_by_name, res.flagged_risks, res.created_at
+		FROM control_test_results res
+		LEFT JOIN users ru ON ru.id = res.tested_by
+		WHERE res.control_test_id = t.id
+		ORDER BY res.tested_at DESC, res.created_at DESC
+		LIMIT 1
+	) lr ON TRUE
+`
+
+func scanControlTest(row interface{ Scan(...any) error }) (*models.ControlTest, error) {
+	t := &models.ControlTest{}
+	var (
+		resultID, result, notes, testedByName sql.NullString
+		testedAt, createdAt                   sql.NullTime
+		testedBy                              *string
+		flagged                               sql.NullInt64
+	)
+	err := row.Scan(&t.ID, &t.ControlID, &t.ControlRef, &t.ControlTitle, &t.FrameworkID,
+		&t.Name, &t.Procedure, &t.Frequency, &t.TesterID, &t.TesterName,
+		&t.NextDueDate, &t.Active, &t.Overdue,
+		&t.CreatedBy, &t.CreatedAt, &t.UpdatedAt,
+		&resultID, &result, &notes, &testedAt, &testedBy, &testedByName, &flagged, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	if resultID.Valid {
+		t.LastResult = &models.ControlTestResult{
+			ID:            resultID.String,
+			ControlTestID: t.ID,
+			Result:        models.ControlTestResultValue(result.String),
+			Notes:         notes.String,
+			TestedAt:      testedAt.Time,
+			TestedBy:      testedBy,
+			TestedByName:  testedByName.String,
+			FlaggedRisks:  int(flagged.Int64),
+			CreatedAt:     createdAt.Time,
+		}
+	}
+	return t, nil
+}
+
+func (r *controlTestRepository) List(ctx context.Context, filter models.ControlTestFilter) ([]*models.ControlTest, error) {
+	query := controlTestSelect + ` WHERE ($1 = '' OR t.framework_control_id::text = $1)
+		AND ($2 = '' OR t.tester_id::text = $2)
+		AND (NOT $3 OR (t.active AND t.next_due_date < CURRENT_DATE))
+		ORDER BY t.next_due_date, fc.control_ref, t.name`
+	rows, err := r.db.QueryContext(ctx, query, filter.ControlID, filter.TesterID, filter.Overdue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tests := []*models.ControlTest{}
+	for rows.Next() {
+		test, err := scanControlTest(rows)
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+	}
+	return tests, rows.Err()
+}
+
+func (r *controlTestRepository) GetByID(ctx context.Context, id string) (*models.ControlTest, error) {
+	test, err := scanControlTest(r.db.QueryRowContext(ctx, controlTestSelect+` WHERE t.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrControlTestNotFound
+	}
+	return test, err
+}
+
+func (r *controlTestRepository) Create(ctx context.Context, test *models.ControlTest) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO control_tests (framework_control_id, name, procedure, frequency, tester_id, next_due_date, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`, test.ControlID, test.Name, test.Procedure, test.Frequency, test.TesterID, test.NextDueDate, test.Active, test.CreatedBy).
+		Scan(&test.ID, &test.CreatedAt, &test.UpdatedAt)
+	return controlTestWriteError(err)
+}
+
+func (r *controlTestRepository) Update(ctx context.Context, test *models.ControlTest) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE control_tests
+		SET name = $2, procedure = $3, frequency = $4, tester_id = $5, next_due_date = $6, active = $7, updated_at = NOW()
+		WHERE id = $1
+	`, test.ID, test.Name, test.Procedure, test.Frequency, test.TesterID, test.NextDueDate, test.Active)
+	if err != nil {
+		return controlTestWriteError(err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrControlTestNotFound
+	}
+	return nil
+}
+
+// controlTestWriteError maps foreign key violations to the reference that
+// doesn't exist
+func controlTestWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "control_tests_tester_id_fkey" {
+			return ErrControlTestTesterNotFound
+		}
+		return ErrFrameworkControlNotFound
+	}
+	return err
+}
+
+func (r *controlTestRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM control_tests WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrControlTestNotFound
+	}
+	return nil
+}
+
+func (r *controlTestRepository) ListResults(ctx context.Context, testID string) ([]*models.ControlTestResult, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM control_tests WHERE id = $1)`, testID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrControlTestNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT res.id, res.control_test_id, res.result, res.notes, res.tested_at, res.tested_by,
+			COALESCE(u.name, ''), res.flagged_risks, res.created_at
+		FROM control_test_results res
+		LEFT JOIN users u ON u.id = res.tested_by
+		WHERE res.control_test_id = $1
+		ORDER BY res.tested_at DESC, res.created_at DESC
+	`, testID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*models.ControlTestResult{}
+	for rows.Next() {
+		result := &models.ControlTestResult{}
+		if err := rows.Scan(&result.ID, &result.ControlTestID, &result.Result, &result.Notes, &result.TestedAt,
+			&result.TestedBy, &result.TestedByName, &result.FlaggedRisks, &result.CreatedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+func (r *controlTestRepository) RecordResult(ctx context.Context, result *models.ControlTestResult, next time.Time) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO control_test_results (control_test_id, result, notes, tested_at, tested_by, flagged_risks)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, result.ControlTestID, result.Result, result.Notes, result.TestedAt, result.TestedBy, result.FlaggedRisks).
+		Scan(&result.ID, &result.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return ErrControlTestNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// A result entered late for an earlier test run doesn't move the
+	// schedule back
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE control_tests t
+		SET next_due_date = $2, updated_at = NOW()
+		WHERE t.id = $1 AND NOT EXISTS (
+			SELECT 1 FROM control_test_results res
+			WHERE res.control_test_id = t.id AND res.tested_at > $3
+		)
+	`, result.ControlTestID, next, result.TestedAt)
+	return err
+}
+
+func (r *controlTestRepository) FlagRisksForReview(ctx context.Context, controlID, userID string) ([]models.FlaggedRisk, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		WITH targets AS (
+			SELECT r.id, r.review_date
+			FROM risks r
+			WHERE r.deleted_at IS NULL
+				AND (r.review_date IS NULL OR r.review_date > CURRENT_DATE)
+				AND r.id IN (SELECT risk_id FROM risk_framework_controls WHERE framework_control_id = $1)
+			FOR UPDATE
+		)
+		UPDATE risks r
+		SET review_date = CURRENT_DATE, version = r.version + 1, updated_at = NOW(), updated_by = $2
+		FROM targets
+		WHERE r.id = targets.id
+		RETURNING r.id, targets.review_date, r.review_date
+	`, controlID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flagged := []models.FlaggedRisk{}
+	for rows.Next() {
+		var risk models.FlaggedRisk
+		if err := rows.Scan(&risk.ID, &risk.PreviousReviewDate, &risk.ReviewDate); err != nil {
+			return nil, err
+		}
+		flagged = append(flagged, risk)
+	}
+	return flagged, rows.Err()
+}
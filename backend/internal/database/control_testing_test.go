@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControlTestRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewControlTestRepository(s.db)
+	frameworkRepo := NewFrameworkRepository(s.db)
+	controlRepo := NewFrameworkControlRepository(s.db)
+	riskControlRepo := NewRiskFrameworkControlRepository(s.db)
+	riskRepo := NewRiskRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "control-tests-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Control Tester",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+	framework, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "ISO tests " + uuid.New().String()})
+	require.NoError(t, err)
+	control, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{FrameworkID: framework.ID, ControlRef: "A.5.15", Title: "Access control"})
+	require.NoError(t, err)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	test := &models.ControlTest{
+		ControlID:   control.ID,
+		Name:        "Quarterly access review",
+		Frequency:   models.RecurrenceQuarterly,
+		TesterID:    &user.ID,
+		NextDueDate: today.AddDate(0, 0, -3),
+		Active:      true,
+		CreatedBy:   &user.ID,
+	}
+	require.NoError(t, repo.Create(ctx, test))
+	missing := uuid.New().String()
+	assert.ErrorIs(t, repo.Create(ctx, &models.ControlTest{ControlID: control.ID, Name: "x", Frequency: models.RecurrenceAnnual,
+		TesterID: &missing, NextDueDate: today}), ErrControlTestTesterNotFound)
+	assert.ErrorIs(t, repo.Create(ctx, &models.ControlTest{ControlID: missing, Name: "x", Frequency: models.RecurrenceAnnual,
+		NextDueDate: today}), ErrFrameworkControlNotFound)
+
+	fetched, err := repo.GetByID(ctx, test.ID)
+	require.NoError(t, err)
+	assert.True(t, fetched.Overdue)
+	assert.Equal(t, "A.5.15", fetched.ControlRef)
+	assert.Equal(t, "Control Tester", fetched.TesterName)
+	assert.Nil(t, fetched.LastResult)
+
+	overdue, err := repo.List(ctx, models.ControlTestFilter{ControlID: control.ID, Overdue: true})
+	require.NoError(t, err)
+	require.Len(t, overdue, 1)
+
+	later := today.AddDate(0, 1, 0)
+	due := today.AddDate(0, 0, -1)
+	newRisk := func(title string, reviewDate *time.Time) *models.Risk {
+		risk := &models.Risk{Title: title, OwnerID: user.ID, Status: models.StatusOpen, Severity: models.SeverityHigh,
+			ReviewDate: reviewDate, CreatedBy: user.ID, UpdatedBy: user.ID}
+		require.NoError(t, riskRepo.Create(ctx, risk))
+		_, err := riskControlRepo.LinkControl(ctx, risk.ID, &models.LinkControlInput{FrameworkControlID: control.ID}, user.ID)
+		require.NoError(t, err)
+		return risk
+	}
+	scheduled, unscheduled, alreadyDue := newRisk("Scheduled", &later), newRisk("Unscheduled", nil), newRisk("Due", &due)
+
+	flagged, err := repo.FlagRisksForReview(ctx, control.ID, user.ID)
+	require.NoError(t, err)
+	require.Len(t, flagged, 2)
+	for _, risk := range flagged {
+		assert.NotEqual(t, alreadyDue.ID, risk.ID)
+		if risk.ID == scheduled.ID {
+			require.NotNil(t, risk.PreviousReviewDate)
+			assert.Equal(t, later.Format("2006-01-02"), risk.PreviousReviewDate.Format("2006-01-02"))
+		}
+	}
+	reloaded, err := riskRepo.FindByID(ctx, unscheduled.ID)
+	require.NoError(t, err)
+	require.NotNil(t, reloaded.ReviewDate)
+	assert.Equal(t, unscheduled.Version+1, reloaded.Version)
+
+	result := &models.ControlTestResult{ControlTestID: test.ID, Result: models.ControlIneffective, Notes: "Leavers kept access",
+		TestedAt: today, TestedBy: &user.ID, FlaggedRisks: len(flagged)}
+	require.NoError(t, repo.RecordResult(ctx, result, models.RecurrenceQuarterly.Next(today)))
+	backdated := &models.ControlTestResult{ControlTestID: test.ID, Result: models.ControlEffective, TestedAt: today.AddDate(0, -3, 0), TestedBy: &user.ID}
+	require.NoError(t, repo.RecordResult(ctx, backdated, models.RecurrenceQuarterly.Next(backdated.TestedAt)))
+
+	fetched, err = repo.GetByID(ctx, test.ID)
+	require.NoError(t, err)
+	assert.False(t, fetched.Overdue)
+	assert.Equal(t, models.RecurrenceQuarterly.Next(today).Format("2006-01-02"), fetched.NextDueDate.Format("2006-01-02"),
+		"a backdated result doesn't move the schedule back")
+	require.NotNil(t, fetched.LastResult)
+	assert.Equal(t, models.ControlIneffective, fetched.LastResult.Result)
+	assert.Equal(t, 2, fetched.LastResult.FlaggedRisks)
+
+	results, err := repo.ListResults(ctx, test.ID)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Control Tester", results[0].TestedByName)
+
+	require.NoError(t, repo.Delete(ctx, test.ID))
+	_, err = repo.ListResults(ctx, test.ID)
+	assert.ErrorIs(t, err, ErrControlTestNotFound)
+}
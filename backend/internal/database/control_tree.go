@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"backend/internal/models"
+)
+
+func (r *frameworkControlRepository) Tree(ctx context.Context, frameworkID string) ([]*models.ControlTreeNode, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM frameworks WHERE id = $1)`, frameworkID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrFrameworkNotFound
+	}
+
+	controls, err := r.frameworkControls(ctx, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	links, err := r.riskLinks(ctx, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	return buildControlTree(controls, links), nil
+}
+
+// frameworkControls returns every control of a framework, in no particular
+// order and without risk counts
+func (r *frameworkControlRepository) frameworkControls(ctx context.Context, frameworkID string) ([]*models.FrameworkControl, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT fc.id, fc.framework_id, f.name, fc.control_ref, fc.title, COALESCE(fc.description, ''),
+			fc.parent_id, fc.sort_order, fc.created_at, fc.updated_at
+		FROM framework_controls fc
+		JOIN frameworks f ON f.id = fc.framework_id
+		WHERE fc.framework_id = $1
+	`, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var controls []*models.FrameworkControl
+	for rows.Next() {
+		control := &models.FrameworkControl{}
+		if err := rows.Scan(
+			&control.ID,
+			&control.FrameworkID,
+			&control.FrameworkName,
+			&control.ControlRef,
+			&control.Title,
+			&control.Description,
+			&control.ParentID,
+			&control.SortOrder,
+			&control.CreatedAt,
+			&control.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		controls = append(controls, control)
+	}
+	return controls, rows.Err()
+}
+
+// riskLinks returns the IDs of the live risks linked to each of the
+// framework's controls
+func (r *frameworkControlRepository) riskLinks(ctx context.Context, frameworkID string) (map[string][]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rfc.framework_control_id, rfc.risk_id
+		FROM risk_framework_controls rfc
+		JOIN framework_controls fc ON fc.id = rfc.framework_control_id
+		JOIN risks r ON r.id = rfc.risk_id AND r.deleted_at IS NULL
+		WHERE fc.framework_id = $1
+	`, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := map[string][]string{}
+	for rows.Next() {
+		var controlID, riskID string
+		if err := rows.Scan(&controlID, &riskID); err != nil {
+			return nil, err
+		}
+		links[controlID] = append(links[controlID], riskID)
+	}
+	return links, rows.Err()
+}
+
+// buildControlTree nests controls under their parents, sorting siblings by
+// sort_order and then control_ref, and fills in each control's own and
+// rolled-up risk counts from links. Controls whose parent is missing become
+// roots.
+func buildControlTree(controls []*models.FrameworkControl, links map[string][]string) []*models.ControlTreeNode {
+	nodes := make(map[string]*models.ControlTreeNode, len(controls))
+	for _, control := range controls {
+		control.LinkedRiskCount = len(links[control.ID])
+		nodes[control.ID] = &models.ControlTreeNode{FrameworkControl: control, Children: []*models.ControlTreeNode{}}
+	}
+
+	roots := []*models.ControlTreeNode{}
+	for _, control := range controls {
+		node := nodes[control.ID]
+		if control.ParentID != nil && nodes[*control.ParentID] != nil {
+			parent := nodes[*control.ParentID]
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	var rollUp func(node *models.ControlTreeNode) map[string]bool
+	rollUp = func(node *models.ControlTreeNode) map[string]bool {
+		sortControlNodes(node.Children)
+		risks := map[string]bool{}
+		for _, id := range links[node.ID] {
+			risks[id] = true
+		}
+		node.ControlCount = 1
+		if node.LinkedRiskCount > 0 {
+			node.CoveredControlCount = 1
+		}
+		for _, child := range node.Children {
+			for id := range rollUp(child) {
+				risks[id] = true
+			}
+			node.ControlCount += child.ControlCount
+			node.CoveredControlCount += child.CoveredControlCount
+		}
+		node.RolledUpRiskCount = len(risks)
+		return risks
+	}
+	sortControlNodes(roots)
+	for _, root := range roots {
+		rollUp(root)
+	}
+	return roots
+}
+
+func sortControlNodes(nodes []*models.ControlTreeNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].SortOrder != nodes[j].SortOrder {
+			return nodes[i].SortOrder < nodes[j].SortOrder
+		}
+		return nodes[i].ControlRef < nodes[j].ControlRef
+	})
+}
+
+// walkControls calls fn for each control in tree order with its family and
+// whether it is a group with controls below it. A top-level control with
+// children is a family heading: it names the family of everything below it
+// and isn't passed to fn itself. Top-level controls without children, as in
+// frameworks imported without a hierarchy, are grouped by controlFamily.
+func walkControls(controls []*models.FrameworkControl, fn func(control *models.FrameworkControl, family, familyTitle string, group bool)) {
+	var walk func(node *models.ControlTreeNode, heading *models.FrameworkControl)
+	walk = func(node *models.ControlTreeNode, heading *models.FrameworkControl) {
+		fn(node.FrameworkControl, heading.ControlRef, heading.Title, len(node.Children) > 0)
+		for _, child := range node.Children {
+			walk(child, heading)
+		}
+	}
+	for _, root := range buildControlTree(controls, nil) {
+		if len(root.Children) == 0 {
+			fn(root.FrameworkControl, controlFamily(root.ControlRef), "", false)
+			continue
+		}
+		for _, child := range root.Children {
+			walk(child, root.FrameworkControl)
+		}
+	}
+}
+
+// controlFamily guesses the family of a control outside any hierarchy by
+// dropping the last segment of its ref, so A.5.1 is in A.5, CC6.1 in CC6 and
+// AC-2 in AC. A ref with no separator is its own family.
+func controlFamily(ref string) string {
+	if i := strings.LastIndexAny(ref, ".-"); i > 0 {
+		return ref[:i]
+	}
+	return ref
+}
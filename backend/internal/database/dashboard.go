@@ -11,6 +11,7 @@ type DashboardRepository interface {
 	GetSummary(ctx context.Context) (*models.DashboardSummaryResponse, error)
 	GetUpcomingReviews(ctx context.Context, days int) (*models.ReviewListResponse, error)
 	GetOverdueReviews(ctx context.Context) (*models.ReviewListResponse, error)
+	GetTopRisks(ctx context.Context, limit int) (*models.TopRiskListResponse, error)
 }
 
 type dashboardRepository struct {
@@ -29,13 +30,13 @@ func (r *dashboardRepository) GetSummary(ctx context.Context) (*models.Dashboard
 	}
 
 	// Get total count
-	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM risks").Scan(&response.TotalRisks)
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM risks WHERE deleted_at IS NULL").Scan(&response.TotalRisks)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get counts by status
-	rows, err := r.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM risks GROUP BY status")
+	rows, err := r.db.QueryContext(ctx, "SELECT status, COUNT(*) FROM risks WHERE deleted_at IS NULL GROUP BY status")
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +52,7 @@ func (r *dashboardRepository) GetSummary(ctx context.Context) (*models.Dashboard
 	rows.Close()
 
 	// Get counts by severity
-	rows, err = r.db.QueryContext(ctx, "SELECT severity, COUNT(*) FROM risks GROUP BY severity")
+	rows, err = r.db.QueryContext(ctx, "SELECT severity, COUNT(*) FROM risks WHERE deleted_at IS NULL GROUP BY severity")
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +71,7 @@ func (r *dashboardRepository) GetSummary(ctx context.Context) (*models.Dashboard
 	rows, err = r.db.QueryContext(ctx, `
 		SELECT c.id, c.name, COUNT(r.id)
 		FROM categories c
-		LEFT JOIN risks r ON r.category_id = c.id
+		LEFT JOIN risks r ON r.category_id = c.id AND r.deleted_at IS NULL
 		GROUP BY c.id, c.name
 		ORDER BY COUNT(r.id) DESC
 	`)
@@ -89,12 +90,28 @@ func (r *dashboardRepository) GetSummary(ctx context.Context) (*models.Dashboard
 
 	// Get overdue reviews count
 	err = r.db.QueryRowContext(ctx,
-		"SELECT COUNT(*) FROM risks WHERE review_date IS NOT NULL AND review_date < NOW()",
+		"SELECT COUNT(*) FROM risks WHERE deleted_at IS NULL AND review_date IS NOT NULL AND review_date < NOW()",
 	).Scan(&response.OverdueReviews)
 	if err != nil {
 		return nil, err
 	}
 
+	// Get overdue control tests count
+	err = r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM control_tests WHERE active AND next_due_date < CURRENT_DATE",
+	).Scan(&response.OverdueControlTests)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get overdue evidence requests count
+	err = r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM evidence_requests WHERE status = 'open' AND due_date < CURRENT_DATE",
+	).Scan(&response.OverdueEvidenceRequests)
+	if err != nil {
+		return nil, err
+	}
+
 	return response, nil
 }
 
@@ -102,7 +119,8 @@ func (r *dashboardRepository) GetUpcomingReviews(ctx context.Context, days int)
 	query := `
 		SELECT id, title, review_date, severity, status
 		FROM risks
-		WHERE review_date IS NOT NULL
+		WHERE deleted_at IS NULL
+			AND review_date IS NOT NULL
 			AND review_date >= NOW()
 			AND review_date <= NOW() + INTERVAL '1 day' * $1
 		ORDER BY review_date ASC
@@ -130,7 +148,8 @@ func (r *dashboardRepository) GetOverdueReviews(ctx context.Context) (*models.Re
 	query := `
 		SELECT id, title, review_date, severity, status
 		FROM risks
-		WHERE review_date IS NOT NULL
+		WHERE deleted_at IS NULL
+			AND review_date IS NOT NULL
 			AND review_date < NOW()
 		ORDER BY review_date ASC
 	`
@@ -152,3 +171,35 @@ func (r *dashboardRepository) GetOverdueReviews(ctx context.Context) (*models.Re
 
 	return response, nil
 }
+
+// GetTopRisks returns the most severe risks that are still open or being
+// mitigated, soonest review first within a severity
+func (r *dashboardRepository) GetTopRisks(ctx context.Context, limit int) (*models.TopRiskListResponse, error) {
+	query := `
+		SELECT r.id, r.title, r.severity, r.status, COALESCE(u.name, ''), COALESCE(c.name, ''), r.review_date
+		FROM risks r
+		LEFT JOIN users u ON u.id = r.owner_id
+		LEFT JOIN categories c ON c.id = r.category_id
+		WHERE r.deleted_at IS NULL
+			AND r.status IN ('open', 'mitigating')
+		ORDER BY r.severity DESC, r.review_date ASC NULLS LAST, r.created_at ASC
+		LIMIT $1
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &models.TopRiskListResponse{Risks: []models.TopRisk{}}
+	for rows.Next() {
+		var risk models.TopRisk
+		if err := rows.Scan(&risk.ID, &risk.Title, &risk.Severity, &risk.Status, &risk.OwnerName, &risk.Category, &risk.ReviewDate); err != nil {
+			return nil, err
+		}
+		response.Risks = append(response.Risks, risk)
+	}
+
+	return response, rows.Err()
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrEvidenceRequestNotFound  = errors.New("evidence request not found")
+	ErrEvidenceAssigneeNotFound = errors.New("assignee not found")
+	ErrEvidenceRequestFulfilled = errors.New("evidence request already fulfilled")
+)
+
+type EvidenceRequestRepository interface {
+	// List returns requests ordered by due date, each with its latest
+	// submission
+	List(ctx context.Context, filter models.EvidenceRequestFilter) ([]*models.EvidenceRequest, error)
+	GetByID(ctx context.Context, id string) (*models.EvidenceRequest, error)
+	// Create stores the request and sets its ID and timestamps
+	Create(ctx context.Context, request *models.EvidenceRequest) error
+	// Update saves the editable fields and status of the request
+	Update(ctx context.Context, request *models.EvidenceRequest) error
+	Delete(ctx context.Context, id string) error
+	// ListSubmissions returns a request's submissions, most recent first
+	ListSubmissions(ctx context.Context, requestID string) ([]*models.EvidenceSubmission, error)
+	// Fulfil stores the submission for the request's current due date and
+	// sets its ID and CreatedAt. The request then comes due again on next,
+	// or is fulfilled when next is nil. ErrEvidenceRequestFulfilled means
+	// the request was fulfilled or its due date moved in the meantime.
+	Fulfil(ctx context.Context, submission *models.EvidenceSubmission, next *time.Time) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) EvidenceRequestRepository
+}
+
+type evidenceRequestRepository struct {
+	db dbtx
+}
+
+func NewEvidenceRequestRepository(db *sql.DB) EvidenceRequestRepository {
+	return &evidenceRequestRepository{db: db}
+}
+
+func (r *evidenceRequestRepository) WithTx(tx *sql.Tx) EvidenceRequestRepository {
+	return &evidenceRequestRepository{db: tx}
+}
+
+const evidenceRequestSelect = `
+	SELECT e.id, e.framework_control_id, fc.control_ref, fc.title, fc.framework_id,
+		e.title, e.description, e.assignee_id, COALESCE(au.name, ''),
+		e.due_date, e.recurrence, e.status, e.status = 'open' AND e.due_date < CURRENT_DATE,
+		e.created_by, e.created_at, e.updated_at,
+		ls.id, ls.due_date, ls.url, ls.note, ls.submitted_by, ls.submitted_by_name, ls.created_at
+	FROM evidence_requests e
+	JOIN framework_controls fc ON fc.id = e.framework_control_id
+	LEFT JOIN users au ON au.id = e.assignee_id
+	LEFT JOIN LATERAL (
+		SELECT s.id, s.due_date, s.url, s.note, s.submitted_by,
+			COALESCE(su.name, '') AS submitted_by_name, s.created_at
+		FROM evidence_submissions s
+		LEFT JOIN users su ON su.id = s.submitted_by
+		WHERE s.evidence_request_id = e.id
+		ORDER BY s.created_at DESC
+		LIMIT 1
+	) ls ON TRUE
+`
+
+func scanEvidenceRequest(row interface{ Scan(...any) error }) (*models.EvidenceRequest, error) {
+	e := &models.EvidenceRequest{}
+	var (
+		submissionID, url, note, submittedByName sql.NullString
+		dueDate, createdAt                       sql.NullTime
+		submittedBy                              *string
+	)
+	err := row.Scan(&e.ID, &e.ControlID, &e.ControlRef, &e.ControlTitle, &e.FrameworkID,
+		&e.Title, &e.Description, &e.AssigneeID, &e.AssigneeName,
+		&e.DueDate, &e.Recurrence, &e.Status, &e.Overdue,
+		&e.CreatedBy, &e.CreatedAt, &e.UpdatedAt,
+		&submissionID, &dueDate, &url, &note, &submittedBy, &submittedByName, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	if submissionID.Valid {
+		e.LastSubmission = &models.EvidenceSubmission{
+			ID:                submissionID.String,
+			EvidenceRequestID: e.ID,
+			DueDate:           dueDate.Time,
+			URL:               url.String,
+			Note:              note.String,
+			SubmittedBy:       submittedBy,
+			SubmittedByName:   submittedByName.String,
+			CreatedAt:         createdAt.Time,
+		}
+	}
+	return e, nil
+}
+
+func (r *evidenceRequestRepository) List(ctx context.Context, filter models.EvidenceRequestFilter) ([]*models.EvidenceRequest, error) {
+	query := evidenceRequestSelect + ` WHERE ($1 = '' OR e.framework_control_id::text = $1)
+		AND ($2 = '' OR e.assignee_id::text = $2)
+		AND ($3 = '' OR e.status = $3)
+		AND (NOT $4 OR (e.status = 'open' AND e.due_date < CURRENT_DATE))
+		ORDER BY e.due_date, fc.control_ref, e.title`
+	rows, err := r.db.QueryContext(ctx, query, filter.ControlID, filter.AssigneeID, filter.Status, filter.Overdue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []*models.EvidenceRequest{}
+	for rows.Next() {
+		request, err := scanEvidenceRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, request)
+	}
+	return requests, rows.Err()
+}
+
+func (r *evidenceRequestRepository) GetByID(ctx context.Context, id string) (*models.EvidenceRequest, error) {
+	request, err := scanEvidenceRequest(r.db.QueryRowContext(ctx, evidenceRequestSelect+` WHERE e.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEvidenceRequestNotFound
+	}
+	return request, err
+}
+
+func (r *evidenceRequestRepository) Create(ctx context.Context, request *models.EvidenceRequest) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO evidence_requests (framework_control_id, title, description, assignee_id, due_date, recurrence, status, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`, request.ControlID, request.Title, request.Description, request.AssigneeID, request.DueDate, request.Recurrence,
+		request.Status, request.CreatedBy).
+		Scan(&request.ID, &request.CreatedAt, &request.UpdatedAt)
+	return evidenceRequestWriteError(err)
+}
+
+func (r *evidenceRequestRepository) Update(ctx context.Context, request *models.EvidenceRequest) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE evidence_requests
+		SET title = $2, description = $3, assignee_id = $4, due_date = $5, recurrence = $6, status = $7, updated_at = NOW()
+		WHERE id = $1
+	`, request.ID, request.Title, request.Description, request.AssigneeID, request.DueDate, request.Recurrence, request.Status)
+	if err != nil {
+		return evidenceRequestWriteError(err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEvidenceRequestNotFound
+	}
+	return nil
+}
+
+// evidenceRequestWriteError maps foreign key violations to the reference
+// that doesn't exist
+func evidenceRequestWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "evidence_requests_assignee_id_fkey" {
+			return ErrEvidenceAssigneeNotFound
+		}
+		return ErrFrameworkControlNotFound
+	}
+	return err
+}
+
+func (r *evidenceRequestRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM evidence_requests WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrEvidenceRequestNotFound
+	}
+	return nil
+}
+
+func (r *evidenceRequestRepository) ListSubmissions(ctx context.Context, requestID string) ([]*models.EvidenceSubmission, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM evidence_requests WHERE id = $1)`, requestID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrEvidenceRequestNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.id, s.evidence_request_id, s.due_date, s.url, s.note, s.submitted_by, COALESCE(u.name, ''), s.created_at
+		FROM evidence_submissions s
+		LEFT JOIN users u ON u.id = s.submitted_by
+		WHERE s.evidence_request_id = $1
+		ORDER BY s.created_at DESC
+	`, requestID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	submissions := []*models.EvidenceSubmission{}
+	for rows.Next() {
+		s := &models.EvidenceSubmission{}
+		if err := rows.Scan(&s.ID, &s.EvidenceRequestID, &s.DueDate, &s.URL, &s.Note, &s.SubmittedBy,
+			&s.SubmittedByName, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		submissions = append(submissions, s)
+	}
+	return submissions, rows.Err()
+}
+
+func (r *evidenceRequestRepository) Fulfil(ctx context.Context, submission *models.EvidenceSubmission, next *time.Time) error {
+	// Moving the request on first, conditional on the due date being
+	// fulfilled, keeps two submissions from counting for the same date
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE evidence_requests
+		SET due_date = COALESCE($3, due_date),
+			status = CASE WHEN $3::date IS NULL THEN 'fulfilled' ELSE status END,
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'open' AND due_date = $2
+	`, submission.EvidenceRequestID, submission.DueDate, next)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM evidence_requests WHERE id = $1)`,
+			submission.EvidenceRequestID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return ErrEvidenceRequestNotFound
+		}
+		return ErrEvidenceRequestFulfilled
+	}
+
+	return r.db.QueryRowContext(ctx, `
+		INSERT INTO evidence_submissions (evidence_request_id, due_date, url, note, submitted_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, submission.EvidenceRequestID, submission.DueDate, submission.URL, submission.Note, submission.SubmittedBy).
+		Scan(&submission.ID, &submission.CreatedAt)
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvidenceRequestRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewEvidenceRequestRepository(s.db)
+	notifications := NewNotificationRepository(s.db)
+	frameworkRepo := NewFrameworkRepository(s.db)
+	controlRepo := NewFrameworkControlRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "evidence-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Evidence Owner",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+	framework, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "SOC 2 evidence " + uuid.New().String()})
+	require.NoError(t, err)
+	control, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{FrameworkID: framework.ID, ControlRef: "CC6.2", Title: "User access reviews"})
+	require.NoError(t, err)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	quarterly := models.RecurrenceQuarterly
+	request := &models.EvidenceRequest{
+		ControlID:  control.ID,
+		Title:      "Quarterly access review export",
+		AssigneeID: &user.ID,
+		DueDate:    today.AddDate(0, 0, -2),
+		Recurrence: &quarterly,
+		Status:     models.EvidenceOpen,
+		CreatedBy:  &user.ID,
+	}
+	require.NoError(t, repo.Create(ctx, request))
+	missing := uuid.New().String()
+	assert.ErrorIs(t, repo.Create(ctx, &models.EvidenceRequest{ControlID: control.ID, Title: "x", DueDate: today,
+		AssigneeID: &missing, Status: models.EvidenceOpen}), ErrEvidenceAssigneeNotFound)
+	assert.ErrorIs(t, repo.Create(ctx, &models.EvidenceRequest{ControlID: missing, Title: "x", DueDate: today,
+		Status: models.EvidenceOpen}), ErrFrameworkControlNotFound)
+
+	fetched, err := repo.GetByID(ctx, request.ID)
+	require.NoError(t, err)
+	assert.True(t, fetched.Overdue)
+	assert.Equal(t, "CC6.2", fetched.ControlRef)
+	assert.Equal(t, "Evidence Owner", fetched.AssigneeName)
+	require.NotNil(t, fetched.Recurrence)
+	assert.Equal(t, quarterly, *fetched.Recurrence)
+
+	overdue, err := repo.List(ctx, models.EvidenceRequestFilter{ControlID: control.ID, Overdue: true})
+	require.NoError(t, err)
+	require.Len(t, overdue, 1)
+
+	due, err := notifications.DueEvidenceRequests(ctx, today, today)
+	require.NoError(t, err)
+	var deadline *models.NotificationDeadline
+	for _, d := range due {
+		if d.EntityID == request.ID {
+			deadline = d
+		}
+	}
+	require.NotNil(t, deadline)
+	assert.Equal(t, "CC6.2 Quarterly access review export", deadline.Title)
+	assert.Equal(t, []string{user.ID}, deadline.UserIDs)
+
+	submission := &models.EvidenceSubmission{EvidenceRequestID: request.ID, DueDate: fetched.DueDate,
+		URL: "https://docs.example.com/q3.pdf", SubmittedBy: &user.ID}
+	next := quarterly.Next(fetched.DueDate)
+	require.NoError(t, repo.Fulfil(ctx, submission, &next))
+	stale := &models.EvidenceSubmission{EvidenceRequestID: request.ID, DueDate: fetched.DueDate, Note: "Twice"}
+	assert.ErrorIs(t, repo.Fulfil(ctx, stale, &next), ErrEvidenceRequestFulfilled, "each due date is fulfilled once")
+
+	fetched, err = repo.GetByID(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EvidenceOpen, fetched.Status)
+	assert.False(t, fetched.Overdue)
+	assert.Equal(t, next.Format("2006-01-02"), fetched.DueDate.Format("2006-01-02"))
+	require.NotNil(t, fetched.LastSubmission)
+	assert.Equal(t, "Evidence Owner", fetched.LastSubmission.SubmittedByName)
+
+	final := &models.EvidenceSubmission{EvidenceRequestID: request.ID, DueDate: fetched.DueDate, Note: "Control retired"}
+	require.NoError(t, repo.Fulfil(ctx, final, nil))
+	fetched, err = repo.GetByID(ctx, request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.EvidenceFulfilled, fetched.Status)
+
+	submissions, err := repo.ListSubmissions(ctx, request.ID)
+	require.NoError(t, err)
+	require.Len(t, submissions, 2)
+	assert.Equal(t, "Control retired", submissions[0].Note)
+
+	require.NoError(t, repo.Delete(ctx, request.ID))
+	_, err = repo.ListSubmissions(ctx, request.ID)
+	assert.ErrorIs(t, err, ErrEvidenceRequestNotFound)
+}
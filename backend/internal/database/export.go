@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"backend/internal/models"
+)
+
+type ExportRepository interface {
+	// EachRisk calls fn for every risk matching params, in list order, reading
+	// rows from the database as it goes. Paging fields are ignored. An error
+	// from fn stops the export and is returned.
+	EachRisk(ctx context.Context, params *models.RiskListParams, fn func(*models.RiskExportRow) error) error
+}
+
+type exportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+func (r *exportRepository) EachRisk(ctx context.Context, params *models.RiskListParams, fn func(*models.RiskExportRow) error) error {
+	where, args := riskListWhere(params)
+
+	// Mitigations and controls are aggregated per risk so each result row is
+	// complete on its own and nothing has to be held back for a second pass
+	query := fmt.Sprintf(`
+		SELECT r.id, r.title, COALESCE(r.description, ''), r.owner_id, COALESCE(u.name, ''), COALESCE(u.email, ''),
+		       r.status, r.severity, COALESCE(c.name, ''), r.review_date, r.tags, r.created_at, r.updated_at,
+		       COALESCE((
+		           SELECT json_agg(json_build_object(
+		               'description', m.description,
+		               'owner', COALESCE(m.owner, ''),
+		               'status', m.status,
+		               'due_date', to_char(m.due_date AT TIME ZONE 'UTC', 'YYYY-MM-DD')
+		           ) ORDER BY m.created_at)
+		           FROM mitigations m WHERE m.risk_id = r.id
+		       ), '[]'),
+		       COALESCE((
+		           SELECT json_agg(json_build_object(
+		               'framework', f.name,
+		               'control_ref', fc.control_ref,
+		               'title', fc.title
+		           ) ORDER BY f.name, fc.control_ref)
+		           FROM risk_framework_controls rfc
+		           JOIN framework_controls fc ON fc.id = rfc.framework_control_id
+		           JOIN frameworks f ON f.id = fc.framework_id
+		           WHERE rfc.risk_id = r.id
+		       ), '[]')
+		FROM risks r
+		LEFT JOIN users u ON u.id = r.owner_id
+		LEFT JOIN categories c ON c.id = r.category_id
+		%s ORDER BY %s, r.id
+	`, where, riskListOrder(params))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := &models.RiskExportRow{}
+		var mitigations, controls []byte
+		err := rows.Scan(
+			&row.ID, &row.Title, &row.Description, &row.OwnerID, &row.OwnerName, &row.OwnerEmail,
+			&row.Status, &row.Severity, &row.Category, &row.ReviewDate, tagsColumn{&row.Tags}, &row.CreatedAt, &row.UpdatedAt,
+			&mitigations, &controls,
+		)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(mitigations, &row.Mitigations); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(controls, &row.Controls); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
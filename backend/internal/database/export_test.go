@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	exportRepo := NewExportRepository(s.db)
+	riskRepo := NewRiskRepository(s.db)
+	mitigationRepo := NewMitigationRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	frameworkRepo := NewFrameworkRepository(s.db)
+	controlRepo := NewFrameworkControlRepository(s.db)
+	linkRepo := NewRiskFrameworkControlRepository(s.db)
+
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "test-export-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Export Tester",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+
+	tag := "export-" + uuid.New().String()
+	risk := &models.Risk{
+		Title:     "Exported Risk",
+		OwnerID:   user.ID,
+		Status:    models.StatusOpen,
+		Severity:  models.SeverityHigh,
+		Tags:      []string{tag},
+		CreatedBy: user.ID,
+		UpdatedBy: user.ID,
+	}
+	require.NoError(t, riskRepo.Create(ctx, risk))
+
+	due := "2024-07-01"
+	_, err := mitigationRepo.Create(ctx, &models.CreateMitigationInput{
+		RiskID:      risk.ID,
+		Description: "Second supplier",
+		Owner:       "Ops",
+		Status:      models.MitigationStatusPlanned,
+		DueDate:     &due,
+	}, user.ID)
+	require.NoError(t, err)
+
+	framework, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "Export Framework " + uuid.New().String()})
+	require.NoError(t, err)
+	control, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{
+		FrameworkID: framework.ID,
+		ControlRef:  "EX-1",
+		Title:       "Export control",
+	})
+	require.NoError(t, err)
+	_, err = linkRepo.LinkControl(ctx, risk.ID, &models.LinkControlInput{FrameworkControlID: control.ID}, user.ID)
+	require.NoError(t, err)
+
+	var rows []*models.RiskExportRow
+	err = exportRepo.EachRisk(ctx, &models.RiskListParams{Tag: tag}, func(row *models.RiskExportRow) error {
+		rows = append(rows, row)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	row := rows[0]
+	assert.Equal(t, risk.ID, row.ID)
+	assert.Equal(t, "Export Tester", row.OwnerName)
+	assert.Equal(t, user.Email, row.OwnerEmail)
+	assert.Equal(t, []string{tag}, row.Tags)
+	require.Len(t, row.Mitigations, 1)
+	assert.Equal(t, "Second supplier", row.Mitigations[0].Description)
+	require.NotNil(t, row.Mitigations[0].DueDate)
+	assert.Equal(t, due, *row.Mitigations[0].DueDate)
+	require.Len(t, row.Controls, 1)
+	assert.Equal(t, "EX-1", row.Controls[0].ControlRef)
+	assert.Equal(t, framework.Name, row.Controls[0].Framework)
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend/internal/models"
+)
+
+var ErrFrameworkLinkNotFound = errors.New("risk control link not found")
+
+// FrameworkMigrationRepository moves risk links from the controls of one
+// framework version to the controls of another
+type FrameworkMigrationRepository interface {
+	// ListLinks lists the risk links to the framework's controls, including
+	// those of trashed risks, ordered by control_ref and risk title
+	ListLinks(ctx context.Context, frameworkID string) ([]*models.FrameworkMigrationLink, error)
+	// Repoint links the link's risk to each of controlIDs, keeping its
+	// notes, and then deletes the link. Controls the risk is already linked
+	// to are skipped. It returns the number of links created.
+	Repoint(ctx context.Context, linkID string, controlIDs []string, userID string) (int, error)
+	// SetPreviousVersion records previousID as the framework's previous
+	// version unless it already has one
+	SetPreviousVersion(ctx context.Context, frameworkID, previousID string) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) FrameworkMigrationRepository
+}
+
+type frameworkMigrationRepository struct {
+	db dbtx
+}
+
+func NewFrameworkMigrationRepository(db *sql.DB) FrameworkMigrationRepository {
+	return &frameworkMigrationRepository{db: db}
+}
+
+func (r *frameworkMigrationRepository) WithTx(tx *sql.Tx) FrameworkMigrationRepository {
+	return &frameworkMigrationRepository{db: tx}
+}
+
+func (r *frameworkMigrationRepository) ListLinks(ctx context.Context, frameworkID string) ([]*models.FrameworkMigrationLink, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT rfc.id, rfc.risk_id, r.title, fc.id, fc.control_ref
+		FROM risk_framework_controls rfc
+		JOIN framework_controls fc ON fc.id = rfc.framework_control_id
+		JOIN risks r ON r.id = rfc.risk_id
+		WHERE fc.framework_id = $1
+		ORDER BY fc.control_ref, r.title, rfc.id
+	`, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*models.FrameworkMigrationLink{}
+	for rows.Next() {
+		link := &models.FrameworkMigrationLink{NewRefs: []string{}}
+		if err := rows.Scan(&link.LinkID, &link.RiskID, &link.RiskTitle, &link.OldControlID, &link.OldRef); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+func (r *frameworkMigrationRepository) Repoint(ctx context.Context, linkID string, controlIDs []string, userID string) (int, error) {
+	created := 0
+	for _, controlID := range controlIDs {
+		result, err := r.db.ExecContext(ctx, `
+			INSERT INTO risk_framework_controls (risk_id, framework_control_id, notes, created_by)
+			SELECT risk_id, $2, notes, $3
+			FROM risk_framework_controls
+			WHERE id = $1
+			ON CONFLICT (risk_id, framework_control_id) DO NOTHING
+		`, linkID, controlID, userID)
+		if err != nil {
+			return 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		created += int(n)
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM risk_framework_controls WHERE id = $1`, linkID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrFrameworkLinkNotFound
+	}
+	return created, nil
+}
+
+func (r *frameworkMigrationRepository) SetPreviousVersion(ctx context.Context, frameworkID, previousID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE frameworks
+		SET previous_version_id = COALESCE(previous_version_id, $2), updated_at = NOW()
+		WHERE id = $1
+	`, frameworkID, previousID)
+	if err != nil {
+		return frameworkWriteError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrFrameworkNotFound
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameworkMigrationRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewFrameworkMigrationRepository(s.db)
+	frameworkRepo := NewFrameworkRepository(s.db)
+	controlRepo := NewFrameworkControlRepository(s.db)
+	riskRepo := NewRiskRepository(s.db)
+	riskControlRepo := NewRiskFrameworkControlRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	old, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "ISO 27001:2013 " + uuid.New().String(), Version: "2013"})
+	require.NoError(t, err)
+	revision, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "ISO 27001:2022 " + uuid.New().String(), Version: "2022"})
+	require.NoError(t, err)
+	missing := uuid.New().String()
+	_, err = frameworkRepo.Update(ctx, revision.ID, &models.UpdateFrameworkInput{PreviousVersionID: &missing})
+	assert.ErrorIs(t, err, ErrFrameworkPreviousVersionNotFound)
+
+	control := func(frameworkID, ref string) *models.FrameworkControl {
+		control, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{FrameworkID: frameworkID, ControlRef: ref, Title: ref})
+		require.NoError(t, err)
+		return control
+	}
+	a1221, a1214 := control(old.ID, "A.12.1.2"), control(old.ID, "A.12.1.4")
+	c89, c832 := control(revision.ID, "8.9"), control(revision.ID, "8.32")
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "migration-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Migration Tester",
+		Role:         models.RoleAdmin,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+	risk := &models.Risk{Title: "Unreviewed changes", OwnerID: user.ID, Status: models.StatusOpen, Severity: models.SeverityHigh,
+		CreatedBy: user.ID, UpdatedBy: user.ID}
+	require.NoError(t, riskRepo.Create(ctx, risk))
+	for _, id := range []string{a1221.ID, a1214.ID, c89.ID} {
+		_, err = riskControlRepo.LinkControl(ctx, risk.ID, &models.LinkControlInput{FrameworkControlID: id, Notes: "Change board"}, user.ID)
+		require.NoError(t, err)
+	}
+
+	links, err := repo.ListLinks(ctx, old.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "A.12.1.2", links[0].OldRef)
+	assert.Equal(t, "Unreviewed changes", links[0].RiskTitle)
+
+	created, err := repo.Repoint(ctx, links[0].LinkID, []string{c89.ID, c832.ID}, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, created, "the risk is already linked to 8.9")
+	_, err = repo.Repoint(ctx, links[0].LinkID, []string{c832.ID}, user.ID)
+	assert.ErrorIs(t, err, ErrFrameworkLinkNotFound)
+
+	linked, err := riskControlRepo.ListByRiskID(ctx, risk.ID)
+	require.NoError(t, err)
+	refs := map[string]string{}
+	for _, link := range linked {
+		refs[link.ControlRef] = link.Notes
+	}
+	assert.Equal(t, map[string]string{"A.12.1.4": "Change board", "8.9": "Change board", "8.32": "Change board"}, refs)
+
+	require.NoError(t, repo.SetPreviousVersion(ctx, revision.ID, old.ID))
+	other, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "ISO 27001:2005 " + uuid.New().String()})
+	require.NoError(t, err)
+	require.NoError(t, repo.SetPreviousVersion(ctx, revision.ID, other.ID))
+	fetched, err := frameworkRepo.GetByID(ctx, revision.ID)
+	require.NoError(t, err)
+	require.NotNil(t, fetched.PreviousVersionID)
+	assert.Equal(t, old.ID, *fetched.PreviousVersionID, "an existing previous version is kept")
+	assert.Equal(t, "2022", fetched.Version)
+	assert.ErrorIs(t, repo.SetPreviousVersion(ctx, missing, old.ID), ErrFrameworkNotFound)
+}
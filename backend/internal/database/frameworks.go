@@ -13,8 +13,10 @@ import (
 )
 
 var ErrFrameworkNotFound = errors.New("framework not found")
+var ErrFrameworkPreviousVersionNotFound = errors.New("previous version not found")
 var ErrFrameworkControlNotFound = errors.New("framework control not found")
 var ErrFrameworkControlInUse = errors.New("framework control is linked to risks")
+var ErrFrameworkControlParent = errors.New("parent must be another control of the same framework and not one below it")
 
 type FrameworkRepository interface {
 	List(ctx context.Context) ([]*models.Framework, error)
@@ -22,6 +24,8 @@ type FrameworkRepository interface {
 	Create(ctx context.Context, input *models.CreateFrameworkInput) (*models.Framework, error)
 	Update(ctx context.Context, id string, input *models.UpdateFrameworkInput) (*models.Framework, error)
 	Delete(ctx context.Context, id string) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) FrameworkRepository
 }
 
 type FrameworkControlRepository interface {
@@ -29,8 +33,22 @@ type FrameworkControlRepository interface {
 	GetByID(ctx context.Context, id string) (*models.FrameworkControl, error)
 	ListLinkedRisks(ctx context.Context, id string) ([]*models.ControlLinkedRisk, error)
 	Create(ctx context.Context, input *models.CreateFrameworkControlInput) (*models.FrameworkControl, error)
+	// Update changes the control. Moving it under another parent first locks
+	// the framework's tree until the transaction ends, so concurrent moves
+	// cannot together make a cycle; call it inside a transaction.
 	Update(ctx context.Context, id string, input *models.UpdateFrameworkControlInput) (*models.FrameworkControl, error)
 	Delete(ctx context.Context, id string) error
+	// Tree returns the framework's controls as a tree, ordered by sort_order
+	// and then control_ref, with risk counts rolled up
+	Tree(ctx context.Context, frameworkID string) ([]*models.ControlTreeNode, error)
+	// Coverage reports which of the framework's controls have linked risks,
+	// overall and per control family
+	Coverage(ctx context.Context, frameworkID string) (*models.FrameworkCoverage, error)
+	// Upsert creates the control, or updates the framework's control with
+	// the same ref, and sets control.ID
+	Upsert(ctx context.Context, control *models.FrameworkControl) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) FrameworkControlRepository
 }
 
 type RiskFrameworkControlRepository interface {
@@ -40,11 +58,11 @@ type RiskFrameworkControlRepository interface {
 }
 
 type frameworkRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 type frameworkControlRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 type riskFrameworkControlRepository struct {
@@ -55,17 +73,25 @@ func NewFrameworkRepository(db *sql.DB) FrameworkRepository {
 	return &frameworkRepository{db: db}
 }
 
+func (r *frameworkRepository) WithTx(tx *sql.Tx) FrameworkRepository {
+	return &frameworkRepository{db: tx}
+}
+
 func NewFrameworkControlRepository(db *sql.DB) FrameworkControlRepository {
 	return &frameworkControlRepository{db: db}
 }
 
+func (r *frameworkControlRepository) WithTx(tx *sql.Tx) FrameworkControlRepository {
+	return &frameworkControlRepository{db: tx}
+}
+
 func NewRiskFrameworkControlRepository(db *sql.DB) RiskFrameworkControlRepository {
 	return &riskFrameworkControlRepository{db: db}
 }
 
 func (r *frameworkRepository) List(ctx context.Context) ([]*models.Framework, error) {
 	query := `
-		SELECT id, name, COALESCE(description, ''), created_at, updated_at
+		SELECT id, name, COALESCE(description, ''), version, previous_version_id, created_at, updated_at
 		FROM frameworks ORDER BY name ASC
 	`
 
@@ -82,6 +108,8 @@ func (r *frameworkRepository) List(ctx context.Context) ([]*models.Framework, er
 			&framework.ID,
 			&framework.Name,
 			&framework.Description,
+			&framework.Version,
+			&framework.PreviousVersionID,
 			&framework.CreatedAt,
 			&framework.UpdatedAt,
 		)
@@ -96,7 +124,7 @@ func (r *frameworkRepository) List(ctx context.Context) ([]*models.Framework, er
 
 func (r *frameworkRepository) GetByID(ctx context.Context, id string) (*models.Framework, error) {
 	query := `
-		SELECT id, name, COALESCE(description, ''), created_at, updated_at
+		SELECT id, name, COALESCE(description, ''), version, previous_version_id, created_at, updated_at
 		FROM frameworks WHERE id = $1
 	`
 
@@ -105,6 +133,8 @@ func (r *frameworkRepository) GetByID(ctx context.Context, id string) (*models.F
 		&framework.ID,
 		&framework.Name,
 		&framework.Description,
+		&framework.Version,
+		&framework.PreviousVersionID,
 		&framework.CreatedAt,
 		&framework.UpdatedAt,
 	)
@@ -121,15 +151,17 @@ func (r *frameworkRepository) GetByID(ctx context.Context, id string) (*models.F
 
 func (r *frameworkRepository) Create(ctx context.Context, input *models.CreateFrameworkInput) (*models.Framework, error) {
 	framework := &models.Framework{
-		ID:          uuid.New().String(),
-		Name:        input.Name,
-		Description: input.Description,
-		CreatedAt:   time.Now(),
+		ID:                uuid.New().String(),
+		Name:              input.Name,
+		Description:       input.Description,
+		Version:           input.Version,
+		PreviousVersionID: input.PreviousVersionID,
+		CreatedAt:         time.Now(),
 	}
 
 	query := `
-		INSERT INTO frameworks (id, name, description, created_at)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO frameworks (id, name, description, version, previous_version_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id, created_at, updated_at
 	`
 
@@ -137,40 +169,55 @@ func (r *frameworkRepository) Create(ctx context.Context, input *models.CreateFr
 		framework.ID,
 		framework.Name,
 		framework.Description,
+		framework.Version,
+		framework.PreviousVersionID,
 		framework.CreatedAt,
 	).Scan(&framework.ID, &framework.CreatedAt, &framework.UpdatedAt)
 
 	if err != nil {
-		return nil, err
+		return nil, frameworkWriteError(err)
 	}
 
 	return framework, nil
 }
 
 func (r *frameworkRepository) Update(ctx context.Context, id string, input *models.UpdateFrameworkInput) (*models.Framework, error) {
-	if input.Name == nil && input.Description == nil {
+	if input.Name == nil && input.Description == nil && input.Version == nil && input.PreviousVersionID == nil {
 		return nil, errors.New("at least one field must be updated")
 	}
 
 	framework := &models.Framework{}
 	query := `
 		UPDATE frameworks
-		SET name = COALESCE($1, name), description = COALESCE($2, description), updated_at = NOW()
-		WHERE id = $3
-		RETURNING id, name, description, created_at, updated_at
+		SET name = COALESCE($1, name), description = COALESCE($2, description), version = COALESCE($3, version),
+			previous_version_id = CASE WHEN $4::text IS NULL THEN previous_version_id ELSE NULLIF($4, '')::uuid END,
+			updated_at = NOW()
+		WHERE id = $5
+		RETURNING id, name, COALESCE(description, ''), version, previous_version_id, created_at, updated_at
 	`
-	err := r.db.QueryRowContext(ctx, query, input.Name, input.Description, id).Scan(
-		&framework.ID, &framework.Name, &framework.Description, &framework.CreatedAt, &framework.UpdatedAt,
+	err := r.db.QueryRowContext(ctx, query, input.Name, input.Description, input.Version, input.PreviousVersionID, id).Scan(
+		&framework.ID, &framework.Name, &framework.Description, &framework.Version, &framework.PreviousVersionID,
+		&framework.CreatedAt, &framework.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrFrameworkNotFound
 		}
-		return nil, err
+		return nil, frameworkWriteError(err)
 	}
 	return framework, nil
 }
 
+// frameworkWriteError maps a previous version that doesn't exist to
+// ErrFrameworkPreviousVersionNotFound
+func frameworkWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == "frameworks_previous_version_id_fkey" {
+		return ErrFrameworkPreviousVersionNotFound
+	}
+	return err
+}
+
 func (r *frameworkRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM frameworks WHERE id = $1`
 	result, err := r.db.ExecContext(ctx, query, id)
@@ -197,10 +244,11 @@ func (r *frameworkControlRepository) List(ctx context.Context, frameworkID, sear
 
 	query := `
 		SELECT fc.id, fc.framework_id, f.name, fc.control_ref, fc.title, COALESCE(fc.description, ''),
-			fc.created_at, fc.updated_at, COUNT(rfc.id) AS linked_risk_count
+			fc.parent_id, fc.sort_order, fc.created_at, fc.updated_at, COUNT(rfc.id) AS linked_risk_count
 		FROM framework_controls fc
 		JOIN frameworks f ON f.id = fc.framework_id
 		LEFT JOIN risk_framework_controls rfc ON rfc.framework_control_id = fc.id
+			AND EXISTS (SELECT 1 FROM risks r WHERE r.id = rfc.risk_id AND r.deleted_at IS NULL)
 		WHERE (NULLIF($1, '') IS NULL OR fc.framework_id = NULLIF($1, '')::uuid)
 		  AND (
 			$2 = '' OR
@@ -208,7 +256,8 @@ func (r *frameworkControlRepository) List(ctx context.Context, frameworkID, sear
 			fc.title ILIKE '%' || $2 || '%' OR
 			COALESCE(fc.description, '') ILIKE '%' || $2 || '%'
 		  )
-		GROUP BY fc.id, fc.framework_id, f.name, fc.control_ref, fc.title, fc.description, fc.created_at, fc.updated_at
+		GROUP BY fc.id, fc.framework_id, f.name, fc.control_ref, fc.title, fc.description, fc.parent_id, fc.sort_order,
+			fc.created_at, fc.updated_at
 		ORDER BY f.name ASC, fc.control_ref ASC
 	`
 
@@ -228,6 +277,8 @@ func (r *frameworkControlRepository) List(ctx context.Context, frameworkID, sear
 			&control.ControlRef,
 			&control.Title,
 			&control.Description,
+			&control.ParentID,
+			&control.SortOrder,
 			&control.CreatedAt,
 			&control.UpdatedAt,
 			&control.LinkedRiskCount,
@@ -243,8 +294,10 @@ func (r *frameworkControlRepository) List(ctx context.Context, frameworkID, sear
 func (r *frameworkControlRepository) GetByID(ctx context.Context, id string) (*models.FrameworkControl, error) {
 	query := `
 		SELECT fc.id, fc.framework_id, f.name, fc.control_ref, fc.title, COALESCE(fc.description, ''),
-			fc.created_at, fc.updated_at,
-			(SELECT COUNT(*) FROM risk_framework_controls rfc WHERE rfc.framework_control_id = fc.id) AS linked_risk_count
+			fc.parent_id, fc.sort_order, fc.created_at, fc.updated_at,
+			(SELECT COUNT(*) FROM risk_framework_controls rfc
+				JOIN risks r ON r.id = rfc.risk_id AND r.deleted_at IS NULL
+				WHERE rfc.framework_control_id = fc.id) AS linked_risk_count
 		FROM framework_controls fc
 		JOIN frameworks f ON f.id = fc.framework_id
 		WHERE fc.id = $1
@@ -258,6 +311,8 @@ func (r *frameworkControlRepository) GetByID(ctx context.Context, id string) (*m
 		&control.ControlRef,
 		&control.Title,
 		&control.Description,
+		&control.ParentID,
+		&control.SortOrder,
 		&control.CreatedAt,
 		&control.UpdatedAt,
 		&control.LinkedRiskCount,
@@ -286,7 +341,7 @@ func (r *frameworkControlRepository) ListLinkedRisks(ctx context.Context, id str
 		JOIN risks r ON r.id = rfc.risk_id
 		LEFT JOIN categories c ON c.id = r.category_id
 		LEFT JOIN users u ON u.id = r.owner_id
-		WHERE rfc.framework_control_id = $1
+		WHERE rfc.framework_control_id = $1 AND r.deleted_at IS NULL
 		ORDER BY r.updated_at DESC, r.title ASC
 	`
 
@@ -323,13 +378,20 @@ func (r *frameworkControlRepository) Create(ctx context.Context, input *models.C
 		ControlRef:  input.ControlRef,
 		Title:       input.Title,
 		Description: input.Description,
+		ParentID:    input.ParentID,
+		SortOrder:   input.SortOrder,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if control.ParentID != nil {
+		if err := r.checkParent(ctx, control.FrameworkID, control.ID, *control.ParentID); err != nil {
+			return nil, err
+		}
+	}
 
 	query := `
-		INSERT INTO framework_controls (id, framework_id, control_ref, title, description, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO framework_controls (id, framework_id, control_ref, title, description, parent_id, sort_order, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	if _, err := r.db.ExecContext(ctx, query,
@@ -338,6 +400,8 @@ func (r *frameworkControlRepository) Create(ctx context.Context, input *models.C
 		control.ControlRef,
 		control.Title,
 		control.Description,
+		control.ParentID,
+		control.SortOrder,
 		control.CreatedAt,
 		control.UpdatedAt,
 	); err != nil {
@@ -348,22 +412,37 @@ func (r *frameworkControlRepository) Create(ctx context.Context, input *models.C
 }
 
 func (r *frameworkControlRepository) Update(ctx context.Context, id string, input *models.UpdateFrameworkControlInput) (*models.FrameworkControl, error) {
-	if input.ControlRef == nil && input.Title == nil && input.Description == nil {
+	if input.ControlRef == nil && input.Title == nil && input.Description == nil && input.ParentID == nil && input.SortOrder == nil {
 		return nil, errors.New("at least one field must be updated")
 	}
+	if input.ParentID != nil && *input.ParentID != "" {
+		current, err := r.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('framework_controls:' || $1))`, current.FrameworkID); err != nil {
+			return nil, err
+		}
+		if err := r.checkParent(ctx, current.FrameworkID, id, *input.ParentID); err != nil {
+			return nil, err
+		}
+	}
 
 	query := `
 		UPDATE framework_controls
 		SET control_ref = COALESCE($1, control_ref),
 			title = COALESCE($2, title),
 			description = COALESCE($3, description),
+			parent_id = CASE WHEN $4::text IS NULL THEN parent_id ELSE NULLIF($4::text, '')::uuid END,
+			sort_order = COALESCE($5, sort_order),
 			updated_at = NOW()
-		WHERE id = $4
+		WHERE id = $6
 		RETURNING id
 	`
 
 	var updatedID string
-	err := r.db.QueryRowContext(ctx, query, input.ControlRef, input.Title, input.Description, id).Scan(&updatedID)
+	err := r.db.QueryRowContext(ctx, query, input.ControlRef, input.Title, input.Description, input.ParentID, input.SortOrder, id).
+		Scan(&updatedID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrFrameworkControlNotFound
@@ -395,6 +474,46 @@ func (r *frameworkControlRepository) Delete(ctx context.Context, id string) erro
 	return nil
 }
 
+// checkParent makes sure parentID is a control of the framework that isn't
+// controlID itself or below it, so that the controls stay a tree
+func (r *frameworkControlRepository) checkParent(ctx context.Context, frameworkID, controlID, parentID string) error {
+	if parentID == controlID || uuid.Validate(parentID) != nil {
+		return ErrFrameworkControlParent
+	}
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM framework_controls WHERE id = $1 AND framework_id = $2
+			UNION
+			SELECT fc.id, fc.parent_id FROM framework_controls fc JOIN ancestors a ON fc.id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $1), EXISTS (SELECT 1 FROM ancestors WHERE id = $3)
+	`
+	var found, cycle bool
+	if err := r.db.QueryRowContext(ctx, query, parentID, frameworkID, controlID).Scan(&found, &cycle); err != nil {
+		return err
+	}
+	if !found || cycle {
+		return ErrFrameworkControlParent
+	}
+	return nil
+}
+
+func (r *frameworkControlRepository) Upsert(ctx context.Context, control *models.FrameworkControl) error {
+	query := `
+		INSERT INTO framework_controls (framework_id, control_ref, title, description, parent_id, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (framework_id, control_ref) DO UPDATE
+		SET title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			parent_id = EXCLUDED.parent_id,
+			sort_order = EXCLUDED.sort_order,
+			updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRowContext(ctx, query, control.FrameworkID, control.ControlRef, control.Title, control.Description,
+		control.ParentID, control.SortOrder).Scan(&control.ID, &control.CreatedAt, &control.UpdatedAt)
+}
+
 func (r *riskFrameworkControlRepository) ListByRiskID(ctx context.Context, riskID string) ([]*models.RiskFrameworkControl, error) {
 	query := `
 		SELECT rfc.id, rfc.risk_id, rfc.framework_control_id,
@@ -527,3 +646,13 @@ func (r *riskFrameworkControlRepository) getByID(ctx context.Context, id string)
 
 	return control, nil
 }
+
+// frameworkName returns the name of a framework, or ErrFrameworkNotFound
+func frameworkName(ctx context.Context, db dbtx, id string) (string, error) {
+	var name string
+	err := db.QueryRowContext(ctx, `SELECT name FROM frameworks WHERE id = $1`, id).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrFrameworkNotFound
+	}
+	return name, err
+}
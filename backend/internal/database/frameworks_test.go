@@ -106,11 +106,63 @@ func TestFrameworkControlRepository_Integration(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, linkedRisks, 0)
 
+	child := &models.FrameworkControl{
+		FrameworkID: framework.ID,
+		ControlRef:  created.ControlRef + "(1)",
+		Title:       "Access reviews",
+		ParentID:    &created.ID,
+		SortOrder:   2,
+	}
+	require.NoError(t, controlRepo.Upsert(ctx, child))
+	childID := child.ID
+
+	child.ID = ""
+	child.Title = "Periodic access reviews"
+	require.NoError(t, controlRepo.Upsert(ctx, child))
+	assert.Equal(t, childID, child.ID)
+
+	fetched, err = controlRepo.GetByID(ctx, childID)
+	require.NoError(t, err)
+	assert.Equal(t, "Periodic access reviews", fetched.Title)
+	require.NotNil(t, fetched.ParentID)
+	assert.Equal(t, created.ID, *fetched.ParentID)
+	assert.Equal(t, 2, fetched.SortOrder)
+
+	_, err = controlRepo.Update(ctx, created.ID, &models.UpdateFrameworkControlInput{ParentID: &childID})
+	assert.ErrorIs(t, err, ErrFrameworkControlParent, "a control can't move below its own child")
+	otherFramework, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "Other Framework " + uuid.New().String()})
+	require.NoError(t, err)
+	_, err = controlRepo.Create(ctx, &models.CreateFrameworkControlInput{
+		FrameworkID: otherFramework.ID, ControlRef: "X-1", Title: "Elsewhere", ParentID: &created.ID,
+	})
+	assert.ErrorIs(t, err, ErrFrameworkControlParent, "parents must be in the same framework")
+
+	tree, err := controlRepo.Tree(ctx, framework.ID)
+	require.NoError(t, err)
+	require.Len(t, tree, 1)
+	assert.Equal(t, created.ID, tree[0].ID)
+	require.Len(t, tree[0].Children, 1)
+	assert.Equal(t, 2, tree[0].ControlCount)
+	_, err = controlRepo.Tree(ctx, uuid.New().String())
+	assert.ErrorIs(t, err, ErrFrameworkNotFound)
+
+	coverage, err := controlRepo.Coverage(ctx, framework.ID)
+	require.NoError(t, err)
+	assert.Equal(t, framework.Name, coverage.FrameworkName)
+	assert.Equal(t, 1, coverage.TotalControls, "the parent is a family heading")
+	assert.Equal(t, 1, coverage.UnlinkedControls)
+	_, err = controlRepo.Coverage(ctx, uuid.New().String())
+	assert.ErrorIs(t, err, ErrFrameworkNotFound)
+
 	err = controlRepo.Delete(ctx, created.ID)
 	require.NoError(t, err)
 
 	_, err = controlRepo.GetByID(ctx, created.ID)
 	assert.ErrorIs(t, err, ErrFrameworkControlNotFound)
+
+	orphan, err := controlRepo.GetByID(ctx, childID)
+	require.NoError(t, err)
+	assert.Nil(t, orphan.ParentID)
 }
 
 func TestRiskFrameworkControlRepository_Integration(t *testing.T) {
@@ -198,3 +250,113 @@ func TestRiskFrameworkControlRepository_Integration(t *testing.T) {
 	err = controlRepo.Delete(ctx, definition.ID)
 	require.NoError(t, err)
 }
+
+func TestBuildControlTree(t *testing.T) {
+	control := func(id, ref, parent string, sortOrder int) *models.FrameworkControl {
+		c := &models.FrameworkControl{ID: id, ControlRef: ref, SortOrder: sortOrder}
+		if parent != "" {
+			c.ParentID = &parent
+		}
+		return c
+	}
+	controls := []*models.FrameworkControl{
+		control("pr", "PR", "", 2),
+		control("id", "ID", "", 1),
+		control("id-am-2", "ID.AM-2", "id-am", 2),
+		control("id-am", "ID.AM", "id", 1),
+		control("id-am-1", "ID.AM-1", "id-am", 1),
+		control("orphan", "ZZ", "missing", 0),
+	}
+	links := map[string][]string{
+		"id-am-1": {"risk-1", "risk-2"},
+		"id-am-2": {"risk-2"},
+		"id":      {"risk-3"},
+	}
+
+	tree := buildControlTree(controls, links)
+	refs := []string{}
+	for _, node := range tree {
+		refs = append(refs, node.ControlRef)
+	}
+	assert.Equal(t, []string{"ZZ", "ID", "PR"}, refs)
+
+	identify := tree[1]
+	assert.Equal(t, 1, identify.LinkedRiskCount)
+	assert.Equal(t, 3, identify.RolledUpRiskCount, "risks linked twice below a node count once")
+	assert.Equal(t, 4, identify.ControlCount)
+	assert.Equal(t, 3, identify.CoveredControlCount)
+
+	assets := identify.Children[0]
+	require.Len(t, assets.Children, 2)
+	assert.Equal(t, "ID.AM-1", assets.Children[0].ControlRef)
+	assert.Equal(t, 2, assets.RolledUpRiskCount)
+	assert.Equal(t, 0, assets.LinkedRiskCount)
+
+	assert.Equal(t, 0, tree[2].RolledUpRiskCount)
+	assert.Empty(t, tree[2].Children)
+}
+
+func TestBuildCoverage(t *testing.T) {
+	control := func(id, ref, parent string) *models.FrameworkControl {
+		c := &models.FrameworkControl{ID: id, ControlRef: ref, Title: "Control " + ref}
+		if parent != "" {
+			c.ParentID = &parent
+		}
+		return c
+	}
+	controls := []*models.FrameworkControl{
+		control("a5", "A.5", ""),
+		control("a5-1", "A.5.1", "a5"),
+		control("a5-2", "A.5.2", "a5"),
+		control("a5-2-1", "A.5.2.1", "a5-2"),
+		control("a6", "A.6", ""),
+		control("a6-1", "A.6.1", "a6"),
+		control("a6-1-1", "A.6.1.1", "a6-1"),
+		control("cc6-1", "CC6.1", ""),
+		control("cc6-2", "CC6.2", ""),
+		control("ac-2", "AC-2", ""),
+	}
+	controls[0].Title = "Organizational controls"
+	statuses := map[string][]models.RiskStatus{
+		"a5":      {models.StatusOpen},
+		"a5-1":    {models.StatusResolved, models.StatusMitigating},
+		"a5-2-1":  {models.StatusAccepted},
+		"a6-1":    {models.StatusOpen},
+		"cc6-1":   {models.StatusResolved},
+		"missing": {models.StatusOpen},
+	}
+
+	coverage := &models.FrameworkCoverage{}
+	buildCoverage(coverage, controls, statuses)
+
+	assert.Equal(t, 7, coverage.TotalControls, "family headings and groups without links of their own aren't counted")
+	assert.Equal(t, 4, coverage.LinkedControls)
+	assert.Equal(t, 3, coverage.UnlinkedControls)
+	assert.Equal(t, 2, coverage.InactiveOnlyControls)
+	assert.Equal(t, 57.1, coverage.CoveragePercent)
+
+	families := map[string]*models.CoverageFamily{}
+	for _, f := range coverage.Families {
+		families[f.Family] = f
+	}
+	require.Len(t, families, 4)
+	assert.Equal(t, &models.CoverageFamily{Family: "A.5", Title: "Organizational controls", Total: 2, Linked: 2,
+		InactiveOnly: 1, CoveragePercent: 100}, families["A.5"])
+	assert.Equal(t, &models.CoverageFamily{Family: "A.6", Title: "Control A.6", Total: 2, Linked: 1, Unlinked: 1,
+		CoveragePercent: 50}, families["A.6"])
+	assert.Equal(t, 2, families["CC6"].Total)
+	assert.Equal(t, 1, families["AC"].Unlinked)
+
+	refs := func(list []*models.CoverageControl) []string {
+		out := []string{}
+		for _, c := range list {
+			out = append(out, c.ControlRef)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"A.6.1.1", "AC-2", "CC6.2"}, refs(coverage.Unlinked))
+	assert.Equal(t, []string{"A.5.2.1", "CC6.1"}, refs(coverage.InactiveOnly))
+	assert.Equal(t, []string{"A.5.1", "A.5.2.1", "A.6.1", "A.6.1.1", "AC-2", "CC6.1", "CC6.2"}, refs(coverage.Controls))
+	assert.Equal(t, 1, coverage.Controls[0].ActiveRiskCount)
+	assert.Equal(t, 2, coverage.Controls[0].LinkedRiskCount)
+}
@@ -20,8 +20,14 @@ type IncidentRepository interface {
 	Create(ctx context.Context, incident *models.Incident) error
 	FindByID(ctx context.Context, id string) (*models.Incident, error)
 	List(ctx context.Context, params *models.IncidentListParams) (*models.IncidentListResponse, error)
+	ListIDs(ctx context.Context, params *models.IncidentListParams) ([]string, error)
 	Update(ctx context.Context, incident *models.Incident) error
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, version int) error
+	ListDeleted(ctx context.Context) ([]*models.Incident, error)
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string, deletedBefore time.Time) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) IncidentRepository
 }
 
 type IncidentRiskRepository interface {
@@ -31,7 +37,7 @@ type IncidentRiskRepository interface {
 }
 
 type incidentRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 type incidentRiskRepository struct {
@@ -42,6 +48,10 @@ func NewIncidentRepository(db *sql.DB) IncidentRepository {
 	return &incidentRepository{db: db}
 }
 
+func (r *incidentRepository) WithTx(tx *sql.Tx) IncidentRepository {
+	return &incidentRepository{db: tx}
+}
+
 func NewIncidentRiskRepository(db *sql.DB) IncidentRiskRepository {
 	return &incidentRiskRepository{db: db}
 }
@@ -70,47 +80,62 @@ func (r *incidentRepository) Create(ctx context.Context, incident *models.Incide
 		incident.DetectedAt = now
 	}
 
+	if incident.Tags == nil {
+		incident.Tags = []string{}
+	}
+
 	query := `
 		INSERT INTO incidents (id, title, description, category_id, priority, status, assignee_id, reporter_id,
 			service_affected, root_cause, resolution_notes, occurred_at, detected_at, resolved_at,
-			created_at, updated_at, created_by, updated_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
-		RETURNING id, created_at, updated_at
+			created_at, updated_at, created_by, updated_by, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING id, created_at, updated_at, version
 	`
 	return r.db.QueryRowContext(ctx, query,
 		incident.ID, incident.Title, incident.Description, incident.CategoryID, incident.Priority, incident.Status,
 		incident.AssigneeID, incident.ReporterID, incident.ServiceAffected, incident.RootCause, incident.ResolutionNotes,
 		incident.OccurredAt, incident.DetectedAt, incident.ResolvedAt,
-		incident.CreatedAt, incident.UpdatedAt, incident.CreatedBy, incident.UpdatedBy,
-	).Scan(&incident.ID, &incident.CreatedAt, &incident.UpdatedAt)
+		incident.CreatedAt, incident.UpdatedAt, incident.CreatedBy, incident.UpdatedBy, tagsValue(incident.Tags),
+	).Scan(&incident.ID, &incident.CreatedAt, &incident.UpdatedAt, &incident.Version)
 }
 
 func (r *incidentRepository) FindByID(ctx context.Context, id string) (*models.Incident, error) {
 	query := `
 		SELECT i.id, i.title, i.description, i.category_id, i.priority, i.status, i.assignee_id, i.reporter_id,
 			i.service_affected, i.root_cause, i.resolution_notes, i.occurred_at, i.detected_at, i.resolved_at,
-			i.created_at, i.updated_at, i.created_by, i.updated_by,
+			i.created_at, i.updated_at, i.created_by, i.updated_by, i.version, i.tags, i.deleted_at,
 			c.id, c.name, c.description
 		FROM incidents i
 		LEFT JOIN incident_categories c ON i.category_id = c.id
-		WHERE i.id = $1
+		WHERE i.id = $1 AND i.deleted_at IS NULL
 	`
+	incident, err := scanIncident(r.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrIncidentNotFound
+		}
+		return nil, err
+	}
+	return incident, nil
+}
+
+// scanIncident scans a row selected with the column list used by FindByID,
+// List and ListDeleted, including the joined category columns.
+func scanIncident(row interface{ Scan(dest ...any) error }) (*models.Incident, error) {
 	incident := &models.Incident{}
 	var catID, catName, catDesc sql.NullString
-	var assigneeID, resolvedAt sql.NullString
+	var assigneeID sql.NullString
+	var resolvedAt, deletedAt sql.NullTime
 	var description, serviceAffected, rootCause, resolutionNotes sql.NullString
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := row.Scan(
 		&incident.ID, &incident.Title, &description, &incident.CategoryID, &incident.Priority, &incident.Status,
 		&assigneeID, &incident.ReporterID, &serviceAffected, &rootCause, &resolutionNotes,
 		&incident.OccurredAt, &incident.DetectedAt, &resolvedAt,
-		&incident.CreatedAt, &incident.UpdatedAt, &incident.CreatedBy, &incident.UpdatedBy,
+		&incident.CreatedAt, &incident.UpdatedAt, &incident.CreatedBy, &incident.UpdatedBy, &incident.Version, tagsColumn{&incident.Tags}, &deletedAt,
 		&catID, &catName, &catDesc,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrIncidentNotFound
-		}
 		return nil, err
 	}
 
@@ -131,10 +156,10 @@ func (r *incidentRepository) FindByID(ctx context.Context, id string) (*models.I
 		incident.AssigneeID = &assigneeID.String
 	}
 	if resolvedAt.Valid {
-		t, err := time.Parse(time.RFC3339, resolvedAt.String)
-		if err == nil {
-			incident.ResolvedAt = &t
-		}
+		incident.ResolvedAt = &resolvedAt.Time
+	}
+	if deletedAt.Valid {
+		incident.DeletedAt = &deletedAt.Time
 	}
 
 	if catID.Valid {
@@ -159,36 +184,8 @@ func (r *incidentRepository) List(ctx context.Context, params *models.IncidentLi
 		params.Limit = 20
 	}
 
-	// Build WHERE clause
-	where := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
-
-	if params.Status != nil {
-		where += fmt.Sprintf(" AND i.status = $%d", argNum)
-		args = append(args, *params.Status)
-		argNum++
-	}
-	if params.Priority != nil {
-		where += fmt.Sprintf(" AND i.priority = $%d", argNum)
-		args = append(args, *params.Priority)
-		argNum++
-	}
-	if params.CategoryID != nil {
-		where += fmt.Sprintf(" AND i.category_id = $%d", argNum)
-		args = append(args, *params.CategoryID)
-		argNum++
-	}
-	if params.AssigneeID != nil {
-		where += fmt.Sprintf(" AND i.assignee_id = $%d", argNum)
-		args = append(args, *params.AssigneeID)
-		argNum++
-	}
-	if params.Search != "" {
-		where += fmt.Sprintf(" AND (i.title ILIKE $%d OR i.description ILIKE $%d OR i.service_affected ILIKE $%d)", argNum, argNum, argNum)
-		args = append(args, "%"+params.Search+"%")
-		argNum++
-	}
+	where, args := incidentListWhere(params)
+	argNum := len(args) + 1
 
 	// Get total count
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM incidents i %s", where)
@@ -220,7 +217,7 @@ func (r *incidentRepository) List(ctx context.Context, params *models.IncidentLi
 	query := fmt.Sprintf(`
 		SELECT i.id, i.title, i.description, i.category_id, i.priority, i.status, i.assignee_id, i.reporter_id,
 			i.service_affected, i.root_cause, i.resolution_notes, i.occurred_at, i.detected_at, i.resolved_at,
-			i.created_at, i.updated_at, i.created_by, i.updated_by,
+			i.created_at, i.updated_at, i.created_by, i.updated_by, i.version, i.tags, i.deleted_at,
 			c.id, c.name, c.description
 		FROM incidents i
 		LEFT JOIN incident_categories c ON i.category_id = c.id
@@ -236,52 +233,10 @@ func (r *incidentRepository) List(ctx context.Context, params *models.IncidentLi
 
 	var incidents []*models.Incident
 	for rows.Next() {
-		incident := &models.Incident{}
-		var catID, catName, catDesc sql.NullString
-		var assigneeID, resolvedAt sql.NullString
-		var description, serviceAffected, rootCause, resolutionNotes sql.NullString
-
-		err := rows.Scan(
-			&incident.ID, &incident.Title, &description, &incident.CategoryID, &incident.Priority, &incident.Status,
-			&assigneeID, &incident.ReporterID, &serviceAffected, &rootCause, &resolutionNotes,
-			&incident.OccurredAt, &incident.DetectedAt, &resolvedAt,
-			&incident.CreatedAt, &incident.UpdatedAt, &incident.CreatedBy, &incident.UpdatedBy,
-			&catID, &catName, &catDesc,
-		)
+		incident, err := scanIncident(rows)
 		if err != nil {
 			return nil, err
 		}
-
-		// Set nullable fields
-		if description.Valid {
-			incident.Description = description.String
-		}
-		if serviceAffected.Valid {
-			incident.ServiceAffected = serviceAffected.String
-		}
-		if rootCause.Valid {
-			incident.RootCause = rootCause.String
-		}
-		if resolutionNotes.Valid {
-			incident.ResolutionNotes = resolutionNotes.String
-		}
-		if assigneeID.Valid {
-			incident.AssigneeID = &assigneeID.String
-		}
-		if resolvedAt.Valid {
-			t, err := time.Parse(time.RFC3339, resolvedAt.String)
-			if err == nil {
-				incident.ResolvedAt = &t
-			}
-		}
-
-		if catID.Valid {
-			incident.Category = &models.IncidentCategory{
-				ID:          catID.String,
-				Name:        catName.String,
-				Description: catDesc.String,
-			}
-		}
 		incidents = append(incidents, incident)
 	}
 
@@ -291,6 +246,69 @@ func (r *incidentRepository) List(ctx context.Context, params *models.IncidentLi
 	}, rows.Err()
 }
 
+// incidentListWhere builds the WHERE clause shared by List and ListIDs.
+// Trashed incidents are never listed.
+func incidentListWhere(params *models.IncidentListParams) (string, []interface{}) {
+	where := "WHERE i.deleted_at IS NULL"
+	args := []interface{}{}
+	argNum := 1
+
+	if params.Status != nil {
+		where += fmt.Sprintf(" AND i.status = $%d", argNum)
+		args = append(args, *params.Status)
+		argNum++
+	}
+	if params.Priority != nil {
+		where += fmt.Sprintf(" AND i.priority = $%d", argNum)
+		args = append(args, *params.Priority)
+		argNum++
+	}
+	if params.CategoryID != nil {
+		where += fmt.Sprintf(" AND i.category_id = $%d", argNum)
+		args = append(args, *params.CategoryID)
+		argNum++
+	}
+	if params.AssigneeID != nil {
+		where += fmt.Sprintf(" AND i.assignee_id = $%d", argNum)
+		args = append(args, *params.AssigneeID)
+		argNum++
+	}
+	if params.Tag != "" {
+		where += fmt.Sprintf(" AND i.tags ? $%d", argNum)
+		args = append(args, params.Tag)
+		argNum++
+	}
+	if params.Search != "" {
+		where += fmt.Sprintf(" AND (i.title ILIKE $%d OR i.description ILIKE $%d OR i.service_affected ILIKE $%d)", argNum, argNum, argNum)
+		args = append(args, "%"+params.Search+"%")
+	}
+
+	return where, args
+}
+
+// ListIDs returns the IDs of every incident matching the filters in params,
+// oldest first. Paging and sorting fields are ignored.
+func (r *incidentRepository) ListIDs(ctx context.Context, params *models.IncidentListParams) ([]string, error) {
+	where, args := incidentListWhere(params)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT i.id FROM incidents i %s ORDER BY i.created_at, i.id", where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Update writes the incident only if its stored version still equals
+// incident.Version, then bumps the version.
 func (r *incidentRepository) Update(ctx context.Context, incident *models.Incident) error {
 	now := time.Now()
 	incident.UpdatedAt = now
@@ -298,19 +316,27 @@ func (r *incidentRepository) Update(ctx context.Context, incident *models.Incide
 	query := `
 		UPDATE incidents SET title = $1, description = $2, category_id = $3, priority = $4, status = $5,
 			assignee_id = $6, service_affected = $7, root_cause = $8, resolution_notes = $9,
-			resolved_at = $10, updated_at = $11, updated_by = $12
-		WHERE id = $13
-		RETURNING updated_at
+			resolved_at = $10, updated_at = $11, updated_by = $12, tags = $13, version = version + 1
+		WHERE id = $14 AND deleted_at IS NULL AND version = $15
+		RETURNING updated_at, version
 	`
-	return r.db.QueryRowContext(ctx, query,
+	err := r.db.QueryRowContext(ctx, query,
 		incident.Title, incident.Description, incident.CategoryID, incident.Priority, incident.Status,
 		incident.AssigneeID, incident.ServiceAffected, incident.RootCause, incident.ResolutionNotes,
-		incident.ResolvedAt, incident.UpdatedAt, incident.UpdatedBy, incident.ID,
-	).Scan(&incident.UpdatedAt)
+		incident.ResolvedAt, incident.UpdatedAt, incident.UpdatedBy, tagsValue(incident.Tags), incident.ID, incident.Version,
+	).Scan(&incident.UpdatedAt, &incident.Version)
+	if err == sql.ErrNoRows {
+		return versionConflictOr(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM incidents WHERE id = $1 AND deleted_at IS NULL)", incident.ID, ErrIncidentNotFound)
+	}
+	return err
 }
 
-func (r *incidentRepository) Delete(ctx context.Context, id string) error {
-	result, err := r.db.ExecContext(ctx, "DELETE FROM incidents WHERE id = $1", id)
+// Delete moves an incident to the trash if it is still at version, keeping
+// its risk links intact. ErrVersionConflict means someone else modified it
+// first.
+func (r *incidentRepository) Delete(ctx context.Context, id string, version int) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE incidents SET deleted_at = NOW(), version = version + 1 WHERE id = $1 AND version = $2 AND deleted_at IS NULL", id, version)
 	if err != nil {
 		return err
 	}
@@ -319,11 +345,53 @@ func (r *incidentRepository) Delete(ctx context.Context, id string) error {
 		return err
 	}
 	if rows == 0 {
-		return ErrIncidentNotFound
+		return versionConflictOr(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM incidents WHERE id = $1 AND deleted_at IS NULL)", id, ErrIncidentNotFound)
 	}
 	return nil
 }
 
+// ListDeleted returns the incidents currently in the trash, most recently deleted first
+func (r *incidentRepository) ListDeleted(ctx context.Context) ([]*models.Incident, error) {
+	query := `
+		SELECT i.id, i.title, i.description, i.category_id, i.priority, i.status, i.assignee_id, i.reporter_id,
+			i.service_affected, i.root_cause, i.resolution_notes, i.occurred_at, i.detected_at, i.resolved_at,
+			i.created_at, i.updated_at, i.created_by, i.updated_by, i.version, i.tags, i.deleted_at,
+			c.id, c.name, c.description
+		FROM incidents i
+		LEFT JOIN incident_categories c ON i.category_id = c.id
+		WHERE i.deleted_at IS NOT NULL
+		ORDER BY i.deleted_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []*models.Incident
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, incident)
+	}
+
+	return incidents, rows.Err()
+}
+
+// Restore takes an incident back out of the trash
+func (r *incidentRepository) Restore(ctx context.Context, id string) error {
+	return restoreFromTrash(ctx, r.db, "incidents", id, ErrIncidentNotFound)
+}
+
+// Purge permanently deletes a trashed incident and its risk links. Only
+// incidents trashed on or before deletedBefore can be purged.
+func (r *incidentRepository) Purge(ctx context.Context, id string, deletedBefore time.Time) error {
+	return purgeFromTrash(ctx, r.db, "incidents", id, deletedBefore, ErrIncidentNotFound)
+}
+
 // IncidentRiskRepository methods
 
 func (r *incidentRiskRepository) ListByIncident(ctx context.Context, incidentID string) ([]*models.IncidentRisk, error) {
@@ -332,7 +400,7 @@ func (r *incidentRiskRepository) ListByIncident(ctx context.Context, incidentID
 			r.id, r.title, r.description, r.status, r.severity
 		FROM incident_risks ir
 		JOIN risks r ON ir.risk_id = r.id
-		WHERE ir.incident_id = $1
+		WHERE ir.incident_id = $1 AND r.deleted_at IS NULL
 		ORDER BY ir.created_at DESC
 	`
 
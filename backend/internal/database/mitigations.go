@@ -28,17 +28,23 @@ type MitigationRepository interface {
 	FindByID(ctx context.Context, id string) (*models.Mitigation, error)
 	ListByRiskID(ctx context.Context, riskID string) ([]*models.Mitigation, error)
 	Update(ctx context.Context, id string, input *models.UpdateMitigationInput, updatedBy string) (*models.Mitigation, error)
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, version int) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) MitigationRepository
 }
 
 type mitigationRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewMitigationRepository(db *sql.DB) MitigationRepository {
 	return &mitigationRepository{db: db}
 }
 
+func (r *mitigationRepository) WithTx(tx *sql.Tx) MitigationRepository {
+	return &mitigationRepository{db: tx}
+}
+
 func (r *mitigationRepository) Create(ctx context.Context, input *models.CreateMitigationInput, createdBy string) (*models.Mitigation, error) {
 	mitigation := &models.Mitigation{
 		ID:          uuid.New().String(),
@@ -63,10 +69,13 @@ func (r *mitigationRepository) Create(ctx context.Context, input *models.CreateM
 	mitigation.CreatedAt = now
 	mitigation.UpdatedAt = now
 
+	// Risks in the trash don't take new mitigations
 	query := `
 		INSERT INTO mitigations (id, risk_id, description, owner, status, due_date, created_at, updated_at, created_by, updated_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id, created_at, updated_at
+		SELECT $1, r.id, $3, $4, $5, $6, $7, $8, $9, $10
+		FROM risks r
+		WHERE r.id = $2 AND r.deleted_at IS NULL
+		RETURNING id, created_at, updated_at, version
 	`
 
 	err := r.db.QueryRowContext(ctx, query,
@@ -80,19 +89,26 @@ func (r *mitigationRepository) Create(ctx context.Context, input *models.CreateM
 		mitigation.UpdatedAt,
 		mitigation.CreatedBy,
 		mitigation.UpdatedBy,
-	).Scan(&mitigation.ID, &mitigation.CreatedAt, &mitigation.UpdatedAt)
+	).Scan(&mitigation.ID, &mitigation.CreatedAt, &mitigation.UpdatedAt, &mitigation.Version)
 
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRiskNotFound
+		}
 		return nil, err
 	}
 
 	return mitigation, nil
 }
 
+// FindByID returns a mitigation of a risk that isn't in the trash
 func (r *mitigationRepository) FindByID(ctx context.Context, id string) (*models.Mitigation, error) {
 	query := `
-		SELECT id, risk_id, description, owner, status, due_date, created_at, updated_at, created_by, updated_by
-		FROM mitigations WHERE id = $1
+		SELECT m.id, m.risk_id, m.description, m.owner, m.status, m.due_date, m.created_at, m.updated_at,
+			m.created_by, m.updated_by, m.version
+		FROM mitigations m
+		JOIN risks r ON r.id = m.risk_id AND r.deleted_at IS NULL
+		WHERE m.id = $1
 	`
 
 	mitigation := &models.Mitigation{}
@@ -107,6 +123,7 @@ func (r *mitigationRepository) FindByID(ctx context.Context, id string) (*models
 		&mitigation.UpdatedAt,
 		&mitigation.CreatedBy,
 		&mitigation.UpdatedBy,
+		&mitigation.Version,
 	)
 
 	if err != nil {
@@ -119,9 +136,20 @@ func (r *mitigationRepository) FindByID(ctx context.Context, id string) (*models
 	return mitigation, nil
 }
 
+// ListByRiskID returns a risk's mitigations, or ErrRiskNotFound when the risk
+// doesn't exist or is in the trash
 func (r *mitigationRepository) ListByRiskID(ctx context.Context, riskID string) ([]*models.Mitigation, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM risks WHERE id = $1 AND deleted_at IS NULL)", riskID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRiskNotFound
+	}
+
 	query := `
-		SELECT id, risk_id, description, owner, status, due_date, created_at, updated_at, created_by, updated_by
+		SELECT id, risk_id, description, owner, status, due_date, created_at, updated_at, created_by, updated_by, version
 		FROM mitigations WHERE risk_id = $1 ORDER BY created_at DESC
 	`
 
@@ -145,6 +173,7 @@ func (r *mitigationRepository) ListByRiskID(ctx context.Context, riskID string)
 			&mitigation.UpdatedAt,
 			&mitigation.CreatedBy,
 			&mitigation.UpdatedBy,
+			&mitigation.Version,
 		)
 		if err != nil {
 			return nil, err
@@ -161,6 +190,9 @@ func (r *mitigationRepository) Update(ctx context.Context, id string, input *mod
 	if err != nil {
 		return nil, err
 	}
+	if input.Version != nil && *input.Version != mitigation.Version {
+		return nil, ErrVersionConflict
+	}
 
 	// Apply updates
 	if input.Description != nil {
@@ -189,9 +221,11 @@ func (r *mitigationRepository) Update(ctx context.Context, id string, input *mod
 	mitigation.UpdatedAt = now
 
 	query := `
-		UPDATE mitigations SET description = $1, owner = $2, status = $3, due_date = $4, updated_at = $5, updated_by = $6
-		WHERE id = $7
-		RETURNING updated_at
+		UPDATE mitigations SET description = $1, owner = $2, status = $3, due_date = $4, updated_at = $5, updated_by = $6,
+			version = version + 1
+		WHERE id = $7 AND version = $8
+		  AND EXISTS (SELECT 1 FROM risks r WHERE r.id = mitigations.risk_id AND r.deleted_at IS NULL)
+		RETURNING updated_at, version
 	`
 
 	err = r.db.QueryRowContext(ctx, query,
@@ -202,11 +236,12 @@ func (r *mitigationRepository) Update(ctx context.Context, id string, input *mod
 		mitigation.UpdatedAt,
 		mitigation.UpdatedBy,
 		mitigation.ID,
-	).Scan(&mitigation.UpdatedAt)
+		mitigation.Version,
+	).Scan(&mitigation.UpdatedAt, &mitigation.Version)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrMitigationNotFound
+			return nil, versionConflictOr(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM mitigations m JOIN risks r ON r.id = m.risk_id AND r.deleted_at IS NULL WHERE m.id = $1)", id, ErrMitigationNotFound)
 		}
 		return nil, err
 	}
@@ -214,8 +249,14 @@ func (r *mitigationRepository) Update(ctx context.Context, id string, input *mod
 	return mitigation, nil
 }
 
-func (r *mitigationRepository) Delete(ctx context.Context, id string) error {
-	result, err := r.db.ExecContext(ctx, "DELETE FROM mitigations WHERE id = $1", id)
+// Delete removes a mitigation if it is still at version. ErrVersionConflict
+// means someone else modified it first.
+func (r *mitigationRepository) Delete(ctx context.Context, id string, version int) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM mitigations
+		WHERE id = $1 AND version = $2
+		  AND EXISTS (SELECT 1 FROM risks r WHERE r.id = mitigations.risk_id AND r.deleted_at IS NULL)
+	`, id, version)
 	if err != nil {
 		return err
 	}
@@ -226,7 +267,7 @@ func (r *mitigationRepository) Delete(ctx context.Context, id string) error {
 	}
 
 	if rows == 0 {
-		return ErrMitigationNotFound
+		return versionConflictOr(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM mitigations m JOIN risks r ON r.id = m.risk_id AND r.deleted_at IS NULL WHERE m.id = $1)", id, ErrMitigationNotFound)
 	}
 
 	return nil
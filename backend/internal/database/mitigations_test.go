@@ -88,10 +88,34 @@ func TestMitigationRepository_Integration(t *testing.T) {
 	assert.GreaterOrEqual(t, len(list), 1)
 
 	// 6. Delete Mitigation
-	err = mitigationRepo.Delete(ctx, mitigation.ID)
+	assert.ErrorIs(t, mitigationRepo.Delete(ctx, mitigation.ID, mitigation.Version), ErrVersionConflict)
+	err = mitigationRepo.Delete(ctx, mitigation.ID, updated.Version)
 	require.NoError(t, err)
 
 	fetched, err = mitigationRepo.FindByID(ctx, mitigation.ID)
 	assert.Error(t, err)
 	assert.Equal(t, ErrMitigationNotFound, err)
+
+	// 7. A trashed risk's mitigations are hidden and can't be written
+	kept, err := mitigationRepo.Create(ctx, &models.CreateMitigationInput{RiskID: risk.ID, Description: "Kept", Owner: "IT",
+		Status: models.MitigationStatusPlanned}, user.ID)
+	require.NoError(t, err)
+	current, err := riskRepo.FindByID(ctx, risk.ID)
+	require.NoError(t, err)
+	require.NoError(t, riskRepo.Delete(ctx, risk.ID, current.Version))
+
+	_, err = mitigationRepo.ListByRiskID(ctx, risk.ID)
+	assert.ErrorIs(t, err, ErrRiskNotFound)
+	_, err = mitigationRepo.FindByID(ctx, kept.ID)
+	assert.ErrorIs(t, err, ErrMitigationNotFound)
+	_, err = mitigationRepo.Create(ctx, &models.CreateMitigationInput{RiskID: risk.ID, Description: "New", Owner: "IT",
+		Status: models.MitigationStatusPlanned}, user.ID)
+	assert.ErrorIs(t, err, ErrRiskNotFound)
+	assert.ErrorIs(t, mitigationRepo.Delete(ctx, kept.ID, kept.Version), ErrMitigationNotFound)
+
+	require.NoError(t, riskRepo.Restore(ctx, risk.ID))
+	restored, err := mitigationRepo.ListByRiskID(ctx, risk.ID)
+	require.NoError(t, err)
+	require.Len(t, restored, 1)
+	assert.Equal(t, kept.ID, restored[0].ID)
 }
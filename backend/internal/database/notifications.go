@@ -0,0 +1,390 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/internal/models"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type NotificationRepository interface {
+	// Create stores a notification. With a dedupe key it is only stored if
+	// the user has no notification with that key yet; created reports which.
+	Create(ctx context.Context, n *models.Notification, dedupeKey string) (created bool, err error)
+	// List returns the user's inbox, newest first
+	List(ctx context.Context, userID string, params *models.NotificationListParams) (*models.NotificationListResponse, error)
+	MarkRead(ctx context.Context, userID, id string, at time.Time) error
+	// MarkAllRead marks every unread inbox entry read and returns how many
+	MarkAllRead(ctx context.Context, userID string, at time.Time) (int, error)
+	// Preferences returns the events the user has chosen channels for;
+	// events missing from the map use the defaults
+	Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error)
+	SetPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error
+	// DueReviews returns open risks whose review date is on or before today
+	// and whose owner hasn't been told about that date yet
+	DueReviews(ctx context.Context, today time.Time) ([]*models.NotificationDeadline, error)
+	// DueMitigations returns planned and in-progress mitigations due on or
+	// before before that haven't been notified for that date yet
+	DueMitigations(ctx context.Context, before time.Time) ([]*models.NotificationDeadline, error)
+	// DueEvidenceRequests returns open, assigned evidence requests due on or
+	// before before that haven't been notified for that date yet. Requests
+	// due before today are raised again once they are overdue.
+	DueEvidenceRequests(ctx context.Context, today, before time.Time) ([]*models.NotificationDeadline, error)
+	// ClaimEmails returns up to limit pending emails due at now and pushes
+	// their next attempt out to leaseUntil so other workers skip them
+	ClaimEmails(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.NotificationEmail, error)
+	// RecordEmail stores the outcome of a send. Pending emails need a
+	// nextAttempt to be picked up again.
+	RecordEmail(ctx context.Context, id string, status models.NotificationEmailStatus, nextAttempt *time.Time, sendErr *string) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) NotificationRepository
+}
+
+type notificationRepository struct {
+	db dbtx
+}
+
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) WithTx(tx *sql.Tx) NotificationRepository {
+	return &notificationRepository{db: tx}
+}
+
+const notificationColumns = `n.id, n.user_id, n.event, n.entity_type, n.entity_id, n.title, n.body, n.link, n.actor_id,
+	n.read_at, n.created_at, n.in_app, n.email_status IS NOT NULL`
+
+func scanNotification(row interface{ Scan(...any) error }, extra ...any) (*models.Notification, error) {
+	n := &models.Notification{}
+	dest := append([]any{&n.ID, &n.UserID, &n.Event, &n.EntityType, &n.EntityID, &n.Title, &n.Body, &n.Link, &n.ActorID,
+		&n.ReadAt, &n.CreatedAt, &n.InApp, &n.Email}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (r *notificationRepository) Create(ctx context.Context, n *models.Notification, dedupeKey string) (bool, error) {
+	var key, emailStatus *string
+	if dedupeKey != "" {
+		key = &dedupeKey
+	}
+	if n.Email {
+		status := string(models.NotificationEmailPending)
+		emailStatus = &status
+	}
+	query := `
+		INSERT INTO notifications (user_id, event, entity_type, entity_id, title, body, link, actor_id, in_app, dedupe_key,
+		                           email_status, email_next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, CASE WHEN $11::notification_email_status IS NULL THEN NULL ELSE NOW() END)
+		ON CONFLICT (user_id, dedupe_key) WHERE dedupe_key IS NOT NULL DO NOTHING
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowContext(ctx, query, n.UserID, n.Event, n.EntityType, n.EntityID, n.Title, n.Body, n.Link, n.ActorID,
+		n.InApp, key, emailStatus).Scan(&n.ID, &n.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *notificationRepository) List(ctx context.Context, userID string, params *models.NotificationListParams) (*models.NotificationListResponse, error) {
+	resp := &models.NotificationListResponse{Notifications: []*models.Notification{}, Limit: params.Limit, Offset: params.Offset}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE read_at IS NULL)
+		FROM notifications
+		WHERE user_id = $1 AND in_app
+	`, userID).Scan(&resp.Total, &resp.Unread)
+	if err != nil {
+		return nil, err
+	}
+	if params.UnreadOnly {
+		resp.Total = resp.Unread
+	}
+
+	query := `
+		SELECT ` + notificationColumns + `
+		FROM notifications n
+		WHERE n.user_id = $1 AND n.in_app AND (NOT $2 OR n.read_at IS NULL)
+		ORDER BY n.created_at DESC, n.id
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.QueryContext(ctx, query, userID, params.UnreadOnly, params.Limit, params.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		resp.Notifications = append(resp.Notifications, n)
+	}
+	return resp, rows.Err()
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id string, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = COALESCE(read_at, $3)
+		WHERE id = $1 AND user_id = $2 AND in_app
+	`, id, userID, at)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID string, at time.Time) (int, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notifications SET read_at = $2
+		WHERE user_id = $1 AND in_app AND read_at IS NULL
+	`, userID, at)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := result.RowsAffected()
+	return int(rows), err
+}
+
+func (r *notificationRepository) Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT event, channels FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := models.NotificationPreferences{}
+	for rows.Next() {
+		var event models.NotificationEvent
+		var channels []byte
+		if err := rows.Scan(&event, &channels); err != nil {
+			return nil, err
+		}
+		var list []models.NotificationChannel
+		if err := json.Unmarshal(channels, &list); err != nil {
+			return nil, err
+		}
+		if list == nil {
+			list = []models.NotificationChannel{}
+		}
+		prefs[event] = list
+	}
+	return prefs, rows.Err()
+}
+
+func (r *notificationRepository) SetPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error {
+	for event, channels := range prefs {
+		if channels == nil {
+			channels = []models.NotificationChannel{}
+		}
+		encoded, err := json.Marshal(channels)
+		if err != nil {
+			return err
+		}
+		_, err = r.db.ExecContext(ctx, `
+			INSERT INTO notification_preferences (user_id, event, channels)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, event) DO UPDATE SET channels = EXCLUDED.channels, updated_at = NOW()
+		`, userID, event, string(encoded))
+		if err != nil {
+			return fmt.Errorf("saving %s preference: %w", event, err)
+		}
+	}
+	return nil
+}
+
+// ReviewDedupeKey, MitigationDueDedupeKey and EvidenceDueDedupeKey identify a deadline notification
+// by the date it is for, so moving the date raises a new one
+func ReviewDedupeKey(riskID string, due time.Time) string {
+	return string(models.NotificationRiskReviewDue) + ":" + riskID + ":" + due.Format(time.DateOnly)
+}
+
+func MitigationDueDedupeKey(mitigationID string, due time.Time) string {
+	return string(models.NotificationMitigationDue) + ":" + mitigationID + ":" + due.UTC().Format(time.DateOnly)
+}
+
+// EvidenceDueDedupeKey tells the reminder before a due date apart from the
+// one once it is missed
+func EvidenceDueDedupeKey(requestID string, due time.Time, overdue bool) string {
+	key := string(models.NotificationEvidenceDue) + ":" + requestID + ":" + due.Format(time.DateOnly)
+	if overdue {
+		key += ":overdue"
+	}
+	return key
+}
+
+func (r *notificationRepository) DueReviews(ctx context.Context, today time.Time) ([]*models.NotificationDeadline, error) {
+	query := `
+		SELECT r.id, r.title, r.review_date, r.owner_id
+		FROM risks r
+		WHERE r.deleted_at IS NULL AND r.status IN ('open', 'mitigating') AND r.review_date <= $1::date
+		  AND NOT EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = r.owner_id AND n.dedupe_key = 'risk.review_due:' || r.id || ':' || to_char(r.review_date, 'YYYY-MM-DD')
+		  )
+		ORDER BY r.review_date, r.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, today.Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadlines := []*models.NotificationDeadline{}
+	for rows.Next() {
+		d := &models.NotificationDeadline{Event: models.NotificationRiskReviewDue, EntityType: "risk"}
+		var ownerID string
+		if err := rows.Scan(&d.EntityID, &d.Title, &d.Due, &ownerID); err != nil {
+			return nil, err
+		}
+		d.RiskID = d.EntityID
+		d.UserIDs = []string{ownerID}
+		deadlines = append(deadlines, d)
+	}
+	return deadlines, rows.Err()
+}
+
+func (r *notificationRepository) DueMitigations(ctx context.Context, before time.Time) ([]*models.NotificationDeadline, error) {
+	// A mitigation's owner is free text; when it is a user's email address
+	// that user is told as well as the risk's owner
+	query := `
+		SELECT m.id, m.risk_id, r.title, m.description, m.due_date, r.owner_id, u.id
+		FROM mitigations m
+		JOIN risks r ON r.id = m.risk_id
+		LEFT JOIN users u ON LOWER(u.email) = LOWER(m.owner) AND u.id <> r.owner_id
+		WHERE r.deleted_at IS NULL AND m.status IN ('planned', 'in_progress') AND m.due_date <= $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = r.owner_id
+			  AND n.dedupe_key = 'mitigation.due:' || m.id || ':' || to_char(m.due_date AT TIME ZONE 'UTC', 'YYYY-MM-DD')
+		  )
+		ORDER BY m.due_date, m.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadlines := []*models.NotificationDeadline{}
+	for rows.Next() {
+		d := &models.NotificationDeadline{Event: models.NotificationMitigationDue, EntityType: "mitigation"}
+		var riskTitle, description, ownerID string
+		var assigneeID *string
+		if err := rows.Scan(&d.EntityID, &d.RiskID, &riskTitle, &description, &d.Due, &ownerID, &assigneeID); err != nil {
+			return nil, err
+		}
+		d.Title = riskTitle
+		if description != "" {
+			d.Title = description
+		}
+		d.UserIDs = []string{ownerID}
+		if assigneeID != nil {
+			d.UserIDs = append(d.UserIDs, *assigneeID)
+		}
+		deadlines = append(deadlines, d)
+	}
+	return deadlines, rows.Err()
+}
+
+func (r *notificationRepository) ClaimEmails(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.NotificationEmail, error) {
+	query := `
+		UPDATE notifications n
+		SET email_next_attempt_at = $2
+		FROM users u
+		WHERE u.id = n.user_id AND n.id IN (
+			SELECT q.id
+			FROM notifications q
+			WHERE q.email_status = 'pending' AND q.email_next_attempt_at <= $1
+			ORDER BY q.email_next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + notificationColumns + `, u.email, n.email_attempts
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []*models.NotificationEmail{}
+	for rows.Next() {
+		email := &models.NotificationEmail{}
+		email.Notification, err = scanNotification(rows, &email.To, &email.Attempts)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+func (r *notificationRepository) RecordEmail(ctx context.Context, id string, status models.NotificationEmailStatus, nextAttempt *time.Time, sendErr *string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE notifications
+		SET email_status = $2, email_attempts = email_attempts + 1, email_next_attempt_at = $3, email_error = $4
+		WHERE id = $1
+	`, id, status, nextAttempt, sendErr)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func (r *notificationRepository) DueEvidenceRequests(ctx context.Context, today, before time.Time) ([]*models.NotificationDeadline, error) {
+	query := `
+		SELECT e.id, fc.control_ref, e.title, e.due_date, e.assignee_id
+		FROM evidence_requests e
+		JOIN framework_controls fc ON fc.id = e.framework_control_id
+		WHERE e.status = 'open' AND e.assignee_id IS NOT NULL AND e.due_date <= $2::date
+		  AND NOT EXISTS (
+			SELECT 1 FROM notifications n
+			WHERE n.user_id = e.assignee_id
+			  AND n.dedupe_key = 'evidence.due:' || e.id || ':' || to_char(e.due_date, 'YYYY-MM-DD')
+				|| CASE WHEN e.due_date < $1::date THEN ':overdue' ELSE '' END
+		  )
+		ORDER BY e.due_date, e.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, today.UTC().Format(time.DateOnly), before.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deadlines := []*models.NotificationDeadline{}
+	for rows.Next() {
+		d := &models.NotificationDeadline{Event: models.NotificationEvidenceDue, EntityType: "evidence_request"}
+		var controlRef, title, assigneeID string
+		if err := rows.Scan(&d.EntityID, &controlRef, &title, &d.Due, &assigneeID); err != nil {
+			return nil, err
+		}
+		d.Title = controlRef + " " + title
+		d.UserIDs = []string{assigneeID}
+		deadlines = append(deadlines, d)
+	}
+	return deadlines, rows.Err()
+}
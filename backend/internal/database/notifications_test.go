@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewNotificationRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	riskRepo := NewRiskRepository(s.db)
+	mitigationRepo := NewMitigationRepository(s.db)
+	ctx := context.Background()
+
+	newUser := func(name string) *models.User {
+		user := &models.User{
+			ID:           uuid.New().String(),
+			Email:        "test-notifications-" + uuid.New().String() + "@example.com",
+			PasswordHash: "hash",
+			Name:         name,
+			Role:         models.RoleMember,
+		}
+		require.NoError(t, userRepo.Create(ctx, user))
+		return user
+	}
+	owner, engineer := newUser("Owner"), newUser("Engineer")
+
+	t.Run("inbox, read and read all", func(t *testing.T) {
+		for _, title := range []string{"first", "second"} {
+			n := &models.Notification{UserID: owner.ID, Event: models.NotificationRiskUpdated, EntityType: "risk",
+				EntityID: uuid.New().String(), Title: title, InApp: true}
+			created, err := repo.Create(ctx, n, "")
+			require.NoError(t, err)
+			assert.True(t, created)
+		}
+		emailOnly := &models.Notification{UserID: owner.ID, Event: models.NotificationRiskAssigned, EntityType: "risk",
+			EntityID: uuid.New().String(), Title: "hidden", Email: true}
+		_, err := repo.Create(ctx, emailOnly, "")
+		require.NoError(t, err)
+
+		inbox, err := repo.List(ctx, owner.ID, &models.NotificationListParams{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, inbox.Notifications, 2, "email-only notifications stay out of the inbox")
+		assert.Equal(t, "second", inbox.Notifications[0].Title)
+		assert.Equal(t, 2, inbox.Unread)
+
+		require.NoError(t, repo.MarkRead(ctx, owner.ID, inbox.Notifications[0].ID, time.Now()))
+		assert.ErrorIs(t, repo.MarkRead(ctx, engineer.ID, inbox.Notifications[1].ID, time.Now()), ErrNotificationNotFound)
+
+		unread, err := repo.List(ctx, owner.ID, &models.NotificationListParams{UnreadOnly: true, Limit: 10})
+		require.NoError(t, err)
+		assert.Equal(t, 1, unread.Total)
+		assert.Equal(t, "first", unread.Notifications[0].Title)
+
+		marked, err := repo.MarkAllRead(ctx, owner.ID, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, 1, marked)
+	})
+
+	t.Run("preferences", func(t *testing.T) {
+		require.NoError(t, repo.SetPreferences(ctx, engineer.ID, models.NotificationPreferences{
+			models.NotificationRiskUpdated:   {},
+			models.NotificationMitigationDue: {models.NotificationChannelEmail},
+		}))
+		require.NoError(t, repo.SetPreferences(ctx, engineer.ID, models.NotificationPreferences{
+			models.NotificationRiskUpdated: {models.NotificationChannelInApp},
+		}))
+		prefs, err := repo.Preferences(ctx, engineer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.NotificationPreferences{
+			models.NotificationRiskUpdated:   {models.NotificationChannelInApp},
+			models.NotificationMitigationDue: {models.NotificationChannelEmail},
+		}, prefs)
+	})
+
+	t.Run("deadlines are raised once per date", func(t *testing.T) {
+		reviewDate := time.Now().AddDate(0, 0, -1)
+		risk := &models.Risk{Title: "Overdue review", OwnerID: owner.ID, Status: models.StatusOpen, Severity: models.SeverityHigh,
+			ReviewDate: &reviewDate, CreatedBy: owner.ID, UpdatedBy: owner.ID}
+		require.NoError(t, riskRepo.Create(ctx, risk))
+		due := time.Now().Add(24 * time.Hour).Format(time.RFC3339)
+		mitigation, err := mitigationRepo.Create(ctx, &models.CreateMitigationInput{RiskID: risk.ID, Description: "Patch servers",
+			Owner: engineer.Email, Status: models.MitigationStatusPlanned, DueDate: &due}, owner.ID)
+		require.NoError(t, err)
+
+		findReview := func() *models.NotificationDeadline {
+			reviews, err := repo.DueReviews(ctx, time.Now())
+			require.NoError(t, err)
+			for _, d := range reviews {
+				if d.EntityID == risk.ID {
+					return d
+				}
+			}
+			return nil
+		}
+		review := findReview()
+		require.NotNil(t, review)
+		assert.Equal(t, []string{owner.ID}, review.UserIDs)
+
+		mitigations, err := repo.DueMitigations(ctx, time.Now().Add(72*time.Hour))
+		require.NoError(t, err)
+		var dueMitigation *models.NotificationDeadline
+		for _, d := range mitigations {
+			if d.EntityID == mitigation.ID {
+				dueMitigation = d
+			}
+		}
+		require.NotNil(t, dueMitigation)
+		assert.Equal(t, []string{owner.ID, engineer.ID}, dueMitigation.UserIDs, "a mitigation owner matching a user's email is told too")
+
+		key := ReviewDedupeKey(risk.ID, review.Due)
+		n := &models.Notification{UserID: owner.ID, Event: models.NotificationRiskReviewDue, EntityType: "risk", EntityID: risk.ID, Title: "Review due"}
+		created, err := repo.Create(ctx, n, key)
+		require.NoError(t, err)
+		assert.True(t, created)
+		created, err = repo.Create(ctx, n, key)
+		require.NoError(t, err)
+		assert.False(t, created)
+		assert.Nil(t, findReview())
+	})
+
+	t.Run("claim and record emails", func(t *testing.T) {
+		n := &models.Notification{UserID: engineer.ID, Event: models.NotificationRiskAssigned, EntityType: "risk",
+			EntityID: uuid.New().String(), Title: "Email me", InApp: true, Email: true}
+		_, err := repo.Create(ctx, n, "")
+		require.NoError(t, err)
+
+		now := time.Now().Add(time.Minute)
+		claim := func() *models.NotificationEmail {
+			emails, err := repo.ClaimEmails(ctx, now, now.Add(time.Minute), 1000)
+			require.NoError(t, err)
+			for _, e := range emails {
+				if e.Notification.ID == n.ID {
+					return e
+				}
+			}
+			return nil
+		}
+		email := claim()
+		require.NotNil(t, email)
+		assert.Equal(t, engineer.Email, email.To)
+		assert.Nil(t, claim(), "a leased email must not be claimed again")
+
+		require.NoError(t, repo.RecordEmail(ctx, n.ID, models.NotificationEmailSent, nil, nil))
+		assert.ErrorIs(t, repo.RecordEmail(ctx, uuid.New().String(), models.NotificationEmailSent, nil, nil), ErrNotificationNotFound)
+	})
+}
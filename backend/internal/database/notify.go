@@ -0,0 +1,31 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Notifier sends Postgres NOTIFY messages. Inside a transaction they are
+// only delivered to listeners if it commits.
+type Notifier interface {
+	Notify(ctx context.Context, channel, payload string) error
+	// WithTx returns a copy of the notifier that runs inside tx
+	WithTx(tx *sql.Tx) Notifier
+}
+
+type notifier struct {
+	db dbtx
+}
+
+func NewNotifier(db *sql.DB) Notifier {
+	return &notifier{db: db}
+}
+
+func (n *notifier) WithTx(tx *sql.Tx) Notifier {
+	return &notifier{db: tx}
+}
+
+func (n *notifier) Notify(ctx context.Context, channel, payload string) error {
+	_, err := n.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return err
+}
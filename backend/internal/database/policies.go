@@ -0,0 +1,521 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrPolicyNotFound           = errors.New("policy not found")
+	ErrPolicyOwnerNotFound      = errors.New("owner not found")
+	ErrPolicyVersionNotFound    = errors.New("policy version not found")
+	ErrPolicyVersionOpen        = errors.New("policy already has a version in draft or awaiting approval")
+	ErrPolicyVersionSubmitted   = errors.New("policy version has already been submitted")
+	ErrPolicyVersionNotPending  = errors.New("policy version is not awaiting approval")
+	ErrPolicyAttachmentNotFound = errors.New("attachment not found on this policy")
+	ErrPolicyControlNotLinked   = errors.New("control is not linked to this policy")
+	ErrPolicySelfDecision       = errors.New("a policy version must be approved or rejected by someone other than its author")
+)
+
+type PolicyRepository interface {
+	// List returns policies ordered by title, each with the version in force
+	List(ctx context.Context, filter models.PolicyFilter) ([]*models.Policy, error)
+	// GetByID returns a policy with its linked controls
+	GetByID(ctx context.Context, id string) (*models.Policy, error)
+	// Create stores the policy and sets its ID and timestamps
+	Create(ctx context.Context, policy *models.Policy) error
+	// Update saves the editable fields and next review date of the policy
+	Update(ctx context.Context, policy *models.Policy) error
+	// Delete removes a policy with its versions, control links and
+	// acknowledgements
+	Delete(ctx context.Context, id string) error
+
+	// ListVersions returns a policy's versions, newest first, without their
+	// bodies
+	ListVersions(ctx context.Context, policyID string) ([]*models.PolicyVersion, error)
+	GetVersion(ctx context.Context, id string) (*models.PolicyVersion, error)
+	// CreateVersion stores a draft with the policy's next number and sets
+	// its ID, Version, Status and timestamps. The attachment, if any, has to
+	// belong to the policy.
+	CreateVersion(ctx context.Context, version *models.PolicyVersion) error
+	// UpdateVersion saves the body, attachment and change summary of a draft
+	UpdateVersion(ctx context.Context, version *models.PolicyVersion) error
+	// DeleteVersion discards a draft
+	DeleteVersion(ctx context.Context, id string) error
+	// SubmitVersion sends a draft for approval
+	SubmitVersion(ctx context.Context, id string) error
+	// DecideVersion approves or rejects a pending version.
+	// ErrPolicySelfDecision means userID created it.
+	DecideVersion(ctx context.Context, id string, status models.PolicyVersionStatus, comment, userID string) error
+
+	// LinkControl records that the policy implements a control. It reports
+	// false when the link already existed.
+	LinkControl(ctx context.Context, policyID, controlID, userID string) (bool, error)
+	UnlinkControl(ctx context.Context, policyID, controlID string) error
+
+	// Acknowledge records that a user has accepted a version. It reports
+	// false when they already had.
+	Acknowledge(ctx context.Context, versionID, userID string) (bool, error)
+	// ListAcknowledgements returns every user ordered by name, with when
+	// they accepted the version if they have
+	ListAcknowledgements(ctx context.Context, versionID string) ([]*models.PolicyAcknowledgement, error)
+
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) PolicyRepository
+}
+
+type policyRepository struct {
+	db dbtx
+}
+
+func NewPolicyRepository(db *sql.DB) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+func (r *policyRepository) WithTx(tx *sql.Tx) PolicyRepository {
+	return &policyRepository{db: tx}
+}
+
+// policySelect embeds the latest approved version, which is the one in
+// force, with its acknowledgement count
+const policySelect = `
+	SELECT p.id, p.title, p.description, p.owner_id, COALESCE(o.name, ''), p.review_cycle,
+		p.next_review_date, COALESCE(p.next_review_date < CURRENT_DATE, FALSE),
+		(SELECT COUNT(*) FROM policy_controls pc WHERE pc.policy_id = p.id),
+		p.created_by, p.created_at, p.updated_at,
+		cv.id, cv.version, cv.attachment_id, cv.filename, cv.change_summary, cv.created_at, cv.decided_at, cv.acknowledgements
+	FROM policies p
+	LEFT JOIN users o ON o.id = p.owner_id
+	LEFT JOIN LATERAL (
+		SELECT v.id, v.version, v.attachment_id, COALESCE(a.filename, '') AS filename, v.change_summary,
+			v.created_at, v.decided_at,
+			(SELECT COUNT(*) FROM policy_acknowledgements pa WHERE pa.policy_version_id = v.id) AS acknowledgements
+		FROM policy_versions v
+		LEFT JOIN attachments a ON a.id = v.attachment_id
+		WHERE v.policy_id = p.id AND v.status = 'approved'
+		ORDER BY v.version DESC
+		LIMIT 1
+	) cv ON TRUE
+`
+
+func scanPolicy(row interface{ Scan(...any) error }) (*models.Policy, error) {
+	p := &models.Policy{}
+	var (
+		versionID, filename, changeSummary sql.NullString
+		version, acknowledgements          sql.NullInt64
+		createdAt                          sql.NullTime
+		attachmentID                       *string
+		decidedAt                          *time.Time
+	)
+	err := row.Scan(&p.ID, &p.Title, &p.Description, &p.OwnerID, &p.OwnerName, &p.ReviewCycle,
+		&p.NextReviewDate, &p.ReviewOverdue, &p.ControlCount,
+		&p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+		&versionID, &version, &attachmentID, &filename, &changeSummary, &createdAt, &decidedAt, &acknowledgements)
+	if err != nil {
+		return nil, err
+	}
+	if versionID.Valid {
+		p.CurrentVersion = &models.PolicyVersion{
+			ID:             versionID.String,
+			PolicyID:       p.ID,
+			Version:        int(version.Int64),
+			AttachmentID:   attachmentID,
+			AttachmentName: filename.String,
+			ChangeSummary:  changeSummary.String,
+			Status:         models.PolicyApproved,
+			CreatedAt:      createdAt.Time,
+			DecidedAt:      decidedAt,
+		}
+		p.AcknowledgementCount = int(acknowledgements.Int64)
+	}
+	return p, nil
+}
+
+func (r *policyRepository) List(ctx context.Context, filter models.PolicyFilter) ([]*models.Policy, error) {
+	query := policySelect + ` WHERE ($1 = '' OR p.owner_id::text = $1)
+		AND ($2 = '' OR EXISTS (
+			SELECT 1 FROM policy_controls pc WHERE pc.policy_id = p.id AND pc.framework_control_id::text = $2
+		))
+		AND (NOT $3 OR p.next_review_date < CURRENT_DATE)
+		ORDER BY p.title, p.created_at`
+	rows, err := r.db.QueryContext(ctx, query, filter.OwnerID, filter.ControlID, filter.ReviewOverdue)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []*models.Policy{}
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+func (r *policyRepository) GetByID(ctx context.Context, id string) (*models.Policy, error) {
+	policy, err := scanPolicy(r.db.QueryRowContext(ctx, policySelect+` WHERE p.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPolicyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT fc.id, fc.control_ref, fc.title, f.id, f.name, pc.created_at
+		FROM policy_controls pc
+		JOIN framework_controls fc ON fc.id = pc.framework_control_id
+		JOIN frameworks f ON f.id = fc.framework_id
+		WHERE pc.policy_id = $1
+		ORDER BY f.name, fc.control_ref
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policy.Controls = []*models.PolicyControl{}
+	for rows.Next() {
+		control := &models.PolicyControl{}
+		if err := rows.Scan(&control.ControlID, &control.ControlRef, &control.Title, &control.FrameworkID,
+			&control.FrameworkName, &control.LinkedAt); err != nil {
+			return nil, err
+		}
+		policy.Controls = append(policy.Controls, control)
+	}
+	return policy, rows.Err()
+}
+
+func (r *policyRepository) Create(ctx context.Context, policy *models.Policy) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO policies (title, description, owner_id, review_cycle, next_review_date, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`, policy.Title, policy.Description, policy.OwnerID, policy.ReviewCycle, policy.NextReviewDate, policy.CreatedBy).
+		Scan(&policy.ID, &policy.CreatedAt, &policy.UpdatedAt)
+	return policyWriteError(err)
+}
+
+func (r *policyRepository) Update(ctx context.Context, policy *models.Policy) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE policies
+		SET title = $2, description = $3, owner_id = $4, review_cycle = $5, next_review_date = $6, updated_at = NOW()
+		WHERE id = $1
+	`, policy.ID, policy.Title, policy.Description, policy.OwnerID, policy.ReviewCycle, policy.NextReviewDate)
+	if err != nil {
+		return policyWriteError(err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// policyWriteError maps a missing owner to ErrPolicyOwnerNotFound
+func policyWriteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" && pgErr.ConstraintName == "policies_owner_id_fkey" {
+		return ErrPolicyOwnerNotFound
+	}
+	return err
+}
+
+func (r *policyRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM policies WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+const policyVersionSelect = `
+	SELECT v.id, v.policy_id, v.version, v.body, v.attachment_id, COALESCE(a.filename, ''), v.change_summary, v.status,
+		v.created_by, COALESCE(cu.name, ''), v.created_at, v.updated_at, v.submitted_at,
+		v.decided_by, COALESCE(du.name, ''), v.decided_at, v.decision_comment
+	FROM policy_versions v
+	LEFT JOIN attachments a ON a.id = v.attachment_id
+	LEFT JOIN users cu ON cu.id = v.created_by
+	LEFT JOIN users du ON du.id = v.decided_by
+`
+
+func scanPolicyVersion(row interface{ Scan(...any) error }) (*models.PolicyVersion, error) {
+	v := &models.PolicyVersion{}
+	err := row.Scan(&v.ID, &v.PolicyID, &v.Version, &v.Body, &v.AttachmentID, &v.AttachmentName, &v.ChangeSummary, &v.Status,
+		&v.CreatedBy, &v.CreatedByName, &v.CreatedAt, &v.UpdatedAt, &v.SubmittedAt,
+		&v.DecidedBy, &v.DecidedByName, &v.DecidedAt, &v.DecisionComment)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *policyRepository) ListVersions(ctx context.Context, policyID string) ([]*models.PolicyVersion, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM policies WHERE id = $1)`, policyID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrPolicyNotFound
+	}
+
+	rows, err := r.db.QueryContext(ctx, policyVersionSelect+` WHERE v.policy_id = $1 ORDER BY v.version DESC`, policyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*models.PolicyVersion{}
+	for rows.Next() {
+		version, err := scanPolicyVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		version.Body = ""
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func (r *policyRepository) GetVersion(ctx context.Context, id string) (*models.PolicyVersion, error) {
+	version, err := scanPolicyVersion(r.db.QueryRowContext(ctx, policyVersionSelect+` WHERE v.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPolicyVersionNotFound
+	}
+	return version, err
+}
+
+func (r *policyRepository) CreateVersion(ctx context.Context, version *models.PolicyVersion) error {
+	if err := r.checkAttachment(ctx, version); err != nil {
+		return err
+	}
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO policy_versions (policy_id, version, body, attachment_id, change_summary, created_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		FROM policy_versions WHERE policy_id = $1
+		RETURNING id, version, status, created_at, updated_at
+	`, version.PolicyID, version.Body, version.AttachmentID, version.ChangeSummary, version.CreatedBy).
+		Scan(&version.ID, &version.Version, &version.Status, &version.CreatedAt, &version.UpdatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		// Two drafts started at once collide on the open index or, failing
+		// that, on the version number
+		case pgErr.Code == "23505":
+			return ErrPolicyVersionOpen
+		case pgErr.Code == "23503" && pgErr.ConstraintName == "policy_versions_attachment_id_fkey":
+			return ErrPolicyAttachmentNotFound
+		case pgErr.Code == "23503":
+			return ErrPolicyNotFound
+		}
+	}
+	return err
+}
+
+func (r *policyRepository) UpdateVersion(ctx context.Context, version *models.PolicyVersion) error {
+	if err := r.checkAttachment(ctx, version); err != nil {
+		return err
+	}
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE policy_versions
+		SET body = $2, attachment_id = $3, change_summary = $4, updated_at = NOW()
+		WHERE id = $1 AND status = 'draft'
+	`, version.ID, version.Body, version.AttachmentID, version.ChangeSummary)
+	if err != nil {
+		return err
+	}
+	return r.versionStateError(ctx, result, version.ID, ErrPolicyVersionSubmitted)
+}
+
+// checkAttachment makes sure a version's document was uploaded to its own
+// policy
+func (r *policyRepository) checkAttachment(ctx context.Context, version *models.PolicyVersion) error {
+	if version.AttachmentID == nil {
+		return nil
+	}
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM attachments WHERE id = $1 AND entity_type = 'policy' AND entity_id = $2)
+	`, *version.AttachmentID, version.PolicyID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPolicyAttachmentNotFound
+	}
+	return nil
+}
+
+func (r *policyRepository) DeleteVersion(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM policy_versions WHERE id = $1 AND status = 'draft'`, id)
+	if err != nil {
+		return err
+	}
+	return r.versionStateError(ctx, result, id, ErrPolicyVersionSubmitted)
+}
+
+func (r *policyRepository) SubmitVersion(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE policy_versions
+		SET status = 'pending', submitted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'draft'
+	`, id)
+	if err != nil {
+		return err
+	}
+	return r.versionStateError(ctx, result, id, ErrPolicyVersionSubmitted)
+}
+
+func (r *policyRepository) DecideVersion(ctx context.Context, id string, status models.PolicyVersionStatus, comment, userID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE policy_versions
+		SET status = $2, decision_comment = $3, decided_by = $4, decided_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending' AND created_by IS DISTINCT FROM $4
+	`, id, status, comment, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil || rows > 0 {
+		return err
+	}
+
+	// Otherwise the version is missing, not pending, or pending and created
+	// by userID
+	var current models.PolicyVersionStatus
+	err = r.db.QueryRowContext(ctx, `SELECT status FROM policy_versions WHERE id = $1`, id).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrPolicyVersionNotFound
+	case err != nil:
+		return err
+	case current != models.PolicyPending:
+		return ErrPolicyVersionNotPending
+	}
+	return ErrPolicySelfDecision
+}
+
+// versionStateError tells apart a version that doesn't exist from one that
+// wasn't in the state a conditional write expected, in which case it
+// returns wrongState
+func (r *policyRepository) versionStateError(ctx context.Context, result sql.Result, id string, wrongState error) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM policy_versions WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return wrongState
+	}
+	return ErrPolicyVersionNotFound
+}
+
+func (r *policyRepository) LinkControl(ctx context.Context, policyID, controlID, userID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO policy_controls (policy_id, framework_control_id, created_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING
+	`, policyID, controlID, userID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "policy_controls_policy_id_fkey" {
+			return false, ErrPolicyNotFound
+		}
+		return false, ErrFrameworkControlNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *policyRepository) UnlinkControl(ctx context.Context, policyID, controlID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		DELETE FROM policy_controls WHERE policy_id = $1 AND framework_control_id = $2
+	`, policyID, controlID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrPolicyControlNotLinked
+	}
+	return nil
+}
+
+func (r *policyRepository) Acknowledge(ctx context.Context, versionID, userID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO policy_acknowledgements (policy_version_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, versionID, userID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return false, ErrPolicyVersionNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *policyRepository) ListAcknowledgements(ctx context.Context, versionID string) ([]*models.PolicyAcknowledgement, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT u.id, u.name, u.email, pa.acknowledged_at
+		FROM users u
+		LEFT JOIN policy_acknowledgements pa ON pa.user_id = u.id AND pa.policy_version_id = $1
+		ORDER BY u.name, u.email
+	`, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	acknowledgements := []*models.PolicyAcknowledgement{}
+	for rows.Next() {
+		a := &models.PolicyAcknowledgement{}
+		if err := rows.Scan(&a.UserID, &a.Name, &a.Email, &a.AcknowledgedAt); err != nil {
+			return nil, err
+		}
+		acknowledgements = append(acknowledgements, a)
+	}
+	return acknowledgements, rows.Err()
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewPolicyRepository(s.db)
+	attachments := NewAttachmentRepository(s.db)
+	frameworkRepo := NewFrameworkRepository(s.db)
+	controlRepo := NewFrameworkControlRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "policy-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Policy Owner",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+	framework, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "ISO 27001 policies " + uuid.New().String()})
+	require.NoError(t, err)
+	control, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{FrameworkID: framework.ID, ControlRef: "A.5.1", Title: "Policies for information security"})
+	require.NoError(t, err)
+
+	policy := &models.Policy{Title: "Information security policy", OwnerID: &user.ID, ReviewCycle: models.RecurrenceAnnual, CreatedBy: &user.ID}
+	require.NoError(t, repo.Create(ctx, policy))
+	missing := uuid.New().String()
+	assert.ErrorIs(t, repo.Create(ctx, &models.Policy{Title: "x", OwnerID: &missing, ReviewCycle: models.RecurrenceAnnual}), ErrPolicyOwnerNotFound)
+
+	linked, err := repo.LinkControl(ctx, policy.ID, control.ID, user.ID)
+	require.NoError(t, err)
+	assert.True(t, linked)
+	linked, err = repo.LinkControl(ctx, policy.ID, control.ID, user.ID)
+	require.NoError(t, err)
+	assert.False(t, linked)
+	_, err = repo.LinkControl(ctx, policy.ID, missing, user.ID)
+	assert.ErrorIs(t, err, ErrFrameworkControlNotFound)
+
+	document := &models.Attachment{EntityType: models.AttachmentPolicy, EntityID: policy.ID, Filename: "isp.pdf",
+		ContentType: "application/pdf", SizeBytes: 10, SHA256: "00", StorageKey: "attachments/policy/" + uuid.New().String()}
+	require.NoError(t, attachments.Create(ctx, document))
+
+	draft := &models.PolicyVersion{PolicyID: policy.ID, Body: "# Information security", AttachmentID: &document.ID,
+		ChangeSummary: "First issue", CreatedBy: &user.ID}
+	require.NoError(t, repo.CreateVersion(ctx, draft))
+	assert.Equal(t, 1, draft.Version)
+	assert.Equal(t, models.PolicyDraft, draft.Status)
+	assert.ErrorIs(t, repo.CreateVersion(ctx, &models.PolicyVersion{PolicyID: policy.ID, Body: "Another"}), ErrPolicyVersionOpen)
+	stranger := uuid.New().String()
+	assert.ErrorIs(t, repo.UpdateVersion(ctx, &models.PolicyVersion{ID: draft.ID, PolicyID: policy.ID, AttachmentID: &stranger}),
+		ErrPolicyAttachmentNotFound)
+
+	require.NoError(t, repo.SubmitVersion(ctx, draft.ID))
+	assert.ErrorIs(t, repo.UpdateVersion(ctx, draft), ErrPolicyVersionSubmitted)
+	assert.ErrorIs(t, repo.DecideVersion(ctx, draft.ID, models.PolicyApproved, "", user.ID), ErrPolicySelfDecision)
+	approver := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "policy-approver-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Policy Approver",
+		Role:         models.RoleAdmin,
+	}
+	require.NoError(t, userRepo.Create(ctx, approver))
+	require.NoError(t, repo.DecideVersion(ctx, draft.ID, models.PolicyApproved, "", approver.ID))
+	assert.ErrorIs(t, repo.DecideVersion(ctx, draft.ID, models.PolicyRejected, "No", approver.ID), ErrPolicyVersionNotPending)
+	assert.ErrorIs(t, attachments.Delete(ctx, document.ID), ErrAttachmentInUse)
+
+	acknowledged, err := repo.Acknowledge(ctx, draft.ID, user.ID)
+	require.NoError(t, err)
+	assert.True(t, acknowledged)
+	acknowledged, err = repo.Acknowledge(ctx, draft.ID, user.ID)
+	require.NoError(t, err)
+	assert.False(t, acknowledged)
+
+	fetched, err := repo.GetByID(ctx, policy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Policy Owner", fetched.OwnerName)
+	require.NotNil(t, fetched.CurrentVersion)
+	assert.Equal(t, draft.ID, fetched.CurrentVersion.ID)
+	assert.Equal(t, "isp.pdf", fetched.CurrentVersion.AttachmentName)
+	assert.Equal(t, 1, fetched.AcknowledgementCount)
+	require.Len(t, fetched.Controls, 1)
+	assert.Equal(t, "A.5.1", fetched.Controls[0].ControlRef)
+
+	users, err := repo.ListAcknowledgements(ctx, draft.ID)
+	require.NoError(t, err)
+	for _, u := range users {
+		if u.UserID == user.ID {
+			assert.NotNil(t, u.AcknowledgedAt)
+		} else {
+			assert.Nil(t, u.AcknowledgedAt)
+		}
+	}
+
+	byControl, err := repo.List(ctx, models.PolicyFilter{ControlID: control.ID})
+	require.NoError(t, err)
+	require.Len(t, byControl, 1)
+	assert.Equal(t, policy.ID, byControl[0].ID)
+
+	revision := &models.PolicyVersion{PolicyID: policy.ID, Body: "# Information security, revised", CreatedBy: &user.ID}
+	require.NoError(t, repo.CreateVersion(ctx, revision))
+	assert.Equal(t, 2, revision.Version)
+	versions, err := repo.ListVersions(ctx, policy.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Empty(t, versions[0].Body)
+	require.NoError(t, repo.DeleteVersion(ctx, revision.ID))
+
+	require.NoError(t, repo.UnlinkControl(ctx, policy.ID, control.ID))
+	assert.ErrorIs(t, repo.UnlinkControl(ctx, policy.ID, control.ID), ErrPolicyControlNotLinked)
+	require.NoError(t, repo.Delete(ctx, policy.ID))
+	_, err = repo.ListVersions(ctx, policy.ID)
+	assert.ErrorIs(t, err, ErrPolicyNotFound)
+	require.NoError(t, attachments.Delete(ctx, document.ID))
+}
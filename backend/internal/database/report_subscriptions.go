@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"backend/internal/models"
+)
+
+var ErrReportSubscriptionNotFound = errors.New("report subscription not found")
+
+type ReportSubscriptionRepository interface {
+	Create(ctx context.Context, sub *models.ReportSubscription) error
+	FindByID(ctx context.Context, id string) (*models.ReportSubscription, error)
+	ListByUser(ctx context.Context, userID string) ([]*models.ReportSubscription, error)
+	Update(ctx context.Context, sub *models.ReportSubscription) error
+	Delete(ctx context.Context, id string) error
+	// ListDue returns active subscriptions whose next run is at or before now
+	ListDue(ctx context.Context, now time.Time, limit int) ([]*models.ReportSubscription, error)
+	// Advance moves a subscription's next run from one time to the next. It
+	// reports false if the run was already claimed, so that when several
+	// instances poll the schedule only one of them sends each report.
+	Advance(ctx context.Context, id string, from, next, ranAt time.Time) (bool, error)
+	CreateDelivery(ctx context.Context, delivery *models.ReportDelivery) error
+	ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*models.ReportDelivery, error)
+}
+
+type reportSubscriptionRepository struct {
+	db *sql.DB
+}
+
+func NewReportSubscriptionRepository(db *sql.DB) ReportSubscriptionRepository {
+	return &reportSubscriptionRepository{db: db}
+}
+
+const reportSubscriptionColumns = `id, user_id, name, frequency, format, filters, active, next_run_at, last_run_at, created_at, updated_at`
+
+func scanReportSubscription(row interface{ Scan(...any) error }) (*models.ReportSubscription, error) {
+	sub := &models.ReportSubscription{}
+	var filters []byte
+	err := row.Scan(&sub.ID, &sub.UserID, &sub.Name, &sub.Frequency, &sub.Format, &filters, &sub.Active,
+		&sub.NextRunAt, &sub.LastRunAt, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(filters, &sub.Filters); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (r *reportSubscriptionRepository) Create(ctx context.Context, sub *models.ReportSubscription) error {
+	filters, err := json.Marshal(sub.Filters)
+	if err != nil {
+		return err
+	}
+	query := `
+		INSERT INTO report_subscriptions (user_id, name, frequency, format, filters, active, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRowContext(ctx, query, sub.UserID, sub.Name, sub.Frequency, sub.Format, string(filters), sub.Active, sub.NextRunAt).
+		Scan(&sub.ID, &sub.CreatedAt, &sub.UpdatedAt)
+}
+
+func (r *reportSubscriptionRepository) FindByID(ctx context.Context, id string) (*models.ReportSubscription, error) {
+	query := `SELECT ` + reportSubscriptionColumns + ` FROM report_subscriptions WHERE id = $1`
+	sub, err := scanReportSubscription(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrReportSubscriptionNotFound
+	}
+	return sub, err
+}
+
+func (r *reportSubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]*models.ReportSubscription, error) {
+	query := `SELECT ` + reportSubscriptionColumns + ` FROM report_subscriptions WHERE user_id = $1 ORDER BY name, created_at`
+	return r.list(ctx, query, userID)
+}
+
+func (r *reportSubscriptionRepository) ListDue(ctx context.Context, now time.Time, limit int) ([]*models.ReportSubscription, error) {
+	query := `
+		SELECT ` + reportSubscriptionColumns + `
+		FROM report_subscriptions
+		WHERE active AND next_run_at <= $1
+		ORDER BY next_run_at
+		LIMIT $2
+	`
+	return r.list(ctx, query, now, limit)
+}
+
+func (r *reportSubscriptionRepository) list(ctx context.Context, query string, args ...any) ([]*models.ReportSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []*models.ReportSubscription{}
+	for rows.Next() {
+		sub, err := scanReportSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (r *reportSubscriptionRepository) Update(ctx context.Context, sub *models.ReportSubscription) error {
+	filters, err := json.Marshal(sub.Filters)
+	if err != nil {
+		return err
+	}
+	query := `
+		UPDATE report_subscriptions
+		SET name = $2, frequency = $3, format = $4, filters = $5, active = $6, next_run_at = $7, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err = r.db.QueryRowContext(ctx, query, sub.ID, sub.Name, sub.Frequency, sub.Format, string(filters), sub.Active, sub.NextRunAt).
+		Scan(&sub.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrReportSubscriptionNotFound
+	}
+	return err
+}
+
+func (r *reportSubscriptionRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM report_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrReportSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *reportSubscriptionRepository) Advance(ctx context.Context, id string, from, next, ranAt time.Time) (bool, error) {
+	query := `
+		UPDATE report_subscriptions
+		SET next_run_at = $3, last_run_at = $4
+		WHERE id = $1 AND next_run_at = $2 AND active
+	`
+	result, err := r.db.ExecContext(ctx, query, id, from, next, ranAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+func (r *reportSubscriptionRepository) CreateDelivery(ctx context.Context, d *models.ReportDelivery) error {
+	query := `
+		INSERT INTO report_deliveries (subscription_id, recipient, status, manual, filename, size_bytes, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRowContext(ctx, query, d.SubscriptionID, d.Recipient, d.Status, d.Manual, d.Filename, d.SizeBytes, d.Error).
+		Scan(&d.ID, &d.CreatedAt)
+}
+
+func (r *reportSubscriptionRepository) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*models.ReportDelivery, error) {
+	query := `
+		SELECT id, subscription_id, recipient, status, manual, filename, size_bytes, error, created_at
+		FROM report_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.QueryContext(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.ReportDelivery{}
+	for rows.Next() {
+		d := &models.ReportDelivery{}
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Recipient, &d.Status, &d.Manual, &d.Filename, &d.SizeBytes, &d.Error, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
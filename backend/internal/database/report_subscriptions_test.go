@@ -0,0 +1,111 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportSubscriptionRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewReportSubscriptionRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "test-reports-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Report Subscriber",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+
+	status := models.StatusOpen
+	due := time.Date(2024, 6, 10, 6, 0, 0, 0, time.UTC)
+	sub := &models.ReportSubscription{
+		UserID:    user.ID,
+		Name:      "Open risks",
+		Frequency: models.ReportFrequencyWeekly,
+		Format:    models.ReportFormatCSV,
+		Filters:   models.ReportFilters{Status: &status, Tag: "vendor"},
+		Active:    true,
+		NextRunAt: due,
+	}
+	require.NoError(t, repo.Create(ctx, sub))
+	require.NotEmpty(t, sub.ID)
+
+	t.Run("FindByID round trips filters", func(t *testing.T) {
+		found, err := repo.FindByID(ctx, sub.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "vendor", found.Filters.Tag)
+		require.NotNil(t, found.Filters.Status)
+		assert.Equal(t, status, *found.Filters.Status)
+		assert.True(t, found.NextRunAt.Equal(due))
+
+		_, err = repo.FindByID(ctx, uuid.New().String())
+		assert.ErrorIs(t, err, ErrReportSubscriptionNotFound)
+	})
+
+	t.Run("ListDue and Advance claim a run once", func(t *testing.T) {
+		subs, err := repo.ListDue(ctx, due, 1000)
+		require.NoError(t, err)
+		var listed *models.ReportSubscription
+		for _, s := range subs {
+			if s.ID == sub.ID {
+				listed = s
+			}
+		}
+		require.NotNil(t, listed)
+
+		next := due.AddDate(0, 0, 7)
+		claimed, err := repo.Advance(ctx, sub.ID, listed.NextRunAt, next, due)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+
+		claimed, err = repo.Advance(ctx, sub.ID, listed.NextRunAt, next, due)
+		require.NoError(t, err)
+		assert.False(t, claimed, "a run can only be claimed once")
+
+		found, err := repo.FindByID(ctx, sub.ID)
+		require.NoError(t, err)
+		assert.True(t, found.NextRunAt.Equal(next))
+		require.NotNil(t, found.LastRunAt)
+	})
+
+	t.Run("deliveries", func(t *testing.T) {
+		msg := "connection refused"
+		require.NoError(t, repo.CreateDelivery(ctx, &models.ReportDelivery{SubscriptionID: sub.ID, Recipient: user.Email, Status: models.ReportDeliveryFailed, Filename: "risks.csv", Error: &msg}))
+		require.NoError(t, repo.CreateDelivery(ctx, &models.ReportDelivery{SubscriptionID: sub.ID, Recipient: user.Email, Status: models.ReportDeliverySent, Manual: true, Filename: "risks.csv", SizeBytes: 120}))
+
+		deliveries, err := repo.ListDeliveries(ctx, sub.ID, 10)
+		require.NoError(t, err)
+		require.Len(t, deliveries, 2)
+		assert.Equal(t, models.ReportDeliverySent, deliveries[0].Status)
+		assert.Equal(t, msg, *deliveries[1].Error)
+	})
+
+	t.Run("Update and Delete", func(t *testing.T) {
+		sub.Active = false
+		sub.Name = "Paused"
+		require.NoError(t, repo.Update(ctx, sub))
+
+		subs, err := repo.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, subs, 1)
+		assert.False(t, subs[0].Active)
+
+		require.NoError(t, repo.Delete(ctx, sub.ID))
+		assert.ErrorIs(t, repo.Delete(ctx, sub.ID), ErrReportSubscriptionNotFound)
+	})
+}
@@ -18,18 +18,28 @@ type RiskRepository interface {
 	Create(ctx context.Context, risk *models.Risk) error
 	FindByID(ctx context.Context, id string) (*models.Risk, error)
 	List(ctx context.Context, params *models.RiskListParams) (*models.RiskListResponse, error)
+	ListIDs(ctx context.Context, params *models.RiskListParams) ([]string, error)
 	Update(ctx context.Context, risk *models.Risk) error
-	Delete(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string, version int) error
+	ListDeleted(ctx context.Context) ([]*models.Risk, error)
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string, deletedBefore time.Time) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) RiskRepository
 }
 
 type riskRepository struct {
-	db *sql.DB
+	db dbtx
 }
 
 func NewRiskRepository(db *sql.DB) RiskRepository {
 	return &riskRepository{db: db}
 }
 
+func (r *riskRepository) WithTx(tx *sql.Tx) RiskRepository {
+	return &riskRepository{db: tx}
+}
+
 func (r *riskRepository) Create(ctx context.Context, risk *models.Risk) error {
 	if risk.ID == "" {
 		risk.ID = uuid.New().String()
@@ -38,31 +48,35 @@ func (r *riskRepository) Create(ctx context.Context, risk *models.Risk) error {
 	risk.CreatedAt = now
 	risk.UpdatedAt = now
 
+	if risk.Tags == nil {
+		risk.Tags = []string{}
+	}
+
 	query := `
-		INSERT INTO risks (id, title, description, owner_id, status, severity, category_id, review_date, created_at, updated_at, created_by, updated_by)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id, created_at, updated_at
+		INSERT INTO risks (id, title, description, owner_id, status, severity, category_id, review_date, created_at, updated_at, created_by, updated_by, tags)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id, created_at, updated_at, version
 	`
 	return r.db.QueryRowContext(ctx, query,
 		risk.ID, risk.Title, risk.Description, risk.OwnerID, risk.Status, risk.Severity,
-		risk.CategoryID, risk.ReviewDate, risk.CreatedAt, risk.UpdatedAt, risk.CreatedBy, risk.UpdatedBy,
-	).Scan(&risk.ID, &risk.CreatedAt, &risk.UpdatedAt)
+		risk.CategoryID, risk.ReviewDate, risk.CreatedAt, risk.UpdatedAt, risk.CreatedBy, risk.UpdatedBy, tagsValue(risk.Tags),
+	).Scan(&risk.ID, &risk.CreatedAt, &risk.UpdatedAt, &risk.Version)
 }
 
 func (r *riskRepository) FindByID(ctx context.Context, id string) (*models.Risk, error) {
 	query := `
-		SELECT r.id, r.title, r.description, r.owner_id, r.status, r.severity, r.category_id, r.review_date, r.created_at, r.updated_at, r.created_by, r.updated_by,
+		SELECT r.id, r.title, r.description, r.owner_id, r.status, r.severity, r.category_id, r.review_date, r.created_at, r.updated_at, r.created_by, r.updated_by, r.version, r.tags,
 		       c.id, c.name, c.description
 		FROM risks r
 		LEFT JOIN categories c ON r.category_id = c.id
-		WHERE r.id = $1
+		WHERE r.id = $1 AND r.deleted_at IS NULL
 	`
 	risk := &models.Risk{}
 	var catID, catName, catDesc sql.NullString
 
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&risk.ID, &risk.Title, &risk.Description, &risk.OwnerID, &risk.Status, &risk.Severity,
-		&risk.CategoryID, &risk.ReviewDate, &risk.CreatedAt, &risk.UpdatedAt, &risk.CreatedBy, &risk.UpdatedBy,
+		&risk.CategoryID, &risk.ReviewDate, &risk.CreatedAt, &risk.UpdatedAt, &risk.CreatedBy, &risk.UpdatedBy, &risk.Version, tagsColumn{&risk.Tags},
 		&catID, &catName, &catDesc,
 	)
 	if err != nil {
@@ -94,36 +108,8 @@ func (r *riskRepository) List(ctx context.Context, params *models.RiskListParams
 		params.Limit = 20
 	}
 
-	// Build WHERE clause
-	where := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
-
-	if params.Status != nil {
-		where += fmt.Sprintf(" AND r.status = $%d", argNum)
-		args = append(args, *params.Status)
-		argNum++
-	}
-	if params.Severity != nil {
-		where += fmt.Sprintf(" AND r.severity = $%d", argNum)
-		args = append(args, *params.Severity)
-		argNum++
-	}
-	if params.CategoryID != nil {
-		where += fmt.Sprintf(" AND r.category_id = $%d", argNum)
-		args = append(args, *params.CategoryID)
-		argNum++
-	}
-	if params.OwnerID != nil {
-		where += fmt.Sprintf(" AND r.owner_id = $%d", argNum)
-		args = append(args, *params.OwnerID)
-		argNum++
-	}
-	if params.Search != "" {
-		where += fmt.Sprintf(" AND (r.title ILIKE $%d OR r.description ILIKE $%d)", argNum, argNum)
-		args = append(args, "%"+params.Search+"%", "%"+params.Search+"%")
-		argNum++
-	}
+	where, args := riskListWhere(params)
+	argNum := len(args) + 1
 
 	// Get total count
 	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM risks r %s", where)
@@ -133,33 +119,15 @@ func (r *riskRepository) List(ctx context.Context, params *models.RiskListParams
 		return nil, err
 	}
 
-	// Build ORDER BY
-	orderBy := "r.created_at"
-	if params.Sort != "" {
-		// Prevent SQL injection by allowing only specific fields
-		switch params.Sort {
-		case "title", "status", "severity", "category_id", "review_date", "updated_at":
-			orderBy = "r." + params.Sort
-		case "created_at":
-			orderBy = "r.created_at"
-		default:
-			orderBy = "r.created_at"
-		}
-	}
-	orderDir := "DESC"
-	if params.Order == "asc" {
-		orderDir = "ASC"
-	}
-
 	// Get paginated results
 	offset := (params.Page - 1) * params.Limit
 	query := fmt.Sprintf(`
-		SELECT r.id, r.title, r.description, r.owner_id, r.status, r.severity, r.category_id, r.review_date, r.created_at, r.updated_at, r.created_by, r.updated_by,
+		SELECT r.id, r.title, r.description, r.owner_id, r.status, r.severity, r.category_id, r.review_date, r.created_at, r.updated_at, r.created_by, r.updated_by, r.version, r.tags,
 		       c.id, c.name, c.description
 		FROM risks r
 		LEFT JOIN categories c ON r.category_id = c.id
-		%s ORDER BY %s %s LIMIT $%d OFFSET $%d
-	`, where, orderBy, orderDir, argNum, argNum+1)
+		%s ORDER BY %s LIMIT $%d OFFSET $%d
+	`, where, riskListOrder(params), argNum, argNum+1)
 	args = append(args, params.Limit, offset)
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -174,7 +142,7 @@ func (r *riskRepository) List(ctx context.Context, params *models.RiskListParams
 		var catID, catName, catDesc sql.NullString
 		err := rows.Scan(
 			&risk.ID, &risk.Title, &risk.Description, &risk.OwnerID, &risk.Status, &risk.Severity,
-			&risk.CategoryID, &risk.ReviewDate, &risk.CreatedAt, &risk.UpdatedAt, &risk.CreatedBy, &risk.UpdatedBy,
+			&risk.CategoryID, &risk.ReviewDate, &risk.CreatedAt, &risk.UpdatedAt, &risk.CreatedBy, &risk.UpdatedBy, &risk.Version, tagsColumn{&risk.Tags},
 			&catID, &catName, &catDesc,
 		)
 		if err != nil {
@@ -196,24 +164,110 @@ func (r *riskRepository) List(ctx context.Context, params *models.RiskListParams
 	}, rows.Err()
 }
 
+// riskListOrder builds the ORDER BY expression for a list. Only known columns
+// are accepted so the sort field can't inject SQL.
+func riskListOrder(params *models.RiskListParams) string {
+	orderBy := "r.created_at"
+	switch params.Sort {
+	case "title", "status", "severity", "category_id", "review_date", "updated_at":
+		orderBy = "r." + params.Sort
+	}
+	orderDir := "DESC"
+	if params.Order == "asc" {
+		orderDir = "ASC"
+	}
+	return orderBy + " " + orderDir
+}
+
+// riskListWhere builds the WHERE clause shared by List and ListIDs. Trashed
+// risks are never listed.
+func riskListWhere(params *models.RiskListParams) (string, []interface{}) {
+	where := "WHERE r.deleted_at IS NULL"
+	args := []interface{}{}
+	argNum := 1
+
+	if params.Status != nil {
+		where += fmt.Sprintf(" AND r.status = $%d", argNum)
+		args = append(args, *params.Status)
+		argNum++
+	}
+	if params.Severity != nil {
+		where += fmt.Sprintf(" AND r.severity = $%d", argNum)
+		args = append(args, *params.Severity)
+		argNum++
+	}
+	if params.CategoryID != nil {
+		where += fmt.Sprintf(" AND r.category_id = $%d", argNum)
+		args = append(args, *params.CategoryID)
+		argNum++
+	}
+	if params.OwnerID != nil {
+		where += fmt.Sprintf(" AND r.owner_id = $%d", argNum)
+		args = append(args, *params.OwnerID)
+		argNum++
+	}
+	if params.Tag != "" {
+		where += fmt.Sprintf(" AND r.tags ? $%d", argNum)
+		args = append(args, params.Tag)
+		argNum++
+	}
+	if params.Search != "" {
+		where += fmt.Sprintf(" AND (r.title ILIKE $%d OR r.description ILIKE $%d)", argNum, argNum)
+		args = append(args, "%"+params.Search+"%")
+	}
+
+	return where, args
+}
+
+// ListIDs returns the IDs of every risk matching the filters in params, oldest
+// first. Paging and sorting fields are ignored.
+func (r *riskRepository) ListIDs(ctx context.Context, params *models.RiskListParams) ([]string, error) {
+	where, args := riskListWhere(params)
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf("SELECT r.id FROM risks r %s ORDER BY r.created_at, r.id", where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Update writes the risk only if its stored version still equals risk.Version,
+// then bumps the version. ErrVersionConflict means someone else got there first.
 func (r *riskRepository) Update(ctx context.Context, risk *models.Risk) error {
 	now := time.Now()
 	risk.UpdatedAt = now
 
 	query := `
 		UPDATE risks SET title = $1, description = $2, owner_id = $3, status = $4, severity = $5,
-			category_id = $6, review_date = $7, updated_at = $8, updated_by = $9
-		WHERE id = $10
-		RETURNING updated_at
+			category_id = $6, review_date = $7, updated_at = $8, updated_by = $9, tags = $10, version = version + 1
+		WHERE id = $11 AND deleted_at IS NULL AND version = $12
+		RETURNING updated_at, version
 	`
-	return r.db.QueryRowContext(ctx, query,
+	err := r.db.QueryRowContext(ctx, query,
 		risk.Title, risk.Description, risk.OwnerID, risk.Status, risk.Severity,
-		risk.CategoryID, risk.ReviewDate, risk.UpdatedAt, risk.UpdatedBy, risk.ID,
-	).Scan(&risk.UpdatedAt)
+		risk.CategoryID, risk.ReviewDate, risk.UpdatedAt, risk.UpdatedBy, tagsValue(risk.Tags), risk.ID, risk.Version,
+	).Scan(&risk.UpdatedAt, &risk.Version)
+	if err == sql.ErrNoRows {
+		return versionConflictOr(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM risks WHERE id = $1 AND deleted_at IS NULL)", risk.ID, ErrRiskNotFound)
+	}
+	return err
 }
 
-func (r *riskRepository) Delete(ctx context.Context, id string) error {
-	result, err := r.db.ExecContext(ctx, "DELETE FROM risks WHERE id = $1", id)
+// Delete moves a risk to the trash if it is still at version. Mitigations,
+// control links and incident links are kept so the risk can be restored
+// intact. ErrVersionConflict means someone else modified it first.
+func (r *riskRepository) Delete(ctx context.Context, id string, version int) error {
+	result, err := r.db.ExecContext(ctx,
+		"UPDATE risks SET deleted_at = NOW(), version = version + 1 WHERE id = $1 AND version = $2 AND deleted_at IS NULL", id, version)
 	if err != nil {
 		return err
 	}
@@ -222,7 +276,60 @@ func (r *riskRepository) Delete(ctx context.Context, id string) error {
 		return err
 	}
 	if rows == 0 {
-		return ErrRiskNotFound
+		return versionConflictOr(ctx, r.db, "SELECT EXISTS(SELECT 1 FROM risks WHERE id = $1 AND deleted_at IS NULL)", id, ErrRiskNotFound)
 	}
 	return nil
 }
+
+// ListDeleted returns the risks currently in the trash, most recently deleted first
+func (r *riskRepository) ListDeleted(ctx context.Context) ([]*models.Risk, error) {
+	query := `
+		SELECT r.id, r.title, r.description, r.owner_id, r.status, r.severity, r.category_id, r.review_date, r.created_at, r.updated_at, r.created_by, r.updated_by, r.version, r.tags, r.deleted_at,
+		       c.id, c.name, c.description
+		FROM risks r
+		LEFT JOIN categories c ON r.category_id = c.id
+		WHERE r.deleted_at IS NOT NULL
+		ORDER BY r.deleted_at DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var risks []*models.Risk
+	for rows.Next() {
+		risk := &models.Risk{}
+		var catID, catName, catDesc sql.NullString
+		err := rows.Scan(
+			&risk.ID, &risk.Title, &risk.Description, &risk.OwnerID, &risk.Status, &risk.Severity,
+			&risk.CategoryID, &risk.ReviewDate, &risk.CreatedAt, &risk.UpdatedAt, &risk.CreatedBy, &risk.UpdatedBy, &risk.Version, tagsColumn{&risk.Tags}, &risk.DeletedAt,
+			&catID, &catName, &catDesc,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if catID.Valid {
+			risk.Category = &models.Category{
+				ID:          catID.String,
+				Name:        catName.String,
+				Description: catDesc.String,
+			}
+		}
+		risks = append(risks, risk)
+	}
+
+	return risks, rows.Err()
+}
+
+// Restore takes a risk back out of the trash
+func (r *riskRepository) Restore(ctx context.Context, id string) error {
+	return restoreFromTrash(ctx, r.db, "risks", id, ErrRiskNotFound)
+}
+
+// Purge permanently deletes a trashed risk, cascading to its mitigations and
+// links. Only risks trashed on or before deletedBefore can be purged.
+func (r *riskRepository) Purge(ctx context.Context, id string, deletedBefore time.Time) error {
+	return purgeFromTrash(ctx, r.db, "risks", id, deletedBefore, ErrRiskNotFound)
+}
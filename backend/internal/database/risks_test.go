@@ -2,7 +2,9 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"testing"
+	"time"
 
 	"backend/internal/models"
 
@@ -55,6 +57,7 @@ func TestRiskRepository_Integration(t *testing.T) {
 	err = riskRepo.Create(ctx, risk)
 	require.NoError(t, err)
 	assert.NotEmpty(t, risk.ID)
+	assert.Equal(t, 1, risk.Version)
 
 	// 3. Get Risk
 	fetchedRisk, err := riskRepo.FindByID(ctx, risk.ID)
@@ -72,6 +75,14 @@ func TestRiskRepository_Integration(t *testing.T) {
 	fetchedRisk, err = riskRepo.FindByID(ctx, risk.ID)
 	require.NoError(t, err)
 	assert.Equal(t, newTitle, fetchedRisk.Title)
+	assert.Equal(t, 2, fetchedRisk.Version)
+
+	// A write carrying the version read before the update is rejected
+	stale := *fetchedRisk
+	stale.Version = 1
+	stale.Title = "Stale Title"
+	err = riskRepo.Update(ctx, &stale)
+	assert.ErrorIs(t, err, ErrVersionConflict)
 
 	// 5. List Risks
 	params := &models.RiskListParams{
@@ -82,11 +93,123 @@ func TestRiskRepository_Integration(t *testing.T) {
 	require.NoError(t, err)
 	assert.GreaterOrEqual(t, len(listResp.Data), 1)
 
-	// 6. Delete Risk
-	err = riskRepo.Delete(ctx, risk.ID)
+	// 6. Delete Risk, which like an update must carry the current version
+	assert.ErrorIs(t, riskRepo.Delete(ctx, risk.ID, 1), ErrVersionConflict)
+	err = riskRepo.Delete(ctx, risk.ID, fetchedRisk.Version)
 	require.NoError(t, err)
 
 	_, err = riskRepo.FindByID(ctx, risk.ID)
 	assert.Error(t, err)
 	assert.Equal(t, ErrRiskNotFound, err)
+
+	// 7. Trash, restore and purge
+	trashed, err := riskRepo.ListDeleted(ctx)
+	require.NoError(t, err)
+	found := false
+	for _, r := range trashed {
+		if r.ID == risk.ID {
+			found = true
+			assert.NotNil(t, r.DeletedAt)
+		}
+	}
+	assert.True(t, found, "deleted risk should be in the trash")
+
+	err = riskRepo.Purge(ctx, risk.ID, time.Now().Add(-time.Hour))
+	assert.Equal(t, ErrPurgeGracePeriod, err)
+
+	err = riskRepo.Restore(ctx, risk.ID)
+	require.NoError(t, err)
+	_, err = riskRepo.FindByID(ctx, risk.ID)
+	require.NoError(t, err)
+
+	err = riskRepo.Restore(ctx, risk.ID)
+	assert.Equal(t, ErrNotInTrash, err)
+
+	restored, err := riskRepo.FindByID(ctx, risk.ID)
+	require.NoError(t, err)
+	require.NoError(t, riskRepo.Delete(ctx, risk.ID, restored.Version))
+	require.NoError(t, riskRepo.Purge(ctx, risk.ID, time.Now().Add(time.Minute)))
+	err = riskRepo.Restore(ctx, risk.ID)
+	assert.Equal(t, ErrRiskNotFound, err)
+}
+
+func TestRiskRepository_TagsAndTransactions(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	riskRepo := NewRiskRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	transactor := NewTransactor(s.db)
+
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "test-bulk-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Bulk Tester",
+		Role:         models.RoleMember,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+
+	tag := "bulk-" + uuid.New().String()
+	risk := &models.Risk{
+		Title:     "Tagged Risk",
+		OwnerID:   user.ID,
+		Status:    models.StatusOpen,
+		Severity:  models.SeverityLow,
+		Tags:      []string{tag},
+		CreatedBy: user.ID,
+		UpdatedBy: user.ID,
+	}
+	require.NoError(t, riskRepo.Create(ctx, risk))
+
+	fetched, err := riskRepo.FindByID(ctx, risk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{tag}, fetched.Tags)
+
+	ids, err := riskRepo.ListIDs(ctx, &models.RiskListParams{Tag: tag})
+	require.NoError(t, err)
+	assert.Equal(t, []string{risk.ID}, ids)
+
+	// A rolled back transaction leaves the risk untouched
+	err = transactor.InTx(ctx, func(tx *sql.Tx) error {
+		fetched.Status = models.StatusResolved
+		if err := riskRepo.WithTx(tx).Update(ctx, fetched); err != nil {
+			return err
+		}
+		return ErrRollback
+	})
+	require.NoError(t, err)
+	current, err := riskRepo.FindByID(ctx, risk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.StatusOpen, current.Status)
+
+	// A failed savepoint only undoes its own writes
+	err = transactor.InTx(ctx, func(tx *sql.Tx) error {
+		failed := transactor.Savepoint(ctx, tx, func() error {
+			current.Title = "Undone"
+			if err := riskRepo.WithTx(tx).Update(ctx, current); err != nil {
+				return err
+			}
+			return ErrRiskNotFound
+		})
+		assert.ErrorIs(t, failed, ErrRiskNotFound)
+
+		return transactor.Savepoint(ctx, tx, func() error {
+			reread, err := riskRepo.WithTx(tx).FindByID(ctx, risk.ID)
+			if err != nil {
+				return err
+			}
+			reread.Tags = append(reread.Tags, "kept")
+			return riskRepo.WithTx(tx).Update(ctx, reread)
+		})
+	})
+	require.NoError(t, err)
+	current, err = riskRepo.FindByID(ctx, risk.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Tagged Risk", current.Title)
+	assert.Equal(t, []string{tag, "kept"}, current.Tags)
 }
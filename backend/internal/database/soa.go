@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrSoAVersionNotFound = errors.New("SoA version not found")
+	ErrSoAVersionPending  = errors.New("framework already has an SoA version awaiting approval")
+	ErrSoAVersionDecided  = errors.New("SoA version has already been approved or rejected")
+	ErrSoAOwnerNotFound   = errors.New("owner not found")
+	ErrSoASelfDecision    = errors.New("an SoA version must be approved or rejected by someone other than its author")
+)
+
+type SoARepository interface {
+	// Get builds the working SoA of a framework from the current
+	// applicability records, in tree order without family headings
+	Get(ctx context.Context, frameworkID string) (*models.StatementOfApplicability, error)
+	// GetEntry returns the working SoA entry of one control
+	GetEntry(ctx context.Context, controlID string) (*models.SoAEntry, error)
+	// SetApplicability creates or replaces a control's applicability record
+	SetApplicability(ctx context.Context, controlID string, input *models.SetApplicabilityInput, userID string) error
+	// ListVersions returns a framework's versions, newest first, without
+	// their entries
+	ListVersions(ctx context.Context, frameworkID string) ([]*models.SoAVersion, error)
+	GetVersion(ctx context.Context, id string) (*models.SoAVersion, error)
+	GetVersionByNumber(ctx context.Context, frameworkID string, version int) (*models.SoAVersion, error)
+	// CreateVersion stores a pending version with the framework's next
+	// number and sets its ID, Version, Status and CreatedAt
+	CreateVersion(ctx context.Context, version *models.SoAVersion) error
+	// Decide approves or rejects a pending version. ErrSoASelfDecision means
+	// userID created it.
+	Decide(ctx context.Context, id string, status models.SoAVersionStatus, comment, userID string) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) SoARepository
+}
+
+type soaRepository struct {
+	db dbtx
+}
+
+func NewSoARepository(db *sql.DB) SoARepository {
+	return &soaRepository{db: db}
+}
+
+func (r *soaRepository) WithTx(tx *sql.Tx) SoARepository {
+	return &soaRepository{db: tx}
+}
+
+func (r *soaRepository) Get(ctx context.Context, frameworkID string) (*models.StatementOfApplicability, error) {
+	name, err := frameworkName(ctx, r.db, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT fc.id, fc.control_ref, fc.title, fc.parent_id, fc.sort_order,
+			ca.applicability, COALESCE(ca.justification, ''), COALESCE(ca.implementation_status, ''),
+			ca.owner_id, COALESCE(u.name, ''), ca.updated_at,
+			(SELECT COUNT(*) FROM risk_framework_controls rfc
+				JOIN risks r ON r.id = rfc.risk_id AND r.deleted_at IS NULL
+				WHERE rfc.framework_control_id = fc.id)
+		FROM framework_controls fc
+		LEFT JOIN control_applicability ca ON ca.framework_control_id = fc.id
+		LEFT JOIN users u ON u.id = ca.owner_id
+		WHERE fc.framework_id = $1
+	`, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var controls []*models.FrameworkControl
+	entries := map[string]*models.SoAEntry{}
+	for rows.Next() {
+		control := &models.FrameworkControl{FrameworkID: frameworkID}
+		entry := &models.SoAEntry{}
+		var applicability sql.NullString
+		if err := rows.Scan(
+			&control.ID,
+			&control.ControlRef,
+			&control.Title,
+			&control.ParentID,
+			&control.SortOrder,
+			&applicability,
+			&entry.Justification,
+			&entry.ImplementationStatus,
+			&entry.OwnerID,
+			&entry.OwnerName,
+			&entry.UpdatedAt,
+			&entry.LinkedRiskCount,
+		); err != nil {
+			return nil, err
+		}
+		entry.Assessed = applicability.Valid
+		entry.Applicability = models.Applicability(applicability.String)
+		controls = append(controls, control)
+		entries[control.ID] = entry
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	soa := &models.StatementOfApplicability{FrameworkID: frameworkID, FrameworkName: name}
+	soa.Entries = buildSoAEntries(controls, entries)
+	soa.Summary = summarizeSoA(soa.Entries)
+	return soa, nil
+}
+
+// buildSoAEntries fills in each entry's control and family and returns them
+// in the order of walkControls
+func buildSoAEntries(controls []*models.FrameworkControl, entries map[string]*models.SoAEntry) []*models.SoAEntry {
+	list := []*models.SoAEntry{}
+	walkControls(controls, func(control *models.FrameworkControl, family, _ string, _ bool) {
+		entry := entries[control.ID]
+		entry.ControlID, entry.ControlRef, entry.Title, entry.Family = control.ID, control.ControlRef, control.Title, family
+		list = append(list, entry)
+	})
+	return list
+}
+
+// summarizeSoA counts entries by applicability and, for applicable controls,
+// by implementation status
+func summarizeSoA(entries []*models.SoAEntry) models.SoASummary {
+	summary := models.SoASummary{Total: len(entries), Implementation: map[models.ImplementationStatus]int{
+		models.ImplementationNotImplemented: 0,
+		models.ImplementationPlanned:        0,
+		models.ImplementationPartial:        0,
+		models.ImplementationImplemented:    0,
+	}}
+	for _, entry := range entries {
+		switch {
+		case !entry.Assessed:
+			summary.Unassessed++
+		case entry.Applicability == models.ApplicabilityExcluded:
+			summary.Excluded++
+		default:
+			summary.Applicable++
+			summary.Implementation[entry.ImplementationStatus]++
+		}
+	}
+	return summary
+}
+
+func (r *soaRepository) GetEntry(ctx context.Context, controlID string) (*models.SoAEntry, error) {
+	var frameworkID string
+	err := r.db.QueryRowContext(ctx, `SELECT framework_id FROM framework_controls WHERE id = $1`, controlID).Scan(&frameworkID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrFrameworkControlNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	soa, err := r.Get(ctx, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range soa.Entries {
+		if entry.ControlID == controlID {
+			return entry, nil
+		}
+	}
+	// Family headings aren't part of the SoA
+	return nil, ErrFrameworkControlNotFound
+}
+
+func (r *soaRepository) SetApplicability(ctx context.Context, controlID string, input *models.SetApplicabilityInput, userID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO control_applicability
+			(framework_control_id, applicability, justification, implementation_status, owner_id, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (framework_control_id) DO UPDATE SET
+			applicability = EXCLUDED.applicability,
+			justification = EXCLUDED.justification,
+			implementation_status = EXCLUDED.implementation_status,
+			owner_id = EXCLUDED.owner_id,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+	`, controlID, input.Applicability, input.Justification, input.ImplementationStatus, input.OwnerID, userID)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "control_applicability_owner_id_fkey" {
+			return ErrSoAOwnerNotFound
+		}
+		return ErrFrameworkControlNotFound
+	}
+	return err
+}
+
+const soaVersionSelect = `
+	SELECT v.id, v.framework_id, f.name, v.version, v.title, v.notes, v.status, v.entries,
+		v.created_by, COALESCE(cu.name, ''), v.created_at,
+		v.decided_by, COALESCE(du.name, ''), v.decided_at, v.decision_comment
+	FROM soa_versions v
+	JOIN frameworks f ON f.id = v.framework_id
+	LEFT JOIN users cu ON cu.id = v.created_by
+	LEFT JOIN users du ON du.id = v.decided_by
+`
+
+func scanSoAVersion(row interface{ Scan(...any) error }) (*models.SoAVersion, error) {
+	v := &models.SoAVersion{}
+	var entries []byte
+	err := row.Scan(&v.ID, &v.FrameworkID, &v.FrameworkName, &v.Version, &v.Title, &v.Notes, &v.Status, &entries,
+		&v.CreatedBy, &v.CreatedByName, &v.CreatedAt, &v.DecidedBy, &v.DecidedByName, &v.DecidedAt, &v.DecisionComment)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(entries, &v.Entries); err != nil {
+		return nil, err
+	}
+	v.Summary = summarizeSoA(v.Entries)
+	return v, nil
+}
+
+func (r *soaRepository) ListVersions(ctx context.Context, frameworkID string) ([]*models.SoAVersion, error) {
+	if _, err := frameworkName(ctx, r.db, frameworkID); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, soaVersionSelect+` WHERE v.framework_id = $1 ORDER BY v.version DESC`, frameworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*models.SoAVersion{}
+	for rows.Next() {
+		version, err := scanSoAVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		version.Entries = nil
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+func (r *soaRepository) GetVersion(ctx context.Context, id string) (*models.SoAVersion, error) {
+	version, err := scanSoAVersion(r.db.QueryRowContext(ctx, soaVersionSelect+` WHERE v.id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSoAVersionNotFound
+	}
+	return version, err
+}
+
+func (r *soaRepository) GetVersionByNumber(ctx context.Context, frameworkID string, number int) (*models.SoAVersion, error) {
+	version, err := scanSoAVersion(r.db.QueryRowContext(ctx,
+		soaVersionSelect+` WHERE v.framework_id = $1 AND v.version = $2`, frameworkID, number))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSoAVersionNotFound
+	}
+	return version, err
+}
+
+func (r *soaRepository) CreateVersion(ctx context.Context, version *models.SoAVersion) error {
+	entries, err := json.Marshal(version.Entries)
+	if err != nil {
+		return err
+	}
+	err = r.db.QueryRowContext(ctx, `
+		INSERT INTO soa_versions (framework_id, version, title, notes, entries, created_by)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5
+		FROM soa_versions WHERE framework_id = $1
+		RETURNING id, version, status, created_at
+	`, version.FrameworkID, version.Title, version.Notes, entries, version.CreatedBy).
+		Scan(&version.ID, &version.Version, &version.Status, &version.CreatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "23505" && pgErr.ConstraintName == "idx_soa_versions_pending":
+			return ErrSoAVersionPending
+		case pgErr.Code == "23503":
+			return ErrFrameworkNotFound
+		}
+	}
+	return err
+}
+
+func (r *soaRepository) Decide(ctx context.Context, id string, status models.SoAVersionStatus, comment, userID string) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE soa_versions
+		SET status = $2, decision_comment = $3, decided_by = $4, decided_at = NOW()
+		WHERE id = $1 AND status = 'pending' AND created_by IS DISTINCT FROM $4
+	`, id, status, comment, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows > 0 {
+		return nil
+	}
+
+	// Otherwise the version is missing, already decided, or pending and
+	// created by userID
+	var current models.SoAVersionStatus
+	err = r.db.QueryRowContext(ctx, `SELECT status FROM soa_versions WHERE id = $1`, id).Scan(&current)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrSoAVersionNotFound
+	case err != nil:
+		return err
+	case current != models.SoAPending:
+		return ErrSoAVersionDecided
+	}
+	return ErrSoASelfDecision
+}
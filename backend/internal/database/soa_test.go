@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSoAEntries(t *testing.T) {
+	parent := "a5"
+	controls := []*models.FrameworkControl{
+		{ID: "a5-2", ControlRef: "A.5.2", Title: "Roles", ParentID: &parent},
+		{ID: "a5", ControlRef: "A.5", Title: "Organizational controls"},
+		{ID: "a5-1", ControlRef: "A.5.1", Title: "Policies", ParentID: &parent},
+		{ID: "cc6-1", ControlRef: "CC6.1", Title: "Logical access"},
+	}
+	entries := map[string]*models.SoAEntry{
+		"a5":    {},
+		"a5-1":  {Assessed: true, Applicability: models.ApplicabilityApplicable, ImplementationStatus: models.ImplementationPartial},
+		"a5-2":  {Assessed: true, Applicability: models.ApplicabilityExcluded, ImplementationStatus: models.ImplementationNotImplemented},
+		"cc6-1": {},
+	}
+
+	list := buildSoAEntries(controls, entries)
+	require.Len(t, list, 3, "family headings are left out")
+	assert.Equal(t, "A.5.1", list[0].ControlRef)
+	assert.Equal(t, "A.5", list[0].Family)
+	assert.Equal(t, "cc6-1", list[2].ControlID)
+	assert.Equal(t, "CC6", list[2].Family)
+
+	summary := summarizeSoA(list)
+	assert.Equal(t, 3, summary.Total)
+	assert.Equal(t, 1, summary.Unassessed)
+	assert.Equal(t, 1, summary.Applicable)
+	assert.Equal(t, 1, summary.Excluded)
+	assert.Equal(t, 1, summary.Implementation[models.ImplementationPartial])
+	assert.Equal(t, 0, summary.Implementation[models.ImplementationNotImplemented], "excluded controls aren't counted")
+}
+
+func TestSoARepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewSoARepository(s.db)
+	frameworkRepo := NewFrameworkRepository(s.db)
+	controlRepo := NewFrameworkControlRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "soa-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "SoA Owner",
+		Role:         models.RoleAdmin,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+	framework, err := frameworkRepo.Create(ctx, &models.CreateFrameworkInput{Name: "ISO SoA " + uuid.New().String()})
+	require.NoError(t, err)
+	policies, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{FrameworkID: framework.ID, ControlRef: "A.5.1", Title: "Policies"})
+	require.NoError(t, err)
+	perimeter, err := controlRepo.Create(ctx, &models.CreateFrameworkControlInput{FrameworkID: framework.ID, ControlRef: "A.7.1", Title: "Perimeters"})
+	require.NoError(t, err)
+
+	soa, err := repo.Get(ctx, framework.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, soa.Summary.Unassessed)
+
+	require.NoError(t, repo.SetApplicability(ctx, policies.ID, &models.SetApplicabilityInput{
+		Applicability: models.ApplicabilityApplicable, ImplementationStatus: models.ImplementationImplemented, OwnerID: &user.ID,
+	}, user.ID))
+	require.NoError(t, repo.SetApplicability(ctx, perimeter.ID, &models.SetApplicabilityInput{
+		Applicability: models.ApplicabilityExcluded, Justification: "Fully remote", ImplementationStatus: models.ImplementationNotImplemented,
+	}, user.ID))
+	missing := uuid.New().String()
+	assert.ErrorIs(t, repo.SetApplicability(ctx, policies.ID, &models.SetApplicabilityInput{
+		Applicability: models.ApplicabilityApplicable, ImplementationStatus: models.ImplementationPlanned, OwnerID: &missing,
+	}, user.ID), ErrSoAOwnerNotFound)
+
+	entry, err := repo.GetEntry(ctx, policies.ID)
+	require.NoError(t, err)
+	assert.True(t, entry.Assessed)
+	assert.Equal(t, "SoA Owner", entry.OwnerName)
+
+	soa, err = repo.Get(ctx, framework.ID)
+	require.NoError(t, err)
+	version := &models.SoAVersion{FrameworkID: framework.ID, Title: "2026 audit", Entries: soa.Entries, CreatedBy: &user.ID}
+	require.NoError(t, repo.CreateVersion(ctx, version))
+	assert.Equal(t, 1, version.Version)
+	assert.Equal(t, models.SoAPending, version.Status)
+	assert.ErrorIs(t, repo.CreateVersion(ctx, &models.SoAVersion{FrameworkID: framework.ID, Entries: soa.Entries}), ErrSoAVersionPending)
+
+	assert.ErrorIs(t, repo.Decide(ctx, version.ID, models.SoAApproved, "ok", user.ID), ErrSoASelfDecision)
+	approver := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "soa-approver-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "SoA Approver",
+		Role:         models.RoleAdmin,
+	}
+	require.NoError(t, userRepo.Create(ctx, approver))
+	require.NoError(t, repo.Decide(ctx, version.ID, models.SoAApproved, "ok", approver.ID))
+	assert.ErrorIs(t, repo.Decide(ctx, version.ID, models.SoARejected, "no", approver.ID), ErrSoAVersionDecided)
+	assert.ErrorIs(t, repo.Decide(ctx, uuid.New().String(), models.SoARejected, "no", approver.ID), ErrSoAVersionNotFound)
+
+	second := &models.SoAVersion{FrameworkID: framework.ID, Entries: soa.Entries}
+	require.NoError(t, repo.CreateVersion(ctx, second))
+	assert.Equal(t, 2, second.Version)
+
+	fetched, err := repo.GetVersionByNumber(ctx, framework.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.SoAApproved, fetched.Status)
+	assert.Equal(t, "SoA Approver", fetched.DecidedByName)
+	require.Len(t, fetched.Entries, 2)
+	assert.Equal(t, 1, fetched.Summary.Excluded)
+
+	versions, err := repo.ListVersions(ctx, framework.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 2, versions[0].Version)
+	assert.Nil(t, versions[0].Entries)
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// tagsColumn scans a JSONB tags column into a string slice. A missing value
+// scans as an empty slice so tags always serialize as an array.
+type tagsColumn struct {
+	dst *[]string
+}
+
+func (t tagsColumn) Scan(src any) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*t.dst = []string{}
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into tags", src)
+	}
+
+	tags := []string{}
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return err
+	}
+	*t.dst = tags
+	return nil
+}
+
+// tagsValue encodes tags for a JSONB tags column
+func tagsValue(tags []string) string {
+	if len(tags) == 0 {
+		return "[]"
+	}
+	b, _ := json.Marshal(tags)
+	return string(b)
+}
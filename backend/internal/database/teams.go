@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"backend/internal/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrTeamNotFound = errors.New("team not found")
+	ErrTeamExists   = errors.New("team already exists")
+)
+
+type TeamRepository interface {
+	Create(ctx context.Context, team *models.Team) error
+	FindByID(ctx context.Context, id string) (*models.Team, error)
+	List(ctx context.Context) ([]*models.Team, error)
+	Update(ctx context.Context, team *models.Team) error
+	Delete(ctx context.Context, id string) error
+	// SetMembers replaces the team's members with userIDs
+	SetMembers(ctx context.Context, teamID string, userIDs []string) error
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) TeamRepository
+}
+
+type teamRepository struct {
+	db dbtx
+}
+
+func NewTeamRepository(db *sql.DB) TeamRepository {
+	return &teamRepository{db: db}
+}
+
+func (r *teamRepository) WithTx(tx *sql.Tx) TeamRepository {
+	return &teamRepository{db: tx}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (r *teamRepository) Create(ctx context.Context, team *models.Team) error {
+	err := r.db.QueryRowContext(ctx, `INSERT INTO teams (name) VALUES ($1) RETURNING id, created_at, updated_at`, team.Name).
+		Scan(&team.ID, &team.CreatedAt, &team.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrTeamExists
+	}
+	return err
+}
+
+func (r *teamRepository) FindByID(ctx context.Context, id string) (*models.Team, error) {
+	teams, err := r.list(ctx, `SELECT id, name, created_at, updated_at FROM teams WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(teams) == 0 {
+		return nil, ErrTeamNotFound
+	}
+	return teams[0], nil
+}
+
+func (r *teamRepository) List(ctx context.Context) ([]*models.Team, error) {
+	return r.list(ctx, `SELECT id, name, created_at, updated_at FROM teams ORDER BY name`)
+}
+
+// list runs query for teams and then loads the members of all of them in
+// one go
+func (r *teamRepository) list(ctx context.Context, query string, args ...any) ([]*models.Team, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []*models.Team{}
+	byID := map[string]*models.Team{}
+	for rows.Next() {
+		team := &models.Team{Members: []*models.TeamMember{}}
+		if err := rows.Scan(&team.ID, &team.Name, &team.CreatedAt, &team.UpdatedAt); err != nil {
+			return nil, err
+		}
+		teams = append(teams, team)
+		byID[team.ID] = team
+	}
+	if err := rows.Err(); err != nil || len(teams) == 0 {
+		return teams, err
+	}
+	rows.Close()
+
+	ids := make([]string, len(teams))
+	for i, team := range teams {
+		ids[i] = team.ID
+	}
+	members, err := r.db.QueryContext(ctx, `
+		SELECT tm.team_id, u.id, u.name, u.email
+		FROM team_members tm
+		JOIN users u ON u.id = tm.user_id
+		WHERE tm.team_id::text IN (SELECT jsonb_array_elements_text($1::jsonb))
+		ORDER BY u.name, u.email
+	`, tagsValue(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer members.Close()
+	for members.Next() {
+		var teamID string
+		m := &models.TeamMember{}
+		if err := members.Scan(&teamID, &m.UserID, &m.Name, &m.Email); err != nil {
+			return nil, err
+		}
+		byID[teamID].Members = append(byID[teamID].Members, m)
+	}
+	return teams, members.Err()
+}
+
+func (r *teamRepository) Update(ctx context.Context, team *models.Team) error {
+	err := r.db.QueryRowContext(ctx, `UPDATE teams SET name = $2, updated_at = NOW() WHERE id = $1 RETURNING updated_at`,
+		team.ID, team.Name).Scan(&team.UpdatedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return ErrTeamNotFound
+	case isUniqueViolation(err):
+		return ErrTeamExists
+	}
+	return err
+}
+
+func (r *teamRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM teams WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTeamNotFound
+	}
+	return nil
+}
+
+func (r *teamRepository) SetMembers(ctx context.Context, teamID string, userIDs []string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM team_members WHERE team_id = $1`, teamID); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO team_members (team_id, user_id)
+		SELECT $1::uuid, value::uuid FROM jsonb_array_elements_text($2::jsonb)
+		ON CONFLICT DO NOTHING
+	`, teamID, tagsValue(userIDs))
+	return err
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrNotInTrash = errors.New("entity is not in the trash")
+var ErrPurgeGracePeriod = errors.New("purge grace period has not elapsed")
+
+// restoreFromTrash clears deleted_at on a trashed row. table must be a trusted
+// identifier; it is interpolated into the query.
+func restoreFromTrash(ctx context.Context, db dbtx, table, id string, notFound error) error {
+	result, err := db.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET deleted_at = NULL, version = version + 1 WHERE id = $1 AND deleted_at IS NOT NULL", table), id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return trashState(ctx, db, table, id, notFound)
+	}
+	return nil
+}
+
+// purgeFromTrash hard-deletes a row that has been in the trash since at least
+// deletedBefore. table must be a trusted identifier.
+func purgeFromTrash(ctx context.Context, db dbtx, table, id string, deletedBefore time.Time, notFound error) error {
+	result, err := db.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at <= $2", table), id, deletedBefore)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		if err := trashState(ctx, db, table, id, notFound); err != nil {
+			return err
+		}
+		return ErrPurgeGracePeriod
+	}
+	return nil
+}
+
+// trashState explains why a trash operation matched no rows: the row is
+// missing (notFound), live (ErrNotInTrash), or trashed (nil).
+func trashState(ctx context.Context, db dbtx, table, id string, notFound error) error {
+	var deletedAt sql.NullTime
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT deleted_at FROM %s WHERE id = $1", table), id).Scan(&deletedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return notFound
+		}
+		return err
+	}
+	if !deletedAt.Valid {
+		return ErrNotInTrash
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrRollback can be returned from an InTx callback to roll the transaction
+// back without reporting a failure, e.g. for a dry run.
+var ErrRollback = errors.New("transaction rolled back")
+
+// dbtx is the part of *sql.DB and *sql.Tx the repositories use, so the same
+// repository code can run standalone or inside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor runs multi-step writes atomically. Repositories that take part
+// expose WithTx to get a copy bound to the transaction.
+type Transactor interface {
+	// InTx runs fn in a transaction that commits if fn returns nil and rolls
+	// back otherwise. ErrRollback rolls back and makes InTx return nil.
+	InTx(ctx context.Context, fn func(tx *sql.Tx) error) error
+	// Savepoint runs fn inside a savepoint of tx, undoing only fn's writes if
+	// it fails so the rest of the transaction can carry on.
+	Savepoint(ctx context.Context, tx *sql.Tx, fn func() error) error
+}
+
+type transactor struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) InTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer commitHooks.Delete(tx)
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		if errors.Is(err, ErrRollback) {
+			return nil
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if hooks, ok := commitHooks.Load(tx); ok {
+		for _, hook := range hooks.(*txHooks).take() {
+			hook()
+		}
+	}
+	return nil
+}
+
+// commitHooks holds the AfterCommit callbacks of open transactions
+var commitHooks sync.Map // *sql.Tx -> *txHooks
+
+type txHooks struct {
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// len returns how many hooks have been added so far
+func (h *txHooks) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.hooks)
+}
+
+// truncate drops the hooks added after the first n
+func (h *txHooks) truncate(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if n < len(h.hooks) {
+		h.hooks = h.hooks[:n]
+	}
+}
+
+func (h *txHooks) take() []func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hooks := h.hooks
+	h.hooks = nil
+	return hooks
+}
+
+// AfterCommit runs fn once tx, begun by a Transactor, has committed, and
+// drops it if tx rolls back. With a nil tx fn runs straight away. Use it
+// for side effects outside the database that must not happen for changes
+// that are rolled back.
+func AfterCommit(tx *sql.Tx, fn func()) {
+	if tx == nil {
+		fn()
+		return
+	}
+	hooks, _ := commitHooks.LoadOrStore(tx, &txHooks{})
+	h := hooks.(*txHooks)
+	h.mu.Lock()
+	h.hooks = append(h.hooks, fn)
+	h.mu.Unlock()
+}
+
+// Savepoint also drops the AfterCommit hooks fn added if it fails, since the
+// writes they follow are undone
+func (t *transactor) Savepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT item"); err != nil {
+		return err
+	}
+	stored, _ := commitHooks.LoadOrStore(tx, &txHooks{})
+	hooks := stored.(*txHooks)
+	mark := hooks.len()
+	if err := fn(); err != nil {
+		hooks.truncate(mark)
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT item"); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT item")
+	return err
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactor_AfterCommit_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	transactor := NewTransactor(s.db)
+	ctx := context.Background()
+
+	ran := 0
+	require.NoError(t, transactor.InTx(ctx, func(tx *sql.Tx) error {
+		AfterCommit(tx, func() { ran++ })
+		assert.Equal(t, 0, ran, "hooks must wait for the commit")
+		return nil
+	}))
+	assert.Equal(t, 1, ran)
+
+	require.NoError(t, transactor.InTx(ctx, func(tx *sql.Tx) error {
+		AfterCommit(tx, func() { ran++ })
+		return ErrRollback
+	}))
+	assert.Equal(t, 1, ran, "hooks are dropped on rollback")
+
+	require.NoError(t, transactor.InTx(ctx, func(tx *sql.Tx) error {
+		AfterCommit(tx, func() { ran++ })
+		err := transactor.Savepoint(ctx, tx, func() error {
+			AfterCommit(tx, func() { ran += 10 })
+			return errors.New("item failed")
+		})
+		assert.Error(t, err)
+		return transactor.Savepoint(ctx, tx, func() error {
+			AfterCommit(tx, func() { ran++ })
+			return nil
+		})
+	}))
+	assert.Equal(t, 3, ran, "hooks of a rolled back savepoint are dropped")
+
+	AfterCommit(nil, func() { ran++ })
+	assert.Equal(t, 4, ran, "without a transaction the hook runs straight away")
+}
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrVersionConflict is returned when a write carries a version that no longer
+// matches the stored row, i.e. someone else modified it since it was read.
+var ErrVersionConflict = errors.New("version conflict")
+
+// versionConflictOr explains why a versioned UPDATE matched no rows. existsQuery
+// must select a single boolean for the row identified by $1: if the row is
+// still there the version was stale, otherwise notFound is returned.
+func versionConflictOr(ctx context.Context, db dbtx, existsQuery, id string, notFound error) error {
+	var exists bool
+	if err := db.QueryRowContext(ctx, existsQuery, id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return notFound
+}
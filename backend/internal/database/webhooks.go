@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/models"
+)
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	FindByID(ctx context.Context, id string) (*models.Webhook, error)
+	List(ctx context.Context) ([]*models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, id string) error
+	// Enqueue queues event for every active webhook subscribed to its type
+	// and returns how many deliveries were queued
+	Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error)
+	// ClaimDue returns up to limit pending deliveries that are due at now and
+	// pushes their next attempt out to leaseUntil, so other workers skip them
+	// while they are being sent. A worker that dies mid-send leaves the
+	// delivery to be retried once the lease runs out.
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDispatch, error)
+	RecordAttempt(ctx context.Context, deliveryID string, attempt *models.WebhookAttempt) error
+	FindDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, params *models.WebhookDeliveryListParams) ([]*models.WebhookDelivery, error)
+	// Redeliver queues a fresh copy of a delivery's event for immediate sending
+	Redeliver(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error)
+	// WithTx returns a copy of the repository that runs inside tx
+	WithTx(tx *sql.Tx) WebhookRepository
+}
+
+type webhookRepository struct {
+	db dbtx
+}
+
+func NewWebhookRepository(db *sql.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) WithTx(tx *sql.Tx) WebhookRepository {
+	return &webhookRepository{db: tx}
+}
+
+const webhookColumns = `id, name, url, secret, event_types, active, created_by, created_at, updated_at`
+
+func scanWebhook(row interface{ Scan(...any) error }) (*models.Webhook, error) {
+	w := &models.Webhook{}
+	err := row.Scan(&w.ID, &w.Name, &w.URL, &w.Secret, tagsColumn{&w.EventTypes}, &w.Active, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, w *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (name, url, secret, event_types, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+	return r.db.QueryRowContext(ctx, query, w.Name, w.URL, w.Secret, tagsValue(w.EventTypes), w.Active, w.CreatedBy).
+		Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (r *webhookRepository) FindByID(ctx context.Context, id string) (*models.Webhook, error) {
+	w, err := scanWebhook(r.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookNotFound
+	}
+	return w, err
+}
+
+func (r *webhookRepository) List(ctx context.Context) ([]*models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY name, created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*models.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *webhookRepository) Update(ctx context.Context, w *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET name = $2, url = $3, secret = $4, event_types = $5, active = $6, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`
+	err := r.db.QueryRowContext(ctx, query, w.ID, w.Name, w.URL, w.Secret, tagsValue(w.EventTypes), w.Active).Scan(&w.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepository) Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at)
+		SELECT id, $1, $2, $3, NOW()
+		FROM webhooks
+		WHERE active AND (event_types ? $2 OR event_types ? '*')
+	`
+	result, err := r.db.ExecContext(ctx, query, event.ID, event.Type, string(payload))
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.last_attempt_at, d.response_status, d.response_body, d.error, d.redelivery_of, d.created_at, d.delivered_at`
+
+func scanWebhookDelivery(row interface{ Scan(...any) error }, extra ...any) (*models.WebhookDelivery, error) {
+	d := &models.WebhookDelivery{}
+	dest := append([]any{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastAttemptAt, &d.ResponseStatus, &d.ResponseBody, &d.Error, &d.RedeliveryOf, &d.CreatedAt, &d.DeliveredAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *webhookRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDispatch, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT q.id
+			FROM webhook_deliveries q
+			JOIN webhooks qw ON qw.id = q.webhook_id
+			WHERE q.status = 'pending' AND q.next_attempt_at <= $1 AND qw.active
+			ORDER BY q.next_attempt_at
+			LIMIT $3
+			FOR UPDATE OF q SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns + `, w.url, w.secret
+	`
+	rows, err := r.db.QueryContext(ctx, query, now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dispatches := []*models.WebhookDispatch{}
+	for rows.Next() {
+		dispatch := &models.WebhookDispatch{}
+		dispatch.Delivery, err = scanWebhookDelivery(rows, &dispatch.URL, &dispatch.Secret)
+		if err != nil {
+			return nil, err
+		}
+		dispatches = append(dispatches, dispatch)
+	}
+	return dispatches, rows.Err()
+}
+
+func (r *webhookRepository) RecordAttempt(ctx context.Context, deliveryID string, a *models.WebhookAttempt) error {
+	var deliveredAt *time.Time
+	if a.Status == models.WebhookDeliveryDelivered {
+		deliveredAt = &a.AttemptedAt
+	}
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, last_attempt_at = $3, next_attempt_at = $4,
+		    response_status = $5, response_body = $6, error = $7, delivered_at = $8
+		WHERE id = $1
+	`
+	result, err := r.db.ExecContext(ctx, query, deliveryID, a.Status, a.AttemptedAt, a.NextAttemptAt,
+		a.ResponseStatus, a.ResponseBody, a.Error, deliveredAt)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrWebhookDeliveryNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepository) FindDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d WHERE d.id = $1`
+	d, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return d, err
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, params *models.WebhookDeliveryListParams) ([]*models.WebhookDelivery, error) {
+	conditions := []string{"TRUE"}
+	args := []any{}
+	if params.WebhookID != "" {
+		args = append(args, params.WebhookID)
+		conditions = append(conditions, fmt.Sprintf("d.webhook_id = $%d", len(args)))
+	}
+	if params.Status != nil {
+		args = append(args, *params.Status)
+		conditions = append(conditions, fmt.Sprintf("d.status = $%d", len(args)))
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webhook_deliveries d
+		WHERE %s
+		ORDER BY d.created_at DESC
+		LIMIT $%d
+	`, webhookDeliveryColumns, strings.Join(conditions, " AND "), len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepository) Redeliver(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	query := `
+		WITH d AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at, redelivery_of)
+			SELECT webhook_id, event_id, event_type, payload, NOW(), id
+			FROM webhook_deliveries
+			WHERE id = $1
+			RETURNING *
+		)
+		SELECT ` + webhookDeliveryColumns + ` FROM d
+	`
+	d, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, deliveryID))
+	if err == sql.ErrNoRows {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	return d, err
+}
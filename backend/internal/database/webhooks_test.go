@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRepository_Integration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	s := New().(*service)
+	repo := NewWebhookRepository(s.db)
+	userRepo := NewUserRepository(s.db)
+	ctx := context.Background()
+
+	user := &models.User{
+		ID:           uuid.New().String(),
+		Email:        "test-webhooks-" + uuid.New().String() + "@example.com",
+		PasswordHash: "hash",
+		Name:         "Webhook Admin",
+		Role:         models.RoleAdmin,
+	}
+	require.NoError(t, userRepo.Create(ctx, user))
+
+	hook := &models.Webhook{
+		Name:       "Pager",
+		URL:        "https://example.com/hook",
+		Secret:     "whsec_test",
+		EventTypes: []string{models.WebhookEventRiskCritical},
+		Active:     true,
+		CreatedBy:  user.ID,
+	}
+	require.NoError(t, repo.Create(ctx, hook))
+	defer repo.Delete(ctx, hook.ID)
+
+	countFor := func(eventID string) int {
+		var n int
+		require.NoError(t, s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1 AND event_id = $2`, hook.ID, eventID).Scan(&n))
+		return n
+	}
+
+	t.Run("Enqueue fans out to subscribed webhooks only", func(t *testing.T) {
+		created := &models.WebhookEvent{ID: uuid.New().String(), Type: models.WebhookEventRiskCreated, EntityType: "risk", EntityID: uuid.New().String()}
+		_, err := repo.Enqueue(ctx, created)
+		require.NoError(t, err)
+		assert.Equal(t, 0, countFor(created.ID))
+
+		critical := &models.WebhookEvent{ID: uuid.New().String(), Type: models.WebhookEventRiskCritical, EntityType: "risk", EntityID: uuid.New().String()}
+		_, err = repo.Enqueue(ctx, critical)
+		require.NoError(t, err)
+		assert.Equal(t, 1, countFor(critical.ID))
+	})
+
+	t.Run("Enqueue in a rolled back transaction queues nothing", func(t *testing.T) {
+		event := &models.WebhookEvent{ID: uuid.New().String(), Type: models.WebhookEventRiskCritical, EntityType: "risk", EntityID: uuid.New().String()}
+		tx, err := s.db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		_, err = repo.WithTx(tx).Enqueue(ctx, event)
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback())
+		assert.Equal(t, 0, countFor(event.ID))
+	})
+
+	t.Run("claim, fail, dead-letter and redeliver", func(t *testing.T) {
+		now := time.Now().Add(time.Minute)
+		dispatches, err := repo.ClaimDue(ctx, now, now.Add(time.Minute), 1000)
+		require.NoError(t, err)
+		var dispatch *models.WebhookDispatch
+		for _, d := range dispatches {
+			if d.Delivery.WebhookID == hook.ID {
+				dispatch = d
+			}
+		}
+		require.NotNil(t, dispatch)
+		assert.Equal(t, hook.URL, dispatch.URL)
+		assert.Equal(t, hook.Secret, dispatch.Secret)
+		assert.Contains(t, string(dispatch.Delivery.Payload), models.WebhookEventRiskCritical)
+
+		again, err := repo.ClaimDue(ctx, now, now.Add(time.Minute), 1000)
+		require.NoError(t, err)
+		for _, d := range again {
+			assert.NotEqual(t, dispatch.Delivery.ID, d.Delivery.ID, "a leased delivery must not be claimed twice")
+		}
+
+		status, msg := 500, "receiver responded with 500"
+		require.NoError(t, repo.RecordAttempt(ctx, dispatch.Delivery.ID, &models.WebhookAttempt{
+			Status: models.WebhookDeliveryDead, AttemptedAt: now, ResponseStatus: &status, Error: &msg,
+		}))
+
+		dead := models.WebhookDeliveryDead
+		deliveries, err := repo.ListDeliveries(ctx, &models.WebhookDeliveryListParams{WebhookID: hook.ID, Status: &dead})
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, msg, *deliveries[0].Error)
+
+		redelivery, err := repo.Redeliver(ctx, dispatch.Delivery.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.WebhookDeliveryPending, redelivery.Status)
+		assert.Equal(t, dispatch.Delivery.EventID, redelivery.EventID)
+		require.NotNil(t, redelivery.RedeliveryOf)
+		assert.Equal(t, dispatch.Delivery.ID, *redelivery.RedeliveryOf)
+
+		_, err = repo.Redeliver(ctx, uuid.New().String())
+		assert.ErrorIs(t, err, ErrWebhookDeliveryNotFound)
+	})
+}
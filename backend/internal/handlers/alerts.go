@@ -0,0 +1,444 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+
+	"backend/internal/alerts"
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxIngestAlerts caps how many alerts one ingestion request may carry
+const maxIngestAlerts = 500
+
+// alertIncidentTag marks incidents opened from an alert
+const alertIncidentTag = "alert"
+
+// AlertHandler manages alert sources and ingests their alerts. Ingestion is
+// authenticated with the source's bearer token rather than a user session;
+// incidents it opens or changes are attributed to the admin who created the
+// source.
+type AlertHandler struct {
+	tx                 database.Transactor
+	alerts             database.AlertRepository
+	incidents          database.IncidentRepository
+	incidentCategories database.IncidentCategoryRepository
+	audit              database.AuditLogRepository
+}
+
+func NewAlertHandler(
+	tx database.Transactor,
+	alertRepo database.AlertRepository,
+	incidents database.IncidentRepository,
+	incidentCategories database.IncidentCategoryRepository,
+	audit database.AuditLogRepository,
+) *AlertHandler {
+	return &AlertHandler{
+		tx:                 tx,
+		alerts:             alertRepo,
+		incidents:          incidents,
+		incidentCategories: incidentCategories,
+		audit:              audit,
+	}
+}
+
+func (h *AlertHandler) ListSources(c *fiber.Ctx) error {
+	sources, err := h.alerts.ListSources(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "alert sources")})
+	}
+	return c.JSON(sources)
+}
+
+func (h *AlertHandler) GetSource(c *fiber.Ctx) error {
+	source, err := h.findSource(c)
+	if err != nil || source == nil {
+		return err
+	}
+	return c.JSON(source)
+}
+
+// CreateSource registers an alert source and generates its token. This is
+// the only response that includes the token.
+func (h *AlertHandler) CreateSource(c *fiber.Ctx) error {
+	var input models.CreateAlertSourceInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	source := &models.AlertSource{
+		Name:      strings.TrimSpace(input.Name),
+		Active:    input.Active == nil || *input.Active,
+		CreatedBy: user.UserID,
+	}
+	if input.Mapping != nil {
+		source.Mapping = *input.Mapping
+	}
+	if errs := h.validateSource(c.Context(), source); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	token := newAlertToken()
+	if err := h.alerts.CreateSource(c.Context(), source, hashToken(token)); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToCreate, "alert source")})
+	}
+	source.Token = token
+	return c.Status(201).JSON(source)
+}
+
+// UpdateSource changes an alert source. With rotate_token the response
+// carries the new token and the old one stops working straight away.
+func (h *AlertHandler) UpdateSource(c *fiber.Ctx) error {
+	source, err := h.findSource(c)
+	if err != nil || source == nil {
+		return err
+	}
+
+	var input models.UpdateAlertSourceInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Name == nil && input.Mapping == nil && input.Active == nil && !input.RotateToken {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	if input.Name != nil {
+		source.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Mapping != nil {
+		source.Mapping = *input.Mapping
+	}
+	if input.Active != nil {
+		source.Active = *input.Active
+	}
+	if errs := h.validateSource(c.Context(), source); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	var token, tokenHash string
+	if input.RotateToken {
+		token = newAlertToken()
+		tokenHash = hashToken(token)
+	}
+	if err := h.alerts.UpdateSource(c.Context(), source, tokenHash); err != nil {
+		if errors.Is(err, database.ErrAlertSourceNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "alert source")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToUpdate, "alert source")})
+	}
+	source.Token = token
+	return c.JSON(source)
+}
+
+// DeleteSource removes an alert source. Incidents it opened are kept.
+func (h *AlertHandler) DeleteSource(c *fiber.Ctx) error {
+	if err := h.alerts.DeleteSource(c.Context(), c.Params("id")); err != nil {
+		if errors.Is(err, database.ErrAlertSourceNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "alert source")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToDelete, "alert source")})
+	}
+	return c.SendStatus(204)
+}
+
+// IncidentAlerts lists the alerts that opened or updated an incident
+func (h *AlertHandler) IncidentAlerts(c *fiber.Ctx) error {
+	list, err := h.alerts.ListByIncident(c.Context(), c.Params("incidentId"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "incident alerts")})
+	}
+	return c.JSON(list)
+}
+
+// Alertmanager ingests a Prometheus Alertmanager webhook notification
+func (h *AlertHandler) Alertmanager(c *fiber.Ctx) error {
+	return h.ingest(c, alerts.ParseAlertmanager)
+}
+
+// Generic ingests alerts in the generic JSON schema, for monitoring tools
+// that cannot send Alertmanager's format
+func (h *AlertHandler) Generic(c *fiber.Ctx) error {
+	return h.ingest(c, alerts.ParseGeneric)
+}
+
+// ingest opens, updates or resolves one incident per alert. The whole
+// payload is applied in one transaction, so a failure leaves nothing behind
+// and the sender's retry starts from scratch.
+func (h *AlertHandler) ingest(c *fiber.Ctx, parse func([]byte) ([]alerts.Alert, error)) error {
+	source, err := h.authenticate(c)
+	if err != nil || source == nil {
+		return err
+	}
+
+	batch, err := parse(c.Body())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(batch) > maxIngestAlerts {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("at most %d alerts can be sent at once", maxIngestAlerts)})
+	}
+
+	ctx := c.Context()
+	categories, err := h.incidentCategories.List(ctx)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "incident categories")})
+	}
+
+	// Alerts are applied in fingerprint order so two concurrent payloads
+	// take the per-fingerprint locks in the same order and cannot deadlock
+	order := make([]int, len(batch))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return batch[order[a]].Fingerprint < batch[order[b]].Fingerprint })
+
+	now := time.Now()
+	results := make([]models.AlertIngestResult, len(batch))
+	err = h.tx.InTx(ctx, func(tx *sql.Tx) error {
+		for _, i := range order {
+			result, err := h.apply(ctx, tx, source, &batch[i], categories, now)
+			if err != nil {
+				return fmt.Errorf("alert %s: %w", batch[i].Fingerprint, err)
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("alert ingestion from source %s failed: %v", source.ID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "failed to ingest alerts"})
+	}
+
+	if err := h.alerts.MarkReceived(ctx, source.ID, now); err != nil {
+		log.Printf("failed to record alert receipt for source %s: %v", source.ID, err)
+	}
+	return c.JSON(models.AlertIngestResponse{Results: results})
+}
+
+// apply handles one alert inside tx. A firing alert opens an incident unless
+// one is already open for its fingerprint, in which case the repeat is
+// counted and any change to its summary or description is copied over. A
+// resolved alert resolves the open incident.
+func (h *AlertHandler) apply(ctx context.Context, tx *sql.Tx, source *models.AlertSource, alert *alerts.Alert, categories []*models.IncidentCategory, now time.Time) (models.AlertIngestResult, error) {
+	links := h.alerts.WithTx(tx)
+	incidents := h.incidents.WithTx(tx)
+	audit := h.audit.WithTx(tx)
+	result := models.AlertIngestResult{Fingerprint: alert.Fingerprint}
+
+	link, err := links.FindOpen(ctx, source.ID, alert.Fingerprint)
+	if err != nil && !errors.Is(err, database.ErrIncidentAlertNotFound) {
+		return result, err
+	}
+
+	if link == nil {
+		if alert.Status == models.AlertStatusResolved {
+			result.Action = models.AlertIngestIgnored
+			return result, nil
+		}
+
+		fields := alerts.Map(source.Mapping, alert, categories)
+		incident := &models.Incident{
+			Title:           alert.Title,
+			Description:     alert.Description,
+			CategoryID:      fields.CategoryID,
+			Priority:        fields.Priority,
+			Status:          models.IncidentStatusNew,
+			ReporterID:      source.CreatedBy,
+			ServiceAffected: fields.ServiceAffected,
+			OccurredAt:      alert.StartsAt,
+			DetectedAt:      now,
+			Tags:            []string{alertIncidentTag},
+			CreatedBy:       source.CreatedBy,
+			UpdatedBy:       source.CreatedBy,
+		}
+		if err := incidents.Create(ctx, incident); err != nil {
+			return result, err
+		}
+		if err := audit.Create(ctx, "incident", incident.ID, models.AuditActionCreated, incidentAuditSnapshot(incident), source.CreatedBy); err != nil {
+			return result, err
+		}
+
+		link = &models.IncidentAlert{
+			SourceID:       source.ID,
+			Fingerprint:    alert.Fingerprint,
+			IncidentID:     incident.ID,
+			Status:         models.AlertStatusFiring,
+			Labels:         alert.Labels,
+			Annotations:    alert.Annotations,
+			StartsAt:       alert.StartsAt,
+			Occurrences:    1,
+			LastReceivedAt: now,
+		}
+		if err := links.CreateAlert(ctx, link); err != nil {
+			return result, err
+		}
+		result.Action = models.AlertIngestCreated
+		result.IncidentID = incident.ID
+		return result, nil
+	}
+
+	incident, err := incidents.FindByID(ctx, link.IncidentID)
+	if err != nil {
+		return result, err
+	}
+	incident.UpdatedBy = source.CreatedBy
+	changes := map[string]any{}
+
+	if alert.Status == models.AlertStatusResolved {
+		resolvedAt := now
+		if alert.EndsAt != nil {
+			resolvedAt = *alert.EndsAt
+		}
+		changes["status"] = auditChange(incident.Status, models.IncidentStatusResolved)
+		incident.Status = models.IncidentStatusResolved
+		if incident.ResolvedAt == nil {
+			changes["resolved_at"] = auditChange(nil, auditTime(&resolvedAt))
+			incident.ResolvedAt = &resolvedAt
+		}
+		if incident.ResolutionNotes == "" {
+			notes := "Alert resolved in " + source.Name
+			changes["resolution_notes"] = auditChange("", notes)
+			incident.ResolutionNotes = notes
+		}
+		link.Status = models.AlertStatusResolved
+		link.EndsAt = &resolvedAt
+		result.Action = models.AlertIngestResolved
+	} else {
+		// Only copy text the alert itself changed, so edits made by
+		// responders are not overwritten by every repeat
+		if !maps.Equal(link.Annotations, alert.Annotations) {
+			if incident.Title != alert.Title {
+				changes["title"] = auditChange(incident.Title, alert.Title)
+				incident.Title = alert.Title
+			}
+			if incident.Description != alert.Description {
+				changes["description"] = auditChange(incident.Description, alert.Description)
+				incident.Description = alert.Description
+			}
+		}
+		link.Occurrences++
+		result.Action = models.AlertIngestUpdated
+	}
+
+	if len(changes) > 0 {
+		if err := incidents.Update(ctx, incident); err != nil {
+			return result, err
+		}
+		if err := audit.Create(ctx, "incident", incident.ID, models.AuditActionUpdated, changes, source.CreatedBy); err != nil {
+			return result, err
+		}
+	}
+
+	link.Labels = alert.Labels
+	link.Annotations = alert.Annotations
+	link.LastReceivedAt = now
+	if err := links.UpdateAlert(ctx, link); err != nil {
+		return result, err
+	}
+	result.IncidentID = incident.ID
+	return result, nil
+}
+
+// authenticate resolves the alert source from the bearer token. When it
+// returns a nil source the response has already been written.
+func (h *AlertHandler) authenticate(c *fiber.Ctx) (*models.AlertSource, error) {
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok || strings.TrimSpace(token) == "" {
+		return nil, c.Status(401).JSON(fiber.Map{"error": "missing alert source token"})
+	}
+
+	source, err := h.alerts.FindSourceByToken(c.Context(), hashToken(strings.TrimSpace(token)))
+	if err != nil {
+		if errors.Is(err, database.ErrAlertSourceNotFound) {
+			return nil, c.Status(401).JSON(fiber.Map{"error": "invalid alert source token"})
+		}
+		return nil, c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "alert source")})
+	}
+	if !source.Active {
+		return nil, c.Status(403).JSON(fiber.Map{"error": "alert source is disabled"})
+	}
+	return source, nil
+}
+
+// findSource loads the alert source named in the path. When it returns a nil
+// source the response has already been written.
+func (h *AlertHandler) findSource(c *fiber.Ctx) (*models.AlertSource, error) {
+	source, err := h.alerts.FindSource(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrAlertSourceNotFound) {
+			return nil, c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "alert source")})
+		}
+		return nil, c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "alert source")})
+	}
+	return source, nil
+}
+
+// validateSource checks a source's name and that its mapping only names
+// valid priorities and existing incident categories
+func (h *AlertHandler) validateSource(ctx context.Context, s *models.AlertSource) fieldErrors {
+	errs := fieldErrors{}
+	requireText(errs, "name", &s.Name, 255)
+
+	m := &s.Mapping
+	validPriority := func(p models.IncidentPriority) bool {
+		return p == models.PriorityP1 || p == models.PriorityP2 || p == models.PriorityP3 || p == models.PriorityP4
+	}
+	if m.DefaultPriority != "" && !validPriority(m.DefaultPriority) {
+		errs["mapping.default_priority"] = "must be one of p1, p2, p3, p4"
+	}
+	for value, p := range m.PriorityMap {
+		if !validPriority(p) {
+			errs["mapping.priority_map"] = fmt.Sprintf("%q must map to one of p1, p2, p3, p4", value)
+		}
+	}
+
+	categoryExists := func(id string) bool {
+		cat, err := h.incidentCategories.FindByID(ctx, id)
+		return err == nil && cat != nil
+	}
+	if m.DefaultCategoryID != nil && *m.DefaultCategoryID == "" {
+		m.DefaultCategoryID = nil
+	}
+	if m.DefaultCategoryID != nil && (!optionalUUID(errs, "mapping.default_category_id", m.DefaultCategoryID) || !categoryExists(*m.DefaultCategoryID)) {
+		errs["mapping.default_category_id"] = "must be an existing incident category"
+	}
+	for value, id := range m.CategoryMap {
+		if !categoryExists(id) {
+			errs["mapping.category_map"] = fmt.Sprintf("%q must map to an existing incident category", value)
+		}
+	}
+	return errs
+}
+
+func newAlertToken() string {
+	return newToken("alrt_")
+}
+
+// newToken returns a random bearer token with a prefix saying what it is for
+func newToken(prefix string) string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
+
+// hashToken is what is stored for a token, so a leaked database does not
+// give away working tokens
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
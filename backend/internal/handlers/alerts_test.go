@@ -0,0 +1,364 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type mockAlertRepo struct {
+	sources   map[string]*models.AlertSource
+	tokens    map[string]string // token hash -> source ID
+	links     []*models.IncidentAlert
+	incidents *mockIncidentRepo
+}
+
+func newMockAlertRepo(incidents *mockIncidentRepo) *mockAlertRepo {
+	return &mockAlertRepo{sources: map[string]*models.AlertSource{}, tokens: map[string]string{}, incidents: incidents}
+}
+
+func (m *mockAlertRepo) CreateSource(ctx context.Context, s *models.AlertSource, tokenHash string) error {
+	s.ID = uuid.New().String()
+	s.CreatedAt = time.Now()
+	s.UpdatedAt = s.CreatedAt
+	stored := *s
+	m.sources[s.ID] = &stored
+	m.tokens[tokenHash] = s.ID
+	return nil
+}
+
+func (m *mockAlertRepo) FindSource(ctx context.Context, id string) (*models.AlertSource, error) {
+	s, ok := m.sources[id]
+	if !ok {
+		return nil, database.ErrAlertSourceNotFound
+	}
+	found := *s
+	return &found, nil
+}
+
+func (m *mockAlertRepo) FindSourceByToken(ctx context.Context, tokenHash string) (*models.AlertSource, error) {
+	id, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, database.ErrAlertSourceNotFound
+	}
+	return m.FindSource(ctx, id)
+}
+
+func (m *mockAlertRepo) ListSources(ctx context.Context) ([]*models.AlertSource, error) {
+	sources := []*models.AlertSource{}
+	for _, s := range m.sources {
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+func (m *mockAlertRepo) UpdateSource(ctx context.Context, s *models.AlertSource, tokenHash string) error {
+	if _, ok := m.sources[s.ID]; !ok {
+		return database.ErrAlertSourceNotFound
+	}
+	stored := *s
+	m.sources[s.ID] = &stored
+	if tokenHash != "" {
+		for hash, id := range m.tokens {
+			if id == s.ID {
+				delete(m.tokens, hash)
+			}
+		}
+		m.tokens[tokenHash] = s.ID
+	}
+	return nil
+}
+
+func (m *mockAlertRepo) DeleteSource(ctx context.Context, id string) error {
+	if _, ok := m.sources[id]; !ok {
+		return database.ErrAlertSourceNotFound
+	}
+	delete(m.sources, id)
+	return nil
+}
+
+func (m *mockAlertRepo) MarkReceived(ctx context.Context, sourceID string, at time.Time) error {
+	if s, ok := m.sources[sourceID]; ok {
+		s.LastReceivedAt = &at
+	}
+	return nil
+}
+
+func (m *mockAlertRepo) FindOpen(ctx context.Context, sourceID, fingerprint string) (*models.IncidentAlert, error) {
+	for i := len(m.links) - 1; i >= 0; i-- {
+		link := m.links[i]
+		if link.SourceID != sourceID || link.Fingerprint != fingerprint {
+			continue
+		}
+		incident, ok := m.incidents.incidents[link.IncidentID]
+		if ok && incident.Status != models.IncidentStatusResolved && incident.Status != models.IncidentStatusClosed {
+			found := *link
+			return &found, nil
+		}
+	}
+	return nil, database.ErrIncidentAlertNotFound
+}
+
+func (m *mockAlertRepo) CreateAlert(ctx context.Context, a *models.IncidentAlert) error {
+	a.ID = uuid.New().String()
+	a.CreatedAt = time.Now()
+	stored := *a
+	m.links = append(m.links, &stored)
+	return nil
+}
+
+func (m *mockAlertRepo) UpdateAlert(ctx context.Context, a *models.IncidentAlert) error {
+	for i, link := range m.links {
+		if link.ID == a.ID {
+			stored := *a
+			m.links[i] = &stored
+			return nil
+		}
+	}
+	return database.ErrIncidentAlertNotFound
+}
+
+func (m *mockAlertRepo) ListByIncident(ctx context.Context, incidentID string) ([]*models.IncidentAlert, error) {
+	list := []*models.IncidentAlert{}
+	for _, link := range m.links {
+		if link.IncidentID == incidentID {
+			list = append(list, link)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockAlertRepo) WithTx(tx *sql.Tx) database.AlertRepository {
+	return m
+}
+
+type alertTestEnv struct {
+	app       *fiber.App
+	alerts    *mockAlertRepo
+	incidents *mockIncidentRepo
+	audit     *mockAuditRepo
+	source    *models.AlertSource
+	token     string
+}
+
+func newAlertTestEnv(t *testing.T) *alertTestEnv {
+	t.Helper()
+	incidents := newMockIncidentRepo()
+	categories := newMockIncidentCategoryRepo()
+	categories.categories["cat-network"] = &models.IncidentCategory{ID: "cat-network", Name: "Network"}
+	env := &alertTestEnv{
+		app:       fiber.New(),
+		alerts:    newMockAlertRepo(incidents),
+		incidents: incidents,
+		audit:     &mockAuditRepo{logs: []*models.AuditLog{}},
+		token:     newAlertToken(),
+	}
+	env.source = &models.AlertSource{Name: "Prometheus", Active: true, CreatedBy: "admin-user-id"}
+	if err := env.alerts.CreateSource(context.Background(), env.source, hashToken(env.token)); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewAlertHandler(&mockTransactor{}, env.alerts, incidents, categories, env.audit)
+	env.app.Post("/alerts/alertmanager", handler.Alertmanager)
+	env.app.Post("/alerts/generic", handler.Generic)
+	env.app.Post("/alert-sources", testAuthMiddleware, handler.CreateSource)
+	env.app.Put("/alert-sources/:id", testAuthMiddleware, handler.UpdateSource)
+	return env
+}
+
+func (env *alertTestEnv) post(t *testing.T, path, token, body string) (int, models.AlertIngestResponse) {
+	t.Helper()
+	req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := env.app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result models.AlertIngestResponse
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func alertmanagerPayload(status, summary string) string {
+	endsAt := "0001-01-01T00:00:00Z"
+	if status == "resolved" {
+		endsAt = "2026-03-02T11:00:00Z"
+	}
+	return `{"version": "4", "status": "` + status + `", "alerts": [{
+		"status": "` + status + `",
+		"labels": {"alertname": "HighErrorRate", "severity": "critical", "service": "checkout", "category": "network"},
+		"annotations": {"summary": "` + summary + `"},
+		"startsAt": "2026-03-02T10:00:00Z",
+		"endsAt": "` + endsAt + `",
+		"fingerprint": "f1"
+	}]}`
+}
+
+func TestAlertIngest_Authentication(t *testing.T) {
+	env := newAlertTestEnv(t)
+
+	if status, _ := env.post(t, "/alerts/alertmanager", "", alertmanagerPayload("firing", "x")); status != 401 {
+		t.Errorf("expected 401 without a token, got %d", status)
+	}
+	if status, _ := env.post(t, "/alerts/alertmanager", "alrt_wrong", alertmanagerPayload("firing", "x")); status != 401 {
+		t.Errorf("expected 401 for an unknown token, got %d", status)
+	}
+
+	env.alerts.sources[env.source.ID].Active = false
+	if status, _ := env.post(t, "/alerts/alertmanager", env.token, alertmanagerPayload("firing", "x")); status != 403 {
+		t.Errorf("expected 403 for a disabled source, got %d", status)
+	}
+	if len(env.incidents.incidents) != 0 {
+		t.Error("rejected alerts must not open incidents")
+	}
+}
+
+func TestAlertIngest_Lifecycle(t *testing.T) {
+	env := newAlertTestEnv(t)
+
+	status, result := env.post(t, "/alerts/alertmanager", env.token, alertmanagerPayload("firing", "Checkout errors"))
+	if status != 200 || len(result.Results) != 1 || result.Results[0].Action != models.AlertIngestCreated {
+		t.Fatalf("expected the first alert to open an incident, got %d %+v", status, result)
+	}
+	incidentID := result.Results[0].IncidentID
+	incident := env.incidents.incidents[incidentID]
+	if incident.Title != "Checkout errors" || incident.Priority != models.PriorityP1 || incident.ServiceAffected != "checkout" {
+		t.Errorf("labels were not mapped: %+v", incident)
+	}
+	if incident.CategoryID == nil || *incident.CategoryID != "cat-network" {
+		t.Errorf("expected category cat-network, got %v", incident.CategoryID)
+	}
+	if incident.ReporterID != "admin-user-id" || incident.Status != models.IncidentStatusNew {
+		t.Errorf("unexpected reporter or status: %+v", incident)
+	}
+	if env.alerts.sources[env.source.ID].LastReceivedAt == nil {
+		t.Error("expected the source's last received time to be recorded")
+	}
+
+	// A repeat updates the open incident instead of opening another
+	status, result = env.post(t, "/alerts/alertmanager", env.token, alertmanagerPayload("firing", "Checkout errors rising"))
+	if status != 200 || result.Results[0].Action != models.AlertIngestUpdated || result.Results[0].IncidentID != incidentID {
+		t.Fatalf("expected the repeat to update the incident, got %d %+v", status, result)
+	}
+	if len(env.incidents.incidents) != 1 {
+		t.Errorf("expected 1 incident, got %d", len(env.incidents.incidents))
+	}
+	if env.incidents.incidents[incidentID].Title != "Checkout errors rising" {
+		t.Errorf("expected the changed summary to be copied over, got %q", env.incidents.incidents[incidentID].Title)
+	}
+	if env.alerts.links[0].Occurrences != 2 {
+		t.Errorf("expected 2 occurrences, got %d", env.alerts.links[0].Occurrences)
+	}
+
+	status, result = env.post(t, "/alerts/alertmanager", env.token, alertmanagerPayload("resolved", "Checkout errors rising"))
+	if status != 200 || result.Results[0].Action != models.AlertIngestResolved {
+		t.Fatalf("expected the resolved alert to resolve the incident, got %d %+v", status, result)
+	}
+	incident = env.incidents.incidents[incidentID]
+	if incident.Status != models.IncidentStatusResolved || incident.ResolvedAt == nil || incident.ResolvedAt.Hour() != 11 {
+		t.Errorf("expected the incident to be resolved at the alert's end time, got %+v", incident)
+	}
+
+	// Nothing is open any more, so a second resolved notification is ignored
+	// and a new firing alert opens a fresh incident
+	if _, result = env.post(t, "/alerts/alertmanager", env.token, alertmanagerPayload("resolved", "Checkout errors rising")); result.Results[0].Action != models.AlertIngestIgnored {
+		t.Errorf("expected the repeated resolve to be ignored, got %+v", result)
+	}
+	if _, result = env.post(t, "/alerts/alertmanager", env.token, alertmanagerPayload("firing", "Checkout errors")); result.Results[0].IncidentID == incidentID {
+		t.Error("a new firing alert should open a new incident once the old one is resolved")
+	}
+
+	var created, updated int
+	for _, entry := range env.audit.logs {
+		switch entry.Action {
+		case models.AuditActionCreated:
+			created++
+		case models.AuditActionUpdated:
+			updated++
+		}
+	}
+	if created != 2 || updated != 2 {
+		t.Errorf("expected 2 created and 2 updated audit entries, got %d and %d", created, updated)
+	}
+}
+
+func TestAlertIngest_Generic(t *testing.T) {
+	env := newAlertTestEnv(t)
+
+	status, result := env.post(t, "/alerts/generic", env.token, `[{"title": "Backup failed", "labels": {"severity": "warning"}}, {"title": "Backup failed", "labels": {"severity": "warning"}}]`)
+	if status != 200 || len(result.Results) != 2 {
+		t.Fatalf("unexpected response %d %+v", status, result)
+	}
+	if result.Results[0].Action != models.AlertIngestCreated || result.Results[1].Action != models.AlertIngestUpdated {
+		t.Errorf("duplicates in one payload should be deduplicated, got %+v", result.Results)
+	}
+	if env.incidents.incidents[result.Results[0].IncidentID].Priority != models.PriorityP3 {
+		t.Error("expected warning to map to p3")
+	}
+
+	if status, _ := env.post(t, "/alerts/generic", env.token, `{"description": "no title"}`); status != 400 {
+		t.Errorf("expected 400 for an alert without a title, got %d", status)
+	}
+}
+
+func TestAlertSources(t *testing.T) {
+	env := newAlertTestEnv(t)
+
+	req := httptest.NewRequest("POST", "/alert-sources", bytes.NewBufferString(`{"name": "Grafana", "mapping": {"priority_label": "level", "default_priority": "p2"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, _ := env.app.Test(req)
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created models.AlertSource
+	json.NewDecoder(resp.Body).Decode(&created)
+	if created.Token == "" || created.CreatedBy != "test-user-id" || created.Mapping.PriorityLabel != "level" {
+		t.Errorf("unexpected source: %+v", created)
+	}
+	if _, ok := env.alerts.tokens[hashToken(created.Token)]; !ok {
+		t.Error("expected only the token hash to be stored")
+	}
+
+	t.Run("invalid mapping", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/alert-sources", bytes.NewBufferString(`{"name": "Bad", "mapping": {"default_priority": "urgent", "category_map": {"db": "missing"}}}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := env.app.Test(req)
+		var body struct {
+			Fields map[string]string `json:"fields"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if resp.StatusCode != 400 || body.Fields["mapping.default_priority"] == "" || body.Fields["mapping.category_map"] == "" {
+			t.Errorf("expected mapping validation errors, got %d %v", resp.StatusCode, body.Fields)
+		}
+	})
+
+	t.Run("rotate token", func(t *testing.T) {
+		req := httptest.NewRequest("PUT", "/alert-sources/"+created.ID, bytes.NewBufferString(`{"rotate_token": true}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := env.app.Test(req)
+		var updated models.AlertSource
+		json.NewDecoder(resp.Body).Decode(&updated)
+		if resp.StatusCode != 200 || updated.Token == "" || updated.Token == created.Token {
+			t.Fatalf("expected a new token, got %d %+v", resp.StatusCode, updated)
+		}
+		if status, _ := env.post(t, "/alerts/generic", created.Token, `{"title": "x"}`); status != 401 {
+			t.Errorf("the old token should stop working, got %d", status)
+		}
+		if status, _ := env.post(t, "/alerts/generic", updated.Token, `{"title": "x"}`); status != 200 {
+			t.Errorf("the new token should work, got %d", status)
+		}
+	})
+}
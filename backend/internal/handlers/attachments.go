@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Attachments are evidence files linked to a risk, mitigation, incident,
+// framework control or policy. Access follows the parent: anyone who can see
+// the parent can list and download its files, and those who can edit it can
+// upload and delete them. The file is kept in the configured store; the
+// database holds its metadata and SHA-256 checksum.
+
+// DefaultAttachmentTypes are the content types accepted when no list is
+// configured: images, PDFs, plain text and office documents
+var DefaultAttachmentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp",
+	"application/pdf",
+	"text/plain", "text/csv", "text/markdown", "application/json",
+	"application/zip",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// attachmentExtensionTypes refine a sniffed content type by file extension.
+// Sniffing only tells that a file is text or a zip archive, so a .csv or
+// .xlsx is recognised by its name as long as its content agrees.
+var attachmentExtensionTypes = map[string]map[string]string{
+	"text/plain": {
+		".csv":  "text/csv",
+		".md":   "text/markdown",
+		".json": "application/json",
+	},
+	"application/zip": {
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	},
+}
+
+// AttachmentLimits bound what can be uploaded
+type AttachmentLimits struct {
+	MaxBytes     int64
+	AllowedTypes []string
+}
+
+type AttachmentHandler struct {
+	tx          database.Transactor
+	attachments database.AttachmentRepository
+	store       storage.Store
+	audit       database.AuditLogRepository
+	limits      AttachmentLimits
+}
+
+func NewAttachmentHandler(tx database.Transactor, attachments database.AttachmentRepository, store storage.Store,
+	audit database.AuditLogRepository, limits AttachmentLimits) *AttachmentHandler {
+	return &AttachmentHandler{tx: tx, attachments: attachments, store: store, audit: audit, limits: limits}
+}
+
+// AttachmentCleanup deletes records together with their attachments, which
+// refer to their parent by type and ID rather than with a foreign key
+type AttachmentCleanup struct {
+	tx          database.Transactor
+	attachments database.AttachmentRepository
+	store       storage.Store
+}
+
+func NewAttachmentCleanup(tx database.Transactor, attachments database.AttachmentRepository, store storage.Store) *AttachmentCleanup {
+	return &AttachmentCleanup{tx: tx, attachments: attachments, store: store}
+}
+
+// Delete runs del in a transaction and, in the same transaction, removes the
+// attachments of entityTypes that it left without a parent. Their files are
+// removed from the store once the transaction commits.
+func (a *AttachmentCleanup) Delete(ctx context.Context, del func(tx *sql.Tx) error, entityTypes ...models.AttachmentEntity) error {
+	return a.tx.InTx(ctx, func(tx *sql.Tx) error {
+		if err := del(tx); err != nil {
+			return err
+		}
+		keys, err := a.attachments.WithTx(tx).DeleteOrphaned(ctx, entityTypes...)
+		if err != nil {
+			return err
+		}
+		database.AfterCommit(tx, func() {
+			for _, key := range keys {
+				if err := a.store.Delete(ctx, key); err != nil {
+					log.Printf("Failed to remove stored attachment %s: %v", key, err)
+				}
+			}
+		})
+		return nil
+	})
+}
+
+// ListForRisk lists the attachments of the :riskId risk
+func (h *AttachmentHandler) ListForRisk(c *fiber.Ctx) error {
+	return h.list(c, models.AttachmentRisk, c.Params("riskId"))
+}
+
+// UploadForRisk attaches a file to the :riskId risk
+func (h *AttachmentHandler) UploadForRisk(c *fiber.Ctx) error {
+	return h.upload(c, models.AttachmentRisk, c.Params("riskId"))
+}
+
+// ListForMitigation lists the attachments of the :id mitigation of the
+// :riskId risk
+func (h *AttachmentHandler) ListForMitigation(c *fiber.Ctx) error {
+	return h.list(c, models.AttachmentMitigation, c.Params("id"))
+}
+
+// UploadForMitigation attaches a file to the :id mitigation of the :riskId
+// risk
+func (h *AttachmentHandler) UploadForMitigation(c *fiber.Ctx) error {
+	return h.upload(c, models.AttachmentMitigation, c.Params("id"))
+}
+
+// ListForIncident lists the attachments of the :incidentId incident
+func (h *AttachmentHandler) ListForIncident(c *fiber.Ctx) error {
+	return h.list(c, models.AttachmentIncident, c.Params("incidentId"))
+}
+
+// UploadForIncident attaches a file to the :incidentId incident
+func (h *AttachmentHandler) UploadForIncident(c *fiber.Ctx) error {
+	return h.upload(c, models.AttachmentIncident, c.Params("incidentId"))
+}
+
+// ListForControl lists the attachments of the :id framework control
+func (h *AttachmentHandler) ListForControl(c *fiber.Ctx) error {
+	return h.list(c, models.AttachmentFrameworkControl, c.Params("id"))
+}
+
+// UploadForControl attaches a file to the :id framework control
+func (h *AttachmentHandler) UploadForControl(c *fiber.Ctx) error {
+	return h.upload(c, models.AttachmentFrameworkControl, c.Params("id"))
+}
+
+// ListForPolicy lists the attachments of the :id policy
+func (h *AttachmentHandler) ListForPolicy(c *fiber.Ctx) error {
+	return h.list(c, models.AttachmentPolicy, c.Params("id"))
+}
+
+// UploadForPolicy attaches a file to the :id policy, typically the document
+// of a version
+func (h *AttachmentHandler) UploadForPolicy(c *fiber.Ctx) error {
+	return h.upload(c, models.AttachmentPolicy, c.Params("id"))
+}
+
+func (h *AttachmentHandler) list(c *fiber.Ctx, entityType models.AttachmentEntity, entityID string) error {
+	if err := h.checkParent(c, entityType, entityID); err != nil {
+		return attachmentError(c, err, entityType, ErrFailedToFetch)
+	}
+	attachments, err := h.attachments.ListByEntity(c.Context(), entityType, entityID)
+	if err != nil {
+		return attachmentError(c, err, entityType, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": attachments})
+}
+
+// upload stores the multipart "file" field. The content type is sniffed
+// from the file rather than trusted from the client, and the checksum is
+// taken while the file streams to the store.
+func (h *AttachmentHandler) upload(c *fiber.Ctx, entityType models.AttachmentEntity, entityID string) error {
+	if err := h.checkParent(c, entityType, entityID); err != nil {
+		return attachmentError(c, err, entityType, ErrFailedToFetch)
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		return validationFailed(c, fieldErrors{"file": "is required"})
+	}
+	if header.Size == 0 {
+		return validationFailed(c, fieldErrors{"file": "is empty"})
+	}
+	if header.Size > h.limits.MaxBytes {
+		return c.Status(413).JSON(fiber.Map{"error": fmt.Sprintf("file is larger than the %d byte limit", h.limits.MaxBytes)})
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	head = head[:n]
+	filename := attachmentFilename(header.Filename)
+	contentType := detectAttachmentType(head, filename)
+	if !h.allowedType(contentType) {
+		return c.Status(415).JSON(fiber.Map{"error": fmt.Sprintf("files of type %s are not allowed", contentType)})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	attachment := &models.Attachment{
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Filename:    filename,
+		ContentType: contentType,
+		SizeBytes:   header.Size,
+		StorageKey:  "attachments/" + string(entityType) + "/" + uuid.New().String(),
+		UploadedBy:  &user.UserID,
+	}
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head), file), hash)
+	if err := h.store.Put(c.Context(), attachment.StorageKey, body, attachment.SizeBytes, contentType); err != nil {
+		log.Printf("Failed to store attachment: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "failed to store attachment"})
+	}
+	attachment.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		if err := h.attachments.WithTx(tx).Create(c.Context(), attachment); err != nil {
+			return err
+		}
+		return h.audit.WithTx(tx).Create(c.Context(), "attachment", attachment.ID, models.AuditActionCreated,
+			attachmentAuditSnapshot(attachment), user.UserID)
+	})
+	if err != nil {
+		h.removeStored(c, attachment.StorageKey)
+		return attachmentError(c, err, entityType, ErrFailedToCreate)
+	}
+
+	created, err := h.attachments.GetByID(c.Context(), attachment.ID)
+	if err != nil {
+		return attachmentError(c, err, entityType, ErrFailedToFetch)
+	}
+	return c.Status(201).JSON(created)
+}
+
+// Get returns the metadata of the :id attachment
+func (h *AttachmentHandler) Get(c *fiber.Ctx) error {
+	attachment, err := h.load(c)
+	if err != nil {
+		return attachmentError(c, err, "", ErrFailedToFetch)
+	}
+	return c.JSON(attachment)
+}
+
+// Download sends the :id attachment's file. Every download is audited.
+func (h *AttachmentHandler) Download(c *fiber.Ctx) error {
+	attachment, err := h.load(c)
+	if err != nil {
+		return attachmentError(c, err, "", ErrFailedToFetch)
+	}
+	file, err := h.store.Open(c.Context(), attachment.StorageKey)
+	if err != nil {
+		log.Printf("Failed to open attachment %s: %v", attachment.ID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "failed to read attachment"})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	h.audit.Create(c.Context(), "attachment", attachment.ID, models.AuditActionDownloaded, map[string]any{
+		"entity_type": string(attachment.EntityType),
+		"entity_id":   attachment.EntityID,
+		"filename":    attachment.Filename,
+	}, user.UserID)
+
+	c.Attachment(attachment.Filename)
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	if digest, err := hex.DecodeString(attachment.SHA256); err == nil {
+		c.Set("Repr-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(digest)+":")
+	}
+	return c.SendStream(file, int(attachment.SizeBytes))
+}
+
+// Delete removes the :id attachment. The caller needs the same rights as
+// for uploading to its parent.
+func (h *AttachmentHandler) Delete(c *fiber.Ctx) error {
+	attachment, err := h.load(c)
+	if err != nil {
+		return attachmentError(c, err, "", ErrFailedToFetch)
+	}
+	user := middleware.GetUserFromContext(c)
+	if !canWriteAttachments(attachment.EntityType, user.Role) {
+		return c.Status(403).JSON(fiber.Map{"error": fmt.Sprintf("you cannot delete attachments of this %s", attachmentEntityLabel(attachment.EntityType))})
+	}
+
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		if err := h.attachments.WithTx(tx).Delete(c.Context(), attachment.ID); err != nil {
+			return err
+		}
+		return h.audit.WithTx(tx).Create(c.Context(), "attachment", attachment.ID, models.AuditActionDeleted,
+			attachmentAuditSnapshot(attachment), user.UserID)
+	})
+	if err != nil {
+		return attachmentError(c, err, attachment.EntityType, ErrFailedToDelete)
+	}
+	h.removeStored(c, attachment.StorageKey)
+	return c.SendStatus(204)
+}
+
+// load fetches the :id attachment if its parent is visible
+func (h *AttachmentHandler) load(c *fiber.Ctx) (*models.Attachment, error) {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return nil, database.ErrAttachmentNotFound
+	}
+	attachment, err := h.attachments.GetByID(c.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := h.attachments.ParentRiskID(c.Context(), attachment.EntityType, attachment.EntityID); err != nil {
+		if errors.Is(err, database.ErrAttachmentParentNotFound) {
+			return nil, database.ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// checkParent makes sure the parent exists and isn't in the trash, and that
+// a mitigation belongs to the :riskId risk
+func (h *AttachmentHandler) checkParent(c *fiber.Ctx, entityType models.AttachmentEntity, entityID string) error {
+	if uuid.Validate(entityID) != nil {
+		return database.ErrAttachmentParentNotFound
+	}
+	riskID, err := h.attachments.ParentRiskID(c.Context(), entityType, entityID)
+	if err != nil {
+		return err
+	}
+	if entityType == models.AttachmentMitigation && riskID != c.Params("riskId") {
+		return database.ErrAttachmentParentNotFound
+	}
+	return nil
+}
+
+func (h *AttachmentHandler) allowedType(contentType string) bool {
+	for _, allowed := range h.limits.AllowedTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// removeStored deletes a file whose metadata is gone or was never saved. A
+// failure only leaves an unreferenced file behind, so it is logged.
+func (h *AttachmentHandler) removeStored(c *fiber.Ctx, key string) {
+	if err := h.store.Delete(c.Context(), key); err != nil {
+		log.Printf("Failed to remove stored attachment %s: %v", key, err)
+	}
+}
+
+// canWriteAttachments mirrors who may edit each kind of parent: anyone for
+// risks and mitigations, responders for incidents and admins for controls.
+// Anyone may upload a policy document, since a version only comes into force
+// once an admin approves it.
+func canWriteAttachments(entityType models.AttachmentEntity, role string) bool {
+	switch entityType {
+	case models.AttachmentIncident:
+		return role == string(models.RoleAdmin) || role == string(models.RoleResponder)
+	case models.AttachmentFrameworkControl:
+		return role == string(models.RoleAdmin)
+	}
+	return true
+}
+
+// detectAttachmentType sniffs the content type from the start of the file,
+// refined by extension for text files and office documents
+func detectAttachmentType(head []byte, filename string) string {
+	sniffed, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if refined, ok := attachmentExtensionTypes[sniffed][strings.ToLower(filepath.Ext(filename))]; ok {
+		return refined
+	}
+	return sniffed
+}
+
+// attachmentFilename keeps the base name of an uploaded file, without any
+// client-side path or control characters
+func attachmentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || strings.TrimSpace(name) == "" {
+		return "attachment"
+	}
+	if len(name) > 255 {
+		ext := filepath.Ext(name)
+		if len(ext) > 16 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:255-len(ext)], "") + ext
+	}
+	return name
+}
+
+// attachmentAuditSnapshot returns the state recorded when an attachment is
+// uploaded or deleted
+func attachmentAuditSnapshot(a *models.Attachment) map[string]any {
+	return map[string]any{
+		"entity_type":  string(a.EntityType),
+		"entity_id":    a.EntityID,
+		"filename":     a.Filename,
+		"content_type": a.ContentType,
+		"size_bytes":   a.SizeBytes,
+		"sha256":       a.SHA256,
+	}
+}
+
+func attachmentEntityLabel(entityType models.AttachmentEntity) string {
+	if entityType == models.AttachmentFrameworkControl {
+		return "control"
+	}
+	return string(entityType)
+}
+
+// attachmentError maps repository errors to responses, using failed for
+// anything unexpected
+func attachmentError(c *fiber.Ctx, err error, entityType models.AttachmentEntity, failed string) error {
+	switch {
+	case errors.Is(err, database.ErrAttachmentNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "attachment")})
+	case errors.Is(err, database.ErrAttachmentParentNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, attachmentEntityLabel(entityType))})
+	case errors.Is(err, database.ErrAttachmentInUse):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	}
+	log.Printf("Attachment request failed: %v", err)
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(failed, "attachment")})
+}
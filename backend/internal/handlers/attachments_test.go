@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAttachmentRepo keeps attachments in memory. parents maps the live
+// parent records to the risk they belong to, "" for all but mitigations.
+// exists reports whether a parent record, trashed or not, is still stored.
+type mockAttachmentRepo struct {
+	attachments []*models.Attachment
+	parents     map[string]string
+	inUse       map[string]bool
+	exists      func(entityType models.AttachmentEntity, entityID string) bool
+}
+
+func (m *mockAttachmentRepo) ListByEntity(ctx context.Context, entityType models.AttachmentEntity, entityID string) ([]*models.Attachment, error) {
+	list := []*models.Attachment{}
+	for _, a := range m.attachments {
+		if a.EntityType == entityType && a.EntityID == entityID {
+			list = append(list, a)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockAttachmentRepo) GetByID(ctx context.Context, id string) (*models.Attachment, error) {
+	for _, a := range m.attachments {
+		if a.ID == id {
+			return a, nil
+		}
+	}
+	return nil, database.ErrAttachmentNotFound
+}
+
+func (m *mockAttachmentRepo) Create(ctx context.Context, attachment *models.Attachment) error {
+	attachment.ID = uuid.New().String()
+	attachment.CreatedAt = time.Now()
+	// Fiber reuses the request buffer that route params point into
+	attachment.EntityID = strings.Clone(attachment.EntityID)
+	m.attachments = append(m.attachments, attachment)
+	return nil
+}
+
+func (m *mockAttachmentRepo) Delete(ctx context.Context, id string) error {
+	if m.inUse[id] {
+		return database.ErrAttachmentInUse
+	}
+	for i, a := range m.attachments {
+		if a.ID == id {
+			m.attachments = append(m.attachments[:i], m.attachments[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrAttachmentNotFound
+}
+
+func (m *mockAttachmentRepo) DeleteOrphaned(ctx context.Context, entityTypes ...models.AttachmentEntity) ([]string, error) {
+	keys := []string{}
+	kept := []*models.Attachment{}
+	for _, a := range m.attachments {
+		if slices.Contains(entityTypes, a.EntityType) && !m.exists(a.EntityType, a.EntityID) {
+			keys = append(keys, a.StorageKey)
+			continue
+		}
+		kept = append(kept, a)
+	}
+	m.attachments = kept
+	return keys, nil
+}
+
+func (m *mockAttachmentRepo) ParentRiskID(ctx context.Context, entityType models.AttachmentEntity, entityID string) (string, error) {
+	riskID, ok := m.parents[entityID]
+	if !ok {
+		return "", database.ErrAttachmentParentNotFound
+	}
+	return riskID, nil
+}
+
+func (m *mockAttachmentRepo) WithTx(tx *sql.Tx) database.AttachmentRepository {
+	return m
+}
+
+// newTestAttachmentCleanup returns a cleanup for tests of other handlers
+// whose records have no attachments, so it never touches the store
+func newTestAttachmentCleanup() *AttachmentCleanup {
+	return NewAttachmentCleanup(&mockTransactor{}, &mockAttachmentRepo{}, nil)
+}
+
+type attachmentTestEnv struct {
+	app   *fiber.App
+	repo  *mockAttachmentRepo
+	store storage.Store
+	audit *mockAuditRepo
+	role  string
+}
+
+func setupAttachmentApp(t *testing.T) *attachmentTestEnv {
+	env := &attachmentTestEnv{
+		repo:  &mockAttachmentRepo{parents: map[string]string{}, inUse: map[string]bool{}},
+		store: storage.NewLocalStore(t.TempDir()),
+		audit: &mockAuditRepo{},
+		role:  "member",
+	}
+	handler := NewAttachmentHandler(&mockTransactor{}, env.repo, env.store, env.audit,
+		AttachmentLimits{MaxBytes: 1024, AllowedTypes: DefaultAttachmentTypes})
+
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserKey, &middleware.UserClaims{UserID: "test-user-id", Role: env.role})
+		return c.Next()
+	}
+	env.app = fiber.New()
+	env.app.Get("/risks/:riskId/attachments", auth, handler.ListForRisk)
+	env.app.Post("/risks/:riskId/attachments", auth, handler.UploadForRisk)
+	env.app.Post("/risks/:riskId/mitigations/:id/attachments", auth, handler.UploadForMitigation)
+	env.app.Post("/incidents/:incidentId/attachments", auth, handler.UploadForIncident)
+	env.app.Post("/policies/:id/attachments", auth, handler.UploadForPolicy)
+	env.app.Get("/attachments/:id/download", auth, handler.Download)
+	env.app.Delete("/attachments/:id", auth, handler.Delete)
+	return env
+}
+
+func (env *attachmentTestEnv) upload(t *testing.T, path, filename string, content []byte) (int, map[string]any) {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	part, _ := w.CreateFormFile("file", filename)
+	part.Write(content)
+	w.Close()
+
+	req := httptest.NewRequest("POST", path, body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := env.app.Test(req)
+	require.NoError(t, err)
+	var result map[string]any
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestAttachmentHandler_Upload(t *testing.T) {
+	env := setupAttachmentApp(t)
+	risk, mitigation := uuid.New().String(), uuid.New().String()
+	env.repo.parents[risk] = ""
+	env.repo.parents[mitigation] = risk
+	path := "/risks/" + risk + "/attachments"
+	csv := []byte("user,last_login\nalice,2026-09-30\n")
+
+	status, body := env.upload(t, path, "C:\\exports\\access-review.csv", csv)
+	require.Equal(t, 201, status, body)
+	sum := sha256.Sum256(csv)
+	assert.Equal(t, hex.EncodeToString(sum[:]), body["sha256"])
+	assert.Equal(t, "text/csv", body["content_type"])
+	assert.Equal(t, "access-review.csv", body["filename"])
+	assert.Equal(t, float64(len(csv)), body["size_bytes"])
+	assert.NotContains(t, body, "storage_key")
+	require.Len(t, env.audit.logs, 1)
+	assert.Equal(t, "attachment", env.audit.logs[0].EntityType)
+	assert.Equal(t, models.AuditActionCreated, env.audit.logs[0].Action)
+
+	t.Run("limits", func(t *testing.T) {
+		status, _ := env.upload(t, path, "big.txt", bytes.Repeat([]byte("a"), 2048))
+		assert.Equal(t, 413, status)
+		status, body := env.upload(t, path, "tool.exe", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00"))
+		assert.Equal(t, 415, status)
+		assert.Contains(t, body["error"], "application/octet-stream")
+		status, body = env.upload(t, path, "looks-like.png", []byte("<html><body>hi</body></html>"))
+		assert.Equal(t, 415, status, "the content decides the type, not the name")
+		status, body = env.upload(t, path, "empty.txt", nil)
+		require.Equal(t, 400, status)
+		assert.Contains(t, body["fields"], "file")
+	})
+
+	t.Run("parents", func(t *testing.T) {
+		status, _ := env.upload(t, "/risks/"+uuid.New().String()+"/attachments", "a.txt", []byte("x"))
+		assert.Equal(t, 404, status)
+		status, _ = env.upload(t, "/risks/"+risk+"/mitigations/"+mitigation+"/attachments", "a.txt", []byte("x"))
+		assert.Equal(t, 201, status)
+		status, _ = env.upload(t, "/risks/"+uuid.New().String()+"/mitigations/"+mitigation+"/attachments", "a.txt", []byte("x"))
+		assert.Equal(t, 404, status, "the mitigation must belong to the risk")
+	})
+
+	status, list := sendTeamRequest(t, env.app, "GET", path, "")
+	require.Equal(t, 200, status)
+	assert.Len(t, list["data"], 1)
+}
+
+func TestAttachmentHandler_DownloadAndDelete(t *testing.T) {
+	env := setupAttachmentApp(t)
+	incident := uuid.New().String()
+	env.repo.parents[incident] = ""
+	pdf := []byte("%PDF-1.7\n% evidence\n")
+
+	env.role = "responder"
+	status, body := env.upload(t, "/incidents/"+incident+"/attachments", "timeline.pdf", pdf)
+	require.Equal(t, 201, status, body)
+	id := body["id"].(string)
+	env.role = "member"
+
+	resp, err := env.app.Test(httptest.NewRequest("GET", "/attachments/"+id+"/download", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "timeline.pdf")
+	assert.Contains(t, resp.Header.Get("Repr-Digest"), "sha-256=:")
+	got, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, pdf, got)
+	assert.Equal(t, models.AuditActionDownloaded, env.audit.logs[len(env.audit.logs)-1].Action)
+
+	status, _ = sendTeamRequest(t, env.app, "DELETE", "/attachments/"+id, "")
+	assert.Equal(t, 403, status, "members cannot edit incidents")
+
+	delete(env.repo.parents, incident)
+	status, _ = sendTeamRequest(t, env.app, "GET", "/attachments/"+id+"/download", "")
+	assert.Equal(t, 404, status, "attachments of a trashed incident are hidden")
+	env.repo.parents[incident] = ""
+
+	env.role = "admin"
+	key := env.repo.attachments[0].StorageKey
+	status, _ = sendTeamRequest(t, env.app, "DELETE", "/attachments/"+id, "")
+	require.Equal(t, 204, status)
+	assert.Equal(t, models.AuditActionDeleted, env.audit.logs[len(env.audit.logs)-1].Action)
+	_, err = env.store.Open(context.Background(), key)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+func TestAttachmentHandler_PolicyDocumentInUse(t *testing.T) {
+	env := setupAttachmentApp(t)
+	policy := uuid.New().String()
+	env.repo.parents[policy] = ""
+
+	status, body := env.upload(t, "/policies/"+policy+"/attachments", "acceptable-use.md", []byte("# Acceptable use\n"))
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, "policy", body["entity_type"])
+	id := body["id"].(string)
+
+	env.repo.inUse[id] = true
+	status, _ = sendTeamRequest(t, env.app, "DELETE", "/attachments/"+id, "")
+	assert.Equal(t, 409, status, "a policy version's document cannot be deleted")
+	file, err := env.store.Open(context.Background(), env.repo.attachments[0].StorageKey)
+	require.NoError(t, err, "the file is kept")
+	file.Close()
+}
+
+func TestDetectAttachmentType(t *testing.T) {
+	tests := []struct {
+		name, filename string
+		content        []byte
+		want           string
+	}{
+		{"png", "screenshot.png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"csv by extension", "export.CSV", []byte("a,b\n1,2\n"), "text/csv"},
+		{"plain text", "app.log", []byte("2026-10-01 login failed\n"), "text/plain"},
+		{"xlsx by extension", "register.xlsx", []byte("PK\x03\x04\x14\x00\x06\x00"), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"extension doesn't override content", "policy.docx", []byte("%PDF-1.4\n"), "application/pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, detectAttachmentType(tt.content, tt.filename))
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Bulk endpoints apply one action to a selection of risks or incidents. The
+// whole batch runs in a single transaction with a savepoint per item, so a
+// failing item is undone on its own and reported without affecting the rest.
+// A dry run, or an atomic batch with any failure, rolls everything back.
+
+// maxBulkItems caps how many records one bulk request may touch
+const maxBulkItems = 500
+
+type BulkHandler struct {
+	tx                 database.Transactor
+	risks              database.RiskRepository
+	categories         database.CategoryRepository
+	incidents          database.IncidentRepository
+	incidentCategories database.IncidentCategoryRepository
+	audit              database.AuditLogRepository
+}
+
+func NewBulkHandler(
+	tx database.Transactor,
+	risks database.RiskRepository,
+	categories database.CategoryRepository,
+	incidents database.IncidentRepository,
+	incidentCategories database.IncidentCategoryRepository,
+	audit database.AuditLogRepository,
+) *BulkHandler {
+	return &BulkHandler{
+		tx:                 tx,
+		risks:              risks,
+		categories:         categories,
+		incidents:          incidents,
+		incidentCategories: incidentCategories,
+		audit:              audit,
+	}
+}
+
+// bulkItemFunc applies the action to one record inside tx and reports what changed
+type bulkItemFunc func(ctx context.Context, tx *sql.Tx, id string) (models.BulkItemStatus, map[string]any, error)
+
+// Risks applies an action to every risk selected by ids or filter
+func (h *BulkHandler) Risks(c *fiber.Ctx) error {
+	var input models.RiskBulkInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	errs := fieldErrors{}
+	checkBulkSelection(errs, input.IDs, input.Filter != nil, input.Filter != nil && *input.Filter == (models.RiskBulkFilter{}))
+	switch input.Action {
+	case models.BulkActionSetStatus:
+		requireOneOf(errs, "value", input.Value, string(models.StatusOpen), string(models.StatusMitigating),
+			string(models.StatusResolved), string(models.StatusAccepted))
+	case models.BulkActionSetOwner:
+		requireUUID(errs, "value", input.Value)
+	case models.BulkActionSetCategory:
+		input.Value = bulkOptionalValue(input.Value)
+		if optionalUUID(errs, "value", input.Value) && input.Value != nil {
+			category, err := h.categories.FindByID(c.Context(), *input.Value)
+			if err != nil || category == nil {
+				errs["value"] = "category does not exist"
+			}
+		}
+	case models.BulkActionAddTag:
+		input.Value = bulkTagValue(errs, input.Value)
+	case models.BulkActionDelete:
+	default:
+		errs["action"] = bulkActionError(models.BulkActionSetStatus, models.BulkActionSetOwner,
+			models.BulkActionSetCategory, models.BulkActionAddTag, models.BulkActionDelete)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	ids := bulkIDs(input.IDs)
+	if input.Filter != nil {
+		var err error
+		ids, err = h.risks.ListIDs(c.Context(), riskBulkParams(input.Filter))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risks"})
+		}
+	}
+	if len(ids) > maxBulkItems {
+		return bulkTooLarge(c, len(ids))
+	}
+
+	user := middleware.GetUserFromContext(c)
+	result, err := h.run(c.Context(), input.Action, ids, input.DryRun, input.Atomic, "risk",
+		func(ctx context.Context, tx *sql.Tx, id string) (models.BulkItemStatus, map[string]any, error) {
+			return h.applyRisk(ctx, tx, id, &input, user.UserID)
+		})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update risks"})
+	}
+	return c.JSON(result)
+}
+
+func (h *BulkHandler) applyRisk(ctx context.Context, tx *sql.Tx, id string, input *models.RiskBulkInput, userID string) (models.BulkItemStatus, map[string]any, error) {
+	risks := h.risks.WithTx(tx)
+	audit := h.audit.WithTx(tx)
+
+	risk, err := risks.FindByID(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if input.Action == models.BulkActionDelete {
+		if err := risks.Delete(ctx, id, risk.Version); err != nil {
+			return "", nil, err
+		}
+		if err := audit.Create(ctx, "risk", id, models.AuditActionDeleted, nil, userID); err != nil {
+			return "", nil, err
+		}
+		return models.BulkItemDeleted, nil, nil
+	}
+
+	changes := make(map[string]any)
+	switch input.Action {
+	case models.BulkActionSetStatus:
+		if status := models.RiskStatus(*input.Value); risk.Status != status {
+			changes["status"] = auditChange(risk.Status, status)
+			risk.Status = status
+		}
+	case models.BulkActionSetOwner:
+		if risk.OwnerID != *input.Value {
+			changes["owner_id"] = auditChange(risk.OwnerID, *input.Value)
+			risk.OwnerID = *input.Value
+		}
+	case models.BulkActionSetCategory:
+		if auditString(risk.CategoryID) != auditString(input.Value) {
+			changes["category_id"] = auditChange(auditString(risk.CategoryID), auditString(input.Value))
+			risk.CategoryID = input.Value
+			risk.Category = nil
+		}
+	case models.BulkActionAddTag:
+		if tags := normalizeTags(append(auditTags(risk.Tags), *input.Value)); len(tags) != len(risk.Tags) {
+			changes["tags"] = auditChange(auditTags(risk.Tags), tags)
+			risk.Tags = tags
+		}
+	}
+	if len(changes) == 0 {
+		return models.BulkItemUnchanged, nil, nil
+	}
+
+	risk.UpdatedBy = userID
+	if err := risks.Update(ctx, risk); err != nil {
+		return "", nil, err
+	}
+	if err := audit.Create(ctx, "risk", id, models.AuditActionUpdated, changes, userID); err != nil {
+		return "", nil, err
+	}
+	return models.BulkItemUpdated, changes, nil
+}
+
+// Incidents applies an action to every incident selected by ids or filter.
+// Deleting incidents stays admin-only, as it is for single deletes.
+func (h *BulkHandler) Incidents(c *fiber.Ctx) error {
+	var input models.IncidentBulkInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	if input.Action == models.BulkActionDelete && user.Role != "admin" {
+		return c.Status(403).JSON(fiber.Map{"error": "admin access required"})
+	}
+
+	errs := fieldErrors{}
+	checkBulkSelection(errs, input.IDs, input.Filter != nil, input.Filter != nil && *input.Filter == (models.IncidentBulkFilter{}))
+	switch input.Action {
+	case models.BulkActionSetStatus:
+		requireOneOf(errs, "value", input.Value, string(models.IncidentStatusNew), string(models.IncidentStatusAcknowledged),
+			string(models.IncidentStatusInProgress), string(models.IncidentStatusOnHold),
+			string(models.IncidentStatusResolved), string(models.IncidentStatusClosed))
+	case models.BulkActionSetAssignee:
+		input.Value = bulkOptionalValue(input.Value)
+		optionalUUID(errs, "value", input.Value)
+	case models.BulkActionSetCategory:
+		input.Value = bulkOptionalValue(input.Value)
+		if optionalUUID(errs, "value", input.Value) && input.Value != nil {
+			category, err := h.incidentCategories.FindByID(c.Context(), *input.Value)
+			if err != nil || category == nil {
+				errs["value"] = "incident category does not exist"
+			}
+		}
+	case models.BulkActionAddTag:
+		input.Value = bulkTagValue(errs, input.Value)
+	case models.BulkActionDelete:
+	default:
+		errs["action"] = bulkActionError(models.BulkActionSetStatus, models.BulkActionSetAssignee,
+			models.BulkActionSetCategory, models.BulkActionAddTag, models.BulkActionDelete)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	ids := bulkIDs(input.IDs)
+	if input.Filter != nil {
+		var err error
+		ids, err = h.incidents.ListIDs(c.Context(), incidentBulkParams(input.Filter))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to fetch incidents"})
+		}
+	}
+	if len(ids) > maxBulkItems {
+		return bulkTooLarge(c, len(ids))
+	}
+
+	result, err := h.run(c.Context(), input.Action, ids, input.DryRun, input.Atomic, "incident",
+		func(ctx context.Context, tx *sql.Tx, id string) (models.BulkItemStatus, map[string]any, error) {
+			return h.applyIncident(ctx, tx, id, &input, user.UserID)
+		})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update incidents"})
+	}
+	return c.JSON(result)
+}
+
+func (h *BulkHandler) applyIncident(ctx context.Context, tx *sql.Tx, id string, input *models.IncidentBulkInput, userID string) (models.BulkItemStatus, map[string]any, error) {
+	incidents := h.incidents.WithTx(tx)
+	audit := h.audit.WithTx(tx)
+
+	incident, err := incidents.FindByID(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if input.Action == models.BulkActionDelete {
+		if err := incidents.Delete(ctx, id, incident.Version); err != nil {
+			return "", nil, err
+		}
+		if err := audit.Create(ctx, "incident", id, models.AuditActionDeleted, nil, userID); err != nil {
+			return "", nil, err
+		}
+		return models.BulkItemDeleted, nil, nil
+	}
+
+	changes := make(map[string]any)
+	switch input.Action {
+	case models.BulkActionSetStatus:
+		if status := models.IncidentStatus(*input.Value); incident.Status != status {
+			changes["status"] = auditChange(incident.Status, status)
+			incident.Status = status
+
+			// Auto-set resolved_at as the single update does
+			if (status == models.IncidentStatusResolved || status == models.IncidentStatusClosed) && incident.ResolvedAt == nil {
+				now := time.Now()
+				changes["resolved_at"] = auditChange(nil, auditTime(&now))
+				incident.ResolvedAt = &now
+			}
+		}
+	case models.BulkActionSetAssignee:
+		if auditString(incident.AssigneeID) != auditString(input.Value) {
+			changes["assignee_id"] = auditChange(auditString(incident.AssigneeID), auditString(input.Value))
+			incident.AssigneeID = input.Value
+			incident.Assignee = nil
+		}
+	case models.BulkActionSetCategory:
+		if auditString(incident.CategoryID) != auditString(input.Value) {
+			changes["category_id"] = auditChange(auditString(incident.CategoryID), auditString(input.Value))
+			incident.CategoryID = input.Value
+			incident.Category = nil
+		}
+	case models.BulkActionAddTag:
+		if tags := normalizeTags(append(auditTags(incident.Tags), *input.Value)); len(tags) != len(incident.Tags) {
+			changes["tags"] = auditChange(auditTags(incident.Tags), tags)
+			incident.Tags = tags
+		}
+	}
+	if len(changes) == 0 {
+		return models.BulkItemUnchanged, nil, nil
+	}
+
+	incident.UpdatedBy = userID
+	if err := incidents.Update(ctx, incident); err != nil {
+		return "", nil, err
+	}
+	if err := audit.Create(ctx, "incident", id, models.AuditActionUpdated, changes, userID); err != nil {
+		return "", nil, err
+	}
+	return models.BulkItemUpdated, changes, nil
+}
+
+// run applies fn to every id in one transaction, isolating each item in a
+// savepoint. The transaction is rolled back for a dry run, and for an atomic
+// batch in which any item failed.
+func (h *BulkHandler) run(ctx context.Context, action models.BulkAction, ids []string, dryRun, atomic bool, entity string, fn bulkItemFunc) (*models.BulkResult, error) {
+	result := &models.BulkResult{
+		Action:  action,
+		DryRun:  dryRun,
+		Atomic:  atomic,
+		Matched: len(ids),
+		Results: make([]*models.BulkItemResult, 0, len(ids)),
+	}
+
+	err := h.tx.InTx(ctx, func(tx *sql.Tx) error {
+		for _, id := range ids {
+			item := &models.BulkItemResult{ID: id}
+			err := h.tx.Savepoint(ctx, tx, func() error {
+				var err error
+				item.Status, item.Changes, err = fn(ctx, tx, id)
+				return err
+			})
+			if err != nil {
+				item.Status = models.BulkItemFailed
+				item.Changes = nil
+				item.Error = bulkItemError(entity, action, err)
+				result.Failed++
+			} else {
+				result.Succeeded++
+			}
+			result.Results = append(result.Results, item)
+		}
+
+		if dryRun || (atomic && result.Failed > 0) {
+			return database.ErrRollback
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Committed = !dryRun && !(atomic && result.Failed > 0)
+	if !dryRun && !result.Committed {
+		for _, item := range result.Results {
+			if item.Status != models.BulkItemFailed {
+				item.Status = models.BulkItemRolledBack
+			}
+		}
+		result.Succeeded = 0
+	}
+	return result, nil
+}
+
+// checkBulkSelection requires exactly one of an ID list or a non-empty filter
+func checkBulkSelection(errs fieldErrors, ids []string, hasFilter, emptyFilter bool) {
+	switch {
+	case len(ids) > 0 && hasFilter:
+		errs["ids"] = "cannot be combined with filter"
+	case len(ids) == 0 && !hasFilter:
+		errs["ids"] = "ids or filter is required"
+	case emptyFilter:
+		errs["filter"] = "must set at least one criterion"
+	}
+	for _, id := range ids {
+		if uuid.Validate(id) != nil {
+			errs["ids"] = "must contain only UUIDs"
+			break
+		}
+	}
+}
+
+// bulkIDs drops repeated ids so each record is processed once
+func bulkIDs(ids []string) []string {
+	unique := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// bulkOptionalValue treats an empty value as clearing the field
+func bulkOptionalValue(value *string) *string {
+	if value != nil && *value == "" {
+		return nil
+	}
+	return value
+}
+
+// bulkTagValue validates and trims the tag for add_tag
+func bulkTagValue(errs fieldErrors, value *string) *string {
+	if !requireText(errs, "value", value, 0) {
+		return value
+	}
+	tag := strings.TrimSpace(*value)
+	return &tag
+}
+
+func bulkActionError(allowed ...models.BulkAction) string {
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		names[i] = string(a)
+	}
+	return "must be one of " + strings.Join(names, ", ")
+}
+
+func bulkTooLarge(c *fiber.Ctx, matched int) error {
+	return c.Status(400).JSON(fiber.Map{
+		"error": fmt.Sprintf("selection matches %d items; at most %d can be changed at once", matched, maxBulkItems),
+	})
+}
+
+// bulkItemError turns an item failure into the message reported for it
+func bulkItemError(entity string, action models.BulkAction, err error) string {
+	switch {
+	case errors.Is(err, database.ErrRiskNotFound), errors.Is(err, database.ErrIncidentNotFound):
+		return fmt.Sprintf(ErrEntityNotFound, entity)
+	case errors.Is(err, database.ErrVersionConflict):
+		return entity + " was modified by another request"
+	case action == models.BulkActionDelete:
+		return fmt.Sprintf(ErrFailedToDelete, entity)
+	default:
+		return fmt.Sprintf(ErrFailedToUpdate, entity)
+	}
+}
+
+func riskBulkParams(f *models.RiskBulkFilter) *models.RiskListParams {
+	params := &models.RiskListParams{Tag: f.Tag, Search: f.Search}
+	if f.Status != "" {
+		s := models.RiskStatus(f.Status)
+		params.Status = &s
+	}
+	if f.Severity != "" {
+		s := models.RiskSeverity(f.Severity)
+		params.Severity = &s
+	}
+	if f.CategoryID != "" {
+		params.CategoryID = &f.CategoryID
+	}
+	if f.OwnerID != "" {
+		params.OwnerID = &f.OwnerID
+	}
+	return params
+}
+
+func incidentBulkParams(f *models.IncidentBulkFilter) *models.IncidentListParams {
+	params := &models.IncidentListParams{Tag: f.Tag, Search: f.Search}
+	if f.Status != "" {
+		s := models.IncidentStatus(f.Status)
+		params.Status = &s
+	}
+	if f.Priority != "" {
+		p := models.IncidentPriority(f.Priority)
+		params.Priority = &p
+	}
+	if f.CategoryID != "" {
+		params.CategoryID = &f.CategoryID
+	}
+	if f.AssigneeID != "" {
+		params.AssigneeID = &f.AssigneeID
+	}
+	return params
+}
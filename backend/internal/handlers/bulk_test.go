@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// mockTransactor runs callbacks without a real transaction. The mock
+// repositories ignore the *sql.Tx they are bound to.
+type mockTransactor struct {
+	rolledBack bool
+}
+
+func (m *mockTransactor) InTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	if err := fn(nil); err != nil {
+		m.rolledBack = true
+		if errors.Is(err, database.ErrRollback) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (m *mockTransactor) Savepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	return fn()
+}
+
+func decodeBulkResult(t *testing.T, body *bytes.Buffer) models.BulkResult {
+	t.Helper()
+	var result models.BulkResult
+	if err := json.Unmarshal(body.Bytes(), &result); err != nil {
+		t.Fatalf("failed to decode bulk result: %v", err)
+	}
+	return result
+}
+
+func TestBulkRisksHandler(t *testing.T) {
+	newApp := func(riskRepo *mockRiskRepo, auditRepo *mockAuditRepo, tx *mockTransactor) *fiber.App {
+		app := fiber.New()
+		categoryRepo := &mockCategoryRepo{categories: map[string]*models.Category{}}
+		handler := NewBulkHandler(tx, riskRepo, categoryRepo, newMockIncidentRepo(), newMockIncidentCategoryRepo(), auditRepo)
+		app.Post("/risks/bulk", testAuthMiddleware, handler.Risks)
+		return app
+	}
+	newRisk := func(status models.RiskStatus) *models.Risk {
+		return &models.Risk{ID: uuid.New().String(), Title: "Risk", Status: status, Severity: models.SeverityLow, Tags: []string{}, Version: 1}
+	}
+	post := func(app *fiber.App, body any) (int, *bytes.Buffer) {
+		b, _ := json.Marshal(body)
+		req := httptest.NewRequest("POST", "/risks/bulk", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(resp.Body)
+		return resp.StatusCode, buf
+	}
+
+	t.Run("sets status by id and audits each item", func(t *testing.T) {
+		open, resolved := newRisk(models.StatusOpen), newRisk(models.StatusResolved)
+		riskRepo := &mockRiskRepo{risks: map[string]*models.Risk{open.ID: open, resolved.ID: resolved}}
+		auditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+		app := newApp(riskRepo, auditRepo, &mockTransactor{})
+
+		status, body := post(app, fiber.Map{"ids": []string{open.ID, resolved.ID}, "action": "set_status", "value": "resolved"})
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		result := decodeBulkResult(t, body)
+		if !result.Committed || result.Matched != 2 || result.Succeeded != 2 {
+			t.Errorf("unexpected summary: %+v", result)
+		}
+		if result.Results[0].Status != models.BulkItemUpdated || result.Results[1].Status != models.BulkItemUnchanged {
+			t.Errorf("unexpected item statuses: %s, %s", result.Results[0].Status, result.Results[1].Status)
+		}
+		if open.Status != models.StatusResolved {
+			t.Errorf("expected risk to be resolved, got %s", open.Status)
+		}
+		if len(auditRepo.logs) != 1 || auditRepo.logs[0].EntityID != open.ID {
+			t.Errorf("expected one audit entry for the changed risk, got %d", len(auditRepo.logs))
+		}
+	})
+
+	t.Run("selects by filter and adds a tag", func(t *testing.T) {
+		a, b, other := newRisk(models.StatusOpen), newRisk(models.StatusOpen), newRisk(models.StatusAccepted)
+		a.Tags = []string{"q3"}
+		riskRepo := &mockRiskRepo{risks: map[string]*models.Risk{a.ID: a, b.ID: b, other.ID: other}}
+		app := newApp(riskRepo, &mockAuditRepo{logs: []*models.AuditLog{}}, &mockTransactor{})
+
+		status, body := post(app, fiber.Map{"filter": fiber.Map{"status": "open"}, "action": "add_tag", "value": " q3 "})
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		result := decodeBulkResult(t, body)
+		if result.Matched != 2 {
+			t.Errorf("expected 2 matched risks, got %d", result.Matched)
+		}
+		if len(b.Tags) != 1 || b.Tags[0] != "q3" {
+			t.Errorf("expected tag to be added, got %v", b.Tags)
+		}
+		if len(a.Tags) != 1 {
+			t.Errorf("expected existing tag not to be duplicated, got %v", a.Tags)
+		}
+		if len(other.Tags) != 0 {
+			t.Errorf("expected unmatched risk to be untouched, got %v", other.Tags)
+		}
+	})
+
+	t.Run("dry run rolls back", func(t *testing.T) {
+		risk := newRisk(models.StatusOpen)
+		tx := &mockTransactor{}
+		app := newApp(&mockRiskRepo{risks: map[string]*models.Risk{risk.ID: risk}}, &mockAuditRepo{logs: []*models.AuditLog{}}, tx)
+
+		status, body := post(app, fiber.Map{"ids": []string{risk.ID}, "action": "delete", "dry_run": true})
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		result := decodeBulkResult(t, body)
+		if result.Committed || !result.DryRun || !tx.rolledBack {
+			t.Errorf("expected dry run to roll back, got %+v", result)
+		}
+		if result.Results[0].Status != models.BulkItemDeleted {
+			t.Errorf("expected preview status deleted, got %s", result.Results[0].Status)
+		}
+	})
+
+	t.Run("failed delete is not audited", func(t *testing.T) {
+		risk := newRisk(models.StatusOpen)
+		riskRepo := &mockRiskRepo{risks: map[string]*models.Risk{risk.ID: risk}}
+		riskRepo.beforeDelete = func() { risk.Version++ }
+		auditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+		app := newApp(riskRepo, auditRepo, &mockTransactor{})
+
+		status, body := post(app, fiber.Map{"ids": []string{risk.ID}, "action": "delete"})
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		result := decodeBulkResult(t, body)
+		if result.Failed != 1 || result.Results[0].Status != models.BulkItemFailed {
+			t.Errorf("expected the racing delete to fail, got %+v", result)
+		}
+		if len(auditRepo.logs) != 0 {
+			t.Errorf("expected no audit entry for a delete that failed, got %d", len(auditRepo.logs))
+		}
+	})
+
+	t.Run("atomic batch with a failure rolls back", func(t *testing.T) {
+		risk := newRisk(models.StatusOpen)
+		missing := uuid.New().String()
+		tx := &mockTransactor{}
+		app := newApp(&mockRiskRepo{risks: map[string]*models.Risk{risk.ID: risk}}, &mockAuditRepo{logs: []*models.AuditLog{}}, tx)
+
+		status, body := post(app, fiber.Map{"ids": []string{risk.ID, missing}, "action": "set_owner", "value": uuid.New().String(), "atomic": true})
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		result := decodeBulkResult(t, body)
+		if result.Committed || !tx.rolledBack || result.Succeeded != 0 || result.Failed != 1 {
+			t.Errorf("expected atomic batch to roll back, got %+v", result)
+		}
+		if result.Results[0].Status != models.BulkItemRolledBack {
+			t.Errorf("expected first item rolled back, got %s", result.Results[0].Status)
+		}
+		if result.Results[1].Status != models.BulkItemFailed || result.Results[1].Error != "risk not found" {
+			t.Errorf("expected missing item to fail, got %+v", result.Results[1])
+		}
+	})
+
+	t.Run("non-atomic batch keeps successes", func(t *testing.T) {
+		risk := newRisk(models.StatusOpen)
+		tx := &mockTransactor{}
+		app := newApp(&mockRiskRepo{risks: map[string]*models.Risk{risk.ID: risk}}, &mockAuditRepo{logs: []*models.AuditLog{}}, tx)
+
+		status, body := post(app, fiber.Map{"ids": []string{risk.ID, uuid.New().String()}, "action": "set_status", "value": "mitigating"})
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		result := decodeBulkResult(t, body)
+		if !result.Committed || tx.rolledBack || result.Succeeded != 1 || result.Failed != 1 {
+			t.Errorf("expected partial success to commit, got %+v", result)
+		}
+	})
+
+	t.Run("rejects invalid requests", func(t *testing.T) {
+		app := newApp(&mockRiskRepo{risks: map[string]*models.Risk{}}, &mockAuditRepo{logs: []*models.AuditLog{}}, &mockTransactor{})
+		tests := []struct {
+			name string
+			body fiber.Map
+		}{
+			{"no selection", fiber.Map{"action": "delete"}},
+			{"ids and filter", fiber.Map{"ids": []string{uuid.New().String()}, "filter": fiber.Map{"status": "open"}, "action": "delete"}},
+			{"empty filter", fiber.Map{"filter": fiber.Map{}, "action": "delete"}},
+			{"invalid id", fiber.Map{"ids": []string{"nope"}, "action": "delete"}},
+			{"unknown action", fiber.Map{"ids": []string{uuid.New().String()}, "action": "archive"}},
+			{"assignee on risks", fiber.Map{"ids": []string{uuid.New().String()}, "action": "set_assignee", "value": uuid.New().String()}},
+			{"invalid status", fiber.Map{"ids": []string{uuid.New().String()}, "action": "set_status", "value": "done"}},
+			{"unknown category", fiber.Map{"ids": []string{uuid.New().String()}, "action": "set_category", "value": uuid.New().String()}},
+			{"blank tag", fiber.Map{"ids": []string{uuid.New().String()}, "action": "add_tag", "value": "  "}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if status, body := post(app, tt.body); status != 400 {
+					t.Errorf("expected status 400, got %d: %s", status, body)
+				}
+			})
+		}
+	})
+}
+
+func TestBulkIncidentsHandler(t *testing.T) {
+	incidentRepo := newMockIncidentRepo()
+	auditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewBulkHandler(&mockTransactor{}, &mockRiskRepo{risks: map[string]*models.Risk{}},
+		&mockCategoryRepo{categories: map[string]*models.Category{}}, incidentRepo, newMockIncidentCategoryRepo(), auditRepo)
+
+	app := fiber.New()
+	app.Post("/incidents/bulk", testAuthMiddleware, handler.Incidents)
+
+	incident := &models.Incident{ID: uuid.New().String(), Title: "Outage", Status: models.IncidentStatusInProgress, Tags: []string{}, Version: 1}
+	incidentRepo.incidents[incident.ID] = incident
+
+	t.Run("closing sets resolved_at", func(t *testing.T) {
+		body, _ := json.Marshal(fiber.Map{"ids": []string{incident.ID}, "action": "set_status", "value": "closed"})
+		req := httptest.NewRequest("POST", "/incidents/bulk", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		if incident.Status != models.IncidentStatusClosed || incident.ResolvedAt == nil {
+			t.Errorf("expected closed incident with resolved_at, got %s %v", incident.Status, incident.ResolvedAt)
+		}
+		if len(auditRepo.logs) != 1 || auditRepo.logs[0].EntityType != "incident" {
+			t.Errorf("expected one incident audit entry, got %d", len(auditRepo.logs))
+		}
+	})
+
+	t.Run("delete requires admin", func(t *testing.T) {
+		body, _ := json.Marshal(fiber.Map{"ids": []string{incident.ID}, "action": "delete"})
+		req := httptest.NewRequest("POST", "/incidents/bulk", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		if resp.StatusCode != 403 {
+			t.Errorf("expected status 403, got %d", resp.StatusCode)
+		}
+	})
+}
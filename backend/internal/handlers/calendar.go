@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/calendar"
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// calendarRefresh is how often subscribed calendar clients are asked to
+// fetch the feed again. Google Calendar polls on its own schedule regardless.
+const calendarRefresh = time.Hour
+
+var calendarNames = map[models.CalendarScope]string{
+	models.CalendarScopeMine: "Risk register: my deadlines",
+	models.CalendarScopeTeam: "Risk register: team deadlines",
+}
+
+type CalendarHandler struct {
+	feeds  database.CalendarRepository
+	appURL string
+	apiURL string
+}
+
+// NewCalendarHandler returns a handler for calendar feeds. Event links point
+// at appURL; feed URLs are built from apiURL, or from the request when it is
+// empty.
+func NewCalendarHandler(feeds database.CalendarRepository, appURL, apiURL string) *CalendarHandler {
+	return &CalendarHandler{feeds: feeds, appURL: appURL, apiURL: strings.TrimRight(apiURL, "/")}
+}
+
+// GetFeed returns the current user's calendar feed, without its token
+func (h *CalendarHandler) GetFeed(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	feed, err := h.feeds.FindFeed(c.Context(), user.UserID)
+	if err != nil {
+		if errors.Is(err, database.ErrCalendarFeedNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "calendar feed")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "calendar feed")})
+	}
+	return c.JSON(feed)
+}
+
+// CreateFeed creates the current user's calendar feed, or gives it a new
+// token if it exists so that the old URLs stop working. The response holds
+// the subscription URLs, which are not shown again.
+func (h *CalendarHandler) CreateFeed(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	token := newToken("cal_")
+	feed, err := h.feeds.SetFeedToken(c.Context(), user.UserID, hashToken(token))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToCreate, "calendar feed")})
+	}
+
+	base := h.apiURL
+	if base == "" {
+		base = c.BaseURL()
+	}
+	feed.Token = token
+	feed.URLs = map[models.CalendarScope]string{}
+	for _, scope := range models.CalendarScopes {
+		feed.URLs[scope] = base + "/api/v1/calendar/" + token + ".ics?scope=" + string(scope)
+	}
+	return c.Status(201).JSON(feed)
+}
+
+// DeleteFeed revokes the current user's calendar feed
+func (h *CalendarHandler) DeleteFeed(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if err := h.feeds.DeleteFeed(c.Context(), user.UserID); err != nil {
+		if errors.Is(err, database.ErrCalendarFeedNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "calendar feed")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToDelete, "calendar feed")})
+	}
+	return c.SendStatus(204)
+}
+
+// Feed serves a calendar feed as iCalendar. Calendar clients can't log in,
+// so the token in the URL is the credential; ?scope=mine (the default) or
+// ?scope=team picks whose deadlines are included.
+func (h *CalendarHandler) Feed(c *fiber.Ctx) error {
+	scope := models.CalendarScope(c.Query("scope", string(models.CalendarScopeMine)))
+	if !slices.Contains(models.CalendarScopes, scope) {
+		return c.Status(400).JSON(fiber.Map{"error": "scope must be mine or team"})
+	}
+
+	feed, err := h.feeds.FindFeedByToken(c.Context(), hashToken(c.Params("token")))
+	if err != nil {
+		if errors.Is(err, database.ErrCalendarFeedNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "calendar feed")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "calendar feed")})
+	}
+
+	entries, err := h.feeds.Entries(c.Context(), feed.UserID, scope)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "calendar feed")})
+	}
+	// Only informational, so a failure here shouldn't fail the fetch
+	_ = h.feeds.MarkFetched(c.Context(), feed.UserID, time.Now())
+
+	c.Set(fiber.HeaderContentType, "text/calendar; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="risk-register-`+string(scope)+`.ics"`)
+	c.Set(fiber.HeaderCacheControl, "private, max-age=300")
+	return calendar.Feed(calendarNames[scope], entries, h.appURL, calendarRefresh).Write(c)
+}
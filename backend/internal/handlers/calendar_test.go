@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCalendarRepo struct {
+	// feeds maps token hashes to feeds
+	feeds   map[string]*models.CalendarFeed
+	entries map[models.CalendarScope][]*models.CalendarEntry
+	fetched map[string]time.Time
+}
+
+func newMockCalendarRepo() *mockCalendarRepo {
+	return &mockCalendarRepo{
+		feeds:   map[string]*models.CalendarFeed{},
+		entries: map[models.CalendarScope][]*models.CalendarEntry{},
+		fetched: map[string]time.Time{},
+	}
+}
+
+func (m *mockCalendarRepo) SetFeedToken(ctx context.Context, userID, tokenHash string) (*models.CalendarFeed, error) {
+	m.DeleteFeed(ctx, userID)
+	feed := &models.CalendarFeed{UserID: userID, CreatedAt: time.Now()}
+	m.feeds[tokenHash] = feed
+	copied := *feed
+	return &copied, nil
+}
+
+func (m *mockCalendarRepo) FindFeed(ctx context.Context, userID string) (*models.CalendarFeed, error) {
+	for _, feed := range m.feeds {
+		if feed.UserID == userID {
+			copied := *feed
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrCalendarFeedNotFound
+}
+
+func (m *mockCalendarRepo) FindFeedByToken(ctx context.Context, tokenHash string) (*models.CalendarFeed, error) {
+	if feed, ok := m.feeds[tokenHash]; ok {
+		copied := *feed
+		return &copied, nil
+	}
+	return nil, database.ErrCalendarFeedNotFound
+}
+
+func (m *mockCalendarRepo) DeleteFeed(ctx context.Context, userID string) error {
+	for hash, feed := range m.feeds {
+		if feed.UserID == userID {
+			delete(m.feeds, hash)
+			return nil
+		}
+	}
+	return database.ErrCalendarFeedNotFound
+}
+
+func (m *mockCalendarRepo) MarkFetched(ctx context.Context, userID string, at time.Time) error {
+	m.fetched[userID] = at
+	return nil
+}
+
+func (m *mockCalendarRepo) Entries(ctx context.Context, userID string, scope models.CalendarScope) ([]*models.CalendarEntry, error) {
+	return m.entries[scope], nil
+}
+
+// setupCalendarApp registers the routes as the server does, with the public
+// feed route next to the authenticated ones
+func setupCalendarApp() (*fiber.App, *mockCalendarRepo) {
+	repo := newMockCalendarRepo()
+	handler := NewCalendarHandler(repo, "https://risk.example.com", "https://api.example.com/")
+	app := fiber.New()
+	app.Get("/api/v1/calendar/:token.ics", handler.Feed)
+	feed := app.Group("/api/v1/calendar/feed", testAuthMiddleware)
+	feed.Get("/", handler.GetFeed)
+	feed.Post("/", handler.CreateFeed)
+	feed.Delete("/", handler.DeleteFeed)
+	return app, repo
+}
+
+func createCalendarFeed(t *testing.T, app *fiber.App) *models.CalendarFeed {
+	t.Helper()
+	resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/calendar/feed", nil))
+	require.NoError(t, err)
+	require.Equal(t, 201, resp.StatusCode)
+	var feed models.CalendarFeed
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&feed))
+	return &feed
+}
+
+func TestCalendarHandler_CreateFeed(t *testing.T) {
+	app, _ := setupCalendarApp()
+
+	feed := createCalendarFeed(t, app)
+	assert.True(t, strings.HasPrefix(feed.Token, "cal_"))
+	assert.Equal(t, "https://api.example.com/api/v1/calendar/"+feed.Token+".ics?scope=mine", feed.URLs[models.CalendarScopeMine])
+	assert.Equal(t, "https://api.example.com/api/v1/calendar/"+feed.Token+".ics?scope=team", feed.URLs[models.CalendarScopeTeam])
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/calendar/feed", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.NotContains(t, string(body), feed.Token, "the token is only shown when created")
+
+	t.Run("rotating replaces the token", func(t *testing.T) {
+		rotated := createCalendarFeed(t, app)
+		assert.NotEqual(t, feed.Token, rotated.Token)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/calendar/"+feed.Token+".ics", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/calendar/"+rotated.Token+".ics", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("delete revokes the feed", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("DELETE", "/api/v1/calendar/feed", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/calendar/feed", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+}
+
+func TestCalendarHandler_Feed(t *testing.T) {
+	app, repo := setupCalendarApp()
+	feed := createCalendarFeed(t, app)
+	review := &models.CalendarEntry{Kind: models.CalendarEntryReview, EntityID: "risk-1", RiskID: "risk-1",
+		RiskTitle: "Backups untested", Date: time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), Severity: models.SeverityHigh,
+		Status: "open", Version: 1, UpdatedAt: time.Now()}
+	teammate := &models.CalendarEntry{Kind: models.CalendarEntryMitigationDue, EntityID: "mitigation-1", RiskID: "risk-2",
+		RiskTitle: "Single region", Description: "Add a second region", Date: time.Date(2026, 11, 9, 0, 0, 0, 0, time.UTC),
+		Severity: models.SeverityMedium, Status: "planned", Version: 1, UpdatedAt: time.Now()}
+	repo.entries[models.CalendarScopeMine] = []*models.CalendarEntry{review}
+	repo.entries[models.CalendarScopeTeam] = []*models.CalendarEntry{review, teammate}
+
+	get := func(query string) (int, string, string) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/calendar/"+feed.Token+".ics"+query, nil))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Content-Type"), string(body)
+	}
+
+	status, contentType, body := get("")
+	require.Equal(t, 200, status)
+	assert.Equal(t, "text/calendar; charset=utf-8", contentType)
+	assert.Contains(t, body, "UID:risk-review-risk-1@risk-register\r\n")
+	assert.Contains(t, body, "URL:https://risk.example.com/app/risks/risk-1\r\n")
+	assert.NotContains(t, body, "mitigation-due-mitigation-1")
+	assert.Contains(t, repo.fetched, "test-user-id")
+
+	status, _, body = get("?scope=team")
+	require.Equal(t, 200, status)
+	assert.Contains(t, body, "UID:risk-review-risk-1@risk-register\r\n")
+	assert.Contains(t, body, "UID:mitigation-due-mitigation-1@risk-register\r\n")
+	assert.Contains(t, body, "X-WR-CALNAME:Risk register: team deadlines\r\n")
+
+	status, _, _ = get("?scope=everyone")
+	assert.Equal(t, 400, status)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/calendar/cal_unknown.ics", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
@@ -70,6 +70,52 @@ func (h *CategoryHandler) Update(c *fiber.Ctx) error {
 	return c.JSON(category)
 }
 
+// Patch applies a JSON merge patch to a category. Setting description to null
+// clears it; name cannot be cleared.
+func (h *CategoryHandler) Patch(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "category id required"})
+	}
+
+	patch, err := parseMergePatch(c)
+	if err != nil {
+		return patchError(c, err)
+	}
+
+	errs := fieldErrors{}
+	patch.allow(errs, "name", "description")
+	var input models.UpdateCategoryInput
+
+	if v, ok := patch.string("name", errs); ok && requireText(errs, "name", v, 0) {
+		input.Name = v
+	}
+	if v, ok := patch.string("description", errs); ok {
+		description := textOrEmpty(v)
+		input.Description = &description
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	var category *models.Category
+	if input.Name == nil && input.Description == nil {
+		category, err = h.categories.FindByID(c.Context(), id)
+	} else {
+		category, err = h.categories.Update(c.Context(), id, &input)
+	}
+	if err == nil && category == nil {
+		err = database.ErrCategoryNotFound
+	}
+	if err != nil {
+		if errors.Is(err, database.ErrCategoryNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "category not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update category"})
+	}
+	return c.JSON(category)
+}
+
 func (h *CategoryHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
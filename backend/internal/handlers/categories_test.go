@@ -157,6 +157,56 @@ func TestUpdateCategoryHandler(t *testing.T) {
 	})
 }
 
+func TestPatchCategoryHandler(t *testing.T) {
+	app := fiber.New()
+	mockRepo := &mockCategoryRepo{categories: make(map[string]*models.Category)}
+	handler := NewCategoryHandler(mockRepo)
+
+	cat := &models.Category{ID: uuid.New().String(), Name: "Operational", Description: "Day-to-day"}
+	mockRepo.categories[cat.ID] = cat
+
+	app.Patch("/categories/:id", handler.Patch)
+
+	t.Run("Null Clears Description", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/categories/"+cat.ID, bytes.NewReader([]byte(`{"description": null}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Errorf("expected status 200, got %d", resp.StatusCode)
+		}
+		if cat.Description != "" || cat.Name != "Operational" {
+			t.Errorf("expected only the description to be cleared, got %+v", cat)
+		}
+	})
+
+	t.Run("Null Name", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/categories/"+cat.ID, bytes.NewReader([]byte(`{"name": null}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 400 {
+			t.Errorf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		req := httptest.NewRequest("PATCH", "/categories/"+uuid.New().String(), bytes.NewReader([]byte(`{}`)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 404 {
+			t.Errorf("expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
+
 func TestDeleteCategoryHandler(t *testing.T) {
 	app := fiber.New()
 	mockRepo := &mockCategoryRepo{categories: make(map[string]*models.Category)}
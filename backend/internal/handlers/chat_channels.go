@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/chat"
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxChatMessages = 200
+
+type ChatChannelHandler struct {
+	channels           database.ChatChannelRepository
+	incidentCategories database.IncidentCategoryRepository
+	dispatcher         *chat.Dispatcher
+}
+
+func NewChatChannelHandler(channels database.ChatChannelRepository, incidentCategories database.IncidentCategoryRepository, dispatcher *chat.Dispatcher) *ChatChannelHandler {
+	return &ChatChannelHandler{channels: channels, incidentCategories: incidentCategories, dispatcher: dispatcher}
+}
+
+// Triggers lists the incident changes a channel can be notified of
+func (h *ChatChannelHandler) Triggers(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"triggers": models.ChatTriggers, "default_templates": chat.DefaultTemplates})
+}
+
+func (h *ChatChannelHandler) List(c *fiber.Ctx) error {
+	channels, err := h.channels.List(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "chat channels")})
+	}
+	return c.JSON(channels)
+}
+
+func (h *ChatChannelHandler) Get(c *fiber.Ctx) error {
+	channel, err := h.find(c)
+	if err != nil || channel == nil {
+		return err
+	}
+	return c.JSON(channel)
+}
+
+func (h *ChatChannelHandler) Create(c *fiber.Ctx) error {
+	var input models.CreateChatChannelInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	channel := &models.ChatChannel{
+		Name:        strings.TrimSpace(input.Name),
+		Type:        input.Type,
+		WebhookURL:  strings.TrimSpace(input.WebhookURL),
+		Triggers:    input.Triggers,
+		Priorities:  input.Priorities,
+		CategoryIDs: input.CategoryIDs,
+		Templates:   input.Templates,
+		Active:      input.Active == nil || *input.Active,
+		CreatedBy:   user.UserID,
+	}
+	if errs := h.validate(c.Context(), channel); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	if err := h.channels.Create(c.Context(), channel); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToCreate, "chat channel")})
+	}
+	return c.Status(201).JSON(channel)
+}
+
+func (h *ChatChannelHandler) Update(c *fiber.Ctx) error {
+	channel, err := h.find(c)
+	if err != nil || channel == nil {
+		return err
+	}
+
+	var input models.UpdateChatChannelInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Name == nil && input.Type == nil && input.WebhookURL == nil && input.Triggers == nil && input.Priorities == nil &&
+		input.CategoryIDs == nil && input.Templates == nil && input.Active == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	if input.Name != nil {
+		channel.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Type != nil {
+		channel.Type = *input.Type
+	}
+	if input.WebhookURL != nil {
+		channel.WebhookURL = strings.TrimSpace(*input.WebhookURL)
+	}
+	if input.Triggers != nil {
+		channel.Triggers = *input.Triggers
+	}
+	if input.Priorities != nil {
+		channel.Priorities = *input.Priorities
+	}
+	if input.CategoryIDs != nil {
+		channel.CategoryIDs = *input.CategoryIDs
+	}
+	if input.Templates != nil {
+		channel.Templates = *input.Templates
+	}
+	if input.Active != nil {
+		channel.Active = *input.Active
+	}
+	if errs := h.validate(c.Context(), channel); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	if err := h.channels.Update(c.Context(), channel); err != nil {
+		if errors.Is(err, database.ErrChatChannelNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "chat channel")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToUpdate, "chat channel")})
+	}
+	return c.JSON(channel)
+}
+
+// Delete removes a channel along with its message log
+func (h *ChatChannelHandler) Delete(c *fiber.Ctx) error {
+	if err := h.channels.Delete(c.Context(), c.Params("id")); err != nil {
+		if errors.Is(err, database.ErrChatChannelNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "chat channel")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToDelete, "chat channel")})
+	}
+	return c.SendStatus(204)
+}
+
+// Messages is a channel's message log, newest first
+func (h *ChatChannelHandler) Messages(c *fiber.Ctx) error {
+	channel, err := h.find(c)
+	if err != nil || channel == nil {
+		return err
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > maxChatMessages {
+		limit = 50
+	}
+	messages, err := h.channels.ListMessages(c.Context(), channel.ID, limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "chat messages")})
+	}
+	return c.JSON(models.ChatMessageListResponse{Messages: messages})
+}
+
+// Test posts a sample message for the given trigger (incident.created by
+// default) straight away, so admins can check the URL and templates. A
+// channel that rejects it gives 502 with the reason.
+func (h *ChatChannelHandler) Test(c *fiber.Ctx) error {
+	channel, err := h.find(c)
+	if err != nil || channel == nil {
+		return err
+	}
+	trigger := models.ChatTrigger(c.Query("trigger", string(models.ChatTriggerCreated)))
+	if _, ok := chat.DefaultTemplates[trigger]; !ok {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("unknown trigger %q", trigger)})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	now := time.Now()
+	data := &chat.MessageData{
+		Trigger: trigger,
+		Incident: &models.Incident{
+			Title:           "Test notification from the risk register",
+			Description:     "This is a test message for the " + channel.Name + " channel.",
+			Priority:        models.PriorityP1,
+			Status:          models.IncidentStatusNew,
+			ServiceAffected: "example-service",
+			ResolutionNotes: "No action needed.",
+			OccurredAt:      now,
+			DetectedAt:      now,
+		},
+		Actor:    user.Email,
+		Assignee: user.Email,
+	}
+	payload, err := chat.Render(channel, trigger, data)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to render test message"})
+	}
+	status, _, err := h.dispatcher.Send(c.Context(), channel.WebhookURL, payload)
+	if err != nil {
+		return c.Status(502).JSON(fiber.Map{"error": err.Error(), "response_status": status})
+	}
+	return c.JSON(fiber.Map{"response_status": status})
+}
+
+// find loads the channel named in the path. When it returns a nil channel
+// the response has already been written.
+func (h *ChatChannelHandler) find(c *fiber.Ctx) (*models.ChatChannel, error) {
+	channel, err := h.channels.FindByID(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrChatChannelNotFound) {
+			return nil, c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "chat channel")})
+		}
+		return nil, c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "chat channel")})
+	}
+	return channel, nil
+}
+
+// validate checks a channel's fields, removes repeated triggers, priorities
+// and categories, and drops empty templates
+func (h *ChatChannelHandler) validate(ctx context.Context, ch *models.ChatChannel) fieldErrors {
+	errs := fieldErrors{}
+	requireText(errs, "name", &ch.Name, 255)
+
+	if ch.Type != models.ChatChannelSlack && ch.Type != models.ChatChannelTeams {
+		errs["type"] = "must be one of slack, teams"
+	}
+	if u, err := url.Parse(ch.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs["webhook_url"] = "must be an absolute http or https URL"
+	}
+
+	known := map[models.ChatTrigger]bool{}
+	for _, t := range models.ChatTriggers {
+		known[t] = true
+	}
+	triggers := []models.ChatTrigger{}
+	for _, t := range ch.Triggers {
+		if !known[t] {
+			errs["triggers"] = fmt.Sprintf("unknown trigger %q", t)
+		} else if !slices.Contains(triggers, t) {
+			triggers = append(triggers, t)
+		}
+	}
+	if len(triggers) == 0 && errs["triggers"] == "" {
+		errs["triggers"] = "must include at least one trigger"
+	}
+	ch.Triggers = triggers
+
+	priorities := []models.IncidentPriority{}
+	for _, p := range ch.Priorities {
+		if p != models.PriorityP1 && p != models.PriorityP2 && p != models.PriorityP3 && p != models.PriorityP4 {
+			errs["priorities"] = "must only contain p1, p2, p3, p4"
+		} else if !slices.Contains(priorities, p) {
+			priorities = append(priorities, p)
+		}
+	}
+	ch.Priorities = priorities
+
+	categoryIDs := []string{}
+	for _, id := range ch.CategoryIDs {
+		if slices.Contains(categoryIDs, id) {
+			continue
+		}
+		if cat, err := h.incidentCategories.FindByID(ctx, id); err != nil || cat == nil {
+			errs["category_ids"] = fmt.Sprintf("%q is not an incident category", id)
+		}
+		categoryIDs = append(categoryIDs, id)
+	}
+	ch.CategoryIDs = categoryIDs
+
+	templates := map[models.ChatTrigger]models.ChatTemplate{}
+	for trigger, tmpl := range ch.Templates {
+		field := "templates." + string(trigger)
+		if !known[trigger] {
+			errs[field] = "unknown trigger"
+			continue
+		}
+		if err := chat.ValidateTemplate(tmpl.Title); err != nil {
+			errs[field+".title"] = err.Error()
+		}
+		if err := chat.ValidateTemplate(tmpl.Body); err != nil {
+			errs[field+".body"] = err.Error()
+		}
+		if tmpl.Title != "" || tmpl.Body != "" {
+			templates[trigger] = tmpl
+		}
+	}
+	ch.Templates = templates
+	return errs
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/chat"
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type mockChatChannelRepo struct {
+	database.ChatChannelRepository
+	channels map[string]*models.ChatChannel
+}
+
+func newMockChatChannelRepo() *mockChatChannelRepo {
+	return &mockChatChannelRepo{channels: map[string]*models.ChatChannel{}}
+}
+
+func (m *mockChatChannelRepo) Create(ctx context.Context, ch *models.ChatChannel) error {
+	ch.ID = uuid.New().String()
+	ch.CreatedAt = time.Now()
+	ch.UpdatedAt = ch.CreatedAt
+	stored := *ch
+	m.channels[ch.ID] = &stored
+	return nil
+}
+
+func (m *mockChatChannelRepo) FindByID(ctx context.Context, id string) (*models.ChatChannel, error) {
+	ch, ok := m.channels[id]
+	if !ok {
+		return nil, database.ErrChatChannelNotFound
+	}
+	found := *ch
+	return &found, nil
+}
+
+func (m *mockChatChannelRepo) Update(ctx context.Context, ch *models.ChatChannel) error {
+	if _, ok := m.channels[ch.ID]; !ok {
+		return database.ErrChatChannelNotFound
+	}
+	stored := *ch
+	m.channels[ch.ID] = &stored
+	return nil
+}
+
+func (m *mockChatChannelRepo) WithTx(tx *sql.Tx) database.ChatChannelRepository {
+	return m
+}
+
+type chatChannelTestEnv struct {
+	app      *fiber.App
+	channels *mockChatChannelRepo
+}
+
+func newChatChannelTestEnv() *chatChannelTestEnv {
+	categories := newMockIncidentCategoryRepo()
+	categories.categories["cat-network"] = &models.IncidentCategory{ID: "cat-network", Name: "Network"}
+	env := &chatChannelTestEnv{app: fiber.New(), channels: newMockChatChannelRepo()}
+
+	dispatcher := chat.NewDispatcher(env.channels, &http.Client{Timeout: 5 * time.Second}, 0)
+	handler := NewChatChannelHandler(env.channels, categories, dispatcher)
+	env.app.Post("/chat-channels", testAuthMiddleware, handler.Create)
+	env.app.Put("/chat-channels/:id", testAuthMiddleware, handler.Update)
+	env.app.Post("/chat-channels/:id/test", testAuthMiddleware, handler.Test)
+	return env
+}
+
+func (env *chatChannelTestEnv) request(t *testing.T, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := env.app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	var result map[string]any
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestChatChannel_Create(t *testing.T) {
+	env := newChatChannelTestEnv()
+
+	status, result := env.request(t, "POST", "/chat-channels", `{
+		"name": " Incidents ",
+		"type": "slack",
+		"webhook_url": "https://hooks.slack.com/services/T0/B0/x",
+		"triggers": ["incident.created", "incident.created", "incident.resolved"],
+		"priorities": ["p1", "p2"],
+		"category_ids": ["cat-network"],
+		"templates": {"incident.created": {"title": "{{.Incident.Title}} ({{upper .Incident.Priority}})"}, "incident.resolved": {}}
+	}`)
+	if status != 201 {
+		t.Fatalf("expected 201, got %d: %v", status, result)
+	}
+	ch := env.channels.channels[result["id"].(string)]
+	if ch.Name != "Incidents" || !ch.Active || ch.CreatedBy != "test-user-id" {
+		t.Errorf("unexpected channel: %+v", ch)
+	}
+	if len(ch.Triggers) != 2 {
+		t.Errorf("expected repeated triggers to be removed, got %v", ch.Triggers)
+	}
+	if _, ok := ch.Templates[models.ChatTriggerResolved]; ok || len(ch.Templates) != 1 {
+		t.Errorf("expected the empty template to be dropped, got %v", ch.Templates)
+	}
+}
+
+func TestChatChannel_CreateValidation(t *testing.T) {
+	env := newChatChannelTestEnv()
+
+	tests := []struct {
+		name  string
+		body  string
+		field string
+	}{
+		{"bad type", `{"name": "x", "type": "irc", "webhook_url": "https://example.com", "triggers": ["incident.created"]}`, "type"},
+		{"bad url", `{"name": "x", "type": "slack", "webhook_url": "hooks.slack.com", "triggers": ["incident.created"]}`, "webhook_url"},
+		{"no triggers", `{"name": "x", "type": "slack", "webhook_url": "https://example.com", "triggers": []}`, "triggers"},
+		{"unknown trigger", `{"name": "x", "type": "slack", "webhook_url": "https://example.com", "triggers": ["risk.created"]}`, "triggers"},
+		{"bad priority", `{"name": "x", "type": "slack", "webhook_url": "https://example.com", "triggers": ["incident.created"], "priorities": ["p9"]}`, "priorities"},
+		{"unknown category", `{"name": "x", "type": "teams", "webhook_url": "https://example.com", "triggers": ["incident.created"], "category_ids": ["nope"]}`, "category_ids"},
+		{"broken template", `{"name": "x", "type": "slack", "webhook_url": "https://example.com", "triggers": ["incident.created"],
+			"templates": {"incident.created": {"body": "{{.Incident.Nope}}"}}}`, "templates.incident.created.body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, result := env.request(t, "POST", "/chat-channels", tt.body)
+			if status != 400 {
+				t.Fatalf("expected 400, got %d", status)
+			}
+			fields, _ := result["fields"].(map[string]any)
+			if _, ok := fields[tt.field]; !ok {
+				t.Errorf("expected an error for %s, got %v", tt.field, result)
+			}
+		})
+	}
+	if len(env.channels.channels) != 0 {
+		t.Error("invalid channels must not be saved")
+	}
+}
+
+func TestChatChannel_Update(t *testing.T) {
+	env := newChatChannelTestEnv()
+	ch := &models.ChatChannel{Name: "Incidents", Type: models.ChatChannelSlack, WebhookURL: "https://example.com",
+		Triggers: []models.ChatTrigger{models.ChatTriggerCreated}, Active: true}
+	env.channels.Create(context.Background(), ch)
+
+	if status, _ := env.request(t, "PUT", "/chat-channels/"+ch.ID, `{}`); status != 400 {
+		t.Errorf("expected 400 for an empty update, got %d", status)
+	}
+	status, _ := env.request(t, "PUT", "/chat-channels/"+ch.ID, `{"type": "teams", "active": false, "priorities": ["p1"]}`)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	updated := env.channels.channels[ch.ID]
+	if updated.Type != models.ChatChannelTeams || updated.Active || len(updated.Priorities) != 1 || len(updated.Triggers) != 1 {
+		t.Errorf("unexpected channel after update: %+v", updated)
+	}
+	if status, _ := env.request(t, "PUT", "/chat-channels/missing", `{"active": true}`); status != 404 {
+		t.Errorf("expected 404, got %d", status)
+	}
+}
+
+func TestChatChannel_Test(t *testing.T) {
+	var received string
+	reject := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		if reject {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid_blocks"))
+		}
+	}))
+	defer receiver.Close()
+
+	env := newChatChannelTestEnv()
+	ch := &models.ChatChannel{Name: "Incidents", Type: models.ChatChannelSlack, WebhookURL: receiver.URL,
+		Triggers: []models.ChatTrigger{models.ChatTriggerCreated}, Active: true}
+	env.channels.Create(context.Background(), ch)
+
+	status, result := env.request(t, "POST", "/chat-channels/"+ch.ID+"/test?trigger=incident.escalated", "")
+	if status != 200 || result["response_status"] != float64(200) {
+		t.Fatalf("expected the test message to be accepted, got %d %v", status, result)
+	}
+	if !strings.Contains(received, "Escalated to P1: Test notification") {
+		t.Errorf("expected the escalation wording, got %s", received)
+	}
+
+	reject = true
+	status, result = env.request(t, "POST", "/chat-channels/"+ch.ID+"/test", "")
+	if status != 502 || result["response_status"] != float64(400) || !strings.Contains(result["error"].(string), "invalid_blocks") {
+		t.Errorf("expected 502 with the channel's reason, got %d %v", status, result)
+	}
+
+	if status, _ := env.request(t, "POST", "/chat-channels/"+ch.ID+"/test?trigger=risk.created", ""); status != 400 {
+		t.Errorf("expected 400 for an unknown trigger, got %d", status)
+	}
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// The crosswalk import reads a CSV or XLSX file with one mapping per row:
+// the source and target framework (by name or ID), the source and target
+// control_ref, the strength (related when blank) and optional notes. When
+// every row maps the same pair of frameworks they can be given once as the
+// source_framework and target_framework form fields instead of columns.
+// Mappings that already exist, either way round, are updated in place.
+
+// crosswalkColumnAliases maps normalized headers to the mapping field they fill
+var crosswalkColumnAliases = map[string]string{
+	"source_framework":   "source_framework",
+	"source_ref":         "source_ref",
+	"source_control":     "source_ref",
+	"source_control_ref": "source_ref",
+	"source":             "source_ref",
+	"target_framework":   "target_framework",
+	"target_ref":         "target_ref",
+	"target_control":     "target_ref",
+	"target_control_ref": "target_ref",
+	"target":             "target_ref",
+	"strength":           "strength",
+	"relationship":       "strength",
+	"notes":              "notes",
+	"comment":            "notes",
+}
+
+// Import validates an uploaded crosswalk and, with commit=true, writes it.
+// Form fields: file (required), sheet (XLSX only), source_framework,
+// target_framework and commit.
+func (h *ControlMappingHandler) Import(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	f, err := file.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "failed to read file"})
+	}
+	defer f.Close()
+
+	table, err := readImportTable(f, filepath.Ext(file.Filename), c.FormValue("sheet"))
+	if err != nil {
+		if errors.Is(err, errImportFormat) {
+			return c.Status(415).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(table.rows) > maxImportRows {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("file has %d rows; at most %d can be imported at once", len(table.rows), maxImportRows),
+		})
+	}
+
+	columns := map[string]int{}
+	for i, header := range table.headers {
+		field, ok := crosswalkColumnAliases[normalizeImportHeader(header)]
+		if _, taken := columns[field]; ok && !taken {
+			columns[field] = i
+		}
+	}
+	defaults := map[string]string{
+		"source_framework": strings.TrimSpace(c.FormValue("source_framework")),
+		"target_framework": strings.TrimSpace(c.FormValue("target_framework")),
+	}
+	errs := fieldErrors{}
+	for _, field := range []string{"source_ref", "target_ref"} {
+		if _, ok := columns[field]; !ok {
+			errs[field] = "must be a column in the file"
+		}
+	}
+	for field, value := range defaults {
+		if _, ok := columns[field]; !ok && value == "" {
+			errs[field] = "must be a column in the file or a form field"
+		}
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	result := &models.ControlMappingImportResult{Filename: file.Filename, Format: table.format}
+	existing, err := h.resolveCrosswalk(c.Context(), table, columns, defaults, result)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to validate import"})
+	}
+
+	if c.FormValue("commit") != "true" {
+		return c.JSON(result)
+	}
+	if result.InvalidRows > 0 || result.TotalRows == 0 {
+		return c.Status(422).JSON(result)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	batchID := uuid.New().String()
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		return h.writeCrosswalk(c.Context(), tx, batchID, existing, result, user.UserID)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to import control mappings"})
+	}
+
+	result.BatchID = batchID
+	result.Committed = true
+	return c.Status(201).JSON(result)
+}
+
+// mappingPair keys a mapping by its controls regardless of direction
+func mappingPair(a, b string) string {
+	if a > b {
+		a, b = b, a
+	}
+	return a + "|" + b
+}
+
+// resolveCrosswalk fills result with one row per non-blank record, resolving
+// frameworks and controls and working out whether each mapping is new. It
+// returns the existing mappings by pair.
+func (h *ControlMappingHandler) resolveCrosswalk(ctx context.Context, table *importTable, columns map[string]int,
+	defaults map[string]string, result *models.ControlMappingImportResult) (map[string]*models.ControlMapping, error) {
+	frameworks, err := h.frameworks.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	frameworkByKey := make(map[string]*models.Framework, 2*len(frameworks))
+	for _, framework := range frameworks {
+		frameworkByKey[framework.ID] = framework
+		frameworkByKey[strings.ToLower(framework.Name)] = framework
+	}
+
+	controlsByFramework := map[string]map[string]*models.FrameworkControl{}
+	findControl := func(frameworkID, ref string) (*models.FrameworkControl, error) {
+		controls, ok := controlsByFramework[frameworkID]
+		if !ok {
+			list, err := h.controls.List(ctx, frameworkID, "")
+			if err != nil {
+				return nil, err
+			}
+			controls = make(map[string]*models.FrameworkControl, len(list))
+			for _, control := range list {
+				controls[control.ControlRef] = control
+			}
+			controlsByFramework[frameworkID] = controls
+		}
+		return controls[ref], nil
+	}
+
+	mappings, err := h.mappings.List(ctx, models.ControlMappingFilter{})
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*models.ControlMapping, len(mappings))
+	for _, mapping := range mappings {
+		existing[mappingPair(mapping.Source.ID, mapping.Target.ID)] = mapping
+	}
+
+	_, hasNotes := columns["notes"]
+	seen := map[string]int{}
+	result.Rows = []*models.ControlMappingImportRow{}
+	for i, record := range table.rows {
+		if isBlankRecord(record) {
+			continue
+		}
+		cell := func(field string) string {
+			col, ok := columns[field]
+			if !ok || col >= len(record) || strings.TrimSpace(record[col]) == "" {
+				return defaults[field]
+			}
+			return strings.TrimSpace(record[col])
+		}
+		row := &models.ControlMappingImportRow{
+			Row:             i + 2,
+			SourceFramework: cell("source_framework"),
+			SourceRef:       cell("source_ref"),
+			TargetFramework: cell("target_framework"),
+			TargetRef:       cell("target_ref"),
+			Strength:        models.MappingStrength(strings.ToLower(cell("strength"))),
+			Notes:           cell("notes"),
+			Errors:          map[string]string{},
+		}
+		if row.Strength == "" {
+			row.Strength = models.MappingRelated
+		}
+		requireOneOf(row.Errors, "strength", (*string)(&row.Strength), mappingStrengths...)
+
+		resolve := func(side, frameworkKey, ref string) (string, error) {
+			framework := frameworkByKey[strings.ToLower(frameworkKey)]
+			switch {
+			case frameworkKey == "":
+				row.Errors[side+"_framework"] = "is required"
+			case framework == nil:
+				row.Errors[side+"_framework"] = "framework not found"
+			case ref == "":
+				row.Errors[side+"_ref"] = "is required"
+			default:
+				control, err := findControl(framework.ID, ref)
+				if err != nil {
+					return "", err
+				}
+				if control == nil {
+					row.Errors[side+"_ref"] = fmt.Sprintf("control not found in %s", framework.Name)
+					return "", nil
+				}
+				return control.ID, nil
+			}
+			return "", nil
+		}
+		if row.SourceControlID, err = resolve("source", row.SourceFramework, row.SourceRef); err != nil {
+			return nil, err
+		}
+		if row.TargetControlID, err = resolve("target", row.TargetFramework, row.TargetRef); err != nil {
+			return nil, err
+		}
+
+		if row.SourceControlID != "" && row.TargetControlID != "" {
+			pair := mappingPair(row.SourceControlID, row.TargetControlID)
+			switch {
+			case row.SourceControlID == row.TargetControlID:
+				row.Errors["target_ref"] = "must differ from the source control"
+			case seen[pair] != 0:
+				row.Errors["target_ref"] = fmt.Sprintf("maps the same controls as row %d", seen[pair])
+			default:
+				seen[pair] = row.Row
+			}
+		}
+
+		if len(row.Errors) > 0 {
+			result.InvalidRows++
+		} else {
+			result.ValidRows++
+			mapping := existing[mappingPair(row.SourceControlID, row.TargetControlID)]
+			if mapping != nil && !hasNotes {
+				row.Notes = mapping.Notes
+			}
+			switch {
+			case mapping == nil:
+				row.Action = "create"
+				result.Created++
+			case mapping.Source.ID == row.SourceControlID && mapping.Strength == row.Strength && mapping.Notes == row.Notes:
+				row.MappingID = mapping.ID
+				row.Action = "unchanged"
+				result.Unchanged++
+			default:
+				row.MappingID = mapping.ID
+				row.Action = "update"
+				result.Updated++
+			}
+			row.Errors = nil
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.TotalRows = len(result.Rows)
+	return existing, nil
+}
+
+// writeCrosswalk creates and updates the mappings of a validated import and
+// records a control_mapping_import audit entry for the batch
+func (h *ControlMappingHandler) writeCrosswalk(ctx context.Context, tx *sql.Tx, batchID string,
+	existing map[string]*models.ControlMapping, result *models.ControlMappingImportResult, userID string) error {
+	mappings := h.mappings.WithTx(tx)
+	for _, row := range result.Rows {
+		switch row.Action {
+		case "create":
+			mapping := &models.ControlMapping{
+				Source:    models.ControlSummary{ID: row.SourceControlID},
+				Target:    models.ControlSummary{ID: row.TargetControlID},
+				Strength:  row.Strength,
+				Notes:     row.Notes,
+				CreatedBy: &userID,
+			}
+			if err := mappings.Create(ctx, mapping); err != nil {
+				return err
+			}
+			row.MappingID = mapping.ID
+		case "update":
+			mapping := existing[mappingPair(row.SourceControlID, row.TargetControlID)]
+			mapping.Source.ID, mapping.Target.ID = row.SourceControlID, row.TargetControlID
+			mapping.Strength, mapping.Notes = row.Strength, row.Notes
+			if err := mappings.Update(ctx, mapping); err != nil {
+				return err
+			}
+		}
+	}
+
+	return h.audit.WithTx(tx).Create(ctx, "control_mapping_import", batchID, models.AuditActionCreated, map[string]any{
+		"filename": result.Filename,
+		"format":   result.Format,
+		"created":  result.Created,
+		"updated":  result.Updated,
+	}, userID)
+}
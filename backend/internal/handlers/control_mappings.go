@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Control mappings (crosswalks) relate controls of different frameworks, so
+// that the risks linked to one control also count towards the controls it
+// maps to.
+
+var mappingStrengths = []string{string(models.MappingEqual), string(models.MappingSubset), string(models.MappingRelated)}
+
+type ControlMappingHandler struct {
+	tx         database.Transactor
+	mappings   database.ControlMappingRepository
+	frameworks database.FrameworkRepository
+	controls   database.FrameworkControlRepository
+	audit      database.AuditLogRepository
+}
+
+func NewControlMappingHandler(
+	tx database.Transactor,
+	mappings database.ControlMappingRepository,
+	frameworks database.FrameworkRepository,
+	controls database.FrameworkControlRepository,
+	audit database.AuditLogRepository,
+) *ControlMappingHandler {
+	return &ControlMappingHandler{tx: tx, mappings: mappings, frameworks: frameworks, controls: controls, audit: audit}
+}
+
+// List returns the mappings, optionally only those touching control_id or
+// framework_id on either side
+func (h *ControlMappingHandler) List(c *fiber.Ctx) error {
+	filter := models.ControlMappingFilter{ControlID: c.Query("control_id"), FrameworkID: c.Query("framework_id")}
+	errs := fieldErrors{}
+	if filter.ControlID != "" {
+		optionalUUID(errs, "control_id", &filter.ControlID)
+	}
+	if filter.FrameworkID != "" {
+		optionalUUID(errs, "framework_id", &filter.FrameworkID)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	mappings, err := h.mappings.List(c.Context(), filter)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "control mappings")})
+	}
+	return c.JSON(fiber.Map{"data": mappings})
+}
+
+func (h *ControlMappingHandler) Get(c *fiber.Ctx) error {
+	mapping, err := h.mappings.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return controlMappingError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(mapping)
+}
+
+func (h *ControlMappingHandler) Create(c *fiber.Ctx) error {
+	var input models.CreateControlMappingInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+
+	errs := fieldErrors{}
+	requireUUID(errs, "source_control_id", &input.SourceControlID)
+	requireUUID(errs, "target_control_id", &input.TargetControlID)
+	requireOneOf(errs, "strength", (*string)(&input.Strength), mappingStrengths...)
+	if input.SourceControlID == input.TargetControlID && input.SourceControlID != "" {
+		errs["target_control_id"] = "must differ from source_control_id"
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	mapping := &models.ControlMapping{
+		Source:   models.ControlSummary{ID: input.SourceControlID},
+		Target:   models.ControlSummary{ID: input.TargetControlID},
+		Strength: input.Strength,
+		Notes:    input.Notes,
+	}
+	if user := middleware.GetUserFromContext(c); user != nil {
+		mapping.CreatedBy = &user.UserID
+	}
+	if err := h.mappings.Create(c.Context(), mapping); err != nil {
+		return controlMappingError(c, err, ErrFailedToCreate)
+	}
+	return h.respond(c, 201, mapping.ID)
+}
+
+// Update changes a mapping's strength and/or notes
+func (h *ControlMappingHandler) Update(c *fiber.Ctx) error {
+	mapping, err := h.mappings.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return controlMappingError(c, err, ErrFailedToFetch)
+	}
+
+	var input models.UpdateControlMappingInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Strength == nil && input.Notes == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+	errs := fieldErrors{}
+	if input.Strength != nil && requireOneOf(errs, "strength", (*string)(input.Strength), mappingStrengths...) {
+		mapping.Strength = *input.Strength
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if input.Notes != nil {
+		mapping.Notes = *input.Notes
+	}
+
+	if err := h.mappings.Update(c.Context(), mapping); err != nil {
+		return controlMappingError(c, err, ErrFailedToUpdate)
+	}
+	return h.respond(c, 200, mapping.ID)
+}
+
+func (h *ControlMappingHandler) Delete(c *fiber.Ctx) error {
+	if err := h.mappings.Delete(c.Context(), c.Params("id")); err != nil {
+		return controlMappingError(c, err, ErrFailedToDelete)
+	}
+	return c.SendStatus(204)
+}
+
+// Crosswalk lists the risks linked to a control and the controls mapped to
+// it, i.e. the controls those risks also cover. framework_id limits the
+// mapped controls to one framework.
+func (h *ControlMappingHandler) Crosswalk(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return c.Status(404).JSON(fiber.Map{"error": "control not found"})
+	}
+	frameworkID := c.Query("framework_id")
+	if frameworkID != "" && uuid.Validate(frameworkID) != nil {
+		return validationFailed(c, fieldErrors{"framework_id": "must be a UUID"})
+	}
+
+	control, err := h.controls.GetByID(c.Context(), id)
+	if err != nil {
+		return mapFrameworkControlError(c, err, "failed to fetch control")
+	}
+	risks, err := h.controls.ListLinkedRisks(c.Context(), id)
+	if err != nil {
+		return mapFrameworkControlError(c, err, "failed to fetch linked risks")
+	}
+	mapped, err := h.mappings.Mapped(c.Context(), id, frameworkID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "control mappings")})
+	}
+
+	if risks == nil {
+		risks = []*models.ControlLinkedRisk{}
+	}
+	return c.JSON(&models.ControlCrosswalk{Control: control, Risks: risks, Mapped: mapped})
+}
+
+// ListMappedForRisk lists the controls a risk covers through the mappings of
+// the controls it is linked to
+func (h *ControlMappingHandler) ListMappedForRisk(c *fiber.Ctx) error {
+	mapped, err := h.mappings.MappedForRisk(c.Context(), c.Params("riskId"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "mapped controls")})
+	}
+	return c.JSON(fiber.Map{"data": mapped})
+}
+
+// respond writes the mapping as stored, with both controls filled in
+func (h *ControlMappingHandler) respond(c *fiber.Ctx, status int, id string) error {
+	mapping, err := h.mappings.GetByID(c.Context(), id)
+	if err != nil {
+		return controlMappingError(c, err, ErrFailedToFetch)
+	}
+	return c.Status(status).JSON(mapping)
+}
+
+// controlMappingError maps repository errors to responses, using failed for
+// anything unexpected
+func controlMappingError(c *fiber.Ctx, err error, failed string) error {
+	switch {
+	case errors.Is(err, database.ErrControlMappingNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "control mapping")})
+	case errors.Is(err, database.ErrControlMappingExists):
+		return c.Status(409).JSON(fiber.Map{"error": "these controls are already mapped"})
+	case errors.Is(err, database.ErrFrameworkControlNotFound):
+		return validationFailed(c, fieldErrors{"control_id": "control not found"})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(failed, "control mapping")})
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockControlMappingRepo fills in control summaries from the control repo
+type mockControlMappingRepo struct {
+	mappings map[string]*models.ControlMapping
+	controls *mockFrameworkControlRepo
+}
+
+func (m *mockControlMappingRepo) summary(id string) models.ControlSummary {
+	control := m.controls.controls[id]
+	return models.ControlSummary{ID: id, FrameworkID: control.FrameworkID, ControlRef: control.ControlRef, Title: control.Title}
+}
+
+func (m *mockControlMappingRepo) List(ctx context.Context, filter models.ControlMappingFilter) ([]*models.ControlMapping, error) {
+	list := []*models.ControlMapping{}
+	for _, mapping := range m.mappings {
+		if filter.ControlID != "" && mapping.Source.ID != filter.ControlID && mapping.Target.ID != filter.ControlID {
+			continue
+		}
+		copied := *mapping
+		list = append(list, &copied)
+	}
+	return list, nil
+}
+
+func (m *mockControlMappingRepo) GetByID(ctx context.Context, id string) (*models.ControlMapping, error) {
+	mapping, ok := m.mappings[id]
+	if !ok {
+		return nil, database.ErrControlMappingNotFound
+	}
+	copied := *mapping
+	return &copied, nil
+}
+
+func (m *mockControlMappingRepo) Create(ctx context.Context, mapping *models.ControlMapping) error {
+	if m.controls.controls[mapping.Source.ID] == nil || m.controls.controls[mapping.Target.ID] == nil {
+		return database.ErrFrameworkControlNotFound
+	}
+	for _, existing := range m.mappings {
+		if mappingPair(existing.Source.ID, existing.Target.ID) == mappingPair(mapping.Source.ID, mapping.Target.ID) {
+			return database.ErrControlMappingExists
+		}
+	}
+	mapping.ID = uuid.New().String()
+	mapping.CreatedAt, mapping.UpdatedAt = time.Now(), time.Now()
+	mapping.Source, mapping.Target = m.summary(mapping.Source.ID), m.summary(mapping.Target.ID)
+	copied := *mapping
+	m.mappings[mapping.ID] = &copied
+	return nil
+}
+
+func (m *mockControlMappingRepo) Update(ctx context.Context, mapping *models.ControlMapping) error {
+	if _, ok := m.mappings[mapping.ID]; !ok {
+		return database.ErrControlMappingNotFound
+	}
+	mapping.Source, mapping.Target = m.summary(mapping.Source.ID), m.summary(mapping.Target.ID)
+	copied := *mapping
+	m.mappings[mapping.ID] = &copied
+	return nil
+}
+
+func (m *mockControlMappingRepo) Delete(ctx context.Context, id string) error {
+	if _, ok := m.mappings[id]; !ok {
+		return database.ErrControlMappingNotFound
+	}
+	delete(m.mappings, id)
+	return nil
+}
+
+func (m *mockControlMappingRepo) Mapped(ctx context.Context, controlID, frameworkID string) ([]*models.MappedControl, error) {
+	mapped := []*models.MappedControl{}
+	for _, mapping := range m.mappings {
+		other := mapping.Target
+		if mapping.Target.ID == controlID {
+			other = mapping.Source
+		} else if mapping.Source.ID != controlID {
+			continue
+		}
+		if frameworkID == "" || other.FrameworkID == frameworkID {
+			mapped = append(mapped, &models.MappedControl{Control: other, MappingID: mapping.ID, Strength: mapping.Strength})
+		}
+	}
+	return mapped, nil
+}
+
+func (m *mockControlMappingRepo) MappedForRisk(ctx context.Context, riskID string) ([]*models.MappedControl, error) {
+	return []*models.MappedControl{}, nil
+}
+
+func (m *mockControlMappingRepo) WithTx(tx *sql.Tx) database.ControlMappingRepository {
+	return m
+}
+
+type crosswalkFixture struct {
+	app      *fiber.App
+	mappings *mockControlMappingRepo
+	audit    *mockAuditRepo
+	iso      *models.Framework
+	soc2     *models.Framework
+	controls map[string]*models.FrameworkControl
+}
+
+func setupCrosswalkApp() *crosswalkFixture {
+	f := &crosswalkFixture{
+		iso:      &models.Framework{ID: uuid.New().String(), Name: "ISO 27001"},
+		soc2:     &models.Framework{ID: uuid.New().String(), Name: "SOC 2"},
+		controls: map[string]*models.FrameworkControl{},
+		audit:    &mockAuditRepo{logs: []*models.AuditLog{}},
+	}
+	controlRepo := &mockFrameworkControlRepo{controls: map[string]*models.FrameworkControl{}}
+	for _, c := range []struct {
+		framework *models.Framework
+		ref       string
+	}{{f.iso, "A.8.1"}, {f.iso, "A.8.2"}, {f.soc2, "CC6.1"}, {f.soc2, "CC6.2"}} {
+		control := &models.FrameworkControl{ID: uuid.New().String(), FrameworkID: c.framework.ID, ControlRef: c.ref, Title: c.ref}
+		controlRepo.controls[control.ID] = control
+		f.controls[c.ref] = control
+	}
+	frameworkRepo := &mockFrameworkRepo{frameworks: map[string]*models.Framework{f.iso.ID: f.iso, f.soc2.ID: f.soc2}}
+	f.mappings = &mockControlMappingRepo{mappings: map[string]*models.ControlMapping{}, controls: controlRepo}
+
+	handler := NewControlMappingHandler(&mockTransactor{}, f.mappings, frameworkRepo, controlRepo, f.audit)
+	f.app = fiber.New()
+	f.app.Post("/control-mappings", testAuthMiddleware, handler.Create)
+	f.app.Post("/control-mappings/import", testAuthMiddleware, handler.Import)
+	f.app.Put("/control-mappings/:id", handler.Update)
+	f.app.Delete("/control-mappings/:id", handler.Delete)
+	f.app.Get("/controls/:id/crosswalk", handler.Crosswalk)
+	return f
+}
+
+func TestControlMappingHandler_Create(t *testing.T) {
+	f := setupCrosswalkApp()
+	iso, soc2 := f.controls["A.8.1"].ID, f.controls["CC6.1"].ID
+
+	body := `{"source_control_id":"` + iso + `","target_control_id":"` + soc2 + `","strength":"subset"}`
+	status, result := sendTeamRequest(t, f.app, "POST", "/control-mappings", body)
+	require.Equal(t, 201, status, result)
+	assert.Equal(t, "subset", result["strength"])
+	assert.Equal(t, "CC6.1", result["target"].(map[string]any)["control_ref"])
+	id := result["id"].(string)
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name, body, field string
+		}{
+			{"missing strength", `{"source_control_id":"` + iso + `","target_control_id":"` + soc2 + `"}`, "strength"},
+			{"bad strength", `{"source_control_id":"` + iso + `","target_control_id":"` + soc2 + `","strength":"close"}`, "strength"},
+			{"same control", `{"source_control_id":"` + iso + `","target_control_id":"` + iso + `","strength":"equal"}`, "target_control_id"},
+			{"malformed control", `{"source_control_id":"nope","target_control_id":"` + soc2 + `","strength":"equal"}`, "source_control_id"},
+			{"unknown control", `{"source_control_id":"` + uuid.New().String() + `","target_control_id":"` + soc2 + `","strength":"equal"}`, "control_id"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, f.app, "POST", "/control-mappings", tt.body)
+				assert.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+
+	t.Run("pairs are mapped once either way round", func(t *testing.T) {
+		body := `{"source_control_id":"` + soc2 + `","target_control_id":"` + iso + `","strength":"equal"}`
+		status, _ := sendTeamRequest(t, f.app, "POST", "/control-mappings", body)
+		assert.Equal(t, 409, status)
+	})
+
+	t.Run("update and delete", func(t *testing.T) {
+		status, result := sendTeamRequest(t, f.app, "PUT", "/control-mappings/"+id, `{"strength":"equal","notes":"reviewed"}`)
+		require.Equal(t, 200, status, result)
+		assert.Equal(t, "equal", result["strength"])
+		assert.Equal(t, "reviewed", result["notes"])
+
+		status, _ = sendTeamRequest(t, f.app, "PUT", "/control-mappings/"+id, `{"strength":"close"}`)
+		assert.Equal(t, 400, status)
+		status, _ = sendTeamRequest(t, f.app, "PUT", "/control-mappings/"+id, `{}`)
+		assert.Equal(t, 400, status)
+
+		status, _ = sendTeamRequest(t, f.app, "DELETE", "/control-mappings/"+id, "")
+		assert.Equal(t, 204, status)
+		status, _ = sendTeamRequest(t, f.app, "DELETE", "/control-mappings/"+id, "")
+		assert.Equal(t, 404, status)
+	})
+}
+
+func TestControlMappingHandler_Crosswalk(t *testing.T) {
+	f := setupCrosswalkApp()
+	iso := f.controls["A.8.1"]
+	for _, ref := range []string{"CC6.1", "CC6.2"} {
+		body := `{"source_control_id":"` + iso.ID + `","target_control_id":"` + f.controls[ref].ID + `","strength":"equal"}`
+		status, _ := sendTeamRequest(t, f.app, "POST", "/control-mappings", body)
+		require.Equal(t, 201, status)
+	}
+
+	status, result := sendTeamRequest(t, f.app, "GET", "/controls/"+iso.ID+"/crosswalk?framework_id="+f.soc2.ID, "")
+	require.Equal(t, 200, status, result)
+	assert.Equal(t, "A.8.1", result["control"].(map[string]any)["control_ref"])
+	assert.Len(t, result["risks"], 0)
+	assert.Len(t, result["mapped"], 2)
+
+	status, result = sendTeamRequest(t, f.app, "GET", "/controls/"+iso.ID+"/crosswalk?framework_id="+f.iso.ID, "")
+	require.Equal(t, 200, status)
+	assert.Len(t, result["mapped"], 0)
+
+	status, _ = sendTeamRequest(t, f.app, "GET", "/controls/"+uuid.New().String()+"/crosswalk", "")
+	assert.Equal(t, 404, status)
+	status, _ = sendTeamRequest(t, f.app, "GET", "/controls/nope/crosswalk", "")
+	assert.Equal(t, 404, status)
+}
+
+func TestControlMappingHandler_Import(t *testing.T) {
+	upload := func(f *crosswalkFixture, content string, fields map[string]string) (int, *models.ControlMappingImportResult, string) {
+		body := new(bytes.Buffer)
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile("file", "crosswalk.csv")
+		part.Write([]byte(content))
+		for k, v := range fields {
+			w.WriteField(k, v)
+		}
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/control-mappings/import", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp, err := f.app.Test(req)
+		require.NoError(t, err)
+		raw := new(bytes.Buffer)
+		raw.ReadFrom(resp.Body)
+		var result models.ControlMappingImportResult
+		json.Unmarshal(raw.Bytes(), &result)
+		return resp.StatusCode, &result, raw.String()
+	}
+	frameworks := func(f *crosswalkFixture, commit bool) map[string]string {
+		fields := map[string]string{"source_framework": "iso 27001", "target_framework": f.soc2.ID}
+		if commit {
+			fields["commit"] = "true"
+		}
+		return fields
+	}
+	csvFile := "Source,Target,Relationship\n" +
+		"A.8.1,CC6.1,Equal\n" +
+		",,\n" +
+		"A.8.2,CC6.1,\n"
+
+	t.Run("preview and commit", func(t *testing.T) {
+		f := setupCrosswalkApp()
+		status, result, raw := upload(f, csvFile, frameworks(f, false))
+		require.Equal(t, 200, status, raw)
+		assert.False(t, result.Committed)
+		assert.Empty(t, f.mappings.mappings)
+		assert.Equal(t, 2, result.TotalRows)
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, models.MappingRelated, result.Rows[1].Strength, "blank strength defaults to related")
+
+		status, result, raw = upload(f, csvFile, frameworks(f, true))
+		require.Equal(t, 201, status, raw)
+		assert.True(t, result.Committed)
+		assert.Len(t, f.mappings.mappings, 2)
+		require.Len(t, f.audit.logs, 1)
+		assert.Equal(t, "control_mapping_import", f.audit.logs[0].EntityType)
+
+		status, result, raw = upload(f, csvFile, frameworks(f, true))
+		require.Equal(t, 201, status, raw)
+		assert.Equal(t, 2, result.Unchanged, "re-importing the same file changes nothing")
+
+		reversed := "source_framework,source_ref,target_framework,target_ref,strength,notes\n" +
+			"SOC 2,CC6.1,ISO 27001,A.8.1,subset,narrower\n"
+		status, result, raw = upload(f, reversed, map[string]string{"commit": "true"})
+		require.Equal(t, 201, status, raw)
+		assert.Equal(t, 1, result.Updated)
+		updated := f.mappings.mappings[result.Rows[0].MappingID]
+		assert.Equal(t, f.controls["CC6.1"].ID, updated.Source.ID)
+		assert.Equal(t, models.MappingSubset, updated.Strength)
+		assert.Equal(t, "narrower", updated.Notes)
+	})
+
+	t.Run("rows with errors block the commit", func(t *testing.T) {
+		f := setupCrosswalkApp()
+		file := "source,target,strength\n" +
+			"A.8.1,CC9.9,equal\n" +
+			"A.8.1,CC6.1,close\n" +
+			"A.8.2,CC6.2,equal\n" +
+			"A.8.2,CC6.2,related\n"
+		status, result, raw := upload(f, file, frameworks(f, true))
+		require.Equal(t, 422, status, raw)
+		assert.Empty(t, f.mappings.mappings)
+		assert.Equal(t, 3, result.InvalidRows)
+		assert.Contains(t, result.Rows[0].Errors["target_ref"], "SOC 2")
+		assert.Contains(t, result.Rows[1].Errors, "strength")
+		assert.Contains(t, result.Rows[3].Errors["target_ref"], "row 4")
+	})
+
+	t.Run("frameworks must be given", func(t *testing.T) {
+		f := setupCrosswalkApp()
+		status, _, raw := upload(f, "source,target\nA.8.1,CC6.1\n", map[string]string{"source_framework": "ISO 27001"})
+		assert.Equal(t, 400, status)
+		assert.Contains(t, raw, "target_framework")
+
+		status, result, raw := upload(f, "source,target\nA.8.1,CC6.1\n", map[string]string{"source_framework": "ISO 9001", "target_framework": "SOC 2"})
+		require.Equal(t, 200, status, raw)
+		assert.Equal(t, "framework not found", result.Rows[0].Errors["source_framework"])
+	})
+}
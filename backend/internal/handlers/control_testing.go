@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Control tests check on a schedule that a framework control works. Each
+// result moves the test's due date on by its frequency; an ineffective
+// result also brings the review date of every risk linked to the control
+// forward to today, so their owners are told to look at them again.
+
+var (
+	recurrences = []string{
+		string(models.RecurrenceMonthly), string(models.RecurrenceQuarterly),
+		string(models.RecurrenceSemiannual), string(models.RecurrenceAnnual),
+	}
+	controlTestResults = []string{
+		string(models.ControlEffective), string(models.ControlPartiallyEffective), string(models.ControlIneffective),
+	}
+)
+
+type ControlTestHandler struct {
+	tx    database.Transactor
+	tests database.ControlTestRepository
+	audit database.AuditLogRepository
+}
+
+func NewControlTestHandler(tx database.Transactor, tests database.ControlTestRepository, audit database.AuditLogRepository) *ControlTestHandler {
+	return &ControlTestHandler{tx: tx, tests: tests, audit: audit}
+}
+
+// List returns control tests, filtered by control_id, tester_id and
+// overdue=true
+func (h *ControlTestHandler) List(c *fiber.Ctx) error {
+	filter := models.ControlTestFilter{
+		ControlID: c.Query("control_id"),
+		TesterID:  c.Query("tester_id"),
+		Overdue:   c.QueryBool("overdue"),
+	}
+	errs := fieldErrors{}
+	if filter.ControlID != "" {
+		optionalUUID(errs, "control_id", &filter.ControlID)
+	}
+	if filter.TesterID != "" {
+		optionalUUID(errs, "tester_id", &filter.TesterID)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	return h.list(c, filter)
+}
+
+// Overdue returns the active tests past their due date, for the dashboard
+func (h *ControlTestHandler) Overdue(c *fiber.Ctx) error {
+	return h.list(c, models.ControlTestFilter{Overdue: true})
+}
+
+// ListForControl returns the tests of the :id control
+func (h *ControlTestHandler) ListForControl(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return controlTestError(c, database.ErrFrameworkControlNotFound, ErrFailedToFetch)
+	}
+	return h.list(c, models.ControlTestFilter{ControlID: id})
+}
+
+func (h *ControlTestHandler) list(c *fiber.Ctx, filter models.ControlTestFilter) error {
+	tests, err := h.tests.List(c.Context(), filter)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": tests})
+}
+
+func (h *ControlTestHandler) Get(c *fiber.Ctx) error {
+	test, err := h.load(c)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(test)
+}
+
+// Create schedules a test of a control. Without next_due_date the first
+// run is due one period from today.
+func (h *ControlTestHandler) Create(c *fiber.Ctx) error {
+	var input models.CreateControlTestInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.TesterID != nil && *input.TesterID == "" {
+		input.TesterID = nil
+	}
+
+	errs := fieldErrors{}
+	requireUUID(errs, "control_id", &input.ControlID)
+	requireText(errs, "name", &input.Name, 255)
+	requireOneOf(errs, "frequency", (*string)(&input.Frequency), recurrences...)
+	optionalUUID(errs, "tester_id", input.TesterID)
+	due := optionalDate(errs, "next_due_date", input.NextDueDate)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if due == nil {
+		next := input.Frequency.Next(todayUTC())
+		due = &next
+	}
+
+	user := middleware.GetUserFromContext(c)
+	test := &models.ControlTest{
+		ControlID:   input.ControlID,
+		Name:        strings.TrimSpace(input.Name),
+		Procedure:   input.Procedure,
+		Frequency:   input.Frequency,
+		TesterID:    input.TesterID,
+		NextDueDate: *due,
+		Active:      true,
+		CreatedBy:   &user.UserID,
+	}
+	if err := h.tests.Create(c.Context(), test); err != nil {
+		if errors.Is(err, database.ErrFrameworkControlNotFound) {
+			return validationFailed(c, fieldErrors{"control_id": "control not found"})
+		}
+		return controlTestError(c, err, ErrFailedToCreate)
+	}
+	created, err := h.tests.GetByID(c.Context(), test.ID)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+
+	h.audit.Create(c.Context(), "control_test", created.ID, models.AuditActionCreated, controlTestAuditSnapshot(created), user.UserID)
+	return c.Status(201).JSON(created)
+}
+
+// Update changes the fields that are set. An empty tester_id unassigns the
+// test.
+func (h *ControlTestHandler) Update(c *fiber.Ctx) error {
+	current, err := h.load(c)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+
+	var input models.UpdateControlTestInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Name == nil && input.Procedure == nil && input.Frequency == nil && input.TesterID == nil &&
+		input.NextDueDate == nil && input.Active == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	errs := fieldErrors{}
+	updated := *current
+	if input.Name != nil && requireText(errs, "name", input.Name, 255) {
+		updated.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Procedure != nil {
+		updated.Procedure = *input.Procedure
+	}
+	if input.Frequency != nil && requireOneOf(errs, "frequency", (*string)(input.Frequency), recurrences...) {
+		updated.Frequency = *input.Frequency
+	}
+	if input.TesterID != nil {
+		if *input.TesterID == "" {
+			updated.TesterID = nil
+		} else if optionalUUID(errs, "tester_id", input.TesterID) {
+			updated.TesterID = input.TesterID
+		}
+	}
+	if input.NextDueDate != nil {
+		if due := optionalDate(errs, "next_due_date", input.NextDueDate); due != nil {
+			updated.NextDueDate = *due
+		}
+	}
+	if input.Active != nil {
+		updated.Active = *input.Active
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	if err := h.tests.Update(c.Context(), &updated); err != nil {
+		return controlTestError(c, err, ErrFailedToUpdate)
+	}
+	after, err := h.tests.GetByID(c.Context(), current.ID)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	if changes := controlTestAuditChanges(current, after); len(changes) > 0 {
+		h.audit.Create(c.Context(), "control_test", current.ID, models.AuditActionUpdated, changes, user.UserID)
+	}
+	return c.JSON(after)
+}
+
+// Delete removes a test along with its results
+func (h *ControlTestHandler) Delete(c *fiber.Ctx) error {
+	test, err := h.load(c)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+	if err := h.tests.Delete(c.Context(), test.ID); err != nil {
+		return controlTestError(c, err, ErrFailedToDelete)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	h.audit.Create(c.Context(), "control_test", test.ID, models.AuditActionDeleted, controlTestAuditSnapshot(test), user.UserID)
+	return c.SendStatus(204)
+}
+
+// ListResults returns the result history of the :id test, most recent first
+func (h *ControlTestHandler) ListResults(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return controlTestError(c, database.ErrControlTestNotFound, ErrFailedToFetch)
+	}
+	results, err := h.tests.ListResults(c.Context(), id)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": results})
+}
+
+// RecordResult records a run of the :id test. Only its tester or an admin
+// may do so. An ineffective result flags the control's linked risks for
+// review; each flagged risk gets its own audit entry, which is what tells
+// its owner.
+func (h *ControlTestHandler) RecordResult(c *fiber.Ctx) error {
+	test, err := h.load(c)
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToFetch)
+	}
+	user := middleware.GetUserFromContext(c)
+	if user.Role != string(models.RoleAdmin) && (test.TesterID == nil || *test.TesterID != user.UserID) {
+		return c.Status(403).JSON(fiber.Map{"error": "only the assigned tester or an admin can record results"})
+	}
+
+	var input models.RecordControlTestResultInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	errs := fieldErrors{}
+	requireOneOf(errs, "result", (*string)(&input.Result), controlTestResults...)
+	testedAt := optionalDate(errs, "tested_at", input.TestedAt)
+	today := todayUTC()
+	if testedAt == nil {
+		testedAt = &today
+	} else if testedAt.After(today) {
+		errs["tested_at"] = "cannot be in the future"
+	}
+	if input.Result == models.ControlIneffective && strings.TrimSpace(input.Notes) == "" {
+		errs["notes"] = "are required for an ineffective result"
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	result := &models.ControlTestResult{
+		ControlTestID: test.ID,
+		Result:        input.Result,
+		Notes:         strings.TrimSpace(input.Notes),
+		TestedAt:      *testedAt,
+		TestedBy:      &user.UserID,
+	}
+	flagged := []models.FlaggedRisk{}
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		tests, audit := h.tests.WithTx(tx), h.audit.WithTx(tx)
+		if result.Result == models.ControlIneffective {
+			risks, err := tests.FlagRisksForReview(c.Context(), test.ControlID, user.UserID)
+			if err != nil {
+				return err
+			}
+			flagged = risks
+			for _, risk := range flagged {
+				err := audit.Create(c.Context(), "risk", risk.ID, models.AuditActionUpdated, map[string]any{
+					"review_date": auditChange(auditDate(risk.PreviousReviewDate), auditDate(&risk.ReviewDate)),
+				}, user.UserID)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		result.FlaggedRisks = len(flagged)
+
+		if err := tests.RecordResult(c.Context(), result, test.Frequency.Next(result.TestedAt)); err != nil {
+			return err
+		}
+		return audit.Create(c.Context(), "control_test", test.ID, models.AuditActionUpdated, map[string]any{
+			"result":        string(result.Result),
+			"tested_at":     auditDate(&result.TestedAt),
+			"flagged_risks": result.FlaggedRisks,
+		}, user.UserID)
+	})
+	if err != nil {
+		return controlTestError(c, err, ErrFailedToCreate)
+	}
+
+	return c.Status(201).JSON(fiber.Map{"result": result, "flagged_risks": flagged})
+}
+
+// load fetches the :id test
+func (h *ControlTestHandler) load(c *fiber.Ctx) (*models.ControlTest, error) {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return nil, database.ErrControlTestNotFound
+	}
+	return h.tests.GetByID(c.Context(), id)
+}
+
+// todayUTC is the current UTC calendar date
+func todayUTC() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// controlTestAuditSnapshot returns the state recorded when a test is
+// created or deleted
+func controlTestAuditSnapshot(test *models.ControlTest) map[string]any {
+	return map[string]any{
+		"control_id":    test.ControlID,
+		"name":          test.Name,
+		"procedure":     test.Procedure,
+		"frequency":     string(test.Frequency),
+		"tester_id":     auditString(test.TesterID),
+		"next_due_date": auditDate(&test.NextDueDate),
+		"active":        test.Active,
+	}
+}
+
+// controlTestAuditChanges returns from/to pairs for the fields that differ
+// between two versions of a test
+func controlTestAuditChanges(before, after *models.ControlTest) map[string]any {
+	changes := make(map[string]any)
+	from, to := controlTestAuditSnapshot(before), controlTestAuditSnapshot(after)
+	for field, value := range to {
+		if from[field] != value {
+			changes[field] = auditChange(from[field], value)
+		}
+	}
+	return changes
+}
+
+// controlTestError maps repository errors to responses, using failed for
+// anything unexpected
+func controlTestError(c *fiber.Ctx, err error, failed string) error {
+	switch {
+	case errors.Is(err, database.ErrControlTestNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "control test")})
+	case errors.Is(err, database.ErrFrameworkControlNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "control")})
+	case errors.Is(err, database.ErrControlTestTesterNotFound):
+		return validationFailed(c, fieldErrors{"tester_id": "user not found"})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(failed, "control test")})
+}
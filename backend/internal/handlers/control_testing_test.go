@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockControlTestRepo keeps tests and results in memory. linkedRisks are the
+// review dates of the risks linked to each control.
+type mockControlTestRepo struct {
+	tests       []*models.ControlTest
+	results     []*models.ControlTestResult
+	linkedRisks map[string]map[string]*time.Time
+}
+
+func (m *mockControlTestRepo) List(ctx context.Context, filter models.ControlTestFilter) ([]*models.ControlTest, error) {
+	tests := []*models.ControlTest{}
+	for _, test := range m.tests {
+		if filter.ControlID != "" && test.ControlID != filter.ControlID {
+			continue
+		}
+		if filter.Overdue && !test.Overdue {
+			continue
+		}
+		tests = append(tests, test)
+	}
+	return tests, nil
+}
+
+func (m *mockControlTestRepo) GetByID(ctx context.Context, id string) (*models.ControlTest, error) {
+	for _, test := range m.tests {
+		if test.ID == id {
+			copied := *test
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrControlTestNotFound
+}
+
+func (m *mockControlTestRepo) Create(ctx context.Context, test *models.ControlTest) error {
+	if _, ok := m.linkedRisks[test.ControlID]; !ok {
+		return database.ErrFrameworkControlNotFound
+	}
+	test.ID = uuid.New().String()
+	stored := *test
+	m.tests = append(m.tests, &stored)
+	return nil
+}
+
+func (m *mockControlTestRepo) Update(ctx context.Context, test *models.ControlTest) error {
+	for i, stored := range m.tests {
+		if stored.ID == test.ID {
+			updated := *test
+			m.tests[i] = &updated
+			return nil
+		}
+	}
+	return database.ErrControlTestNotFound
+}
+
+func (m *mockControlTestRepo) Delete(ctx context.Context, id string) error {
+	for i, test := range m.tests {
+		if test.ID == id {
+			m.tests = append(m.tests[:i], m.tests[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrControlTestNotFound
+}
+
+func (m *mockControlTestRepo) ListResults(ctx context.Context, testID string) ([]*models.ControlTestResult, error) {
+	results := []*models.ControlTestResult{}
+	for _, result := range m.results {
+		if result.ControlTestID == testID {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+func (m *mockControlTestRepo) RecordResult(ctx context.Context, result *models.ControlTestResult, next time.Time) error {
+	result.ID = uuid.New().String()
+	m.results = append(m.results, result)
+	for _, test := range m.tests {
+		if test.ID == result.ControlTestID {
+			test.NextDueDate = next
+			test.LastResult = result
+		}
+	}
+	return nil
+}
+
+func (m *mockControlTestRepo) FlagRisksForReview(ctx context.Context, controlID, userID string) ([]models.FlaggedRisk, error) {
+	today := todayUTC()
+	flagged := []models.FlaggedRisk{}
+	for id, reviewDate := range m.linkedRisks[controlID] {
+		if reviewDate != nil && !reviewDate.After(today) {
+			continue
+		}
+		flagged = append(flagged, models.FlaggedRisk{ID: id, PreviousReviewDate: reviewDate, ReviewDate: today})
+		m.linkedRisks[controlID][id] = &today
+	}
+	return flagged, nil
+}
+
+func (m *mockControlTestRepo) WithTx(tx *sql.Tx) database.ControlTestRepository {
+	return m
+}
+
+func setupControlTestApp(role string) (*fiber.App, *mockControlTestRepo, *mockAuditRepo) {
+	repo := &mockControlTestRepo{linkedRisks: map[string]map[string]*time.Time{}}
+	audit := &mockAuditRepo{}
+	handler := NewControlTestHandler(&mockTransactor{}, repo, audit)
+
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserKey, &middleware.UserClaims{UserID: "test-user-id", Role: role})
+		return c.Next()
+	}
+	app := fiber.New()
+	app.Get("/control-tests", auth, handler.List)
+	app.Post("/control-tests", auth, handler.Create)
+	app.Put("/control-tests/:id", auth, handler.Update)
+	app.Post("/control-tests/:id/results", auth, handler.RecordResult)
+	app.Get("/control-tests/:id/results", auth, handler.ListResults)
+	return app, repo, audit
+}
+
+func TestControlTestHandler_Create(t *testing.T) {
+	app, repo, audit := setupControlTestApp("admin")
+	control := uuid.New().String()
+	repo.linkedRisks[control] = map[string]*time.Time{}
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name, body, field string
+		}{
+			{"missing control", `{"name":"Access review","frequency":"quarterly"}`, "control_id"},
+			{"missing name", `{"control_id":"` + control + `","frequency":"quarterly"}`, "name"},
+			{"unknown frequency", `{"control_id":"` + control + `","name":"Access review","frequency":"weekly"}`, "frequency"},
+			{"bad due date", `{"control_id":"` + control + `","name":"Access review","frequency":"annual","next_due_date":"soon"}`, "next_due_date"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, app, "POST", "/control-tests", tt.body)
+				require.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+
+	status, body := sendTeamRequest(t, app, "POST", "/control-tests", `{"control_id":"`+uuid.New().String()+`","name":"Access review","frequency":"quarterly"}`)
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "control_id")
+
+	status, body = sendTeamRequest(t, app, "POST", "/control-tests", `{"control_id":"`+control+`","name":"Access review","frequency":"quarterly"}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, models.RecurrenceQuarterly.Next(todayUTC()).Format(time.RFC3339), body["next_due_date"], "first run is due a period from today")
+	require.Len(t, audit.logs, 1)
+	assert.Equal(t, "control_test", audit.logs[0].EntityType)
+
+	status, body = sendTeamRequest(t, app, "PUT", "/control-tests/"+body["id"].(string), `{"frequency":"annual","tester_id":"test-user-id"}`)
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "tester_id")
+}
+
+func TestControlTestHandler_RecordResult(t *testing.T) {
+	control := uuid.New().String()
+	tester := "tester-id"
+	future := time.Now().AddDate(0, 2, 0)
+	past := time.Now().AddDate(0, -1, 0)
+	newApp := func(role string) (*fiber.App, *mockControlTestRepo, *mockAuditRepo, string) {
+		app, repo, audit := setupControlTestApp(role)
+		repo.linkedRisks[control] = map[string]*time.Time{"risk-later": &future, "risk-due": &past, "risk-unscheduled": nil}
+		test := &models.ControlTest{ID: uuid.New().String(), ControlID: control, Name: "Access review",
+			Frequency: models.RecurrenceMonthly, TesterID: &tester, Active: true}
+		repo.tests = append(repo.tests, test)
+		return app, repo, audit, "/control-tests/" + test.ID + "/results"
+	}
+
+	t.Run("only the tester or an admin", func(t *testing.T) {
+		app, _, _, path := newApp("member")
+		status, _ := sendTeamRequest(t, app, "POST", path, `{"result":"effective"}`)
+		assert.Equal(t, 403, status)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		app, _, _, path := newApp("admin")
+		tests := []struct {
+			name, body, field string
+		}{
+			{"missing result", `{}`, "result"},
+			{"unknown result", `{"result":"great"}`, "result"},
+			{"future date", `{"result":"effective","tested_at":"` + future.Format("2006-01-02") + `"}`, "tested_at"},
+			{"ineffective without notes", `{"result":"ineffective"}`, "notes"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, app, "POST", path, tt.body)
+				require.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+
+	t.Run("effective result moves the schedule on", func(t *testing.T) {
+		app, repo, audit, path := newApp("admin")
+		status, body := sendTeamRequest(t, app, "POST", path, `{"result":"effective","tested_at":"2026-01-31"}`)
+		require.Equal(t, 201, status, body)
+		assert.Equal(t, "2026-02-28", repo.tests[0].NextDueDate.Format("2006-01-02"))
+		assert.Empty(t, body["flagged_risks"])
+		require.Len(t, audit.logs, 1)
+		assert.Equal(t, "control_test", audit.logs[0].EntityType)
+	})
+
+	t.Run("ineffective result flags linked risks", func(t *testing.T) {
+		app, repo, audit, path := newApp("admin")
+		status, body := sendTeamRequest(t, app, "POST", path, `{"result":"ineffective","notes":"Leavers kept access"}`)
+		require.Equal(t, 201, status, body)
+		assert.Len(t, body["flagged_risks"], 2, "risks already due are left alone")
+		assert.Equal(t, float64(2), body["result"].(map[string]any)["flagged_risks"])
+
+		today := todayUTC().Format("2006-01-02")
+		riskLogs := map[string]map[string]any{}
+		for _, log := range audit.logs {
+			if log.EntityType == "risk" {
+				riskLogs[log.EntityID] = log.Changes
+			}
+		}
+		assert.Equal(t, map[string]any{"review_date": auditChange(future.Format("2006-01-02"), today)}, riskLogs["risk-later"])
+		assert.Equal(t, map[string]any{"review_date": auditChange(nil, today)}, riskLogs["risk-unscheduled"])
+		assert.NotContains(t, riskLogs, "risk-due")
+
+		results, _ := repo.ListResults(context.Background(), repo.tests[0].ID)
+		assert.Len(t, results, 1)
+	})
+
+	t.Run("assigned tester", func(t *testing.T) {
+		app, repo, _, path := newApp("member")
+		assigned := "test-user-id"
+		repo.tests[0].TesterID = &assigned
+		status, _ := sendTeamRequest(t, app, "POST", path, `{"result":"partially_effective","notes":"Two stale accounts"}`)
+		assert.Equal(t, 201, status)
+	})
+}
+
+func TestControlTestHandler_ListOverdue(t *testing.T) {
+	app, repo, _ := setupControlTestApp("member")
+	repo.tests = []*models.ControlTest{
+		{ID: uuid.New().String(), Name: "Backups restore", Active: true, Overdue: true},
+		{ID: uuid.New().String(), Name: "Access review", Active: true},
+	}
+
+	status, body := sendTeamRequest(t, app, "GET", "/control-tests?overdue=true", "")
+	require.Equal(t, 200, status)
+	require.Len(t, body["data"], 1)
+	assert.Equal(t, "Backups restore", body["data"].([]any)[0].(map[string]any)["name"])
+
+	status, _ = sendTeamRequest(t, app, "GET", "/control-tests?control_id=nope", "")
+	assert.Equal(t, 400, status)
+}
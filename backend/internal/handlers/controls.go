@@ -1,22 +1,29 @@
 package handlers
 
 import (
+	"bytes"
+	"database/sql"
 	"errors"
+	"fmt"
 
 	"backend/internal/database"
 	"backend/internal/middleware"
 	"backend/internal/models"
+	"backend/internal/reports"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type FrameworkControlHandler struct {
+	tx       database.Transactor
 	controls database.FrameworkControlRepository
+	cleanup  *AttachmentCleanup
 }
 
-func NewFrameworkControlHandler(controls database.FrameworkControlRepository) *FrameworkControlHandler {
-	return &FrameworkControlHandler{controls: controls}
+func NewFrameworkControlHandler(tx database.Transactor, controls database.FrameworkControlRepository, cleanup *AttachmentCleanup) *FrameworkControlHandler {
+	return &FrameworkControlHandler{tx: tx, controls: controls, cleanup: cleanup}
 }
 
 func (h *FrameworkControlHandler) List(c *fiber.Ctx) error {
@@ -46,9 +53,15 @@ func (h *FrameworkControlHandler) Create(c *fiber.Ctx) error {
 	if input.Title == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "title is required"})
 	}
+	if input.ParentID != nil && *input.ParentID == "" {
+		input.ParentID = nil
+	}
 
 	control, err := h.controls.Create(c.Context(), &input)
 	if err != nil {
+		if errors.Is(err, database.ErrFrameworkControlParent) {
+			return validationFailed(c, fieldErrors{"parent_id": err.Error()})
+		}
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == "23505" {
@@ -89,7 +102,7 @@ func (h *FrameworkControlHandler) Update(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if input.ControlRef == nil && input.Title == nil && input.Description == nil {
+	if input.ControlRef == nil && input.Title == nil && input.Description == nil && input.ParentID == nil && input.SortOrder == nil {
 		return c.Status(400).JSON(fiber.Map{"error": "at least one field must be provided"})
 	}
 	if input.ControlRef != nil && *input.ControlRef == "" {
@@ -99,24 +112,161 @@ func (h *FrameworkControlHandler) Update(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "title cannot be empty"})
 	}
 
-	control, err := h.controls.Update(c.Context(), id, &input)
+	control, err := h.update(c, id, &input)
 	if err != nil {
-		if errors.Is(err, database.ErrFrameworkControlNotFound) {
-			return c.Status(404).JSON(fiber.Map{"error": "control not found"})
+		return mapFrameworkControlError(c, err, "failed to update control")
+	}
+
+	return c.JSON(control)
+}
+
+// Patch applies a JSON merge patch to a control. Setting description to null
+// clears it and setting parent_id to null moves the control to the top of the
+// tree; control_ref, title and sort_order cannot be cleared.
+func (h *FrameworkControlHandler) Patch(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "control id required"})
+	}
+
+	patch, err := parseMergePatch(c)
+	if err != nil {
+		return patchError(c, err)
+	}
+
+	errs := fieldErrors{}
+	patch.allow(errs, "control_ref", "title", "description", "parent_id", "sort_order")
+	var input models.UpdateFrameworkControlInput
+
+	if v, ok := patch.string("control_ref", errs); ok && requireText(errs, "control_ref", v, 0) {
+		input.ControlRef = v
+	}
+	if v, ok := patch.string("title", errs); ok && requireText(errs, "title", v, 0) {
+		input.Title = v
+	}
+	if v, ok := patch.string("description", errs); ok {
+		description := textOrEmpty(v)
+		input.Description = &description
+	}
+	if v, ok := patch.string("parent_id", errs); ok && optionalUUID(errs, "parent_id", v) {
+		parentID := textOrEmpty(v)
+		input.ParentID = &parentID
+	}
+	if v, ok := patch.integer("sort_order", errs); ok {
+		if v == nil {
+			errs["sort_order"] = "cannot be null"
 		}
-		return c.Status(500).JSON(fiber.Map{"error": "failed to update control"})
+		input.SortOrder = v
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
 	}
 
+	var control *models.FrameworkControl
+	if input.ControlRef == nil && input.Title == nil && input.Description == nil && input.ParentID == nil && input.SortOrder == nil {
+		control, err = h.controls.GetByID(c.Context(), id)
+	} else {
+		control, err = h.update(c, id, &input)
+	}
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return c.Status(409).JSON(fiber.Map{"error": "Control reference already exists for this framework"})
+		}
+		return mapFrameworkControlError(c, err, "failed to update control")
+	}
 	return c.JSON(control)
 }
 
+// update runs the repository update in a transaction, which holds the lock
+// taken to re-parent the control until the new parent is written
+func (h *FrameworkControlHandler) update(c *fiber.Ctx, id string, input *models.UpdateFrameworkControlInput) (*models.FrameworkControl, error) {
+	var control *models.FrameworkControl
+	err := h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		var err error
+		control, err = h.controls.WithTx(tx).Update(c.Context(), id, input)
+		return err
+	})
+	return control, err
+}
+
+// Tree returns a framework's controls nested under their parents, with
+// linked risks rolled up so that coverage can be read per domain
+func (h *FrameworkControlHandler) Tree(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "framework")})
+	}
+
+	tree, err := h.controls.Tree(c.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrFrameworkNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "framework")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch controls"})
+	}
+	return c.JSON(fiber.Map{"data": tree})
+}
+
+// Coverage reports how many of a framework's controls have linked risks,
+// overall and per family, and lists the controls that have none or only
+// resolved and accepted ones
+func (h *FrameworkControlHandler) Coverage(c *fiber.Ctx) error {
+	coverage, err := h.loadCoverage(c)
+	if err != nil {
+		return coverageError(c, err)
+	}
+	return c.JSON(coverage)
+}
+
+// CoverageExport downloads the coverage report as CSV (format=csv, the
+// default) or XLSX with one row per control
+func (h *FrameworkControlHandler) CoverageExport(c *fiber.Ctx) error {
+	format := c.Query("format", "csv")
+	contentType, ok := reports.ExportContentType(format)
+	if !ok || format == "json" {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be one of csv, xlsx"})
+	}
+	coverage, err := h.loadCoverage(c)
+	if err != nil {
+		return coverageError(c, err)
+	}
+
+	var buf bytes.Buffer
+	if err := reports.WriteCoverageExport(&buf, format, coverage); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to export coverage"})
+	}
+	c.Attachment(reports.CoverageFilename(coverage.FrameworkName, coverage.GeneratedAt, format))
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(buf.Bytes())
+}
+
+func (h *FrameworkControlHandler) loadCoverage(c *fiber.Ctx) (*models.FrameworkCoverage, error) {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return nil, database.ErrFrameworkNotFound
+	}
+	return h.controls.Coverage(c.Context(), id)
+}
+
+func coverageError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, database.ErrFrameworkNotFound) {
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "framework")})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "coverage")})
+}
+
+// Delete removes a control; its children move up to the top of the tree
 func (h *FrameworkControlHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "control id required"})
 	}
 
-	if err := h.controls.Delete(c.Context(), id); err != nil {
+	err := h.cleanup.Delete(c.Context(), func(tx *sql.Tx) error {
+		return h.controls.WithTx(tx).Delete(c.Context(), id)
+	}, models.AttachmentFrameworkControl)
+	if err != nil {
 		return mapFrameworkControlError(c, err, "failed to delete control")
 	}
 
@@ -3,7 +3,9 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"backend/internal/models"
@@ -15,12 +17,16 @@ import (
 func TestFrameworkControlHandler(t *testing.T) {
 	app := fiber.New()
 	repo := &mockFrameworkControlRepo{controls: make(map[string]*models.FrameworkControl)}
-	handler := NewFrameworkControlHandler(repo)
+	handler := NewFrameworkControlHandler(&mockTransactor{}, repo, newTestAttachmentCleanup())
 
 	app.Get("/controls", testAuthMiddleware, handler.List)
 	app.Post("/controls", testAuthMiddleware, handler.Create)
 	app.Put("/controls/:id", testAuthMiddleware, handler.Update)
+	app.Patch("/controls/:id", testAuthMiddleware, handler.Patch)
 	app.Delete("/controls/:id", testAuthMiddleware, handler.Delete)
+	app.Get("/frameworks/:id/tree", testAuthMiddleware, handler.Tree)
+	app.Get("/frameworks/:id/coverage", testAuthMiddleware, handler.Coverage)
+	app.Get("/frameworks/:id/coverage/export", testAuthMiddleware, handler.CoverageExport)
 
 	frameworkID := uuid.New().String()
 
@@ -68,4 +74,122 @@ func TestFrameworkControlHandler(t *testing.T) {
 			t.Fatalf("expected 1 control, got %d", len(response["data"]))
 		}
 	})
+	t.Run("Move Control Under Parent", func(t *testing.T) {
+		parent := &models.FrameworkControl{ID: uuid.New().String(), FrameworkID: frameworkID, ControlRef: "A.12", Title: "Operations security"}
+		repo.controls[parent.ID] = parent
+		var child *models.FrameworkControl
+		for _, control := range repo.controls {
+			if control.ControlRef == "A.12.1.1" {
+				child = control
+			}
+		}
+
+		patch := func(body string) int {
+			req := httptest.NewRequest("PATCH", "/controls/"+child.ID, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			return resp.StatusCode
+		}
+
+		if status := patch(`{"parent_id":"` + parent.ID + `","sort_order":2}`); status != 200 {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if child.ParentID == nil || *child.ParentID != parent.ID || child.SortOrder != 2 {
+			t.Fatalf("expected the control under %s at position 2, got %+v", parent.ID, child)
+		}
+
+		req := httptest.NewRequest("GET", "/frameworks/"+frameworkID+"/tree", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var response map[string][]*models.ControlTreeNode
+		json.NewDecoder(resp.Body).Decode(&response)
+		if len(response["data"]) != 1 || len(response["data"][0].Children) != 1 {
+			t.Fatalf("expected one root with one child, got %+v", response["data"])
+		}
+
+		if status := patch(`{"parent_id":"` + child.ID + `"}`); status != 400 {
+			t.Errorf("expected status 400 for a control under itself, got %d", status)
+		}
+		if status := patch(`{"sort_order":null}`); status != 400 {
+			t.Errorf("expected status 400 for a null sort_order, got %d", status)
+		}
+		if status := patch(`{"parent_id":null}`); status != 200 || child.ParentID != nil {
+			t.Errorf("expected the control back at the top, got %d %+v", status, child)
+		}
+	})
+
+	t.Run("Tree Of Unknown Framework", func(t *testing.T) {
+		for _, id := range []string{uuid.New().String(), "nope"} {
+			resp, err := app.Test(httptest.NewRequest("GET", "/frameworks/"+id+"/tree", nil))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != 404 {
+				t.Errorf("expected status 404 for %s, got %d", id, resp.StatusCode)
+			}
+		}
+	})
+
+	t.Run("Coverage", func(t *testing.T) {
+		for _, control := range repo.controls {
+			control.LinkedRiskCount = 0
+		}
+		covered := &models.FrameworkControl{ID: uuid.New().String(), FrameworkID: frameworkID, ControlRef: "A.5.1", Title: "Policies", LinkedRiskCount: 2}
+		repo.controls[covered.ID] = covered
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/frameworks/"+frameworkID+"/coverage", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		var coverage models.FrameworkCoverage
+		json.NewDecoder(resp.Body).Decode(&coverage)
+		if resp.StatusCode != 200 || coverage.LinkedControls != 1 || len(coverage.Unlinked) != coverage.TotalControls-1 {
+			t.Fatalf("expected one covered control, got %d %+v", resp.StatusCode, coverage)
+		}
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/frameworks/"+frameworkID+"/coverage/export", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+			t.Fatalf("expected a CSV file, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		if !strings.Contains(resp.Header.Get("Content-Disposition"), "coverage-mock-") {
+			t.Errorf("expected a coverage filename, got %s", resp.Header.Get("Content-Disposition"))
+		}
+		if !strings.HasPrefix(string(body), "family,control_ref,title,status") || !strings.Contains(string(body), "A.5.1,Policies,covered,2,2") {
+			t.Errorf("unexpected export:\n%s", body)
+		}
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/frameworks/"+frameworkID+"/coverage/export?format=xlsx", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		if resp.StatusCode != 200 || !bytes.HasPrefix(body, []byte("PK")) {
+			t.Errorf("expected an XLSX file, got %d", resp.StatusCode)
+		}
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/frameworks/"+frameworkID+"/coverage/export?format=json", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 400 {
+			t.Errorf("expected status 400 for json, got %d", resp.StatusCode)
+		}
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/frameworks/nope/coverage", nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 404 {
+			t.Errorf("expected status 404, got %d", resp.StatusCode)
+		}
+	})
 }
@@ -53,3 +53,20 @@ func (h *DashboardHandler) OverdueReviews(c *fiber.Ctx) error {
 
 	return c.JSON(response)
 }
+
+// TopRisks returns the most severe unresolved risks (default: 10, max: 50)
+func (h *DashboardHandler) TopRisks(c *fiber.Ctx) error {
+	limit := 10
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsedLimit, err := strconv.Atoi(limitParam); err == nil && parsedLimit > 0 && parsedLimit <= 50 {
+			limit = parsedLimit
+		}
+	}
+
+	response, err := h.repo.GetTopRisks(c.Context(), limit)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch top risks"})
+	}
+
+	return c.JSON(response)
+}
@@ -15,6 +15,7 @@ type mockDashboardRepo struct {
 	summary  *models.DashboardSummaryResponse
 	upcoming *models.ReviewListResponse
 	overdue  *models.ReviewListResponse
+	top      *models.TopRiskListResponse
 }
 
 func (m *mockDashboardRepo) GetSummary(ctx context.Context) (*models.DashboardSummaryResponse, error) {
@@ -29,6 +30,17 @@ func (m *mockDashboardRepo) GetOverdueReviews(ctx context.Context) (*models.Revi
 	return m.overdue, nil
 }
 
+func (m *mockDashboardRepo) GetTopRisks(ctx context.Context, limit int) (*models.TopRiskListResponse, error) {
+	if m.top == nil {
+		return &models.TopRiskListResponse{Risks: []models.TopRisk{}}, nil
+	}
+	risks := m.top.Risks
+	if len(risks) > limit {
+		risks = risks[:limit]
+	}
+	return &models.TopRiskListResponse{Risks: risks}, nil
+}
+
 func TestDashboardHandler(t *testing.T) {
 	app := fiber.New()
 	mockRepo := &mockDashboardRepo{
@@ -42,6 +54,9 @@ func TestDashboardHandler(t *testing.T) {
 		overdue: &models.ReviewListResponse{
 			Risks: []models.ReviewRisk{{ID: "2", Title: "Overdue"}},
 		},
+		top: &models.TopRiskListResponse{
+			Risks: []models.TopRisk{{ID: "3", Title: "Critical"}, {ID: "4", Title: "High"}},
+		},
 	}
 	handler := NewDashboardHandler(mockRepo)
 
@@ -51,6 +66,7 @@ func TestDashboardHandler(t *testing.T) {
 	app.Get("/dashboard/summary", handler.Summary)
 	app.Get("/dashboard/reviews/upcoming", handler.UpcomingReviews)
 	app.Get("/dashboard/reviews/overdue", handler.OverdueReviews)
+	app.Get("/dashboard/risks/top", handler.TopRisks)
 
 	t.Run("Summary", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/dashboard/summary", nil)
@@ -105,4 +121,22 @@ func TestDashboardHandler(t *testing.T) {
 			t.Errorf("expected 1 overdue review, got %d", len(response.Risks))
 		}
 	})
+
+	t.Run("Top Risks", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/dashboard/risks/top?limit=1", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+
+		if resp.StatusCode != 200 {
+			t.Errorf("expected status 200, got %d", resp.StatusCode)
+		}
+
+		var response models.TopRiskListResponse
+		json.NewDecoder(resp.Body).Decode(&response)
+		if len(response.Risks) != 1 {
+			t.Errorf("expected 1 top risk, got %d", len(response.Risks))
+		}
+	})
 }
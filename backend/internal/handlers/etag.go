@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Risks, incidents and mitigations carry a version that is bumped on every
+// write. It is exposed as a strong ETag, and writes must send it back in
+// If-Match so concurrent edits are rejected instead of silently overwritten.
+
+var errIfMatchRequired = errors.New("If-Match header is required")
+var errIfMatchFailed = errors.New("If-Match does not match the current version")
+
+// versionETag formats a row version as an entity tag
+func versionETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+func setVersionETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, versionETag(version))
+}
+
+// checkIfMatch compares the request's If-Match header with the current version.
+// "*" matches any version; weak tags never match.
+func checkIfMatch(c *fiber.Ctx, current int) error {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" {
+		return errIfMatchRequired
+	}
+
+	want := versionETag(current)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == want {
+			return nil
+		}
+	}
+	return errIfMatchFailed
+}
+
+// preconditionFailed answers a write whose If-Match check failed. A mismatch
+// returns 412 with the current server copy so the client can merge and retry.
+func preconditionFailed(c *fiber.Ctx, err error, entity string, current any, version int) error {
+	if errors.Is(err, errIfMatchRequired) {
+		return c.Status(428).JSON(fiber.Map{"error": errIfMatchRequired.Error()})
+	}
+	setVersionETag(c, version)
+	return c.Status(412).JSON(fiber.Map{
+		"error":   entity + " has been modified since it was read",
+		"current": current,
+	})
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Evidence requests ask someone for the evidence a control needs by a due
+// date, once or on a recurrence. The assignee fulfils a request with a link
+// to the document, a note, or both; a recurring request then comes due again
+// one period after the date it was fulfilled for. The notification worker
+// reminds assignees as due dates approach.
+
+var evidenceStatuses = []string{string(models.EvidenceOpen), string(models.EvidenceFulfilled)}
+
+type EvidenceRequestHandler struct {
+	tx       database.Transactor
+	requests database.EvidenceRequestRepository
+	audit    database.AuditLogRepository
+}
+
+func NewEvidenceRequestHandler(tx database.Transactor, requests database.EvidenceRequestRepository, audit database.AuditLogRepository) *EvidenceRequestHandler {
+	return &EvidenceRequestHandler{tx: tx, requests: requests, audit: audit}
+}
+
+// List returns evidence requests, filtered by control_id, assignee_id,
+// status and overdue=true
+func (h *EvidenceRequestHandler) List(c *fiber.Ctx) error {
+	filter := models.EvidenceRequestFilter{
+		ControlID:  c.Query("control_id"),
+		AssigneeID: c.Query("assignee_id"),
+		Status:     c.Query("status"),
+		Overdue:    c.QueryBool("overdue"),
+	}
+	errs := fieldErrors{}
+	if filter.ControlID != "" {
+		optionalUUID(errs, "control_id", &filter.ControlID)
+	}
+	if filter.AssigneeID != "" {
+		optionalUUID(errs, "assignee_id", &filter.AssigneeID)
+	}
+	if filter.Status != "" {
+		requireOneOf(errs, "status", &filter.Status, evidenceStatuses...)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	return h.list(c, filter)
+}
+
+// Overdue returns the open requests past their due date, for the dashboard
+func (h *EvidenceRequestHandler) Overdue(c *fiber.Ctx) error {
+	return h.list(c, models.EvidenceRequestFilter{Overdue: true})
+}
+
+// ListForControl returns the evidence requests of the :id control
+func (h *EvidenceRequestHandler) ListForControl(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return evidenceRequestError(c, database.ErrFrameworkControlNotFound, ErrFailedToFetch)
+	}
+	return h.list(c, models.EvidenceRequestFilter{ControlID: id})
+}
+
+func (h *EvidenceRequestHandler) list(c *fiber.Ctx, filter models.EvidenceRequestFilter) error {
+	requests, err := h.requests.List(c.Context(), filter)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": requests})
+}
+
+func (h *EvidenceRequestHandler) Get(c *fiber.Ctx) error {
+	request, err := h.load(c)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(request)
+}
+
+// Create asks for evidence for a control. Without a recurrence the request
+// is one-off.
+func (h *EvidenceRequestHandler) Create(c *fiber.Ctx) error {
+	var input models.CreateEvidenceRequestInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.AssigneeID != nil && *input.AssigneeID == "" {
+		input.AssigneeID = nil
+	}
+
+	errs := fieldErrors{}
+	requireUUID(errs, "control_id", &input.ControlID)
+	requireText(errs, "title", &input.Title, 255)
+	optionalUUID(errs, "assignee_id", input.AssigneeID)
+	due := optionalDate(errs, "due_date", &input.DueDate)
+	if due == nil && errs["due_date"] == "" {
+		errs["due_date"] = "is required"
+	}
+	recurrence := evidenceRecurrence(errs, input.Recurrence)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	request := &models.EvidenceRequest{
+		ControlID:   input.ControlID,
+		Title:       strings.TrimSpace(input.Title),
+		Description: input.Description,
+		AssigneeID:  input.AssigneeID,
+		DueDate:     *due,
+		Recurrence:  recurrence,
+		Status:      models.EvidenceOpen,
+		CreatedBy:   &user.UserID,
+	}
+	if err := h.requests.Create(c.Context(), request); err != nil {
+		if errors.Is(err, database.ErrFrameworkControlNotFound) {
+			return validationFailed(c, fieldErrors{"control_id": "control not found"})
+		}
+		return evidenceRequestError(c, err, ErrFailedToCreate)
+	}
+	created, err := h.requests.GetByID(c.Context(), request.ID)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+
+	h.audit.Create(c.Context(), "evidence_request", created.ID, models.AuditActionCreated, evidenceRequestAuditSnapshot(created), user.UserID)
+	return c.Status(201).JSON(created)
+}
+
+// Update changes the fields that are set. An empty assignee_id unassigns
+// the request and an empty recurrence makes it one-off. Giving a fulfilled
+// request a new due date opens it again.
+func (h *EvidenceRequestHandler) Update(c *fiber.Ctx) error {
+	current, err := h.load(c)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+
+	var input models.UpdateEvidenceRequestInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Title == nil && input.Description == nil && input.AssigneeID == nil && input.DueDate == nil && input.Recurrence == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	errs := fieldErrors{}
+	updated := *current
+	if input.Title != nil && requireText(errs, "title", input.Title, 255) {
+		updated.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		updated.Description = *input.Description
+	}
+	if input.AssigneeID != nil {
+		if *input.AssigneeID == "" {
+			updated.AssigneeID = nil
+		} else if optionalUUID(errs, "assignee_id", input.AssigneeID) {
+			updated.AssigneeID = input.AssigneeID
+		}
+	}
+	if input.DueDate != nil {
+		if due := optionalDate(errs, "due_date", input.DueDate); due != nil {
+			updated.DueDate = *due
+			updated.Status = models.EvidenceOpen
+		} else if errs["due_date"] == "" {
+			errs["due_date"] = "cannot be empty"
+		}
+	}
+	if input.Recurrence != nil {
+		updated.Recurrence = evidenceRecurrence(errs, *input.Recurrence)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	if err := h.requests.Update(c.Context(), &updated); err != nil {
+		return evidenceRequestError(c, err, ErrFailedToUpdate)
+	}
+	after, err := h.requests.GetByID(c.Context(), current.ID)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	if changes := evidenceRequestAuditChanges(current, after); len(changes) > 0 {
+		h.audit.Create(c.Context(), "evidence_request", current.ID, models.AuditActionUpdated, changes, user.UserID)
+	}
+	return c.JSON(after)
+}
+
+// Delete removes a request along with its submissions
+func (h *EvidenceRequestHandler) Delete(c *fiber.Ctx) error {
+	request, err := h.load(c)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+	if err := h.requests.Delete(c.Context(), request.ID); err != nil {
+		return evidenceRequestError(c, err, ErrFailedToDelete)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	h.audit.Create(c.Context(), "evidence_request", request.ID, models.AuditActionDeleted, evidenceRequestAuditSnapshot(request), user.UserID)
+	return c.SendStatus(204)
+}
+
+// ListSubmissions returns the evidence provided for the :id request, most
+// recent first
+func (h *EvidenceRequestHandler) ListSubmissions(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return evidenceRequestError(c, database.ErrEvidenceRequestNotFound, ErrFailedToFetch)
+	}
+	submissions, err := h.requests.ListSubmissions(c.Context(), id)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": submissions})
+}
+
+// Fulfil provides the evidence for the :id request's current due date. Only
+// its assignee or an admin may do so.
+func (h *EvidenceRequestHandler) Fulfil(c *fiber.Ctx) error {
+	request, err := h.load(c)
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToFetch)
+	}
+	user := middleware.GetUserFromContext(c)
+	if user.Role != string(models.RoleAdmin) && (request.AssigneeID == nil || *request.AssigneeID != user.UserID) {
+		return c.Status(403).JSON(fiber.Map{"error": "only the assignee or an admin can fulfil this request"})
+	}
+	if request.Status != models.EvidenceOpen {
+		return evidenceRequestError(c, database.ErrEvidenceRequestFulfilled, ErrFailedToCreate)
+	}
+
+	var input models.FulfilEvidenceRequestInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	input.URL, input.Note = strings.TrimSpace(input.URL), strings.TrimSpace(input.Note)
+	errs := fieldErrors{}
+	if input.URL != "" {
+		if u, err := url.Parse(input.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs["url"] = "must be an http or https URL"
+		} else if len(input.URL) > 2048 {
+			errs["url"] = "must be at most 2048 characters"
+		}
+	} else if input.Note == "" {
+		errs["url"] = "or a note is required"
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	submission := &models.EvidenceSubmission{
+		EvidenceRequestID: request.ID,
+		DueDate:           request.DueDate,
+		URL:               input.URL,
+		Note:              input.Note,
+		SubmittedBy:       &user.UserID,
+	}
+	var next *models.EvidenceRequest
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		requests := h.requests.WithTx(tx)
+		var nextDue *time.Time
+		if request.Recurrence != nil {
+			due := request.Recurrence.Next(request.DueDate)
+			nextDue = &due
+		}
+		if err := requests.Fulfil(c.Context(), submission, nextDue); err != nil {
+			return err
+		}
+		after, err := requests.GetByID(c.Context(), request.ID)
+		if err != nil {
+			return err
+		}
+		next = after
+
+		changes := evidenceRequestAuditChanges(request, after)
+		maps.Copy(changes, map[string]any{
+			"fulfilled_due_date": auditDate(&submission.DueDate),
+			"url":                submission.URL,
+			"note":               submission.Note,
+		})
+		return h.audit.WithTx(tx).Create(c.Context(), "evidence_request", request.ID, models.AuditActionUpdated, changes, user.UserID)
+	})
+	if err != nil {
+		return evidenceRequestError(c, err, ErrFailedToCreate)
+	}
+
+	return c.Status(201).JSON(fiber.Map{"submission": submission, "request": next})
+}
+
+// load fetches the :id request
+func (h *EvidenceRequestHandler) load(c *fiber.Ctx) (*models.EvidenceRequest, error) {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return nil, database.ErrEvidenceRequestNotFound
+	}
+	return h.requests.GetByID(c.Context(), id)
+}
+
+// evidenceRecurrence checks a recurrence field, where empty means one-off
+func evidenceRecurrence(errs fieldErrors, value models.Recurrence) *models.Recurrence {
+	if value == "" {
+		return nil
+	}
+	if !requireOneOf(errs, "recurrence", (*string)(&value), recurrences...) {
+		return nil
+	}
+	return &value
+}
+
+// evidenceRequestAuditSnapshot returns the state recorded when a request is
+// created or deleted
+func evidenceRequestAuditSnapshot(request *models.EvidenceRequest) map[string]any {
+	return map[string]any{
+		"control_id":  request.ControlID,
+		"title":       request.Title,
+		"description": request.Description,
+		"assignee_id": auditString(request.AssigneeID),
+		"due_date":    auditDate(&request.DueDate),
+		"recurrence":  auditString((*string)(request.Recurrence)),
+		"status":      string(request.Status),
+	}
+}
+
+// evidenceRequestAuditChanges returns from/to pairs for the fields that
+// differ between two versions of a request
+func evidenceRequestAuditChanges(before, after *models.EvidenceRequest) map[string]any {
+	changes := make(map[string]any)
+	from, to := evidenceRequestAuditSnapshot(before), evidenceRequestAuditSnapshot(after)
+	for field, value := range to {
+		if from[field] != value {
+			changes[field] = auditChange(from[field], value)
+		}
+	}
+	return changes
+}
+
+// evidenceRequestError maps repository errors to responses, using failed
+// for anything unexpected
+func evidenceRequestError(c *fiber.Ctx, err error, failed string) error {
+	switch {
+	case errors.Is(err, database.ErrEvidenceRequestNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "evidence request")})
+	case errors.Is(err, database.ErrFrameworkControlNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "control")})
+	case errors.Is(err, database.ErrEvidenceAssigneeNotFound):
+		return validationFailed(c, fieldErrors{"assignee_id": "user not found"})
+	case errors.Is(err, database.ErrEvidenceRequestFulfilled):
+		return c.Status(409).JSON(fiber.Map{"error": "this evidence request has already been fulfilled"})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(failed, "evidence request")})
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockEvidenceRequestRepo keeps requests and submissions in memory. Requests
+// can only be created for the controls in controls.
+type mockEvidenceRequestRepo struct {
+	requests    []*models.EvidenceRequest
+	submissions []*models.EvidenceSubmission
+	controls    map[string]bool
+}
+
+func (m *mockEvidenceRequestRepo) List(ctx context.Context, filter models.EvidenceRequestFilter) ([]*models.EvidenceRequest, error) {
+	requests := []*models.EvidenceRequest{}
+	for _, request := range m.requests {
+		if filter.ControlID != "" && request.ControlID != filter.ControlID {
+			continue
+		}
+		if filter.Status != "" && string(request.Status) != filter.Status {
+			continue
+		}
+		if filter.Overdue && !request.Overdue {
+			continue
+		}
+		requests = append(requests, request)
+	}
+	return requests, nil
+}
+
+func (m *mockEvidenceRequestRepo) GetByID(ctx context.Context, id string) (*models.EvidenceRequest, error) {
+	for _, request := range m.requests {
+		if request.ID == id {
+			copied := *request
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrEvidenceRequestNotFound
+}
+
+func (m *mockEvidenceRequestRepo) Create(ctx context.Context, request *models.EvidenceRequest) error {
+	if !m.controls[request.ControlID] {
+		return database.ErrFrameworkControlNotFound
+	}
+	request.ID = uuid.New().String()
+	stored := *request
+	m.requests = append(m.requests, &stored)
+	return nil
+}
+
+func (m *mockEvidenceRequestRepo) Update(ctx context.Context, request *models.EvidenceRequest) error {
+	for i, stored := range m.requests {
+		if stored.ID == request.ID {
+			updated := *request
+			m.requests[i] = &updated
+			return nil
+		}
+	}
+	return database.ErrEvidenceRequestNotFound
+}
+
+func (m *mockEvidenceRequestRepo) Delete(ctx context.Context, id string) error {
+	for i, request := range m.requests {
+		if request.ID == id {
+			m.requests = append(m.requests[:i], m.requests[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrEvidenceRequestNotFound
+}
+
+func (m *mockEvidenceRequestRepo) ListSubmissions(ctx context.Context, requestID string) ([]*models.EvidenceSubmission, error) {
+	submissions := []*models.EvidenceSubmission{}
+	for _, submission := range m.submissions {
+		if submission.EvidenceRequestID == requestID {
+			submissions = append(submissions, submission)
+		}
+	}
+	return submissions, nil
+}
+
+func (m *mockEvidenceRequestRepo) Fulfil(ctx context.Context, submission *models.EvidenceSubmission, next *time.Time) error {
+	for _, request := range m.requests {
+		if request.ID != submission.EvidenceRequestID {
+			continue
+		}
+		if request.Status != models.EvidenceOpen || !request.DueDate.Equal(submission.DueDate) {
+			return database.ErrEvidenceRequestFulfilled
+		}
+		if next != nil {
+			request.DueDate = *next
+		} else {
+			request.Status = models.EvidenceFulfilled
+		}
+		submission.ID = uuid.New().String()
+		m.submissions = append(m.submissions, submission)
+		request.LastSubmission = submission
+		return nil
+	}
+	return database.ErrEvidenceRequestNotFound
+}
+
+func (m *mockEvidenceRequestRepo) WithTx(tx *sql.Tx) database.EvidenceRequestRepository {
+	return m
+}
+
+func setupEvidenceApp(role string) (*fiber.App, *mockEvidenceRequestRepo, *mockAuditRepo) {
+	repo := &mockEvidenceRequestRepo{controls: map[string]bool{}}
+	audit := &mockAuditRepo{}
+	handler := NewEvidenceRequestHandler(&mockTransactor{}, repo, audit)
+
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserKey, &middleware.UserClaims{UserID: "test-user-id", Role: role})
+		return c.Next()
+	}
+	app := fiber.New()
+	app.Get("/evidence-requests", auth, handler.List)
+	app.Post("/evidence-requests", auth, handler.Create)
+	app.Put("/evidence-requests/:id", auth, handler.Update)
+	app.Get("/evidence-requests/:id/submissions", auth, handler.ListSubmissions)
+	app.Post("/evidence-requests/:id/submissions", auth, handler.Fulfil)
+	return app, repo, audit
+}
+
+func TestEvidenceRequestHandler_Create(t *testing.T) {
+	app, repo, audit := setupEvidenceApp("admin")
+	control := uuid.New().String()
+	repo.controls[control] = true
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name, body, field string
+		}{
+			{"missing control", `{"title":"Access review export","due_date":"2026-12-31"}`, "control_id"},
+			{"missing title", `{"control_id":"` + control + `","due_date":"2026-12-31"}`, "title"},
+			{"missing due date", `{"control_id":"` + control + `","title":"Access review export"}`, "due_date"},
+			{"bad due date", `{"control_id":"` + control + `","title":"Access review export","due_date":"31/12/2026"}`, "due_date"},
+			{"unknown recurrence", `{"control_id":"` + control + `","title":"Access review export","due_date":"2026-12-31","recurrence":"weekly"}`, "recurrence"},
+			{"bad assignee", `{"control_id":"` + control + `","title":"Access review export","due_date":"2026-12-31","assignee_id":"bob"}`, "assignee_id"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, app, "POST", "/evidence-requests", tt.body)
+				require.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+
+	status, body := sendTeamRequest(t, app, "POST", "/evidence-requests", `{"control_id":"`+uuid.New().String()+`","title":"Access review export","due_date":"2026-12-31"}`)
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "control_id")
+
+	status, body = sendTeamRequest(t, app, "POST", "/evidence-requests",
+		`{"control_id":"`+control+`","title":" Access review export ","due_date":"2026-12-31","recurrence":"quarterly","assignee_id":"`+uuid.New().String()+`"}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, "Access review export", body["title"])
+	assert.Equal(t, "quarterly", body["recurrence"])
+	assert.Equal(t, "open", body["status"])
+	require.Len(t, audit.logs, 1)
+	assert.Equal(t, "evidence_request", audit.logs[0].EntityType)
+
+	status, body = sendTeamRequest(t, app, "POST", "/evidence-requests", `{"control_id":"`+control+`","title":"Pen test report","due_date":"2027-03-01"}`)
+	require.Equal(t, 201, status, body)
+	assert.NotContains(t, body, "recurrence", "no recurrence means a one-off request")
+}
+
+func TestEvidenceRequestHandler_Fulfil(t *testing.T) {
+	due := time.Date(2026, 9, 30, 0, 0, 0, 0, time.UTC)
+	newApp := func(role, assignee string, recurrence *models.Recurrence) (*fiber.App, *mockEvidenceRequestRepo, *mockAuditRepo, string) {
+		app, repo, audit := setupEvidenceApp(role)
+		request := &models.EvidenceRequest{ID: uuid.New().String(), ControlID: uuid.New().String(), Title: "Access review export",
+			AssigneeID: &assignee, DueDate: due, Recurrence: recurrence, Status: models.EvidenceOpen, Overdue: true}
+		repo.requests = append(repo.requests, request)
+		return app, repo, audit, "/evidence-requests/" + request.ID
+	}
+	quarterly := models.RecurrenceQuarterly
+
+	t.Run("only the assignee or an admin", func(t *testing.T) {
+		app, _, _, path := newApp("member", uuid.New().String(), nil)
+		status, _ := sendTeamRequest(t, app, "POST", path+"/submissions", `{"note":"Done"}`)
+		assert.Equal(t, 403, status)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		app, _, _, path := newApp("admin", uuid.New().String(), nil)
+		tests := []struct {
+			name, body string
+		}{
+			{"nothing provided", `{"url":" ","note":""}`},
+			{"not a web link", `{"url":"file:///etc/passwd"}`},
+			{"relative link", `{"url":"/docs/review.pdf"}`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, app, "POST", path+"/submissions", tt.body)
+				require.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], "url")
+			})
+		}
+	})
+
+	t.Run("recurring request comes due again", func(t *testing.T) {
+		app, repo, audit, path := newApp("admin", uuid.New().String(), &quarterly)
+		status, body := sendTeamRequest(t, app, "POST", path+"/submissions", `{"url":"https://docs.example.com/access-review-q3.pdf"}`)
+		require.Equal(t, 201, status, body)
+		request := body["request"].(map[string]any)
+		assert.Equal(t, "open", request["status"])
+		assert.Equal(t, "2026-12-30T00:00:00Z", request["due_date"])
+		assert.Equal(t, "2026-09-30T00:00:00Z", body["submission"].(map[string]any)["due_date"])
+
+		require.Len(t, audit.logs, 1)
+		assert.Equal(t, auditChange("2026-09-30", "2026-12-30"), audit.logs[0].Changes["due_date"])
+		assert.Equal(t, "2026-09-30", audit.logs[0].Changes["fulfilled_due_date"])
+
+		submissions, _ := repo.ListSubmissions(context.Background(), repo.requests[0].ID)
+		assert.Len(t, submissions, 1)
+	})
+
+	t.Run("assignee fulfils a one-off request once", func(t *testing.T) {
+		app, repo, audit, path := newApp("member", "test-user-id", nil)
+		status, body := sendTeamRequest(t, app, "POST", path+"/submissions", `{"note":"Reviewed in the September CAB, minutes attached to the ticket"}`)
+		require.Equal(t, 201, status, body)
+		assert.Equal(t, "fulfilled", body["request"].(map[string]any)["status"])
+		assert.Equal(t, auditChange("open", "fulfilled"), audit.logs[0].Changes["status"])
+
+		status, _ = sendTeamRequest(t, app, "POST", path+"/submissions", `{"note":"Again"}`)
+		assert.Equal(t, 409, status)
+		assert.Len(t, repo.submissions, 1)
+	})
+}
+
+func TestEvidenceRequestHandler_UpdateReopens(t *testing.T) {
+	app, repo, audit := setupEvidenceApp("admin")
+	request := &models.EvidenceRequest{ID: uuid.New().String(), ControlID: uuid.New().String(), Title: "Pen test report",
+		DueDate: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Status: models.EvidenceFulfilled}
+	repo.requests = append(repo.requests, request)
+
+	status, body := sendTeamRequest(t, app, "PUT", "/evidence-requests/"+request.ID, `{"due_date":"2027-03-01","recurrence":"annual"}`)
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, "open", body["status"])
+	assert.Equal(t, "annual", body["recurrence"])
+	require.Len(t, audit.logs, 1)
+	assert.Equal(t, auditChange(nil, "annual"), audit.logs[0].Changes["recurrence"])
+
+	status, body = sendTeamRequest(t, app, "PUT", "/evidence-requests/"+request.ID, `{"recurrence":""}`)
+	require.Equal(t, 200, status, body)
+	assert.NotContains(t, body, "recurrence")
+
+	status, body = sendTeamRequest(t, app, "PUT", "/evidence-requests/"+request.ID, `{"due_date":""}`)
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "due_date")
+}
+
+func TestEvidenceRequestHandler_List(t *testing.T) {
+	app, repo, _ := setupEvidenceApp("member")
+	repo.requests = []*models.EvidenceRequest{
+		{ID: uuid.New().String(), Title: "Backup restore log", Status: models.EvidenceOpen, Overdue: true},
+		{ID: uuid.New().String(), Title: "Access review export", Status: models.EvidenceOpen},
+		{ID: uuid.New().String(), Title: "Pen test report", Status: models.EvidenceFulfilled},
+	}
+
+	status, body := sendTeamRequest(t, app, "GET", "/evidence-requests?overdue=true", "")
+	require.Equal(t, 200, status)
+	require.Len(t, body["data"], 1)
+	assert.Equal(t, "Backup restore log", body["data"].([]any)[0].(map[string]any)["title"])
+
+	status, body = sendTeamRequest(t, app, "GET", "/evidence-requests?status=fulfilled", "")
+	require.Equal(t, 200, status)
+	assert.Len(t, body["data"], 1)
+
+	status, body = sendTeamRequest(t, app, "GET", "/evidence-requests?status=late", "")
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "status")
+}
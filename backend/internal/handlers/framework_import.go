@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"backend/internal/catalog"
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// The catalog import replaces a framework's controls with those of an OSCAL
+// catalog (.json) or a spreadsheet (.csv or .xlsx) with control_ref, title,
+// description and parent_ref columns. Controls are matched by ref, so
+// importing a newer edition of the same catalog updates controls in place and
+// keeps their risk links. Like the risk import, it only previews the changes
+// unless commit=true.
+
+var errCatalogFormat = errors.New("file must be an OSCAL catalog (.json) or a .csv or .xlsx spreadsheet")
+
+// catalogColumnAliases maps normalized spreadsheet headers to the control
+// field they fill
+var catalogColumnAliases = map[string]string{
+	"control_ref": "control_ref",
+	"ref":         "control_ref",
+	"control":     "control_ref",
+	"control_id":  "control_ref",
+	"id":          "control_ref",
+	"title":       "title",
+	"name":        "title",
+	"description": "description",
+	"statement":   "description",
+	"parent_ref":  "parent_ref",
+	"parent":      "parent_ref",
+	"parent_id":   "parent_ref",
+}
+
+type FrameworkImportHandler struct {
+	tx         database.Transactor
+	frameworks database.FrameworkRepository
+	controls   database.FrameworkControlRepository
+	audit      database.AuditLogRepository
+}
+
+func NewFrameworkImportHandler(
+	tx database.Transactor,
+	frameworks database.FrameworkRepository,
+	controls database.FrameworkControlRepository,
+	audit database.AuditLogRepository,
+) *FrameworkImportHandler {
+	return &FrameworkImportHandler{tx: tx, frameworks: frameworks, controls: controls, audit: audit}
+}
+
+// Import compares an uploaded catalog with the framework's controls and,
+// with commit=true, applies the difference. Form fields: file (required),
+// sheet (XLSX only), prune (also delete controls missing from the catalog,
+// except those still linked to risks) and commit.
+func (h *FrameworkImportHandler) Import(c *fiber.Ctx) error {
+	framework, err := h.frameworks.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrFrameworkNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "framework")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "framework")})
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	f, err := file.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "failed to read file"})
+	}
+	defer f.Close()
+
+	cat, columnErrs, err := readCatalog(f, filepath.Ext(file.Filename), c.FormValue("sheet"))
+	if err != nil {
+		if errors.Is(err, errCatalogFormat) {
+			return c.Status(415).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(columnErrs) > 0 {
+		return validationFailed(c, columnErrs)
+	}
+	if len(cat.Controls) > maxImportRows {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("catalog has %d controls; at most %d can be imported at once", len(cat.Controls), maxImportRows),
+		})
+	}
+
+	result := &models.ControlImportResult{
+		FrameworkID:    framework.ID,
+		Filename:       file.Filename,
+		Format:         cat.Format,
+		CatalogTitle:   cat.Title,
+		CatalogVersion: cat.Version,
+		Prune:          c.FormValue("prune") == "true",
+		Errors:         cat.Validate(),
+	}
+	existing, err := h.controls.List(c.Context(), framework.ID, "")
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "framework controls")})
+	}
+	catalog.Diff(existing, cat, result)
+
+	if c.FormValue("commit") != "true" {
+		return c.JSON(result)
+	}
+	if len(result.Errors) > 0 || result.Total == 0 {
+		return c.Status(422).JSON(result)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	batchID := uuid.New().String()
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		return h.apply(c.Context(), tx, batchID, cat, existing, result, user.UserID)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to import controls"})
+	}
+
+	result.BatchID = batchID
+	result.Committed = true
+	return c.Status(201).JSON(result)
+}
+
+// apply writes the added and changed controls, parents first so that their
+// IDs are known, deletes removed ones when pruning, and records a
+// framework_import audit entry
+func (h *FrameworkImportHandler) apply(ctx context.Context, tx *sql.Tx, batchID string, cat *catalog.Catalog,
+	existing []*models.FrameworkControl, result *models.ControlImportResult, userID string) error {
+	controls := h.controls.WithTx(tx)
+
+	ids := make(map[string]string, len(existing)+len(result.Added))
+	for _, control := range existing {
+		ids[control.ControlRef] = control.ID
+	}
+	pending := make(map[string]*models.ControlImportItem, len(result.Added)+len(result.Changed))
+	for _, item := range result.Added {
+		pending[item.ControlRef] = item
+	}
+	for _, item := range result.Changed {
+		pending[item.ControlRef] = item
+	}
+
+	for _, control := range cat.Controls {
+		item, ok := pending[control.Ref]
+		if !ok {
+			continue
+		}
+		fc := &models.FrameworkControl{
+			FrameworkID: result.FrameworkID,
+			ControlRef:  control.Ref,
+			Title:       control.Title,
+			Description: control.Description,
+			SortOrder:   control.Position,
+		}
+		if control.ParentRef != "" {
+			parentID := ids[control.ParentRef]
+			fc.ParentID = &parentID
+		}
+		if err := controls.Upsert(ctx, fc); err != nil {
+			return err
+		}
+		ids[control.Ref] = fc.ID
+		item.ControlID = fc.ID
+	}
+
+	deleted := 0
+	if result.Prune {
+		for _, item := range result.Removed {
+			err := h.tx.Savepoint(ctx, tx, func() error {
+				return controls.Delete(ctx, item.ControlID)
+			})
+			if errors.Is(err, database.ErrFrameworkControlInUse) {
+				item.InUse = true
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			deleted++
+		}
+	}
+
+	return h.audit.WithTx(tx).Create(ctx, "framework_import", batchID, models.AuditActionCreated, map[string]any{
+		"framework_id": result.FrameworkID,
+		"filename":     result.Filename,
+		"format":       result.Format,
+		"added":        len(result.Added),
+		"changed":      len(result.Changed),
+		"removed":      deleted,
+	}, userID)
+}
+
+// readCatalog parses an uploaded catalog, picking the parser by extension.
+// Spreadsheets missing a required column are reported in errs.
+func readCatalog(r io.Reader, ext, sheet string) (cat *catalog.Catalog, errs fieldErrors, err error) {
+	switch strings.ToLower(ext) {
+	case ".json":
+		cat, err = catalog.ParseOSCAL(r)
+		return cat, nil, err
+	case ".csv", ".xlsx":
+		table, err := readImportTable(r, ext, sheet)
+		if err != nil {
+			return nil, nil, err
+		}
+		cat, errs = catalogFromTable(table)
+		return cat, errs, nil
+	default:
+		return nil, nil, errCatalogFormat
+	}
+}
+
+// catalogFromTable reads one control per non-blank row. The control_ref and
+// title columns must be present.
+func catalogFromTable(table *importTable) (*catalog.Catalog, fieldErrors) {
+	columns := map[string]int{}
+	for i, header := range table.headers {
+		field, ok := catalogColumnAliases[normalizeImportHeader(header)]
+		if _, taken := columns[field]; ok && !taken {
+			columns[field] = i
+		}
+	}
+	errs := fieldErrors{}
+	for _, field := range []string{"control_ref", "title"} {
+		if _, ok := columns[field]; !ok {
+			errs[field] = "must be a column in the file"
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	cat := &catalog.Catalog{Format: table.format}
+	for i, record := range table.rows {
+		if isBlankRecord(record) {
+			continue
+		}
+		cell := func(field string) string {
+			col, ok := columns[field]
+			if !ok || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+		cat.Controls = append(cat.Controls, &catalog.Control{
+			Ref:         cell("control_ref"),
+			Title:       cell("title"),
+			Description: cell("description"),
+			ParentRef:   cell("parent_ref"),
+			Row:         i + 2,
+		})
+	}
+	return cat, nil
+}
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestFrameworkImportHandler(t *testing.T) {
+	framework := &models.Framework{ID: "framework-id", Name: "ISO 27001"}
+
+	newApp := func() (*fiber.App, *mockFrameworkControlRepo, *mockAuditRepo) {
+		frameworkRepo := &mockFrameworkRepo{frameworks: map[string]*models.Framework{framework.ID: framework}}
+		controlRepo := &mockFrameworkControlRepo{controls: map[string]*models.FrameworkControl{}, inUse: map[string]bool{}}
+		auditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+		handler := NewFrameworkImportHandler(&mockTransactor{}, frameworkRepo, controlRepo, auditRepo)
+
+		app := fiber.New()
+		app.Post("/frameworks/:id/import", testAuthMiddleware, handler.Import)
+		return app, controlRepo, auditRepo
+	}
+	upload := func(app *fiber.App, frameworkID, filename string, content []byte, fields map[string]string) (int, *models.ControlImportResult, string) {
+		body := new(bytes.Buffer)
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile("file", filename)
+		part.Write(content)
+		for k, v := range fields {
+			w.WriteField(k, v)
+		}
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/frameworks/"+frameworkID+"/import", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw := new(bytes.Buffer)
+		raw.ReadFrom(resp.Body)
+		var result models.ControlImportResult
+		json.Unmarshal(raw.Bytes(), &result)
+		return resp.StatusCode, &result, raw.String()
+	}
+	byRef := func(repo *mockFrameworkControlRepo) map[string]*models.FrameworkControl {
+		controls := map[string]*models.FrameworkControl{}
+		for _, control := range repo.controls {
+			controls[control.ControlRef] = control
+		}
+		return controls
+	}
+	commit := map[string]string{"commit": "true"}
+
+	csvFile := []byte("Ref,Name,Statement,Parent\n" +
+		"A.5.1,Policies for information security,Policies are defined.,A.5\n" +
+		"A.5,Organizational controls,,\n" +
+		",,,\n" +
+		"A.5.2,Information security roles,Roles are allocated.,A.5\n")
+
+	t.Run("preview lists controls without importing", func(t *testing.T) {
+		app, controlRepo, _ := newApp()
+		status, result, raw := upload(app, framework.ID, "iso.csv", csvFile, nil)
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, raw)
+		}
+		if result.Committed || len(controlRepo.controls) != 0 {
+			t.Error("preview must not import controls")
+		}
+		if result.Total != 3 || len(result.Added) != 3 || len(result.Errors) != 0 {
+			t.Errorf("expected 3 added controls, got %s", raw)
+		}
+	})
+
+	t.Run("commit keeps the hierarchy and catalog order", func(t *testing.T) {
+		app, controlRepo, auditRepo := newApp()
+		status, result, raw := upload(app, framework.ID, "iso.csv", csvFile, commit)
+		if status != 201 {
+			t.Fatalf("expected status 201, got %d: %s", status, raw)
+		}
+		if !result.Committed || result.BatchID == "" {
+			t.Errorf("expected a committed batch, got %s", raw)
+		}
+
+		controls := byRef(controlRepo)
+		parent, child := controls["A.5"], controls["A.5.1"]
+		if parent == nil || child == nil || len(controls) != 3 {
+			t.Fatalf("expected 3 controls, got %v", controls)
+		}
+		if parent.ParentID != nil || child.ParentID == nil || *child.ParentID != parent.ID {
+			t.Error("expected A.5.1 to be a child of A.5")
+		}
+		if child.SortOrder != 1 || parent.SortOrder != 2 || child.Description != "Policies are defined." {
+			t.Errorf("unexpected control: %+v", child)
+		}
+		if len(auditRepo.logs) != 1 || auditRepo.logs[0].EntityType != "framework_import" || auditRepo.logs[0].EntityID != result.BatchID {
+			t.Errorf("expected one framework_import audit entry, got %+v", auditRepo.logs)
+		}
+	})
+
+	t.Run("re-import updates changed controls in place", func(t *testing.T) {
+		app, controlRepo, _ := newApp()
+		upload(app, framework.ID, "iso.csv", csvFile, commit)
+		before := byRef(controlRepo)
+
+		status, result, raw := upload(app, framework.ID, "iso.csv", csvFile, commit)
+		if status != 201 || result.Unchanged != 3 || len(result.Added)+len(result.Changed)+len(result.Removed) != 0 {
+			t.Fatalf("expected an unchanged re-import, got %d: %s", status, raw)
+		}
+
+		edited := []byte("Ref,Name,Statement,Parent\n" +
+			"A.5.1,Policies for information security,Policies are defined and approved.,A.5\n" +
+			"A.5,Organizational controls,,\n" +
+			"A.5.3,Segregation of duties,,A.5\n")
+		status, result, raw = upload(app, framework.ID, "iso.csv", edited, commit)
+		if status != 201 {
+			t.Fatalf("expected status 201, got %d: %s", status, raw)
+		}
+		if len(result.Added) != 1 || len(result.Changed) != 1 || len(result.Removed) != 1 || result.Unchanged != 1 {
+			t.Fatalf("unexpected diff: %s", raw)
+		}
+		if fields := result.Changed[0].Fields; len(fields) != 1 || fields[0] != "description" {
+			t.Errorf("expected only the description to change, got %v", fields)
+		}
+
+		after := byRef(controlRepo)
+		if after["A.5.1"].ID != before["A.5.1"].ID || after["A.5.1"].Description != "Policies are defined and approved." {
+			t.Errorf("expected A.5.1 to be updated in place, got %+v", after["A.5.1"])
+		}
+		if after["A.5.2"] == nil {
+			t.Error("removed controls must be kept unless pruning")
+		}
+	})
+
+	t.Run("prune keeps controls that are in use", func(t *testing.T) {
+		app, controlRepo, auditRepo := newApp()
+		upload(app, framework.ID, "iso.csv", csvFile, commit)
+		controlRepo.inUse[byRef(controlRepo)["A.5.2"].ID] = true
+
+		status, result, raw := upload(app, framework.ID, "iso.csv", []byte("control_ref,title\nA.5,Organizational controls\n"),
+			map[string]string{"commit": "true", "prune": "true"})
+		if status != 201 {
+			t.Fatalf("expected status 201, got %d: %s", status, raw)
+		}
+		if len(result.Removed) != 2 {
+			t.Fatalf("expected 2 removed controls, got %s", raw)
+		}
+		for _, item := range result.Removed {
+			if item.InUse != (item.ControlRef == "A.5.2") {
+				t.Errorf("unexpected in_use for %s", item.ControlRef)
+			}
+		}
+		controls := byRef(controlRepo)
+		if controls["A.5.1"] != nil || controls["A.5.2"] == nil {
+			t.Errorf("expected only A.5.1 to be deleted, got %v", controls)
+		}
+		if changes := auditRepo.logs[len(auditRepo.logs)-1].Changes; changes["removed"] != 1 {
+			t.Errorf("expected the audit entry to count 1 removal, got %v", changes)
+		}
+	})
+
+	t.Run("commit refuses a catalog with errors", func(t *testing.T) {
+		app, controlRepo, _ := newApp()
+		file := []byte("control_ref,title,parent_ref\nA.1,One,A.9\nA.1,Again,\n")
+		status, result, raw := upload(app, framework.ID, "iso.csv", file, commit)
+		if status != 422 {
+			t.Fatalf("expected status 422, got %d: %s", status, raw)
+		}
+		if len(result.Errors) != 2 || len(controlRepo.controls) != 0 {
+			t.Errorf("expected 2 errors and no controls, got %s", raw)
+		}
+	})
+
+	t.Run("imports an OSCAL catalog", func(t *testing.T) {
+		app, controlRepo, _ := newApp()
+		file := []byte(`{"catalog": {"metadata": {"title": "Catalog", "version": "5.1"}, "groups": [
+			{"id": "ac", "title": "Access Control", "controls": [
+				{"id": "ac-1", "title": "Policy and Procedures", "props": [{"name": "label", "value": "AC-1"}]}
+			]}
+		]}}`)
+		status, result, raw := upload(app, framework.ID, "catalog.json", file, commit)
+		if status != 201 || result.Format != "oscal" || result.CatalogVersion != "5.1" {
+			t.Fatalf("unexpected response %d: %s", status, raw)
+		}
+		controls := byRef(controlRepo)
+		if controls["AC-1"] == nil || controls["ac"] == nil || *controls["AC-1"].ParentID != controls["ac"].ID {
+			t.Errorf("expected AC-1 under the ac group, got %v", controls)
+		}
+	})
+
+	t.Run("rejects bad uploads", func(t *testing.T) {
+		app, _, _ := newApp()
+		if status, _, _ := upload(app, "missing", "iso.csv", csvFile, nil); status != 404 {
+			t.Errorf("expected 404 for an unknown framework, got %d", status)
+		}
+		if status, _, _ := upload(app, framework.ID, "iso.txt", csvFile, nil); status != 415 {
+			t.Errorf("expected 415 for an unknown format, got %d", status)
+		}
+		status, _, raw := upload(app, framework.ID, "iso.csv", []byte("Ref,Notes\nA.1,x\n"), nil)
+		if status != 400 || !bytes.Contains([]byte(raw), []byte("title")) {
+			t.Errorf("expected a missing title column error, got %d: %s", status, raw)
+		}
+	})
+}
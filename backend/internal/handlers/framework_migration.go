@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// A framework migration moves risk links from the controls of one version of
+// a framework (ISO 27001:2013, say) to those of its successor (ISO
+// 27001:2022). It reads a CSV or XLSX mapping file with an old_ref and a
+// new_ref column. An old control may be listed on several rows when it was
+// split, and several old controls may map to one new control when they were
+// merged. A blank new_ref retires the old control on purpose. Links to old
+// controls that aren't mapped stay where they are and are reported, as are
+// the new controls nothing maps to. Like the other imports it only previews
+// the changes unless commit=true.
+
+// errMigrationInvalid rolls back a commit whose plan, read again inside the
+// transaction, has invalid rows or none at all
+var errMigrationInvalid = errors.New("migration has invalid rows")
+
+// migrationColumnAliases maps normalized headers to the mapping field they fill
+var migrationColumnAliases = map[string]string{
+	"old_ref":     "old_ref",
+	"old":         "old_ref",
+	"old_control": "old_ref",
+	"from":        "old_ref",
+	"from_ref":    "old_ref",
+	"source_ref":  "old_ref",
+	"new_ref":     "new_ref",
+	"new":         "new_ref",
+	"new_control": "new_ref",
+	"to":          "new_ref",
+	"to_ref":      "new_ref",
+	"target_ref":  "new_ref",
+}
+
+type FrameworkMigrationHandler struct {
+	tx         database.Transactor
+	frameworks database.FrameworkRepository
+	controls   database.FrameworkControlRepository
+	migrations database.FrameworkMigrationRepository
+	audit      database.AuditLogRepository
+}
+
+func NewFrameworkMigrationHandler(
+	tx database.Transactor,
+	frameworks database.FrameworkRepository,
+	controls database.FrameworkControlRepository,
+	migrations database.FrameworkMigrationRepository,
+	audit database.AuditLogRepository,
+) *FrameworkMigrationHandler {
+	return &FrameworkMigrationHandler{tx: tx, frameworks: frameworks, controls: controls, migrations: migrations, audit: audit}
+}
+
+// Migrate previews moving the risk links of an older framework version to
+// the framework :id and, with commit=true, moves them. Form fields: file
+// (required), sheet (XLSX only), from (the old framework by name or ID,
+// defaulting to the framework's previous version) and commit.
+func (h *FrameworkMigrationHandler) Migrate(c *fiber.Ctx) error {
+	target, err := h.frameworks.GetByID(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrFrameworkNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "framework")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "framework")})
+	}
+
+	errs := fieldErrors{}
+	source, err := h.sourceFramework(c.Context(), target, strings.TrimSpace(c.FormValue("from")), errs)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "frameworks")})
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+	f, err := file.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "failed to read file"})
+	}
+	defer f.Close()
+
+	table, err := readImportTable(f, filepath.Ext(file.Filename), c.FormValue("sheet"))
+	if err != nil {
+		if errors.Is(err, errImportFormat) {
+			return c.Status(415).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(table.rows) > maxImportRows {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("file has %d rows; at most %d can be imported at once", len(table.rows), maxImportRows),
+		})
+	}
+
+	columns := map[string]int{}
+	for i, header := range table.headers {
+		field, ok := migrationColumnAliases[normalizeImportHeader(header)]
+		if _, taken := columns[field]; ok && !taken {
+			columns[field] = i
+		}
+	}
+	for _, field := range []string{"old_ref", "new_ref"} {
+		if _, ok := columns[field]; !ok {
+			errs[field] = "must be a column in the file"
+		}
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	result := &models.FrameworkMigrationResult{
+		Filename:      file.Filename,
+		Format:        table.format,
+		FromFramework: models.FrameworkVersionSummary{ID: source.ID, Name: source.Name, Version: source.Version},
+		ToFramework:   models.FrameworkVersionSummary{ID: target.ID, Name: target.Name, Version: target.Version},
+	}
+	if c.FormValue("commit") != "true" {
+		if _, err := h.plan(c.Context(), h.controls, h.migrations, table, columns, result); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to validate migration"})
+		}
+		return c.JSON(result)
+	}
+
+	// The plan is built inside the transaction so that the links it moves
+	// are the ones there when they are moved
+	user := middleware.GetUserFromContext(c)
+	batchID := uuid.New().String()
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		successors, err := h.plan(c.Context(), h.controls.WithTx(tx), h.migrations.WithTx(tx), table, columns, result)
+		if err != nil {
+			return err
+		}
+		if result.InvalidRows > 0 || result.TotalRows == 0 {
+			return errMigrationInvalid
+		}
+		return h.apply(c.Context(), tx, batchID, target, successors, result, user.UserID)
+	})
+	if errors.Is(err, errMigrationInvalid) {
+		return c.Status(422).JSON(result)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to migrate risk links"})
+	}
+
+	result.BatchID = batchID
+	result.Committed = true
+	return c.Status(201).JSON(result)
+}
+
+// sourceFramework finds the framework to migrate from, by name or ID, or the
+// target's previous version when from is blank. Problems go in errs.
+func (h *FrameworkMigrationHandler) sourceFramework(ctx context.Context, target *models.Framework, from string,
+	errs fieldErrors) (*models.Framework, error) {
+	if from == "" {
+		if target.PreviousVersionID == nil {
+			errs["from"] = "is required when the framework has no previous version"
+			return nil, nil
+		}
+		from = *target.PreviousVersionID
+	}
+
+	frameworks, err := h.frameworks.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, framework := range frameworks {
+		if framework.ID != from && !strings.EqualFold(framework.Name, from) {
+			continue
+		}
+		if framework.ID == target.ID {
+			errs["from"] = "must be a different framework"
+			return nil, nil
+		}
+		return framework, nil
+	}
+	errs["from"] = "framework not found"
+	return nil, nil
+}
+
+// plan fills result with one row per non-blank record, resolving the refs
+// against the two frameworks, and works out where each risk link goes. It
+// returns the new controls of each mapped old control by ID.
+func (h *FrameworkMigrationHandler) plan(ctx context.Context, controls database.FrameworkControlRepository,
+	migrations database.FrameworkMigrationRepository, table *importTable, columns map[string]int,
+	result *models.FrameworkMigrationResult) (map[string][]*models.FrameworkControl, error) {
+	controlsByRef := func(frameworkID string) (map[string]*models.FrameworkControl, []*models.FrameworkControl, error) {
+		list, err := controls.List(ctx, frameworkID, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ControlRef < list[j].ControlRef })
+		byRef := make(map[string]*models.FrameworkControl, len(list))
+		for _, control := range list {
+			byRef[control.ControlRef] = control
+		}
+		return byRef, list, nil
+	}
+	oldByRef, oldControls, err := controlsByRef(result.FromFramework.ID)
+	if err != nil {
+		return nil, err
+	}
+	newByRef, newControls, err := controlsByRef(result.ToFramework.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	successors := map[string][]*models.FrameworkControl{}
+	retired := map[string]int{}
+	mapped := map[string]int{}
+	seen := map[string]int{}
+	result.Rows = []*models.FrameworkMigrationRow{}
+	for i, record := range table.rows {
+		if isBlankRecord(record) {
+			continue
+		}
+		cell := func(field string) string {
+			col := columns[field]
+			if col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+		row := &models.FrameworkMigrationRow{Row: i + 2, OldRef: cell("old_ref"), NewRef: cell("new_ref"), Errors: map[string]string{}}
+
+		old := oldByRef[row.OldRef]
+		switch {
+		case row.OldRef == "":
+			row.Errors["old_ref"] = "is required"
+		case old == nil:
+			row.Errors["old_ref"] = fmt.Sprintf("control not found in %s", result.FromFramework.Name)
+		default:
+			row.OldControlID = old.ID
+		}
+		var successor *models.FrameworkControl
+		if row.NewRef != "" {
+			if successor = newByRef[row.NewRef]; successor == nil {
+				row.Errors["new_ref"] = fmt.Sprintf("control not found in %s", result.ToFramework.Name)
+			} else {
+				row.NewControlID = successor.ID
+			}
+		}
+
+		if old != nil && len(row.Errors) == 0 {
+			pair := old.ID + "|" + row.NewControlID
+			switch {
+			case seen[pair] != 0:
+				row.Errors["new_ref"] = fmt.Sprintf("maps the same controls as row %d", seen[pair])
+			case successor == nil && mapped[old.ID] != 0:
+				row.Errors["new_ref"] = fmt.Sprintf("is blank but row %d maps the control", mapped[old.ID])
+			case successor != nil && retired[old.ID] != 0:
+				row.Errors["new_ref"] = fmt.Sprintf("maps a control row %d retires", retired[old.ID])
+			default:
+				seen[pair] = row.Row
+			}
+		}
+
+		if len(row.Errors) > 0 {
+			result.InvalidRows++
+		} else {
+			result.ValidRows++
+			if successor == nil {
+				retired[old.ID] = row.Row
+			} else {
+				mapped[old.ID] = row.Row
+				successors[old.ID] = append(successors[old.ID], successor)
+			}
+			row.Errors = nil
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	result.TotalRows = len(result.Rows)
+
+	links, err := migrations.ListLinks(ctx, result.FromFramework.ID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := migrations.ListLinks(ctx, result.ToFramework.ID)
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[string]bool, len(existing)+len(links))
+	linkCounts := map[string]int{}
+	for _, link := range existing {
+		linked[link.RiskID+"|"+link.OldControlID] = true
+		linkCounts[link.OldControlID]++
+	}
+	for _, link := range links {
+		linkCounts[link.OldControlID]++
+		for _, control := range successors[link.OldControlID] {
+			link.NewRefs = append(link.NewRefs, control.ControlRef)
+			if key := link.RiskID + "|" + control.ID; !linked[key] {
+				linked[key] = true
+				result.LinksCreated++
+			}
+		}
+		if len(link.NewRefs) == 0 {
+			result.LinksUnmapped++
+		} else {
+			result.LinksMoved++
+		}
+	}
+	result.Links = links
+
+	result.Unmapped = []*models.FrameworkMigrationControl{}
+	for _, control := range oldControls {
+		if len(successors[control.ID]) == 0 {
+			result.Unmapped = append(result.Unmapped, migrationControl(control, linkCounts[control.ID], retired[control.ID] != 0))
+		}
+	}
+	targeted := map[string]bool{}
+	for _, mappedTo := range successors {
+		for _, control := range mappedTo {
+			targeted[control.ID] = true
+		}
+	}
+	result.NewControls = []*models.FrameworkMigrationControl{}
+	for _, control := range newControls {
+		if !targeted[control.ID] {
+			result.NewControls = append(result.NewControls, migrationControl(control, linkCounts[control.ID], false))
+		}
+	}
+	return successors, nil
+}
+
+func migrationControl(control *models.FrameworkControl, links int, retired bool) *models.FrameworkMigrationControl {
+	return &models.FrameworkMigrationControl{
+		ControlID:       control.ID,
+		ControlRef:      control.ControlRef,
+		Title:           control.Title,
+		LinkedRiskCount: links,
+		Retired:         retired,
+	}
+}
+
+// apply moves the links of mapped controls, records the old framework as the
+// target's previous version when it has none, and writes a
+// framework_migration audit entry
+func (h *FrameworkMigrationHandler) apply(ctx context.Context, tx *sql.Tx, batchID string, target *models.Framework,
+	successors map[string][]*models.FrameworkControl, result *models.FrameworkMigrationResult, userID string) error {
+	migrations := h.migrations.WithTx(tx)
+
+	result.LinksCreated = 0
+	for _, link := range result.Links {
+		controls := successors[link.OldControlID]
+		if len(controls) == 0 {
+			continue
+		}
+		ids := make([]string, len(controls))
+		for i, control := range controls {
+			ids[i] = control.ID
+		}
+		created, err := migrations.Repoint(ctx, link.LinkID, ids, userID)
+		if err != nil {
+			return err
+		}
+		result.LinksCreated += created
+	}
+
+	if target.PreviousVersionID == nil {
+		if err := migrations.SetPreviousVersion(ctx, target.ID, result.FromFramework.ID); err != nil {
+			return err
+		}
+	}
+
+	return h.audit.WithTx(tx).Create(ctx, "framework_migration", batchID, models.AuditActionCreated, map[string]any{
+		"from_framework_id": result.FromFramework.ID,
+		"to_framework_id":   result.ToFramework.ID,
+		"filename":          result.Filename,
+		"format":            result.Format,
+		"links_moved":       result.LinksMoved,
+		"links_created":     result.LinksCreated,
+		"links_unmapped":    result.LinksUnmapped,
+	}, userID)
+}
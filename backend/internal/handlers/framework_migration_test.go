@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type mockMigrationLink struct {
+	riskID, riskTitle, controlID string
+}
+
+type mockFrameworkMigrationRepo struct {
+	frameworks *mockFrameworkRepo
+	controls   *mockFrameworkControlRepo
+	links      map[string]*mockMigrationLink
+}
+
+func (m *mockFrameworkMigrationRepo) ListLinks(ctx context.Context, frameworkID string) ([]*models.FrameworkMigrationLink, error) {
+	links := []*models.FrameworkMigrationLink{}
+	for id, link := range m.links {
+		control := m.controls.controls[link.controlID]
+		if control.FrameworkID != frameworkID {
+			continue
+		}
+		links = append(links, &models.FrameworkMigrationLink{LinkID: id, RiskID: link.riskID, RiskTitle: link.riskTitle,
+			OldControlID: control.ID, OldRef: control.ControlRef, NewRefs: []string{}})
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].OldRef != links[j].OldRef {
+			return links[i].OldRef < links[j].OldRef
+		}
+		return links[i].RiskTitle < links[j].RiskTitle
+	})
+	return links, nil
+}
+
+func (m *mockFrameworkMigrationRepo) linked(riskID, controlID string) bool {
+	for _, link := range m.links {
+		if link.riskID == riskID && link.controlID == controlID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mockFrameworkMigrationRepo) Repoint(ctx context.Context, linkID string, controlIDs []string, userID string) (int, error) {
+	link, ok := m.links[linkID]
+	if !ok {
+		return 0, database.ErrFrameworkLinkNotFound
+	}
+	created := 0
+	for _, controlID := range controlIDs {
+		if !m.linked(link.riskID, controlID) {
+			m.links[uuid.New().String()] = &mockMigrationLink{riskID: link.riskID, riskTitle: link.riskTitle, controlID: controlID}
+			created++
+		}
+	}
+	delete(m.links, linkID)
+	return created, nil
+}
+
+func (m *mockFrameworkMigrationRepo) SetPreviousVersion(ctx context.Context, frameworkID, previousID string) error {
+	framework, ok := m.frameworks.frameworks[frameworkID]
+	if !ok {
+		return database.ErrFrameworkNotFound
+	}
+	if framework.PreviousVersionID == nil {
+		framework.PreviousVersionID = &previousID
+	}
+	return nil
+}
+
+func (m *mockFrameworkMigrationRepo) WithTx(tx *sql.Tx) database.FrameworkMigrationRepository {
+	return m
+}
+
+func TestFrameworkMigrationHandler(t *testing.T) {
+	newApp := func() (*fiber.App, *mockFrameworkRepo, *mockFrameworkMigrationRepo, *mockAuditRepo) {
+		frameworkRepo := &mockFrameworkRepo{frameworks: map[string]*models.Framework{
+			"old-id": {ID: "old-id", Name: "ISO 27001:2013", Version: "2013"},
+			"new-id": {ID: "new-id", Name: "ISO 27001:2022", Version: "2022"},
+		}}
+		controlRepo := &mockFrameworkControlRepo{controls: map[string]*models.FrameworkControl{}, inUse: map[string]bool{}}
+		for _, control := range []*models.FrameworkControl{
+			{ID: "a5-1-1", FrameworkID: "old-id", ControlRef: "A.5.1.1", Title: "Policies for information security"},
+			{ID: "a5-1-2", FrameworkID: "old-id", ControlRef: "A.5.1.2", Title: "Review of the policies"},
+			{ID: "a6-1-5", FrameworkID: "old-id", ControlRef: "A.6.1.5", Title: "Information security in project management"},
+			{ID: "a12-1-2", FrameworkID: "old-id", ControlRef: "A.12.1.2", Title: "Change management"},
+			{ID: "a14-2-1", FrameworkID: "old-id", ControlRef: "A.14.2.1", Title: "Secure development policy"},
+			{ID: "5-1", FrameworkID: "new-id", ControlRef: "5.1", Title: "Policies for information security"},
+			{ID: "5-8", FrameworkID: "new-id", ControlRef: "5.8", Title: "Information security in project management"},
+			{ID: "8-9", FrameworkID: "new-id", ControlRef: "8.9", Title: "Configuration management"},
+			{ID: "8-32", FrameworkID: "new-id", ControlRef: "8.32", Title: "Change management"},
+		} {
+			controlRepo.controls[control.ID] = control
+		}
+		migrationRepo := &mockFrameworkMigrationRepo{frameworks: frameworkRepo, controls: controlRepo, links: map[string]*mockMigrationLink{
+			"link-1": {riskID: "risk-1", riskTitle: "Outdated policies", controlID: "a5-1-1"},
+			"link-2": {riskID: "risk-1", riskTitle: "Outdated policies", controlID: "a5-1-2"},
+			"link-3": {riskID: "risk-2", riskTitle: "Unreviewed changes", controlID: "a12-1-2"},
+			"link-4": {riskID: "risk-2", riskTitle: "Unreviewed changes", controlID: "8-9"},
+			"link-5": {riskID: "risk-3", riskTitle: "Insecure code", controlID: "a14-2-1"},
+		}}
+		auditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+		handler := NewFrameworkMigrationHandler(&mockTransactor{}, frameworkRepo, controlRepo, migrationRepo, auditRepo)
+
+		app := fiber.New()
+		app.Post("/frameworks/:id/migrate", testAuthMiddleware, handler.Migrate)
+		return app, frameworkRepo, migrationRepo, auditRepo
+	}
+	upload := func(app *fiber.App, frameworkID string, content []byte, fields map[string]string) (int, *models.FrameworkMigrationResult, string) {
+		body := new(bytes.Buffer)
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile("file", "mapping.csv")
+		part.Write(content)
+		for k, v := range fields {
+			w.WriteField(k, v)
+		}
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/frameworks/"+frameworkID+"/migrate", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw := new(bytes.Buffer)
+		raw.ReadFrom(resp.Body)
+		var result models.FrameworkMigrationResult
+		json.Unmarshal(raw.Bytes(), &result)
+		return resp.StatusCode, &result, raw.String()
+	}
+	controlsOf := func(repo *mockFrameworkMigrationRepo, riskID string) map[string]bool {
+		controls := map[string]bool{}
+		for _, link := range repo.links {
+			if link.riskID == riskID {
+				controls[link.controlID] = true
+			}
+		}
+		return controls
+	}
+	from := map[string]string{"from": "iso 27001:2013"}
+	commit := map[string]string{"from": "old-id", "commit": "true"}
+
+	// A.5.1.1 and A.5.1.2 merge into 5.1, A.12.1.2 splits into 8.9 and 8.32,
+	// A.6.1.5 is retired and A.14.2.1 is left out
+	csvFile := []byte("Old,New\n" +
+		"A.5.1.1,5.1\n" +
+		"A.5.1.2,5.1\n" +
+		",\n" +
+		"A.12.1.2,8.9\n" +
+		"A.12.1.2,8.32\n" +
+		"A.6.1.5,\n")
+
+	t.Run("dry run reports moves and unmapped controls", func(t *testing.T) {
+		app, _, migrationRepo, auditRepo := newApp()
+		status, result, raw := upload(app, "new-id", csvFile, from)
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, raw)
+		}
+		if result.Committed || len(migrationRepo.links) != 5 || len(auditRepo.logs) != 0 {
+			t.Error("dry run must not move links")
+		}
+		if result.FromFramework.ID != "old-id" || result.ToFramework.Version != "2022" {
+			t.Errorf("unexpected frameworks: %s", raw)
+		}
+		if result.TotalRows != 5 || result.ValidRows != 5 || result.InvalidRows != 0 {
+			t.Errorf("expected 5 valid rows, got %s", raw)
+		}
+		if result.LinksMoved != 3 || result.LinksCreated != 2 || result.LinksUnmapped != 1 {
+			t.Errorf("expected 3 moved, 2 created and 1 unmapped link, got %s", raw)
+		}
+		if len(result.Links) != 4 || result.Links[0].OldRef != "A.12.1.2" || len(result.Links[0].NewRefs) != 2 {
+			t.Errorf("expected A.12.1.2 to split into two controls, got %s", raw)
+		}
+
+		if len(result.Unmapped) != 2 {
+			t.Fatalf("expected 2 unmapped controls, got %s", raw)
+		}
+		left, retired := result.Unmapped[0], result.Unmapped[1]
+		if left.ControlRef != "A.14.2.1" || left.Retired || left.LinkedRiskCount != 1 {
+			t.Errorf("expected A.14.2.1 to be flagged with its link, got %+v", left)
+		}
+		if retired.ControlRef != "A.6.1.5" || !retired.Retired {
+			t.Errorf("expected A.6.1.5 to be retired, got %+v", retired)
+		}
+		if len(result.NewControls) != 1 || result.NewControls[0].ControlRef != "5.8" {
+			t.Errorf("expected 5.8 to be the only new control, got %s", raw)
+		}
+	})
+
+	t.Run("commit repoints links and records the previous version", func(t *testing.T) {
+		app, frameworkRepo, migrationRepo, auditRepo := newApp()
+		status, result, raw := upload(app, "new-id", csvFile, commit)
+		if status != 201 {
+			t.Fatalf("expected status 201, got %d: %s", status, raw)
+		}
+		if !result.Committed || result.BatchID == "" || result.LinksCreated != 2 {
+			t.Errorf("expected a committed batch creating 2 links, got %s", raw)
+		}
+
+		expected := map[string][]string{"risk-1": {"5-1"}, "risk-2": {"8-9", "8-32"}, "risk-3": {"a14-2-1"}}
+		for riskID, controlIDs := range expected {
+			controls := controlsOf(migrationRepo, riskID)
+			if len(controls) != len(controlIDs) {
+				t.Errorf("%s: expected links to %v, got %v", riskID, controlIDs, controls)
+			}
+			for _, id := range controlIDs {
+				if !controls[id] {
+					t.Errorf("%s: expected a link to %s, got %v", riskID, id, controls)
+				}
+			}
+		}
+
+		previous := frameworkRepo.frameworks["new-id"].PreviousVersionID
+		if previous == nil || *previous != "old-id" {
+			t.Errorf("expected old-id to become the previous version, got %v", previous)
+		}
+		if len(auditRepo.logs) != 1 || auditRepo.logs[0].EntityType != "framework_migration" || auditRepo.logs[0].EntityID != result.BatchID {
+			t.Errorf("expected one framework_migration audit entry, got %+v", auditRepo.logs)
+		}
+	})
+
+	t.Run("from defaults to the previous version", func(t *testing.T) {
+		app, frameworkRepo, _, _ := newApp()
+		if status, _, raw := upload(app, "new-id", csvFile, nil); status != 400 {
+			t.Errorf("expected status 400 without a previous version, got %d: %s", status, raw)
+		}
+
+		previous := "old-id"
+		frameworkRepo.frameworks["new-id"].PreviousVersionID = &previous
+		status, result, raw := upload(app, "new-id", csvFile, nil)
+		if status != 200 || result.FromFramework.ID != "old-id" {
+			t.Errorf("expected to migrate from old-id, got %d: %s", status, raw)
+		}
+	})
+
+	t.Run("invalid rows block the commit", func(t *testing.T) {
+		app, _, migrationRepo, _ := newApp()
+		invalid := []byte("old_ref,new_ref\n" +
+			"A.5.1.1,5.1\n" +
+			"A.9.9.9,5.1\n" +
+			"A.5.1.2,9.99\n" +
+			"A.5.1.1,5.1\n" +
+			"A.5.1.1,\n" +
+			",5.8\n")
+		status, result, raw := upload(app, "new-id", invalid, from)
+		if status != 200 || result.ValidRows != 1 || result.InvalidRows != 5 {
+			t.Fatalf("expected 1 valid and 5 invalid rows, got %d: %s", status, raw)
+		}
+		for i, field := range []string{"old_ref", "new_ref", "new_ref", "new_ref", "old_ref"} {
+			if result.Rows[i+1].Errors[field] == "" {
+				t.Errorf("row %d: expected a %s error, got %+v", result.Rows[i+1].Row, field, result.Rows[i+1].Errors)
+			}
+		}
+
+		status, _, raw = upload(app, "new-id", invalid, commit)
+		if status != 422 || len(migrationRepo.links) != 5 {
+			t.Errorf("expected status 422 and no moves, got %d: %s", status, raw)
+		}
+	})
+
+	t.Run("rejects bad requests", func(t *testing.T) {
+		app, _, _, _ := newApp()
+		if status, _, raw := upload(app, "missing-id", csvFile, from); status != 404 {
+			t.Errorf("expected status 404 for an unknown framework, got %d: %s", status, raw)
+		}
+		if status, _, raw := upload(app, "new-id", csvFile, map[string]string{"from": "new-id"}); status != 400 {
+			t.Errorf("expected status 400 migrating a framework to itself, got %d: %s", status, raw)
+		}
+		if status, _, raw := upload(app, "new-id", csvFile, map[string]string{"from": "NIST CSF"}); status != 400 {
+			t.Errorf("expected status 400 for an unknown source framework, got %d: %s", status, raw)
+		}
+		if status, _, raw := upload(app, "new-id", []byte("Old,Title\nA.5.1.1,Policies\n"), from); status != 400 {
+			t.Errorf("expected status 400 without a new_ref column, got %d: %s", status, raw)
+		}
+		if status, _, raw := upload(app, "new-id", []byte("Old,New\n"), commit); status != 422 {
+			t.Errorf("expected status 422 committing an empty mapping, got %d: %s", status, raw)
+		}
+	})
+}
@@ -1,21 +1,26 @@
 package handlers
 
 import (
+	"database/sql"
 	"errors"
+	"strings"
 
 	"backend/internal/database"
 	"backend/internal/models"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type FrameworkHandler struct {
 	frameworkRepo database.FrameworkRepository
+	cleanup       *AttachmentCleanup
 }
 
-func NewFrameworkHandler(frameworkRepo database.FrameworkRepository) *FrameworkHandler {
+func NewFrameworkHandler(frameworkRepo database.FrameworkRepository, cleanup *AttachmentCleanup) *FrameworkHandler {
 	return &FrameworkHandler{
 		frameworkRepo: frameworkRepo,
+		cleanup:       cleanup,
 	}
 }
 
@@ -39,9 +44,19 @@ func (h *FrameworkHandler) Create(c *fiber.Ctx) error {
 	if input.Name == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
 	}
+	if input.PreviousVersionID != nil && *input.PreviousVersionID == "" {
+		input.PreviousVersionID = nil
+	}
+	input.Version = strings.TrimSpace(input.Version)
+	if msg := validateFrameworkVersion("", &input.Version, input.PreviousVersionID); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
 
 	framework, err := h.frameworkRepo.Create(c.Context(), &input)
 	if err != nil {
+		if errors.Is(err, database.ErrFrameworkPreviousVersionNotFound) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to create framework"})
 	}
 	return c.Status(201).JSON(framework)
@@ -59,31 +74,48 @@ func (h *FrameworkHandler) Update(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
-	if input.Name == nil && input.Description == nil {
+	if input.Name == nil && input.Description == nil && input.Version == nil && input.PreviousVersionID == nil {
 		return c.Status(400).JSON(fiber.Map{"error": "at least one field must be provided"})
 	}
 	if input.Name != nil && *input.Name == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "name cannot be empty"})
 	}
+	if input.Version != nil {
+		trimmed := strings.TrimSpace(*input.Version)
+		input.Version = &trimmed
+	}
+	previous := input.PreviousVersionID
+	if previous != nil && *previous == "" {
+		previous = nil
+	}
+	if msg := validateFrameworkVersion(id, input.Version, previous); msg != "" {
+		return c.Status(400).JSON(fiber.Map{"error": msg})
+	}
 
 	framework, err := h.frameworkRepo.Update(c.Context(), id, &input)
 	if err != nil {
 		if errors.Is(err, database.ErrFrameworkNotFound) {
 			return c.Status(404).JSON(fiber.Map{"error": "framework not found"})
 		}
+		if errors.Is(err, database.ErrFrameworkPreviousVersionNotFound) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to update framework"})
 	}
 	return c.JSON(framework)
 }
 
-// Delete deletes a framework (admin only)
+// Delete deletes a framework with its controls (admin only)
 func (h *FrameworkHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
 	if id == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "framework id required"})
 	}
 
-	if err := h.frameworkRepo.Delete(c.Context(), id); err != nil {
+	err := h.cleanup.Delete(c.Context(), func(tx *sql.Tx) error {
+		return h.frameworkRepo.WithTx(tx).Delete(c.Context(), id)
+	}, models.AttachmentFrameworkControl)
+	if err != nil {
 		if errors.Is(err, database.ErrFrameworkNotFound) {
 			return c.Status(404).JSON(fiber.Map{"error": "framework not found"})
 		}
@@ -92,6 +124,23 @@ func (h *FrameworkHandler) Delete(c *fiber.Ctx) error {
 	return c.SendStatus(204)
 }
 
+// validateFrameworkVersion checks the version label and the framework a
+// revision supersedes, returning a message for the first problem found
+func validateFrameworkVersion(id string, version, previousVersionID *string) string {
+	if version != nil && len(*version) > 50 {
+		return "version must be at most 50 characters"
+	}
+	if previousVersionID != nil {
+		if uuid.Validate(*previousVersionID) != nil {
+			return "previous_version_id must be a UUID"
+		}
+		if *previousVersionID == id {
+			return "a framework cannot be its own previous version"
+		}
+	}
+	return ""
+}
+
 func mapFrameworkControlError(c *fiber.Ctx, err error, defaultMessage string) error {
 	if errors.Is(err, database.ErrFrameworkControlNotFound) {
 		return c.Status(404).JSON(fiber.Map{"error": "control not found"})
@@ -99,5 +148,8 @@ func mapFrameworkControlError(c *fiber.Ctx, err error, defaultMessage string) er
 	if errors.Is(err, database.ErrFrameworkControlInUse) {
 		return c.Status(409).JSON(fiber.Map{"error": "control is linked to one or more risks"})
 	}
+	if errors.Is(err, database.ErrFrameworkControlParent) {
+		return validationFailed(c, fieldErrors{"parent_id": err.Error()})
+	}
 	return c.Status(500).JSON(fiber.Map{"error": defaultMessage})
 }
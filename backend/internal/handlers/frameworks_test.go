@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http/httptest"
 	"testing"
@@ -35,12 +36,17 @@ func (m *mockFrameworkRepo) GetByID(ctx context.Context, id string) (*models.Fra
 }
 
 func (m *mockFrameworkRepo) Create(ctx context.Context, input *models.CreateFrameworkInput) (*models.Framework, error) {
+	if input.PreviousVersionID != nil && m.frameworks[*input.PreviousVersionID] == nil {
+		return nil, database.ErrFrameworkPreviousVersionNotFound
+	}
 	framework := &models.Framework{
-		ID:          uuid.New().String(),
-		Name:        input.Name,
-		Description: input.Description,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:                uuid.New().String(),
+		Name:              input.Name,
+		Description:       input.Description,
+		Version:           input.Version,
+		PreviousVersionID: input.PreviousVersionID,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
 	}
 	m.frameworks[framework.ID] = framework
 	return framework, nil
@@ -57,6 +63,19 @@ func (m *mockFrameworkRepo) Update(ctx context.Context, id string, input *models
 	if input.Description != nil {
 		framework.Description = *input.Description
 	}
+	if input.Version != nil {
+		framework.Version = *input.Version
+	}
+	if input.PreviousVersionID != nil {
+		switch previous := *input.PreviousVersionID; {
+		case previous == "":
+			framework.PreviousVersionID = nil
+		case m.frameworks[previous] == nil:
+			return nil, database.ErrFrameworkPreviousVersionNotFound
+		default:
+			framework.PreviousVersionID = &previous
+		}
+	}
 	framework.UpdatedAt = time.Now()
 	m.frameworks[id] = framework
 	return framework, nil
@@ -70,6 +89,10 @@ func (m *mockFrameworkRepo) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (m *mockFrameworkRepo) WithTx(tx *sql.Tx) database.FrameworkRepository {
+	return m
+}
+
 type mockControlRepo struct {
 	controls       map[string]*models.RiskFrameworkControl
 	definitionRepo *mockFrameworkControlRepo
@@ -117,6 +140,8 @@ func (m *mockControlRepo) UnlinkControl(ctx context.Context, id string) error {
 
 type mockFrameworkControlRepo struct {
 	controls map[string]*models.FrameworkControl
+	// inUse holds the IDs of controls linked to risks, which can't be deleted
+	inUse map[string]bool
 }
 
 func (m *mockFrameworkControlRepo) List(ctx context.Context, frameworkID, search string) ([]*models.FrameworkControl, error) {
@@ -173,6 +198,18 @@ func (m *mockFrameworkControlRepo) Update(ctx context.Context, id string, input
 	if input.Description != nil {
 		control.Description = *input.Description
 	}
+	if input.ParentID != nil {
+		if *input.ParentID == id || (*input.ParentID != "" && m.controls[*input.ParentID] == nil) {
+			return nil, database.ErrFrameworkControlParent
+		}
+		control.ParentID = input.ParentID
+		if *input.ParentID == "" {
+			control.ParentID = nil
+		}
+	}
+	if input.SortOrder != nil {
+		control.SortOrder = *input.SortOrder
+	}
 	control.UpdatedAt = time.Now()
 	return control, nil
 }
@@ -181,14 +218,83 @@ func (m *mockFrameworkControlRepo) Delete(ctx context.Context, id string) error
 	if _, ok := m.controls[id]; !ok {
 		return database.ErrFrameworkControlNotFound
 	}
+	if m.inUse[id] {
+		return database.ErrFrameworkControlInUse
+	}
 	delete(m.controls, id)
 	return nil
 }
 
+// Tree nests the framework's controls without rolling up counts
+func (m *mockFrameworkControlRepo) Tree(ctx context.Context, frameworkID string) ([]*models.ControlTreeNode, error) {
+	nodes := map[string]*models.ControlTreeNode{}
+	for _, control := range m.controls {
+		if control.FrameworkID == frameworkID {
+			nodes[control.ID] = &models.ControlTreeNode{FrameworkControl: control, Children: []*models.ControlTreeNode{}}
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, database.ErrFrameworkNotFound
+	}
+	roots := []*models.ControlTreeNode{}
+	for _, node := range nodes {
+		if node.ParentID != nil && nodes[*node.ParentID] != nil {
+			nodes[*node.ParentID].Children = append(nodes[*node.ParentID].Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots, nil
+}
+
+// Coverage counts a control as covered when it has linked risks
+func (m *mockFrameworkControlRepo) Coverage(ctx context.Context, frameworkID string) (*models.FrameworkCoverage, error) {
+	coverage := &models.FrameworkCoverage{FrameworkID: frameworkID, FrameworkName: "Mock", GeneratedAt: time.Now(),
+		Families: []*models.CoverageFamily{}, Unlinked: []*models.CoverageControl{}, InactiveOnly: []*models.CoverageControl{}}
+	for _, control := range m.controls {
+		if control.FrameworkID != frameworkID {
+			continue
+		}
+		entry := &models.CoverageControl{ID: control.ID, ControlRef: control.ControlRef, Title: control.Title,
+			Status: models.ControlCovered, LinkedRiskCount: control.LinkedRiskCount, ActiveRiskCount: control.LinkedRiskCount}
+		coverage.TotalControls++
+		if control.LinkedRiskCount > 0 {
+			coverage.LinkedControls++
+		} else {
+			entry.Status = models.ControlUnlinked
+			coverage.UnlinkedControls++
+			coverage.Unlinked = append(coverage.Unlinked, entry)
+		}
+		coverage.Controls = append(coverage.Controls, entry)
+	}
+	if coverage.TotalControls == 0 {
+		return nil, database.ErrFrameworkNotFound
+	}
+	return coverage, nil
+}
+
+func (m *mockFrameworkControlRepo) Upsert(ctx context.Context, control *models.FrameworkControl) error {
+	for _, existing := range m.controls {
+		if existing.FrameworkID == control.FrameworkID && existing.ControlRef == control.ControlRef {
+			control.ID = existing.ID
+		}
+	}
+	if control.ID == "" {
+		control.ID = uuid.New().String()
+	}
+	copied := *control
+	m.controls[control.ID] = &copied
+	return nil
+}
+
+func (m *mockFrameworkControlRepo) WithTx(tx *sql.Tx) database.FrameworkControlRepository {
+	return m
+}
+
 func TestFrameworkHandler(t *testing.T) {
 	app := fiber.New()
 	mockFwRepo := &mockFrameworkRepo{frameworks: make(map[string]*models.Framework)}
-	handler := NewFrameworkHandler(mockFwRepo)
+	handler := NewFrameworkHandler(mockFwRepo, newTestAttachmentCleanup())
 
 	// Setup routes
 	app.Get("/frameworks", testAuthMiddleware, handler.List)
@@ -239,6 +345,49 @@ func TestFrameworkHandler(t *testing.T) {
 			t.Errorf("expected 1 framework, got %d", len(response["data"]))
 		}
 	})
+
+	t.Run("Framework Versions", func(t *testing.T) {
+		old := &models.Framework{ID: uuid.New().String(), Name: "ISO 27001:2013", Version: "2013"}
+		mockFwRepo.frameworks[old.ID] = old
+		send := func(method, path string, body any) (int, *models.Framework) {
+			raw, _ := json.Marshal(body)
+			req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			var framework models.Framework
+			json.NewDecoder(resp.Body).Decode(&framework)
+			return resp.StatusCode, &framework
+		}
+
+		status, created := send("POST", "/frameworks", map[string]any{
+			"name": "ISO 27001:2022", "version": " 2022 ", "previous_version_id": old.ID,
+		})
+		if status != 201 || created.Version != "2022" || created.PreviousVersionID == nil || *created.PreviousVersionID != old.ID {
+			t.Fatalf("expected a 2022 revision of %s, got %d: %+v", old.ID, status, created)
+		}
+
+		missing := uuid.New().String()
+		for _, tc := range []struct {
+			name string
+			body map[string]any
+		}{
+			{"unknown previous version", map[string]any{"previous_version_id": missing}},
+			{"malformed previous version", map[string]any{"previous_version_id": "2013"}},
+			{"own previous version", map[string]any{"previous_version_id": created.ID}},
+		} {
+			if status, _ := send("PUT", "/frameworks/"+created.ID, tc.body); status != 400 {
+				t.Errorf("%s: expected status 400, got %d", tc.name, status)
+			}
+		}
+
+		status, updated := send("PUT", "/frameworks/"+created.ID, map[string]any{"previous_version_id": ""})
+		if status != 200 || updated.PreviousVersionID != nil || updated.Version != "2022" {
+			t.Errorf("expected the previous version to be cleared, got %d: %+v", status, updated)
+		}
+	})
 }
 
 func TestControlHandler(t *testing.T) {
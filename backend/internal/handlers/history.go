@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"reflect"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HistoryHandler reconstructs risks and incidents from their audit trail.
+//
+// Replay relies on the audit payload convention used by the risk and incident
+// handlers: a "created" entry holds a full snapshot of the entity's fields and
+// every "updated" entry holds {"from": ..., "to": ...} pairs for the fields it
+// touched. Keys that are not from/to pairs (such as link_risk events) describe
+// relationships rather than fields and are skipped during replay. Entries
+// written before the convention are brought into line by normalizeHistory.
+type HistoryHandler struct {
+	audit database.AuditLogRepository
+}
+
+func NewHistoryHandler(audit database.AuditLogRepository) *HistoryHandler {
+	return &HistoryHandler{audit: audit}
+}
+
+// RiskAsOf returns a risk as it looked at the as_of timestamp
+func (h *HistoryHandler) RiskAsOf(c *fiber.Ctx) error {
+	return h.asOf(c, "risk")
+}
+
+// IncidentAsOf returns an incident as it looked at the as_of timestamp
+func (h *HistoryHandler) IncidentAsOf(c *fiber.Ctx) error {
+	return h.asOf(c, "incident")
+}
+
+// RiskDiff returns the fields of a risk that changed between two timestamps
+func (h *HistoryHandler) RiskDiff(c *fiber.Ctx) error {
+	return h.diff(c, "risk")
+}
+
+// IncidentDiff returns the fields of an incident that changed between two timestamps
+func (h *HistoryHandler) IncidentDiff(c *fiber.Ctx) error {
+	return h.diff(c, "incident")
+}
+
+func (h *HistoryHandler) asOf(c *fiber.Ctx, entityType string) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(400).JSON(fiber.Map{"error": entityType + " id required"})
+	}
+
+	asOf, err := parseHistoryTime(c.Query("as_of"), time.Now())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "as_of must be an RFC 3339 timestamp"})
+	}
+
+	snapshot, err := h.snapshot(c, entityType, id, asOf)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch " + entityType + " history"})
+	}
+	if snapshot == nil {
+		return c.Status(404).JSON(fiber.Map{"error": entityType + " did not exist at as_of"})
+	}
+
+	return c.JSON(snapshot)
+}
+
+func (h *HistoryHandler) diff(c *fiber.Ctx, entityType string) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(400).JSON(fiber.Map{"error": entityType + " id required"})
+	}
+
+	if c.Query("from") == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "from is required"})
+	}
+	from, err := parseHistoryTime(c.Query("from"), time.Time{})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "from must be an RFC 3339 timestamp"})
+	}
+	to, err := parseHistoryTime(c.Query("to"), time.Now())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "to must be an RFC 3339 timestamp"})
+	}
+	if to.Before(from) {
+		return c.Status(400).JSON(fiber.Map{"error": "to must not be before from"})
+	}
+
+	before, err := h.snapshot(c, entityType, id, from)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch " + entityType + " history"})
+	}
+	after, err := h.snapshot(c, entityType, id, to)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch " + entityType + " history"})
+	}
+	if before == nil && after == nil {
+		return c.Status(404).JSON(fiber.Map{"error": entityType + " did not exist in the requested range"})
+	}
+
+	var beforeState, afterState map[string]any
+	if before != nil {
+		beforeState = before.State
+	}
+	if after != nil {
+		afterState = after.State
+	}
+
+	return c.JSON(&models.HistoryDiff{
+		EntityType: entityType,
+		EntityID:   id,
+		From:       from,
+		To:         to,
+		Changes:    diffHistoryStates(beforeState, afterState),
+	})
+}
+
+func (h *HistoryHandler) snapshot(c *fiber.Ctx, entityType, id string, asOf time.Time) (*models.HistorySnapshot, error) {
+	logs, err := h.audit.ListHistory(c.Context(), entityType, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := replayHistory(entityType, logs)
+	if snapshot == nil {
+		return nil, nil
+	}
+	snapshot.EntityType = entityType
+	snapshot.EntityID = id
+	snapshot.AsOf = asOf
+	return snapshot, nil
+}
+
+// replayHistory folds audit entries (oldest first) into the entity state they
+// describe. It returns nil when the entries contain no trace of the entity.
+func replayHistory(entityType string, logs []*models.AuditLog) *models.HistorySnapshot {
+	fields := historyFields[entityType]
+	var snapshot *models.HistorySnapshot
+
+	for _, log := range logs {
+		if snapshot == nil {
+			snapshot = &models.HistorySnapshot{State: map[string]any{}}
+		}
+
+		switch log.Action {
+		case models.AuditActionCreated:
+			snapshot.State = map[string]any{}
+			snapshot.Deleted = false
+			for field := range fields {
+				snapshot.State[field] = nil
+			}
+			for field, value := range log.Changes {
+				snapshot.State[field] = normalizeHistory(fields[field], value)
+			}
+		case models.AuditActionUpdated:
+			for field, value := range log.Changes {
+				change, ok := value.(map[string]any)
+				if !ok {
+					continue
+				}
+				if to, ok := change["to"]; ok {
+					snapshot.State[field] = normalizeHistory(fields[field], to)
+				}
+			}
+		case models.AuditActionDeleted, models.AuditActionPurged:
+			snapshot.Deleted = true
+		case models.AuditActionRestored:
+			snapshot.Deleted = false
+		}
+
+		snapshot.LastModifiedAt = log.CreatedAt
+		snapshot.LastModifiedBy = log.UserID
+	}
+
+	return snapshot
+}
+
+// historyFormat is how a field's value is recorded in the audit log
+type historyFormat int
+
+const (
+	historyValue historyFormat = iota
+	// historyOptional values are null when unset
+	historyOptional
+	// historyDate values are YYYY-MM-DD dates or null
+	historyDate
+	// historyTime values are RFC 3339 timestamps in UTC or null
+	historyTime
+)
+
+// historyFields are the fields of each entity's snapshot. Older "created"
+// entries lack some of them, and older entries recorded unset values as ""
+// and dates as full timestamps.
+var historyFields = map[string]map[string]historyFormat{
+	"risk": {
+		"title": historyValue, "description": historyValue, "owner_id": historyValue, "status": historyValue,
+		"severity": historyValue, "category_id": historyOptional, "review_date": historyDate, "tags": historyValue,
+	},
+	"incident": {
+		"title": historyValue, "description": historyValue, "category_id": historyOptional, "priority": historyValue,
+		"status": historyValue, "assignee_id": historyOptional, "reporter_id": historyValue,
+		"service_affected": historyValue, "root_cause": historyValue, "resolution_notes": historyValue,
+		"occurred_at": historyTime, "detected_at": historyTime, "resolved_at": historyTime, "tags": historyValue,
+	},
+}
+
+// normalizeHistory rewrites a recorded value in the current audit format.
+// Values it can't parse are kept as they are.
+func normalizeHistory(format historyFormat, value any) any {
+	s, ok := value.(string)
+	if !ok || format == historyValue {
+		return value
+	}
+	if s == "" {
+		return nil
+	}
+	switch format {
+	case historyDate:
+		if _, err := time.Parse("2006-01-02", s); err == nil {
+			return s
+		}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t.Format("2006-01-02")
+		}
+	case historyTime:
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return value
+}
+
+// diffHistoryStates returns every field whose value differs between two replayed states
+func diffHistoryStates(before, after map[string]any) map[string]models.FieldChange {
+	changes := make(map[string]models.FieldChange)
+	for field, from := range before {
+		if to := after[field]; !reflect.DeepEqual(from, to) {
+			changes[field] = models.FieldChange{From: from, To: to}
+		}
+	}
+	for field, to := range after {
+		if _, seen := before[field]; !seen && to != nil {
+			changes[field] = models.FieldChange{From: nil, To: to}
+		}
+	}
+	return changes
+}
+
+func parseHistoryTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// auditChange builds the from/to pair recorded for an updated field
+func auditChange(from, to any) map[string]any {
+	return map[string]any{"from": from, "to": to}
+}
+
+// auditString normalizes an optional string so a missing value is recorded as null
+func auditString(s *string) any {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// auditDate normalizes an optional calendar date to YYYY-MM-DD or null
+func auditDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+// auditTime normalizes an optional timestamp to RFC 3339 or null
+func auditTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// riskAuditSnapshot returns the full normalized state recorded when a risk is created
+func riskAuditSnapshot(risk *models.Risk) map[string]any {
+	return map[string]any{
+		"title":       risk.Title,
+		"description": risk.Description,
+		"owner_id":    risk.OwnerID,
+		"status":      string(risk.Status),
+		"severity":    string(risk.Severity),
+		"category_id": auditString(risk.CategoryID),
+		"review_date": auditDate(risk.ReviewDate),
+		"tags":        auditTags(risk.Tags),
+	}
+}
+
+// incidentAuditSnapshot returns the full normalized state recorded when an incident is created
+func incidentAuditSnapshot(incident *models.Incident) map[string]any {
+	return map[string]any{
+		"title":            incident.Title,
+		"description":      incident.Description,
+		"category_id":      auditString(incident.CategoryID),
+		"priority":         string(incident.Priority),
+		"status":           string(incident.Status),
+		"assignee_id":      auditString(incident.AssigneeID),
+		"reporter_id":      incident.ReporterID,
+		"service_affected": incident.ServiceAffected,
+		"root_cause":       incident.RootCause,
+		"resolution_notes": incident.ResolutionNotes,
+		"occurred_at":      auditTime(&incident.OccurredAt),
+		"detected_at":      auditTime(&incident.DetectedAt),
+		"resolved_at":      auditTime(incident.ResolvedAt),
+		"tags":             auditTags(incident.Tags),
+	}
+}
+
+// mitigationAuditSnapshot returns the full normalized state recorded when a mitigation is created
+func mitigationAuditSnapshot(m *models.Mitigation) map[string]any {
+	return map[string]any{
+		"risk_id":     m.RiskID,
+		"description": m.Description,
+		"owner":       m.Owner,
+		"status":      string(m.Status),
+		"due_date":    auditDate(m.DueDate),
+	}
+}
+
+// mitigationAuditChanges returns from/to pairs for the fields that differ
+// between two versions of a mitigation
+func mitigationAuditChanges(before, after *models.Mitigation) map[string]any {
+	changes := make(map[string]any)
+	from, to := mitigationAuditSnapshot(before), mitigationAuditSnapshot(after)
+	for field, value := range to {
+		if from[field] != value {
+			changes[field] = auditChange(from[field], value)
+		}
+	}
+	return changes
+}
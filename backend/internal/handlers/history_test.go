@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestHistoryHandler(t *testing.T) {
+	app := fiber.New()
+	mockRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewHistoryHandler(mockRepo)
+
+	riskID := uuid.New().String()
+	created := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	escalated := created.Add(24 * time.Hour)
+	linked := escalated.Add(time.Hour)
+	resolved := escalated.Add(48 * time.Hour)
+
+	mockRepo.logs = append(mockRepo.logs,
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "risk", EntityID: riskID,
+			Action: models.AuditActionCreated, UserID: "user1", CreatedAt: created,
+			Changes: map[string]any{
+				"title":       "Vendor outage",
+				"status":      "open",
+				"severity":    "medium",
+				"category_id": nil,
+				"review_date": nil,
+			},
+		},
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "risk", EntityID: riskID,
+			Action: models.AuditActionUpdated, UserID: "user2", CreatedAt: escalated,
+			Changes: map[string]any{
+				"severity":    map[string]any{"from": "medium", "to": "critical"},
+				"review_date": map[string]any{"from": nil, "to": "2026-02-01"},
+			},
+		},
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "risk", EntityID: riskID,
+			Action: models.AuditActionUpdated, UserID: "user2", CreatedAt: linked,
+			Changes: map[string]any{"action": "link_risk", "risk_id": "other"},
+		},
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "risk", EntityID: riskID,
+			Action: models.AuditActionUpdated, UserID: "user3", CreatedAt: resolved,
+			Changes: map[string]any{
+				"status": map[string]any{"from": "open", "to": "resolved"},
+			},
+		},
+	)
+
+	app.Get("/risks/:id/history", handler.RiskAsOf)
+	app.Get("/risks/:id/history/diff", handler.RiskDiff)
+
+	t.Run("As Of Between Updates", func(t *testing.T) {
+		asOf := escalated.Add(time.Minute).Format(time.RFC3339)
+		req := httptest.NewRequest("GET", "/risks/"+riskID+"/history?as_of="+url.QueryEscape(asOf), nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+
+		var snapshot models.HistorySnapshot
+		if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if snapshot.State["severity"] != "critical" {
+			t.Errorf("expected severity critical, got %v", snapshot.State["severity"])
+		}
+		if snapshot.State["status"] != "open" {
+			t.Errorf("expected status open, got %v", snapshot.State["status"])
+		}
+		if _, ok := snapshot.State["action"]; ok {
+			t.Errorf("relationship events should not leak into the state")
+		}
+		if snapshot.LastModifiedBy != "user2" {
+			t.Errorf("expected last modified by user2, got %s", snapshot.LastModifiedBy)
+		}
+	})
+
+	t.Run("Before Creation", func(t *testing.T) {
+		asOf := created.Add(-time.Hour).Format(time.RFC3339)
+		req := httptest.NewRequest("GET", "/risks/"+riskID+"/history?as_of="+url.QueryEscape(asOf), nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 404 {
+			t.Errorf("expected status 404, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Invalid As Of", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/risks/"+riskID+"/history?as_of=yesterday", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 400 {
+			t.Errorf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		from := created.Add(time.Minute).Format(time.RFC3339)
+		to := resolved.Add(time.Minute).Format(time.RFC3339)
+		req := httptest.NewRequest("GET", "/risks/"+riskID+"/history/diff?from="+url.QueryEscape(from)+"&to="+url.QueryEscape(to), nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+
+		var diff models.HistoryDiff
+		if err := json.NewDecoder(resp.Body).Decode(&diff); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(diff.Changes) != 3 {
+			t.Errorf("expected 3 changed fields, got %d: %v", len(diff.Changes), diff.Changes)
+		}
+		if diff.Changes["status"].To != "resolved" {
+			t.Errorf("expected status to be resolved, got %v", diff.Changes["status"].To)
+		}
+		if diff.Changes["review_date"].From != nil {
+			t.Errorf("expected review_date to start empty, got %v", diff.Changes["review_date"].From)
+		}
+	})
+
+	t.Run("Diff Requires From", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/risks/"+riskID+"/history/diff", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 400 {
+			t.Errorf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestHistoryHandler_LegacyEntries(t *testing.T) {
+	app := fiber.New()
+	mockRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewHistoryHandler(mockRepo)
+
+	riskID, incidentID := uuid.New().String(), uuid.New().String()
+	created := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	updated := created.Add(24 * time.Hour)
+
+	// Entries as written before created snapshots held every field
+	mockRepo.logs = append(mockRepo.logs,
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "risk", EntityID: riskID,
+			Action: models.AuditActionCreated, UserID: "user1", CreatedAt: created,
+			Changes: map[string]any{
+				"title": "Vendor outage", "description": "", "owner_id": "user1", "status": "open", "severity": "medium",
+			},
+		},
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "risk", EntityID: riskID,
+			Action: models.AuditActionUpdated, UserID: "user1", CreatedAt: updated,
+			Changes: map[string]any{
+				"review_date": map[string]any{"from": "", "to": "2024-03-01T00:00:00Z"},
+			},
+		},
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "incident", EntityID: incidentID,
+			Action: models.AuditActionCreated, UserID: "user1", CreatedAt: created,
+			Changes: map[string]any{
+				"title": "Checkout down", "description": "", "priority": "high", "status": "open", "service_affected": "shop",
+			},
+		},
+		&models.AuditLog{
+			ID: uuid.New().String(), EntityType: "incident", EntityID: incidentID,
+			Action: models.AuditActionUpdated, UserID: "user1", CreatedAt: updated,
+			Changes: map[string]any{
+				"category_id": map[string]any{"from": "", "to": ""},
+				"resolved_at": map[string]any{"to": "2024-01-02T10:30:00.123456+02:00"},
+			},
+		},
+	)
+
+	app.Get("/risks/:id/history", handler.RiskAsOf)
+	app.Get("/incidents/:id/history", handler.IncidentAsOf)
+
+	snapshot := func(t *testing.T, path string, asOf time.Time) map[string]any {
+		t.Helper()
+		req := httptest.NewRequest("GET", path+"?as_of="+url.QueryEscape(asOf.Format(time.RFC3339)), nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		var snapshot models.HistorySnapshot
+		if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return snapshot.State
+	}
+
+	t.Run("Risk", func(t *testing.T) {
+		state := snapshot(t, "/risks/"+riskID+"/history", created)
+		for _, field := range []string{"category_id", "review_date", "tags"} {
+			if value, ok := state[field]; !ok || value != nil {
+				t.Errorf("expected %s to be null, got %v (present %v)", field, value, ok)
+			}
+		}
+		state = snapshot(t, "/risks/"+riskID+"/history", updated)
+		if state["review_date"] != "2024-03-01" {
+			t.Errorf("expected review_date 2024-03-01, got %v", state["review_date"])
+		}
+	})
+
+	t.Run("Incident", func(t *testing.T) {
+		state := snapshot(t, "/incidents/"+incidentID+"/history", created)
+		for _, field := range []string{"assignee_id", "reporter_id", "occurred_at", "resolved_at"} {
+			if value, ok := state[field]; !ok || value != nil {
+				t.Errorf("expected %s to be null, got %v (present %v)", field, value, ok)
+			}
+		}
+		state = snapshot(t, "/incidents/"+incidentID+"/history", updated)
+		if state["resolved_at"] != "2024-01-02T08:30:00Z" {
+			t.Errorf("expected resolved_at 2024-01-02T08:30:00Z, got %v", state["resolved_at"])
+		}
+		if state["category_id"] != nil {
+			t.Errorf("expected category_id to be null, got %v", state["category_id"])
+		}
+	})
+}
@@ -61,6 +61,7 @@ func (h *IncidentHandler) List(c *fiber.Ctx) error {
 		Page:   c.QueryInt("page", 1),
 		Limit:  c.QueryInt("limit", 20),
 		Search: c.Query("search"),
+		Tag:    c.Query("tag"),
 		Sort:   c.Query("sort", "created_at"),
 		Order:  c.Query("order", "desc"),
 	}
@@ -96,6 +97,7 @@ func (h *IncidentHandler) Get(c *fiber.Ctx) error {
 		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch incident"})
 	}
+	setVersionETag(c, incident.Version)
 	return c.JSON(incident)
 }
 
@@ -165,6 +167,7 @@ func (h *IncidentHandler) Create(c *fiber.Ctx) error {
 		ServiceAffected: input.ServiceAffected,
 		OccurredAt:      occurredAt,
 		DetectedAt:      detectedAt,
+		Tags:            normalizeTags(input.Tags),
 		CreatedBy:       user.UserID,
 		UpdatedBy:       user.UserID,
 	}
@@ -172,19 +175,10 @@ func (h *IncidentHandler) Create(c *fiber.Ctx) error {
 	if err := h.incidents.Create(c.Context(), incident); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "failed to create incident"})
 	}
+	setVersionETag(c, incident.Version)
 
-	// Log audit event
-	changes := map[string]any{
-		"title":           incident.Title,
-		"description":     incident.Description,
-		"priority":        incident.Priority,
-		"status":          incident.Status,
-		"service_affected": incident.ServiceAffected,
-	}
-	if incident.CategoryID != nil {
-		changes["category_id"] = *incident.CategoryID
-	}
-	h.audit.Create(c.Context(), "incident", incident.ID, models.AuditActionCreated, changes, user.UserID)
+	// Log audit event with a full snapshot so history can be replayed from it
+	h.audit.Create(c.Context(), "incident", incident.ID, models.AuditActionCreated, incidentAuditSnapshot(incident), user.UserID)
 
 	return c.Status(201).JSON(incident)
 }
@@ -205,6 +199,9 @@ func (h *IncidentHandler) Update(c *fiber.Ctx) error {
 		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch incident"})
 	}
+	if err := checkIfMatch(c, incident.Version); err != nil {
+		return preconditionFailed(c, err, "incident", incident, incident.Version)
+	}
 
 	user := middleware.GetUserFromContext(c)
 	incident.UpdatedBy = user.UserID
@@ -225,21 +222,27 @@ func (h *IncidentHandler) Update(c *fiber.Ctx) error {
 		changes["priority"] = map[string]any{"from": incident.Priority, "to": *input.Priority}
 		incident.Priority = *input.Priority
 	}
+	var resolvedAt *time.Time
+	if input.ResolvedAt != nil {
+		if t, err := time.Parse(time.RFC3339, *input.ResolvedAt); err == nil {
+			resolvedAt = &t
+		}
+	}
 	if input.Status != nil {
 		changes["status"] = map[string]any{"from": incident.Status, "to": *input.Status}
 		incident.Status = *input.Status
 
-		// Auto-set resolved_at when status changes to resolved or closed
-		if (*input.Status == models.IncidentStatusResolved || *input.Status == models.IncidentStatusClosed) && incident.ResolvedAt == nil {
+		// Auto-set resolved_at when status changes to resolved or closed,
+		// unless the request sets it explicitly
+		resolving := *input.Status == models.IncidentStatusResolved || *input.Status == models.IncidentStatusClosed
+		if resolving && resolvedAt == nil && incident.ResolvedAt == nil {
 			now := time.Now()
+			changes["resolved_at"] = auditChange(nil, auditTime(&now))
 			incident.ResolvedAt = &now
 		}
 	}
 	if input.CategoryID != nil {
-		var oldCategoryID string
-		if incident.CategoryID != nil {
-			oldCategoryID = *incident.CategoryID
-		}
+		oldCategoryID := auditString(incident.CategoryID)
 
 		normalizedCategoryID := h.normalizeCategoryID(input.CategoryID)
 
@@ -261,7 +264,7 @@ func (h *IncidentHandler) Update(c *fiber.Ctx) error {
 		}
 	}
 	if input.AssigneeID != nil {
-		changes["assignee_id"] = map[string]any{"from": incident.AssigneeID, "to": input.AssigneeID}
+		changes["assignee_id"] = auditChange(auditString(incident.AssigneeID), auditString(input.AssigneeID))
 		incident.AssigneeID = input.AssigneeID
 	}
 	if input.ServiceAffected != nil {
@@ -276,15 +279,20 @@ func (h *IncidentHandler) Update(c *fiber.Ctx) error {
 		changes["resolution_notes"] = map[string]any{"from": incident.ResolutionNotes, "to": *input.ResolutionNotes}
 		incident.ResolutionNotes = *input.ResolutionNotes
 	}
-	if input.ResolvedAt != nil {
-		t, err := time.Parse(time.RFC3339, *input.ResolvedAt)
-		if err == nil {
-			incident.ResolvedAt = &t
-			changes["resolved_at"] = map[string]any{"to": *input.ResolvedAt}
-		}
+	if resolvedAt != nil {
+		changes["resolved_at"] = auditChange(auditTime(incident.ResolvedAt), auditTime(resolvedAt))
+		incident.ResolvedAt = resolvedAt
+	}
+	if input.Tags != nil {
+		tags := normalizeTags(*input.Tags)
+		changes["tags"] = auditChange(auditTags(incident.Tags), tags)
+		incident.Tags = tags
 	}
 
 	if err := h.incidents.Update(c.Context(), incident); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to update incident"})
 	}
 
@@ -293,15 +301,146 @@ func (h *IncidentHandler) Update(c *fiber.Ctx) error {
 		h.audit.Create(c.Context(), "incident", incident.ID, models.AuditActionUpdated, changes, user.UserID)
 	}
 
+	setVersionETag(c, incident.Version)
+	return c.JSON(incident)
+}
+
+// Patch applies a JSON merge patch to an incident. Unlike Update, a member set
+// to null clears the field, so an incident can be unassigned or uncategorized.
+func (h *IncidentHandler) Patch(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	patch, err := parseMergePatch(c)
+	if err != nil {
+		return patchError(c, err)
+	}
+
+	incident, err := h.incidents.FindByID(c.Context(), id)
+	if err != nil {
+		if err == database.ErrIncidentNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "incident not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch incident"})
+	}
+	if err := checkIfMatch(c, incident.Version); err != nil {
+		return preconditionFailed(c, err, "incident", incident, incident.Version)
+	}
+
+	errs := fieldErrors{}
+	patch.allow(errs, "title", "description", "category_id", "priority", "status", "assignee_id",
+		"service_affected", "root_cause", "resolution_notes", "resolved_at", "tags")
+	changes := make(map[string]any)
+
+	if v, ok := patch.string("title", errs); ok && requireText(errs, "title", v, 255) {
+		changes["title"] = auditChange(incident.Title, *v)
+		incident.Title = *v
+	}
+	if v, ok := patch.string("description", errs); ok {
+		changes["description"] = auditChange(incident.Description, textOrEmpty(v))
+		incident.Description = textOrEmpty(v)
+	}
+	if v, ok := patch.string("category_id", errs); ok {
+		if v == nil {
+			changes["category_id"] = auditChange(auditString(incident.CategoryID), nil)
+			incident.CategoryID = nil
+			incident.Category = nil
+		} else if cat, err := h.incidentCategories.FindByID(c.Context(), *v); err != nil || cat == nil {
+			errs["category_id"] = "does not exist"
+		} else {
+			changes["category_id"] = auditChange(auditString(incident.CategoryID), *v)
+			incident.CategoryID = v
+			incident.Category = cat
+		}
+	}
+	if v, ok := patch.string("priority", errs); ok && requireOneOf(errs, "priority", v,
+		string(models.PriorityP1), string(models.PriorityP2), string(models.PriorityP3), string(models.PriorityP4)) {
+		changes["priority"] = auditChange(string(incident.Priority), *v)
+		incident.Priority = models.IncidentPriority(*v)
+	}
+	if v, ok := patch.string("assignee_id", errs); ok && optionalUUID(errs, "assignee_id", v) {
+		changes["assignee_id"] = auditChange(auditString(incident.AssigneeID), auditString(v))
+		incident.AssigneeID = v
+	}
+	if v, ok := patch.string("service_affected", errs); ok {
+		changes["service_affected"] = auditChange(incident.ServiceAffected, textOrEmpty(v))
+		incident.ServiceAffected = textOrEmpty(v)
+	}
+	if v, ok := patch.string("root_cause", errs); ok {
+		changes["root_cause"] = auditChange(incident.RootCause, textOrEmpty(v))
+		incident.RootCause = textOrEmpty(v)
+	}
+	if v, ok := patch.string("resolution_notes", errs); ok {
+		changes["resolution_notes"] = auditChange(incident.ResolutionNotes, textOrEmpty(v))
+		incident.ResolutionNotes = textOrEmpty(v)
+	}
+	if v, ok := patch.strings("tags", errs); ok {
+		tags := normalizeTags(v)
+		changes["tags"] = auditChange(auditTags(incident.Tags), tags)
+		incident.Tags = tags
+	}
+	_, patchesResolvedAt := patch["resolved_at"]
+	if v, ok := patch.timestamp("resolved_at", errs); ok {
+		changes["resolved_at"] = auditChange(auditTime(incident.ResolvedAt), auditTime(v))
+		incident.ResolvedAt = v
+	}
+	if v, ok := patch.string("status", errs); ok && requireOneOf(errs, "status", v,
+		string(models.IncidentStatusNew), string(models.IncidentStatusAcknowledged), string(models.IncidentStatusInProgress),
+		string(models.IncidentStatusOnHold), string(models.IncidentStatusResolved), string(models.IncidentStatusClosed)) {
+		changes["status"] = auditChange(string(incident.Status), *v)
+		incident.Status = models.IncidentStatus(*v)
+
+		// Auto-set resolved_at as Update does, unless the patch sets it explicitly
+		resolving := incident.Status == models.IncidentStatusResolved || incident.Status == models.IncidentStatusClosed
+		if resolving && !patchesResolvedAt && incident.ResolvedAt == nil {
+			now := time.Now()
+			changes["resolved_at"] = auditChange(nil, auditTime(&now))
+			incident.ResolvedAt = &now
+		}
+	}
+
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if len(changes) == 0 {
+		setVersionETag(c, incident.Version)
+		return c.JSON(incident)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	incident.UpdatedBy = user.UserID
+
+	if err := h.incidents.Update(c.Context(), incident); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update incident"})
+	}
+	h.audit.Create(c.Context(), "incident", incident.ID, models.AuditActionUpdated, changes, user.UserID)
+
+	setVersionETag(c, incident.Version)
 	return c.JSON(incident)
 }
 
 func (h *IncidentHandler) Delete(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	incident, err := h.incidents.FindByID(c.Context(), id)
+	if err != nil {
+		if err == database.ErrIncidentNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "incident not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch incident"})
+	}
+	if err := checkIfMatch(c, incident.Version); err != nil {
+		return preconditionFailed(c, err, "incident", incident, incident.Version)
+	}
+
 	user := middleware.GetUserFromContext(c)
 
-	if err := h.incidents.Delete(c.Context(), id); err != nil {
+	if err := h.incidents.Delete(c.Context(), id, incident.Version); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
 		if err == database.ErrIncidentNotFound {
 			return c.Status(404).JSON(fiber.Map{"error": "incident not found"})
 		}
@@ -314,6 +453,19 @@ func (h *IncidentHandler) Delete(c *fiber.Ctx) error {
 	return c.SendStatus(204)
 }
 
+// conflict answers an update that lost a race with another writer after
+// passing the If-Match check, returning the copy that won.
+func (h *IncidentHandler) conflict(c *fiber.Ctx, id string) error {
+	current, err := h.incidents.FindByID(c.Context(), id)
+	if err != nil {
+		if err == database.ErrIncidentNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "incident not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch incident"})
+	}
+	return preconditionFailed(c, errIfMatchFailed, "incident", current, current.Version)
+}
+
 // IncidentRiskHandler handles incident-risk link operations
 type IncidentRiskHandler struct {
 	incidentRisks database.IncidentRiskRepository
@@ -539,6 +539,7 @@ func TestIncidentHandler_Integration_Update(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incident.ID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -570,6 +571,7 @@ func TestIncidentHandler_Integration_Update(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incident.ID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -601,6 +603,7 @@ func TestIncidentHandler_Integration_Update(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incident.ID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -631,6 +634,7 @@ func TestIncidentHandler_Integration_Update(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incident.ID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -661,6 +665,7 @@ func TestIncidentHandler_Integration_Update(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incident.ID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -675,6 +680,7 @@ func TestIncidentHandler_Integration_Update(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/non-existent-id", bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -724,6 +730,7 @@ func TestIncidentHandler_Integration_Delete(t *testing.T) {
 		require.NoError(t, err)
 
 		req := httptest.NewRequest("DELETE", "/incidents/"+incident.ID, nil)
+		req.Header.Set("If-Match", "*")
 		resp, err := app.Test(req)
 		require.NoError(t, err)
 		assert.Equal(t, 204, resp.StatusCode)
@@ -736,6 +743,7 @@ func TestIncidentHandler_Integration_Delete(t *testing.T) {
 
 	t.Run("delete non-existent incident returns 404", func(t *testing.T) {
 		req := httptest.NewRequest("DELETE", "/incidents/non-existent-id", nil)
+		req.Header.Set("If-Match", "*")
 		resp, err := app.Test(req)
 		require.NoError(t, err)
 		assert.Equal(t, 404, resp.StatusCode)
@@ -1027,6 +1035,7 @@ func TestIncidentHandler_Integration_FullWorkflow(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incidentID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -1073,6 +1082,7 @@ func TestIncidentHandler_Integration_FullWorkflow(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incidentID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -1102,6 +1112,7 @@ func TestIncidentHandler_Integration_FullWorkflow(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incidentID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -1111,6 +1122,7 @@ func TestIncidentHandler_Integration_FullWorkflow(t *testing.T) {
 	// Step 9: Delete incident
 	t.Run("step 9 - delete incident", func(t *testing.T) {
 		req := httptest.NewRequest("DELETE", "/incidents/"+incidentID, nil)
+		req.Header.Set("If-Match", "*")
 		resp, err := app.Test(req)
 		require.NoError(t, err)
 		assert.Equal(t, 204, resp.StatusCode)
@@ -1275,6 +1287,7 @@ func TestIncidentHandler_Integration_EmptyCategoryNormalization(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/incidents/"+incident.ID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "*")
 
 		resp, err := app.Test(req)
 		require.NoError(t, err)
@@ -3,8 +3,10 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http/httptest"
+	"sort"
 	"testing"
 	"time"
 
@@ -74,23 +76,81 @@ func (m *mockIncidentRepo) List(ctx context.Context, params *models.IncidentList
 	}, nil
 }
 
+func (m *mockIncidentRepo) ListIDs(ctx context.Context, params *models.IncidentListParams) ([]string, error) {
+	var ids []string
+	for id, incident := range m.incidents {
+		if params.Status != nil && incident.Status != *params.Status {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
 func (m *mockIncidentRepo) Update(ctx context.Context, incident *models.Incident) error {
 	if _, ok := m.incidents[incident.ID]; !ok {
 		return database.ErrIncidentNotFound
 	}
 	incident.UpdatedAt = time.Now()
+	incident.Version++
 	m.incidents[incident.ID] = incident
 	return nil
 }
 
-func (m *mockIncidentRepo) Delete(ctx context.Context, id string) error {
-	if _, ok := m.incidents[id]; !ok {
+func (m *mockIncidentRepo) Delete(ctx context.Context, id string, version int) error {
+	incident, ok := m.incidents[id]
+	if !ok {
+		return database.ErrIncidentNotFound
+	}
+	if incident.Version != version {
+		return database.ErrVersionConflict
+	}
+	delete(m.incidents, id)
+	return nil
+}
+
+func (m *mockIncidentRepo) ListDeleted(ctx context.Context) ([]*models.Incident, error) {
+	var incidents []*models.Incident
+	for _, incident := range m.incidents {
+		if incident.DeletedAt != nil {
+			incidents = append(incidents, incident)
+		}
+	}
+	return incidents, nil
+}
+
+func (m *mockIncidentRepo) Restore(ctx context.Context, id string) error {
+	incident, ok := m.incidents[id]
+	if !ok {
+		return database.ErrIncidentNotFound
+	}
+	if incident.DeletedAt == nil {
+		return database.ErrNotInTrash
+	}
+	incident.DeletedAt = nil
+	return nil
+}
+
+func (m *mockIncidentRepo) Purge(ctx context.Context, id string, deletedBefore time.Time) error {
+	incident, ok := m.incidents[id]
+	if !ok {
 		return database.ErrIncidentNotFound
 	}
+	if incident.DeletedAt == nil {
+		return database.ErrNotInTrash
+	}
+	if incident.DeletedAt.After(deletedBefore) {
+		return database.ErrPurgeGracePeriod
+	}
 	delete(m.incidents, id)
 	return nil
 }
 
+func (m *mockIncidentRepo) WithTx(tx *sql.Tx) database.IncidentRepository {
+	return m
+}
+
 type mockIncidentRiskRepo struct {
 	links map[string]*models.IncidentRisk // key: incidentID:riskID
 }
@@ -657,6 +717,7 @@ func TestIncidentHandler_Update(t *testing.T) {
 			body, _ := json.Marshal(tt.input)
 			req := httptest.NewRequest("PUT", "/incidents/"+tt.id, bytes.NewReader(body))
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", "*")
 
 			resp, err := app.Test(req)
 			if err != nil {
@@ -678,6 +739,99 @@ func TestIncidentHandler_Update(t *testing.T) {
 	}
 }
 
+func TestIncidentHandler_UpdateResolvedAt(t *testing.T) {
+	app := fiber.New()
+	mockIncidentRepo := newMockIncidentRepo()
+	mockAuditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewIncidentHandler(mockIncidentRepo, newMockIncidentCategoryRepo(), newMockIncidentRiskRepo(), mockAuditRepo)
+
+	incident := &models.Incident{ID: uuid.New().String(), Title: "Database outage", Status: models.IncidentStatusInProgress,
+		Priority: models.PriorityP2}
+	mockIncidentRepo.incidents[incident.ID] = incident
+	app.Put("/incidents/:id", testAuthMiddleware, handler.Update)
+
+	body := `{"status": "resolved", "resolved_at": "2026-01-02T08:30:00Z"}`
+	req := httptest.NewRequest("PUT", "/incidents/"+incident.ID, bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", versionETag(incident.Version))
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	if incident.ResolvedAt == nil || !incident.ResolvedAt.Equal(time.Date(2026, 1, 2, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("expected the explicit resolved_at to be kept, got %v", incident.ResolvedAt)
+	}
+	if len(mockAuditRepo.logs) != 1 {
+		t.Fatalf("expected one audit entry, got %d", len(mockAuditRepo.logs))
+	}
+	change, _ := mockAuditRepo.logs[0].Changes["resolved_at"].(map[string]any)
+	if change["from"] != nil || change["to"] != "2026-01-02T08:30:00Z" {
+		t.Errorf("expected resolved_at to change from null to the explicit value, got %v", change)
+	}
+}
+
+func TestIncidentHandler_Patch(t *testing.T) {
+	app := fiber.New()
+	mockIncidentRepo := newMockIncidentRepo()
+	mockAuditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewIncidentHandler(mockIncidentRepo, newMockIncidentCategoryRepo(), newMockIncidentRiskRepo(), mockAuditRepo)
+
+	assignee := uuid.New().String()
+	incident := &models.Incident{
+		ID:         uuid.New().String(),
+		Title:      "Database outage",
+		Status:     models.IncidentStatusInProgress,
+		Priority:   models.PriorityP2,
+		AssigneeID: &assignee,
+	}
+	mockIncidentRepo.incidents[incident.ID] = incident
+
+	app.Patch("/incidents/:id", testAuthMiddleware, handler.Patch)
+
+	t.Run("Unassign And Resolve", func(t *testing.T) {
+		body := `{"assignee_id": null, "status": "resolved"}`
+		req := httptest.NewRequest("PATCH", "/incidents/"+incident.ID, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", versionETag(incident.Version))
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		if incident.AssigneeID != nil {
+			t.Errorf("expected assignee to be cleared, got %v", *incident.AssigneeID)
+		}
+		if incident.ResolvedAt == nil {
+			t.Errorf("expected resolved_at to be set when resolving")
+		}
+	})
+
+	t.Run("Invalid Priority", func(t *testing.T) {
+		body := `{"priority": "urgent", "assignee_id": "not-a-uuid"}`
+		req := httptest.NewRequest("PATCH", "/incidents/"+incident.ID, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", versionETag(incident.Version))
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 400 {
+			t.Errorf("expected status 400, got %d", resp.StatusCode)
+		}
+		if incident.Priority != models.PriorityP2 {
+			t.Errorf("an invalid patch should not be applied")
+		}
+	})
+}
+
 func TestIncidentHandler_Delete(t *testing.T) {
 	existingID := uuid.New().String()
 
@@ -730,6 +884,7 @@ func TestIncidentHandler_Delete(t *testing.T) {
 			app.Delete("/incidents/:id", testAuthMiddleware, handler.Delete)
 
 			req := httptest.NewRequest("DELETE", "/incidents/"+tt.id, nil)
+			req.Header.Set("If-Match", "*")
 
 			resp, err := app.Test(req)
 			if err != nil {
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"database/sql"
+	"errors"
 	"log"
 
 	"backend/internal/database"
@@ -12,10 +14,13 @@ import (
 
 type MitigationHandler struct {
 	mitigationRepo database.MitigationRepository
+	audit          database.AuditLogRepository
+	cleanup        *AttachmentCleanup
 }
 
-func NewMitigationHandler(mitigationRepo database.MitigationRepository) *MitigationHandler {
-	return &MitigationHandler{mitigationRepo: mitigationRepo}
+func NewMitigationHandler(mitigationRepo database.MitigationRepository, audit database.AuditLogRepository,
+	cleanup *AttachmentCleanup) *MitigationHandler {
+	return &MitigationHandler{mitigationRepo: mitigationRepo, audit: audit, cleanup: cleanup}
 }
 
 // List returns all mitigations for a specific risk
@@ -27,6 +32,9 @@ func (h *MitigationHandler) List(c *fiber.Ctx) error {
 
 	mitigations, err := h.mitigationRepo.ListByRiskID(c.Context(), riskID)
 	if err != nil {
+		if errors.Is(err, database.ErrRiskNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "risk not found"})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch mitigations"})
 	}
 
@@ -68,10 +76,15 @@ func (h *MitigationHandler) Create(c *fiber.Ctx) error {
 
 	mitigation, err := h.mitigationRepo.Create(c.Context(), &input, user.UserID)
 	if err != nil {
+		if errors.Is(err, database.ErrRiskNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "risk not found"})
+		}
 		log.Printf("Failed to create mitigation: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "failed to create mitigation"})
 	}
+	h.audit.Create(c.Context(), "mitigation", mitigation.ID, models.AuditActionCreated, mitigationAuditSnapshot(mitigation), user.UserID)
 
+	setVersionETag(c, mitigation.Version)
 	return c.Status(201).JSON(mitigation)
 }
 
@@ -97,14 +110,106 @@ func (h *MitigationHandler) Update(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request body"})
 	}
 
+	current, err := h.findForWrite(c, id)
+	if err != nil || current == nil {
+		return err
+	}
+	input.Version = &current.Version
+
 	mitigation, err := h.mitigationRepo.Update(c.Context(), id, &input, user.UserID)
 	if err != nil {
 		if err == database.ErrMitigationNotFound {
 			return c.Status(404).JSON(fiber.Map{"error": "mitigation not found"})
 		}
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to update mitigation"})
 	}
+	if changes := mitigationAuditChanges(current, mitigation); len(changes) > 0 {
+		h.audit.Create(c.Context(), "mitigation", id, models.AuditActionUpdated, changes, user.UserID)
+	}
 
+	setVersionETag(c, mitigation.Version)
+	return c.JSON(mitigation)
+}
+
+// Patch applies a JSON merge patch to a mitigation. due_date can be cleared
+// by setting it to null.
+func (h *MitigationHandler) Patch(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if user == nil {
+		return c.Status(401).JSON(fiber.Map{"error": "unauthorized"})
+	}
+
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "mitigation id is required"})
+	}
+
+	patch, err := parseMergePatch(c)
+	if err != nil {
+		return patchError(c, err)
+	}
+
+	current, err := h.findForWrite(c, id)
+	if err != nil || current == nil {
+		return err
+	}
+
+	errs := fieldErrors{}
+	patch.allow(errs, "description", "owner", "status", "due_date")
+	input := models.UpdateMitigationInput{Version: &current.Version}
+	changed := false
+
+	if v, ok := patch.string("description", errs); ok && requireText(errs, "description", v, 0) {
+		input.Description = v
+		changed = true
+	}
+	if v, ok := patch.string("owner", errs); ok && requireText(errs, "owner", v, 255) {
+		input.Owner = v
+		changed = true
+	}
+	if v, ok := patch.string("status", errs); ok && requireOneOf(errs, "status", v,
+		string(models.MitigationStatusPlanned), string(models.MitigationStatusInProgress),
+		string(models.MitigationStatusCompleted), string(models.MitigationStatusCancelled)) {
+		status := models.MitigationStatus(*v)
+		input.Status = &status
+		changed = true
+	}
+	if v, ok := patch.date("due_date", errs); ok {
+		// The repository clears the due date when given an empty string
+		dueDate := ""
+		if v != nil {
+			dueDate = v.Format("2006-01-02")
+		}
+		input.DueDate = &dueDate
+		changed = true
+	}
+
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if !changed {
+		setVersionETag(c, current.Version)
+		return c.JSON(current)
+	}
+
+	mitigation, err := h.mitigationRepo.Update(c.Context(), id, &input, user.UserID)
+	if err != nil {
+		if err == database.ErrMitigationNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "mitigation not found"})
+		}
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update mitigation"})
+	}
+	if changes := mitigationAuditChanges(current, mitigation); len(changes) > 0 {
+		h.audit.Create(c.Context(), "mitigation", id, models.AuditActionUpdated, changes, user.UserID)
+	}
+
+	setVersionETag(c, mitigation.Version)
 	return c.JSON(mitigation)
 }
 
@@ -125,12 +230,57 @@ func (h *MitigationHandler) Delete(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "mitigation id is required"})
 	}
 
-	if err := h.mitigationRepo.Delete(c.Context(), id); err != nil {
+	current, err := h.findForWrite(c, id)
+	if err != nil || current == nil {
+		return err
+	}
+
+	err = h.cleanup.Delete(c.Context(), func(tx *sql.Tx) error {
+		return h.mitigationRepo.WithTx(tx).Delete(c.Context(), id, current.Version)
+	}, models.AttachmentMitigation)
+	if err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
 		if err == database.ErrMitigationNotFound {
 			return c.Status(404).JSON(fiber.Map{"error": "mitigation not found"})
 		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to delete mitigation"})
 	}
+	h.audit.Create(c.Context(), "mitigation", id, models.AuditActionDeleted, nil, user.UserID)
 
 	return c.SendStatus(204)
 }
+
+// findForWrite loads a mitigation of the :riskId risk and checks it against
+// If-Match. Mitigations of a trashed risk aren't found. When it returns a nil
+// mitigation the response has already been written.
+func (h *MitigationHandler) findForWrite(c *fiber.Ctx, id string) (*models.Mitigation, error) {
+	mitigation, err := h.mitigationRepo.FindByID(c.Context(), id)
+	if err == nil && mitigation.RiskID != c.Params("riskId") {
+		err = database.ErrMitigationNotFound
+	}
+	if err != nil {
+		if err == database.ErrMitigationNotFound {
+			return nil, c.Status(404).JSON(fiber.Map{"error": "mitigation not found"})
+		}
+		return nil, c.Status(500).JSON(fiber.Map{"error": "failed to fetch mitigation"})
+	}
+	if err := checkIfMatch(c, mitigation.Version); err != nil {
+		return nil, preconditionFailed(c, err, "mitigation", mitigation, mitigation.Version)
+	}
+	return mitigation, nil
+}
+
+// conflict answers an update that lost a race with another writer after
+// passing the If-Match check, returning the copy that won.
+func (h *MitigationHandler) conflict(c *fiber.Ctx, id string) error {
+	current, err := h.mitigationRepo.FindByID(c.Context(), id)
+	if err != nil {
+		if err == database.ErrMitigationNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "mitigation not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch mitigation"})
+	}
+	return preconditionFailed(c, errIfMatchFailed, "mitigation", current, current.Version)
+}
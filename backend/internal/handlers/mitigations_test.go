@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"net/http/httptest"
 	"testing"
@@ -17,9 +18,14 @@ import (
 
 type mockMitigationRepo struct {
 	mitigations map[string]*models.Mitigation
+	// trashed holds the IDs of risks in the trash, whose mitigations are hidden
+	trashed map[string]bool
 }
 
 func (m *mockMitigationRepo) Create(ctx context.Context, input *models.CreateMitigationInput, createdBy string) (*models.Mitigation, error) {
+	if m.trashed[input.RiskID] {
+		return nil, database.ErrRiskNotFound
+	}
 	mitigation := &models.Mitigation{
 		ID:          uuid.New().String(),
 		RiskID:      input.RiskID,
@@ -40,13 +46,16 @@ func (m *mockMitigationRepo) Create(ctx context.Context, input *models.CreateMit
 }
 
 func (m *mockMitigationRepo) FindByID(ctx context.Context, id string) (*models.Mitigation, error) {
-	if val, ok := m.mitigations[id]; ok {
+	if val, ok := m.mitigations[id]; ok && !m.trashed[val.RiskID] {
 		return val, nil
 	}
 	return nil, database.ErrMitigationNotFound
 }
 
 func (m *mockMitigationRepo) ListByRiskID(ctx context.Context, riskID string) ([]*models.Mitigation, error) {
+	if m.trashed[riskID] {
+		return nil, database.ErrRiskNotFound
+	}
 	var list []*models.Mitigation
 	for _, val := range m.mitigations {
 		if val.RiskID == riskID {
@@ -57,10 +66,16 @@ func (m *mockMitigationRepo) ListByRiskID(ctx context.Context, riskID string) ([
 }
 
 func (m *mockMitigationRepo) Update(ctx context.Context, id string, input *models.UpdateMitigationInput, updatedBy string) (*models.Mitigation, error) {
-	mitigation, ok := m.mitigations[id]
+	stored, ok := m.mitigations[id]
 	if !ok {
 		return nil, database.ErrMitigationNotFound
 	}
+	if input.Version != nil && *input.Version != stored.Version {
+		return nil, database.ErrVersionConflict
+	}
+	// Work on a copy, as the database would, so callers can compare versions
+	updated := *stored
+	mitigation := &updated
 	if input.Description != nil {
 		mitigation.Description = *input.Description
 	}
@@ -80,22 +95,31 @@ func (m *mockMitigationRepo) Update(ctx context.Context, id string, input *model
 	}
 	mitigation.UpdatedBy = updatedBy
 	mitigation.UpdatedAt = time.Now()
+	mitigation.Version++
 	m.mitigations[id] = mitigation
 	return mitigation, nil
 }
 
-func (m *mockMitigationRepo) Delete(ctx context.Context, id string) error {
-	if _, ok := m.mitigations[id]; !ok {
+func (m *mockMitigationRepo) Delete(ctx context.Context, id string, version int) error {
+	mitigation, ok := m.mitigations[id]
+	if !ok {
 		return database.ErrMitigationNotFound
 	}
+	if mitigation.Version != version {
+		return database.ErrVersionConflict
+	}
 	delete(m.mitigations, id)
 	return nil
 }
 
+func (m *mockMitigationRepo) WithTx(tx *sql.Tx) database.MitigationRepository {
+	return m
+}
+
 func TestListMitigationsHandler(t *testing.T) {
 	app := fiber.New()
 	mockRepo := &mockMitigationRepo{mitigations: make(map[string]*models.Mitigation)}
-	handler := NewMitigationHandler(mockRepo)
+	handler := NewMitigationHandler(mockRepo, &mockAuditRepo{logs: []*models.AuditLog{}}, newTestAttachmentCleanup())
 
 	riskID := uuid.New().String()
 	// Add test data
@@ -133,7 +157,7 @@ func TestListMitigationsHandler(t *testing.T) {
 func TestCreateMitigationHandler(t *testing.T) {
 	app := fiber.New()
 	mockRepo := &mockMitigationRepo{mitigations: make(map[string]*models.Mitigation)}
-	handler := NewMitigationHandler(mockRepo)
+	handler := NewMitigationHandler(mockRepo, &mockAuditRepo{logs: []*models.AuditLog{}}, newTestAttachmentCleanup())
 
 	// Use testAuthMiddleware from risks_test.go
 	app.Post("/risks/:riskId/mitigations", testAuthMiddleware, handler.Create)
@@ -191,7 +215,8 @@ func TestCreateMitigationHandler(t *testing.T) {
 func TestUpdateMitigationHandler(t *testing.T) {
 	app := fiber.New()
 	mockRepo := &mockMitigationRepo{mitigations: make(map[string]*models.Mitigation)}
-	handler := NewMitigationHandler(mockRepo)
+	auditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewMitigationHandler(mockRepo, auditRepo, newTestAttachmentCleanup())
 
 	riskID := uuid.New().String()
 	mit := &models.Mitigation{
@@ -212,6 +237,7 @@ func TestUpdateMitigationHandler(t *testing.T) {
 		body, _ := json.Marshal(input)
 		req := httptest.NewRequest("PUT", "/risks/"+riskID+"/mitigations/"+mit.ID, bytes.NewReader(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", versionETag(mit.Version))
 
 		resp, err := app.Test(req)
 		if err != nil {
@@ -227,6 +253,15 @@ func TestUpdateMitigationHandler(t *testing.T) {
 		if updated.Description != newDesc {
 			t.Errorf("expected description %s, got %s", newDesc, updated.Description)
 		}
+
+		if len(auditRepo.logs) != 1 {
+			t.Fatalf("expected 1 audit entry, got %d", len(auditRepo.logs))
+		}
+		entry := auditRepo.logs[0]
+		change, _ := entry.Changes["description"].(map[string]any)
+		if entry.EntityType != "mitigation" || entry.Action != models.AuditActionUpdated || change["to"] != newDesc || len(entry.Changes) != 1 {
+			t.Errorf("unexpected audit entry: %+v", entry)
+		}
 	})
 
 	t.Run("Not Found", func(t *testing.T) {
@@ -249,10 +284,48 @@ func TestUpdateMitigationHandler(t *testing.T) {
 	})
 }
 
+func TestPatchMitigationHandler(t *testing.T) {
+	app := fiber.New()
+	mockRepo := &mockMitigationRepo{mitigations: make(map[string]*models.Mitigation)}
+	handler := NewMitigationHandler(mockRepo, &mockAuditRepo{logs: []*models.AuditLog{}}, newTestAttachmentCleanup())
+
+	riskID := uuid.New().String()
+	dueDate := time.Now().AddDate(0, 1, 0)
+	mit := &models.Mitigation{
+		ID:          uuid.New().String(),
+		RiskID:      riskID,
+		Description: "Rotate keys",
+		Owner:       "Security",
+		DueDate:     &dueDate,
+	}
+	mockRepo.mitigations[mit.ID] = mit
+
+	app.Patch("/risks/:riskId/mitigations/:id", testAuthMiddleware, handler.Patch)
+
+	req := httptest.NewRequest("PATCH", "/risks/"+riskID+"/mitigations/"+mit.ID, bytes.NewReader([]byte(`{"due_date": null}`)))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", versionETag(mit.Version))
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	updated := mockRepo.mitigations[mit.ID]
+	if updated.DueDate != nil {
+		t.Errorf("expected due_date to be cleared")
+	}
+	if updated.Description != "Rotate keys" {
+		t.Errorf("absent members should be left untouched, got description %q", updated.Description)
+	}
+}
+
 func TestDeleteMitigationHandler(t *testing.T) {
 	app := fiber.New()
 	mockRepo := &mockMitigationRepo{mitigations: make(map[string]*models.Mitigation)}
-	handler := NewMitigationHandler(mockRepo)
+	handler := NewMitigationHandler(mockRepo, &mockAuditRepo{logs: []*models.AuditLog{}}, newTestAttachmentCleanup())
 
 	riskID := uuid.New().String()
 	mit := &models.Mitigation{
@@ -266,6 +339,7 @@ func TestDeleteMitigationHandler(t *testing.T) {
 
 	t.Run("Valid Delete", func(t *testing.T) {
 		req := httptest.NewRequest("DELETE", "/risks/"+riskID+"/mitigations/"+mit.ID, nil)
+		req.Header.Set("If-Match", versionETag(mit.Version))
 
 		resp, err := app.Test(req)
 		if err != nil {
@@ -294,3 +368,54 @@ func TestDeleteMitigationHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestMitigationHandler_TrashedRisk(t *testing.T) {
+	app := fiber.New()
+	riskID, otherRiskID := uuid.New().String(), uuid.New().String()
+	mockRepo := &mockMitigationRepo{mitigations: make(map[string]*models.Mitigation), trashed: map[string]bool{riskID: true}}
+	handler := NewMitigationHandler(mockRepo, &mockAuditRepo{logs: []*models.AuditLog{}}, newTestAttachmentCleanup())
+
+	mit := &models.Mitigation{ID: uuid.New().String(), RiskID: riskID, Description: "Rotate keys", Version: 1}
+	other := &models.Mitigation{ID: uuid.New().String(), RiskID: otherRiskID, Description: "Review access", Version: 1}
+	mockRepo.mitigations[mit.ID] = mit
+	mockRepo.mitigations[other.ID] = other
+
+	app.Get("/risks/:riskId/mitigations", testAuthMiddleware, handler.List)
+	app.Post("/risks/:riskId/mitigations", testAuthMiddleware, handler.Create)
+	app.Put("/risks/:riskId/mitigations/:id", testAuthMiddleware, handler.Update)
+	app.Patch("/risks/:riskId/mitigations/:id", testAuthMiddleware, handler.Patch)
+	app.Delete("/risks/:riskId/mitigations/:id", testAuthMiddleware, handler.Delete)
+
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", versionETag(mit.Version))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	base := "/risks/" + riskID + "/mitigations"
+	for _, tc := range []struct {
+		name, method, path, body string
+	}{
+		{"List", "GET", base, ""},
+		{"Create", "POST", base, `{"description": "Review access", "owner": "IT"}`},
+		{"Update", "PUT", base + "/" + mit.ID, `{"description": "Rotate keys monthly"}`},
+		{"Patch", "PATCH", base + "/" + mit.ID, `{"description": "Rotate keys monthly"}`},
+		{"Delete", "DELETE", base + "/" + mit.ID, ""},
+		{"Another Risk's Mitigation", "PUT", "/risks/" + uuid.New().String() + "/mitigations/" + other.ID, `{"description": "x"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if status := send(tc.method, tc.path, tc.body); status != 404 {
+				t.Errorf("expected status 404, got %d", status)
+			}
+		})
+	}
+	if len(mockRepo.mitigations) != 2 || mockRepo.mitigations[mit.ID].Description != "Rotate keys" ||
+		mockRepo.mitigations[other.ID].Description != "Review access" {
+		t.Error("mitigations of a trashed risk must not change")
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/notifications"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxNotifications = 100
+
+type NotificationHandler struct {
+	notifications database.NotificationRepository
+}
+
+func NewNotificationHandler(notifications database.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{notifications: notifications}
+}
+
+// List returns the current user's inbox, newest first. ?unread=true leaves
+// out notifications that have been read.
+func (h *NotificationHandler) List(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	params := &models.NotificationListParams{
+		UnreadOnly: c.QueryBool("unread"),
+		Limit:      c.QueryInt("limit", 20),
+		Offset:     c.QueryInt("offset", 0),
+	}
+	if params.Limit < 1 || params.Limit > maxNotifications {
+		params.Limit = 20
+	}
+	if params.Offset < 0 {
+		params.Offset = 0
+	}
+	resp, err := h.notifications.List(c.Context(), user.UserID, params)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "notifications")})
+	}
+	return c.JSON(resp)
+}
+
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	if err := h.notifications.MarkRead(c.Context(), user.UserID, c.Params("id"), time.Now()); err != nil {
+		if errors.Is(err, database.ErrNotificationNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "notification")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToUpdate, "notification")})
+	}
+	return c.SendStatus(204)
+}
+
+func (h *NotificationHandler) MarkAllRead(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	count, err := h.notifications.MarkAllRead(c.Context(), user.UserID, time.Now())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToUpdate, "notifications")})
+	}
+	return c.JSON(fiber.Map{"marked": count})
+}
+
+// Preferences returns the channels every event is sent on for the current
+// user, with defaults filled in
+func (h *NotificationHandler) Preferences(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	prefs, err := h.notifications.Preferences(c.Context(), user.UserID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "notification preferences")})
+	}
+	return c.JSON(fiber.Map{"preferences": fullPreferences(prefs), "channels": models.NotificationChannels})
+}
+
+// UpdatePreferences sets the channels for the events in the body, e.g.
+// {"risk.updated": ["in_app", "email"], "incident.updated": []}. Events left
+// out keep their current setting; an empty list turns an event off.
+func (h *NotificationHandler) UpdatePreferences(c *fiber.Ctx) error {
+	var input models.NotificationPreferences
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if len(input) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	errs := fieldErrors{}
+	for event, channels := range input {
+		if !slices.Contains(models.NotificationEvents, event) {
+			errs[string(event)] = "unknown event"
+			continue
+		}
+		unique := []models.NotificationChannel{}
+		for _, channel := range channels {
+			if !slices.Contains(models.NotificationChannels, channel) {
+				errs[string(event)] = "channels must be in_app or email"
+			} else if !slices.Contains(unique, channel) {
+				unique = append(unique, channel)
+			}
+		}
+		input[event] = unique
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	if err := h.notifications.SetPreferences(c.Context(), user.UserID, input); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToUpdate, "notification preferences")})
+	}
+	return h.Preferences(c)
+}
+
+// fullPreferences lists every event with the user's choice or the default
+func fullPreferences(prefs models.NotificationPreferences) models.NotificationPreferences {
+	full := models.NotificationPreferences{}
+	for _, event := range models.NotificationEvents {
+		full[event] = notifications.Channels(prefs, event)
+	}
+	return full
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type mockNotificationRepo struct {
+	database.NotificationRepository
+	notifications []*models.Notification
+	prefs         map[string]models.NotificationPreferences
+}
+
+func newMockNotificationRepo() *mockNotificationRepo {
+	return &mockNotificationRepo{prefs: map[string]models.NotificationPreferences{}}
+}
+
+func (m *mockNotificationRepo) List(ctx context.Context, userID string, params *models.NotificationListParams) (*models.NotificationListResponse, error) {
+	resp := &models.NotificationListResponse{Notifications: []*models.Notification{}, Limit: params.Limit, Offset: params.Offset}
+	for _, n := range m.notifications {
+		if n.UserID != userID {
+			continue
+		}
+		if n.ReadAt == nil {
+			resp.Unread++
+		}
+		if params.UnreadOnly && n.ReadAt != nil {
+			continue
+		}
+		resp.Total++
+		resp.Notifications = append(resp.Notifications, n)
+	}
+	return resp, nil
+}
+
+func (m *mockNotificationRepo) MarkRead(ctx context.Context, userID, id string, at time.Time) error {
+	for _, n := range m.notifications {
+		if n.ID == id && n.UserID == userID {
+			n.ReadAt = &at
+			return nil
+		}
+	}
+	return database.ErrNotificationNotFound
+}
+
+func (m *mockNotificationRepo) MarkAllRead(ctx context.Context, userID string, at time.Time) (int, error) {
+	count := 0
+	for _, n := range m.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &at
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockNotificationRepo) Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{}
+	for event, channels := range m.prefs[userID] {
+		prefs[event] = channels
+	}
+	return prefs, nil
+}
+
+func (m *mockNotificationRepo) SetPreferences(ctx context.Context, userID string, prefs models.NotificationPreferences) error {
+	if m.prefs[userID] == nil {
+		m.prefs[userID] = models.NotificationPreferences{}
+	}
+	for event, channels := range prefs {
+		m.prefs[userID][event] = channels
+	}
+	return nil
+}
+
+func setupNotificationApp() (*fiber.App, *mockNotificationRepo) {
+	repo := newMockNotificationRepo()
+	handler := NewNotificationHandler(repo)
+	app := fiber.New()
+	app.Use(testAuthMiddleware)
+	app.Get("/notifications", handler.List)
+	app.Post("/notifications/read-all", handler.MarkAllRead)
+	app.Get("/notifications/preferences", handler.Preferences)
+	app.Put("/notifications/preferences", handler.UpdatePreferences)
+	app.Post("/notifications/:id/read", handler.MarkRead)
+	return app, repo
+}
+
+func TestNotificationHandler_Inbox(t *testing.T) {
+	app, repo := setupNotificationApp()
+	repo.notifications = []*models.Notification{
+		{ID: "n1", UserID: "test-user-id", Title: "one"},
+		{ID: "n2", UserID: "test-user-id", Title: "two"},
+		{ID: "n3", UserID: "someone-else", Title: "not mine"},
+	}
+
+	list := func(query string) models.NotificationListResponse {
+		resp, _ := app.Test(httptest.NewRequest("GET", "/notifications"+query, nil))
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var result models.NotificationListResponse
+		json.NewDecoder(resp.Body).Decode(&result)
+		return result
+	}
+	if got := list(""); got.Total != 2 || got.Unread != 2 || got.Limit != 20 {
+		t.Errorf("unexpected inbox %+v", got)
+	}
+
+	resp, _ := app.Test(httptest.NewRequest("POST", "/notifications/n1/read", nil))
+	if resp.StatusCode != 204 || repo.notifications[0].ReadAt == nil {
+		t.Fatalf("expected n1 to be marked read, got %d", resp.StatusCode)
+	}
+	if got := list("?unread=true"); got.Total != 1 || got.Unread != 1 || got.Notifications[0].ID != "n2" {
+		t.Errorf("unexpected unread inbox %+v", got)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("POST", "/notifications/n3/read", nil))
+	if resp.StatusCode != 404 {
+		t.Errorf("expected 404 for another user's notification, got %d", resp.StatusCode)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("POST", "/notifications/read-all", nil))
+	var marked map[string]int
+	json.NewDecoder(resp.Body).Decode(&marked)
+	if resp.StatusCode != 200 || marked["marked"] != 1 || repo.notifications[2].ReadAt != nil {
+		t.Errorf("expected only the user's unread notification to be marked, got %d %v", resp.StatusCode, marked)
+	}
+}
+
+func TestNotificationHandler_Preferences(t *testing.T) {
+	app, repo := setupNotificationApp()
+
+	put := func(body string) (int, map[string]any) {
+		req := httptest.NewRequest("PUT", "/notifications/preferences", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, _ := app.Test(req)
+		var result map[string]any
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, result := put(`{"risk.updated": ["email", "in_app", "email"], "incident.updated": []}`)
+	if status != 200 {
+		t.Fatalf("expected 200, got %d: %v", status, result)
+	}
+	saved := repo.prefs["test-user-id"]
+	if len(saved[models.NotificationRiskUpdated]) != 2 || len(saved[models.NotificationIncidentUpdated]) != 0 {
+		t.Errorf("unexpected saved preferences %v", saved)
+	}
+	prefs := result["preferences"].(map[string]any)
+	if len(prefs) != len(models.NotificationEvents) {
+		t.Errorf("expected every event in the response, got %v", prefs)
+	}
+	if got := prefs["risk.assigned"].([]any); len(got) != 2 {
+		t.Errorf("expected the default channels for risk.assigned, got %v", got)
+	}
+	if got := prefs["incident.updated"].([]any); len(got) != 0 {
+		t.Errorf("expected incident.updated to be off, got %v", got)
+	}
+
+	if status, _ := put(`{}`); status != 400 {
+		t.Errorf("expected 400 for an empty body, got %d", status)
+	}
+	status, result = put(`{"risk.deleted": ["in_app"], "risk.updated": ["sms"]}`)
+	fields, _ := result["fields"].(map[string]any)
+	if status != 400 || fields["risk.deleted"] == nil || fields["risk.updated"] == nil {
+		t.Errorf("expected both entries to be rejected, got %d %v", status, result)
+	}
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// PATCH endpoints accept RFC 7396 JSON merge patches. Every patchable resource
+// is flat, so a patch is a single object whose members replace the matching
+// fields; a member set to null clears the field, and absent members are left
+// untouched. Problems with individual members are collected as field errors so
+// clients get every mistake in one response.
+
+const mimeMergePatch = "application/merge-patch+json"
+
+var errPatchMediaType = errors.New("content type must be " + mimeMergePatch + " or application/json")
+var errPatchNotObject = errors.New("merge patch must be a JSON object")
+
+// mergePatch is a decoded merge patch, keyed by field name
+type mergePatch map[string]json.RawMessage
+
+// fieldErrors maps a field name to what is wrong with its patched value
+type fieldErrors map[string]string
+
+func parseMergePatch(c *fiber.Ctx) (mergePatch, error) {
+	mediaType, _, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil || (mediaType != mimeMergePatch && mediaType != fiber.MIMEApplicationJSON) {
+		return nil, errPatchMediaType
+	}
+
+	var patch mergePatch
+	if err := json.Unmarshal(c.Body(), &patch); err != nil || patch == nil {
+		return nil, errPatchNotObject
+	}
+	return patch, nil
+}
+
+// patchError answers a request whose body could not be read as a merge patch
+func patchError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errPatchMediaType) {
+		return c.Status(415).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
+
+// validationFailed answers a patch with one or more invalid fields
+func validationFailed(c *fiber.Ctx, errs fieldErrors) error {
+	return c.Status(400).JSON(fiber.Map{"error": "validation failed", "fields": errs})
+}
+
+// allow flags every member that is not one of the patchable fields
+func (p mergePatch) allow(errs fieldErrors, fields ...string) {
+	for field := range p {
+		known := false
+		for _, allowed := range fields {
+			if field == allowed {
+				known = true
+				break
+			}
+		}
+		if !known {
+			errs[field] = "is not a patchable field"
+		}
+	}
+}
+
+// string reads a string member. present reports whether the member is in the
+// patch with a usable value; value is nil when it was explicitly null.
+func (p mergePatch) string(field string, errs fieldErrors) (value *string, present bool) {
+	raw, ok := p[field]
+	if !ok {
+		return nil, false
+	}
+	if isJSONNull(raw) {
+		return nil, true
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		errs[field] = "must be a string"
+		return nil, false
+	}
+	return &s, true
+}
+
+// strings reads an array-of-strings member. An explicit null reads as an
+// empty list.
+func (p mergePatch) strings(field string, errs fieldErrors) (value []string, present bool) {
+	raw, ok := p[field]
+	if !ok {
+		return nil, false
+	}
+	if isJSONNull(raw) {
+		return []string{}, true
+	}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		errs[field] = "must be an array of strings"
+		return nil, false
+	}
+	return value, true
+}
+
+// integer reads a whole-number member; value is nil when it was explicitly null
+func (p mergePatch) integer(field string, errs fieldErrors) (value *int, present bool) {
+	raw, ok := p[field]
+	if !ok {
+		return nil, false
+	}
+	if isJSONNull(raw) {
+		return nil, true
+	}
+	var n int
+	if err := json.Unmarshal(raw, &n); err != nil {
+		errs[field] = "must be an integer"
+		return nil, false
+	}
+	return &n, true
+}
+
+// date reads a YYYY-MM-DD member
+func (p mergePatch) date(field string, errs fieldErrors) (value *time.Time, present bool) {
+	s, ok := p.string(field, errs)
+	if !ok || s == nil {
+		return nil, ok
+	}
+	t, err := time.Parse("2006-01-02", *s)
+	if err != nil {
+		errs[field] = "must be a date in YYYY-MM-DD format"
+		return nil, false
+	}
+	return &t, true
+}
+
+// timestamp reads an RFC 3339 member
+func (p mergePatch) timestamp(field string, errs fieldErrors) (value *time.Time, present bool) {
+	s, ok := p.string(field, errs)
+	if !ok || s == nil {
+		return nil, ok
+	}
+	t, err := time.Parse(time.RFC3339, *s)
+	if err != nil {
+		errs[field] = "must be an RFC 3339 timestamp"
+		return nil, false
+	}
+	return &t, true
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// requireText checks a patched value for a field that cannot be cleared
+func requireText(errs fieldErrors, field string, value *string, maxLen int) bool {
+	switch {
+	case value == nil:
+		errs[field] = "cannot be null"
+	case strings.TrimSpace(*value) == "":
+		errs[field] = "cannot be empty"
+	case maxLen > 0 && len(*value) > maxLen:
+		errs[field] = fmt.Sprintf("must be at most %d characters", maxLen)
+	default:
+		return true
+	}
+	return false
+}
+
+// requireUUID checks a patched value for a reference that cannot be cleared
+func requireUUID(errs fieldErrors, field string, value *string) bool {
+	if value == nil {
+		errs[field] = "cannot be null"
+		return false
+	}
+	return optionalUUID(errs, field, value)
+}
+
+// optionalUUID checks a patched value for a reference that may be cleared
+func optionalUUID(errs fieldErrors, field string, value *string) bool {
+	if value != nil && uuid.Validate(*value) != nil {
+		errs[field] = "must be a UUID"
+		return false
+	}
+	return true
+}
+
+// optionalDate reads a YYYY-MM-DD body field, returning nil when it is
+// absent, empty or invalid
+func optionalDate(errs fieldErrors, field string, value *string) *time.Time {
+	if value == nil || *value == "" {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		errs[field] = "must be a date in YYYY-MM-DD format"
+		return nil
+	}
+	return &t
+}
+
+// requireOneOf checks a patched value for an enum field that cannot be cleared
+func requireOneOf(errs fieldErrors, field string, value *string, allowed ...string) bool {
+	if value == nil {
+		errs[field] = "cannot be null"
+		return false
+	}
+	for _, a := range allowed {
+		if *value == a {
+			return true
+		}
+	}
+	errs[field] = "must be one of " + strings.Join(allowed, ", ")
+	return false
+}
+
+// textOrEmpty maps a cleared free-text field to the empty string it is stored as
+func textOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
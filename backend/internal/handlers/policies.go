@@ -0,0 +1,691 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// The policy register holds the policies that implement framework controls.
+// A policy's document is written in numbered versions: its owner drafts a
+// version as markdown or an uploaded attachment and submits it, and an admin
+// approves or rejects it. The latest approved version is the one in force;
+// approving it schedules the next review one review cycle later and asks
+// every user to acknowledge the new version afresh.
+
+// errPolicyForbidden is returned when the caller neither owns the policy
+// nor is an admin
+var errPolicyForbidden = errors.New("only the policy owner or an admin can do this")
+
+type PolicyHandler struct {
+	tx       database.Transactor
+	policies database.PolicyRepository
+	audit    database.AuditLogRepository
+	cleanup  *AttachmentCleanup
+}
+
+func NewPolicyHandler(tx database.Transactor, policies database.PolicyRepository, audit database.AuditLogRepository,
+	cleanup *AttachmentCleanup) *PolicyHandler {
+	return &PolicyHandler{tx: tx, policies: policies, audit: audit, cleanup: cleanup}
+}
+
+// List returns policies, filtered by owner_id, control_id and
+// review_overdue=true
+func (h *PolicyHandler) List(c *fiber.Ctx) error {
+	filter := models.PolicyFilter{
+		OwnerID:       c.Query("owner_id"),
+		ControlID:     c.Query("control_id"),
+		ReviewOverdue: c.QueryBool("review_overdue"),
+	}
+	errs := fieldErrors{}
+	if filter.OwnerID != "" {
+		optionalUUID(errs, "owner_id", &filter.OwnerID)
+	}
+	if filter.ControlID != "" {
+		optionalUUID(errs, "control_id", &filter.ControlID)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	return h.list(c, filter)
+}
+
+// ListForControl returns the policies linked to the :id control
+func (h *PolicyHandler) ListForControl(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return policyError(c, database.ErrFrameworkControlNotFound, ErrFailedToFetch)
+	}
+	return h.list(c, models.PolicyFilter{ControlID: id})
+}
+
+func (h *PolicyHandler) list(c *fiber.Ctx, filter models.PolicyFilter) error {
+	policies, err := h.policies.List(c.Context(), filter)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": policies})
+}
+
+// Get returns the :id policy with its linked controls
+func (h *PolicyHandler) Get(c *fiber.Ctx) error {
+	policy, err := h.load(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(policy)
+}
+
+// Create registers a policy. It has no document until a version is
+// approved.
+func (h *PolicyHandler) Create(c *fiber.Ctx) error {
+	var input models.CreatePolicyInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.OwnerID != nil && *input.OwnerID == "" {
+		input.OwnerID = nil
+	}
+	if input.ReviewCycle == "" {
+		input.ReviewCycle = models.RecurrenceAnnual
+	}
+
+	errs := fieldErrors{}
+	requireText(errs, "title", &input.Title, 255)
+	optionalUUID(errs, "owner_id", input.OwnerID)
+	requireOneOf(errs, "review_cycle", (*string)(&input.ReviewCycle), recurrences...)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	policy := &models.Policy{
+		Title:       strings.TrimSpace(input.Title),
+		Description: input.Description,
+		OwnerID:     input.OwnerID,
+		ReviewCycle: input.ReviewCycle,
+		CreatedBy:   &user.UserID,
+	}
+	if err := h.policies.Create(c.Context(), policy); err != nil {
+		return policyError(c, err, ErrFailedToCreate)
+	}
+	created, err := h.policies.GetByID(c.Context(), policy.ID)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+
+	h.audit.Create(c.Context(), "policy", created.ID, models.AuditActionCreated, policyAuditSnapshot(created), user.UserID)
+	return c.Status(201).JSON(created)
+}
+
+// Update changes the fields that are set. An empty owner_id leaves the
+// policy without an owner and an empty next_review_date unschedules its
+// review.
+func (h *PolicyHandler) Update(c *fiber.Ctx) error {
+	current, err := h.load(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+
+	var input models.UpdatePolicyInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Title == nil && input.Description == nil && input.OwnerID == nil && input.ReviewCycle == nil && input.NextReviewDate == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	errs := fieldErrors{}
+	updated := *current
+	if input.Title != nil && requireText(errs, "title", input.Title, 255) {
+		updated.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		updated.Description = *input.Description
+	}
+	if input.OwnerID != nil {
+		if *input.OwnerID == "" {
+			updated.OwnerID = nil
+		} else if optionalUUID(errs, "owner_id", input.OwnerID) {
+			updated.OwnerID = input.OwnerID
+		}
+	}
+	if input.ReviewCycle != nil && requireOneOf(errs, "review_cycle", (*string)(input.ReviewCycle), recurrences...) {
+		updated.ReviewCycle = *input.ReviewCycle
+	}
+	if input.NextReviewDate != nil {
+		updated.NextReviewDate = optionalDate(errs, "next_review_date", input.NextReviewDate)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	if err := h.policies.Update(c.Context(), &updated); err != nil {
+		return policyError(c, err, ErrFailedToUpdate)
+	}
+	after, err := h.policies.GetByID(c.Context(), current.ID)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	if changes := policyAuditChanges(current, after); len(changes) > 0 {
+		h.audit.Create(c.Context(), "policy", current.ID, models.AuditActionUpdated, changes, user.UserID)
+	}
+	return c.JSON(after)
+}
+
+// Delete removes a policy with its versions, control links and
+// acknowledgements
+func (h *PolicyHandler) Delete(c *fiber.Ctx) error {
+	policy, err := h.load(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	err = h.cleanup.Delete(c.Context(), func(tx *sql.Tx) error {
+		return h.policies.WithTx(tx).Delete(c.Context(), policy.ID)
+	}, models.AttachmentPolicy)
+	if err != nil {
+		return policyError(c, err, ErrFailedToDelete)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	h.audit.Create(c.Context(), "policy", policy.ID, models.AuditActionDeleted, policyAuditSnapshot(policy), user.UserID)
+	return c.SendStatus(204)
+}
+
+// LinkControl records that the :id policy implements a control. Linking a
+// control twice is not an error.
+func (h *PolicyHandler) LinkControl(c *fiber.Ctx) error {
+	policy, err := h.loadForEdit(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	var input models.LinkPolicyControlInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	errs := fieldErrors{}
+	requireUUID(errs, "control_id", &input.ControlID)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	linked, err := h.policies.LinkControl(c.Context(), policy.ID, input.ControlID, user.UserID)
+	if err != nil {
+		if errors.Is(err, database.ErrFrameworkControlNotFound) {
+			return validationFailed(c, fieldErrors{"control_id": "control not found"})
+		}
+		return policyError(c, err, ErrFailedToCreate)
+	}
+	status := 200
+	if linked {
+		status = 201
+		h.audit.Create(c.Context(), "policy", policy.ID, models.AuditActionUpdated, map[string]any{
+			"control_linked": input.ControlID,
+		}, user.UserID)
+	}
+	return h.respond(c, status, policy.ID)
+}
+
+// UnlinkControl removes the :controlId control from the :id policy
+func (h *PolicyHandler) UnlinkControl(c *fiber.Ctx) error {
+	policy, err := h.loadForEdit(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	controlID := c.Params("controlId")
+	if uuid.Validate(controlID) != nil {
+		return policyError(c, database.ErrPolicyControlNotLinked, ErrFailedToDelete)
+	}
+	if err := h.policies.UnlinkControl(c.Context(), policy.ID, controlID); err != nil {
+		return policyError(c, err, ErrFailedToDelete)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	h.audit.Create(c.Context(), "policy", policy.ID, models.AuditActionUpdated, map[string]any{
+		"control_unlinked": controlID,
+	}, user.UserID)
+	return c.SendStatus(204)
+}
+
+// ListVersions returns the versions of the :id policy, newest first
+func (h *PolicyHandler) ListVersions(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return policyError(c, database.ErrPolicyNotFound, ErrFailedToFetch)
+	}
+	versions, err := h.policies.ListVersions(c.Context(), id)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": versions})
+}
+
+// CreateVersion drafts the next version of the :id policy. Only its owner
+// or an admin may, and only while no other version is in draft or awaiting
+// approval.
+func (h *PolicyHandler) CreateVersion(c *fiber.Ctx) error {
+	policy, err := h.loadForEdit(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	var input models.CreatePolicyVersionInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.AttachmentID != nil && *input.AttachmentID == "" {
+		input.AttachmentID = nil
+	}
+	errs := fieldErrors{}
+	optionalUUID(errs, "attachment_id", input.AttachmentID)
+	requirePolicyDocument(errs, input.Body, input.AttachmentID)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	version := &models.PolicyVersion{
+		PolicyID:      policy.ID,
+		Body:          input.Body,
+		AttachmentID:  input.AttachmentID,
+		ChangeSummary: strings.TrimSpace(input.ChangeSummary),
+		CreatedBy:     &user.UserID,
+	}
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		if err := h.policies.WithTx(tx).CreateVersion(c.Context(), version); err != nil {
+			return err
+		}
+		return h.audit.WithTx(tx).Create(c.Context(), "policy_version", version.ID, models.AuditActionCreated,
+			policyVersionAuditSnapshot(version), user.UserID)
+	})
+	if err != nil {
+		return policyError(c, err, ErrFailedToCreate)
+	}
+	return h.respondVersion(c, 201, version.ID)
+}
+
+func (h *PolicyHandler) GetVersion(c *fiber.Ctx) error {
+	version, err := h.loadVersion(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(version)
+}
+
+// UpdateVersion changes the fields of a draft that are set. An empty
+// attachment_id removes its attachment.
+func (h *PolicyHandler) UpdateVersion(c *fiber.Ctx) error {
+	current, err := h.loadVersionForEdit(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	var input models.UpdatePolicyVersionInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Body == nil && input.AttachmentID == nil && input.ChangeSummary == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	errs := fieldErrors{}
+	updated := *current
+	if input.Body != nil {
+		updated.Body = *input.Body
+	}
+	if input.AttachmentID != nil {
+		if *input.AttachmentID == "" {
+			updated.AttachmentID = nil
+		} else if optionalUUID(errs, "attachment_id", input.AttachmentID) {
+			updated.AttachmentID = input.AttachmentID
+		}
+	}
+	if input.ChangeSummary != nil {
+		updated.ChangeSummary = strings.TrimSpace(*input.ChangeSummary)
+	}
+	if len(errs) == 0 {
+		requirePolicyDocument(errs, updated.Body, updated.AttachmentID)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		if err := h.policies.WithTx(tx).UpdateVersion(c.Context(), &updated); err != nil {
+			return err
+		}
+		changes := policyVersionAuditChanges(current, &updated)
+		if len(changes) == 0 {
+			return nil
+		}
+		return h.audit.WithTx(tx).Create(c.Context(), "policy_version", current.ID, models.AuditActionUpdated, changes, user.UserID)
+	})
+	if err != nil {
+		return policyError(c, err, ErrFailedToUpdate)
+	}
+	return h.respondVersion(c, 200, current.ID)
+}
+
+// DeleteVersion discards a draft
+func (h *PolicyHandler) DeleteVersion(c *fiber.Ctx) error {
+	version, err := h.loadVersionForEdit(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	if err := h.policies.DeleteVersion(c.Context(), version.ID); err != nil {
+		return policyError(c, err, ErrFailedToDelete)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	h.audit.Create(c.Context(), "policy_version", version.ID, models.AuditActionDeleted, policyVersionAuditSnapshot(version), user.UserID)
+	return c.SendStatus(204)
+}
+
+// SubmitVersion sends a draft to the admins for approval
+func (h *PolicyHandler) SubmitVersion(c *fiber.Ctx) error {
+	version, err := h.loadVersionForEdit(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		if err := h.policies.WithTx(tx).SubmitVersion(c.Context(), version.ID); err != nil {
+			return err
+		}
+		return h.audit.WithTx(tx).Create(c.Context(), "policy_version", version.ID, models.AuditActionUpdated, map[string]any{
+			"status": auditChange(string(models.PolicyDraft), string(models.PolicyPending)),
+		}, user.UserID)
+	})
+	if err != nil {
+		return policyError(c, err, ErrFailedToUpdate)
+	}
+	return h.respondVersion(c, 200, version.ID)
+}
+
+// ApproveVersion puts a pending version in force, with an optional comment,
+// and schedules the policy's next review
+func (h *PolicyHandler) ApproveVersion(c *fiber.Ctx) error {
+	return h.decide(c, models.PolicyApproved)
+}
+
+// RejectVersion turns down a pending version; the comment explains why
+func (h *PolicyHandler) RejectVersion(c *fiber.Ctx) error {
+	return h.decide(c, models.PolicyRejected)
+}
+
+func (h *PolicyHandler) decide(c *fiber.Ctx, status models.PolicyVersionStatus) error {
+	version, err := h.loadVersion(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	var input models.PolicyDecisionInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+		}
+	}
+	input.Comment = strings.TrimSpace(input.Comment)
+	if status == models.PolicyRejected && input.Comment == "" {
+		return validationFailed(c, fieldErrors{"comment": "is required when rejecting"})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		policies, audit := h.policies.WithTx(tx), h.audit.WithTx(tx)
+		if err := policies.DecideVersion(c.Context(), version.ID, status, input.Comment, user.UserID); err != nil {
+			return err
+		}
+		err := audit.Create(c.Context(), "policy_version", version.ID, models.AuditActionUpdated, map[string]any{
+			"status":  auditChange(string(models.PolicyPending), string(status)),
+			"comment": input.Comment,
+		}, user.UserID)
+		if err != nil || status != models.PolicyApproved {
+			return err
+		}
+
+		policy, err := policies.GetByID(c.Context(), version.PolicyID)
+		if err != nil {
+			return err
+		}
+		updated := *policy
+		next := updated.ReviewCycle.Next(todayUTC())
+		updated.NextReviewDate = &next
+		if err := policies.Update(c.Context(), &updated); err != nil {
+			return err
+		}
+		return audit.Create(c.Context(), "policy", policy.ID, models.AuditActionUpdated, map[string]any{
+			"version":          version.Version,
+			"next_review_date": auditChange(auditDate(policy.NextReviewDate), auditDate(&next)),
+		}, user.UserID)
+	})
+	if err != nil {
+		return policyError(c, err, ErrFailedToUpdate)
+	}
+	return h.respondVersion(c, 200, version.ID)
+}
+
+// Acknowledge records that the caller has read and accepted the version of
+// the :id policy in force. Acknowledging it again is not an error.
+func (h *PolicyHandler) Acknowledge(c *fiber.Ctx) error {
+	policy, err := h.load(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	if policy.CurrentVersion == nil {
+		return c.Status(409).JSON(fiber.Map{"error": "policy has no approved version to acknowledge"})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	created, err := h.policies.Acknowledge(c.Context(), policy.CurrentVersion.ID, user.UserID)
+	if err != nil {
+		return policyError(c, err, ErrFailedToCreate)
+	}
+	status := 200
+	if created {
+		status = 201
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"policy_id":  policy.ID,
+		"version_id": policy.CurrentVersion.ID,
+		"version":    policy.CurrentVersion.Version,
+	})
+}
+
+// ListAcknowledgements shows who has and hasn't accepted the version of the
+// :id policy in force
+func (h *PolicyHandler) ListAcknowledgements(c *fiber.Ctx) error {
+	policy, err := h.load(c)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	if policy.CurrentVersion == nil {
+		return c.Status(409).JSON(fiber.Map{"error": "policy has no approved version"})
+	}
+
+	users, err := h.policies.ListAcknowledgements(c.Context(), policy.CurrentVersion.ID)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	result := &models.PolicyAcknowledgements{
+		PolicyID:     policy.ID,
+		VersionID:    policy.CurrentVersion.ID,
+		Version:      policy.CurrentVersion.Version,
+		Acknowledged: []*models.PolicyAcknowledgement{},
+		Outstanding:  []*models.PolicyAcknowledgement{},
+	}
+	for _, u := range users {
+		if u.AcknowledgedAt != nil {
+			result.Acknowledged = append(result.Acknowledged, u)
+		} else {
+			result.Outstanding = append(result.Outstanding, u)
+		}
+	}
+	return c.JSON(result)
+}
+
+// load fetches the :id policy
+func (h *PolicyHandler) load(c *fiber.Ctx) (*models.Policy, error) {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return nil, database.ErrPolicyNotFound
+	}
+	return h.policies.GetByID(c.Context(), id)
+}
+
+// loadForEdit fetches the :id policy if the caller owns it or is an admin
+func (h *PolicyHandler) loadForEdit(c *fiber.Ctx) (*models.Policy, error) {
+	policy, err := h.load(c)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditPolicy(policy, middleware.GetUserFromContext(c)) {
+		return nil, errPolicyForbidden
+	}
+	return policy, nil
+}
+
+// loadVersion fetches the :id policy version
+func (h *PolicyHandler) loadVersion(c *fiber.Ctx) (*models.PolicyVersion, error) {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return nil, database.ErrPolicyVersionNotFound
+	}
+	return h.policies.GetVersion(c.Context(), id)
+}
+
+// loadVersionForEdit fetches the :id version if it is still a draft and the
+// caller owns its policy or is an admin
+func (h *PolicyHandler) loadVersionForEdit(c *fiber.Ctx) (*models.PolicyVersion, error) {
+	version, err := h.loadVersion(c)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := h.policies.GetByID(c.Context(), version.PolicyID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEditPolicy(policy, middleware.GetUserFromContext(c)) {
+		return nil, errPolicyForbidden
+	}
+	if version.Status != models.PolicyDraft {
+		return nil, database.ErrPolicyVersionSubmitted
+	}
+	return version, nil
+}
+
+// respond writes the policy as stored, with its controls
+func (h *PolicyHandler) respond(c *fiber.Ctx, status int, id string) error {
+	policy, err := h.policies.GetByID(c.Context(), id)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	return c.Status(status).JSON(policy)
+}
+
+// respondVersion writes the version as stored, with names filled in
+func (h *PolicyHandler) respondVersion(c *fiber.Ctx, status int, id string) error {
+	version, err := h.policies.GetVersion(c.Context(), id)
+	if err != nil {
+		return policyError(c, err, ErrFailedToFetch)
+	}
+	return c.Status(status).JSON(version)
+}
+
+func canEditPolicy(policy *models.Policy, user *middleware.UserClaims) bool {
+	return user.Role == string(models.RoleAdmin) || (policy.OwnerID != nil && *policy.OwnerID == user.UserID)
+}
+
+// requirePolicyDocument checks that a version has a body or an attachment
+func requirePolicyDocument(errs fieldErrors, body string, attachmentID *string) {
+	if strings.TrimSpace(body) == "" && attachmentID == nil {
+		errs["body"] = "or an attachment_id is required"
+	}
+}
+
+// policyAuditSnapshot returns the state recorded when a policy is created
+// or deleted
+func policyAuditSnapshot(policy *models.Policy) map[string]any {
+	return map[string]any{
+		"title":            policy.Title,
+		"description":      policy.Description,
+		"owner_id":         auditString(policy.OwnerID),
+		"review_cycle":     string(policy.ReviewCycle),
+		"next_review_date": auditDate(policy.NextReviewDate),
+	}
+}
+
+// policyAuditChanges returns from/to pairs for the fields that differ
+// between two versions of a policy
+func policyAuditChanges(before, after *models.Policy) map[string]any {
+	changes := make(map[string]any)
+	from, to := policyAuditSnapshot(before), policyAuditSnapshot(after)
+	for field, value := range to {
+		if from[field] != value {
+			changes[field] = auditChange(from[field], value)
+		}
+	}
+	return changes
+}
+
+// policyVersionAuditSnapshot returns the state recorded when a version is
+// created or deleted. The body is kept in full so the audit log shows what
+// each draft said.
+func policyVersionAuditSnapshot(version *models.PolicyVersion) map[string]any {
+	return map[string]any{
+		"policy_id":      version.PolicyID,
+		"version":        version.Version,
+		"body":           version.Body,
+		"attachment_id":  auditString(version.AttachmentID),
+		"change_summary": version.ChangeSummary,
+		"status":         string(version.Status),
+	}
+}
+
+// policyVersionAuditChanges returns from/to pairs for the fields that
+// differ between two states of a draft
+func policyVersionAuditChanges(before, after *models.PolicyVersion) map[string]any {
+	changes := make(map[string]any)
+	from, to := policyVersionAuditSnapshot(before), policyVersionAuditSnapshot(after)
+	for field, value := range to {
+		if from[field] != value {
+			changes[field] = auditChange(from[field], value)
+		}
+	}
+	return changes
+}
+
+// policyError maps repository errors to responses, using failed for
+// anything unexpected
+func policyError(c *fiber.Ctx, err error, failed string) error {
+	switch {
+	case errors.Is(err, errPolicyForbidden), errors.Is(err, database.ErrPolicySelfDecision):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, database.ErrPolicyNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "policy")})
+	case errors.Is(err, database.ErrPolicyVersionNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "policy version")})
+	case errors.Is(err, database.ErrFrameworkControlNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "control")})
+	case errors.Is(err, database.ErrPolicyControlNotLinked):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, database.ErrPolicyVersionOpen), errors.Is(err, database.ErrPolicyVersionSubmitted),
+		errors.Is(err, database.ErrPolicyVersionNotPending):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, database.ErrPolicyOwnerNotFound):
+		return validationFailed(c, fieldErrors{"owner_id": "user not found"})
+	case errors.Is(err, database.ErrPolicyAttachmentNotFound):
+		return validationFailed(c, fieldErrors{"attachment_id": "attachment not found on this policy"})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(failed, "policy")})
+}
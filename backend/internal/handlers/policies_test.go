@@ -0,0 +1,481 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockPolicyRepo keeps policies and their versions in memory. Controls can
+// only be linked if they are in controls, and attachments maps each
+// attachment to the policy it was uploaded to.
+type mockPolicyRepo struct {
+	policies         []*models.Policy
+	versions         []*models.PolicyVersion
+	controls         map[string]bool
+	links            map[string]map[string]bool
+	attachments      map[string]string
+	users            []*models.PolicyAcknowledgement
+	acknowledgements map[string]map[string]time.Time
+}
+
+func newMockPolicyRepo() *mockPolicyRepo {
+	return &mockPolicyRepo{
+		controls:         map[string]bool{},
+		links:            map[string]map[string]bool{},
+		attachments:      map[string]string{},
+		acknowledgements: map[string]map[string]time.Time{},
+	}
+}
+
+func (m *mockPolicyRepo) List(ctx context.Context, filter models.PolicyFilter) ([]*models.Policy, error) {
+	policies := []*models.Policy{}
+	for _, policy := range m.policies {
+		if filter.ControlID != "" && !m.links[policy.ID][filter.ControlID] {
+			continue
+		}
+		p, _ := m.GetByID(ctx, policy.ID)
+		if filter.ReviewOverdue && !p.ReviewOverdue {
+			continue
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+func (m *mockPolicyRepo) GetByID(ctx context.Context, id string) (*models.Policy, error) {
+	for _, policy := range m.policies {
+		if policy.ID != id {
+			continue
+		}
+		p := *policy
+		p.ReviewOverdue = p.NextReviewDate != nil && p.NextReviewDate.Before(todayUTC())
+		for _, v := range m.versions {
+			if v.PolicyID == id && v.Status == models.PolicyApproved && (p.CurrentVersion == nil || v.Version > p.CurrentVersion.Version) {
+				p.CurrentVersion = v
+				p.AcknowledgementCount = len(m.acknowledgements[v.ID])
+			}
+		}
+		p.ControlCount = len(m.links[id])
+		return &p, nil
+	}
+	return nil, database.ErrPolicyNotFound
+}
+
+func (m *mockPolicyRepo) Create(ctx context.Context, policy *models.Policy) error {
+	policy.ID = uuid.New().String()
+	stored := *policy
+	m.policies = append(m.policies, &stored)
+	return nil
+}
+
+func (m *mockPolicyRepo) Update(ctx context.Context, policy *models.Policy) error {
+	for i, stored := range m.policies {
+		if stored.ID == policy.ID {
+			updated := *policy
+			updated.CurrentVersion = nil
+			m.policies[i] = &updated
+			return nil
+		}
+	}
+	return database.ErrPolicyNotFound
+}
+
+func (m *mockPolicyRepo) Delete(ctx context.Context, id string) error {
+	for i, policy := range m.policies {
+		if policy.ID == id {
+			m.policies = append(m.policies[:i], m.policies[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrPolicyNotFound
+}
+
+func (m *mockPolicyRepo) ListVersions(ctx context.Context, policyID string) ([]*models.PolicyVersion, error) {
+	versions := []*models.PolicyVersion{}
+	for _, v := range m.versions {
+		if v.PolicyID == policyID {
+			versions = append([]*models.PolicyVersion{v}, versions...)
+		}
+	}
+	return versions, nil
+}
+
+func (m *mockPolicyRepo) GetVersion(ctx context.Context, id string) (*models.PolicyVersion, error) {
+	for _, v := range m.versions {
+		if v.ID == id {
+			copied := *v
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrPolicyVersionNotFound
+}
+
+func (m *mockPolicyRepo) CreateVersion(ctx context.Context, version *models.PolicyVersion) error {
+	if version.AttachmentID != nil && m.attachments[*version.AttachmentID] != version.PolicyID {
+		return database.ErrPolicyAttachmentNotFound
+	}
+	number := 1
+	for _, v := range m.versions {
+		if v.PolicyID != version.PolicyID {
+			continue
+		}
+		if v.Status == models.PolicyDraft || v.Status == models.PolicyPending {
+			return database.ErrPolicyVersionOpen
+		}
+		number = v.Version + 1
+	}
+	version.ID = uuid.New().String()
+	version.Version = number
+	version.Status = models.PolicyDraft
+	stored := *version
+	m.versions = append(m.versions, &stored)
+	return nil
+}
+
+func (m *mockPolicyRepo) UpdateVersion(ctx context.Context, version *models.PolicyVersion) error {
+	if version.AttachmentID != nil && m.attachments[*version.AttachmentID] != version.PolicyID {
+		return database.ErrPolicyAttachmentNotFound
+	}
+	return m.transition(version.ID, models.PolicyDraft, database.ErrPolicyVersionSubmitted, func(v *models.PolicyVersion) {
+		v.Body, v.AttachmentID, v.ChangeSummary = version.Body, version.AttachmentID, version.ChangeSummary
+	})
+}
+
+func (m *mockPolicyRepo) DeleteVersion(ctx context.Context, id string) error {
+	for i, v := range m.versions {
+		if v.ID == id {
+			if v.Status != models.PolicyDraft {
+				return database.ErrPolicyVersionSubmitted
+			}
+			m.versions = append(m.versions[:i], m.versions[i+1:]...)
+			return nil
+		}
+	}
+	return database.ErrPolicyVersionNotFound
+}
+
+func (m *mockPolicyRepo) SubmitVersion(ctx context.Context, id string) error {
+	return m.transition(id, models.PolicyDraft, database.ErrPolicyVersionSubmitted, func(v *models.PolicyVersion) {
+		v.Status = models.PolicyPending
+	})
+}
+
+func (m *mockPolicyRepo) DecideVersion(ctx context.Context, id string, status models.PolicyVersionStatus, comment, userID string) error {
+	for _, v := range m.versions {
+		if v.ID == id && v.Status == models.PolicyPending && v.CreatedBy != nil && *v.CreatedBy == userID {
+			return database.ErrPolicySelfDecision
+		}
+	}
+	return m.transition(id, models.PolicyPending, database.ErrPolicyVersionNotPending, func(v *models.PolicyVersion) {
+		v.Status, v.DecisionComment, v.DecidedBy = status, comment, &userID
+	})
+}
+
+func (m *mockPolicyRepo) transition(id string, from models.PolicyVersionStatus, wrongState error, apply func(*models.PolicyVersion)) error {
+	for _, v := range m.versions {
+		if v.ID == id {
+			if v.Status != from {
+				return wrongState
+			}
+			apply(v)
+			return nil
+		}
+	}
+	return database.ErrPolicyVersionNotFound
+}
+
+func (m *mockPolicyRepo) LinkControl(ctx context.Context, policyID, controlID, userID string) (bool, error) {
+	if !m.controls[controlID] {
+		return false, database.ErrFrameworkControlNotFound
+	}
+	if m.links[policyID] == nil {
+		m.links[policyID] = map[string]bool{}
+	}
+	if m.links[policyID][controlID] {
+		return false, nil
+	}
+	m.links[policyID][controlID] = true
+	return true, nil
+}
+
+func (m *mockPolicyRepo) UnlinkControl(ctx context.Context, policyID, controlID string) error {
+	if !m.links[policyID][controlID] {
+		return database.ErrPolicyControlNotLinked
+	}
+	delete(m.links[policyID], controlID)
+	return nil
+}
+
+func (m *mockPolicyRepo) Acknowledge(ctx context.Context, versionID, userID string) (bool, error) {
+	if m.acknowledgements[versionID] == nil {
+		m.acknowledgements[versionID] = map[string]time.Time{}
+	}
+	if _, ok := m.acknowledgements[versionID][userID]; ok {
+		return false, nil
+	}
+	m.acknowledgements[versionID][userID] = time.Now()
+	return true, nil
+}
+
+func (m *mockPolicyRepo) ListAcknowledgements(ctx context.Context, versionID string) ([]*models.PolicyAcknowledgement, error) {
+	result := []*models.PolicyAcknowledgement{}
+	for _, u := range m.users {
+		a := *u
+		if at, ok := m.acknowledgements[versionID][u.UserID]; ok {
+			a.AcknowledgedAt = &at
+		}
+		result = append(result, &a)
+	}
+	return result, nil
+}
+
+func (m *mockPolicyRepo) WithTx(tx *sql.Tx) database.PolicyRepository {
+	return m
+}
+
+// setupPolicyApp serves the policy routes to a caller with the given role
+// and the ID test-user-id
+func setupPolicyApp(repo *mockPolicyRepo, role string) (*fiber.App, *mockAuditRepo) {
+	audit := &mockAuditRepo{}
+	handler := NewPolicyHandler(&mockTransactor{}, repo, audit, newTestAttachmentCleanup())
+
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserKey, &middleware.UserClaims{UserID: "test-user-id", Role: role})
+		return c.Next()
+	}
+	app := fiber.New()
+	app.Get("/policies", auth, handler.List)
+	app.Post("/policies", auth, handler.Create)
+	app.Put("/policies/:id", auth, handler.Update)
+	app.Post("/policies/:id/controls", auth, handler.LinkControl)
+	app.Delete("/policies/:id/controls/:controlId", auth, handler.UnlinkControl)
+	app.Post("/policies/:id/versions", auth, handler.CreateVersion)
+	app.Post("/policies/:id/acknowledge", auth, handler.Acknowledge)
+	app.Get("/policies/:id/acknowledgements", auth, handler.ListAcknowledgements)
+	app.Put("/policy-versions/:id", auth, handler.UpdateVersion)
+	app.Post("/policy-versions/:id/submit", auth, handler.SubmitVersion)
+	app.Post("/policy-versions/:id/approve", auth, handler.ApproveVersion)
+	app.Post("/policy-versions/:id/reject", auth, handler.RejectVersion)
+	return app, audit
+}
+
+func TestPolicyHandler_Create(t *testing.T) {
+	repo := newMockPolicyRepo()
+	app, audit := setupPolicyApp(repo, "admin")
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name, body, field string
+		}{
+			{"missing title", `{"title":"  "}`, "title"},
+			{"unknown review cycle", `{"title":"Access control policy","review_cycle":"biennial"}`, "review_cycle"},
+			{"bad owner", `{"title":"Access control policy","owner_id":"alice"}`, "owner_id"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, app, "POST", "/policies", tt.body)
+				require.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+
+	status, body := sendTeamRequest(t, app, "POST", "/policies", `{"title":" Access control policy ","owner_id":""}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, "Access control policy", body["title"])
+	assert.Equal(t, "annual", body["review_cycle"], "reviews default to annual")
+	assert.NotContains(t, body, "owner_id")
+	assert.NotContains(t, body, "current_version")
+	require.Len(t, audit.logs, 1)
+	assert.Equal(t, "policy", audit.logs[0].EntityType)
+
+	status, body = sendTeamRequest(t, app, "PUT", "/policies/"+body["id"].(string), `{"review_cycle":"semiannual","next_review_date":"2027-01-31"}`)
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, "2027-01-31T00:00:00Z", body["next_review_date"])
+	assert.Equal(t, auditChange("annual", "semiannual"), audit.logs[1].Changes["review_cycle"])
+}
+
+func TestPolicyHandler_VersionWorkflow(t *testing.T) {
+	repo := newMockPolicyRepo()
+	owner := "test-user-id"
+	policy := &models.Policy{ID: uuid.New().String(), Title: "Information security policy", OwnerID: &owner,
+		ReviewCycle: models.RecurrenceAnnual}
+	repo.policies = append(repo.policies, policy)
+	attachment := uuid.New().String()
+	repo.attachments[attachment] = policy.ID
+	elsewhere := uuid.New().String()
+	repo.attachments[elsewhere] = uuid.New().String()
+
+	member, memberAudit := setupPolicyApp(repo, "member")
+	admin, adminAudit := setupPolicyApp(repo, "admin")
+	path := "/policies/" + policy.ID + "/versions"
+
+	t.Run("only the owner or an admin drafts", func(t *testing.T) {
+		other := newMockPolicyRepo()
+		other.policies = append(other.policies, &models.Policy{ID: policy.ID, Title: policy.Title, ReviewCycle: models.RecurrenceAnnual})
+		app, _ := setupPolicyApp(other, "member")
+		status, _ := sendTeamRequest(t, app, "POST", path, `{"body":"# Policy"}`)
+		assert.Equal(t, 403, status)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name, body, field string
+		}{
+			{"no document", `{"body":"  ","change_summary":"First issue"}`, "body"},
+			{"bad attachment", `{"attachment_id":"policy.pdf"}`, "attachment_id"},
+			{"attachment of another policy", `{"attachment_id":"` + elsewhere + `"}`, "attachment_id"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, member, "POST", path, tt.body)
+				require.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+
+	status, body := sendTeamRequest(t, member, "POST", path, `{"body":"# Information security\n\nDraft","change_summary":" First issue "}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, float64(1), body["version"])
+	assert.Equal(t, "draft", body["status"])
+	assert.Equal(t, "First issue", body["change_summary"])
+	versionPath := "/policy-versions/" + body["id"].(string)
+
+	status, _ = sendTeamRequest(t, member, "POST", path, `{"body":"Another"}`)
+	assert.Equal(t, 409, status, "one open version at a time")
+
+	status, body = sendTeamRequest(t, member, "PUT", versionPath, `{"body":"","attachment_id":"`+attachment+`"}`)
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, attachment, body["attachment_id"])
+	require.Len(t, memberAudit.logs, 2)
+	assert.Equal(t, auditChange(nil, attachment), memberAudit.logs[1].Changes["attachment_id"])
+
+	status, body = sendTeamRequest(t, member, "PUT", versionPath, `{"attachment_id":""}`)
+	require.Equal(t, 400, status, "a version needs a body or an attachment")
+	assert.Contains(t, body["fields"], "body")
+
+	status, body = sendTeamRequest(t, member, "POST", versionPath+"/submit", "")
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, "pending", body["status"])
+
+	status, _ = sendTeamRequest(t, member, "PUT", versionPath, `{"change_summary":"Too late"}`)
+	assert.Equal(t, 409, status, "submitted versions cannot be edited")
+
+	status, body = sendTeamRequest(t, admin, "POST", versionPath+"/reject", `{"comment":" "}`)
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "comment")
+
+	status, _ = sendTeamRequest(t, admin, "POST", versionPath+"/approve", `{"comment":"Looks good to me"}`)
+	assert.Equal(t, 403, status, "the author cannot approve their own version")
+	require.Empty(t, adminAudit.logs)
+	author := uuid.New().String()
+	repo.versions[0].CreatedBy = &author
+
+	status, body = sendTeamRequest(t, admin, "POST", versionPath+"/approve", `{"comment":"Approved at the October board"}`)
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, "approved", body["status"])
+
+	approved, err := repo.GetByID(context.Background(), policy.ID)
+	require.NoError(t, err)
+	require.NotNil(t, approved.CurrentVersion)
+	assert.Equal(t, 1, approved.CurrentVersion.Version)
+	require.NotNil(t, approved.NextReviewDate)
+	assert.Equal(t, models.RecurrenceAnnual.Next(todayUTC()), *approved.NextReviewDate)
+	require.Len(t, adminAudit.logs, 2)
+	assert.Equal(t, auditChange("pending", "approved"), adminAudit.logs[0].Changes["status"])
+	assert.Equal(t, "policy", adminAudit.logs[1].EntityType)
+
+	status, _ = sendTeamRequest(t, admin, "POST", versionPath+"/reject", `{"comment":"Changed my mind"}`)
+	assert.Equal(t, 409, status)
+
+	status, body = sendTeamRequest(t, member, "POST", path, `{"body":"# Information security\n\nRevised","change_summary":"Annual review"}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, float64(2), body["version"])
+}
+
+func TestPolicyHandler_Acknowledge(t *testing.T) {
+	repo := newMockPolicyRepo()
+	policy := &models.Policy{ID: uuid.New().String(), Title: "Acceptable use policy", ReviewCycle: models.RecurrenceAnnual}
+	repo.policies = append(repo.policies, policy)
+	repo.users = []*models.PolicyAcknowledgement{
+		{UserID: "other-user-id", Name: "Alex"},
+		{UserID: "test-user-id", Name: "Sam"},
+	}
+	app, _ := setupPolicyApp(repo, "member")
+	path := "/policies/" + policy.ID
+
+	status, _ := sendTeamRequest(t, app, "POST", path+"/acknowledge", "")
+	assert.Equal(t, 409, status, "nothing to acknowledge before a version is approved")
+
+	version := &models.PolicyVersion{ID: uuid.New().String(), PolicyID: policy.ID, Version: 1, Body: "Be sensible",
+		Status: models.PolicyApproved}
+	repo.versions = append(repo.versions, version)
+
+	status, body := sendTeamRequest(t, app, "POST", path+"/acknowledge", "")
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, version.ID, body["version_id"])
+	status, _ = sendTeamRequest(t, app, "POST", path+"/acknowledge", "")
+	assert.Equal(t, 200, status, "acknowledging again is not an error")
+
+	status, body = sendTeamRequest(t, app, "GET", path+"/acknowledgements", "")
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, float64(1), body["version"])
+	require.Len(t, body["acknowledged"], 1)
+	assert.Equal(t, "Sam", body["acknowledged"].([]any)[0].(map[string]any)["name"])
+	require.Len(t, body["outstanding"], 1)
+	assert.Equal(t, "Alex", body["outstanding"].([]any)[0].(map[string]any)["name"])
+
+	// A new approved version has to be acknowledged afresh
+	repo.versions = append(repo.versions, &models.PolicyVersion{ID: uuid.New().String(), PolicyID: policy.ID, Version: 2,
+		Body: "Be very sensible", Status: models.PolicyApproved})
+	status, body = sendTeamRequest(t, app, "GET", path+"/acknowledgements", "")
+	require.Equal(t, 200, status, body)
+	assert.Empty(t, body["acknowledged"])
+	assert.Len(t, body["outstanding"], 2)
+}
+
+func TestPolicyHandler_Controls(t *testing.T) {
+	repo := newMockPolicyRepo()
+	policy := &models.Policy{ID: uuid.New().String(), Title: "Backup policy", ReviewCycle: models.RecurrenceAnnual}
+	repo.policies = append(repo.policies, policy)
+	control := uuid.New().String()
+	repo.controls[control] = true
+
+	member, _ := setupPolicyApp(repo, "member")
+	status, _ := sendTeamRequest(t, member, "POST", "/policies/"+policy.ID+"/controls", `{"control_id":"`+control+`"}`)
+	assert.Equal(t, 403, status, "members can only link controls to policies they own")
+
+	app, audit := setupPolicyApp(repo, "admin")
+	path := "/policies/" + policy.ID + "/controls"
+	status, body := sendTeamRequest(t, app, "POST", path, `{"control_id":"`+uuid.New().String()+`"}`)
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "control_id")
+
+	status, body = sendTeamRequest(t, app, "POST", path, `{"control_id":"`+control+`"}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, float64(1), body["control_count"])
+	status, _ = sendTeamRequest(t, app, "POST", path, `{"control_id":"`+control+`"}`)
+	assert.Equal(t, 200, status)
+	assert.Len(t, audit.logs, 1)
+
+	status, body = sendTeamRequest(t, app, "GET", "/policies?control_id="+control, "")
+	require.Equal(t, 200, status)
+	assert.Len(t, body["data"], 1)
+
+	status, _ = sendTeamRequest(t, app, "DELETE", path+"/"+control, "")
+	assert.Equal(t, 204, status)
+	status, _ = sendTeamRequest(t, app, "DELETE", path+"/"+control, "")
+	assert.Equal(t, 404, status)
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/reports"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxDeliveryHistory is how many past deliveries the history endpoint returns
+const maxDeliveryHistory = 100
+
+type ReportSubscriptionHandler struct {
+	subscriptions database.ReportSubscriptionRepository
+	deliverer     *reports.Deliverer
+}
+
+func NewReportSubscriptionHandler(subscriptions database.ReportSubscriptionRepository, deliverer *reports.Deliverer) *ReportSubscriptionHandler {
+	return &ReportSubscriptionHandler{subscriptions: subscriptions, deliverer: deliverer}
+}
+
+// List returns the current user's subscriptions
+func (h *ReportSubscriptionHandler) List(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	subs, err := h.subscriptions.ListByUser(c.Context(), user.UserID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "report subscriptions")})
+	}
+	return c.JSON(subs)
+}
+
+func (h *ReportSubscriptionHandler) Get(c *fiber.Ctx) error {
+	sub, err := h.find(c)
+	if err != nil || sub == nil {
+		return err
+	}
+	return c.JSON(sub)
+}
+
+// Create subscribes the current user to a report. The first report is sent
+// at the next scheduled slot for its frequency.
+func (h *ReportSubscriptionHandler) Create(c *fiber.Ctx) error {
+	var input models.CreateReportSubscriptionInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Format == "" {
+		input.Format = models.ReportFormatPDF
+	}
+
+	user := middleware.GetUserFromContext(c)
+	sub := &models.ReportSubscription{
+		UserID:    user.UserID,
+		Name:      strings.TrimSpace(input.Name),
+		Frequency: input.Frequency,
+		Format:    input.Format,
+		Filters:   input.Filters,
+		Active:    input.Active == nil || *input.Active,
+		NextRunAt: reports.NextRun(input.Frequency, time.Now()),
+	}
+	if errs := validateReportSubscription(sub); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	if err := h.subscriptions.Create(c.Context(), sub); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToCreate, "report subscription")})
+	}
+	return c.Status(201).JSON(sub)
+}
+
+// Update changes a subscription. Changing the frequency or reactivating it
+// reschedules the next report from now.
+func (h *ReportSubscriptionHandler) Update(c *fiber.Ctx) error {
+	sub, err := h.find(c)
+	if err != nil || sub == nil {
+		return err
+	}
+
+	var input models.UpdateReportSubscriptionInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Name == nil && input.Frequency == nil && input.Format == nil && input.Filters == nil && input.Active == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	reschedule := false
+	if input.Name != nil {
+		sub.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Frequency != nil && *input.Frequency != sub.Frequency {
+		sub.Frequency = *input.Frequency
+		reschedule = true
+	}
+	if input.Format != nil {
+		sub.Format = *input.Format
+	}
+	if input.Filters != nil {
+		sub.Filters = *input.Filters
+	}
+	if input.Active != nil {
+		reschedule = reschedule || (*input.Active && !sub.Active)
+		sub.Active = *input.Active
+	}
+	if errs := validateReportSubscription(sub); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if reschedule {
+		sub.NextRunAt = reports.NextRun(sub.Frequency, time.Now())
+	}
+
+	if err := h.subscriptions.Update(c.Context(), sub); err != nil {
+		if errors.Is(err, database.ErrReportSubscriptionNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "report subscription")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToUpdate, "report subscription")})
+	}
+	return c.JSON(sub)
+}
+
+func (h *ReportSubscriptionHandler) Delete(c *fiber.Ctx) error {
+	sub, err := h.find(c)
+	if err != nil || sub == nil {
+		return err
+	}
+	if err := h.subscriptions.Delete(c.Context(), sub.ID); err != nil && !errors.Is(err, database.ErrReportSubscriptionNotFound) {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToDelete, "report subscription")})
+	}
+	return c.SendStatus(204)
+}
+
+// Deliveries returns a subscription's most recent deliveries, newest first
+func (h *ReportSubscriptionHandler) Deliveries(c *fiber.Ctx) error {
+	sub, err := h.find(c)
+	if err != nil || sub == nil {
+		return err
+	}
+	deliveries, err := h.subscriptions.ListDeliveries(c.Context(), sub.ID, maxDeliveryHistory)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "report deliveries")})
+	}
+	return c.JSON(models.ReportDeliveryListResponse{Deliveries: deliveries})
+}
+
+// Send delivers a subscription's report immediately without changing its
+// schedule. A failed send is still recorded and returned, with status 502.
+func (h *ReportSubscriptionHandler) Send(c *fiber.Ctx) error {
+	sub, err := h.find(c)
+	if err != nil || sub == nil {
+		return err
+	}
+	delivery, err := h.deliverer.Deliver(c.Context(), sub, true)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to send report"})
+	}
+	if delivery.Status == models.ReportDeliveryFailed {
+		return c.Status(502).JSON(delivery)
+	}
+	return c.JSON(delivery)
+}
+
+// find loads the subscription named in the path. Other users' subscriptions
+// are reported as not found, except to admins. When it returns a nil
+// subscription the response has already been written.
+func (h *ReportSubscriptionHandler) find(c *fiber.Ctx) (*models.ReportSubscription, error) {
+	sub, err := h.subscriptions.FindByID(c.Context(), c.Params("id"))
+	if err != nil && !errors.Is(err, database.ErrReportSubscriptionNotFound) {
+		return nil, c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "report subscription")})
+	}
+	user := middleware.GetUserFromContext(c)
+	if sub == nil || (sub.UserID != user.UserID && user.Role != string(models.RoleAdmin)) {
+		return nil, c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "report subscription")})
+	}
+	return sub, nil
+}
+
+func validateReportSubscription(sub *models.ReportSubscription) fieldErrors {
+	errs := fieldErrors{}
+	requireText(errs, "name", &sub.Name, 255)
+	requireOneOf(errs, "frequency", (*string)(&sub.Frequency), string(models.ReportFrequencyWeekly), string(models.ReportFrequencyMonthly))
+	requireOneOf(errs, "format", (*string)(&sub.Format), string(models.ReportFormatPDF), string(models.ReportFormatCSV), string(models.ReportFormatXLSX))
+
+	f := sub.Filters
+	if f.Status != nil {
+		requireOneOf(errs, "filters.status", (*string)(f.Status), string(models.StatusOpen), string(models.StatusMitigating),
+			string(models.StatusResolved), string(models.StatusAccepted))
+	}
+	if f.Severity != nil {
+		requireOneOf(errs, "filters.severity", (*string)(f.Severity), string(models.SeverityLow), string(models.SeverityMedium),
+			string(models.SeverityHigh), string(models.SeverityCritical))
+	}
+	optionalUUID(errs, "filters.category_id", f.CategoryID)
+	if _, err := reports.ParseSections(strings.Join(f.Sections, ",")); err != nil {
+		errs["filters.sections"] = err.Error()
+	}
+	if f.Granularity != "" {
+		requireOneOf(errs, "filters.granularity", (*string)(&f.Granularity), string(models.GranularityMonthly), string(models.GranularityWeekly))
+	}
+	if f.TopRisks < 0 || f.TopRisks > reports.MaxTopRisks {
+		errs["filters.top_risks"] = fmt.Sprintf("must be between 0 and %d", reports.MaxTopRisks)
+	}
+	return errs
+}
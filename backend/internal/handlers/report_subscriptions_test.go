@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/mailer"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/reports"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type mockReportSubscriptionRepo struct {
+	subs       map[string]*models.ReportSubscription
+	deliveries []*models.ReportDelivery
+}
+
+func (m *mockReportSubscriptionRepo) Create(ctx context.Context, sub *models.ReportSubscription) error {
+	sub.ID = uuid.New().String()
+	sub.CreatedAt = time.Now()
+	sub.UpdatedAt = sub.CreatedAt
+	m.subs[sub.ID] = sub
+	return nil
+}
+
+func (m *mockReportSubscriptionRepo) FindByID(ctx context.Context, id string) (*models.ReportSubscription, error) {
+	sub, ok := m.subs[id]
+	if !ok {
+		return nil, database.ErrReportSubscriptionNotFound
+	}
+	found := *sub
+	return &found, nil
+}
+
+func (m *mockReportSubscriptionRepo) ListByUser(ctx context.Context, userID string) ([]*models.ReportSubscription, error) {
+	subs := []*models.ReportSubscription{}
+	for _, sub := range m.subs {
+		if sub.UserID == userID {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func (m *mockReportSubscriptionRepo) Update(ctx context.Context, sub *models.ReportSubscription) error {
+	if _, ok := m.subs[sub.ID]; !ok {
+		return database.ErrReportSubscriptionNotFound
+	}
+	m.subs[sub.ID] = sub
+	return nil
+}
+
+func (m *mockReportSubscriptionRepo) Delete(ctx context.Context, id string) error {
+	if _, ok := m.subs[id]; !ok {
+		return database.ErrReportSubscriptionNotFound
+	}
+	delete(m.subs, id)
+	return nil
+}
+
+func (m *mockReportSubscriptionRepo) ListDue(ctx context.Context, now time.Time, limit int) ([]*models.ReportSubscription, error) {
+	return nil, nil
+}
+
+func (m *mockReportSubscriptionRepo) Advance(ctx context.Context, id string, from, next, ranAt time.Time) (bool, error) {
+	return false, nil
+}
+
+func (m *mockReportSubscriptionRepo) CreateDelivery(ctx context.Context, d *models.ReportDelivery) error {
+	d.ID = uuid.New().String()
+	m.deliveries = append(m.deliveries, d)
+	return nil
+}
+
+func (m *mockReportSubscriptionRepo) ListDeliveries(ctx context.Context, subscriptionID string, limit int) ([]*models.ReportDelivery, error) {
+	deliveries := []*models.ReportDelivery{}
+	for _, d := range m.deliveries {
+		if d.SubscriptionID == subscriptionID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestReportSubscriptionHandler(t *testing.T) {
+	user := &models.User{ID: "test-user-id", Email: "test@example.com", Name: "Test User"}
+
+	newApp := func(m mailer.Mailer) (*fiber.App, *mockReportSubscriptionRepo) {
+		repo := &mockReportSubscriptionRepo{subs: map[string]*models.ReportSubscription{
+			"other": {ID: "other", UserID: "someone-else", Name: "Theirs", Frequency: models.ReportFrequencyWeekly, Format: models.ReportFormatPDF, Active: true},
+		}}
+		dashboardRepo := &mockDashboardRepo{
+			summary: &models.DashboardSummaryResponse{BySeverity: map[string]int{}, ByStatus: map[string]int{}},
+			overdue: &models.ReviewListResponse{},
+			top:     &models.TopRiskListResponse{},
+		}
+		generator := reports.NewGenerator(dashboardRepo, &mockAnalyticsRepo{response: &models.AnalyticsResponse{}})
+		users := &mockUserRepo{users: map[string]*models.User{user.Email: user}}
+		deliverer := reports.NewDeliverer(generator, &mockExportRepo{}, users, repo, m)
+		handler := NewReportSubscriptionHandler(repo, deliverer)
+
+		app := fiber.New()
+		app.Use(testAuthMiddleware)
+		app.Get("/report-subscriptions", handler.List)
+		app.Post("/report-subscriptions", handler.Create)
+		app.Get("/report-subscriptions/:id", handler.Get)
+		app.Put("/report-subscriptions/:id", handler.Update)
+		app.Delete("/report-subscriptions/:id", handler.Delete)
+		app.Get("/report-subscriptions/:id/deliveries", handler.Deliveries)
+		app.Post("/report-subscriptions/:id/send", handler.Send)
+		return app, repo
+	}
+	request := func(app *fiber.App, method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw := new(bytes.Buffer)
+		raw.ReadFrom(resp.Body)
+		return resp.StatusCode, raw.Bytes()
+	}
+
+	t.Run("create schedules the first report", func(t *testing.T) {
+		app, repo := newApp(mailer.LogMailer{})
+		status, body := request(app, "POST", "/report-subscriptions", `{"name":"Board pack","frequency":"monthly","filters":{"sections":["summary","top_risks"]}}`)
+		if status != 201 {
+			t.Fatalf("expected status 201, got %d: %s", status, body)
+		}
+		var sub models.ReportSubscription
+		json.Unmarshal(body, &sub)
+		if sub.UserID != user.ID || sub.Format != models.ReportFormatPDF || !sub.Active {
+			t.Errorf("unexpected subscription: %+v", sub)
+		}
+		if sub.NextRunAt.Day() != 1 || !sub.NextRunAt.After(time.Now()) {
+			t.Errorf("expected the first run on the 1st of next month, got %s", sub.NextRunAt)
+		}
+		if len(repo.subs) != 2 {
+			t.Error("expected the subscription to be stored")
+		}
+	})
+
+	t.Run("create validates fields", func(t *testing.T) {
+		app, _ := newApp(mailer.LogMailer{})
+		status, body := request(app, "POST", "/report-subscriptions", `{"name":" ","frequency":"daily","format":"docx","filters":{"sections":["appendix"],"severity":"extreme","top_risks":99}}`)
+		if status != 400 {
+			t.Fatalf("expected status 400, got %d", status)
+		}
+		var resp struct {
+			Fields map[string]string `json:"fields"`
+		}
+		json.Unmarshal(body, &resp)
+		for _, field := range []string{"name", "frequency", "format", "filters.sections", "filters.severity", "filters.top_risks"} {
+			if resp.Fields[field] == "" {
+				t.Errorf("expected an error for %s, got %v", field, resp.Fields)
+			}
+		}
+	})
+
+	t.Run("only the owner can see a subscription", func(t *testing.T) {
+		app, _ := newApp(mailer.LogMailer{})
+		if status, _ := request(app, "GET", "/report-subscriptions/other", ""); status != 404 {
+			t.Errorf("expected status 404, got %d", status)
+		}
+		if status, _ := request(app, "DELETE", "/report-subscriptions/other", ""); status != 404 {
+			t.Errorf("expected status 404, got %d", status)
+		}
+		status, body := request(app, "GET", "/report-subscriptions", "")
+		if status != 200 || string(body) != "[]" {
+			t.Errorf("expected an empty list, got %d: %s", status, body)
+		}
+	})
+
+	t.Run("update reschedules on frequency change", func(t *testing.T) {
+		app, repo := newApp(mailer.LogMailer{})
+		past := time.Now().Add(-time.Hour)
+		repo.subs["mine"] = &models.ReportSubscription{ID: "mine", UserID: user.ID, Name: "Weekly", Frequency: models.ReportFrequencyWeekly,
+			Format: models.ReportFormatPDF, Active: true, NextRunAt: past}
+
+		status, body := request(app, "PUT", "/report-subscriptions/mine", `{"frequency":"monthly","format":"xlsx"}`)
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		sub := repo.subs["mine"]
+		if sub.Frequency != models.ReportFrequencyMonthly || sub.Format != models.ReportFormatXLSX || sub.NextRunAt.Equal(past) {
+			t.Errorf("unexpected subscription after update: %+v", sub)
+		}
+		if status, _ := request(app, "PUT", "/report-subscriptions/mine", `{}`); status != 400 {
+			t.Errorf("expected status 400 for an empty update, got %d", status)
+		}
+	})
+
+	t.Run("send records delivery history", func(t *testing.T) {
+		app, repo := newApp(mailer.LogMailer{})
+		repo.subs["mine"] = &models.ReportSubscription{ID: "mine", UserID: user.ID, Name: "Register", Frequency: models.ReportFrequencyWeekly,
+			Format: models.ReportFormatCSV, Active: true}
+
+		status, body := request(app, "POST", "/report-subscriptions/mine/send", "")
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, body)
+		}
+		status, body = request(app, "GET", "/report-subscriptions/mine/deliveries", "")
+		var history models.ReportDeliveryListResponse
+		json.Unmarshal(body, &history)
+		if status != 200 || len(history.Deliveries) != 1 {
+			t.Fatalf("expected 1 delivery, got %d: %s", status, body)
+		}
+		d := history.Deliveries[0]
+		if d.Status != models.ReportDeliverySent || !d.Manual || d.Recipient != user.Email || !strings.HasSuffix(d.Filename, ".csv") {
+			t.Errorf("unexpected delivery: %+v", d)
+		}
+	})
+
+	t.Run("failed send is recorded", func(t *testing.T) {
+		app, repo := newApp(failingMailer{})
+		repo.subs["mine"] = &models.ReportSubscription{ID: "mine", UserID: user.ID, Name: "Board pack", Frequency: models.ReportFrequencyWeekly,
+			Format: models.ReportFormatPDF, Active: true}
+
+		status, body := request(app, "POST", "/report-subscriptions/mine/send", "")
+		if status != 502 {
+			t.Fatalf("expected status 502, got %d: %s", status, body)
+		}
+		if len(repo.deliveries) != 1 || repo.deliveries[0].Status != models.ReportDeliveryFailed {
+			t.Errorf("expected a failed delivery to be recorded, got %+v", repo.deliveries)
+		}
+	})
+
+	t.Run("admins can manage any subscription", func(t *testing.T) {
+		repo := &mockReportSubscriptionRepo{subs: map[string]*models.ReportSubscription{
+			"other": {ID: "other", UserID: "someone-else", Name: "Theirs"},
+		}}
+		handler := NewReportSubscriptionHandler(repo, nil)
+		app := fiber.New()
+		app.Get("/report-subscriptions/:id", func(c *fiber.Ctx) error {
+			c.Locals(middleware.UserKey, &middleware.UserClaims{UserID: "admin-id", Role: "admin"})
+			return c.Next()
+		}, handler.Get)
+		if status, _ := request(app, "GET", "/report-subscriptions/other", ""); status != 200 {
+			t.Errorf("expected status 200, got %d", status)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"strconv"
+	"time"
+
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/reports"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type ReportHandler struct {
+	generator *reports.Generator
+}
+
+func NewReportHandler(generator *reports.Generator) *ReportHandler {
+	return &ReportHandler{generator: generator}
+}
+
+// RiskReport renders the risk register as a PDF for board packs. The
+// sections query parameter picks which parts are included, in report order.
+func (h *ReportHandler) RiskReport(c *fiber.Ctx) error {
+	sections, err := reports.ParseSections(c.Query("sections"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	granularity := models.AnalyticsGranularity(c.Query("granularity", "monthly"))
+	if granularity != models.GranularityMonthly && granularity != models.GranularityWeekly {
+		return c.Status(400).JSON(fiber.Map{"error": "granularity must be monthly or weekly"})
+	}
+
+	top := reports.DefaultTopRisks
+	if value := c.Query("top"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > reports.MaxTopRisks {
+			return c.Status(400).JSON(fiber.Map{"error": "top must be between 1 and " + strconv.Itoa(reports.MaxTopRisks)})
+		}
+		top = n
+	}
+
+	opts := reports.Options{
+		Title:       c.Query("title"),
+		Subtitle:    c.Query("subtitle"),
+		Cover:       c.QueryBool("cover", true),
+		Sections:    sections,
+		Granularity: granularity,
+		TopRisks:    top,
+		GeneratedAt: time.Now(),
+	}
+	if user := middleware.GetUserFromContext(c); user != nil {
+		opts.PreparedBy = user.Email
+	}
+
+	// Rendered into memory first so a failure can still return a JSON error
+	var buf bytes.Buffer
+	if err := h.generator.GeneratePDF(c.Context(), opts, &buf); err != nil {
+		log.Printf("risk report failed: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "failed to generate report"})
+	}
+
+	c.Attachment(reports.ReportFilename(opts.GeneratedAt))
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"backend/internal/models"
+	"backend/internal/reports"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestReportHandler_RiskReport(t *testing.T) {
+	dashboardRepo := &mockDashboardRepo{
+		summary: &models.DashboardSummaryResponse{TotalRisks: 1, BySeverity: map[string]int{"high": 1}, ByStatus: map[string]int{"open": 1}},
+		overdue: &models.ReviewListResponse{Risks: []models.ReviewRisk{}},
+		top:     &models.TopRiskListResponse{Risks: []models.TopRisk{{ID: "1", Title: "Vendor outage", Severity: "high", Status: "open"}}},
+	}
+	analyticsRepo := &mockAnalyticsRepo{response: &models.AnalyticsResponse{}}
+	handler := NewReportHandler(reports.NewGenerator(dashboardRepo, analyticsRepo))
+
+	app := fiber.New()
+	app.Get("/reports/risk", testAuthMiddleware, handler.RiskReport)
+
+	t.Run("renders a pdf", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reports/risk?sections=summary,top_risks&title=Board%20pack", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "application/pdf" {
+			t.Errorf("expected application/pdf, got %s", ct)
+		}
+		body := new(bytes.Buffer)
+		body.ReadFrom(resp.Body)
+		if !bytes.HasPrefix(body.Bytes(), []byte("%PDF-")) {
+			t.Error("expected a PDF body")
+		}
+	})
+
+	t.Run("rejects bad parameters", func(t *testing.T) {
+		for _, query := range []string{"sections=appendix", "top=0", "top=500", "granularity=daily"} {
+			resp, err := app.Test(httptest.NewRequest("GET", "/reports/risk?"+query, nil))
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != 400 {
+				t.Errorf("%s: expected status 400, got %d", query, resp.StatusCode)
+			}
+		}
+	})
+}
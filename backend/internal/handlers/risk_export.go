@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/reports"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RiskExportHandler struct {
+	exports database.ExportRepository
+}
+
+func NewRiskExportHandler(exports database.ExportRepository) *RiskExportHandler {
+	return &RiskExportHandler{exports: exports}
+}
+
+// Export writes every risk matching the list filters as a CSV, XLSX or JSON
+// file. Rows are streamed from the database to the client as they are read.
+func (h *RiskExportHandler) Export(c *fiber.Ctx) error {
+	format := c.Query("format", "csv")
+	contentType, ok := reports.ExportContentType(format)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be one of csv, xlsx, json"})
+	}
+	params := riskListParams(c)
+
+	c.Attachment(reports.ExportFilename(time.Now(), format))
+	c.Set(fiber.HeaderContentType, contentType)
+
+	// The stream writer runs after the handler returns, so it can't use the
+	// request context. Headers are already sent by then; a failure part way
+	// through can only be logged and leaves a truncated file.
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := reports.WriteRiskExport(context.Background(), w, h.exports, format, params); err != nil {
+			log.Printf("risk export failed: %v", err)
+		}
+	})
+	return nil
+}
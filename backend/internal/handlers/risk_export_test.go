@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+type mockExportRepo struct {
+	rows   []*models.RiskExportRow
+	params *models.RiskListParams
+}
+
+func (m *mockExportRepo) EachRisk(ctx context.Context, params *models.RiskListParams, fn func(*models.RiskExportRow) error) error {
+	m.params = params
+	for _, row := range m.rows {
+		if params.Status != nil && row.Status != *params.Status {
+			continue
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRiskExportHandler(t *testing.T) {
+	due := "2024-07-01"
+	review := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	repo := &mockExportRepo{rows: []*models.RiskExportRow{
+		{
+			ID: "risk-1", Title: "Vendor outage", OwnerName: "Ada", OwnerEmail: "ada@example.com",
+			Status: models.StatusOpen, Severity: models.SeverityHigh, Category: "Operational", ReviewDate: &review,
+			Tags: []string{"vendor", "q3"},
+			Mitigations: []models.RiskExportMitigation{
+				{Description: "Second supplier", Owner: "Ops", Status: models.MitigationStatusPlanned, DueDate: &due},
+			},
+			Controls: []models.RiskExportControl{{Framework: "ISO 27001", ControlRef: "A.5.19", Title: "Supplier relationships"}},
+		},
+		{ID: "risk-2", Title: "Key person", Status: models.StatusAccepted, Severity: models.SeverityLow, Tags: []string{}},
+	}}
+
+	app := fiber.New()
+	app.Get("/risks/export", testAuthMiddleware, NewRiskExportHandler(repo).Export)
+
+	get := func(t *testing.T, url string) (int, string, []byte) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body := new(bytes.Buffer)
+		body.ReadFrom(resp.Body)
+		return resp.StatusCode, resp.Header.Get("Content-Disposition"), body.Bytes()
+	}
+
+	t.Run("csv honours filters", func(t *testing.T) {
+		status, disposition, body := get(t, "/risks/export?format=csv&status=open&tag=vendor&sort=title&order=asc")
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		if !strings.Contains(disposition, "attachment") || !strings.Contains(disposition, ".csv") {
+			t.Errorf("unexpected Content-Disposition %q", disposition)
+		}
+		if repo.params.Tag != "vendor" || repo.params.Sort != "title" || repo.params.Order != "asc" {
+			t.Errorf("filters not passed through: %+v", repo.params)
+		}
+
+		records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid CSV: %v", err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("expected header and 1 row, got %d records", len(records))
+		}
+		row := map[string]string{}
+		for i, col := range records[0] {
+			row[col] = records[1][i]
+		}
+		if row["owner_name"] != "Ada" || row["review_date"] != "2024-06-30" || row["tags"] != "vendor; q3" {
+			t.Errorf("unexpected row: %v", row)
+		}
+		if row["mitigations"] != "Second supplier (planned, owner: Ops, due: 2024-07-01)" {
+			t.Errorf("unexpected mitigations cell %q", row["mitigations"])
+		}
+		if row["controls"] != "ISO 27001 A.5.19" {
+			t.Errorf("unexpected controls cell %q", row["controls"])
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		status, _, body := get(t, "/risks/export?format=json")
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		var rows []models.RiskExportRow
+		if err := json.Unmarshal(body, &rows); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if len(rows) != 2 || len(rows[0].Mitigations) != 1 || rows[0].Controls[0].Title != "Supplier relationships" {
+			t.Errorf("unexpected rows: %+v", rows)
+		}
+	})
+
+	t.Run("json with no matches is an empty array", func(t *testing.T) {
+		_, _, body := get(t, "/risks/export?format=json&status=resolved")
+		if strings.TrimSpace(string(body)) != "[]" {
+			t.Errorf("expected empty array, got %q", body)
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		status, _, body := get(t, "/risks/export?format=xlsx")
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d", status)
+		}
+		f, err := excelize.OpenReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("invalid XLSX: %v", err)
+		}
+		defer f.Close()
+		rows, err := f.GetRows("Risks")
+		if err != nil {
+			t.Fatalf("failed to read sheet: %v", err)
+		}
+		if len(rows) != 3 || rows[0][1] != "title" || rows[2][1] != "Key person" {
+			t.Errorf("unexpected sheet contents: %v", rows)
+		}
+	})
+
+	t.Run("rejects unknown format", func(t *testing.T) {
+		if status, _, _ := get(t, "/risks/export?format=pdf"); status != 400 {
+			t.Errorf("expected status 400, got %d", status)
+		}
+	})
+}
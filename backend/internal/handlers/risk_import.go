@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// The risk import takes a CSV or XLSX file whose first row holds column
+// headers. Columns are mapped to risk fields, either explicitly or by header
+// name, and every row is resolved and validated up front. Without commit=true
+// the response is only a preview; with it, the rows are created in one
+// transaction, and only if none of them has errors.
+
+// maxImportRows caps the number of data rows in one import file
+const maxImportRows = 5000
+
+var errImportFormat = errors.New("file must be a .csv or .xlsx spreadsheet")
+
+// importFieldAliases maps normalized header names to the field they fill when
+// no explicit mapping is given
+var importFieldAliases = map[string]models.RiskImportField{
+	"title":       models.ImportFieldTitle,
+	"description": models.ImportFieldDescription,
+	"owner":       models.ImportFieldOwnerEmail,
+	"owner_email": models.ImportFieldOwnerEmail,
+	"status":      models.ImportFieldStatus,
+	"severity":    models.ImportFieldSeverity,
+	"category":    models.ImportFieldCategory,
+	"review_date": models.ImportFieldReviewDate,
+	"tags":        models.ImportFieldTags,
+}
+
+type RiskImportHandler struct {
+	tx         database.Transactor
+	risks      database.RiskRepository
+	users      database.UserRepository
+	categories database.CategoryRepository
+	audit      database.AuditLogRepository
+}
+
+func NewRiskImportHandler(
+	tx database.Transactor,
+	risks database.RiskRepository,
+	users database.UserRepository,
+	categories database.CategoryRepository,
+	audit database.AuditLogRepository,
+) *RiskImportHandler {
+	return &RiskImportHandler{
+		tx:         tx,
+		risks:      risks,
+		users:      users,
+		categories: categories,
+		audit:      audit,
+	}
+}
+
+// importTable is a spreadsheet read into memory. Rows may be shorter than the
+// header when trailing cells are empty.
+type importTable struct {
+	format  string
+	headers []string
+	rows    [][]string
+	// excelDate converts an XLSX date serial; nil for CSV
+	excelDate func(serial float64) (time.Time, error)
+}
+
+// Import validates an uploaded risk spreadsheet and, when asked to, creates
+// the risks. Form fields: file (required), mapping (JSON object of field to
+// column header), sheet (XLSX only) and commit.
+func (h *RiskImportHandler) Import(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file is required"})
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "failed to read file"})
+	}
+	defer f.Close()
+
+	table, err := readImportTable(f, filepath.Ext(file.Filename), c.FormValue("sheet"))
+	if err != nil {
+		if errors.Is(err, errImportFormat) {
+			return c.Status(415).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	if len(table.rows) > maxImportRows {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("file has %d rows; at most %d can be imported at once", len(table.rows), maxImportRows),
+		})
+	}
+
+	mapping, errs := importMapping(table.headers, c.FormValue("mapping"))
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	rows, err := h.resolveRows(c.Context(), table, mapping)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to validate import"})
+	}
+
+	result := &models.RiskImportResult{
+		Filename:        file.Filename,
+		Format:          table.format,
+		Columns:         table.headers,
+		Mapping:         make(map[models.RiskImportField]string, len(mapping)),
+		UnmappedColumns: []string{},
+		TotalRows:       len(rows),
+		Rows:            rows,
+	}
+	mapped := make(map[int]bool, len(mapping))
+	for field, col := range mapping {
+		result.Mapping[field] = table.headers[col]
+		mapped[col] = true
+	}
+	for i, header := range table.headers {
+		if !mapped[i] {
+			result.UnmappedColumns = append(result.UnmappedColumns, header)
+		}
+	}
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			result.InvalidRows++
+		} else {
+			result.ValidRows++
+		}
+	}
+
+	if c.FormValue("commit") != "true" {
+		return c.JSON(result)
+	}
+	if result.InvalidRows > 0 || result.TotalRows == 0 {
+		return c.Status(422).JSON(result)
+	}
+
+	user := middleware.GetUserFromContext(c)
+	batchID := uuid.New().String()
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		return h.createRisks(c.Context(), tx, batchID, result, user.UserID)
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to import risks"})
+	}
+
+	result.BatchID = batchID
+	result.Committed = true
+	return c.Status(201).JSON(result)
+}
+
+// createRisks inserts every row and records one audit entry per risk plus a
+// risk_import entry for the batch as a whole
+func (h *RiskImportHandler) createRisks(ctx context.Context, tx *sql.Tx, batchID string, result *models.RiskImportResult, userID string) error {
+	risks := h.risks.WithTx(tx)
+	audit := h.audit.WithTx(tx)
+
+	riskIDs := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		risk := &models.Risk{
+			Title:       row.Title,
+			Description: row.Description,
+			OwnerID:     row.OwnerID,
+			Status:      row.Status,
+			Severity:    row.Severity,
+			CategoryID:  row.CategoryID,
+			Tags:        row.Tags,
+			CreatedBy:   userID,
+			UpdatedBy:   userID,
+		}
+		if row.ReviewDate != nil {
+			t, _ := time.Parse("2006-01-02", *row.ReviewDate)
+			risk.ReviewDate = &t
+		}
+
+		if err := risks.Create(ctx, risk); err != nil {
+			return err
+		}
+		if err := audit.Create(ctx, "risk", risk.ID, models.AuditActionCreated, riskAuditSnapshot(risk), userID); err != nil {
+			return err
+		}
+		row.RiskID = risk.ID
+		riskIDs = append(riskIDs, risk.ID)
+	}
+
+	return audit.Create(ctx, "risk_import", batchID, models.AuditActionCreated, map[string]any{
+		"filename": result.Filename,
+		"format":   result.Format,
+		"rows":     len(riskIDs),
+		"risk_ids": riskIDs,
+	}, userID)
+}
+
+// resolveRows turns each non-blank data row into the risk it describes,
+// looking owners up by email and categories by name
+func (h *RiskImportHandler) resolveRows(ctx context.Context, table *importTable, mapping map[models.RiskImportField]int) ([]*models.RiskImportRow, error) {
+	categories, err := h.categories.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryIDs[strings.ToLower(category.Name)] = category.ID
+	}
+	owners := make(map[string]string)
+
+	rows := []*models.RiskImportRow{}
+	for i, record := range table.rows {
+		cell := func(field models.RiskImportField) string {
+			col, ok := mapping[field]
+			if !ok || col >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[col])
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+
+		// Row numbers match the spreadsheet, counting the header as row 1
+		row := &models.RiskImportRow{
+			Row:         i + 2,
+			Title:       cell(models.ImportFieldTitle),
+			Description: cell(models.ImportFieldDescription),
+			OwnerEmail:  cell(models.ImportFieldOwnerEmail),
+			Status:      models.RiskStatus(strings.ToLower(cell(models.ImportFieldStatus))),
+			Severity:    models.RiskSeverity(strings.ToLower(cell(models.ImportFieldSeverity))),
+			Category:    cell(models.ImportFieldCategory),
+			Tags:        normalizeTags(splitImportTags(cell(models.ImportFieldTags))),
+		}
+		errs := fieldErrors{}
+
+		if row.Title == "" {
+			errs["title"] = "is required"
+		} else if len(row.Title) > 255 {
+			errs["title"] = "must be at most 255 characters"
+		}
+
+		if row.OwnerEmail == "" {
+			errs["owner_email"] = "is required"
+		} else if id, ok := owners[row.OwnerEmail]; ok {
+			row.OwnerID = id
+		} else {
+			user, err := h.users.FindByEmail(ctx, row.OwnerEmail)
+			if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+				return nil, err
+			}
+			if user != nil {
+				row.OwnerID = user.ID
+			}
+			owners[row.OwnerEmail] = row.OwnerID
+		}
+		if row.OwnerEmail != "" && row.OwnerID == "" {
+			errs["owner_email"] = "no user with this email"
+		}
+
+		if row.Status == "" {
+			row.Status = models.StatusOpen
+		}
+		status := string(row.Status)
+		requireOneOf(errs, "status", &status, string(models.StatusOpen), string(models.StatusMitigating),
+			string(models.StatusResolved), string(models.StatusAccepted))
+
+		if row.Severity == "" {
+			row.Severity = models.SeverityMedium
+		}
+		severity := string(row.Severity)
+		requireOneOf(errs, "severity", &severity, string(models.SeverityLow), string(models.SeverityMedium),
+			string(models.SeverityHigh), string(models.SeverityCritical))
+
+		if row.Category != "" {
+			if id, ok := categoryIDs[strings.ToLower(row.Category)]; ok {
+				row.CategoryID = &id
+			} else {
+				errs["category"] = "no category with this name"
+			}
+		}
+
+		if value := cell(models.ImportFieldReviewDate); value != "" {
+			if t, ok := table.parseDate(value); ok {
+				date := t.Format("2006-01-02")
+				row.ReviewDate = &date
+			} else {
+				errs["review_date"] = "must be a date in YYYY-MM-DD format"
+			}
+		}
+
+		if len(errs) > 0 {
+			row.Errors = errs
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseDate reads an ISO date, or for XLSX files the serial number Excel
+// stores date cells as
+func (t *importTable) parseDate(value string) (time.Time, bool) {
+	if d, err := time.Parse("2006-01-02", value); err == nil {
+		return d, true
+	}
+	if t.excelDate == nil {
+		return time.Time{}, false
+	}
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	d, err := t.excelDate(serial)
+	return d, err == nil
+}
+
+// importMapping resolves which column fills each field. An explicit mapping
+// replaces header matching entirely; either way title and owner_email must be
+// mapped.
+func importMapping(headers []string, explicit string) (map[models.RiskImportField]int, fieldErrors) {
+	mapping := make(map[models.RiskImportField]int)
+	errs := fieldErrors{}
+
+	if explicit == "" {
+		for i, header := range headers {
+			field, ok := importFieldAliases[normalizeImportHeader(header)]
+			if _, taken := mapping[field]; ok && !taken {
+				mapping[field] = i
+			}
+		}
+	} else {
+		var columns map[string]string
+		if err := json.Unmarshal([]byte(explicit), &columns); err != nil {
+			errs["mapping"] = "must be a JSON object of field to column header"
+			return nil, errs
+		}
+		for field, column := range columns {
+			if !isImportField(models.RiskImportField(field)) {
+				errs[field] = "is not an importable field"
+				continue
+			}
+			col := -1
+			for i, header := range headers {
+				if strings.EqualFold(strings.TrimSpace(header), strings.TrimSpace(column)) {
+					col = i
+					break
+				}
+			}
+			if col < 0 {
+				errs[field] = fmt.Sprintf("column %q not found", column)
+				continue
+			}
+			mapping[models.RiskImportField(field)] = col
+		}
+	}
+
+	for _, field := range []models.RiskImportField{models.ImportFieldTitle, models.ImportFieldOwnerEmail} {
+		if _, ok := mapping[field]; !ok && errs[string(field)] == "" {
+			errs[string(field)] = "must be mapped to a column"
+		}
+	}
+	return mapping, errs
+}
+
+func isImportField(field models.RiskImportField) bool {
+	for _, known := range importFieldAliases {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// readImportTable reads a CSV or XLSX upload, picking the parser by extension
+func readImportTable(r io.Reader, ext, sheet string) (*importTable, error) {
+	var table *importTable
+	var err error
+	switch strings.ToLower(ext) {
+	case ".csv":
+		table, err = readImportCSV(r)
+	case ".xlsx":
+		table, err = readImportXLSX(r, sheet)
+	default:
+		return nil, errImportFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(table.headers) == 0 || isBlankRecord(table.headers) {
+		return nil, errors.New("file has no header row")
+	}
+	return table, nil
+}
+
+func readImportCSV(r io.Reader) (*importTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+
+	table := &importTable{format: "csv"}
+	if len(records) > 0 {
+		// Spreadsheet programs often prefix UTF-8 CSV exports with a BOM
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+		table.headers = records[0]
+		table.rows = records[1:]
+	}
+	return table, nil
+}
+
+func readImportXLSX(r io.Reader, sheet string) (*importTable, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %v", err)
+	}
+	defer f.Close()
+
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	}
+	if idx, err := f.GetSheetIndex(sheet); err != nil || idx < 0 {
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+	records, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX: %v", err)
+	}
+
+	table := &importTable{
+		format: "xlsx",
+		excelDate: func(serial float64) (time.Time, error) {
+			return excelize.ExcelDateToTime(serial, false)
+		},
+	}
+	if len(records) > 0 {
+		table.headers = records[0]
+		table.rows = records[1:]
+	}
+	return table, nil
+}
+
+func normalizeImportHeader(header string) string {
+	header = strings.ToLower(strings.TrimSpace(header))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(header)
+}
+
+// splitImportTags splits a tags cell on commas or semicolons
+func splitImportTags(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestRiskImportHandler(t *testing.T) {
+	owner := &models.User{ID: "owner-id", Email: "owner@example.com", Name: "Owner"}
+	category := &models.Category{ID: "category-id", Name: "Operational"}
+
+	newApp := func() (*fiber.App, *mockRiskRepo, *mockAuditRepo) {
+		riskRepo := &mockRiskRepo{risks: map[string]*models.Risk{}}
+		auditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+		userRepo := &mockUserRepo{users: map[string]*models.User{owner.Email: owner}}
+		categoryRepo := &mockCategoryRepo{categories: map[string]*models.Category{category.ID: category}}
+		handler := NewRiskImportHandler(&mockTransactor{}, riskRepo, userRepo, categoryRepo, auditRepo)
+
+		app := fiber.New()
+		app.Post("/risks/import", testAuthMiddleware, handler.Import)
+		return app, riskRepo, auditRepo
+	}
+	upload := func(app *fiber.App, filename string, content []byte, fields map[string]string) (int, *models.RiskImportResult, string) {
+		body := new(bytes.Buffer)
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile("file", filename)
+		part.Write(content)
+		for k, v := range fields {
+			w.WriteField(k, v)
+		}
+		w.Close()
+
+		req := httptest.NewRequest("POST", "/risks/import", body)
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw := new(bytes.Buffer)
+		raw.ReadFrom(resp.Body)
+		var result models.RiskImportResult
+		json.Unmarshal(raw.Bytes(), &result)
+		return resp.StatusCode, &result, raw.String()
+	}
+
+	csvFile := []byte("\ufeffTitle,Owner,Severity,Category,Review Date,Tags,Notes\n" +
+		"Vendor outage,owner@example.com,High,operational,2024-06-30,\"vendor, q3\",ignored\n" +
+		",,,,,,\n" +
+		"Data leak,nobody@example.com,extreme,Unknown,30/06/2024,,\n")
+
+	t.Run("preview reports row errors without importing", func(t *testing.T) {
+		app, riskRepo, _ := newApp()
+		status, result, raw := upload(app, "register.csv", csvFile, nil)
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, raw)
+		}
+		if result.Committed || len(riskRepo.risks) != 0 {
+			t.Error("preview must not import risks")
+		}
+		if result.TotalRows != 2 || result.ValidRows != 1 || result.InvalidRows != 1 {
+			t.Errorf("unexpected counts: total=%d valid=%d invalid=%d", result.TotalRows, result.ValidRows, result.InvalidRows)
+		}
+		if len(result.UnmappedColumns) != 1 || result.UnmappedColumns[0] != "Notes" {
+			t.Errorf("expected Notes to be unmapped, got %v", result.UnmappedColumns)
+		}
+
+		valid := result.Rows[0]
+		if valid.Row != 2 || valid.OwnerID != owner.ID || valid.Severity != models.SeverityHigh || valid.Status != models.StatusOpen {
+			t.Errorf("unexpected resolved row: %+v", valid)
+		}
+		if valid.CategoryID == nil || *valid.CategoryID != category.ID {
+			t.Errorf("expected category to match by name, got %v", valid.CategoryID)
+		}
+		if len(valid.Tags) != 2 || valid.Tags[0] != "vendor" || valid.Tags[1] != "q3" {
+			t.Errorf("unexpected tags: %v", valid.Tags)
+		}
+
+		invalid := result.Rows[1]
+		if invalid.Row != 4 {
+			t.Errorf("expected spreadsheet row 4, got %d", invalid.Row)
+		}
+		for _, field := range []string{"owner_email", "severity", "category", "review_date"} {
+			if invalid.Errors[field] == "" {
+				t.Errorf("expected an error for %s, got %v", field, invalid.Errors)
+			}
+		}
+	})
+
+	t.Run("commit with invalid rows imports nothing", func(t *testing.T) {
+		app, riskRepo, auditRepo := newApp()
+		status, result, _ := upload(app, "register.csv", csvFile, map[string]string{"commit": "true"})
+		if status != 422 {
+			t.Errorf("expected status 422, got %d", status)
+		}
+		if result.Committed || len(riskRepo.risks) != 0 || len(auditRepo.logs) != 0 {
+			t.Error("expected nothing to be imported")
+		}
+	})
+
+	t.Run("commit imports valid file and records the batch", func(t *testing.T) {
+		app, riskRepo, auditRepo := newApp()
+		file := []byte("Risk Name,Email\nVendor outage,owner@example.com\nKey person,owner@example.com\n")
+		mapping := `{"title":"Risk Name","owner_email":"email"}`
+		status, result, raw := upload(app, "register.csv", file, map[string]string{"commit": "true", "mapping": mapping})
+		if status != 201 {
+			t.Fatalf("expected status 201, got %d: %s", status, raw)
+		}
+		if !result.Committed || result.BatchID == "" || len(riskRepo.risks) != 2 {
+			t.Errorf("expected 2 imported risks, got %d (%+v)", len(riskRepo.risks), result)
+		}
+		if result.Rows[0].RiskID == "" {
+			t.Error("expected imported rows to carry the new risk id")
+		}
+		if len(auditRepo.logs) != 3 {
+			t.Fatalf("expected 2 risk entries and 1 batch entry, got %d", len(auditRepo.logs))
+		}
+		batch := auditRepo.logs[2]
+		if batch.EntityType != "risk_import" || batch.EntityID != result.BatchID || batch.Changes["rows"] != 2 {
+			t.Errorf("unexpected batch audit entry: %+v", batch)
+		}
+	})
+
+	t.Run("reads xlsx with date cells", func(t *testing.T) {
+		app, _, _ := newApp()
+		f := excelize.NewFile()
+		sheet := f.GetSheetName(0)
+		f.SetSheetRow(sheet, "A1", &[]any{"title", "owner_email", "review_date"})
+		f.SetSheetRow(sheet, "A2", &[]any{"Vendor outage", "owner@example.com", time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)})
+		var buf bytes.Buffer
+		if err := f.Write(&buf); err != nil {
+			t.Fatalf("failed to build xlsx: %v", err)
+		}
+
+		status, result, raw := upload(app, "register.xlsx", buf.Bytes(), nil)
+		if status != 200 {
+			t.Fatalf("expected status 200, got %d: %s", status, raw)
+		}
+		if result.Format != "xlsx" || result.ValidRows != 1 {
+			t.Fatalf("unexpected result: %s", raw)
+		}
+		if date := result.Rows[0].ReviewDate; date == nil || *date != "2024-06-30" {
+			t.Errorf("expected review date 2024-06-30, got %v", date)
+		}
+	})
+
+	t.Run("rejects bad uploads", func(t *testing.T) {
+		app, _, _ := newApp()
+		tests := []struct {
+			name     string
+			filename string
+			content  string
+			fields   map[string]string
+			status   int
+		}{
+			{"unsupported type", "register.txt", "title\n", nil, 415},
+			{"missing owner column", "register.csv", "title\nRisk\n", nil, 400},
+			{"unknown mapping column", "register.csv", "title,owner\nRisk,a@b.c\n", map[string]string{"mapping": `{"title":"name","owner_email":"owner"}`}, 400},
+			{"unknown mapping field", "register.csv", "title,owner\nRisk,a@b.c\n", map[string]string{"mapping": `{"title":"title","owner_email":"owner","likelihood":"title"}`}, 400},
+			{"empty file", "register.csv", "", nil, 400},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, _, raw := upload(app, tt.filename, []byte(tt.content), tt.fields)
+				if status != tt.status {
+					t.Errorf("expected status %d, got %d: %s", tt.status, status, raw)
+				}
+			})
+		}
+	})
+}
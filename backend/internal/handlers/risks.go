@@ -46,10 +46,20 @@ func NewRiskHandler(risks database.RiskRepository, categories database.CategoryR
 }
 
 func (h *RiskHandler) List(c *fiber.Ctx) error {
+	response, err := h.risks.List(c.Context(), riskListParams(c))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risks"})
+	}
+	return c.JSON(response)
+}
+
+// riskListParams reads the list filters, sort and paging from the query string
+func riskListParams(c *fiber.Ctx) *models.RiskListParams {
 	params := &models.RiskListParams{
 		Page:  c.QueryInt("page", 1),
 		Limit: c.QueryInt("limit", 20),
 		Search: c.Query("search"),
+		Tag:    c.Query("tag"),
 		Sort:   c.Query("sort", "created_at"),
 		Order:  c.Query("order", "desc"),
 	}
@@ -68,12 +78,7 @@ func (h *RiskHandler) List(c *fiber.Ctx) error {
 	if ownerID := c.Query("owner_id"); ownerID != "" {
 		params.OwnerID = &ownerID
 	}
-
-	response, err := h.risks.List(c.Context(), params)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risks"})
-	}
-	return c.JSON(response)
+	return params
 }
 
 func (h *RiskHandler) Get(c *fiber.Ctx) error {
@@ -85,6 +90,7 @@ func (h *RiskHandler) Get(c *fiber.Ctx) error {
 		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risk"})
 	}
+	setVersionETag(c, risk.Version)
 	return c.JSON(risk)
 }
 
@@ -126,6 +132,7 @@ func (h *RiskHandler) Create(c *fiber.Ctx) error {
 		Status:      input.Status,
 		Severity:    input.Severity,
 		CategoryID:  categoryID,
+		Tags:        normalizeTags(input.Tags),
 		CreatedBy:   user.UserID,
 		UpdatedBy:   user.UserID,
 	}
@@ -140,19 +147,10 @@ func (h *RiskHandler) Create(c *fiber.Ctx) error {
 	if err := h.risks.Create(c.Context(), risk); err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "failed to create risk"})
 	}
+	setVersionETag(c, risk.Version)
 
-	// Log audit event
-	changes := map[string]any{
-		"title":       risk.Title,
-		"description": risk.Description,
-		"owner_id":    risk.OwnerID,
-		"status":      risk.Status,
-		"severity":    risk.Severity,
-	}
-	if risk.CategoryID != nil {
-		changes["category_id"] = *risk.CategoryID
-	}
-	h.audit.Create(c.Context(), "risk", risk.ID, models.AuditActionCreated, changes, user.UserID)
+	// Log audit event with a full snapshot so history can be replayed from it
+	h.audit.Create(c.Context(), "risk", risk.ID, models.AuditActionCreated, riskAuditSnapshot(risk), user.UserID)
 
 	return c.Status(201).JSON(risk)
 }
@@ -173,6 +171,9 @@ func (h *RiskHandler) Update(c *fiber.Ctx) error {
 		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risk"})
 	}
+	if err := checkIfMatch(c, risk.Version); err != nil {
+		return preconditionFailed(c, err, "risk", risk, risk.Version)
+	}
 
 	user := middleware.GetUserFromContext(c)
 	risk.UpdatedBy = user.UserID
@@ -202,10 +203,7 @@ func (h *RiskHandler) Update(c *fiber.Ctx) error {
 		risk.Severity = *input.Severity
 	}
 	if input.CategoryID != nil {
-		var oldCategoryID string
-		if risk.CategoryID != nil {
-			oldCategoryID = *risk.CategoryID
-		}
+		oldCategoryID := auditString(risk.CategoryID)
 
 		// Normalize category ID first (handles empty string -> nil for clearing category)
 		normalizedCategoryID := h.normalizeCategoryID(input.CategoryID)
@@ -239,18 +237,22 @@ func (h *RiskHandler) Update(c *fiber.Ctx) error {
 		}
 	}
 	if input.ReviewDate != nil {
-		var oldReviewDate string
-		if risk.ReviewDate != nil {
-			oldReviewDate = risk.ReviewDate.Format("2006-01-02")
-		}
 		t, err := time.Parse("2006-01-02", *input.ReviewDate)
 		if err == nil {
-			changes["review_date"] = map[string]any{"from": oldReviewDate, "to": *input.ReviewDate}
+			changes["review_date"] = auditChange(auditDate(risk.ReviewDate), auditDate(&t))
 			risk.ReviewDate = &t
 		}
 	}
+	if input.Tags != nil {
+		tags := normalizeTags(*input.Tags)
+		changes["tags"] = auditChange(auditTags(risk.Tags), tags)
+		risk.Tags = tags
+	}
 
 	if err := h.risks.Update(c.Context(), risk); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to update risk"})
 	}
 
@@ -259,23 +261,142 @@ func (h *RiskHandler) Update(c *fiber.Ctx) error {
 		h.audit.Create(c.Context(), "risk", risk.ID, models.AuditActionUpdated, changes, user.UserID)
 	}
 
+	setVersionETag(c, risk.Version)
 	return c.JSON(risk)
 }
 
-func (h *RiskHandler) Delete(c *fiber.Ctx) error {
+// Patch applies a JSON merge patch to a risk. Unlike Update, a member set to
+// null clears the field, so category_id and review_date can be removed.
+func (h *RiskHandler) Patch(c *fiber.Ctx) error {
 	id := c.Params("id")
 
+	patch, err := parseMergePatch(c)
+	if err != nil {
+		return patchError(c, err)
+	}
+
+	risk, err := h.risks.FindByID(c.Context(), id)
+	if err != nil {
+		if err == database.ErrRiskNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "risk not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risk"})
+	}
+	if err := checkIfMatch(c, risk.Version); err != nil {
+		return preconditionFailed(c, err, "risk", risk, risk.Version)
+	}
+
+	errs := fieldErrors{}
+	patch.allow(errs, "title", "description", "owner_id", "status", "severity", "category_id", "review_date", "tags")
+	changes := make(map[string]any)
+
+	if v, ok := patch.string("title", errs); ok && requireText(errs, "title", v, 255) {
+		changes["title"] = auditChange(risk.Title, *v)
+		risk.Title = *v
+	}
+	if v, ok := patch.string("description", errs); ok {
+		changes["description"] = auditChange(risk.Description, textOrEmpty(v))
+		risk.Description = textOrEmpty(v)
+	}
+	if v, ok := patch.string("owner_id", errs); ok && requireUUID(errs, "owner_id", v) {
+		changes["owner_id"] = auditChange(risk.OwnerID, *v)
+		risk.OwnerID = *v
+	}
+	if v, ok := patch.string("status", errs); ok && requireOneOf(errs, "status", v,
+		string(models.StatusOpen), string(models.StatusMitigating), string(models.StatusResolved), string(models.StatusAccepted)) {
+		changes["status"] = auditChange(string(risk.Status), *v)
+		risk.Status = models.RiskStatus(*v)
+	}
+	if v, ok := patch.string("severity", errs); ok && requireOneOf(errs, "severity", v,
+		string(models.SeverityLow), string(models.SeverityMedium), string(models.SeverityHigh), string(models.SeverityCritical)) {
+		changes["severity"] = auditChange(string(risk.Severity), *v)
+		risk.Severity = models.RiskSeverity(*v)
+	}
+	if v, ok := patch.string("category_id", errs); ok {
+		if v == nil {
+			changes["category_id"] = auditChange(auditString(risk.CategoryID), nil)
+			risk.CategoryID = nil
+			risk.Category = nil
+		} else if cat, err := h.categories.FindByID(c.Context(), *v); err != nil || cat == nil {
+			errs["category_id"] = "does not exist"
+		} else {
+			changes["category_id"] = auditChange(auditString(risk.CategoryID), *v)
+			risk.CategoryID = v
+			risk.Category = cat
+		}
+	}
+	if v, ok := patch.date("review_date", errs); ok {
+		changes["review_date"] = auditChange(auditDate(risk.ReviewDate), auditDate(v))
+		risk.ReviewDate = v
+	}
+	if v, ok := patch.strings("tags", errs); ok {
+		tags := normalizeTags(v)
+		changes["tags"] = auditChange(auditTags(risk.Tags), tags)
+		risk.Tags = tags
+	}
+
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if len(changes) == 0 {
+		setVersionETag(c, risk.Version)
+		return c.JSON(risk)
+	}
+
 	user := middleware.GetUserFromContext(c)
+	risk.UpdatedBy = user.UserID
 
-	// Log audit event before deletion
-	h.audit.Create(c.Context(), "risk", id, models.AuditActionDeleted, nil, user.UserID)
+	if err := h.risks.Update(c.Context(), risk); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update risk"})
+	}
+	h.audit.Create(c.Context(), "risk", risk.ID, models.AuditActionUpdated, changes, user.UserID)
+
+	setVersionETag(c, risk.Version)
+	return c.JSON(risk)
+}
 
-	if err := h.risks.Delete(c.Context(), id); err != nil {
+func (h *RiskHandler) Delete(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	risk, err := h.risks.FindByID(c.Context(), id)
+	if err != nil {
+		if err == database.ErrRiskNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "risk not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risk"})
+	}
+	if err := checkIfMatch(c, risk.Version); err != nil {
+		return preconditionFailed(c, err, "risk", risk, risk.Version)
+	}
+
+	user := middleware.GetUserFromContext(c)
+
+	if err := h.risks.Delete(c.Context(), id, risk.Version); err != nil {
+		if errors.Is(err, database.ErrVersionConflict) {
+			return h.conflict(c, id)
+		}
 		if err == database.ErrRiskNotFound {
 			return c.Status(404).JSON(fiber.Map{"error": "risk not found"})
 		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to delete risk"})
 	}
+	h.audit.Create(c.Context(), "risk", id, models.AuditActionDeleted, nil, user.UserID)
 
 	return c.SendStatus(204)
 }
+
+// conflict answers an update that lost a race with another writer after
+// passing the If-Match check, returning the copy that won.
+func (h *RiskHandler) conflict(c *fiber.Ctx, id string) error {
+	current, err := h.risks.FindByID(c.Context(), id)
+	if err != nil {
+		if err == database.ErrRiskNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "risk not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risk"})
+	}
+	return preconditionFailed(c, errIfMatchFailed, "risk", current, current.Version)
+}
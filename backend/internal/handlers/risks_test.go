@@ -3,8 +3,11 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"sort"
 	"testing"
 	"time"
 
@@ -18,6 +21,9 @@ import (
 
 type mockRiskRepo struct {
 	risks map[string]*models.Risk
+	// beforeDelete, when set, runs at the start of Delete to simulate a
+	// concurrent write
+	beforeDelete func()
 }
 
 func (m *mockRiskRepo) Create(ctx context.Context, risk *models.Risk) error {
@@ -26,6 +32,7 @@ func (m *mockRiskRepo) Create(ctx context.Context, risk *models.Risk) error {
 	}
 	risk.CreatedAt = time.Now()
 	risk.UpdatedAt = time.Now()
+	risk.Version++
 	m.risks[risk.ID] = risk
 	return nil
 }
@@ -52,20 +59,84 @@ func (m *mockRiskRepo) List(ctx context.Context, params *models.RiskListParams)
 	}, nil
 }
 
+func (m *mockRiskRepo) ListIDs(ctx context.Context, params *models.RiskListParams) ([]string, error) {
+	var ids []string
+	for id, risk := range m.risks {
+		if params.Status != nil && risk.Status != *params.Status {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
 func (m *mockRiskRepo) Update(ctx context.Context, risk *models.Risk) error {
 	if _, ok := m.risks[risk.ID]; !ok {
 		return nil
 	}
 	risk.UpdatedAt = time.Now()
+	risk.Version++
 	m.risks[risk.ID] = risk
 	return nil
 }
 
-func (m *mockRiskRepo) Delete(ctx context.Context, id string) error {
+func (m *mockRiskRepo) Delete(ctx context.Context, id string, version int) error {
+	if m.beforeDelete != nil {
+		m.beforeDelete()
+	}
+	risk, ok := m.risks[id]
+	if !ok {
+		return database.ErrRiskNotFound
+	}
+	if risk.Version != version {
+		return database.ErrVersionConflict
+	}
 	delete(m.risks, id)
 	return nil
 }
 
+func (m *mockRiskRepo) ListDeleted(ctx context.Context) ([]*models.Risk, error) {
+	var risks []*models.Risk
+	for _, risk := range m.risks {
+		if risk.DeletedAt != nil {
+			risks = append(risks, risk)
+		}
+	}
+	return risks, nil
+}
+
+func (m *mockRiskRepo) Restore(ctx context.Context, id string) error {
+	risk, ok := m.risks[id]
+	if !ok {
+		return database.ErrRiskNotFound
+	}
+	if risk.DeletedAt == nil {
+		return database.ErrNotInTrash
+	}
+	risk.DeletedAt = nil
+	return nil
+}
+
+func (m *mockRiskRepo) Purge(ctx context.Context, id string, deletedBefore time.Time) error {
+	risk, ok := m.risks[id]
+	if !ok {
+		return database.ErrRiskNotFound
+	}
+	if risk.DeletedAt == nil {
+		return database.ErrNotInTrash
+	}
+	if risk.DeletedAt.After(deletedBefore) {
+		return database.ErrPurgeGracePeriod
+	}
+	delete(m.risks, id)
+	return nil
+}
+
+func (m *mockRiskRepo) WithTx(tx *sql.Tx) database.RiskRepository {
+	return m
+}
+
 type mockCategoryRepo struct {
 	categories map[string]*models.Category
 }
@@ -138,6 +209,16 @@ func (m *mockAuditRepo) ListByEntity(ctx context.Context, entityType, entityID s
 	return result, nil
 }
 
+func (m *mockAuditRepo) ListHistory(ctx context.Context, entityType, entityID string, until time.Time) ([]*models.AuditLog, error) {
+	var result []*models.AuditLog
+	for _, log := range m.logs {
+		if log.EntityType == entityType && log.EntityID == entityID && !log.CreatedAt.After(until) {
+			result = append(result, log)
+		}
+	}
+	return result, nil
+}
+
 func (m *mockAuditRepo) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
 	log := &models.AuditLog{
 		ID:         uuid.New().String(),
@@ -152,6 +233,10 @@ func (m *mockAuditRepo) Create(ctx context.Context, entityType, entityID string,
 	return nil
 }
 
+func (m *mockAuditRepo) WithTx(tx *sql.Tx) database.AuditLogRepository {
+	return m
+}
+
 // testAuthMiddleware sets up a user context for testing protected handlers
 func testAuthMiddleware(c *fiber.Ctx) error {
 	c.Locals(middleware.UserKey, &middleware.UserClaims{
@@ -418,6 +503,7 @@ func TestUpdateRiskHandler_ValidInput(t *testing.T) {
 
 	req := httptest.NewRequest("PUT", "/risks/"+testRisk.ID, bytes.NewReader(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", versionETag(testRisk.Version))
 
 	resp, err := app.Test(req)
 	if err != nil {
@@ -436,6 +522,225 @@ func TestUpdateRiskHandler_ValidInput(t *testing.T) {
 	}
 }
 
+func TestUpdateRiskHandler_IfMatch(t *testing.T) {
+	app := fiber.New()
+	mockRiskRepo := &mockRiskRepo{risks: make(map[string]*models.Risk)}
+	mockCategoryRepo := &mockCategoryRepo{categories: make(map[string]*models.Category)}
+	mockAuditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewRiskHandler(mockRiskRepo, mockCategoryRepo, mockAuditRepo)
+
+	testRisk := &models.Risk{
+		ID:      uuid.New().String(),
+		Title:   "Original Title",
+		Status:  "open",
+		Version: 3,
+	}
+	mockRiskRepo.risks[testRisk.ID] = testRisk
+
+	app.Get("/risks/:id", testAuthMiddleware, handler.Get)
+	app.Put("/risks/:id", testAuthMiddleware, handler.Update)
+	app.Delete("/risks/:id", testAuthMiddleware, handler.Delete)
+
+	put := func(ifMatch string) *http.Response {
+		body, _ := json.Marshal(map[string]interface{}{"title": "Updated Title"})
+		req := httptest.NewRequest("PUT", "/risks/"+testRisk.ID, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("Get Sets ETag", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/risks/"+testRisk.ID, nil))
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if etag := resp.Header.Get("ETag"); etag != `"3"` {
+			t.Errorf("expected ETag \"3\", got %s", etag)
+		}
+	})
+
+	t.Run("Missing If-Match", func(t *testing.T) {
+		resp := put("")
+		if resp.StatusCode != 428 {
+			t.Errorf("expected status 428, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Stale If-Match", func(t *testing.T) {
+		resp := put(`"2"`)
+		if resp.StatusCode != 412 {
+			t.Fatalf("expected status 412, got %d", resp.StatusCode)
+		}
+
+		var response struct {
+			Current models.Risk `json:"current"`
+		}
+		json.NewDecoder(resp.Body).Decode(&response)
+		if response.Current.Version != 3 || response.Current.Title != "Original Title" {
+			t.Errorf("expected the current server copy, got %+v", response.Current)
+		}
+		if len(mockAuditRepo.logs) != 0 {
+			t.Errorf("a rejected update should not be audited")
+		}
+	})
+
+	t.Run("Matching If-Match", func(t *testing.T) {
+		resp := put(`"3"`)
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		if etag := resp.Header.Get("ETag"); etag != `"4"` {
+			t.Errorf("expected ETag \"4\", got %s", etag)
+		}
+	})
+
+	t.Run("Delete With Stale If-Match", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/risks/"+testRisk.ID, nil)
+		req.Header.Set("If-Match", `"3"`)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 412 {
+			t.Errorf("expected status 412, got %d", resp.StatusCode)
+		}
+		if _, exists := mockRiskRepo.risks[testRisk.ID]; !exists {
+			t.Errorf("risk should not have been deleted")
+		}
+	})
+
+	t.Run("Delete Racing An Update", func(t *testing.T) {
+		stored := mockRiskRepo.risks[testRisk.ID]
+		mockRiskRepo.beforeDelete = func() {
+			updated := *stored
+			updated.Title = "Concurrent Title"
+			updated.Version++
+			mockRiskRepo.risks[testRisk.ID] = &updated
+		}
+		defer func() { mockRiskRepo.beforeDelete = nil }()
+
+		req := httptest.NewRequest("DELETE", "/risks/"+testRisk.ID, nil)
+		req.Header.Set("If-Match", versionETag(stored.Version))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 412 {
+			t.Fatalf("expected status 412, got %d", resp.StatusCode)
+		}
+		var response struct {
+			Current models.Risk `json:"current"`
+		}
+		json.NewDecoder(resp.Body).Decode(&response)
+		if response.Current.Title != "Concurrent Title" || response.Current.Version != stored.Version+1 {
+			t.Errorf("expected the concurrent copy, got %+v", response.Current)
+		}
+		if _, exists := mockRiskRepo.risks[testRisk.ID]; !exists {
+			t.Errorf("risk should not have been deleted")
+		}
+		for _, log := range mockAuditRepo.logs {
+			if log.Action == models.AuditActionDeleted {
+				t.Errorf("a rejected delete should not be audited")
+			}
+		}
+	})
+}
+
+func TestPatchRiskHandler(t *testing.T) {
+	app := fiber.New()
+	mockRiskRepo := &mockRiskRepo{risks: make(map[string]*models.Risk)}
+	mockCategoryRepo := &mockCategoryRepo{categories: make(map[string]*models.Category)}
+	mockAuditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewRiskHandler(mockRiskRepo, mockCategoryRepo, mockAuditRepo)
+
+	category := &models.Category{ID: uuid.New().String(), Name: "Operational"}
+	mockCategoryRepo.categories[category.ID] = category
+
+	reviewDate := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	testRisk := &models.Risk{
+		ID:         uuid.New().String(),
+		Title:      "Original Title",
+		Status:     "open",
+		Severity:   "medium",
+		CategoryID: &category.ID,
+		Category:   category,
+		ReviewDate: &reviewDate,
+	}
+	mockRiskRepo.risks[testRisk.ID] = testRisk
+
+	app.Patch("/risks/:id", testAuthMiddleware, handler.Patch)
+
+	patch := func(body, contentType string) *http.Response {
+		req := httptest.NewRequest("PATCH", "/risks/"+testRisk.ID, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", versionETag(testRisk.Version))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("Explicit Null Clears Fields", func(t *testing.T) {
+		resp := patch(`{"review_date": null, "category_id": null}`, "application/merge-patch+json")
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+		if testRisk.ReviewDate != nil || testRisk.CategoryID != nil {
+			t.Errorf("expected review_date and category_id to be cleared")
+		}
+		if testRisk.Title != "Original Title" {
+			t.Errorf("absent members should be left untouched, got title %q", testRisk.Title)
+		}
+
+		last := mockAuditRepo.logs[len(mockAuditRepo.logs)-1]
+		change, _ := last.Changes["review_date"].(map[string]any)
+		if change["from"] != "2026-03-01" || change["to"] != nil {
+			t.Errorf("expected review_date change to null in audit log, got %v", last.Changes["review_date"])
+		}
+	})
+
+	t.Run("Field Errors", func(t *testing.T) {
+		resp := patch(`{"title": "", "severity": "extreme", "review_date": "soon", "owner_id": null, "id": "x"}`, "application/json")
+		if resp.StatusCode != 400 {
+			t.Fatalf("expected status 400, got %d", resp.StatusCode)
+		}
+
+		var response struct {
+			Fields map[string]string `json:"fields"`
+		}
+		json.NewDecoder(resp.Body).Decode(&response)
+		for _, field := range []string{"title", "severity", "review_date", "owner_id", "id"} {
+			if _, ok := response.Fields[field]; !ok {
+				t.Errorf("expected a field error for %s, got %v", field, response.Fields)
+			}
+		}
+		if testRisk.Severity != "medium" {
+			t.Errorf("an invalid patch should not be applied")
+		}
+	})
+
+	t.Run("Not An Object", func(t *testing.T) {
+		resp := patch(`["title"]`, "application/merge-patch+json")
+		if resp.StatusCode != 400 {
+			t.Errorf("expected status 400, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Unsupported Media Type", func(t *testing.T) {
+		resp := patch(`{"title": "New"}`, "text/plain")
+		if resp.StatusCode != 415 {
+			t.Errorf("expected status 415, got %d", resp.StatusCode)
+		}
+	})
+}
+
 func TestDeleteRiskHandler(t *testing.T) {
 	app := fiber.New()
 	mockRiskRepo := &mockRiskRepo{risks: make(map[string]*models.Risk)}
@@ -453,6 +758,7 @@ func TestDeleteRiskHandler(t *testing.T) {
 	app.Delete("/risks/:id", testAuthMiddleware, handler.Delete)
 
 	req := httptest.NewRequest("DELETE", "/risks/"+testRisk.ID, nil)
+	req.Header.Set("If-Match", versionETag(testRisk.Version))
 
 	resp, err := app.Test(req)
 	if err != nil {
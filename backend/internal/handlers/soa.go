@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/reports"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// The Statement of Applicability records, for each control of a framework,
+// whether it applies, why and how far it is implemented. The working SoA is
+// built from the current records; submitting it freezes a numbered version
+// that an admin approves or rejects, so each audit cycle has a signed-off
+// copy that later ones can be compared with.
+
+var (
+	applicabilities        = []string{string(models.ApplicabilityApplicable), string(models.ApplicabilityExcluded)}
+	implementationStatuses = []string{
+		string(models.ImplementationNotImplemented), string(models.ImplementationPlanned),
+		string(models.ImplementationPartial), string(models.ImplementationImplemented),
+	}
+)
+
+type SoAHandler struct {
+	tx    database.Transactor
+	soa   database.SoARepository
+	audit database.AuditLogRepository
+}
+
+func NewSoAHandler(tx database.Transactor, soa database.SoARepository, audit database.AuditLogRepository) *SoAHandler {
+	return &SoAHandler{tx: tx, soa: soa, audit: audit}
+}
+
+// Get returns the working SoA of the :id framework
+func (h *SoAHandler) Get(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrFrameworkNotFound, ErrFailedToFetch)
+	}
+	soa, err := h.soa.Get(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(soa)
+}
+
+// Export downloads the working SoA as CSV or XLSX
+func (h *SoAHandler) Export(c *fiber.Ctx) error {
+	format, contentType, ok := soaExportFormat(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be one of csv, xlsx"})
+	}
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrFrameworkNotFound, ErrFailedToFetch)
+	}
+	soa, err := h.soa.Get(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+
+	draft := &models.SoAVersion{
+		FrameworkID:   soa.FrameworkID,
+		FrameworkName: soa.FrameworkName,
+		Status:        models.SoADraft,
+		Summary:       soa.Summary,
+		Entries:       soa.Entries,
+		CreatedAt:     time.Now(),
+	}
+	return sendSoAExport(c, format, contentType, draft)
+}
+
+// SetApplicability creates or replaces the applicability record of the :id
+// control
+func (h *SoAHandler) SetApplicability(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrFrameworkControlNotFound, ErrFailedToFetch)
+	}
+
+	var input models.SetApplicabilityInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	input.Justification = strings.TrimSpace(input.Justification)
+	if input.ImplementationStatus == "" {
+		input.ImplementationStatus = models.ImplementationNotImplemented
+	}
+	if input.OwnerID != nil && *input.OwnerID == "" {
+		input.OwnerID = nil
+	}
+
+	errs := fieldErrors{}
+	requireOneOf(errs, "applicability", (*string)(&input.Applicability), applicabilities...)
+	requireOneOf(errs, "implementation_status", (*string)(&input.ImplementationStatus), implementationStatuses...)
+	optionalUUID(errs, "owner_id", input.OwnerID)
+	if input.Applicability == models.ApplicabilityExcluded && input.Justification == "" {
+		errs["justification"] = "is required for an excluded control"
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	before, err := h.soa.GetEntry(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+	user := middleware.GetUserFromContext(c)
+	if err := h.soa.SetApplicability(c.Context(), id, &input, user.UserID); err != nil {
+		return soaError(c, err, ErrFailedToUpdate)
+	}
+	after, err := h.soa.GetEntry(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+
+	if !before.Assessed {
+		h.audit.Create(c.Context(), "control_applicability", id, models.AuditActionCreated, soaEntryAuditSnapshot(after), user.UserID)
+	} else if changes := soaEntryAuditChanges(before, after); len(changes) > 0 {
+		h.audit.Create(c.Context(), "control_applicability", id, models.AuditActionUpdated, changes, user.UserID)
+	}
+	return c.JSON(after)
+}
+
+// ListVersions lists the :id framework's SoA versions, newest first
+func (h *SoAHandler) ListVersions(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrFrameworkNotFound, ErrFailedToFetch)
+	}
+	versions, err := h.soa.ListVersions(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(fiber.Map{"data": versions})
+}
+
+// CreateVersion freezes the working SoA of the :id framework as a new
+// version awaiting approval. Every control has to have been assessed.
+func (h *SoAHandler) CreateVersion(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrFrameworkNotFound, ErrFailedToFetch)
+	}
+	var input models.CreateSoAVersionInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+		}
+	}
+
+	soa, err := h.soa.Get(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+	if len(soa.Entries) == 0 {
+		return c.Status(422).JSON(fiber.Map{"error": "framework has no controls"})
+	}
+	if soa.Summary.Unassessed > 0 {
+		unassessed := []string{}
+		for _, entry := range soa.Entries {
+			if !entry.Assessed {
+				unassessed = append(unassessed, entry.ControlRef)
+			}
+		}
+		return c.Status(422).JSON(fiber.Map{
+			"error":      "every control must be assessed before the SoA is submitted",
+			"unassessed": unassessed,
+		})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	version := &models.SoAVersion{
+		FrameworkID: id,
+		Title:       strings.TrimSpace(input.Title),
+		Notes:       input.Notes,
+		Entries:     soa.Entries,
+		CreatedBy:   &user.UserID,
+	}
+	err = h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		if err := h.soa.WithTx(tx).CreateVersion(c.Context(), version); err != nil {
+			return err
+		}
+		return h.audit.WithTx(tx).Create(c.Context(), "soa_version", version.ID, models.AuditActionCreated, map[string]any{
+			"framework_id": id,
+			"version":      version.Version,
+			"title":        version.Title,
+			"status":       string(version.Status),
+		}, user.UserID)
+	})
+	if err != nil {
+		return soaError(c, err, ErrFailedToCreate)
+	}
+	return h.respondVersion(c, 201, version.ID)
+}
+
+func (h *SoAHandler) GetVersion(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrSoAVersionNotFound, ErrFailedToFetch)
+	}
+	return h.respondVersion(c, 200, id)
+}
+
+// ExportVersion downloads an SoA version as CSV or XLSX
+func (h *SoAHandler) ExportVersion(c *fiber.Ctx) error {
+	format, contentType, ok := soaExportFormat(c)
+	if !ok {
+		return c.Status(400).JSON(fiber.Map{"error": "format must be one of csv, xlsx"})
+	}
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrSoAVersionNotFound, ErrFailedToFetch)
+	}
+	version, err := h.soa.GetVersion(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+	return sendSoAExport(c, format, contentType, version)
+}
+
+// Approve signs off a pending version, with an optional comment
+func (h *SoAHandler) Approve(c *fiber.Ctx) error {
+	return h.decide(c, models.SoAApproved)
+}
+
+// Reject turns down a pending version; the comment explains why
+func (h *SoAHandler) Reject(c *fiber.Ctx) error {
+	return h.decide(c, models.SoARejected)
+}
+
+func (h *SoAHandler) decide(c *fiber.Ctx, status models.SoAVersionStatus) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrSoAVersionNotFound, ErrFailedToFetch)
+	}
+	var input models.SoADecisionInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+		}
+	}
+	input.Comment = strings.TrimSpace(input.Comment)
+	if status == models.SoARejected && input.Comment == "" {
+		return validationFailed(c, fieldErrors{"comment": "is required when rejecting"})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	err := h.tx.InTx(c.Context(), func(tx *sql.Tx) error {
+		if err := h.soa.WithTx(tx).Decide(c.Context(), id, status, input.Comment, user.UserID); err != nil {
+			return err
+		}
+		return h.audit.WithTx(tx).Create(c.Context(), "soa_version", id, models.AuditActionUpdated, map[string]any{
+			"status":  auditChange(string(models.SoAPending), string(status)),
+			"comment": input.Comment,
+		}, user.UserID)
+	})
+	if err != nil {
+		return soaError(c, err, ErrFailedToUpdate)
+	}
+	return h.respondVersion(c, 200, id)
+}
+
+// Compare lists what changed between two SoA versions of the :id framework:
+// from and to are version numbers, and without to the working SoA is used
+func (h *SoAHandler) Compare(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if uuid.Validate(id) != nil {
+		return soaError(c, database.ErrFrameworkNotFound, ErrFailedToFetch)
+	}
+	errs := fieldErrors{}
+	from := soaVersionNumber(errs, "from", c.Query("from"))
+	var to *int
+	if c.Query("to") != "" {
+		n := soaVersionNumber(errs, "to", c.Query("to"))
+		to = &n
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	fromVersion, err := h.soa.GetVersionByNumber(c.Context(), id, from)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+	var toEntries []*models.SoAEntry
+	if to != nil {
+		toVersion, err := h.soa.GetVersionByNumber(c.Context(), id, *to)
+		if err != nil {
+			return soaError(c, err, ErrFailedToFetch)
+		}
+		toEntries = toVersion.Entries
+	} else {
+		soa, err := h.soa.Get(c.Context(), id)
+		if err != nil {
+			return soaError(c, err, ErrFailedToFetch)
+		}
+		toEntries = soa.Entries
+	}
+
+	comparison := compareSoA(fromVersion.Entries, toEntries)
+	comparison.FrameworkID = id
+	comparison.FromVersion = from
+	comparison.ToVersion = to
+	return c.JSON(comparison)
+}
+
+func soaVersionNumber(errs fieldErrors, field, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		errs[field] = "must be a version number"
+	}
+	return n
+}
+
+// compareSoA matches entries by control_ref and lists those only in to
+// (added), only in from (removed) and in both with different fields
+func compareSoA(from, to []*models.SoAEntry) *models.SoAComparison {
+	comparison := &models.SoAComparison{
+		Added:   []*models.SoAEntry{},
+		Removed: []*models.SoAEntry{},
+		Changed: []*models.SoAEntryChange{},
+	}
+	before := make(map[string]*models.SoAEntry, len(from))
+	for _, entry := range from {
+		before[entry.ControlRef] = entry
+	}
+	seen := make(map[string]bool, len(to))
+	for _, entry := range to {
+		seen[entry.ControlRef] = true
+		old, ok := before[entry.ControlRef]
+		if !ok {
+			comparison.Added = append(comparison.Added, entry)
+			continue
+		}
+		if changes := diffHistoryStates(soaEntryFields(old), soaEntryFields(entry)); len(changes) > 0 {
+			comparison.Changed = append(comparison.Changed, &models.SoAEntryChange{
+				ControlRef: entry.ControlRef,
+				Title:      entry.Title,
+				Changes:    changes,
+			})
+		}
+	}
+	for _, entry := range from {
+		if !seen[entry.ControlRef] {
+			comparison.Removed = append(comparison.Removed, entry)
+		}
+	}
+	return comparison
+}
+
+// soaEntryFields are the fields of an entry compared between versions, with
+// the owner by name as versions outlive user accounts
+func soaEntryFields(entry *models.SoAEntry) map[string]any {
+	return map[string]any{
+		"title":                 entry.Title,
+		"applicability":         string(entry.Applicability),
+		"justification":         entry.Justification,
+		"implementation_status": string(entry.ImplementationStatus),
+		"owner":                 entry.OwnerName,
+	}
+}
+
+// soaEntryAuditSnapshot returns the state recorded when a control is first
+// assessed
+func soaEntryAuditSnapshot(entry *models.SoAEntry) map[string]any {
+	return map[string]any{
+		"applicability":         string(entry.Applicability),
+		"justification":         entry.Justification,
+		"implementation_status": string(entry.ImplementationStatus),
+		"owner_id":              auditString(entry.OwnerID),
+	}
+}
+
+// soaEntryAuditChanges returns from/to pairs for the fields that differ
+// between two assessments of a control
+func soaEntryAuditChanges(before, after *models.SoAEntry) map[string]any {
+	changes := make(map[string]any)
+	from, to := soaEntryAuditSnapshot(before), soaEntryAuditSnapshot(after)
+	for field, value := range to {
+		if from[field] != value {
+			changes[field] = auditChange(from[field], value)
+		}
+	}
+	return changes
+}
+
+// respondVersion writes the version as stored, with names filled in
+func (h *SoAHandler) respondVersion(c *fiber.Ctx, status int, id string) error {
+	version, err := h.soa.GetVersion(c.Context(), id)
+	if err != nil {
+		return soaError(c, err, ErrFailedToFetch)
+	}
+	return c.Status(status).JSON(version)
+}
+
+func soaExportFormat(c *fiber.Ctx) (format, contentType string, ok bool) {
+	format = c.Query("format", "csv")
+	contentType, ok = reports.ExportContentType(format)
+	return format, contentType, ok && format != "json"
+}
+
+func sendSoAExport(c *fiber.Ctx, format, contentType string, soa *models.SoAVersion) error {
+	var buf bytes.Buffer
+	if err := reports.WriteSoAExport(&buf, format, soa); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to export statement of applicability"})
+	}
+	c.Attachment(reports.SoAFilename(soa.FrameworkName, soa.Version, soa.CreatedAt, format))
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(buf.Bytes())
+}
+
+// soaError maps repository errors to responses, using failed for anything
+// unexpected
+func soaError(c *fiber.Ctx, err error, failed string) error {
+	switch {
+	case errors.Is(err, database.ErrFrameworkNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "framework")})
+	case errors.Is(err, database.ErrFrameworkControlNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "control")})
+	case errors.Is(err, database.ErrSoAVersionNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "SoA version")})
+	case errors.Is(err, database.ErrSoAVersionPending), errors.Is(err, database.ErrSoAVersionDecided):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, database.ErrSoASelfDecision):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, database.ErrSoAOwnerNotFound):
+		return validationFailed(c, fieldErrors{"owner_id": "user not found"})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(failed, "statement of applicability")})
+}
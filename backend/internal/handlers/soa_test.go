@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockSoARepo keeps one framework's entries, in order, and its versions
+type mockSoARepo struct {
+	frameworkID string
+	entries     []*models.SoAEntry
+	versions    []*models.SoAVersion
+}
+
+func (m *mockSoARepo) Get(ctx context.Context, frameworkID string) (*models.StatementOfApplicability, error) {
+	if frameworkID != m.frameworkID {
+		return nil, database.ErrFrameworkNotFound
+	}
+	soa := &models.StatementOfApplicability{FrameworkID: frameworkID, FrameworkName: "ISO 27001"}
+	for _, entry := range m.entries {
+		copied := *entry
+		soa.Entries = append(soa.Entries, &copied)
+		soa.Summary.Total++
+		if !entry.Assessed {
+			soa.Summary.Unassessed++
+		}
+	}
+	return soa, nil
+}
+
+func (m *mockSoARepo) GetEntry(ctx context.Context, controlID string) (*models.SoAEntry, error) {
+	for _, entry := range m.entries {
+		if entry.ControlID == controlID {
+			copied := *entry
+			return &copied, nil
+		}
+	}
+	return nil, database.ErrFrameworkControlNotFound
+}
+
+func (m *mockSoARepo) SetApplicability(ctx context.Context, controlID string, input *models.SetApplicabilityInput, userID string) error {
+	for _, entry := range m.entries {
+		if entry.ControlID == controlID {
+			entry.Assessed = true
+			entry.Applicability = input.Applicability
+			entry.Justification = input.Justification
+			entry.ImplementationStatus = input.ImplementationStatus
+			entry.OwnerID = input.OwnerID
+			return nil
+		}
+	}
+	return database.ErrFrameworkControlNotFound
+}
+
+func (m *mockSoARepo) ListVersions(ctx context.Context, frameworkID string) ([]*models.SoAVersion, error) {
+	if frameworkID != m.frameworkID {
+		return nil, database.ErrFrameworkNotFound
+	}
+	return m.versions, nil
+}
+
+func (m *mockSoARepo) GetVersion(ctx context.Context, id string) (*models.SoAVersion, error) {
+	for _, version := range m.versions {
+		if version.ID == id {
+			return version, nil
+		}
+	}
+	return nil, database.ErrSoAVersionNotFound
+}
+
+func (m *mockSoARepo) GetVersionByNumber(ctx context.Context, frameworkID string, number int) (*models.SoAVersion, error) {
+	for _, version := range m.versions {
+		if version.FrameworkID == frameworkID && version.Version == number {
+			return version, nil
+		}
+	}
+	return nil, database.ErrSoAVersionNotFound
+}
+
+func (m *mockSoARepo) CreateVersion(ctx context.Context, version *models.SoAVersion) error {
+	for _, existing := range m.versions {
+		if existing.Status == models.SoAPending {
+			return database.ErrSoAVersionPending
+		}
+	}
+	version.ID = uuid.New().String()
+	version.FrameworkName = "ISO 27001"
+	version.Version = len(m.versions) + 1
+	version.Status = models.SoAPending
+	version.CreatedAt = time.Now()
+	m.versions = append(m.versions, version)
+	return nil
+}
+
+func (m *mockSoARepo) Decide(ctx context.Context, id string, status models.SoAVersionStatus, comment, userID string) error {
+	version, err := m.GetVersion(ctx, id)
+	if err != nil {
+		return err
+	}
+	if version.Status != models.SoAPending {
+		return database.ErrSoAVersionDecided
+	}
+	if version.CreatedBy != nil && *version.CreatedBy == userID {
+		return database.ErrSoASelfDecision
+	}
+	now := time.Now()
+	version.Status, version.DecisionComment, version.DecidedBy, version.DecidedAt = status, comment, &userID, &now
+	return nil
+}
+
+func (m *mockSoARepo) WithTx(tx *sql.Tx) database.SoARepository {
+	return m
+}
+
+func setupSoAApp() (*fiber.App, *mockSoARepo, *mockAuditRepo) {
+	repo := &mockSoARepo{
+		frameworkID: uuid.New().String(),
+		entries: []*models.SoAEntry{
+			{ControlID: uuid.New().String(), ControlRef: "A.5.1", Title: "Policies", Family: "A.5"},
+			{ControlID: uuid.New().String(), ControlRef: "A.7.1", Title: "Physical perimeters", Family: "A.7"},
+		},
+	}
+	audit := &mockAuditRepo{}
+	handler := NewSoAHandler(&mockTransactor{}, repo, audit)
+
+	app := fiber.New()
+	app.Get("/frameworks/:id/soa", testAuthMiddleware, handler.Get)
+	app.Get("/frameworks/:id/soa/export", testAuthMiddleware, handler.Export)
+	app.Get("/frameworks/:id/soa/compare", testAuthMiddleware, handler.Compare)
+	app.Post("/frameworks/:id/soa/versions", testAuthMiddleware, handler.CreateVersion)
+	app.Put("/controls/:id/applicability", testAuthMiddleware, handler.SetApplicability)
+	app.Get("/soa-versions/:id/export", testAuthMiddleware, handler.ExportVersion)
+	app.Post("/soa-versions/:id/approve", testAuthMiddleware, handler.Approve)
+	app.Post("/soa-versions/:id/reject", testAuthMiddleware, handler.Reject)
+	return app, repo, audit
+}
+
+func TestSoAHandler_SetApplicability(t *testing.T) {
+	app, repo, audit := setupSoAApp()
+	control := repo.entries[0]
+	path := "/controls/" + control.ControlID + "/applicability"
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name, body, field string
+		}{
+			{"missing applicability", `{}`, "applicability"},
+			{"unknown status", `{"applicability":"applicable","implementation_status":"done"}`, "implementation_status"},
+			{"exclusion without justification", `{"applicability":"excluded","justification":"  "}`, "justification"},
+			{"bad owner", `{"applicability":"applicable","owner_id":"someone"}`, "owner_id"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, app, "PUT", path, tt.body)
+				require.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+
+	status, body := sendTeamRequest(t, app, "PUT", path, `{"applicability":"applicable","justification":"Risk R-12"}`)
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, "not_implemented", body["implementation_status"])
+	require.Len(t, audit.logs, 1)
+	assert.Equal(t, models.AuditActionCreated, audit.logs[0].Action)
+
+	status, _ = sendTeamRequest(t, app, "PUT", path, `{"applicability":"applicable","justification":"Risk R-12","implementation_status":"implemented"}`)
+	require.Equal(t, 200, status)
+	require.Len(t, audit.logs, 2)
+	assert.Equal(t, map[string]any{"implementation_status": auditChange("not_implemented", "implemented")}, audit.logs[1].Changes)
+
+	status, _ = sendTeamRequest(t, app, "PUT", "/controls/"+uuid.New().String()+"/applicability", `{"applicability":"applicable"}`)
+	assert.Equal(t, 404, status)
+}
+
+func TestSoAHandler_Versions(t *testing.T) {
+	app, repo, _ := setupSoAApp()
+	versionsPath := "/frameworks/" + repo.frameworkID + "/soa/versions"
+
+	status, body := sendTeamRequest(t, app, "POST", versionsPath, `{"title":"2026 audit"}`)
+	require.Equal(t, 422, status)
+	assert.Equal(t, []any{"A.5.1", "A.7.1"}, body["unassessed"])
+
+	for _, entry := range repo.entries {
+		entry.Assessed, entry.Applicability, entry.ImplementationStatus = true, models.ApplicabilityApplicable, models.ImplementationImplemented
+	}
+	status, body = sendTeamRequest(t, app, "POST", versionsPath, `{"title":"2026 audit"}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, float64(1), body["version"])
+	assert.Equal(t, "pending", body["status"])
+	first := body["id"].(string)
+
+	status, _ = sendTeamRequest(t, app, "POST", versionsPath, "")
+	assert.Equal(t, 409, status, "only one version can await approval")
+
+	status, body = sendTeamRequest(t, app, "POST", "/soa-versions/"+first+"/reject", `{}`)
+	require.Equal(t, 400, status)
+	assert.Contains(t, body["fields"], "comment")
+
+	status, _ = sendTeamRequest(t, app, "POST", "/soa-versions/"+first+"/approve", `{"comment":"Looks good to me"}`)
+	assert.Equal(t, 403, status, "the author cannot sign off their own version")
+	author := uuid.New().String()
+	repo.versions[0].CreatedBy = &author
+
+	status, body = sendTeamRequest(t, app, "POST", "/soa-versions/"+first+"/approve", `{"comment":"Signed off by the CISO"}`)
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, "approved", body["status"])
+	status, _ = sendTeamRequest(t, app, "POST", "/soa-versions/"+first+"/reject", `{"comment":"too late"}`)
+	assert.Equal(t, 409, status)
+
+	repo.entries[1].Applicability = models.ApplicabilityExcluded
+	repo.entries[1].Justification = "No offices"
+	repo.entries = append(repo.entries, &models.SoAEntry{ControlID: uuid.New().String(), ControlRef: "A.8.1", Assessed: true,
+		Applicability: models.ApplicabilityApplicable})
+
+	status, body = sendTeamRequest(t, app, "GET", "/frameworks/"+repo.frameworkID+"/soa/compare?from=1", "")
+	require.Equal(t, 200, status, body)
+	assert.Nil(t, body["to_version"])
+	assert.Len(t, body["added"], 1)
+	assert.Len(t, body["removed"], 0)
+	require.Len(t, body["changed"], 1)
+	changed := body["changed"].([]any)[0].(map[string]any)
+	assert.Equal(t, "A.7.1", changed["control_ref"])
+	assert.Contains(t, changed["changes"], "applicability")
+	assert.Contains(t, changed["changes"], "justification")
+
+	status, _ = sendTeamRequest(t, app, "GET", "/frameworks/"+repo.frameworkID+"/soa/compare?from=0", "")
+	assert.Equal(t, 400, status)
+	status, _ = sendTeamRequest(t, app, "GET", "/frameworks/"+repo.frameworkID+"/soa/compare?from=1&to=7", "")
+	assert.Equal(t, 404, status)
+
+	t.Run("export", func(t *testing.T) {
+		resp, err := app.Test(httptest.NewRequest("GET", "/soa-versions/"+first+"/export", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "soa-iso-27001-v1.csv")
+		body, _ := io.ReadAll(resp.Body)
+		lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+		require.Len(t, lines, 3)
+		assert.Equal(t, "family,control_ref,title,applicability,justification,implementation_status,owner,linked_risks", lines[0])
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/frameworks/"+repo.frameworkID+"/soa/export?format=xlsx", nil))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "soa-iso-27001-draft-")
+		body, _ = io.ReadAll(resp.Body)
+		assert.True(t, strings.HasPrefix(string(body), "PK"), "expected an XLSX file")
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/soa-versions/"+first+"/export?format=pdf", nil))
+		require.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+}
+
+func TestCompareSoA(t *testing.T) {
+	from := []*models.SoAEntry{
+		{ControlRef: "A.5.1", Title: "Policies", Applicability: models.ApplicabilityApplicable, OwnerName: "Ana"},
+		{ControlRef: "A.5.2", Title: "Roles", Applicability: models.ApplicabilityApplicable},
+	}
+	to := []*models.SoAEntry{
+		{ControlRef: "A.5.1", Title: "Policies", Applicability: models.ApplicabilityApplicable, OwnerName: "Ben"},
+		{ControlRef: "A.5.3", Title: "Segregation of duties", Applicability: models.ApplicabilityApplicable},
+	}
+
+	comparison := compareSoA(from, to)
+	require.Len(t, comparison.Added, 1)
+	assert.Equal(t, "A.5.3", comparison.Added[0].ControlRef)
+	require.Len(t, comparison.Removed, 1)
+	assert.Equal(t, "A.5.2", comparison.Removed[0].ControlRef)
+	require.Len(t, comparison.Changed, 1)
+	assert.Equal(t, map[string]models.FieldChange{"owner": {From: "Ana", To: "Ben"}}, comparison.Changed[0].Changes)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/realtime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamRetry is how long EventSource clients wait before reconnecting
+const streamRetry = 3 * time.Second
+
+type StreamHandler struct {
+	broker    *realtime.Broker
+	heartbeat time.Duration
+	maxAge    time.Duration
+}
+
+// NewStreamHandler returns a handler that sends a comment every heartbeat
+// to keep idle connections open, and ends each stream after maxAge so that
+// clients reconnect and their token is checked again
+func NewStreamHandler(broker *realtime.Broker, heartbeat, maxAge time.Duration) *StreamHandler {
+	return &StreamHandler{broker: broker, heartbeat: heartbeat, maxAge: maxAge}
+}
+
+// Stream sends change events as Server-Sent Events. ?types=risk,incident
+// limits the entity types and ?entity_id= follows a single entity. Clients
+// that reconnect with Last-Event-ID get the events they missed, or a
+// stream.reset event if those are no longer known.
+func (h *StreamHandler) Stream(c *fiber.Ctx) error {
+	user := middleware.GetUserFromContext(c)
+	filter := &realtime.Filter{Role: user.Role, EntityID: c.Query("entity_id")}
+	if types := c.Query("types"); types != "" {
+		known := realtime.StreamedEntityTypes()
+		for _, t := range strings.Split(types, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(known, t) {
+				return c.Status(400).JSON(fiber.Map{"error": "types must be a comma-separated list of " + strings.Join(known, ", ")})
+			}
+			filter.EntityTypes = append(filter.EntityTypes, t)
+		}
+	}
+	lastEventID := c.Get("Last-Event-ID", c.Query("last_event_id"))
+
+	sub, missed, resumed := h.broker.Subscribe(lastEventID, filter.Allows)
+	if !resumed {
+		missed = []*models.StreamEvent{realtime.NewResetEvent()}
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	// The stream writer runs after the handler returns; a failed write means
+	// the client has gone away
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Unsubscribe()
+
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+		for _, e := range missed {
+			writeStreamEvent(w, e)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(h.heartbeat)
+		defer heartbeat.Stop()
+		expired := time.NewTimer(h.maxAge)
+		defer expired.Stop()
+		for {
+			select {
+			case e, ok := <-sub.C:
+				if !ok {
+					return
+				}
+				writeStreamEvent(w, e)
+			case <-heartbeat.C:
+				w.WriteString(": ping\n\n")
+			case <-expired.C:
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func writeStreamEvent(w *bufio.Writer, e *models.StreamEvent) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %s\ndata: %s\n\n", e.ID, data)
+}
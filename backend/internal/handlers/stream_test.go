@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/middleware"
+	"backend/internal/models"
+	"backend/internal/realtime"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func setupStreamApp(role string) (*fiber.App, *realtime.Broker) {
+	broker := realtime.NewBroker()
+	handler := NewStreamHandler(broker, 20*time.Millisecond, 150*time.Millisecond)
+	app := fiber.New()
+	app.Get("/events", func(c *fiber.Ctx) error {
+		c.Locals(middleware.UserKey, &middleware.UserClaims{UserID: "test-user-id", Email: "test@example.com", Role: role})
+		return c.Next()
+	}, handler.Stream)
+	return app, broker
+}
+
+func readStream(t *testing.T, app *fiber.App, path, lastEventID string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := app.Test(req, 2000)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func publishStreamEvent(broker *realtime.Broker, id, entityType, entityID string, action models.AuditAction) {
+	broker.Publish(&models.StreamEvent{ID: id, Type: entityType + "." + string(action), EntityType: entityType, EntityID: entityID, Action: action})
+}
+
+func TestStream_Live(t *testing.T) {
+	app, broker := setupStreamApp("member")
+	go func() {
+		for broker.Subscribers() == 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		publishStreamEvent(broker, "e1", "incident", "inc-1", models.AuditActionUpdated)
+		publishStreamEvent(broker, "e2", "incident", "inc-1", models.AuditActionPurged)
+	}()
+
+	status, body := readStream(t, app, "/events", "")
+	if status != 200 {
+		t.Fatalf("expected 200, got %d", status)
+	}
+	if !strings.HasPrefix(body, "retry: 3000\n\n") {
+		t.Errorf("expected a retry hint first, got %q", body)
+	}
+	if !strings.Contains(body, "id: e1\ndata: {\"id\":\"e1\",\"type\":\"incident.updated\"") {
+		t.Errorf("expected the update event, got %q", body)
+	}
+	if strings.Contains(body, "e2") {
+		t.Errorf("members shouldn't hear about purged incidents: %q", body)
+	}
+	if !strings.Contains(body, ": ping\n\n") {
+		t.Errorf("expected heartbeats, got %q", body)
+	}
+	if broker.Subscribers() != 0 {
+		t.Error("expected the subscription to end with the stream")
+	}
+}
+
+func TestStream_Resume(t *testing.T) {
+	app, broker := setupStreamApp("admin")
+	publishStreamEvent(broker, "e1", "risk", "risk-1", models.AuditActionUpdated)
+	publishStreamEvent(broker, "e2", "incident", "inc-1", models.AuditActionUpdated)
+	publishStreamEvent(broker, "e3", "risk", "risk-2", models.AuditActionCreated)
+	publishStreamEvent(broker, "e4", "risk", "risk-1", models.AuditActionDeleted)
+
+	_, body := readStream(t, app, "/events?types=risk&entity_id=risk-1", "e1")
+	if strings.Contains(body, "id: e2") || strings.Contains(body, "id: e3") || !strings.Contains(body, "id: e4") {
+		t.Errorf("expected only the followed risk's missed event, got %q", body)
+	}
+
+	_, body = readStream(t, app, "/events", "forgotten")
+	if !strings.Contains(body, `"type":"stream.reset"`) {
+		t.Errorf("expected a reset for an unknown event ID, got %q", body)
+	}
+}
+
+func TestStream_InvalidTypes(t *testing.T) {
+	app, _ := setupStreamApp("member")
+	if status, _ := readStream(t, app, "/events?types=risk,webhook", ""); status != 400 {
+		t.Errorf("expected 400, got %d", status)
+	}
+}
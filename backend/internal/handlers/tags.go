@@ -0,0 +1,25 @@
+package handlers
+
+import "strings"
+
+// normalizeTags trims tags and drops blanks and duplicates, keeping the order
+// they were given in. It never returns nil so tags always serialize as an array.
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// auditTags copies tags for an audit payload so later edits to the slice don't
+// leak into the recorded value
+func auditTags(tags []string) []string {
+	return append([]string{}, tags...)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type TeamHandler struct {
+	transactor database.Transactor
+	teams      database.TeamRepository
+	users      database.UserRepository
+}
+
+func NewTeamHandler(transactor database.Transactor, teams database.TeamRepository, users database.UserRepository) *TeamHandler {
+	return &TeamHandler{transactor: transactor, teams: teams, users: users}
+}
+
+func (h *TeamHandler) List(c *fiber.Ctx) error {
+	teams, err := h.teams.List(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "teams")})
+	}
+	return c.JSON(teams)
+}
+
+func (h *TeamHandler) Get(c *fiber.Ctx) error {
+	team, err := h.teams.FindByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.teamError(c, err, ErrFailedToFetch)
+	}
+	return c.JSON(team)
+}
+
+func (h *TeamHandler) Create(c *fiber.Ctx) error {
+	var input models.CreateTeamInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+
+	team := &models.Team{Name: strings.TrimSpace(input.Name)}
+	errs := fieldErrors{}
+	validateTeamName(errs, team.Name)
+	h.validateMembers(c.Context(), errs, input.MemberIDs)
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	err := h.transactor.InTx(c.Context(), func(tx *sql.Tx) error {
+		teams := h.teams.WithTx(tx)
+		if err := teams.Create(c.Context(), team); err != nil {
+			return err
+		}
+		return teams.SetMembers(c.Context(), team.ID, input.MemberIDs)
+	})
+	if err != nil {
+		return h.teamError(c, err, ErrFailedToCreate)
+	}
+	return h.respond(c, 201, team.ID)
+}
+
+// Update renames a team and/or replaces its members
+func (h *TeamHandler) Update(c *fiber.Ctx) error {
+	team, err := h.teams.FindByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return h.teamError(c, err, ErrFailedToFetch)
+	}
+
+	var input models.UpdateTeamInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Name == nil && input.MemberIDs == nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	errs := fieldErrors{}
+	if input.Name != nil {
+		team.Name = strings.TrimSpace(*input.Name)
+		validateTeamName(errs, team.Name)
+	}
+	if input.MemberIDs != nil {
+		h.validateMembers(c.Context(), errs, *input.MemberIDs)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	err = h.transactor.InTx(c.Context(), func(tx *sql.Tx) error {
+		teams := h.teams.WithTx(tx)
+		if input.Name != nil {
+			if err := teams.Update(c.Context(), team); err != nil {
+				return err
+			}
+		}
+		if input.MemberIDs != nil {
+			return teams.SetMembers(c.Context(), team.ID, *input.MemberIDs)
+		}
+		return nil
+	})
+	if err != nil {
+		return h.teamError(c, err, ErrFailedToUpdate)
+	}
+	return h.respond(c, 200, team.ID)
+}
+
+func (h *TeamHandler) Delete(c *fiber.Ctx) error {
+	if err := h.teams.Delete(c.Context(), c.Params("id")); err != nil {
+		return h.teamError(c, err, ErrFailedToDelete)
+	}
+	return c.SendStatus(204)
+}
+
+// respond writes the team as stored, members included
+func (h *TeamHandler) respond(c *fiber.Ctx, status int, id string) error {
+	team, err := h.teams.FindByID(c.Context(), id)
+	if err != nil {
+		return h.teamError(c, err, ErrFailedToFetch)
+	}
+	return c.Status(status).JSON(team)
+}
+
+// teamError maps repository errors to responses, using failed for anything
+// unexpected
+func (h *TeamHandler) teamError(c *fiber.Ctx, err error, failed string) error {
+	switch {
+	case errors.Is(err, database.ErrTeamNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "team")})
+	case errors.Is(err, database.ErrTeamExists):
+		return validationFailed(c, fieldErrors{"name": "a team with this name already exists"})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(failed, "team")})
+}
+
+func validateTeamName(errs fieldErrors, name string) {
+	switch {
+	case name == "":
+		errs["name"] = "is required"
+	case len(name) > 255:
+		errs["name"] = "must be at most 255 characters"
+	}
+}
+
+// validateMembers checks that every member ID is an existing user
+func (h *TeamHandler) validateMembers(ctx context.Context, errs fieldErrors, ids []string) {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			errs["member_ids"] = fmt.Sprintf("%q is not a valid user ID", id)
+			return
+		}
+		user, err := h.users.FindByID(ctx, id)
+		if err != nil || user == nil {
+			errs["member_ids"] = fmt.Sprintf("user %s does not exist", id)
+			return
+		}
+	}
+}
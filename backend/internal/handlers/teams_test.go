@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTeamRepo struct {
+	teams   map[string]*models.Team
+	members map[string][]string
+}
+
+func newMockTeamRepo() *mockTeamRepo {
+	return &mockTeamRepo{teams: map[string]*models.Team{}, members: map[string][]string{}}
+}
+
+func (m *mockTeamRepo) Create(ctx context.Context, team *models.Team) error {
+	for _, existing := range m.teams {
+		if existing.Name == team.Name {
+			return database.ErrTeamExists
+		}
+	}
+	team.ID = uuid.New().String()
+	team.CreatedAt, team.UpdatedAt = time.Now(), time.Now()
+	copied := *team
+	m.teams[team.ID] = &copied
+	return nil
+}
+
+func (m *mockTeamRepo) FindByID(ctx context.Context, id string) (*models.Team, error) {
+	team, ok := m.teams[id]
+	if !ok {
+		return nil, database.ErrTeamNotFound
+	}
+	copied := *team
+	copied.Members = []*models.TeamMember{}
+	for _, userID := range m.members[id] {
+		copied.Members = append(copied.Members, &models.TeamMember{UserID: userID})
+	}
+	return &copied, nil
+}
+
+func (m *mockTeamRepo) List(ctx context.Context) ([]*models.Team, error) {
+	teams := []*models.Team{}
+	for id := range m.teams {
+		team, _ := m.FindByID(ctx, id)
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+func (m *mockTeamRepo) Update(ctx context.Context, team *models.Team) error {
+	if _, ok := m.teams[team.ID]; !ok {
+		return database.ErrTeamNotFound
+	}
+	for _, existing := range m.teams {
+		if existing.Name == team.Name && existing.ID != team.ID {
+			return database.ErrTeamExists
+		}
+	}
+	m.teams[team.ID].Name = team.Name
+	return nil
+}
+
+func (m *mockTeamRepo) Delete(ctx context.Context, id string) error {
+	if _, ok := m.teams[id]; !ok {
+		return database.ErrTeamNotFound
+	}
+	delete(m.teams, id)
+	delete(m.members, id)
+	return nil
+}
+
+func (m *mockTeamRepo) SetMembers(ctx context.Context, teamID string, userIDs []string) error {
+	m.members[teamID] = append([]string(nil), userIDs...)
+	return nil
+}
+
+func (m *mockTeamRepo) WithTx(tx *sql.Tx) database.TeamRepository {
+	return m
+}
+
+func setupTeamApp() (*fiber.App, *mockTeamRepo, *models.User) {
+	teams := newMockTeamRepo()
+	member := &models.User{ID: uuid.New().String(), Email: "member@example.com", Name: "Member"}
+	users := &mockUserRepo{users: map[string]*models.User{member.Email: member}}
+	handler := NewTeamHandler(&mockTransactor{}, teams, users)
+	app := fiber.New()
+	app.Post("/teams", handler.Create)
+	app.Get("/teams/:id", handler.Get)
+	app.Put("/teams/:id", handler.Update)
+	app.Delete("/teams/:id", handler.Delete)
+	return app, teams, member
+}
+
+func sendTeamRequest(t *testing.T, app *fiber.App, method, path, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	var result map[string]any
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp.StatusCode, result
+}
+
+func TestTeamHandler_Create(t *testing.T) {
+	app, teams, member := setupTeamApp()
+
+	status, body := sendTeamRequest(t, app, "POST", "/teams", `{"name":" Platform ","member_ids":["`+member.ID+`"]}`)
+	require.Equal(t, 201, status, body)
+	assert.Equal(t, "Platform", body["name"])
+	id := body["id"].(string)
+	assert.Equal(t, []string{member.ID}, teams.members[id])
+
+	t.Run("validation", func(t *testing.T) {
+		tests := []struct {
+			name, body, field string
+		}{
+			{"missing name", `{"name":"  "}`, "name"},
+			{"unknown member", `{"name":"Ops","member_ids":["` + uuid.New().String() + `"]}`, "member_ids"},
+			{"malformed member", `{"name":"Ops","member_ids":["nope"]}`, "member_ids"},
+			{"duplicate name", `{"name":"Platform"}`, "name"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				status, body := sendTeamRequest(t, app, "POST", "/teams", tt.body)
+				assert.Equal(t, 400, status)
+				assert.Contains(t, body["fields"], tt.field)
+			})
+		}
+	})
+}
+
+func TestTeamHandler_Update(t *testing.T) {
+	app, teams, member := setupTeamApp()
+	_, body := sendTeamRequest(t, app, "POST", "/teams", `{"name":"Platform","member_ids":["`+member.ID+`"]}`)
+	id := body["id"].(string)
+
+	status, body := sendTeamRequest(t, app, "PUT", "/teams/"+id, `{"name":"Infrastructure"}`)
+	require.Equal(t, 200, status, body)
+	assert.Equal(t, "Infrastructure", body["name"])
+	assert.Equal(t, []string{member.ID}, teams.members[id], "members are kept when not given")
+
+	status, _ = sendTeamRequest(t, app, "PUT", "/teams/"+id, `{"member_ids":[]}`)
+	require.Equal(t, 200, status)
+	assert.Empty(t, teams.members[id])
+
+	status, _ = sendTeamRequest(t, app, "PUT", "/teams/"+id, `{}`)
+	assert.Equal(t, 400, status)
+
+	status, _ = sendTeamRequest(t, app, "PUT", "/teams/"+uuid.New().String(), `{"name":"Other"}`)
+	assert.Equal(t, 404, status)
+
+	status, _ = sendTeamRequest(t, app, "DELETE", "/teams/"+id, "")
+	assert.Equal(t, 204, status)
+	status, _ = sendTeamRequest(t, app, "GET", "/teams/"+id, "")
+	assert.Equal(t, 404, status)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TrashHandler lists, restores and purges soft-deleted risks and incidents
+type TrashHandler struct {
+	risks       database.RiskRepository
+	incidents   database.IncidentRepository
+	audit       database.AuditLogRepository
+	cleanup     *AttachmentCleanup
+	gracePeriod time.Duration
+}
+
+// NewTrashHandler creates a TrashHandler. Trashed entities can only be purged
+// once they have been in the trash for at least gracePeriod.
+func NewTrashHandler(
+	risks database.RiskRepository,
+	incidents database.IncidentRepository,
+	audit database.AuditLogRepository,
+	cleanup *AttachmentCleanup,
+	gracePeriod time.Duration,
+) *TrashHandler {
+	return &TrashHandler{risks: risks, incidents: incidents, audit: audit, cleanup: cleanup, gracePeriod: gracePeriod}
+}
+
+// ListRisks returns the risks in the trash
+func (h *TrashHandler) ListRisks(c *fiber.Ctx) error {
+	risks, err := h.risks.ListDeleted(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch trashed risks"})
+	}
+	if risks == nil {
+		risks = []*models.Risk{}
+	}
+	return c.JSON(fiber.Map{"data": risks, "grace_period_hours": h.gracePeriod.Hours()})
+}
+
+// RestoreRisk takes a risk back out of the trash
+func (h *TrashHandler) RestoreRisk(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := middleware.GetUserFromContext(c)
+
+	if err := h.risks.Restore(c.Context(), id); err != nil {
+		return mapTrashError(c, err, "risk", "failed to restore risk")
+	}
+	h.audit.Create(c.Context(), "risk", id, models.AuditActionRestored, nil, user.UserID)
+
+	risk, err := h.risks.FindByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch risk"})
+	}
+	return c.JSON(risk)
+}
+
+// PurgeRisk permanently deletes a trashed risk (admin only)
+func (h *TrashHandler) PurgeRisk(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := middleware.GetUserFromContext(c)
+
+	err := h.cleanup.Delete(c.Context(), func(tx *sql.Tx) error {
+		return h.risks.WithTx(tx).Purge(c.Context(), id, time.Now().Add(-h.gracePeriod))
+	}, models.AttachmentRisk, models.AttachmentMitigation)
+	if err != nil {
+		return mapTrashError(c, err, "risk", "failed to purge risk")
+	}
+	h.audit.Create(c.Context(), "risk", id, models.AuditActionPurged, nil, user.UserID)
+
+	return c.SendStatus(204)
+}
+
+// ListIncidents returns the incidents in the trash
+func (h *TrashHandler) ListIncidents(c *fiber.Ctx) error {
+	incidents, err := h.incidents.ListDeleted(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch trashed incidents"})
+	}
+	if incidents == nil {
+		incidents = []*models.Incident{}
+	}
+	return c.JSON(fiber.Map{"data": incidents, "grace_period_hours": h.gracePeriod.Hours()})
+}
+
+// RestoreIncident takes an incident back out of the trash
+func (h *TrashHandler) RestoreIncident(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := middleware.GetUserFromContext(c)
+
+	if err := h.incidents.Restore(c.Context(), id); err != nil {
+		return mapTrashError(c, err, "incident", "failed to restore incident")
+	}
+	h.audit.Create(c.Context(), "incident", id, models.AuditActionRestored, nil, user.UserID)
+
+	incident, err := h.incidents.FindByID(c.Context(), id)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch incident"})
+	}
+	return c.JSON(incident)
+}
+
+// PurgeIncident permanently deletes a trashed incident (admin only)
+func (h *TrashHandler) PurgeIncident(c *fiber.Ctx) error {
+	id := c.Params("id")
+	user := middleware.GetUserFromContext(c)
+
+	err := h.cleanup.Delete(c.Context(), func(tx *sql.Tx) error {
+		return h.incidents.WithTx(tx).Purge(c.Context(), id, time.Now().Add(-h.gracePeriod))
+	}, models.AttachmentIncident)
+	if err != nil {
+		return mapTrashError(c, err, "incident", "failed to purge incident")
+	}
+	h.audit.Create(c.Context(), "incident", id, models.AuditActionPurged, nil, user.UserID)
+
+	return c.SendStatus(204)
+}
+
+func mapTrashError(c *fiber.Ctx, err error, entity, defaultMessage string) error {
+	switch {
+	case errors.Is(err, database.ErrRiskNotFound), errors.Is(err, database.ErrIncidentNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": entity + " not found"})
+	case errors.Is(err, database.ErrNotInTrash):
+		return c.Status(409).JSON(fiber.Map{"error": entity + " is not in the trash"})
+	case errors.Is(err, database.ErrPurgeGracePeriod):
+		return c.Status(409).JSON(fiber.Map{"error": entity + " is still within the purge grace period"})
+	}
+	return c.Status(500).JSON(fiber.Map{"error": defaultMessage})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+	"backend/internal/storage"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestTrashHandler_Risks(t *testing.T) {
+	app := fiber.New()
+	mockRiskRepo := &mockRiskRepo{risks: make(map[string]*models.Risk)}
+	mockAuditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewTrashHandler(mockRiskRepo, newMockIncidentRepo(), mockAuditRepo, newTestAttachmentCleanup(), 24*time.Hour)
+
+	recentlyDeleted := time.Now().Add(-time.Hour)
+	longDeleted := time.Now().Add(-72 * time.Hour)
+
+	live := &models.Risk{ID: uuid.New().String(), Title: "Live"}
+	recent := &models.Risk{ID: uuid.New().String(), Title: "Recent", DeletedAt: &recentlyDeleted}
+	old := &models.Risk{ID: uuid.New().String(), Title: "Old", DeletedAt: &longDeleted}
+	mockRiskRepo.risks[live.ID] = live
+	mockRiskRepo.risks[recent.ID] = recent
+	mockRiskRepo.risks[old.ID] = old
+
+	app.Get("/risks/trash", testAuthMiddleware, handler.ListRisks)
+	app.Post("/risks/:id/restore", testAuthMiddleware, handler.RestoreRisk)
+	app.Delete("/risks/:id/purge", testAuthMiddleware, handler.PurgeRisk)
+
+	t.Run("List Trash", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/risks/trash", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+
+		var response struct {
+			Data []*models.Risk `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(response.Data) != 2 {
+			t.Errorf("expected 2 trashed risks, got %d", len(response.Data))
+		}
+	})
+
+	t.Run("Purge Within Grace Period", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/risks/"+recent.ID+"/purge", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 409 {
+			t.Errorf("expected status 409, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Purge Live Risk", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/risks/"+live.ID+"/purge", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 409 {
+			t.Errorf("expected status 409, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Purge After Grace Period", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/risks/"+old.ID+"/purge", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 204 {
+			t.Errorf("expected status 204, got %d", resp.StatusCode)
+		}
+		if _, exists := mockRiskRepo.risks[old.ID]; exists {
+			t.Errorf("risk should have been purged")
+		}
+	})
+
+	t.Run("Restore", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/risks/"+recent.ID+"/restore", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 200 {
+			t.Errorf("expected status 200, got %d", resp.StatusCode)
+		}
+		if recent.DeletedAt != nil {
+			t.Errorf("risk should have been restored")
+		}
+
+		last := mockAuditRepo.logs[len(mockAuditRepo.logs)-1]
+		if last.Action != models.AuditActionRestored {
+			t.Errorf("expected restored audit entry, got %s", last.Action)
+		}
+	})
+
+	t.Run("Restore Unknown Risk", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/risks/"+uuid.New().String()+"/restore", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		if resp.StatusCode != 404 {
+			t.Errorf("expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestTrashHandler_Incidents(t *testing.T) {
+	app := fiber.New()
+	mockIncidentRepo := newMockIncidentRepo()
+	mockAuditRepo := &mockAuditRepo{logs: []*models.AuditLog{}}
+	handler := NewTrashHandler(&mockRiskRepo{risks: make(map[string]*models.Risk)}, mockIncidentRepo, mockAuditRepo, newTestAttachmentCleanup(), 0)
+
+	deletedAt := time.Now().Add(-time.Minute)
+	incident := &models.Incident{ID: uuid.New().String(), Title: "Outage", DeletedAt: &deletedAt}
+	mockIncidentRepo.incidents[incident.ID] = incident
+
+	app.Delete("/incidents/:id/purge", testAuthMiddleware, handler.PurgeIncident)
+
+	req := httptest.NewRequest("DELETE", "/incidents/"+incident.ID+"/purge", nil)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 204 {
+		t.Errorf("expected status 204, got %d", resp.StatusCode)
+	}
+	if len(mockAuditRepo.logs) != 1 || mockAuditRepo.logs[0].Action != models.AuditActionPurged {
+		t.Errorf("expected a purged audit entry")
+	}
+}
+
+func TestTrashHandler_PurgeRiskAttachments(t *testing.T) {
+	app := fiber.New()
+	deletedAt := time.Now().Add(-time.Minute)
+	purged := &models.Risk{ID: uuid.New().String(), Title: "Purged", DeletedAt: &deletedAt}
+	trashed := &models.Risk{ID: uuid.New().String(), Title: "Still in the trash", DeletedAt: &deletedAt}
+	riskRepo := &mockRiskRepo{risks: map[string]*models.Risk{purged.ID: purged, trashed.ID: trashed}}
+	mitigationID := uuid.New().String()
+
+	store := storage.NewLocalStore(t.TempDir())
+	attachments := &mockAttachmentRepo{exists: func(entityType models.AttachmentEntity, entityID string) bool {
+		if entityType == models.AttachmentMitigation && entityID == mitigationID {
+			entityID = purged.ID
+		}
+		_, ok := riskRepo.risks[entityID]
+		return ok
+	}}
+	attach := func(entityType models.AttachmentEntity, entityID string) *models.Attachment {
+		attachment := &models.Attachment{ID: uuid.New().String(), EntityType: entityType, EntityID: entityID, StorageKey: uuid.New().String()}
+		if err := store.Put(context.Background(), attachment.StorageKey, strings.NewReader("evidence"), 8, "text/plain"); err != nil {
+			t.Fatalf("failed to store attachment: %v", err)
+		}
+		attachments.attachments = append(attachments.attachments, attachment)
+		return attachment
+	}
+	onRisk := attach(models.AttachmentRisk, purged.ID)
+	onMitigation := attach(models.AttachmentMitigation, mitigationID)
+	kept := attach(models.AttachmentRisk, trashed.ID)
+
+	handler := NewTrashHandler(riskRepo, newMockIncidentRepo(), &mockAuditRepo{logs: []*models.AuditLog{}},
+		NewAttachmentCleanup(&mockTransactor{}, attachments, store), 0)
+	app.Delete("/risks/:id/purge", testAuthMiddleware, handler.PurgeRisk)
+
+	resp, err := app.Test(httptest.NewRequest("DELETE", "/risks/"+purged.ID+"/purge", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 204 {
+		t.Fatalf("expected status 204, got %d", resp.StatusCode)
+	}
+	if len(attachments.attachments) != 1 || attachments.attachments[0].ID != kept.ID {
+		t.Errorf("expected only the trashed risk's attachment to remain, got %v", attachments.attachments)
+	}
+	for _, removed := range []*models.Attachment{onRisk, onMitigation} {
+		if _, err := store.Open(context.Background(), removed.StorageKey); !errors.Is(err, storage.ErrNotFound) {
+			t.Errorf("expected the file of %s %s to be removed, got %v", removed.EntityType, removed.EntityID, err)
+		}
+	}
+	file, err := store.Open(context.Background(), kept.StorageKey)
+	if err != nil {
+		t.Fatalf("expected the trashed risk's file to be kept: %v", err)
+	}
+	file.Close()
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"backend/internal/database"
+	"backend/internal/middleware"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// minWebhookSecretLength keeps caller-chosen secrets long enough to resist
+// guessing; generated secrets are 32 random bytes
+const minWebhookSecretLength = 16
+
+const maxWebhookDeliveries = 200
+
+type WebhookHandler struct {
+	webhooks database.WebhookRepository
+}
+
+func NewWebhookHandler(webhooks database.WebhookRepository) *WebhookHandler {
+	return &WebhookHandler{webhooks: webhooks}
+}
+
+// EventTypes lists the event types a webhook can subscribe to
+func (h *WebhookHandler) EventTypes(c *fiber.Ctx) error {
+	return c.JSON(models.WebhookEventTypes)
+}
+
+func (h *WebhookHandler) List(c *fiber.Ctx) error {
+	webhooks, err := h.webhooks.List(c.Context())
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "webhooks")})
+	}
+	for _, w := range webhooks {
+		w.Secret = ""
+	}
+	return c.JSON(webhooks)
+}
+
+func (h *WebhookHandler) Get(c *fiber.Ctx) error {
+	webhook, err := h.find(c)
+	if err != nil || webhook == nil {
+		return err
+	}
+	webhook.Secret = ""
+	return c.JSON(webhook)
+}
+
+// Create registers a webhook. If no secret is given one is generated; either
+// way this is the only response that includes it.
+func (h *WebhookHandler) Create(c *fiber.Ctx) error {
+	var input models.CreateWebhookInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+
+	user := middleware.GetUserFromContext(c)
+	webhook := &models.Webhook{
+		Name:       strings.TrimSpace(input.Name),
+		URL:        strings.TrimSpace(input.URL),
+		Secret:     input.Secret,
+		EventTypes: input.EventTypes,
+		Active:     input.Active == nil || *input.Active,
+		CreatedBy:  user.UserID,
+	}
+
+	errs := validateWebhook(webhook)
+	if webhook.Secret == "" {
+		webhook.Secret = newWebhookSecret()
+	} else if len(webhook.Secret) < minWebhookSecretLength {
+		errs["secret"] = fmt.Sprintf("must be at least %d characters", minWebhookSecretLength)
+	}
+	if len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+
+	if err := h.webhooks.Create(c.Context(), webhook); err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToCreate, "webhook")})
+	}
+	return c.Status(201).JSON(webhook)
+}
+
+// Update changes a webhook. With rotate_secret the response carries the new
+// secret, and deliveries still queued are signed with it.
+func (h *WebhookHandler) Update(c *fiber.Ctx) error {
+	webhook, err := h.find(c)
+	if err != nil || webhook == nil {
+		return err
+	}
+
+	var input models.UpdateWebhookInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": ErrInvalidRequestBody})
+	}
+	if input.Name == nil && input.URL == nil && input.EventTypes == nil && input.Active == nil && !input.RotateSecret {
+		return c.Status(400).JSON(fiber.Map{"error": ErrAtLeastOneField})
+	}
+
+	if input.Name != nil {
+		webhook.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.URL != nil {
+		webhook.URL = strings.TrimSpace(*input.URL)
+	}
+	if input.EventTypes != nil {
+		webhook.EventTypes = *input.EventTypes
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+	if errs := validateWebhook(webhook); len(errs) > 0 {
+		return validationFailed(c, errs)
+	}
+	if input.RotateSecret {
+		webhook.Secret = newWebhookSecret()
+	}
+
+	if err := h.webhooks.Update(c.Context(), webhook); err != nil {
+		if errors.Is(err, database.ErrWebhookNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "webhook")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToUpdate, "webhook")})
+	}
+	if !input.RotateSecret {
+		webhook.Secret = ""
+	}
+	return c.JSON(webhook)
+}
+
+// Delete removes a webhook along with its delivery log
+func (h *WebhookHandler) Delete(c *fiber.Ctx) error {
+	if err := h.webhooks.Delete(c.Context(), c.Params("id")); err != nil {
+		if errors.Is(err, database.ErrWebhookNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "webhook")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToDelete, "webhook")})
+	}
+	return c.SendStatus(204)
+}
+
+// Deliveries is a webhook's delivery log, newest first, optionally filtered
+// by status
+func (h *WebhookHandler) Deliveries(c *fiber.Ctx) error {
+	webhook, err := h.find(c)
+	if err != nil || webhook == nil {
+		return err
+	}
+	params := &models.WebhookDeliveryListParams{WebhookID: webhook.ID}
+	if status := c.Query("status"); status != "" {
+		s := models.WebhookDeliveryStatus(status)
+		if s != models.WebhookDeliveryPending && s != models.WebhookDeliveryDelivered && s != models.WebhookDeliveryDead {
+			return c.Status(400).JSON(fiber.Map{"error": "status must be one of pending, delivered, dead"})
+		}
+		params.Status = &s
+	}
+	return h.listDeliveries(c, params)
+}
+
+// DeadLetters lists deliveries across all webhooks that ran out of retries
+func (h *WebhookHandler) DeadLetters(c *fiber.Ctx) error {
+	status := models.WebhookDeliveryDead
+	return h.listDeliveries(c, &models.WebhookDeliveryListParams{Status: &status})
+}
+
+func (h *WebhookHandler) listDeliveries(c *fiber.Ctx, params *models.WebhookDeliveryListParams) error {
+	params.Limit = c.QueryInt("limit", 50)
+	if params.Limit < 1 || params.Limit > maxWebhookDeliveries {
+		params.Limit = 50
+	}
+	deliveries, err := h.webhooks.ListDeliveries(c.Context(), params)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "webhook deliveries")})
+	}
+	return c.JSON(models.WebhookDeliveryListResponse{Deliveries: deliveries})
+}
+
+// Redeliver queues a delivery's event to be sent again straight away. The
+// original stays in the log and the copy links back to it.
+func (h *WebhookHandler) Redeliver(c *fiber.Ctx) error {
+	delivery, err := h.webhooks.Redeliver(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrWebhookDeliveryNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "webhook delivery")})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to redeliver webhook"})
+	}
+	return c.Status(202).JSON(delivery)
+}
+
+// find loads the webhook named in the path. When it returns a nil webhook
+// the response has already been written.
+func (h *WebhookHandler) find(c *fiber.Ctx) (*models.Webhook, error) {
+	webhook, err := h.webhooks.FindByID(c.Context(), c.Params("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrWebhookNotFound) {
+			return nil, c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf(ErrEntityNotFound, "webhook")})
+		}
+		return nil, c.Status(500).JSON(fiber.Map{"error": fmt.Sprintf(ErrFailedToFetch, "webhook")})
+	}
+	return webhook, nil
+}
+
+// validateWebhook checks a webhook's fields and removes repeated event types
+func validateWebhook(w *models.Webhook) fieldErrors {
+	errs := fieldErrors{}
+	requireText(errs, "name", &w.Name, 255)
+
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs["url"] = "must be an absolute http or https URL"
+	}
+
+	known := map[string]bool{models.WebhookEventAll: true}
+	for _, t := range models.WebhookEventTypes {
+		known[t] = true
+	}
+	seen := map[string]bool{}
+	eventTypes := []string{}
+	for _, t := range w.EventTypes {
+		if !known[t] {
+			errs["event_types"] = fmt.Sprintf("unknown event type %q", t)
+			continue
+		}
+		if !seen[t] {
+			seen[t] = true
+			eventTypes = append(eventTypes, t)
+		}
+	}
+	if len(eventTypes) == 0 && errs["event_types"] == "" {
+		errs["event_types"] = "must include at least one event type"
+	}
+	w.EventTypes = eventTypes
+	return errs
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type mockWebhookRepo struct {
+	webhooks   map[string]*models.Webhook
+	deliveries []*models.WebhookDelivery
+}
+
+func (m *mockWebhookRepo) Create(ctx context.Context, w *models.Webhook) error {
+	w.ID = uuid.New().String()
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+	stored := *w
+	m.webhooks[w.ID] = &stored
+	return nil
+}
+
+func (m *mockWebhookRepo) FindByID(ctx context.Context, id string) (*models.Webhook, error) {
+	w, ok := m.webhooks[id]
+	if !ok {
+		return nil, database.ErrWebhookNotFound
+	}
+	found := *w
+	return &found, nil
+}
+
+func (m *mockWebhookRepo) List(ctx context.Context) ([]*models.Webhook, error) {
+	list := []*models.Webhook{}
+	for _, w := range m.webhooks {
+		found := *w
+		list = append(list, &found)
+	}
+	return list, nil
+}
+
+func (m *mockWebhookRepo) Update(ctx context.Context, w *models.Webhook) error {
+	if _, ok := m.webhooks[w.ID]; !ok {
+		return database.ErrWebhookNotFound
+	}
+	stored := *w
+	m.webhooks[w.ID] = &stored
+	return nil
+}
+
+func (m *mockWebhookRepo) Delete(ctx context.Context, id string) error {
+	if _, ok := m.webhooks[id]; !ok {
+		return database.ErrWebhookNotFound
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *mockWebhookRepo) Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error) {
+	return 0, nil
+}
+
+func (m *mockWebhookRepo) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDispatch, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepo) RecordAttempt(ctx context.Context, deliveryID string, attempt *models.WebhookAttempt) error {
+	return nil
+}
+
+func (m *mockWebhookRepo) FindDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, database.ErrWebhookDeliveryNotFound
+}
+
+func (m *mockWebhookRepo) ListDeliveries(ctx context.Context, params *models.WebhookDeliveryListParams) ([]*models.WebhookDelivery, error) {
+	list := []*models.WebhookDelivery{}
+	for _, d := range m.deliveries {
+		if params.WebhookID != "" && d.WebhookID != params.WebhookID {
+			continue
+		}
+		if params.Status != nil && d.Status != *params.Status {
+			continue
+		}
+		list = append(list, d)
+	}
+	return list, nil
+}
+
+func (m *mockWebhookRepo) Redeliver(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	original, err := m.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	redelivery := &models.WebhookDelivery{
+		ID: uuid.New().String(), WebhookID: original.WebhookID, EventID: original.EventID, EventType: original.EventType,
+		Status: models.WebhookDeliveryPending, RedeliveryOf: &original.ID, CreatedAt: time.Now(),
+	}
+	m.deliveries = append(m.deliveries, redelivery)
+	return redelivery, nil
+}
+
+func (m *mockWebhookRepo) WithTx(tx *sql.Tx) database.WebhookRepository { return m }
+
+func TestWebhookHandler(t *testing.T) {
+	newApp := func() (*fiber.App, *mockWebhookRepo) {
+		repo := &mockWebhookRepo{webhooks: map[string]*models.Webhook{}}
+		handler := NewWebhookHandler(repo)
+		app := fiber.New()
+		app.Use(testAuthMiddleware)
+		app.Get("/webhooks", handler.List)
+		app.Post("/webhooks", handler.Create)
+		app.Get("/webhooks/dead-letters", handler.DeadLetters)
+		app.Post("/webhooks/deliveries/:id/redeliver", handler.Redeliver)
+		app.Get("/webhooks/:id", handler.Get)
+		app.Put("/webhooks/:id", handler.Update)
+		app.Delete("/webhooks/:id", handler.Delete)
+		app.Get("/webhooks/:id/deliveries", handler.Deliveries)
+		return app, repo
+	}
+	request := func(app *fiber.App, method, path, body string) (int, []byte) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		raw := new(bytes.Buffer)
+		raw.ReadFrom(resp.Body)
+		return resp.StatusCode, raw.Bytes()
+	}
+
+	t.Run("create generates a secret that is only shown once", func(t *testing.T) {
+		app, repo := newApp()
+		status, body := request(app, "POST", "/webhooks", `{"name":"Pager","url":"https://example.com/hook","event_types":["incident.created","risk.critical","incident.created"]}`)
+		if status != 201 {
+			t.Fatalf("expected status 201, got %d: %s", status, body)
+		}
+		var created models.Webhook
+		json.Unmarshal(body, &created)
+		if !strings.HasPrefix(created.Secret, "whsec_") || !created.Active || len(created.EventTypes) != 2 {
+			t.Errorf("unexpected webhook: %+v", created)
+		}
+		if repo.webhooks[created.ID].Secret != created.Secret {
+			t.Error("expected the secret to be stored")
+		}
+
+		_, body = request(app, "GET", "/webhooks/"+created.ID, "")
+		if strings.Contains(string(body), created.Secret) {
+			t.Error("secret must not be returned after creation")
+		}
+		_, body = request(app, "GET", "/webhooks", "")
+		if strings.Contains(string(body), created.Secret) {
+			t.Error("secret must not be listed")
+		}
+	})
+
+	t.Run("create validates fields", func(t *testing.T) {
+		app, _ := newApp()
+		status, body := request(app, "POST", "/webhooks", `{"name":"","url":"ftp://example.com","secret":"short","event_types":["risk.exploded"]}`)
+		if status != 400 {
+			t.Fatalf("expected status 400, got %d", status)
+		}
+		var resp struct {
+			Fields map[string]string `json:"fields"`
+		}
+		json.Unmarshal(body, &resp)
+		for _, field := range []string{"name", "url", "secret", "event_types"} {
+			if resp.Fields[field] == "" {
+				t.Errorf("expected an error for %s, got %v", field, resp.Fields)
+			}
+		}
+	})
+
+	t.Run("update can rotate the secret", func(t *testing.T) {
+		app, repo := newApp()
+		repo.webhooks["hook"] = &models.Webhook{ID: "hook", Name: "Pager", URL: "https://example.com", Secret: "old-secret-old-secret", EventTypes: []string{"*"}, Active: true}
+
+		status, body := request(app, "PUT", "/webhooks/hook", `{"active":false}`)
+		if status != 200 || strings.Contains(string(body), "old-secret") || repo.webhooks["hook"].Active {
+			t.Errorf("unexpected update response %d: %s", status, body)
+		}
+
+		status, body = request(app, "PUT", "/webhooks/hook", `{"rotate_secret":true}`)
+		var updated models.Webhook
+		json.Unmarshal(body, &updated)
+		if status != 200 || updated.Secret == "" || updated.Secret == "old-secret-old-secret" || repo.webhooks["hook"].Secret != updated.Secret {
+			t.Errorf("expected a new secret, got %d: %s", status, body)
+		}
+	})
+
+	t.Run("delivery log, dead letters and redelivery", func(t *testing.T) {
+		app, repo := newApp()
+		repo.webhooks["hook"] = &models.Webhook{ID: "hook", Name: "Pager", URL: "https://example.com", EventTypes: []string{"*"}}
+		repo.deliveries = []*models.WebhookDelivery{
+			{ID: "d1", WebhookID: "hook", EventType: "risk.created", Status: models.WebhookDeliveryDelivered},
+			{ID: "d2", WebhookID: "hook", EventType: "risk.updated", Status: models.WebhookDeliveryDead, Attempts: 8},
+		}
+
+		status, body := request(app, "GET", "/webhooks/hook/deliveries", "")
+		var log models.WebhookDeliveryListResponse
+		json.Unmarshal(body, &log)
+		if status != 200 || len(log.Deliveries) != 2 {
+			t.Errorf("expected 2 deliveries, got %d: %s", status, body)
+		}
+		if status, _ := request(app, "GET", "/webhooks/hook/deliveries?status=lost", ""); status != 400 {
+			t.Errorf("expected status 400 for an unknown status, got %d", status)
+		}
+
+		_, body = request(app, "GET", "/webhooks/dead-letters", "")
+		json.Unmarshal(body, &log)
+		if len(log.Deliveries) != 1 || log.Deliveries[0].ID != "d2" {
+			t.Errorf("expected only the dead delivery, got %s", body)
+		}
+
+		status, body = request(app, "POST", "/webhooks/deliveries/d2/redeliver", "")
+		var redelivery models.WebhookDelivery
+		json.Unmarshal(body, &redelivery)
+		if status != 202 || redelivery.Status != models.WebhookDeliveryPending || redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != "d2" {
+			t.Errorf("unexpected redelivery %d: %s", status, body)
+		}
+		if status, _ := request(app, "POST", "/webhooks/deliveries/missing/redeliver", ""); status != 404 {
+			t.Errorf("expected status 404, got %d", status)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package mailer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMessage() *Message {
+	return &Message{
+		To:      []string{"cro@example.com"},
+		Subject: "Weekly risk report – Board",
+		Body:    "Your report is attached.",
+		Attachments: []Attachment{
+			{Filename: "risk-report.pdf", ContentType: "application/pdf", Data: bytes.Repeat([]byte("%PDF"), 100)},
+		},
+	}
+}
+
+// parseMessage decodes a built message back into its subject and parts
+func parseMessage(t *testing.T, data []byte) (string, map[string][]byte) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("invalid subject: %v", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("invalid content type: %v", err)
+	}
+
+	parts := map[string][]byte{}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid part: %v", err)
+		}
+		// multipart.Reader transparently decodes quoted-printable only, so
+		// base64 is decoded here
+		raw, _ := io.ReadAll(part)
+		decoded, err := decodeBase64Lines(raw)
+		if err != nil {
+			t.Fatalf("invalid base64: %v", err)
+		}
+		name := part.FileName()
+		if name == "" {
+			name = "body"
+		}
+		parts[name] = decoded
+	}
+	return subject, parts
+}
+
+func TestBuild(t *testing.T) {
+	data, err := Build("reports@example.com", testMessage(), time.Date(2024, 6, 3, 6, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+	subject, parts := parseMessage(t, data)
+	if subject != "Weekly risk report – Board" {
+		t.Errorf("unexpected subject %q", subject)
+	}
+	if string(parts["body"]) != "Your report is attached." {
+		t.Errorf("unexpected body %q", parts["body"])
+	}
+	if !bytes.Equal(parts["risk-report.pdf"], testMessage().Attachments[0].Data) {
+		t.Error("attachment did not round trip")
+	}
+	for _, line := range strings.Split(string(data), "\r\n") {
+		if len(line) > 998 {
+			t.Fatal("line exceeds the SMTP length limit")
+		}
+	}
+
+	if _, err := Build("reports@example.com", &Message{Subject: "x"}, time.Now()); err == nil {
+		t.Error("expected an error without recipients")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := NewFileMailer(dir, "reports@example.com")
+	for i := 0; i < 2; i++ {
+		if err := m.Send(context.Background(), testMessage()); err != nil {
+			t.Fatalf("send failed: %v", err)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if _, parts := parseMessage(t, data); len(parts) != 2 {
+		t.Errorf("expected body and attachment, got %d parts", len(parts))
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+
+	type received struct {
+		from, to string
+		data     []byte
+	}
+	got := make(chan received, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// A minimal SMTP server: enough of RFC 5321 for net/smtp.SendMail
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		var msg received
+		reply("220 localhost ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				msg.from = strings.Trim(strings.TrimPrefix(cmd, "MAIL FROM:"), "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				msg.to = strings.Trim(strings.TrimPrefix(cmd, "RCPT TO:"), "<>")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+				var data bytes.Buffer
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				msg.data = data.Bytes()
+				reply("250 queued")
+			case cmd == "QUIT":
+				reply("221 bye")
+				got <- msg
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	m := NewSMTPMailer(host, portNum, "", "", "reports@example.com")
+	if err := m.Send(context.Background(), testMessage()); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	select {
+	case msg := <-got:
+		if msg.from != "reports@example.com" || msg.to != "cro@example.com" {
+			t.Errorf("unexpected envelope %s -> %s", msg.from, msg.to)
+		}
+		if _, parts := parseMessage(t, msg.data); !bytes.Equal(parts["risk-report.pdf"], testMessage().Attachments[0].Data) {
+			t.Error("attachment did not arrive intact")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not receive the message")
+	}
+}
+
+func decodeBase64Lines(raw []byte) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(raw)), ""))
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FileMailer writes each message to an .eml file instead of sending it, so
+// generated mail can be opened in a mail client during development
+type FileMailer struct {
+	dir  string
+	from string
+	seq  atomic.Int64
+}
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	data, err := Build(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%03d.eml", now.UTC().Format("20060102T150405.000"), m.seq.Add(1))
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o644)
+}
+
+// LogMailer only logs that a message would have been sent
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+	names := make([]string, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		names[i] = fmt.Sprintf("%s (%d bytes)", a.Filename, len(a.Data))
+	}
+	log.Printf("mail to %s: %q, attachments: %s", strings.Join(msg.To, ", "), msg.Subject, strings.Join(names, ", "))
+	return nil
+}
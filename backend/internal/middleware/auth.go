@@ -49,6 +49,18 @@ func AuthMiddleware(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// QueryToken lets clients that cannot set headers, such as the browser's
+// EventSource, send their token as ?access_token=. Only use it where it is
+// needed, since URLs tend to end up in logs.
+func QueryToken(c *fiber.Ctx) error {
+	if c.Get("Authorization") == "" {
+		if token := c.Query("access_token"); token != "" {
+			c.Request().Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+	return c.Next()
+}
+
 func GetUserFromContext(c *fiber.Ctx) *UserClaims {
 	user, ok := c.Locals(UserKey).(*UserClaims)
 	if !ok {
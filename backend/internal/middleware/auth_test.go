@@ -151,3 +151,31 @@ func TestRequireResponder_MemberRoleForbidden(t *testing.T) {
 		t.Errorf("expected status 403 for member, got %d", resp.StatusCode)
 	}
 }
+
+func TestQueryToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/events", QueryToken, AuthMiddleware, func(c *fiber.Ctx) error {
+		return c.SendString(GetUserFromContext(c).UserID)
+	})
+
+	token, err := auth.GenerateToken(&models.User{ID: "test-id", Email: "test@example.com", Role: models.RoleMember})
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/events?access_token="+token, nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/events?access_token=invalid", nil))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.StatusCode != 401 {
+		t.Errorf("expected status 401, got %d", resp.StatusCode)
+	}
+}
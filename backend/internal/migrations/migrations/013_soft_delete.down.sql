@@ -0,0 +1,11 @@
+-- Note: PostgreSQL does not support removing enum values directly, so the
+-- 'restored' and 'purged' audit actions are left in place.
+DROP INDEX IF EXISTS idx_incidents_deleted_at;
+DROP INDEX IF EXISTS idx_risks_deleted_at;
+
+-- Trashed rows would reappear once the column is gone, so remove them first
+DELETE FROM incidents WHERE deleted_at IS NOT NULL;
+DELETE FROM risks WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE incidents DROP COLUMN deleted_at;
+ALTER TABLE risks DROP COLUMN deleted_at;
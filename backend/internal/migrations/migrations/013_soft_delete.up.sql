@@ -0,0 +1,10 @@
+-- Soft delete for risks and incidents: rows are moved to the trash by setting
+-- deleted_at and only removed for good by an explicit purge.
+ALTER TABLE risks ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE incidents ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_risks_deleted_at ON risks(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_incidents_deleted_at ON incidents(deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'restored';
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'purged';
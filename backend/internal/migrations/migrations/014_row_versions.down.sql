@@ -0,0 +1,3 @@
+ALTER TABLE mitigations DROP COLUMN version;
+ALTER TABLE incidents DROP COLUMN version;
+ALTER TABLE risks DROP COLUMN version;
@@ -0,0 +1,5 @@
+-- Row versions for optimistic concurrency: every write bumps version and
+-- clients send the version they last read back in If-Match.
+ALTER TABLE risks ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE incidents ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE mitigations ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
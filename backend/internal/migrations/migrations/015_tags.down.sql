@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_incidents_tags;
+DROP INDEX IF EXISTS idx_risks_tags;
+
+ALTER TABLE incidents DROP COLUMN tags;
+ALTER TABLE risks DROP COLUMN tags;
@@ -0,0 +1,6 @@
+-- Free-form tags on risks and incidents, stored as a JSONB array of strings
+ALTER TABLE risks ADD COLUMN tags JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE incidents ADD COLUMN tags JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE INDEX idx_risks_tags ON risks USING GIN (tags);
+CREATE INDEX idx_incidents_tags ON incidents USING GIN (tags);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_report_deliveries_subscription;
+DROP INDEX IF EXISTS idx_report_subscriptions_due;
+DROP INDEX IF EXISTS idx_report_subscriptions_user;
+
+DROP TABLE IF EXISTS report_deliveries;
+DROP TABLE IF EXISTS report_subscriptions;
+
+DROP TYPE IF EXISTS report_delivery_status;
+DROP TYPE IF EXISTS report_format;
+DROP TYPE IF EXISTS report_frequency;
@@ -0,0 +1,35 @@
+CREATE TYPE report_frequency AS ENUM ('weekly', 'monthly');
+CREATE TYPE report_format AS ENUM ('pdf', 'csv', 'xlsx');
+CREATE TYPE report_delivery_status AS ENUM ('sent', 'failed');
+
+-- Recurring report digests emailed to the subscribing user
+CREATE TABLE report_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    frequency report_frequency NOT NULL,
+    format report_format NOT NULL DEFAULT 'pdf',
+    filters JSONB NOT NULL DEFAULT '{}'::jsonb,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- One row per attempt to send a subscription's report
+CREATE TABLE report_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    subscription_id UUID NOT NULL REFERENCES report_subscriptions(id) ON DELETE CASCADE,
+    recipient VARCHAR(255) NOT NULL,
+    status report_delivery_status NOT NULL,
+    manual BOOLEAN NOT NULL DEFAULT FALSE,
+    filename VARCHAR(255) NOT NULL,
+    size_bytes INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_report_subscriptions_user ON report_subscriptions(user_id);
+CREATE INDEX idx_report_subscriptions_due ON report_subscriptions(next_run_at) WHERE active;
+CREATE INDEX idx_report_deliveries_subscription ON report_deliveries(subscription_id, created_at DESC);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_dead;
+DROP INDEX IF EXISTS idx_webhook_deliveries_webhook;
+DROP INDEX IF EXISTS idx_webhook_deliveries_due;
+DROP INDEX IF EXISTS idx_webhooks_event_types;
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+
+DROP TYPE IF EXISTS webhook_delivery_status;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_incident_alerts_incident;
+DROP INDEX IF EXISTS idx_incident_alerts_fingerprint;
+
+DROP TABLE IF EXISTS incident_alerts;
+DROP TABLE IF EXISTS alert_sources;
@@ -0,0 +1,32 @@
+-- Monitoring systems allowed to open incidents through the alert endpoints
+CREATE TABLE alert_sources (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    mapping JSONB NOT NULL DEFAULT '{}'::jsonb,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    last_received_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- One row per alert fingerprint and incident it opened. A repeat of the
+-- alert updates the row while its incident is still open.
+CREATE TABLE incident_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source_id UUID NOT NULL REFERENCES alert_sources(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(255) NOT NULL,
+    incident_id UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('firing', 'resolved')),
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    annotations JSONB NOT NULL DEFAULT '{}'::jsonb,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_incident_alerts_fingerprint ON incident_alerts(source_id, fingerprint, created_at DESC);
+CREATE INDEX idx_incident_alerts_incident ON incident_alerts(incident_id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_chat_messages_channel;
+DROP INDEX IF EXISTS idx_chat_messages_due;
+
+DROP TABLE IF EXISTS chat_messages;
+DROP TABLE IF EXISTS chat_channels;
+
+DROP TYPE IF EXISTS chat_message_status;
+DROP TYPE IF EXISTS chat_channel_type;
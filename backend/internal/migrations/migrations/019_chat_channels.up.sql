@@ -0,0 +1,40 @@
+CREATE TYPE chat_channel_type AS ENUM ('slack', 'teams');
+CREATE TYPE chat_message_status AS ENUM ('pending', 'sent', 'failed');
+
+-- Slack and Teams incoming webhooks that receive incident notifications.
+-- Empty priorities or category_ids match every incident.
+CREATE TABLE chat_channels (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    type chat_channel_type NOT NULL,
+    webhook_url TEXT NOT NULL,
+    triggers JSONB NOT NULL DEFAULT '[]'::jsonb,
+    priorities JSONB NOT NULL DEFAULT '[]'::jsonb,
+    category_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    templates JSONB NOT NULL DEFAULT '{}'::jsonb,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Rendered messages, queued in the same transaction as the audit entry that
+-- raised them and kept as the channel's message log
+CREATE TABLE chat_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    channel_id UUID NOT NULL REFERENCES chat_channels(id) ON DELETE CASCADE,
+    trigger VARCHAR(50) NOT NULL,
+    incident_id UUID NOT NULL,
+    payload JSONB NOT NULL,
+    status chat_message_status NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE,
+    last_attempt_at TIMESTAMP WITH TIME ZONE,
+    response_status INTEGER,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_chat_messages_due ON chat_messages(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_chat_messages_channel ON chat_messages(channel_id, created_at DESC);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_notifications_email_due;
+DROP INDEX IF EXISTS idx_notifications_dedupe;
+DROP INDEX IF EXISTS idx_notifications_unread;
+DROP INDEX IF EXISTS idx_notifications_inbox;
+
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+
+DROP TYPE IF EXISTS notification_email_status;
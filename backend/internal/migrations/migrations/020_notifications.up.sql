@@ -0,0 +1,38 @@
+CREATE TYPE notification_email_status AS ENUM ('pending', 'sent', 'failed');
+
+-- Per-user notifications. in_app rows make up the inbox; email_status is set
+-- when the notification is also emailed and tracks that delivery.
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    read_at TIMESTAMP WITH TIME ZONE,
+    -- Deadline notifications carry a key so each deadline is only raised once
+    dedupe_key VARCHAR(255),
+    email_status notification_email_status,
+    email_attempts INTEGER NOT NULL DEFAULT 0,
+    email_next_attempt_at TIMESTAMP WITH TIME ZONE,
+    email_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Channels chosen per event. Events without a row use the defaults.
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    channels JSONB NOT NULL DEFAULT '[]'::jsonb,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, event)
+);
+
+CREATE INDEX idx_notifications_inbox ON notifications(user_id, created_at DESC) WHERE in_app;
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE in_app AND read_at IS NULL;
+CREATE UNIQUE INDEX idx_notifications_dedupe ON notifications(user_id, dedupe_key) WHERE dedupe_key IS NOT NULL;
+CREATE INDEX idx_notifications_email_due ON notifications(email_next_attempt_at) WHERE email_status = 'pending';
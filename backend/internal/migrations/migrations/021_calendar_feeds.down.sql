@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_team_members_user;
+
+DROP TABLE IF EXISTS calendar_feeds;
+DROP TABLE IF EXISTS team_members;
+DROP TABLE IF EXISTS teams;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_framework_controls_parent;
+
+ALTER TABLE framework_controls
+    DROP COLUMN IF EXISTS sort_order,
+    DROP COLUMN IF EXISTS parent_id;
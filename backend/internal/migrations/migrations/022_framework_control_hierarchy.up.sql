@@ -0,0 +1,7 @@
+-- Controls imported from a catalog keep its structure: parent_id points at
+-- the enclosing group or control and sort_order is the catalog's order
+ALTER TABLE framework_controls
+    ADD COLUMN parent_id UUID REFERENCES framework_controls(id) ON DELETE SET NULL,
+    ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_framework_controls_parent ON framework_controls(parent_id);
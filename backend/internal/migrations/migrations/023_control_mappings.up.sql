@@ -0,0 +1,20 @@
+-- Crosswalk mappings between controls, usually of different frameworks.
+-- strength describes the source control against the target: equal, subset
+-- (the source covers only part of the target) or related. A pair of controls
+-- is mapped at most once, whichever way round.
+CREATE TABLE control_mappings (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source_control_id UUID NOT NULL REFERENCES framework_controls(id) ON DELETE CASCADE,
+    target_control_id UUID NOT NULL REFERENCES framework_controls(id) ON DELETE CASCADE,
+    strength VARCHAR(20) NOT NULL CHECK (strength IN ('equal', 'subset', 'related')),
+    notes TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (source_control_id <> target_control_id)
+);
+
+CREATE UNIQUE INDEX idx_control_mappings_pair ON control_mappings
+    (LEAST(source_control_id, target_control_id), GREATEST(source_control_id, target_control_id));
+CREATE INDEX idx_control_mappings_source ON control_mappings(source_control_id);
+CREATE INDEX idx_control_mappings_target ON control_mappings(target_control_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS soa_versions;
+DROP TABLE IF EXISTS control_applicability;
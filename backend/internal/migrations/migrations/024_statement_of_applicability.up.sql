@@ -0,0 +1,39 @@
+-- Statement of Applicability: whether each control applies, why, how far it
+-- is implemented and who owns it. A control without a row hasn't been
+-- assessed yet.
+CREATE TABLE control_applicability (
+    framework_control_id UUID PRIMARY KEY REFERENCES framework_controls(id) ON DELETE CASCADE,
+    applicability VARCHAR(20) NOT NULL CHECK (applicability IN ('applicable', 'excluded')),
+    justification TEXT NOT NULL DEFAULT '',
+    implementation_status VARCHAR(20) NOT NULL DEFAULT 'not_implemented'
+        CHECK (implementation_status IN ('not_implemented', 'planned', 'partial', 'implemented')),
+    owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_control_applicability_owner ON control_applicability(owner_id);
+
+-- Versions freeze a framework's SoA for sign-off. entries holds every record
+-- as it was when the version was created, so versions from different audit
+-- cycles can be compared after the controls have moved on. A version is
+-- pending until it is approved or rejected, and a framework has at most one
+-- pending version.
+CREATE TABLE soa_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    framework_id UUID NOT NULL REFERENCES frameworks(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    entries JSONB NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    decision_comment TEXT NOT NULL DEFAULT '',
+    UNIQUE (framework_id, version)
+);
+
+CREATE UNIQUE INDEX idx_soa_versions_pending ON soa_versions(framework_id) WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS control_test_results;
+DROP TABLE IF EXISTS control_tests;
@@ -0,0 +1,33 @@
+-- Scheduled tests of whether a control works. Recording a result moves
+-- next_due_date on by the frequency from the day of the test.
+CREATE TABLE control_tests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    framework_control_id UUID NOT NULL REFERENCES framework_controls(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    procedure TEXT NOT NULL DEFAULT '',
+    frequency VARCHAR(20) NOT NULL CHECK (frequency IN ('monthly', 'quarterly', 'semiannual', 'annual')),
+    tester_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    next_due_date DATE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_control_tests_control ON control_tests(framework_control_id);
+CREATE INDEX idx_control_tests_due ON control_tests(next_due_date) WHERE active;
+
+-- Every result recorded for a test. flagged_risks is how many linked risks
+-- an ineffective result brought forward for review.
+CREATE TABLE control_test_results (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    control_test_id UUID NOT NULL REFERENCES control_tests(id) ON DELETE CASCADE,
+    result VARCHAR(30) NOT NULL CHECK (result IN ('effective', 'partially_effective', 'ineffective')),
+    notes TEXT NOT NULL DEFAULT '',
+    tested_at DATE NOT NULL,
+    tested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    flagged_risks INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_control_test_results_test ON control_test_results(control_test_id, tested_at DESC);
@@ -0,0 +1,3 @@
+-- Note: PostgreSQL does not support removing enum values directly, so the
+-- 'downloaded' audit action is left in place. Stored files are not removed.
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,19 @@
+-- Evidence files attached to risks, mitigations, incidents and framework
+-- controls. The file itself lives in the configured storage under
+-- storage_key; the parent is referenced by type and ID like audit entries.
+CREATE TABLE attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entity_type VARCHAR(50) NOT NULL CHECK (entity_type IN ('risk', 'mitigation', 'incident', 'framework_control')),
+    entity_id UUID NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+    sha256 CHAR(64) NOT NULL,
+    storage_key VARCHAR(500) NOT NULL UNIQUE,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_attachments_entity ON attachments(entity_type, entity_id);
+
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'downloaded';
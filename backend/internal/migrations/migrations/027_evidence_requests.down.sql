@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS evidence_submissions;
+DROP TABLE IF EXISTS evidence_requests;
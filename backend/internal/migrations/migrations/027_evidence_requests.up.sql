@@ -0,0 +1,35 @@
+-- Evidence an assignee is asked to provide for a control by due_date.
+-- Fulfilling a recurring request moves due_date on by the recurrence and
+-- keeps it open; a one-off request (no recurrence) becomes fulfilled.
+CREATE TABLE evidence_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    framework_control_id UUID NOT NULL REFERENCES framework_controls(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    due_date DATE NOT NULL,
+    recurrence VARCHAR(20) CHECK (recurrence IN ('monthly', 'quarterly', 'semiannual', 'annual')),
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'fulfilled')),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_evidence_requests_control ON evidence_requests(framework_control_id);
+CREATE INDEX idx_evidence_requests_assignee ON evidence_requests(assignee_id);
+CREATE INDEX idx_evidence_requests_due ON evidence_requests(due_date) WHERE status = 'open';
+
+-- The evidence provided for each period of a request. due_date is the date
+-- the submission was for.
+CREATE TABLE evidence_submissions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    evidence_request_id UUID NOT NULL REFERENCES evidence_requests(id) ON DELETE CASCADE,
+    due_date DATE NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    note TEXT NOT NULL DEFAULT '',
+    submitted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (url <> '' OR note <> '')
+);
+
+CREATE INDEX idx_evidence_submissions_request ON evidence_submissions(evidence_request_id, created_at DESC);
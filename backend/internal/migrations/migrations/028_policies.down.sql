@@ -0,0 +1,9 @@
+DELETE FROM attachments WHERE entity_type = 'policy';
+ALTER TABLE attachments DROP CONSTRAINT attachments_entity_type_check;
+ALTER TABLE attachments ADD CONSTRAINT attachments_entity_type_check
+    CHECK (entity_type IN ('risk', 'mitigation', 'incident', 'framework_control'));
+
+DROP TABLE IF EXISTS policy_acknowledgements;
+DROP TABLE IF EXISTS policy_controls;
+DROP TABLE IF EXISTS policy_versions;
+DROP TABLE IF EXISTS policies;
@@ -0,0 +1,68 @@
+-- Policy register. A policy's content lives in numbered versions; the
+-- latest approved version is the one in force and is due for review one
+-- review_cycle after its approval.
+CREATE TABLE policies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    review_cycle VARCHAR(20) NOT NULL DEFAULT 'annual'
+        CHECK (review_cycle IN ('monthly', 'quarterly', 'semiannual', 'annual')),
+    next_review_date DATE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_policies_owner ON policies(owner_id);
+
+-- A version's document is a markdown body, an attachment of the policy, or
+-- both. Drafts can be edited until they are submitted; a submitted version
+-- is pending until it is approved or rejected. A policy has at most one
+-- version in draft or pending at a time.
+CREATE TABLE policy_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    policy_id UUID NOT NULL REFERENCES policies(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    attachment_id UUID REFERENCES attachments(id) ON DELETE RESTRICT,
+    change_summary TEXT NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'pending', 'approved', 'rejected')),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    submitted_at TIMESTAMP WITH TIME ZONE,
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    decision_comment TEXT NOT NULL DEFAULT '',
+    UNIQUE (policy_id, version),
+    CHECK (body <> '' OR attachment_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX idx_policy_versions_open ON policy_versions(policy_id) WHERE status IN ('draft', 'pending');
+CREATE INDEX idx_policy_versions_attachment ON policy_versions(attachment_id);
+
+-- The controls a policy implements
+CREATE TABLE policy_controls (
+    policy_id UUID NOT NULL REFERENCES policies(id) ON DELETE CASCADE,
+    framework_control_id UUID NOT NULL REFERENCES framework_controls(id) ON DELETE CASCADE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (policy_id, framework_control_id)
+);
+
+CREATE INDEX idx_policy_controls_control ON policy_controls(framework_control_id);
+
+-- Who has read and accepted each approved version
+CREATE TABLE policy_acknowledgements (
+    policy_version_id UUID NOT NULL REFERENCES policy_versions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    acknowledged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (policy_version_id, user_id)
+);
+
+-- Policies can have attachments, which is where version documents are
+-- uploaded
+ALTER TABLE attachments DROP CONSTRAINT attachments_entity_type_check;
+ALTER TABLE attachments ADD CONSTRAINT attachments_entity_type_check
+    CHECK (entity_type IN ('risk', 'mitigation', 'incident', 'framework_control', 'policy'));
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_frameworks_previous_version;
+
+ALTER TABLE frameworks
+    DROP CONSTRAINT IF EXISTS frameworks_previous_version_check,
+    DROP COLUMN IF EXISTS previous_version_id,
+    DROP COLUMN IF EXISTS version;
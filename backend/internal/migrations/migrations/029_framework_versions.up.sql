@@ -0,0 +1,9 @@
+-- Frameworks carry the version of the standard they hold, and a revision
+-- points at the framework it supersedes so risk links can be migrated
+-- from one to the other
+ALTER TABLE frameworks
+    ADD COLUMN version VARCHAR(50) NOT NULL DEFAULT '',
+    ADD COLUMN previous_version_id UUID REFERENCES frameworks(id) ON DELETE SET NULL,
+    ADD CONSTRAINT frameworks_previous_version_check CHECK (previous_version_id <> id);
+
+CREATE INDEX idx_frameworks_previous_version ON frameworks(previous_version_id);
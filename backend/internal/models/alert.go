@@ -0,0 +1,95 @@
+package models
+
+import "time"
+
+// AlertStatus is the state an incoming alert reports
+type AlertStatus string
+
+const (
+	AlertStatusFiring   AlertStatus = "firing"
+	AlertStatusResolved AlertStatus = "resolved"
+)
+
+// AlertMapping says how an alert source's labels become incident fields.
+// Empty label names fall back to the Alertmanager conventions: severity,
+// service and category.
+type AlertMapping struct {
+	PriorityLabel string `json:"priority_label,omitempty"`
+	// PriorityMap maps label values (case-insensitive) to priorities and is
+	// consulted before the built-in critical/high/warning/info mapping
+	PriorityMap     map[string]IncidentPriority `json:"priority_map,omitempty"`
+	DefaultPriority IncidentPriority            `json:"default_priority,omitempty"`
+	ServiceLabel    string                      `json:"service_label,omitempty"`
+	CategoryLabel   string                      `json:"category_label,omitempty"`
+	// CategoryMap maps label values (case-insensitive) to incident category
+	// IDs. Values not in the map are matched against category names.
+	CategoryMap       map[string]string `json:"category_map,omitempty"`
+	DefaultCategoryID *string           `json:"default_category_id,omitempty"`
+}
+
+// AlertSource is a monitoring system allowed to open incidents. It
+// authenticates with a bearer token that is only returned when the source is
+// created or its token is rotated; only a hash of it is stored.
+type AlertSource struct {
+	ID             string       `json:"id"`
+	Name           string       `json:"name"`
+	Token          string       `json:"token,omitempty"`
+	Mapping        AlertMapping `json:"mapping"`
+	Active         bool         `json:"active"`
+	CreatedBy      string       `json:"created_by"`
+	LastReceivedAt *time.Time   `json:"last_received_at,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+type CreateAlertSourceInput struct {
+	Name    string        `json:"name"`
+	Mapping *AlertMapping `json:"mapping"`
+	Active  *bool         `json:"active"`
+}
+
+type UpdateAlertSourceInput struct {
+	Name    *string       `json:"name"`
+	Mapping *AlertMapping `json:"mapping"`
+	Active  *bool         `json:"active"`
+	// RotateToken replaces the token with a new generated one
+	RotateToken bool `json:"rotate_token"`
+}
+
+// IncidentAlert ties an alert fingerprint from a source to the incident it
+// opened. Repeats of the alert update the same row while the incident is open.
+type IncidentAlert struct {
+	ID             string            `json:"id"`
+	SourceID       string            `json:"source_id"`
+	Fingerprint    string            `json:"fingerprint"`
+	IncidentID     string            `json:"incident_id"`
+	Status         AlertStatus       `json:"status"`
+	Labels         map[string]string `json:"labels"`
+	Annotations    map[string]string `json:"annotations"`
+	StartsAt       time.Time         `json:"starts_at"`
+	EndsAt         *time.Time        `json:"ends_at,omitempty"`
+	Occurrences    int               `json:"occurrences"`
+	CreatedAt      time.Time         `json:"created_at"`
+	LastReceivedAt time.Time         `json:"last_received_at"`
+}
+
+// AlertIngestAction is what ingesting one alert did
+type AlertIngestAction string
+
+const (
+	AlertIngestCreated  AlertIngestAction = "created"
+	AlertIngestUpdated  AlertIngestAction = "updated"
+	AlertIngestResolved AlertIngestAction = "resolved"
+	// AlertIngestIgnored is a resolved alert with no open incident
+	AlertIngestIgnored AlertIngestAction = "ignored"
+)
+
+type AlertIngestResult struct {
+	Fingerprint string            `json:"fingerprint"`
+	Action      AlertIngestAction `json:"action"`
+	IncidentID  string            `json:"incident_id,omitempty"`
+}
+
+type AlertIngestResponse struct {
+	Results []AlertIngestResult `json:"results"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// AttachmentEntity is the kind of record an attachment belongs to
+type AttachmentEntity string
+
+const (
+	AttachmentRisk             AttachmentEntity = "risk"
+	AttachmentMitigation       AttachmentEntity = "mitigation"
+	AttachmentIncident         AttachmentEntity = "incident"
+	AttachmentFrameworkControl AttachmentEntity = "framework_control"
+	AttachmentPolicy           AttachmentEntity = "policy"
+)
+
+// Attachment is an evidence file linked to a risk, mitigation, incident,
+// framework control or policy. SHA256 is the hex digest of the content as
+// uploaded.
+type Attachment struct {
+	ID             string           `json:"id"`
+	EntityType     AttachmentEntity `json:"entity_type"`
+	EntityID       string           `json:"entity_id"`
+	Filename       string           `json:"filename"`
+	ContentType    string           `json:"content_type"`
+	SizeBytes      int64            `json:"size_bytes"`
+	SHA256         string           `json:"sha256"`
+	StorageKey     string           `json:"-"`
+	UploadedBy     *string          `json:"uploaded_by,omitempty"`
+	UploadedByName string           `json:"uploaded_by_name,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
@@ -5,9 +5,13 @@ import "time"
 type AuditAction string
 
 const (
-	AuditActionCreated AuditAction = "created"
-	AuditActionUpdated AuditAction = "updated"
-	AuditActionDeleted AuditAction = "deleted"
+	AuditActionCreated  AuditAction = "created"
+	AuditActionUpdated  AuditAction = "updated"
+	AuditActionDeleted  AuditAction = "deleted"
+	AuditActionRestored AuditAction = "restored"
+	AuditActionPurged   AuditAction = "purged"
+	// AuditActionDownloaded records that an attachment was read
+	AuditActionDownloaded AuditAction = "downloaded"
 )
 
 type AuditLog struct {
@@ -0,0 +1,78 @@
+package models
+
+type BulkAction string
+
+const (
+	BulkActionSetStatus   BulkAction = "set_status"
+	BulkActionSetOwner    BulkAction = "set_owner"
+	BulkActionSetAssignee BulkAction = "set_assignee"
+	BulkActionSetCategory BulkAction = "set_category"
+	BulkActionAddTag      BulkAction = "add_tag"
+	BulkActionDelete      BulkAction = "delete"
+)
+
+type BulkItemStatus string
+
+const (
+	BulkItemUpdated    BulkItemStatus = "updated"
+	BulkItemDeleted    BulkItemStatus = "deleted"
+	BulkItemUnchanged  BulkItemStatus = "unchanged"
+	BulkItemFailed     BulkItemStatus = "failed"
+	BulkItemRolledBack BulkItemStatus = "rolled_back"
+)
+
+// RiskBulkFilter selects risks with the same criteria as the list endpoint
+type RiskBulkFilter struct {
+	Status     string `json:"status"`
+	Severity   string `json:"severity"`
+	CategoryID string `json:"category_id"`
+	OwnerID    string `json:"owner_id"`
+	Tag        string `json:"tag"`
+	Search     string `json:"search"`
+}
+
+// IncidentBulkFilter selects incidents with the same criteria as the list endpoint
+type IncidentBulkFilter struct {
+	Status     string `json:"status"`
+	Priority   string `json:"priority"`
+	CategoryID string `json:"category_id"`
+	AssigneeID string `json:"assignee_id"`
+	Tag        string `json:"tag"`
+	Search     string `json:"search"`
+}
+
+type RiskBulkInput struct {
+	IDs    []string        `json:"ids"`
+	Filter *RiskBulkFilter `json:"filter"`
+	Action BulkAction      `json:"action"`
+	Value  *string         `json:"value"`
+	DryRun bool            `json:"dry_run"`
+	Atomic bool            `json:"atomic"`
+}
+
+type IncidentBulkInput struct {
+	IDs    []string            `json:"ids"`
+	Filter *IncidentBulkFilter `json:"filter"`
+	Action BulkAction          `json:"action"`
+	Value  *string             `json:"value"`
+	DryRun bool                `json:"dry_run"`
+	Atomic bool                `json:"atomic"`
+}
+
+type BulkItemResult struct {
+	ID      string         `json:"id"`
+	Status  BulkItemStatus `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Changes map[string]any `json:"changes,omitempty"`
+}
+
+type BulkResult struct {
+	Action    BulkAction        `json:"action"`
+	DryRun    bool              `json:"dry_run"`
+	Atomic    bool              `json:"atomic"`
+	Committed bool              `json:"committed"`
+	Matched   int               `json:"matched"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []*BulkItemResult `json:"results"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// CalendarScope picks whose deadlines a calendar feed shows
+type CalendarScope string
+
+const (
+	// CalendarScopeMine covers risks the user owns and mitigations assigned
+	// to them
+	CalendarScopeMine CalendarScope = "mine"
+	// CalendarScopeTeam widens that to everyone sharing a team with the user
+	CalendarScopeTeam CalendarScope = "team"
+)
+
+var CalendarScopes = []CalendarScope{CalendarScopeMine, CalendarScopeTeam}
+
+// CalendarFeed is a user's subscribable calendar. The token is part of the
+// feed URLs and is only returned when the feed is created or rotated; only a
+// hash of it is stored.
+type CalendarFeed struct {
+	UserID        string                   `json:"user_id"`
+	Token         string                   `json:"token,omitempty"`
+	URLs          map[CalendarScope]string `json:"urls,omitempty"`
+	CreatedAt     time.Time                `json:"created_at"`
+	LastFetchedAt *time.Time               `json:"last_fetched_at,omitempty"`
+}
+
+// CalendarEntryKind is the kind of deadline a calendar entry marks
+type CalendarEntryKind string
+
+const (
+	CalendarEntryReview        CalendarEntryKind = "review"
+	CalendarEntryMitigationDue CalendarEntryKind = "mitigation_due"
+)
+
+// CalendarEntry is a risk review date or a mitigation due date. EntityID is
+// the risk for reviews and the mitigation for due dates.
+type CalendarEntry struct {
+	Kind        CalendarEntryKind
+	EntityID    string
+	RiskID      string
+	RiskTitle   string
+	Description string
+	Date        time.Time
+	Severity    RiskSeverity
+	Status      string
+	Owner       string
+	Version     int
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChatChannelType is the incoming-webhook message format a channel posts
+type ChatChannelType string
+
+const (
+	ChatChannelSlack ChatChannelType = "slack"
+	ChatChannelTeams ChatChannelType = "teams"
+)
+
+// ChatTrigger is an incident lifecycle change that can post to chat
+type ChatTrigger string
+
+const (
+	ChatTriggerCreated ChatTrigger = "incident.created"
+	// ChatTriggerEscalated fires when an existing incident is raised to P1
+	ChatTriggerEscalated ChatTrigger = "incident.escalated"
+	ChatTriggerAssigned  ChatTrigger = "incident.assigned"
+	ChatTriggerResolved  ChatTrigger = "incident.resolved"
+)
+
+// ChatTriggers lists every trigger a channel can subscribe to
+var ChatTriggers = []ChatTrigger{ChatTriggerCreated, ChatTriggerEscalated, ChatTriggerAssigned, ChatTriggerResolved}
+
+type ChatMessageStatus string
+
+const (
+	ChatMessagePending ChatMessageStatus = "pending"
+	ChatMessageSent    ChatMessageStatus = "sent"
+	// ChatMessageFailed means every retry failed
+	ChatMessageFailed ChatMessageStatus = "failed"
+)
+
+// ChatTemplate is a message heading and body written as Go text/template
+// text. Empty fields use the built-in wording for the trigger.
+type ChatTemplate struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// ChatChannel posts incident notifications to a Slack or Teams incoming
+// webhook. Empty Priorities or CategoryIDs match every incident.
+type ChatChannel struct {
+	ID          string                       `json:"id"`
+	Name        string                       `json:"name"`
+	Type        ChatChannelType              `json:"type"`
+	WebhookURL  string                       `json:"webhook_url"`
+	Triggers    []ChatTrigger                `json:"triggers"`
+	Priorities  []IncidentPriority           `json:"priorities"`
+	CategoryIDs []string                     `json:"category_ids"`
+	Templates   map[ChatTrigger]ChatTemplate `json:"templates"`
+	Active      bool                         `json:"active"`
+	CreatedBy   string                       `json:"created_by"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	UpdatedAt   time.Time                    `json:"updated_at"`
+}
+
+type CreateChatChannelInput struct {
+	Name        string                       `json:"name"`
+	Type        ChatChannelType              `json:"type"`
+	WebhookURL  string                       `json:"webhook_url"`
+	Triggers    []ChatTrigger                `json:"triggers"`
+	Priorities  []IncidentPriority           `json:"priorities"`
+	CategoryIDs []string                     `json:"category_ids"`
+	Templates   map[ChatTrigger]ChatTemplate `json:"templates"`
+	Active      *bool                        `json:"active"`
+}
+
+type UpdateChatChannelInput struct {
+	Name        *string                       `json:"name"`
+	Type        *ChatChannelType              `json:"type"`
+	WebhookURL  *string                       `json:"webhook_url"`
+	Triggers    *[]ChatTrigger                `json:"triggers"`
+	Priorities  *[]IncidentPriority           `json:"priorities"`
+	CategoryIDs *[]string                     `json:"category_ids"`
+	Templates   *map[ChatTrigger]ChatTemplate `json:"templates"`
+	Active      *bool                         `json:"active"`
+}
+
+// ChatMessage is a rendered notification queued for, or posted to, a channel
+type ChatMessage struct {
+	ID             string            `json:"id"`
+	ChannelID      string            `json:"channel_id"`
+	Trigger        ChatTrigger       `json:"trigger"`
+	IncidentID     string            `json:"incident_id"`
+	Payload        json.RawMessage   `json:"payload"`
+	Status         ChatMessageStatus `json:"status"`
+	Attempts       int               `json:"attempts"`
+	NextAttemptAt  *time.Time        `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time        `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int              `json:"response_status,omitempty"`
+	Error          *string           `json:"error,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	SentAt         *time.Time        `json:"sent_at,omitempty"`
+}
+
+// ChatDispatch is a claimed message together with where to post it
+type ChatDispatch struct {
+	Message    *ChatMessage
+	WebhookURL string
+}
+
+// ChatAttempt is the outcome of posting a message once
+type ChatAttempt struct {
+	Status         ChatMessageStatus
+	AttemptedAt    time.Time
+	NextAttemptAt  *time.Time
+	ResponseStatus *int
+	Error          *string
+}
+
+type ChatMessageListResponse struct {
+	Messages []*ChatMessage `json:"messages"`
+}
@@ -9,22 +9,41 @@ type FrameworkControl struct {
 	ControlRef      string    `json:"control_ref" db:"control_ref"`
 	Title           string    `json:"title" db:"title"`
 	Description     string    `json:"description,omitempty" db:"description"`
+	ParentID        *string   `json:"parent_id,omitempty" db:"parent_id"`
+	SortOrder       int       `json:"sort_order" db:"sort_order"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 	LinkedRiskCount int       `json:"linked_risk_count" db:"linked_risk_count"`
 }
 
 type CreateFrameworkControlInput struct {
-	FrameworkID string `json:"framework_id" validate:"required,uuid"`
-	ControlRef  string `json:"control_ref" validate:"required"`
-	Title       string `json:"title" validate:"required"`
-	Description string `json:"description"`
+	FrameworkID string  `json:"framework_id" validate:"required,uuid"`
+	ControlRef  string  `json:"control_ref" validate:"required"`
+	Title       string  `json:"title" validate:"required"`
+	Description string  `json:"description"`
+	ParentID    *string `json:"parent_id"`
+	SortOrder   int     `json:"sort_order"`
 }
 
+// UpdateFrameworkControlInput changes the fields that are set. An empty
+// ParentID moves the control to the top of the tree.
 type UpdateFrameworkControlInput struct {
 	ControlRef  *string `json:"control_ref"`
 	Title       *string `json:"title"`
 	Description *string `json:"description"`
+	ParentID    *string `json:"parent_id"`
+	SortOrder   *int    `json:"sort_order"`
+}
+
+// ControlTreeNode is a control with its children. The rolled-up counts cover
+// the control and everything below it: the distinct risks linked to any of
+// them, how many controls there are and how many have a linked risk.
+type ControlTreeNode struct {
+	*FrameworkControl
+	RolledUpRiskCount   int                `json:"rolled_up_risk_count"`
+	ControlCount        int                `json:"control_count"`
+	CoveredControlCount int                `json:"covered_control_count"`
+	Children            []*ControlTreeNode `json:"children"`
 }
 
 type LinkControlInput struct {
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// ControlCoverageStatus says how well a control is covered by the register
+type ControlCoverageStatus string
+
+const (
+	// ControlCovered controls have at least one open or mitigating risk
+	ControlCovered ControlCoverageStatus = "covered"
+	// ControlInactiveOnly controls are only linked to resolved or accepted
+	// risks
+	ControlInactiveOnly ControlCoverageStatus = "inactive_only"
+	// ControlUnlinked controls have no linked risks
+	ControlUnlinked ControlCoverageStatus = "unlinked"
+)
+
+// CoverageControl is one control in a coverage report
+type CoverageControl struct {
+	ID              string                `json:"id"`
+	ControlRef      string                `json:"control_ref"`
+	Title           string                `json:"title"`
+	Family          string                `json:"family"`
+	Status          ControlCoverageStatus `json:"status"`
+	LinkedRiskCount int                   `json:"linked_risk_count"`
+	ActiveRiskCount int                   `json:"active_risk_count"`
+}
+
+// CoverageFamily sums up the controls of one family. Linked includes the
+// inactive-only controls, as does the coverage percentage.
+type CoverageFamily struct {
+	Family          string  `json:"family"`
+	Title           string  `json:"title,omitempty"`
+	Total           int     `json:"total"`
+	Linked          int     `json:"linked"`
+	Unlinked        int     `json:"unlinked"`
+	InactiveOnly    int     `json:"inactive_only"`
+	CoveragePercent float64 `json:"coverage_percent"`
+}
+
+// FrameworkCoverage is the gap analysis of one framework: how many of its
+// controls have a linked risk, and which ones don't or only have closed
+// risks. Controls lists every control for exports and isn't sent as JSON.
+type FrameworkCoverage struct {
+	FrameworkID          string             `json:"framework_id"`
+	FrameworkName        string             `json:"framework_name"`
+	GeneratedAt          time.Time          `json:"generated_at"`
+	TotalControls        int                `json:"total_controls"`
+	LinkedControls       int                `json:"linked_controls"`
+	UnlinkedControls     int                `json:"unlinked_controls"`
+	InactiveOnlyControls int                `json:"inactive_only_controls"`
+	CoveragePercent      float64            `json:"coverage_percent"`
+	Families             []*CoverageFamily  `json:"families"`
+	Unlinked             []*CoverageControl `json:"unlinked"`
+	InactiveOnly         []*CoverageControl `json:"inactive_only"`
+	Controls             []*CoverageControl `json:"-"`
+}
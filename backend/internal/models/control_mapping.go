@@ -0,0 +1,126 @@
+package models
+
+import "time"
+
+// MappingStrength says how a mapping's source control relates to its target
+type MappingStrength string
+
+const (
+	MappingEqual MappingStrength = "equal"
+	// MappingSubset means the source control covers only part of the target
+	MappingSubset  MappingStrength = "subset"
+	MappingRelated MappingStrength = "related"
+)
+
+// MappingRelation is how the control a mapping is followed from relates to
+// the control it leads to: the mapping's strength, with subset turned round
+// to superset when the mapping is followed from its target
+type MappingRelation string
+
+const (
+	RelationEqual    MappingRelation = "equal"
+	RelationSubset   MappingRelation = "subset"
+	RelationSuperset MappingRelation = "superset"
+	RelationRelated  MappingRelation = "related"
+)
+
+// MappingCoverage is how much of a mapped control is covered by what covers
+// the control it was reached from: full when they are equal or the mapped
+// control is a subset of it, partial otherwise
+type MappingCoverage string
+
+const (
+	CoverageFull    MappingCoverage = "full"
+	CoveragePartial MappingCoverage = "partial"
+)
+
+// ControlSummary identifies a control and its framework
+type ControlSummary struct {
+	ID            string `json:"id"`
+	FrameworkID   string `json:"framework_id"`
+	FrameworkName string `json:"framework_name"`
+	ControlRef    string `json:"control_ref"`
+	Title         string `json:"title"`
+}
+
+// ControlMapping is a crosswalk entry between two controls
+type ControlMapping struct {
+	ID        string          `json:"id"`
+	Source    ControlSummary  `json:"source"`
+	Target    ControlSummary  `json:"target"`
+	Strength  MappingStrength `json:"strength"`
+	Notes     string          `json:"notes"`
+	CreatedBy *string         `json:"created_by,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type CreateControlMappingInput struct {
+	SourceControlID string          `json:"source_control_id"`
+	TargetControlID string          `json:"target_control_id"`
+	Strength        MappingStrength `json:"strength"`
+	Notes           string          `json:"notes"`
+}
+
+type UpdateControlMappingInput struct {
+	Strength *MappingStrength `json:"strength"`
+	Notes    *string          `json:"notes"`
+}
+
+// ControlMappingFilter narrows a mapping list to those touching a control or
+// a framework, on either side
+type ControlMappingFilter struct {
+	ControlID   string
+	FrameworkID string
+}
+
+// MappedControl is a control reached through a mapping
+type MappedControl struct {
+	Control   ControlSummary  `json:"control"`
+	MappingID string          `json:"mapping_id"`
+	Strength  MappingStrength `json:"strength"`
+	Relation  MappingRelation `json:"relation"`
+	Coverage  MappingCoverage `json:"coverage"`
+	// Via is the control the mapping was followed from, when listing the
+	// controls a risk covers through its linked controls
+	Via *ControlSummary `json:"via,omitempty"`
+}
+
+// ControlCrosswalk answers which controls of other frameworks are covered by
+// the risks linked to a control
+type ControlCrosswalk struct {
+	Control *FrameworkControl    `json:"control"`
+	Risks   []*ControlLinkedRisk `json:"risks"`
+	Mapped  []*MappedControl     `json:"mapped"`
+}
+
+// ControlMappingImportRow is one spreadsheet row resolved into a mapping
+type ControlMappingImportRow struct {
+	Row             int             `json:"row"`
+	SourceFramework string          `json:"source_framework"`
+	SourceRef       string          `json:"source_ref"`
+	TargetFramework string          `json:"target_framework"`
+	TargetRef       string          `json:"target_ref"`
+	Strength        MappingStrength `json:"strength"`
+	Notes           string          `json:"notes,omitempty"`
+	SourceControlID string          `json:"source_control_id,omitempty"`
+	TargetControlID string          `json:"target_control_id,omitempty"`
+	MappingID       string          `json:"mapping_id,omitempty"`
+	// Action is create, update or unchanged for a valid row
+	Action string            `json:"action,omitempty"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type ControlMappingImportResult struct {
+	BatchID     string                     `json:"batch_id,omitempty"`
+	Filename    string                     `json:"filename"`
+	Format      string                     `json:"format"`
+	Committed   bool                       `json:"committed"`
+	TotalRows   int                        `json:"total_rows"`
+	ValidRows   int                        `json:"valid_rows"`
+	InvalidRows int                        `json:"invalid_rows"`
+	Created     int                        `json:"created"`
+	Updated     int                        `json:"updated"`
+	Unchanged   int                        `json:"unchanged"`
+	Rows        []*ControlMappingImportRow `json:"rows"`
+}
@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// ControlTestResultValue is the outcome of one run of a control test
+type ControlTestResultValue string
+
+const (
+	ControlEffective          ControlTestResultValue = "effective"
+	ControlPartiallyEffective ControlTestResultValue = "partially_effective"
+	ControlIneffective        ControlTestResultValue = "ineffective"
+)
+
+// ControlTest is a scheduled check of whether a framework control works.
+// Overdue is set for active tests whose next_due_date has passed.
+type ControlTest struct {
+	ID           string             `json:"id"`
+	ControlID    string             `json:"control_id"`
+	ControlRef   string             `json:"control_ref"`
+	ControlTitle string             `json:"control_title"`
+	FrameworkID  string             `json:"framework_id"`
+	Name         string             `json:"name"`
+	Procedure    string             `json:"procedure"`
+	Frequency    Recurrence         `json:"frequency"`
+	TesterID     *string            `json:"tester_id,omitempty"`
+	TesterName   string             `json:"tester_name,omitempty"`
+	NextDueDate  time.Time          `json:"next_due_date"`
+	Active       bool               `json:"active"`
+	Overdue      bool               `json:"overdue"`
+	LastResult   *ControlTestResult `json:"last_result,omitempty"`
+	CreatedBy    *string            `json:"created_by,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+// ControlTestResult is one recorded run of a control test. FlaggedRisks is
+// the number of linked risks an ineffective result brought forward for
+// review.
+type ControlTestResult struct {
+	ID            string                 `json:"id"`
+	ControlTestID string                 `json:"control_test_id"`
+	Result        ControlTestResultValue `json:"result"`
+	Notes         string                 `json:"notes"`
+	TestedAt      time.Time              `json:"tested_at"`
+	TestedBy      *string                `json:"tested_by,omitempty"`
+	TestedByName  string                 `json:"tested_by_name,omitempty"`
+	FlaggedRisks  int                    `json:"flagged_risks"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// ControlTestFilter narrows a list of control tests. Overdue only keeps
+// active tests past their due date.
+type ControlTestFilter struct {
+	ControlID string
+	TesterID  string
+	Overdue   bool
+}
+
+// CreateControlTestInput schedules a test. NextDueDate is YYYY-MM-DD and
+// defaults to one period from today.
+type CreateControlTestInput struct {
+	ControlID   string     `json:"control_id"`
+	Name        string     `json:"name"`
+	Procedure   string     `json:"procedure"`
+	Frequency   Recurrence `json:"frequency"`
+	TesterID    *string    `json:"tester_id"`
+	NextDueDate *string    `json:"next_due_date"`
+}
+
+// UpdateControlTestInput changes the fields that are set
+type UpdateControlTestInput struct {
+	Name        *string     `json:"name"`
+	Procedure   *string     `json:"procedure"`
+	Frequency   *Recurrence `json:"frequency"`
+	TesterID    *string     `json:"tester_id"`
+	NextDueDate *string     `json:"next_due_date"`
+	Active      *bool       `json:"active"`
+}
+
+// RecordControlTestResultInput records a run of a test. TestedAt is
+// YYYY-MM-DD and defaults to today.
+type RecordControlTestResultInput struct {
+	Result   ControlTestResultValue `json:"result"`
+	Notes    string                 `json:"notes"`
+	TestedAt *string                `json:"tested_at"`
+}
+
+// FlaggedRisk is a risk whose review date an ineffective test result
+// brought forward, with the review date it had before
+type FlaggedRisk struct {
+	ID                 string     `json:"id"`
+	PreviousReviewDate *time.Time `json:"previous_review_date,omitempty"`
+	ReviewDate         time.Time  `json:"review_date"`
+}
@@ -11,11 +11,13 @@ type CategoryCount struct {
 
 // DashboardSummaryResponse represents the dashboard summary data
 type DashboardSummaryResponse struct {
-	TotalRisks     int             `json:"total_risks"`
-	ByStatus       map[string]int  `json:"by_status"`
-	BySeverity     map[string]int  `json:"by_severity"`
-	ByCategory     []CategoryCount `json:"by_category"`
-	OverdueReviews int             `json:"overdue_reviews"`
+	TotalRisks              int             `json:"total_risks"`
+	ByStatus                map[string]int  `json:"by_status"`
+	BySeverity              map[string]int  `json:"by_severity"`
+	ByCategory              []CategoryCount `json:"by_category"`
+	OverdueReviews          int             `json:"overdue_reviews"`
+	OverdueControlTests     int             `json:"overdue_control_tests"`
+	OverdueEvidenceRequests int             `json:"overdue_evidence_requests"`
 }
 
 // ReviewRisk represents a risk with review date information
@@ -27,6 +29,22 @@ type ReviewRisk struct {
 	Status     string    `json:"status"`
 }
 
+// TopRisk is an unresolved risk ranked by severity for reporting
+type TopRisk struct {
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	Severity   string     `json:"severity"`
+	Status     string     `json:"status"`
+	OwnerName  string     `json:"owner_name"`
+	Category   string     `json:"category"`
+	ReviewDate *time.Time `json:"review_date,omitempty"`
+}
+
+// TopRiskListResponse represents the response for the top risks endpoint
+type TopRiskListResponse struct {
+	Risks []TopRisk `json:"risks"`
+}
+
 // ReviewListResponse represents the response for review endpoints
 type ReviewListResponse struct {
 	Risks []ReviewRisk `json:"risks"`
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+type EvidenceRequestStatus string
+
+const (
+	EvidenceOpen      EvidenceRequestStatus = "open"
+	EvidenceFulfilled EvidenceRequestStatus = "fulfilled"
+)
+
+// EvidenceRequest asks an assignee to provide evidence for a framework
+// control by DueDate. Recurring requests stay open and come due again one
+// period later each time they are fulfilled. Overdue is set for open
+// requests whose due date has passed.
+type EvidenceRequest struct {
+	ID             string                `json:"id"`
+	ControlID      string                `json:"control_id"`
+	ControlRef     string                `json:"control_ref"`
+	ControlTitle   string                `json:"control_title"`
+	FrameworkID    string                `json:"framework_id"`
+	Title          string                `json:"title"`
+	Description    string                `json:"description"`
+	AssigneeID     *string               `json:"assignee_id,omitempty"`
+	AssigneeName   string                `json:"assignee_name,omitempty"`
+	DueDate        time.Time             `json:"due_date"`
+	Recurrence     *Recurrence           `json:"recurrence,omitempty"`
+	Status         EvidenceRequestStatus `json:"status"`
+	Overdue        bool                  `json:"overdue"`
+	LastSubmission *EvidenceSubmission   `json:"last_submission,omitempty"`
+	CreatedBy      *string               `json:"created_by,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+}
+
+// EvidenceSubmission is the evidence provided for one due date of a
+// request: a link to a document, a note, or both
+type EvidenceSubmission struct {
+	ID                string    `json:"id"`
+	EvidenceRequestID string    `json:"evidence_request_id"`
+	DueDate           time.Time `json:"due_date"`
+	URL               string    `json:"url"`
+	Note              string    `json:"note"`
+	SubmittedBy       *string   `json:"submitted_by,omitempty"`
+	SubmittedByName   string    `json:"submitted_by_name,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// EvidenceRequestFilter narrows a list of evidence requests. Overdue only
+// keeps open requests past their due date.
+type EvidenceRequestFilter struct {
+	ControlID  string
+	AssigneeID string
+	Status     string
+	Overdue    bool
+}
+
+// CreateEvidenceRequestInput asks for evidence. DueDate is YYYY-MM-DD; an
+// empty Recurrence makes a one-off request.
+type CreateEvidenceRequestInput struct {
+	ControlID   string     `json:"control_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	AssigneeID  *string    `json:"assignee_id"`
+	DueDate     string     `json:"due_date"`
+	Recurrence  Recurrence `json:"recurrence"`
+}
+
+// UpdateEvidenceRequestInput changes the fields that are set
+type UpdateEvidenceRequestInput struct {
+	Title       *string     `json:"title"`
+	Description *string     `json:"description"`
+	AssigneeID  *string     `json:"assignee_id"`
+	DueDate     *string     `json:"due_date"`
+	Recurrence  *Recurrence `json:"recurrence"`
+}
+
+// FulfilEvidenceRequestInput provides the evidence for a request's current
+// due date. At least one of URL and Note is needed.
+type FulfilEvidenceRequestInput struct {
+	URL  string `json:"url"`
+	Note string `json:"note"`
+}
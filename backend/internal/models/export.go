@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// RiskExportRow is one risk as written to a register export, with the related
+// records auditors need inlined
+type RiskExportRow struct {
+	ID          string                 `json:"id"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	OwnerID     string                 `json:"owner_id"`
+	OwnerName   string                 `json:"owner_name"`
+	OwnerEmail  string                 `json:"owner_email"`
+	Status      RiskStatus             `json:"status"`
+	Severity    RiskSeverity           `json:"severity"`
+	Category    string                 `json:"category"`
+	ReviewDate  *time.Time             `json:"review_date"`
+	Tags        []string               `json:"tags"`
+	Mitigations []RiskExportMitigation `json:"mitigations"`
+	Controls    []RiskExportControl    `json:"controls"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+type RiskExportMitigation struct {
+	Description string           `json:"description"`
+	Owner       string           `json:"owner"`
+	Status      MitigationStatus `json:"status"`
+	DueDate     *string          `json:"due_date"`
+}
+
+type RiskExportControl struct {
+	Framework  string `json:"framework"`
+	ControlRef string `json:"control_ref"`
+	Title      string `json:"title"`
+}
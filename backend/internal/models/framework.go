@@ -2,20 +2,31 @@ package models
 
 import "time"
 
+// Framework is a standard whose controls risks are mapped to. Version is
+// the edition it holds, such as 2022, and PreviousVersionID points at the
+// framework for the edition it supersedes.
 type Framework struct {
-	ID          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description,omitempty" db:"description"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID                string    `json:"id" db:"id"`
+	Name              string    `json:"name" db:"name"`
+	Description       string    `json:"description,omitempty" db:"description"`
+	Version           string    `json:"version" db:"version"`
+	PreviousVersionID *string   `json:"previous_version_id,omitempty" db:"previous_version_id"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type CreateFrameworkInput struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description"`
+	Name              string  `json:"name" validate:"required"`
+	Description       string  `json:"description"`
+	Version           string  `json:"version"`
+	PreviousVersionID *string `json:"previous_version_id"`
 }
 
+// UpdateFrameworkInput changes the fields that are set. An empty
+// PreviousVersionID clears it.
 type UpdateFrameworkInput struct {
-	Name        *string `json:"name"`
-	Description *string `json:"description"`
+	Name              *string `json:"name"`
+	Description       *string `json:"description"`
+	Version           *string `json:"version"`
+	PreviousVersionID *string `json:"previous_version_id"`
 }
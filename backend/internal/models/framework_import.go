@@ -0,0 +1,41 @@
+package models
+
+// ControlImportItem is a control the import adds, changes or removes
+type ControlImportItem struct {
+	ControlRef string `json:"control_ref"`
+	Title      string `json:"title"`
+	ParentRef  string `json:"parent_ref,omitempty"`
+	ControlID  string `json:"control_id,omitempty"`
+	// Fields lists what changed: title, description, parent or position
+	Fields []string `json:"fields,omitempty"`
+	// InUse marks a removed control that was kept because something still
+	// links to it
+	InUse bool `json:"in_use,omitempty"`
+}
+
+// ControlImportError is a problem with one control in the file. Row is set
+// for spreadsheets, counting the header as row 1.
+type ControlImportError struct {
+	Row        int    `json:"row,omitempty"`
+	ControlRef string `json:"control_ref,omitempty"`
+	Error      string `json:"error"`
+}
+
+// ControlImportResult is the difference between a framework's controls and
+// an imported catalog, and what was done about it when committed
+type ControlImportResult struct {
+	BatchID        string                `json:"batch_id,omitempty"`
+	FrameworkID    string                `json:"framework_id"`
+	Filename       string                `json:"filename"`
+	Format         string                `json:"format"`
+	CatalogTitle   string                `json:"catalog_title,omitempty"`
+	CatalogVersion string                `json:"catalog_version,omitempty"`
+	Committed      bool                  `json:"committed"`
+	Prune          bool                  `json:"prune"`
+	Total          int                   `json:"total"`
+	Added          []*ControlImportItem  `json:"added"`
+	Changed        []*ControlImportItem  `json:"changed"`
+	Removed        []*ControlImportItem  `json:"removed"`
+	Unchanged      int                   `json:"unchanged"`
+	Errors         []*ControlImportError `json:"errors"`
+}
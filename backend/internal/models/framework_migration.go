@@ -0,0 +1,66 @@
+package models
+
+// FrameworkVersionSummary identifies a framework and the version it holds
+type FrameworkVersionSummary struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// FrameworkMigrationRow is one row of a migration mapping file: a control of
+// the old framework and the control of the new one that replaces it. A
+// blank NewRef retires the old control without a successor.
+type FrameworkMigrationRow struct {
+	Row          int               `json:"row"`
+	OldRef       string            `json:"old_ref"`
+	NewRef       string            `json:"new_ref"`
+	OldControlID string            `json:"old_control_id,omitempty"`
+	NewControlID string            `json:"new_control_id,omitempty"`
+	Errors       map[string]string `json:"errors,omitempty"`
+}
+
+// FrameworkMigrationLink is a risk's link to a control of the old framework
+// and the controls of the new framework it moves to. NewRefs is empty for
+// links that stay where they are because their control isn't mapped.
+type FrameworkMigrationLink struct {
+	LinkID       string   `json:"link_id"`
+	RiskID       string   `json:"risk_id"`
+	RiskTitle    string   `json:"risk_title"`
+	OldControlID string   `json:"old_control_id"`
+	OldRef       string   `json:"old_ref"`
+	NewRefs      []string `json:"new_refs"`
+}
+
+// FrameworkMigrationControl is a control the report draws attention to.
+// For old controls Retired means the mapping file says it has no
+// successor, as opposed to leaving it out.
+type FrameworkMigrationControl struct {
+	ControlID       string `json:"control_id"`
+	ControlRef      string `json:"control_ref"`
+	Title           string `json:"title"`
+	LinkedRiskCount int    `json:"linked_risk_count"`
+	Retired         bool   `json:"retired,omitempty"`
+}
+
+// FrameworkMigrationResult reports what migrating risk links from one
+// framework version to another does, or did once Committed. Unmapped lists
+// the old controls with no successor, whose links are left in place, and
+// NewControls the controls of the new framework that nothing maps to.
+type FrameworkMigrationResult struct {
+	BatchID       string                       `json:"batch_id,omitempty"`
+	Filename      string                       `json:"filename"`
+	Format        string                       `json:"format"`
+	Committed     bool                         `json:"committed"`
+	FromFramework FrameworkVersionSummary      `json:"from_framework"`
+	ToFramework   FrameworkVersionSummary      `json:"to_framework"`
+	TotalRows     int                          `json:"total_rows"`
+	ValidRows     int                          `json:"valid_rows"`
+	InvalidRows   int                          `json:"invalid_rows"`
+	LinksMoved    int                          `json:"links_moved"`
+	LinksCreated  int                          `json:"links_created"`
+	LinksUnmapped int                          `json:"links_unmapped"`
+	Rows          []*FrameworkMigrationRow     `json:"rows"`
+	Links         []*FrameworkMigrationLink    `json:"links"`
+	Unmapped      []*FrameworkMigrationControl `json:"unmapped"`
+	NewControls   []*FrameworkMigrationControl `json:"new_controls"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// FieldChange represents the before and after value of a single field
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// HistorySnapshot is an entity reconstructed from its audit trail at a point in time
+type HistorySnapshot struct {
+	EntityType     string         `json:"entity_type"`
+	EntityID       string         `json:"entity_id"`
+	AsOf           time.Time      `json:"as_of"`
+	Deleted        bool           `json:"deleted"`
+	State          map[string]any `json:"state"`
+	LastModifiedAt time.Time      `json:"last_modified_at"`
+	LastModifiedBy string         `json:"last_modified_by,omitempty"`
+}
+
+// HistoryDiff lists the fields that changed between two points in time
+type HistoryDiff struct {
+	EntityType string                 `json:"entity_type"`
+	EntityID   string                 `json:"entity_id"`
+	From       time.Time              `json:"from"`
+	To         time.Time              `json:"to"`
+	Changes    map[string]FieldChange `json:"changes"`
+}
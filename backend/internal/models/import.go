@@ -0,0 +1,46 @@
+package models
+
+// RiskImportField is a risk attribute that a spreadsheet column can be mapped to
+type RiskImportField string
+
+const (
+	ImportFieldTitle       RiskImportField = "title"
+	ImportFieldDescription RiskImportField = "description"
+	ImportFieldOwnerEmail  RiskImportField = "owner_email"
+	ImportFieldStatus      RiskImportField = "status"
+	ImportFieldSeverity    RiskImportField = "severity"
+	ImportFieldCategory    RiskImportField = "category"
+	ImportFieldReviewDate  RiskImportField = "review_date"
+	ImportFieldTags        RiskImportField = "tags"
+)
+
+// RiskImportRow is one spreadsheet row resolved into the risk it would create
+type RiskImportRow struct {
+	Row         int               `json:"row"`
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	OwnerEmail  string            `json:"owner_email"`
+	OwnerID     string            `json:"owner_id,omitempty"`
+	Status      RiskStatus        `json:"status"`
+	Severity    RiskSeverity      `json:"severity"`
+	Category    string            `json:"category,omitempty"`
+	CategoryID  *string           `json:"category_id,omitempty"`
+	ReviewDate  *string           `json:"review_date,omitempty"`
+	Tags        []string          `json:"tags"`
+	RiskID      string            `json:"risk_id,omitempty"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+type RiskImportResult struct {
+	BatchID         string                     `json:"batch_id,omitempty"`
+	Filename        string                     `json:"filename"`
+	Format          string                     `json:"format"`
+	Committed       bool                       `json:"committed"`
+	Columns         []string                   `json:"columns"`
+	Mapping         map[RiskImportField]string `json:"mapping"`
+	UnmappedColumns []string                   `json:"unmapped_columns"`
+	TotalRows       int                        `json:"total_rows"`
+	ValidRows       int                        `json:"valid_rows"`
+	InvalidRows     int                        `json:"invalid_rows"`
+	Rows            []*RiskImportRow           `json:"rows"`
+}
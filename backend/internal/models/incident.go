@@ -62,6 +62,9 @@ type Incident struct {
 	UpdatedAt       time.Time         `json:"updated_at" db:"updated_at"`
 	CreatedBy       string            `json:"created_by" db:"created_by"`
 	UpdatedBy       string            `json:"updated_by" db:"updated_by"`
+	Version         int               `json:"version" db:"version"`
+	Tags            []string          `json:"tags" db:"tags"`
+	DeletedAt       *time.Time        `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 type CreateIncidentInput struct {
@@ -74,6 +77,7 @@ type CreateIncidentInput struct {
 	ServiceAffected string            `json:"service_affected"`
 	OccurredAt      *string           `json:"occurred_at"`
 	DetectedAt      *string           `json:"detected_at"`
+	Tags            []string          `json:"tags"`
 }
 
 type UpdateIncidentInput struct {
@@ -87,6 +91,7 @@ type UpdateIncidentInput struct {
 	RootCause       *string           `json:"root_cause"`
 	ResolutionNotes *string           `json:"resolution_notes"`
 	ResolvedAt      *string           `json:"resolved_at"`
+	Tags            *[]string         `json:"tags"`
 }
 
 type IncidentListParams struct {
@@ -94,6 +99,7 @@ type IncidentListParams struct {
 	Priority   *IncidentPriority
 	CategoryID *string
 	AssigneeID *string
+	Tag        string
 	Search     string
 	Sort       string
 	Order      string
@@ -22,6 +22,7 @@ type Mitigation struct {
 	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
 	CreatedBy   string           `json:"created_by" db:"created_by"`
 	UpdatedBy   string           `json:"updated_by" db:"updated_by"`
+	Version     int              `json:"version" db:"version"`
 }
 
 type CreateMitigationInput struct {
@@ -37,4 +38,7 @@ type UpdateMitigationInput struct {
 	Owner       *string           `json:"owner" validate:"omitempty,min=1,max=255"`
 	Status      *MitigationStatus `json:"status"`
 	DueDate     *string           `json:"due_date"`
+	// Version, when set, must match the stored version for the update to apply.
+	// It comes from the If-Match header rather than the request body.
+	Version *int `json:"-"`
 }
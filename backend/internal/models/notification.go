@@ -0,0 +1,121 @@
+package models
+
+import "time"
+
+type NotificationEvent string
+
+const (
+	// NotificationRiskAssigned tells a user they were made a risk's owner
+	NotificationRiskAssigned NotificationEvent = "risk.assigned"
+	// NotificationRiskUpdated tells an owner someone else changed their risk
+	NotificationRiskUpdated NotificationEvent = "risk.updated"
+	// NotificationRiskReviewDue tells an owner their risk's review date has passed
+	NotificationRiskReviewDue NotificationEvent = "risk.review_due"
+	// NotificationMitigationUpdated tells a risk's owner someone else added
+	// or changed one of its mitigations
+	NotificationMitigationUpdated NotificationEvent = "mitigation.updated"
+	// NotificationMitigationDue tells the owners of an open mitigation that
+	// its due date is close or has passed
+	NotificationMitigationDue NotificationEvent = "mitigation.due"
+	// NotificationIncidentAssigned tells a user they were assigned an incident
+	NotificationIncidentAssigned NotificationEvent = "incident.assigned"
+	// NotificationIncidentUpdated tells an incident's reporter and assignee
+	// that someone else changed its status or priority
+	NotificationIncidentUpdated NotificationEvent = "incident.updated"
+	// NotificationEvidenceDue tells the assignee of an open evidence request
+	// that its due date is close or has passed
+	NotificationEvidenceDue NotificationEvent = "evidence.due"
+)
+
+// NotificationEvents lists every event a user can set preferences for
+var NotificationEvents = []NotificationEvent{
+	NotificationRiskAssigned, NotificationRiskUpdated, NotificationRiskReviewDue,
+	NotificationMitigationUpdated, NotificationMitigationDue,
+	NotificationIncidentAssigned, NotificationIncidentUpdated,
+	NotificationEvidenceDue,
+}
+
+type NotificationChannel string
+
+const (
+	NotificationChannelInApp NotificationChannel = "in_app"
+	NotificationChannelEmail NotificationChannel = "email"
+)
+
+var NotificationChannels = []NotificationChannel{NotificationChannelInApp, NotificationChannelEmail}
+
+// DefaultNotificationChannels are the channels used for an event until the
+// user chooses otherwise: assignments and deadlines are also emailed, other
+// changes only appear in the inbox
+func DefaultNotificationChannels(event NotificationEvent) []NotificationChannel {
+	switch event {
+	case NotificationRiskAssigned, NotificationRiskReviewDue, NotificationMitigationDue, NotificationIncidentAssigned,
+		NotificationEvidenceDue:
+		return []NotificationChannel{NotificationChannelInApp, NotificationChannelEmail}
+	}
+	return []NotificationChannel{NotificationChannelInApp}
+}
+
+// NotificationPreferences maps each event to the channels it is sent on. An
+// empty list turns the event off.
+type NotificationPreferences map[NotificationEvent][]NotificationChannel
+
+type NotificationEmailStatus string
+
+const (
+	NotificationEmailPending NotificationEmailStatus = "pending"
+	NotificationEmailSent    NotificationEmailStatus = "sent"
+	NotificationEmailFailed  NotificationEmailStatus = "failed"
+)
+
+// Notification is an entry in a user's inbox. Link is the web app path of
+// the thing it is about.
+type Notification struct {
+	ID         string            `json:"id"`
+	UserID     string            `json:"user_id"`
+	Event      NotificationEvent `json:"event"`
+	EntityType string            `json:"entity_type"`
+	EntityID   string            `json:"entity_id"`
+	Title      string            `json:"title"`
+	Body       string            `json:"body"`
+	Link       string            `json:"link"`
+	ActorID    *string           `json:"actor_id,omitempty"`
+	ReadAt     *time.Time        `json:"read_at,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	// InApp and Email record the channels chosen when it was created
+	InApp bool `json:"-"`
+	Email bool `json:"-"`
+}
+
+type NotificationListParams struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+type NotificationListResponse struct {
+	Notifications []*Notification `json:"notifications"`
+	Total         int             `json:"total"`
+	Unread        int             `json:"unread"`
+	Limit         int             `json:"limit"`
+	Offset        int             `json:"offset"`
+}
+
+// NotificationDeadline is a review or due date that has come round, with the
+// users to tell about it. RiskID is empty for evidence requests.
+type NotificationDeadline struct {
+	Event      NotificationEvent
+	EntityType string
+	EntityID   string
+	RiskID     string
+	Title      string
+	Due        time.Time
+	UserIDs    []string
+}
+
+// NotificationEmail is a queued email claimed for sending
+type NotificationEmail struct {
+	Notification *Notification
+	To           string
+	Attempts     int
+}
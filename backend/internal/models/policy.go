@@ -0,0 +1,140 @@
+package models
+
+import "time"
+
+type PolicyVersionStatus string
+
+const (
+	PolicyDraft    PolicyVersionStatus = "draft"
+	PolicyPending  PolicyVersionStatus = "pending"
+	PolicyApproved PolicyVersionStatus = "approved"
+	PolicyRejected PolicyVersionStatus = "rejected"
+)
+
+// Policy is an entry in the policy register. The version in force is the
+// latest approved one; CurrentVersion is nil until a version is approved.
+// The policy is due for review one ReviewCycle after that approval, and
+// ReviewOverdue is set once NextReviewDate has passed. Controls is only
+// filled in for a single policy.
+type Policy struct {
+	ID                   string           `json:"id"`
+	Title                string           `json:"title"`
+	Description          string           `json:"description"`
+	OwnerID              *string          `json:"owner_id,omitempty"`
+	OwnerName            string           `json:"owner_name,omitempty"`
+	ReviewCycle          Recurrence       `json:"review_cycle"`
+	NextReviewDate       *time.Time       `json:"next_review_date,omitempty"`
+	ReviewOverdue        bool             `json:"review_overdue"`
+	CurrentVersion       *PolicyVersion   `json:"current_version,omitempty"`
+	AcknowledgementCount int              `json:"acknowledgement_count"`
+	ControlCount         int              `json:"control_count"`
+	Controls             []*PolicyControl `json:"controls,omitempty"`
+	CreatedBy            *string          `json:"created_by,omitempty"`
+	CreatedAt            time.Time        `json:"created_at"`
+	UpdatedAt            time.Time        `json:"updated_at"`
+}
+
+// PolicyVersion is one numbered revision of a policy's document: a markdown
+// Body, an attachment of the policy, or both. Drafts are edited until they
+// are submitted for approval. Body is left out of version lists and of the
+// current version embedded in a policy.
+type PolicyVersion struct {
+	ID              string              `json:"id"`
+	PolicyID        string              `json:"policy_id"`
+	Version         int                 `json:"version"`
+	Body            string              `json:"body,omitempty"`
+	AttachmentID    *string             `json:"attachment_id,omitempty"`
+	AttachmentName  string              `json:"attachment_name,omitempty"`
+	ChangeSummary   string              `json:"change_summary"`
+	Status          PolicyVersionStatus `json:"status"`
+	CreatedBy       *string             `json:"created_by,omitempty"`
+	CreatedByName   string              `json:"created_by_name,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+	SubmittedAt     *time.Time          `json:"submitted_at,omitempty"`
+	DecidedBy       *string             `json:"decided_by,omitempty"`
+	DecidedByName   string              `json:"decided_by_name,omitempty"`
+	DecidedAt       *time.Time          `json:"decided_at,omitempty"`
+	DecisionComment string              `json:"decision_comment,omitempty"`
+}
+
+// PolicyControl is a framework control a policy implements
+type PolicyControl struct {
+	ControlID     string    `json:"control_id"`
+	ControlRef    string    `json:"control_ref"`
+	Title         string    `json:"title"`
+	FrameworkID   string    `json:"framework_id"`
+	FrameworkName string    `json:"framework_name"`
+	LinkedAt      time.Time `json:"linked_at"`
+}
+
+// PolicyAcknowledgement is one user's acceptance of a policy version.
+// AcknowledgedAt is nil for users who haven't accepted it yet.
+type PolicyAcknowledgement struct {
+	UserID         string     `json:"user_id"`
+	Name           string     `json:"name"`
+	Email          string     `json:"email"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+}
+
+// PolicyAcknowledgements lists who has and hasn't accepted the version of a
+// policy that is in force
+type PolicyAcknowledgements struct {
+	PolicyID     string                   `json:"policy_id"`
+	VersionID    string                   `json:"version_id"`
+	Version      int                      `json:"version"`
+	Acknowledged []*PolicyAcknowledgement `json:"acknowledged"`
+	Outstanding  []*PolicyAcknowledgement `json:"outstanding"`
+}
+
+// PolicyFilter narrows a list of policies. ReviewOverdue only keeps policies
+// past their next review date.
+type PolicyFilter struct {
+	OwnerID       string
+	ControlID     string
+	ReviewOverdue bool
+}
+
+// CreatePolicyInput registers a policy. ReviewCycle defaults to annual.
+type CreatePolicyInput struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	OwnerID     *string    `json:"owner_id"`
+	ReviewCycle Recurrence `json:"review_cycle"`
+}
+
+// UpdatePolicyInput changes the fields that are set. NextReviewDate is
+// YYYY-MM-DD and moves the review that approving a version scheduled.
+type UpdatePolicyInput struct {
+	Title          *string     `json:"title"`
+	Description    *string     `json:"description"`
+	OwnerID        *string     `json:"owner_id"`
+	ReviewCycle    *Recurrence `json:"review_cycle"`
+	NextReviewDate *string     `json:"next_review_date"`
+}
+
+// CreatePolicyVersionInput drafts a new version. At least one of Body and
+// AttachmentID is needed.
+type CreatePolicyVersionInput struct {
+	Body          string  `json:"body"`
+	AttachmentID  *string `json:"attachment_id"`
+	ChangeSummary string  `json:"change_summary"`
+}
+
+// UpdatePolicyVersionInput changes the fields of a draft that are set. An
+// empty AttachmentID removes the attachment.
+type UpdatePolicyVersionInput struct {
+	Body          *string `json:"body"`
+	AttachmentID  *string `json:"attachment_id"`
+	ChangeSummary *string `json:"change_summary"`
+}
+
+// PolicyDecisionInput approves or rejects a pending version. Rejections
+// need a comment.
+type PolicyDecisionInput struct {
+	Comment string `json:"comment"`
+}
+
+type LinkPolicyControlInput struct {
+	ControlID string `json:"control_id"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Recurrence is how often a scheduled task comes round again
+type Recurrence string
+
+const (
+	RecurrenceMonthly    Recurrence = "monthly"
+	RecurrenceQuarterly  Recurrence = "quarterly"
+	RecurrenceSemiannual Recurrence = "semiannual"
+	RecurrenceAnnual     Recurrence = "annual"
+)
+
+// Recurrences lists the valid recurrences, shortest first
+var Recurrences = []Recurrence{RecurrenceMonthly, RecurrenceQuarterly, RecurrenceSemiannual, RecurrenceAnnual}
+
+var recurrenceMonths = map[Recurrence]int{
+	RecurrenceMonthly:    1,
+	RecurrenceQuarterly:  3,
+	RecurrenceSemiannual: 6,
+	RecurrenceAnnual:     12,
+}
+
+// Next returns the date one period after from. Days past the end of a
+// shorter month are clamped, so 31 January monthly is due 28 or 29 February.
+func (r Recurrence) Next(from time.Time) time.Time {
+	months := recurrenceMonths[r]
+	first := time.Date(from.Year(), from.Month()+time.Month(months), 1, 0, 0, 0, 0, from.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := from.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, from.Hour(), from.Minute(), from.Second(), from.Nanosecond(), from.Location())
+}
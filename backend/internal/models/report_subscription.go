@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+type ReportFrequency string
+
+const (
+	ReportFrequencyWeekly  ReportFrequency = "weekly"
+	ReportFrequencyMonthly ReportFrequency = "monthly"
+)
+
+type ReportFormat string
+
+const (
+	ReportFormatPDF  ReportFormat = "pdf"
+	ReportFormatCSV  ReportFormat = "csv"
+	ReportFormatXLSX ReportFormat = "xlsx"
+)
+
+type ReportDeliveryStatus string
+
+const (
+	ReportDeliverySent   ReportDeliveryStatus = "sent"
+	ReportDeliveryFailed ReportDeliveryStatus = "failed"
+)
+
+// ReportFilters narrows what a subscription's report contains. The risk
+// filters apply to csv and xlsx register exports; sections, granularity and
+// top_risks shape the pdf report.
+type ReportFilters struct {
+	Status      *RiskStatus          `json:"status,omitempty"`
+	Severity    *RiskSeverity        `json:"severity,omitempty"`
+	CategoryID  *string              `json:"category_id,omitempty"`
+	Tag         string               `json:"tag,omitempty"`
+	Sections    []string             `json:"sections,omitempty"`
+	Granularity AnalyticsGranularity `json:"granularity,omitempty"`
+	TopRisks    int                  `json:"top_risks,omitempty"`
+}
+
+// ReportSubscription emails a report to its user every week or month
+type ReportSubscription struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Name      string          `json:"name"`
+	Frequency ReportFrequency `json:"frequency"`
+	Format    ReportFormat    `json:"format"`
+	Filters   ReportFilters   `json:"filters"`
+	Active    bool            `json:"active"`
+	NextRunAt time.Time       `json:"next_run_at"`
+	LastRunAt *time.Time      `json:"last_run_at,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+type CreateReportSubscriptionInput struct {
+	Name      string          `json:"name"`
+	Frequency ReportFrequency `json:"frequency"`
+	Format    ReportFormat    `json:"format"`
+	Filters   ReportFilters   `json:"filters"`
+	Active    *bool           `json:"active"`
+}
+
+type UpdateReportSubscriptionInput struct {
+	Name      *string          `json:"name"`
+	Frequency *ReportFrequency `json:"frequency"`
+	Format    *ReportFormat    `json:"format"`
+	Filters   *ReportFilters   `json:"filters"`
+	Active    *bool            `json:"active"`
+}
+
+// ReportDelivery records one attempt to email a subscription's report.
+// Manual deliveries were requested through the API rather than the schedule.
+type ReportDelivery struct {
+	ID             string               `json:"id"`
+	SubscriptionID string               `json:"subscription_id"`
+	Recipient      string               `json:"recipient"`
+	Status         ReportDeliveryStatus `json:"status"`
+	Manual         bool                 `json:"manual"`
+	Filename       string               `json:"filename"`
+	SizeBytes      int                  `json:"size_bytes"`
+	Error          *string              `json:"error,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+}
+
+type ReportDeliveryListResponse struct {
+	Deliveries []*ReportDelivery `json:"deliveries"`
+}
@@ -35,6 +35,9 @@ type Risk struct {
 	UpdatedAt   time.Time    `json:"updated_at" db:"updated_at"`
 	CreatedBy   string       `json:"created_by" db:"created_by"`
 	UpdatedBy   string       `json:"updated_by" db:"updated_by"`
+	Version     int          `json:"version" db:"version"`
+	Tags        []string     `json:"tags" db:"tags"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
 type CreateRiskInput struct {
@@ -45,6 +48,7 @@ type CreateRiskInput struct {
 	Severity    RiskSeverity `json:"severity"`
 	CategoryID  *string      `json:"category_id"`
 	ReviewDate  *string      `json:"review_date"`
+	Tags        []string     `json:"tags"`
 }
 
 type UpdateRiskInput struct {
@@ -55,6 +59,7 @@ type UpdateRiskInput struct {
 	Severity    *RiskSeverity `json:"severity"`
 	CategoryID  *string       `json:"category_id"`
 	ReviewDate  *string       `json:"review_date"`
+	Tags        *[]string     `json:"tags"`
 }
 
 type RiskListParams struct {
@@ -62,6 +67,7 @@ type RiskListParams struct {
 	Severity   *RiskSeverity
 	CategoryID *string
 	OwnerID    *string
+	Tag        string
 	Search     string
 	Sort       string
 	Order      string
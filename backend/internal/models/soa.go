@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+// Applicability says whether a control is in scope of the ISMS
+type Applicability string
+
+const (
+	ApplicabilityApplicable Applicability = "applicable"
+	ApplicabilityExcluded   Applicability = "excluded"
+)
+
+type ImplementationStatus string
+
+const (
+	ImplementationNotImplemented ImplementationStatus = "not_implemented"
+	ImplementationPlanned        ImplementationStatus = "planned"
+	ImplementationPartial        ImplementationStatus = "partial"
+	ImplementationImplemented    ImplementationStatus = "implemented"
+)
+
+// SoAEntry is one control's line in a Statement of Applicability. Controls
+// that haven't been assessed have Assessed false and no applicability.
+type SoAEntry struct {
+	ControlID            string               `json:"control_id"`
+	ControlRef           string               `json:"control_ref"`
+	Title                string               `json:"title"`
+	Family               string               `json:"family"`
+	Assessed             bool                 `json:"assessed"`
+	Applicability        Applicability        `json:"applicability,omitempty"`
+	Justification        string               `json:"justification"`
+	ImplementationStatus ImplementationStatus `json:"implementation_status,omitempty"`
+	OwnerID              *string              `json:"owner_id,omitempty"`
+	OwnerName            string               `json:"owner_name,omitempty"`
+	LinkedRiskCount      int                  `json:"linked_risk_count"`
+	UpdatedAt            *time.Time           `json:"updated_at,omitempty"`
+}
+
+// SoASummary counts the entries of an SoA. Implementation only counts
+// applicable controls.
+type SoASummary struct {
+	Total          int                          `json:"total"`
+	Unassessed     int                          `json:"unassessed"`
+	Applicable     int                          `json:"applicable"`
+	Excluded       int                          `json:"excluded"`
+	Implementation map[ImplementationStatus]int `json:"implementation"`
+}
+
+// StatementOfApplicability is the working SoA of a framework, built from the
+// current applicability records
+type StatementOfApplicability struct {
+	FrameworkID   string      `json:"framework_id"`
+	FrameworkName string      `json:"framework_name"`
+	Summary       SoASummary  `json:"summary"`
+	Entries       []*SoAEntry `json:"entries"`
+}
+
+// SetApplicabilityInput replaces a control's applicability record. Excluded
+// controls need a justification.
+type SetApplicabilityInput struct {
+	Applicability        Applicability        `json:"applicability"`
+	Justification        string               `json:"justification"`
+	ImplementationStatus ImplementationStatus `json:"implementation_status"`
+	OwnerID              *string              `json:"owner_id"`
+}
+
+type SoAVersionStatus string
+
+const (
+	// SoADraft describes the working SoA when it is exported; it is never
+	// stored as a version
+	SoADraft    SoAVersionStatus = "draft"
+	SoAPending  SoAVersionStatus = "pending"
+	SoAApproved SoAVersionStatus = "approved"
+	SoARejected SoAVersionStatus = "rejected"
+)
+
+// SoAVersion is a numbered, frozen copy of a framework's SoA and its
+// sign-off. Entries is left out of version lists.
+type SoAVersion struct {
+	ID              string           `json:"id"`
+	FrameworkID     string           `json:"framework_id"`
+	FrameworkName   string           `json:"framework_name"`
+	Version         int              `json:"version"`
+	Title           string           `json:"title"`
+	Notes           string           `json:"notes"`
+	Status          SoAVersionStatus `json:"status"`
+	Summary         SoASummary       `json:"summary"`
+	Entries         []*SoAEntry      `json:"entries,omitempty"`
+	CreatedBy       *string          `json:"created_by,omitempty"`
+	CreatedByName   string           `json:"created_by_name,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+	DecidedBy       *string          `json:"decided_by,omitempty"`
+	DecidedByName   string           `json:"decided_by_name,omitempty"`
+	DecidedAt       *time.Time       `json:"decided_at,omitempty"`
+	DecisionComment string           `json:"decision_comment,omitempty"`
+}
+
+type CreateSoAVersionInput struct {
+	Title string `json:"title"`
+	Notes string `json:"notes"`
+}
+
+// SoADecisionInput approves or rejects a pending version. Rejections need a
+// comment.
+type SoADecisionInput struct {
+	Comment string `json:"comment"`
+}
+
+// SoAEntryChange lists the fields of one control that differ between two
+// SoAs
+type SoAEntryChange struct {
+	ControlRef string                 `json:"control_ref"`
+	Title      string                 `json:"title"`
+	Changes    map[string]FieldChange `json:"changes"`
+}
+
+// SoAComparison is the difference between two SoA versions of a framework,
+// matched by control_ref. A nil ToVersion compares against the working SoA.
+type SoAComparison struct {
+	FrameworkID string            `json:"framework_id"`
+	FromVersion int               `json:"from_version"`
+	ToVersion   *int              `json:"to_version"`
+	Added       []*SoAEntry       `json:"added"`
+	Removed     []*SoAEntry       `json:"removed"`
+	Changed     []*SoAEntryChange `json:"changed"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// StreamEventReset tells a client it may have missed events and should
+// reload what it is showing
+const StreamEventReset = "stream.reset"
+
+// StreamEvent is a change pushed to clients over the event stream. It only
+// names the entity and the fields that changed; clients fetch the entity
+// again to see the new state.
+type StreamEvent struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	EntityType string      `json:"entity_type,omitempty"`
+	EntityID   string      `json:"entity_id,omitempty"`
+	Action     AuditAction `json:"action,omitempty"`
+	ActorID    string      `json:"actor_id,omitempty"`
+	Fields     []string    `json:"fields,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Team is a group of users. A user's team is everyone who shares at least
+// one team with them, which is what the "team" calendar feed covers.
+type Team struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Members   []*TeamMember `json:"members"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type TeamMember struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+type CreateTeamInput struct {
+	Name      string   `json:"name"`
+	MemberIDs []string `json:"member_ids"`
+}
+
+// UpdateTeamInput renames a team and/or replaces its members
+type UpdateTeamInput struct {
+	Name      *string   `json:"name"`
+	MemberIDs *[]string `json:"member_ids"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import "time"
+
+// Webhook event types. Each is "<entity>.<audit action>", plus risk.critical,
+// which fires when a risk is created as or raised to critical severity.
+const (
+	WebhookEventRiskCreated       = "risk.created"
+	WebhookEventRiskUpdated       = "risk.updated"
+	WebhookEventRiskDeleted       = "risk.deleted"
+	WebhookEventRiskRestored      = "risk.restored"
+	WebhookEventRiskPurged        = "risk.purged"
+	WebhookEventRiskCritical      = "risk.critical"
+	WebhookEventMitigationCreated = "mitigation.created"
+	WebhookEventMitigationUpdated = "mitigation.updated"
+	WebhookEventMitigationDeleted = "mitigation.deleted"
+	WebhookEventIncidentCreated   = "incident.created"
+	WebhookEventIncidentUpdated   = "incident.updated"
+	WebhookEventIncidentDeleted   = "incident.deleted"
+	WebhookEventIncidentRestored  = "incident.restored"
+	WebhookEventIncidentPurged    = "incident.purged"
+	// WebhookEventAll subscribes a webhook to every event type
+	WebhookEventAll = "*"
+)
+
+// WebhookEventTypes lists every event a webhook can subscribe to
+var WebhookEventTypes = []string{
+	WebhookEventRiskCreated, WebhookEventRiskUpdated, WebhookEventRiskDeleted, WebhookEventRiskRestored,
+	WebhookEventRiskPurged, WebhookEventRiskCritical,
+	WebhookEventMitigationCreated, WebhookEventMitigationUpdated, WebhookEventMitigationDeleted,
+	WebhookEventIncidentCreated, WebhookEventIncidentUpdated, WebhookEventIncidentDeleted,
+	WebhookEventIncidentRestored, WebhookEventIncidentPurged,
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// WebhookDeliveryDead means every retry failed; the delivery is kept for
+	// the dead-letter view until it is redelivered by hand
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// Webhook posts signed event payloads to an external URL. The secret is
+// only returned when the webhook is created or its secret is rotated.
+type Webhook struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type CreateWebhookInput struct {
+	Name       string   `json:"name"`
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"event_types"`
+	Active     *bool    `json:"active"`
+}
+
+type UpdateWebhookInput struct {
+	Name       *string   `json:"name"`
+	URL        *string   `json:"url"`
+	EventTypes *[]string `json:"event_types"`
+	Active     *bool     `json:"active"`
+	// RotateSecret replaces the secret with a new generated one
+	RotateSecret bool `json:"rotate_secret"`
+}
+
+// WebhookEvent is the JSON body posted to webhooks. Data holds the audit
+// entry's changes: a full snapshot for created events and from/to pairs
+// for updates.
+type WebhookEvent struct {
+	ID         string         `json:"id"`
+	Type       string         `json:"type"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	EntityType string         `json:"entity_type"`
+	EntityID   string         `json:"entity_id"`
+	ActorID    string         `json:"actor_id,omitempty"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// WebhookDelivery is one event queued for one webhook, with the outcome of
+// its most recent attempt
+type WebhookDelivery struct {
+	ID             string                `json:"id"`
+	WebhookID      string                `json:"webhook_id"`
+	EventID        string                `json:"event_id"`
+	EventType      string                `json:"event_type"`
+	Payload        []byte                `json:"-"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time            `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int                  `json:"response_status,omitempty"`
+	ResponseBody   *string               `json:"response_body,omitempty"`
+	Error          *string               `json:"error,omitempty"`
+	RedeliveryOf   *string               `json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+}
+
+// WebhookDispatch is a claimed delivery together with where to send it
+type WebhookDispatch struct {
+	Delivery *WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// WebhookAttempt is the outcome of posting a delivery once
+type WebhookAttempt struct {
+	Status         WebhookDeliveryStatus
+	AttemptedAt    time.Time
+	NextAttemptAt  *time.Time
+	ResponseStatus *int
+	ResponseBody   *string
+	Error          *string
+}
+
+type WebhookDeliveryListParams struct {
+	WebhookID string
+	Status    *WebhookDeliveryStatus
+	Limit     int
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+}
@@ -0,0 +1,234 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+// auditNotifier records audit entries and notifies the users a change
+// concerns. Like the webhook emitter it writes inside the caller's
+// transaction, so rolled back changes notify nobody.
+type auditNotifier struct {
+	database.AuditLogRepository
+	notifications database.NotificationRepository
+	risks         database.RiskRepository
+	mitigations   database.MitigationRepository
+	incidents     database.IncidentRepository
+	users         database.UserRepository
+}
+
+// NewAuditNotifier wraps audit so that risk, mitigation and incident entries
+// also notify owners, assignees and reporters. Users are never notified of
+// their own changes.
+func NewAuditNotifier(audit database.AuditLogRepository, notifications database.NotificationRepository, risks database.RiskRepository,
+	mitigations database.MitigationRepository, incidents database.IncidentRepository, users database.UserRepository) database.AuditLogRepository {
+	return &auditNotifier{
+		AuditLogRepository: audit,
+		notifications:      notifications,
+		risks:              risks,
+		mitigations:        mitigations,
+		incidents:          incidents,
+		users:              users,
+	}
+}
+
+func (a *auditNotifier) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	if err := a.AuditLogRepository.Create(ctx, entityType, entityID, action, changes, userID); err != nil {
+		return err
+	}
+	if action != models.AuditActionCreated && action != models.AuditActionUpdated {
+		return nil
+	}
+
+	var notes []*models.Notification
+	var err error
+	switch entityType {
+	case "risk":
+		notes, err = a.riskNotifications(ctx, entityID, action, changes, userID)
+	case "mitigation":
+		notes, err = a.mitigationNotifications(ctx, entityID, action, changes, userID)
+	case "incident":
+		notes, err = a.incidentNotifications(ctx, entityID, action, changes, userID)
+	}
+	if err != nil {
+		return err
+	}
+	for _, n := range notes {
+		if n.UserID == "" || n.UserID == userID {
+			continue
+		}
+		if userID != "" {
+			n.ActorID = &userID
+		}
+		if _, err := deliver(ctx, a.notifications, n, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *auditNotifier) riskNotifications(ctx context.Context, riskID string, action models.AuditAction, changes map[string]any, actorID string) ([]*models.Notification, error) {
+	risk, err := a.risks.FindByID(ctx, riskID)
+	if err != nil {
+		return nil, fmt.Errorf("loading risk for notifications: %w", err)
+	}
+	actor := a.displayName(ctx, actorID)
+	note := func(event models.NotificationEvent, userID, title, body string) *models.Notification {
+		return &models.Notification{UserID: userID, Event: event, EntityType: "risk", EntityID: risk.ID,
+			Title: title, Body: body, Link: riskLink(risk.ID)}
+	}
+	assigned := func() *models.Notification {
+		return note(models.NotificationRiskAssigned, risk.OwnerID,
+			fmt.Sprintf("You are now the owner of risk %q", risk.Title),
+			fmt.Sprintf("%s made you the owner of this %s severity risk.", actor, risk.Severity))
+	}
+
+	if action == models.AuditActionCreated {
+		return []*models.Notification{assigned()}, nil
+	}
+	if to, ok := changedTo(changes, "owner_id"); ok && to != "" {
+		// A new owner hears about the assignment rather than the other edits
+		return []*models.Notification{assigned()}, nil
+	}
+	return []*models.Notification{note(models.NotificationRiskUpdated, risk.OwnerID,
+		fmt.Sprintf("Risk %q was updated", risk.Title),
+		fmt.Sprintf("%s changed %s.", actor, describeChanges(changes)))}, nil
+}
+
+func (a *auditNotifier) mitigationNotifications(ctx context.Context, mitigationID string, action models.AuditAction, changes map[string]any, actorID string) ([]*models.Notification, error) {
+	mitigation, err := a.mitigations.FindByID(ctx, mitigationID)
+	if err != nil {
+		return nil, fmt.Errorf("loading mitigation for notifications: %w", err)
+	}
+	risk, err := a.risks.FindByID(ctx, mitigation.RiskID)
+	if errors.Is(err, database.ErrRiskNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading risk for notifications: %w", err)
+	}
+
+	actor := a.displayName(ctx, actorID)
+	n := &models.Notification{UserID: risk.OwnerID, Event: models.NotificationMitigationUpdated, EntityType: "mitigation",
+		EntityID: mitigation.ID, Link: riskLink(risk.ID)}
+	if action == models.AuditActionCreated {
+		n.Title = fmt.Sprintf("New mitigation on risk %q", risk.Title)
+		n.Body = fmt.Sprintf("%s added a mitigation: %s", actor, truncate(mitigation.Description, 200))
+	} else {
+		n.Title = fmt.Sprintf("A mitigation on risk %q was updated", risk.Title)
+		n.Body = fmt.Sprintf("%s changed %s of %q.", actor, describeChanges(changes), truncate(mitigation.Description, 100))
+	}
+	return []*models.Notification{n}, nil
+}
+
+func (a *auditNotifier) incidentNotifications(ctx context.Context, incidentID string, action models.AuditAction, changes map[string]any, actorID string) ([]*models.Notification, error) {
+	incident, err := a.incidents.FindByID(ctx, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("loading incident for notifications: %w", err)
+	}
+	actor := a.displayName(ctx, actorID)
+	priority := strings.ToUpper(string(incident.Priority))
+	note := func(event models.NotificationEvent, userID, title, body string) *models.Notification {
+		return &models.Notification{UserID: userID, Event: event, EntityType: "incident", EntityID: incident.ID,
+			Title: title, Body: body, Link: incidentLink(incident.ID)}
+	}
+
+	var notes []*models.Notification
+	assignee := ""
+	if incident.AssigneeID != nil {
+		assignee = *incident.AssigneeID
+	}
+	newlyAssigned := false
+	if action == models.AuditActionCreated {
+		newlyAssigned = assignee != ""
+	} else if to, ok := changedTo(changes, "assignee_id"); ok && to != "" {
+		newlyAssigned = true
+	}
+	if newlyAssigned {
+		notes = append(notes, note(models.NotificationIncidentAssigned, assignee,
+			fmt.Sprintf("You were assigned incident %q", incident.Title),
+			fmt.Sprintf("%s assigned this %s incident to you.", actor, priority)))
+	}
+
+	if action == models.AuditActionUpdated {
+		var parts []string
+		if from, to, ok := change(changes, "status"); ok {
+			parts = append(parts, fmt.Sprintf("status from %s to %s", humanize(from), humanize(to)))
+		}
+		if from, to, ok := change(changes, "priority"); ok {
+			parts = append(parts, fmt.Sprintf("priority from %s to %s", strings.ToUpper(from), strings.ToUpper(to)))
+		}
+		if len(parts) > 0 {
+			recipients := []string{incident.ReporterID}
+			if assignee != "" && !newlyAssigned && assignee != incident.ReporterID {
+				recipients = append(recipients, assignee)
+			}
+			for _, userID := range recipients {
+				notes = append(notes, note(models.NotificationIncidentUpdated, userID,
+					fmt.Sprintf("Incident %q was updated", incident.Title),
+					fmt.Sprintf("%s changed the %s.", actor, strings.Join(parts, " and the "))))
+			}
+		}
+	}
+	return notes, nil
+}
+
+// displayName is how the user who made a change is named in messages
+func (a *auditNotifier) displayName(ctx context.Context, userID string) string {
+	if userID != "" {
+		if user, err := a.users.FindByID(ctx, userID); err == nil && user != nil {
+			if user.Name != "" {
+				return user.Name
+			}
+			return user.Email
+		}
+	}
+	return "Someone"
+}
+
+// change returns the from and to values of an updated field
+func change(changes map[string]any, field string) (from, to string, ok bool) {
+	c, ok := changes[field].(map[string]any)
+	if !ok {
+		return "", "", false
+	}
+	str := func(v any) string {
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+	return str(c["from"]), str(c["to"]), true
+}
+
+// changedTo returns the new value of an updated field
+func changedTo(changes map[string]any, field string) (string, bool) {
+	_, to, ok := change(changes, field)
+	return to, ok
+}
+
+func humanize(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return strings.ReplaceAll(s, "_", " ")
+}
+
+// WithTx binds the notifier to tx. The mitigation repository has no
+// transactional variant; mitigations are never changed inside one.
+func (a *auditNotifier) WithTx(tx *sql.Tx) database.AuditLogRepository {
+	return &auditNotifier{
+		AuditLogRepository: a.AuditLogRepository.WithTx(tx),
+		notifications:      a.notifications.WithTx(tx),
+		risks:              a.risks.WithTx(tx),
+		mitigations:        a.mitigations,
+		incidents:          a.incidents.WithTx(tx),
+		users:              a.users,
+	}
+}
@@ -0,0 +1,108 @@
+// Package notifications tells users about changes that concern them. Risk,
+// mitigation and incident changes are picked up from audit entries and
+// stored alongside them; review and due dates are raised by a background
+// worker, which also emails the notifications users asked to get by email.
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+// Channels returns the channels the user wants event sent on
+func Channels(prefs models.NotificationPreferences, event models.NotificationEvent) []models.NotificationChannel {
+	if channels, ok := prefs[event]; ok {
+		return channels
+	}
+	return models.DefaultNotificationChannels(event)
+}
+
+// deliver stores n for its user on the channels they chose for its event.
+// Notifications the user has turned off are dropped, except deadlines, which
+// are stored hidden so the same deadline is not raised again.
+func deliver(ctx context.Context, repo database.NotificationRepository, n *models.Notification, dedupeKey string) (bool, error) {
+	prefs, err := repo.Preferences(ctx, n.UserID)
+	if err != nil {
+		return false, fmt.Errorf("loading notification preferences: %w", err)
+	}
+	channels := Channels(prefs, n.Event)
+	n.InApp = slices.Contains(channels, models.NotificationChannelInApp)
+	n.Email = slices.Contains(channels, models.NotificationChannelEmail)
+	if !n.InApp && !n.Email && dedupeKey == "" {
+		return false, nil
+	}
+	created, err := repo.Create(ctx, n, dedupeKey)
+	if err != nil {
+		return false, fmt.Errorf("storing %s notification: %w", n.Event, err)
+	}
+	return created && (n.InApp || n.Email), nil
+}
+
+// riskLink, incidentLink and controlsLink are the web app pages
+// notifications point to. Mitigations are shown on their risk's page and
+// evidence requests on the controls page.
+func riskLink(riskID string) string {
+	return "/app/risks/" + riskID
+}
+
+func incidentLink(incidentID string) string {
+	return "/app/incidents/" + incidentID
+}
+
+func controlsLink() string {
+	return "/app/controls"
+}
+
+// fieldLabels names the audited fields mentioned in "changed ..." messages
+var fieldLabels = map[string]string{
+	"owner_id":         "owner",
+	"category_id":      "category",
+	"review_date":      "review date",
+	"due_date":         "due date",
+	"assignee_id":      "assignee",
+	"service_affected": "service affected",
+	"root_cause":       "root cause",
+	"resolution_notes": "resolution notes",
+	"occurred_at":      "occurrence time",
+	"detected_at":      "detection time",
+	"resolved_at":      "resolution time",
+}
+
+// describeChanges lists the changed fields, e.g. "status and severity"
+func describeChanges(changes map[string]any) string {
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		label, ok := fieldLabels[field]
+		if !ok {
+			label = strings.ReplaceAll(field, "_", " ")
+		}
+		fields = append(fields, label)
+	}
+	slices.Sort(fields)
+	switch len(fields) {
+	case 0:
+		return "it"
+	case 1:
+		return fields[0]
+	}
+	return strings.Join(fields[:len(fields)-1], ", ") + " and " + fields[len(fields)-1]
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format("2 January 2006")
+}
@@ -0,0 +1,388 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/mailer"
+	"backend/internal/models"
+)
+
+type stubRepo struct {
+	database.NotificationRepository
+	prefs         map[string]models.NotificationPreferences
+	notifications []*models.Notification
+	keys          map[string]bool
+	reviews       []*models.NotificationDeadline
+	mitigations   []*models.NotificationDeadline
+	evidence      []*models.NotificationDeadline
+	dueBefore     time.Time
+	evidenceDue   time.Time
+	emails        []*models.NotificationEmail
+	recorded      map[string]models.NotificationEmailStatus
+	nextAttempt   map[string]*time.Time
+}
+
+func newStubRepo() *stubRepo {
+	return &stubRepo{
+		prefs:       map[string]models.NotificationPreferences{},
+		keys:        map[string]bool{},
+		recorded:    map[string]models.NotificationEmailStatus{},
+		nextAttempt: map[string]*time.Time{},
+	}
+}
+
+func (s *stubRepo) Preferences(ctx context.Context, userID string) (models.NotificationPreferences, error) {
+	return s.prefs[userID], nil
+}
+
+func (s *stubRepo) Create(ctx context.Context, n *models.Notification, dedupeKey string) (bool, error) {
+	if dedupeKey != "" {
+		if s.keys[n.UserID+dedupeKey] {
+			return false, nil
+		}
+		s.keys[n.UserID+dedupeKey] = true
+	}
+	n.ID = "n" + string(rune('a'+len(s.notifications)))
+	s.notifications = append(s.notifications, n)
+	return true, nil
+}
+
+func (s *stubRepo) DueReviews(ctx context.Context, today time.Time) ([]*models.NotificationDeadline, error) {
+	return s.reviews, nil
+}
+
+func (s *stubRepo) DueMitigations(ctx context.Context, before time.Time) ([]*models.NotificationDeadline, error) {
+	s.dueBefore = before
+	return s.mitigations, nil
+}
+
+func (s *stubRepo) DueEvidenceRequests(ctx context.Context, today, before time.Time) ([]*models.NotificationDeadline, error) {
+	s.evidenceDue = before
+	return s.evidence, nil
+}
+
+func (s *stubRepo) ClaimEmails(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.NotificationEmail, error) {
+	claimed := s.emails
+	s.emails = nil
+	return claimed, nil
+}
+
+func (s *stubRepo) RecordEmail(ctx context.Context, id string, status models.NotificationEmailStatus, next *time.Time, sendErr *string) error {
+	s.recorded[id] = status
+	s.nextAttempt[id] = next
+	return nil
+}
+
+func (s *stubRepo) WithTx(tx *sql.Tx) database.NotificationRepository { return s }
+
+// events lists the stored notifications as "event>user"
+func (s *stubRepo) events() []string {
+	var list []string
+	for _, n := range s.notifications {
+		list = append(list, string(n.Event)+">"+n.UserID)
+	}
+	return list
+}
+
+type stubAudit struct {
+	database.AuditLogRepository
+}
+
+func (stubAudit) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	return nil
+}
+
+func (a stubAudit) WithTx(tx *sql.Tx) database.AuditLogRepository { return a }
+
+type stubRisks struct {
+	database.RiskRepository
+	risks map[string]*models.Risk
+}
+
+func (s *stubRisks) FindByID(ctx context.Context, id string) (*models.Risk, error) {
+	if r, ok := s.risks[id]; ok {
+		return r, nil
+	}
+	return nil, database.ErrRiskNotFound
+}
+
+func (s *stubRisks) WithTx(tx *sql.Tx) database.RiskRepository { return s }
+
+type stubMitigations struct {
+	database.MitigationRepository
+	mitigation *models.Mitigation
+}
+
+func (s *stubMitigations) FindByID(ctx context.Context, id string) (*models.Mitigation, error) {
+	return s.mitigation, nil
+}
+
+type stubIncidents struct {
+	database.IncidentRepository
+	incident *models.Incident
+}
+
+func (s *stubIncidents) FindByID(ctx context.Context, id string) (*models.Incident, error) {
+	return s.incident, nil
+}
+
+func (s *stubIncidents) WithTx(tx *sql.Tx) database.IncidentRepository { return s }
+
+type stubUsers struct {
+	database.UserRepository
+}
+
+func (stubUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	if id == "actor" {
+		return &models.User{ID: id, Name: "Jane Doe"}, nil
+	}
+	return nil, database.ErrUserNotFound
+}
+
+type notifierEnv struct {
+	repo     *stubRepo
+	risk     *models.Risk
+	incident *models.Incident
+	audit    database.AuditLogRepository
+}
+
+func newNotifierEnv() *notifierEnv {
+	assignee := "responder"
+	env := &notifierEnv{
+		repo:     newStubRepo(),
+		risk:     &models.Risk{ID: "risk-1", Title: "Vendor outage", OwnerID: "owner", Severity: models.SeverityHigh},
+		incident: &models.Incident{ID: "inc-1", Title: "Checkout down", Priority: models.PriorityP1, ReporterID: "reporter", AssigneeID: &assignee},
+	}
+	mitigation := &models.Mitigation{ID: "mit-1", RiskID: "risk-1", Description: "Add a second supplier"}
+	env.audit = NewAuditNotifier(stubAudit{}, env.repo, &stubRisks{risks: map[string]*models.Risk{"risk-1": env.risk}},
+		&stubMitigations{mitigation: mitigation}, &stubIncidents{incident: env.incident}, stubUsers{}).WithTx(nil)
+	return env
+}
+
+func update(from, to any) map[string]any {
+	return map[string]any{"from": from, "to": to}
+}
+
+func TestAuditNotifier_Risks(t *testing.T) {
+	env := newNotifierEnv()
+	ctx := context.Background()
+
+	env.audit.Create(ctx, "risk", "risk-1", models.AuditActionCreated, map[string]any{}, "actor")
+	env.audit.Create(ctx, "risk", "risk-1", models.AuditActionUpdated, map[string]any{"status": update("open", "mitigating"), "review_date": update(nil, "2026-12-01")}, "actor")
+	// Owners aren't told about their own changes
+	env.audit.Create(ctx, "risk", "risk-1", models.AuditActionUpdated, map[string]any{"status": update("mitigating", "open")}, "owner")
+
+	got := strings.Join(env.repo.events(), ",")
+	if got != "risk.assigned>owner,risk.updated>owner" {
+		t.Fatalf("unexpected notifications %s", got)
+	}
+	assigned, updated := env.repo.notifications[0], env.repo.notifications[1]
+	if assigned.Title != `You are now the owner of risk "Vendor outage"` || assigned.Body != "Jane Doe made you the owner of this high severity risk." {
+		t.Errorf("unexpected assignment text: %q / %q", assigned.Title, assigned.Body)
+	}
+	if assigned.Link != "/app/risks/risk-1" || assigned.ActorID == nil || *assigned.ActorID != "actor" {
+		t.Errorf("unexpected link or actor: %+v", assigned)
+	}
+	if updated.Body != "Jane Doe changed review date and status." {
+		t.Errorf("unexpected update text %q", updated.Body)
+	}
+
+	// Handing the risk to someone else tells the new owner only
+	env.repo.notifications = nil
+	env.risk.OwnerID = "new-owner"
+	env.audit.Create(ctx, "risk", "risk-1", models.AuditActionUpdated, map[string]any{"owner_id": update("owner", "new-owner"), "severity": update("high", "critical")}, "actor")
+	if got := strings.Join(env.repo.events(), ","); got != "risk.assigned>new-owner" {
+		t.Errorf("unexpected notifications %s", got)
+	}
+}
+
+func TestAuditNotifier_Mitigations(t *testing.T) {
+	env := newNotifierEnv()
+	ctx := context.Background()
+
+	env.audit.Create(ctx, "mitigation", "mit-1", models.AuditActionCreated, map[string]any{}, "actor")
+	env.audit.Create(ctx, "mitigation", "mit-1", models.AuditActionUpdated, map[string]any{"due_date": update(nil, "2026-11-01")}, "actor")
+	env.audit.Create(ctx, "mitigation", "mit-1", models.AuditActionDeleted, nil, "actor")
+
+	if got := strings.Join(env.repo.events(), ","); got != "mitigation.updated>owner,mitigation.updated>owner" {
+		t.Fatalf("unexpected notifications %s", got)
+	}
+	if n := env.repo.notifications[0]; n.Body != "Jane Doe added a mitigation: Add a second supplier" || n.Link != "/app/risks/risk-1" {
+		t.Errorf("unexpected notification %+v", n)
+	}
+	if n := env.repo.notifications[1]; n.Body != `Jane Doe changed due date of "Add a second supplier".` {
+		t.Errorf("unexpected update text %q", n.Body)
+	}
+}
+
+func TestAuditNotifier_Incidents(t *testing.T) {
+	env := newNotifierEnv()
+	ctx := context.Background()
+
+	env.audit.Create(ctx, "incident", "inc-1", models.AuditActionCreated, map[string]any{}, "reporter")
+	if got := strings.Join(env.repo.events(), ","); got != "incident.assigned>responder" {
+		t.Fatalf("unexpected notifications %s", got)
+	}
+	if n := env.repo.notifications[0]; n.Body != "Someone assigned this P1 incident to you." || n.Link != "/app/incidents/inc-1" {
+		t.Errorf("unexpected notification %+v", n)
+	}
+
+	env.repo.notifications = nil
+	env.audit.Create(ctx, "incident", "inc-1", models.AuditActionUpdated, map[string]any{
+		"status":   update("new", "in_progress"),
+		"priority": update("p2", "p1"),
+	}, "actor")
+	if got := strings.Join(env.repo.events(), ","); got != "incident.updated>reporter,incident.updated>responder" {
+		t.Fatalf("unexpected notifications %s", got)
+	}
+	if n := env.repo.notifications[0]; n.Body != "Jane Doe changed the status from new to in progress and the priority from P2 to P1." {
+		t.Errorf("unexpected update text %q", n.Body)
+	}
+
+	// A reassignment tells the new assignee once, not also as an update
+	env.repo.notifications = nil
+	env.audit.Create(ctx, "incident", "inc-1", models.AuditActionUpdated, map[string]any{
+		"assignee_id": update(nil, "responder"),
+		"status":      update("in_progress", "resolved"),
+	}, "actor")
+	if got := strings.Join(env.repo.events(), ","); got != "incident.assigned>responder,incident.updated>reporter" {
+		t.Errorf("unexpected notifications %s", got)
+	}
+}
+
+func TestAuditNotifier_Preferences(t *testing.T) {
+	env := newNotifierEnv()
+	env.repo.prefs["owner"] = models.NotificationPreferences{
+		models.NotificationRiskUpdated:  {},
+		models.NotificationRiskAssigned: {models.NotificationChannelEmail},
+	}
+	ctx := context.Background()
+
+	env.audit.Create(ctx, "risk", "risk-1", models.AuditActionUpdated, map[string]any{"status": update("open", "accepted")}, "actor")
+	if len(env.repo.notifications) != 0 {
+		t.Fatalf("expected a turned-off event to store nothing, got %v", env.repo.events())
+	}
+
+	env.audit.Create(ctx, "risk", "risk-1", models.AuditActionCreated, map[string]any{}, "actor")
+	if n := env.repo.notifications[0]; n.InApp || !n.Email {
+		t.Errorf("expected an email-only notification, got in_app=%v email=%v", n.InApp, n.Email)
+	}
+
+	env.audit.Create(ctx, "mitigation", "mit-1", models.AuditActionCreated, map[string]any{}, "actor")
+	if n := env.repo.notifications[1]; !n.InApp || n.Email {
+		t.Errorf("expected the in-app default for mitigation changes, got in_app=%v email=%v", n.InApp, n.Email)
+	}
+}
+
+type stubMailer struct {
+	sent []*mailer.Message
+	err  error
+}
+
+func (m *stubMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestWorker_RaiseDeadlines(t *testing.T) {
+	repo := newStubRepo()
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	repo.reviews = []*models.NotificationDeadline{{
+		Event: models.NotificationRiskReviewDue, EntityType: "risk", EntityID: "risk-1", RiskID: "risk-1",
+		Title: "Vendor outage", Due: time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), UserIDs: []string{"owner"},
+	}}
+	repo.mitigations = []*models.NotificationDeadline{{
+		Event: models.NotificationMitigationDue, EntityType: "mitigation", EntityID: "mit-1", RiskID: "risk-1",
+		Title: "Add a second supplier", Due: now.Add(48 * time.Hour), UserIDs: []string{"owner", "engineer"},
+	}}
+	repo.prefs["engineer"] = models.NotificationPreferences{models.NotificationMitigationDue: {}}
+	repo.evidence = []*models.NotificationDeadline{{
+		Event: models.NotificationEvidenceDue, EntityType: "evidence_request", EntityID: "ev-1",
+		Title: "A.5.15 Access review export", Due: time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC), UserIDs: []string{"assignee"},
+	}, {
+		Event: models.NotificationEvidenceDue, EntityType: "evidence_request", EntityID: "ev-2",
+		Title: "A.8.13 Backup restore log", Due: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), UserIDs: []string{"assignee"},
+	}}
+	worker := NewWorker(repo, &stubMailer{}, "", time.Minute, 72*time.Hour, 7*24*time.Hour)
+
+	raised, err := worker.RaiseDeadlines(context.Background(), now)
+	if err != nil || raised != 4 {
+		t.Fatalf("RaiseDeadlines() = %d, %v", raised, err)
+	}
+	if !repo.dueBefore.Equal(now.Add(72 * time.Hour)) {
+		t.Errorf("expected mitigations due within the notice period, got %v", repo.dueBefore)
+	}
+	if !repo.evidenceDue.Equal(now.Add(7 * 24 * time.Hour)) {
+		t.Errorf("expected evidence requests due within their notice period, got %v", repo.evidenceDue)
+	}
+	review, due := repo.notifications[0], repo.notifications[1]
+	if review.Title != `Review due for risk "Vendor outage"` || review.Body != "This risk was due for review on 15 October 2026." || !review.Email {
+		t.Errorf("unexpected review notification %+v", review)
+	}
+	if due.Body != "This mitigation is due on 20 October 2026." || due.Link != "/app/risks/risk-1" {
+		t.Errorf("unexpected due notification %+v", due)
+	}
+	// The engineer turned the event off; a hidden entry still marks it raised
+	if hidden := repo.notifications[2]; hidden.UserID != "engineer" || hidden.InApp || hidden.Email {
+		t.Errorf("expected a hidden entry for the engineer, got %+v", hidden)
+	}
+	evidence := repo.notifications[3]
+	if evidence.UserID != "assignee" || evidence.Title != "Evidence overdue: A.5.15 Access review export" ||
+		evidence.Body != "Evidence for this control was due on 17 October 2026 and has not been provided." ||
+		evidence.Link != "/app/controls" || !evidence.Email {
+		t.Errorf("unexpected evidence notification %+v", evidence)
+	}
+	// Evidence due today isn't overdue yet
+	if dueToday := repo.notifications[4]; dueToday.Title != "Evidence due: A.8.13 Backup restore log" ||
+		dueToday.Body != "Evidence for this control is due on 18 October 2026." {
+		t.Errorf("unexpected evidence notification %+v", dueToday)
+	}
+
+	if raised, _ := worker.RaiseDeadlines(context.Background(), now); raised != 0 {
+		t.Errorf("expected deadlines to be raised once, got %d", raised)
+	}
+}
+
+func TestWorker_SendEmails(t *testing.T) {
+	repo := newStubRepo()
+	mail := &stubMailer{}
+	worker := NewWorker(repo, mail, "https://risk.example.com/", time.Minute, 0, 0)
+	now := time.Now()
+
+	repo.emails = []*models.NotificationEmail{{
+		Notification: &models.Notification{ID: "n1", Title: "Review due", Body: "Please review.", Link: "/app/risks/risk-1"},
+		To:           "owner@example.com",
+	}}
+	if n, err := worker.SendEmails(context.Background(), now); err != nil || n != 1 {
+		t.Fatalf("SendEmails() = %d, %v", n, err)
+	}
+	if repo.recorded["n1"] != models.NotificationEmailSent || len(mail.sent) != 1 {
+		t.Fatalf("expected the email to be sent, got %v", repo.recorded)
+	}
+	msg := mail.sent[0]
+	if msg.To[0] != "owner@example.com" || msg.Subject != "Review due" || !strings.Contains(msg.Body, "https://risk.example.com/app/risks/risk-1") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	mail.err = errors.New("connection refused")
+	repo.emails = []*models.NotificationEmail{
+		{Notification: &models.Notification{ID: "n2"}, To: "a@example.com"},
+		{Notification: &models.Notification{ID: "n3"}, To: "b@example.com", Attempts: MaxEmailAttempts - 1},
+	}
+	worker.SendEmails(context.Background(), now)
+	if repo.recorded["n2"] != models.NotificationEmailPending || repo.nextAttempt["n2"] == nil || !repo.nextAttempt["n2"].After(now) {
+		t.Errorf("expected a retry, got %v at %v", repo.recorded["n2"], repo.nextAttempt["n2"])
+	}
+	if repo.recorded["n3"] != models.NotificationEmailFailed || repo.nextAttempt["n3"] != nil {
+		t.Errorf("expected the last attempt to fail, got %v", repo.recorded["n3"])
+	}
+}
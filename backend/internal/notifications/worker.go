@@ -0,0 +1,175 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/mailer"
+	"backend/internal/models"
+	"backend/internal/webhooks"
+)
+
+const (
+	// MaxEmailAttempts is how many times an email is tried before it is
+	// marked failed
+	MaxEmailAttempts = 5
+	emailBatchSize   = 100
+	emailLease       = 5 * time.Minute
+)
+
+// Worker raises review and due date notifications and sends the emails
+// queued for notifications
+type Worker struct {
+	notifications  database.NotificationRepository
+	mailer         mailer.Mailer
+	appURL         string
+	interval       time.Duration
+	dueWithin      time.Duration
+	evidenceWithin time.Duration
+}
+
+// NewWorker returns a worker that runs every interval. Mitigations are
+// notified once they are due within dueWithin and evidence requests once
+// they are due within evidenceWithin; appURL is the web app's base URL used
+// for links in emails.
+func NewWorker(notifications database.NotificationRepository, m mailer.Mailer, appURL string, interval, dueWithin, evidenceWithin time.Duration) *Worker {
+	return &Worker{
+		notifications:  notifications,
+		mailer:         m,
+		appURL:         strings.TrimRight(appURL, "/"),
+		interval:       interval,
+		dueWithin:      dueWithin,
+		evidenceWithin: evidenceWithin,
+	}
+}
+
+// Run checks deadlines and sends queued emails every interval until ctx is
+// cancelled. A zero interval disables the worker.
+func (w *Worker) Run(ctx context.Context) {
+	if w.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.RaiseDeadlines(ctx, time.Now()); err != nil {
+			log.Printf("notifications: %v", err)
+		}
+		if _, err := w.SendEmails(ctx, time.Now()); err != nil {
+			log.Printf("notifications: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RaiseDeadlines notifies owners of review dates that have passed and
+// mitigations that fall due, and assignees of evidence requests that fall
+// due, and returns how many notifications it stored. Each deadline is
+// raised once per date.
+func (w *Worker) RaiseDeadlines(ctx context.Context, now time.Time) (int, error) {
+	reviews, err := w.notifications.DueReviews(ctx, now)
+	if err != nil {
+		return 0, fmt.Errorf("listing due reviews: %w", err)
+	}
+	mitigations, err := w.notifications.DueMitigations(ctx, now.Add(w.dueWithin))
+	if err != nil {
+		return 0, fmt.Errorf("listing due mitigations: %w", err)
+	}
+	evidence, err := w.notifications.DueEvidenceRequests(ctx, now, now.Add(w.evidenceWithin))
+	if err != nil {
+		return 0, fmt.Errorf("listing due evidence requests: %w", err)
+	}
+
+	raised := 0
+	deadlines := append(append(reviews, mitigations...), evidence...)
+	for _, d := range deadlines {
+		for _, userID := range d.UserIDs {
+			n, key := deadlineNotification(d, userID, now)
+			created, err := deliver(ctx, w.notifications, n, key)
+			if err != nil {
+				return raised, err
+			}
+			if created {
+				raised++
+			}
+		}
+	}
+	return raised, nil
+}
+
+func deadlineNotification(d *models.NotificationDeadline, userID string, now time.Time) (*models.Notification, string) {
+	n := &models.Notification{UserID: userID, Event: d.Event, EntityType: d.EntityType, EntityID: d.EntityID, Link: riskLink(d.RiskID)}
+	switch d.Event {
+	case models.NotificationRiskReviewDue:
+		n.Title = fmt.Sprintf("Review due for risk %q", d.Title)
+		n.Body = fmt.Sprintf("This risk was due for review on %s.", formatDate(d.Due))
+		return n, database.ReviewDedupeKey(d.EntityID, d.Due)
+	case models.NotificationEvidenceDue:
+		// Evidence is due by the end of its due date
+		overdue := d.Due.Format(time.DateOnly) < now.UTC().Format(time.DateOnly)
+		n.Link = controlsLink()
+		n.Title = fmt.Sprintf("Evidence due: %s", truncate(d.Title, 200))
+		if overdue {
+			n.Title = fmt.Sprintf("Evidence overdue: %s", truncate(d.Title, 200))
+			n.Body = fmt.Sprintf("Evidence for this control was due on %s and has not been provided.", formatDate(d.Due))
+		} else {
+			n.Body = fmt.Sprintf("Evidence for this control is due on %s.", formatDate(d.Due))
+		}
+		return n, database.EvidenceDueDedupeKey(d.EntityID, d.Due, overdue)
+	}
+
+	n.Title = fmt.Sprintf("Mitigation due: %s", truncate(d.Title, 200))
+	if d.Due.Before(now) {
+		n.Body = fmt.Sprintf("This mitigation was due on %s and is still open.", formatDate(d.Due))
+	} else {
+		n.Body = fmt.Sprintf("This mitigation is due on %s.", formatDate(d.Due))
+	}
+	return n, database.MitigationDueDedupeKey(d.EntityID, d.Due)
+}
+
+// SendEmails sends queued notification emails due at now and returns how
+// many it attempted. Failed sends are retried with backoff.
+func (w *Worker) SendEmails(ctx context.Context, now time.Time) (int, error) {
+	emails, err := w.notifications.ClaimEmails(ctx, now, now.Add(emailLease), emailBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claiming notification emails: %w", err)
+	}
+	for _, email := range emails {
+		msg := &mailer.Message{To: []string{email.To}, Subject: email.Notification.Title, Body: w.emailBody(email.Notification)}
+		status := models.NotificationEmailSent
+		var next *time.Time
+		var errMsg *string
+		if err := w.mailer.Send(ctx, msg); err != nil {
+			text := err.Error()
+			errMsg = &text
+			status = models.NotificationEmailFailed
+			if email.Attempts+1 < MaxEmailAttempts {
+				at := now.Add(webhooks.Backoff(email.Attempts + 1))
+				status, next = models.NotificationEmailPending, &at
+			}
+		}
+		if err := w.notifications.RecordEmail(ctx, email.Notification.ID, status, next, errMsg); err != nil {
+			return 0, fmt.Errorf("recording notification email %s: %w", email.Notification.ID, err)
+		}
+	}
+	return len(emails), nil
+}
+
+func (w *Worker) emailBody(n *models.Notification) string {
+	var b strings.Builder
+	b.WriteString(n.Body)
+	b.WriteString("\n")
+	if w.appURL != "" && n.Link != "" {
+		fmt.Fprintf(&b, "\n%s%s\n", w.appURL, n.Link)
+	}
+	b.WriteString("\nYou can choose which notifications are emailed to you in your notification preferences.\n")
+	return b.String()
+}
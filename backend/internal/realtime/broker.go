@@ -0,0 +1,135 @@
+// Package realtime streams changes to risks, mitigations and incidents to
+// connected clients. Changes are picked up from audit entries and fanned out
+// by an in-process Broker; with several API instances, Postgres
+// LISTEN/NOTIFY carries each change to every instance's broker.
+package realtime
+
+import (
+	"sync"
+
+	"backend/internal/models"
+)
+
+const (
+	// historySize is how many recent events are kept for clients that
+	// reconnect with Last-Event-ID
+	historySize = 500
+	// subscriberBuffer is how many events a slow client can fall behind
+	// before it is disconnected
+	subscriberBuffer = 64
+)
+
+// Broker fans events out to subscribers
+type Broker struct {
+	mu      sync.Mutex
+	subs    map[*Subscription]struct{}
+	history []*models.StreamEvent
+	closed  bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: map[*Subscription]struct{}{}}
+}
+
+// Subscription receives the events that pass its filter. C is closed when
+// the subscriber falls too far behind or the broker shuts down; the client
+// should reconnect and resume from the last event it saw.
+type Subscription struct {
+	C      <-chan *models.StreamEvent
+	c      chan *models.StreamEvent
+	filter func(*models.StreamEvent) bool
+	broker *Broker
+}
+
+// Subscribe registers a subscriber for events that pass filter. With a
+// lastEventID it also returns the events published since then; resumed is
+// false if that event is no longer known, so the client may have missed
+// some.
+func (b *Broker) Subscribe(lastEventID string, filter func(*models.StreamEvent) bool) (sub *Subscription, missed []*models.StreamEvent, resumed bool) {
+	c := make(chan *models.StreamEvent, subscriberBuffer)
+	sub = &Subscription{C: c, c: c, filter: filter, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(c)
+		return sub, nil, true
+	}
+	b.subs[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	for i := len(b.history) - 1; i >= 0; i-- {
+		if b.history[i].ID != lastEventID {
+			continue
+		}
+		for _, e := range b.history[i+1:] {
+			if filter(e) {
+				missed = append(missed, e)
+			}
+		}
+		return sub, missed, true
+	}
+	return sub, nil, false
+}
+
+// Unsubscribe stops delivery to sub. It is safe to call more than once.
+func (s *Subscription) Unsubscribe() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// remove drops sub and closes its channel; b.mu must be held
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
+
+// Publish delivers e to every subscriber whose filter accepts it. It never
+// blocks: a subscriber with a full buffer is disconnected instead.
+func (b *Broker) Publish(e *models.StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	if e.Type != models.StreamEventReset {
+		b.history = append(b.history, e)
+		if len(b.history) > historySize {
+			b.history = append(b.history[:0:0], b.history[len(b.history)-historySize:]...)
+		}
+	}
+	for sub := range b.subs {
+		if !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+			b.remove(sub)
+		}
+	}
+}
+
+// Close disconnects every subscriber; later subscriptions are closed
+// straight away
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub)
+	}
+}
+
+// Subscribers returns how many clients are connected
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
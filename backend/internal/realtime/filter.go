@@ -0,0 +1,56 @@
+package realtime
+
+import (
+	"slices"
+
+	"backend/internal/models"
+)
+
+// Filter selects the events a client receives
+type Filter struct {
+	// Role is the user's role, which decides what they may see
+	Role string
+	// EntityTypes limits the stream to some entity types; empty means all
+	EntityTypes []string
+	// EntityID limits the stream to one entity, e.g. for an incident page
+	EntityID string
+}
+
+// StreamedEntityTypes lists the entity types a client can filter on
+func StreamedEntityTypes() []string {
+	types := make([]string, 0, len(streamedEntities))
+	for t := range streamedEntities {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// Allows reports whether the client should receive e. Reset events always
+// go through.
+func (f *Filter) Allows(e *models.StreamEvent) bool {
+	if e.Type == models.StreamEventReset {
+		return true
+	}
+	if !Visible(e, f.Role) {
+		return false
+	}
+	if len(f.EntityTypes) > 0 && !slices.Contains(f.EntityTypes, e.EntityType) {
+		return false
+	}
+	return f.EntityID == "" || f.EntityID == e.EntityID
+}
+
+// Visible reports whether a user with role may see e. It follows the API's
+// read access: every user can read risks, mitigations and incidents, but
+// incidents in the trash are only visible to admins, so only they hear that
+// one was purged.
+func Visible(e *models.StreamEvent, role string) bool {
+	if !streamedEntities[e.EntityType] {
+		return false
+	}
+	if e.EntityType == "incident" && e.Action == models.AuditActionPurged {
+		return role == string(models.RoleAdmin)
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+// Listen forwards events published by PostgresPublisher on any instance to
+// broker until ctx is cancelled. It holds its own connection and reconnects
+// when that drops; since events may have been missed meanwhile, clients are
+// then told to reload.
+func Listen(ctx context.Context, connString string, broker *Broker) {
+	delay := listenRetryMin
+	connected := false
+	for ctx.Err() == nil {
+		err := listen(ctx, connString, func() {
+			if connected {
+				broker.Publish(NewResetEvent())
+			}
+			connected = true
+			delay = listenRetryMin
+		}, broker.Publish)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("realtime: listening for events failed, retrying in %s: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, listenRetryMax)
+	}
+}
+
+func listen(ctx context.Context, connString string, onListening func(), publish func(*models.StreamEvent)) error {
+	conn, err := pgx.Connect(ctx, connString)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{Channel}.Sanitize()); err != nil {
+		return err
+	}
+	onListening()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var e models.StreamEvent
+		if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+			log.Printf("realtime: ignoring malformed event: %v", err)
+			continue
+		}
+		publish(&e)
+	}
+}
+
+// NewResetEvent returns an event telling clients to reload what they show
+func NewResetEvent() *models.StreamEvent {
+	return &models.StreamEvent{ID: uuid.New().String(), Type: models.StreamEventReset, OccurredAt: time.Now().UTC()}
+}
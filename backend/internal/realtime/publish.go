@@ -0,0 +1,120 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Channel is the Postgres NOTIFY channel events travel on between instances
+const Channel = "risk_register_events"
+
+// streamedEntities are the audit entity types pushed to clients
+var streamedEntities = map[string]bool{"risk": true, "mitigation": true, "incident": true}
+
+// Publisher sends events to the brokers of every API instance
+type Publisher interface {
+	// Publish sends e once tx commits, or straight away when tx is nil
+	Publish(ctx context.Context, tx *sql.Tx, e *models.StreamEvent) error
+}
+
+// LocalPublisher publishes to a single in-process broker, for running one
+// API instance
+type LocalPublisher struct {
+	broker *Broker
+}
+
+func NewLocalPublisher(broker *Broker) *LocalPublisher {
+	return &LocalPublisher{broker: broker}
+}
+
+func (p *LocalPublisher) Publish(ctx context.Context, tx *sql.Tx, e *models.StreamEvent) error {
+	database.AfterCommit(tx, func() { p.broker.Publish(e) })
+	return nil
+}
+
+// PostgresPublisher sends events with NOTIFY, which Postgres delivers on
+// commit to every instance running Listen, this one included
+type PostgresPublisher struct {
+	notifier database.Notifier
+}
+
+func NewPostgresPublisher(notifier database.Notifier) *PostgresPublisher {
+	return &PostgresPublisher{notifier: notifier}
+}
+
+func (p *PostgresPublisher) Publish(ctx context.Context, tx *sql.Tx, e *models.StreamEvent) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	notifier := p.notifier
+	if tx != nil {
+		notifier = notifier.WithTx(tx)
+	}
+	return notifier.Notify(ctx, Channel, string(payload))
+}
+
+// Event returns the stream event for an audit entry, or nil if the entry's
+// entity type is not streamed
+func Event(entityType, entityID string, action models.AuditAction, changes map[string]any, actorID string, at time.Time) *models.StreamEvent {
+	if !streamedEntities[entityType] {
+		return nil
+	}
+	e := &models.StreamEvent{
+		ID:         uuid.New().String(),
+		Type:       entityType + "." + string(action),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		ActorID:    actorID,
+		OccurredAt: at.UTC(),
+	}
+	if action == models.AuditActionUpdated {
+		for field := range changes {
+			e.Fields = append(e.Fields, field)
+		}
+		slices.Sort(e.Fields)
+	}
+	return e
+}
+
+// auditPublisher records audit entries and publishes the stream events they
+// raise once the change is committed
+type auditPublisher struct {
+	database.AuditLogRepository
+	publisher Publisher
+	tx        *sql.Tx
+}
+
+// NewAuditPublisher wraps audit so that every entry about a risk,
+// mitigation or incident is also streamed to connected clients
+func NewAuditPublisher(audit database.AuditLogRepository, publisher Publisher) database.AuditLogRepository {
+	return &auditPublisher{AuditLogRepository: audit, publisher: publisher}
+}
+
+func (a *auditPublisher) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	if err := a.AuditLogRepository.Create(ctx, entityType, entityID, action, changes, userID); err != nil {
+		return err
+	}
+	e := Event(entityType, entityID, action, changes, userID, time.Now())
+	if e == nil {
+		return nil
+	}
+	if err := a.publisher.Publish(ctx, a.tx, e); err != nil {
+		return fmt.Errorf("publishing %s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+func (a *auditPublisher) WithTx(tx *sql.Tx) database.AuditLogRepository {
+	return &auditPublisher{AuditLogRepository: a.AuditLogRepository.WithTx(tx), publisher: a.publisher, tx: tx}
+}
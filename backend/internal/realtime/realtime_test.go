@@ -0,0 +1,205 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+func event(id, entityType string, action models.AuditAction) *models.StreamEvent {
+	return &models.StreamEvent{ID: id, Type: entityType + "." + string(action), EntityType: entityType, EntityID: id + "-entity", Action: action}
+}
+
+func all(*models.StreamEvent) bool { return true }
+
+func receive(t *testing.T, sub *Subscription) *models.StreamEvent {
+	t.Helper()
+	select {
+	case e := <-sub.C:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an event")
+		return nil
+	}
+}
+
+func TestBroker_PublishAndFilter(t *testing.T) {
+	b := NewBroker()
+	risks, _, _ := b.Subscribe("", (&Filter{EntityTypes: []string{"risk"}}).Allows)
+	everything, _, _ := b.Subscribe("", all)
+
+	b.Publish(event("e1", "incident", models.AuditActionUpdated))
+	b.Publish(event("e2", "risk", models.AuditActionCreated))
+
+	if e := receive(t, risks); e.ID != "e2" {
+		t.Errorf("expected only the risk event, got %s", e.ID)
+	}
+	if e := receive(t, everything); e.ID != "e1" {
+		t.Errorf("expected e1 first, got %s", e.ID)
+	}
+	if e := receive(t, everything); e.ID != "e2" {
+		t.Errorf("expected e2 second, got %s", e.ID)
+	}
+
+	risks.Unsubscribe()
+	risks.Unsubscribe()
+	if _, ok := <-risks.C; ok {
+		t.Error("expected the channel to be closed after unsubscribing")
+	}
+	if n := b.Subscribers(); n != 1 {
+		t.Errorf("expected 1 subscriber, got %d", n)
+	}
+}
+
+func TestBroker_Resume(t *testing.T) {
+	b := NewBroker()
+	for _, id := range []string{"e1", "e2", "e3"} {
+		b.Publish(event(id, "risk", models.AuditActionUpdated))
+	}
+	b.Publish(event("e4", "incident", models.AuditActionUpdated))
+
+	_, missed, resumed := b.Subscribe("e1", (&Filter{EntityTypes: []string{"risk"}}).Allows)
+	if !resumed || len(missed) != 2 || missed[0].ID != "e2" || missed[1].ID != "e3" {
+		t.Errorf("expected e2 and e3 to be replayed, got %v %v", resumed, missed)
+	}
+
+	if _, missed, resumed := b.Subscribe("e4", all); !resumed || len(missed) != 0 {
+		t.Errorf("expected nothing to replay after the latest event, got %v", missed)
+	}
+	if _, _, resumed := b.Subscribe("unknown", all); resumed {
+		t.Error("expected an unknown event ID to need a reset")
+	}
+
+	for i := 0; i < historySize+10; i++ {
+		b.Publish(event("x", "risk", models.AuditActionUpdated))
+	}
+	if _, _, resumed := b.Subscribe("e2", all); resumed {
+		t.Error("expected events older than the history to need a reset")
+	}
+}
+
+func TestBroker_SlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker()
+	slow, _, _ := b.Subscribe("", all)
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(event("e", "risk", models.AuditActionUpdated))
+	}
+	if b.Subscribers() != 0 {
+		t.Fatal("expected the full subscriber to be dropped")
+	}
+	n := 0
+	for range slow.C {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("expected the buffered events to be readable before the close, got %d", n)
+	}
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := NewBroker()
+	sub, _, _ := b.Subscribe("", all)
+	b.Close()
+	if _, ok := <-sub.C; ok {
+		t.Error("expected Close to end subscriptions")
+	}
+	late, _, _ := b.Subscribe("", all)
+	if _, ok := <-late.C; ok {
+		t.Error("expected subscriptions after Close to be closed")
+	}
+	b.Publish(event("e1", "risk", models.AuditActionCreated))
+}
+
+func TestFilter(t *testing.T) {
+	purged := event("e1", "incident", models.AuditActionPurged)
+	if (&Filter{Role: "member"}).Allows(purged) || (&Filter{Role: "responder"}).Allows(purged) {
+		t.Error("only admins should hear about purged incidents")
+	}
+	if !(&Filter{Role: "admin"}).Allows(purged) {
+		t.Error("admins should hear about purged incidents")
+	}
+	if (&Filter{}).Allows(event("e2", "risk_import", models.AuditActionCreated)) {
+		t.Error("entity types that aren't streamed should be filtered out")
+	}
+
+	follow := &Filter{Role: "member", EntityID: "e3-entity"}
+	if !follow.Allows(event("e3", "incident", models.AuditActionUpdated)) || follow.Allows(event("e4", "incident", models.AuditActionUpdated)) {
+		t.Error("expected the entity filter to follow one incident")
+	}
+	if !follow.Allows(NewResetEvent()) {
+		t.Error("reset events should always be delivered")
+	}
+}
+
+func TestEvent(t *testing.T) {
+	e := Event("risk", "risk-1", models.AuditActionUpdated, map[string]any{"status": nil, "owner_id": nil}, "user-1", time.Now())
+	if e.Type != "risk.updated" || e.EntityID != "risk-1" || e.ActorID != "user-1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if len(e.Fields) != 2 || e.Fields[0] != "owner_id" || e.Fields[1] != "status" {
+		t.Errorf("expected the sorted changed fields, got %v", e.Fields)
+	}
+	if e := Event("risk", "risk-1", models.AuditActionCreated, map[string]any{"title": "x"}, "", time.Now()); e.Fields != nil {
+		t.Errorf("created events shouldn't list fields, got %v", e.Fields)
+	}
+	if Event("risk_import", "imp-1", models.AuditActionCreated, nil, "", time.Now()) != nil {
+		t.Error("expected no event for entity types that aren't streamed")
+	}
+}
+
+type stubAudit struct {
+	database.AuditLogRepository
+	entries int
+}
+
+func (s *stubAudit) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	s.entries++
+	return nil
+}
+
+func (s *stubAudit) WithTx(tx *sql.Tx) database.AuditLogRepository { return s }
+
+type stubNotifier struct {
+	channel, payload string
+}
+
+func (s *stubNotifier) Notify(ctx context.Context, channel, payload string) error {
+	s.channel, s.payload = channel, payload
+	return nil
+}
+
+func (s *stubNotifier) WithTx(tx *sql.Tx) database.Notifier { return s }
+
+func TestAuditPublisher(t *testing.T) {
+	b := NewBroker()
+	sub, _, _ := b.Subscribe("", all)
+	audit := &stubAudit{}
+	publisher := NewAuditPublisher(audit, NewLocalPublisher(b)).WithTx(nil)
+
+	publisher.Create(context.Background(), "incident", "inc-1", models.AuditActionUpdated, map[string]any{"status": nil}, "user-1")
+	publisher.Create(context.Background(), "risk_import", "imp-1", models.AuditActionCreated, nil, "user-1")
+	if audit.entries != 2 {
+		t.Errorf("expected both audit entries to be written, got %d", audit.entries)
+	}
+	if e := receive(t, sub); e.Type != "incident.updated" || e.EntityID != "inc-1" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	select {
+	case e := <-sub.C:
+		t.Errorf("expected no event for the import, got %+v", e)
+	default:
+	}
+
+	notifier := &stubNotifier{}
+	publisher = NewAuditPublisher(audit, NewPostgresPublisher(notifier))
+	publisher.Create(context.Background(), "risk", "risk-1", models.AuditActionDeleted, nil, "user-1")
+	var sent models.StreamEvent
+	if err := json.Unmarshal([]byte(notifier.payload), &sent); err != nil || notifier.channel != Channel || sent.Type != "risk.deleted" {
+		t.Errorf("unexpected notification on %q: %s", notifier.channel, notifier.payload)
+	}
+}
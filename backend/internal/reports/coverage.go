@@ -0,0 +1,107 @@
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// CoverageExportColumns are the per-control columns of a coverage export
+var CoverageExportColumns = []string{
+	"family", "control_ref", "title", "status", "linked_risks", "active_risks",
+}
+
+// coverageFamilyColumns are the columns of the families sheet of an XLSX
+// coverage export
+var coverageFamilyColumns = []string{
+	"family", "title", "controls", "linked", "unlinked", "inactive_only", "coverage_percent",
+}
+
+// CoverageFilename is the download name for a coverage export of the named
+// framework generated at t
+func CoverageFilename(framework string, t time.Time, format string) string {
+	return "coverage-" + filenameSlug(framework) + "-" + t.UTC().Format("2006-01-02") + "." + format
+}
+
+// WriteCoverageExport writes a coverage report as CSV or XLSX. The CSV has
+// one row per control; the XLSX also has a summary sheet with the totals and
+// one row per family.
+func WriteCoverageExport(w io.Writer, format string, coverage *models.FrameworkCoverage) error {
+	if format == "xlsx" {
+		return writeCoverageXLSX(w, coverage)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CoverageExportColumns); err != nil {
+		return err
+	}
+	for _, control := range coverage.Controls {
+		if err := cw.Write(coverageRecord(control)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func coverageRecord(control *models.CoverageControl) []string {
+	return []string{
+		cellText(control.Family), cellText(control.ControlRef), cellText(control.Title), string(control.Status),
+		strconv.Itoa(control.LinkedRiskCount), strconv.Itoa(control.ActiveRiskCount),
+	}
+}
+
+func writeCoverageXLSX(w io.Writer, coverage *models.FrameworkCoverage) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	summary := [][]any{
+		{"framework", cellText(coverage.FrameworkName)},
+		{"generated_at", coverage.GeneratedAt.UTC().Format(time.RFC3339)},
+		{"total_controls", coverage.TotalControls},
+		{"linked_controls", coverage.LinkedControls},
+		{"unlinked_controls", coverage.UnlinkedControls},
+		{"inactive_only_controls", coverage.InactiveOnlyControls},
+		{"coverage_percent", coverage.CoveragePercent},
+		{},
+		toRow(coverageFamilyColumns),
+	}
+	for _, f := range coverage.Families {
+		summary = append(summary, []any{cellText(f.Family), cellText(f.Title), f.Total, f.Linked, f.Unlinked, f.InactiveOnly, f.CoveragePercent})
+	}
+
+	controls := [][]any{toRow(CoverageExportColumns)}
+	for _, control := range coverage.Controls {
+		controls = append(controls, []any{
+			cellText(control.Family), cellText(control.ControlRef), cellText(control.Title), string(control.Status),
+			control.LinkedRiskCount, control.ActiveRiskCount,
+		})
+	}
+
+	file.SetSheetName(file.GetSheetName(0), "Summary")
+	if _, err := file.NewSheet("Controls"); err != nil {
+		return err
+	}
+	for sheet, rows := range map[string][][]any{"Summary": summary, "Controls": controls} {
+		for i, row := range rows {
+			if err := file.SetSheetRow(sheet, fmt.Sprintf("A%d", i+1), &row); err != nil {
+				return err
+			}
+		}
+	}
+	return file.Write(w)
+}
+
+func toRow(values []string) []any {
+	row := make([]any, len(values))
+	for i, v := range values {
+		row[i] = v
+	}
+	return row
+}
@@ -0,0 +1,220 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/mailer"
+	"backend/internal/models"
+)
+
+// DeliveryHour is the hour, in UTC, at which scheduled reports go out
+const DeliveryHour = 6
+
+// dueBatchSize caps how many subscriptions one scheduler tick sends; the
+// rest are picked up on the next tick
+const dueBatchSize = 100
+
+// NextRun returns the first scheduled send strictly after t: Monday morning
+// for weekly reports and the first of the month for monthly ones
+func NextRun(frequency models.ReportFrequency, t time.Time) time.Time {
+	t = t.UTC()
+	if frequency == models.ReportFrequencyMonthly {
+		next := time.Date(t.Year(), t.Month(), 1, DeliveryHour, 0, 0, 0, time.UTC)
+		if !next.After(t) {
+			next = next.AddDate(0, 1, 0)
+		}
+		return next
+	}
+
+	next := time.Date(t.Year(), t.Month(), t.Day(), DeliveryHour, 0, 0, 0, time.UTC)
+	next = next.AddDate(0, 0, (int(time.Monday)-int(next.Weekday())+7)%7)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// Deliverer renders a subscription's report and emails it to the subscriber
+type Deliverer struct {
+	generator     *Generator
+	exports       database.ExportRepository
+	users         database.UserRepository
+	subscriptions database.ReportSubscriptionRepository
+	mailer        mailer.Mailer
+}
+
+func NewDeliverer(generator *Generator, exports database.ExportRepository, users database.UserRepository,
+	subscriptions database.ReportSubscriptionRepository, m mailer.Mailer) *Deliverer {
+	return &Deliverer{generator: generator, exports: exports, users: users, subscriptions: subscriptions, mailer: m}
+}
+
+// Deliver sends sub's report now and records the attempt. A report that
+// can't be rendered or sent is recorded as a failed delivery rather than
+// returned as an error; err is only set when there is nothing to record.
+func (d *Deliverer) Deliver(ctx context.Context, sub *models.ReportSubscription, manual bool) (*models.ReportDelivery, error) {
+	user, err := d.users.FindByID(ctx, sub.UserID)
+	if err == nil && user == nil {
+		err = database.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("finding subscriber: %w", err)
+	}
+
+	now := time.Now()
+	delivery := &models.ReportDelivery{
+		SubscriptionID: sub.ID,
+		Recipient:      user.Email,
+		Status:         models.ReportDeliverySent,
+		Manual:         manual,
+	}
+
+	attachment, err := d.render(ctx, sub, now)
+	if err == nil {
+		delivery.Filename = attachment.Filename
+		delivery.SizeBytes = len(attachment.Data)
+		err = d.mailer.Send(ctx, &mailer.Message{
+			To:          []string{user.Email},
+			Subject:     reportSubject(sub),
+			Body:        reportBody(sub, user, now),
+			Attachments: []mailer.Attachment{*attachment},
+		})
+	}
+	if err != nil {
+		msg := err.Error()
+		delivery.Status = models.ReportDeliveryFailed
+		delivery.Error = &msg
+	}
+
+	if err := d.subscriptions.CreateDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("recording delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// render produces the report attachment in the subscription's format
+func (d *Deliverer) render(ctx context.Context, sub *models.ReportSubscription, now time.Time) (*mailer.Attachment, error) {
+	var buf bytes.Buffer
+	f := sub.Filters
+
+	if sub.Format == models.ReportFormatPDF {
+		sections, err := ParseSections(strings.Join(f.Sections, ","))
+		if err != nil {
+			return nil, err
+		}
+		opts := Options{
+			Title:       sub.Name,
+			Subtitle:    reportPeriod(sub.Frequency, now),
+			Cover:       true,
+			Sections:    sections,
+			Granularity: f.Granularity,
+			TopRisks:    f.TopRisks,
+			GeneratedAt: now,
+		}
+		if err := d.generator.GeneratePDF(ctx, opts, &buf); err != nil {
+			return nil, err
+		}
+		return &mailer.Attachment{Filename: ReportFilename(now), ContentType: "application/pdf", Data: buf.Bytes()}, nil
+	}
+
+	format := string(sub.Format)
+	contentType, ok := ExportContentType(format)
+	if !ok {
+		return nil, fmt.Errorf("unsupported report format %q", format)
+	}
+	params := &models.RiskListParams{Status: f.Status, Severity: f.Severity, CategoryID: f.CategoryID, Tag: f.Tag}
+	if err := WriteRiskExport(ctx, &buf, d.exports, format, params); err != nil {
+		return nil, err
+	}
+	return &mailer.Attachment{Filename: ExportFilename(now, format), ContentType: contentType, Data: buf.Bytes()}, nil
+}
+
+func reportPeriod(frequency models.ReportFrequency, now time.Time) string {
+	if frequency == models.ReportFrequencyMonthly {
+		return "Monthly report, " + now.UTC().Format("January 2006")
+	}
+	return "Weekly report, " + now.UTC().Format("2 January 2006")
+}
+
+func reportSubject(sub *models.ReportSubscription) string {
+	if sub.Frequency == models.ReportFrequencyMonthly {
+		return "Monthly risk report: " + sub.Name
+	}
+	return "Weekly risk report: " + sub.Name
+}
+
+func reportBody(sub *models.ReportSubscription, user *models.User, now time.Time) string {
+	return fmt.Sprintf("Hi %s,\n\nYour %s report %q is attached. It was generated on %s.\n\n"+
+		"You are receiving this because you subscribed to it in the Risk Register. "+
+		"You can change or cancel the subscription under report subscriptions.\n",
+		user.Name, sub.Frequency, sub.Name, now.UTC().Format("2 January 2006 at 15:04 MST"))
+}
+
+// Scheduler sends subscriptions' reports when they fall due
+type Scheduler struct {
+	subscriptions database.ReportSubscriptionRepository
+	deliverer     *Deliverer
+	interval      time.Duration
+}
+
+func NewScheduler(subscriptions database.ReportSubscriptionRepository, deliverer *Deliverer, interval time.Duration) *Scheduler {
+	return &Scheduler{subscriptions: subscriptions, deliverer: deliverer, interval: interval}
+}
+
+// Run checks for due reports every interval until ctx is cancelled. A zero
+// interval disables the scheduler.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunDue(ctx, time.Now()); err != nil {
+			log.Printf("report scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue sends every report due at now and returns how many were sent or
+// attempted. Each run is claimed before it is sent, so a report goes out at
+// most once per period even with several instances running; a failed send is
+// recorded in the delivery history and not retried until the next period.
+// Runs missed while the server was down collapse into a single send.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.subscriptions.ListDue(ctx, now, dueBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("listing due subscriptions: %w", err)
+	}
+
+	sent := 0
+	for _, sub := range due {
+		claimed, err := s.subscriptions.Advance(ctx, sub.ID, sub.NextRunAt, NextRun(sub.Frequency, now), now)
+		if err != nil {
+			return sent, fmt.Errorf("claiming subscription %s: %w", sub.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+		delivery, err := s.deliverer.Deliver(ctx, sub, false)
+		if err != nil {
+			log.Printf("report subscription %s: %v", sub.ID, err)
+			continue
+		}
+		if delivery.Status == models.ReportDeliveryFailed {
+			log.Printf("report subscription %s: delivery failed: %s", sub.ID, *delivery.Error)
+		}
+		sent++
+	}
+	return sent, nil
+}
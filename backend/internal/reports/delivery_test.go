@@ -0,0 +1,190 @@
+package reports
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/mailer"
+	"backend/internal/models"
+)
+
+type stubDashboard struct{}
+
+func (stubDashboard) GetSummary(ctx context.Context) (*models.DashboardSummaryResponse, error) {
+	return &models.DashboardSummaryResponse{BySeverity: map[string]int{}, ByStatus: map[string]int{}}, nil
+}
+func (stubDashboard) GetUpcomingReviews(ctx context.Context, days int) (*models.ReviewListResponse, error) {
+	return &models.ReviewListResponse{}, nil
+}
+func (stubDashboard) GetOverdueReviews(ctx context.Context) (*models.ReviewListResponse, error) {
+	return &models.ReviewListResponse{}, nil
+}
+func (stubDashboard) GetTopRisks(ctx context.Context, limit int) (*models.TopRiskListResponse, error) {
+	return &models.TopRiskListResponse{}, nil
+}
+
+type stubAnalytics struct{}
+
+func (stubAnalytics) GetAnalytics(ctx context.Context, granularity models.AnalyticsGranularity) (*models.AnalyticsResponse, error) {
+	return &models.AnalyticsResponse{}, nil
+}
+
+type stubExports struct {
+	params *models.RiskListParams
+}
+
+func (s *stubExports) EachRisk(ctx context.Context, params *models.RiskListParams, fn func(*models.RiskExportRow) error) error {
+	s.params = params
+	return fn(&models.RiskExportRow{ID: "risk-1", Title: "Vendor outage", Status: models.StatusOpen})
+}
+
+type stubUsers struct{}
+
+func (stubUsers) Create(ctx context.Context, user *models.User) error { return nil }
+func (stubUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return nil, database.ErrUserNotFound
+}
+func (stubUsers) FindByID(ctx context.Context, id string) (*models.User, error) {
+	if id != "user-1" {
+		return nil, database.ErrUserNotFound
+	}
+	return &models.User{ID: id, Email: "cro@example.com", Name: "Chief Risk Officer"}, nil
+}
+
+type stubSubscriptions struct {
+	database.ReportSubscriptionRepository
+	subs       map[string]*models.ReportSubscription
+	deliveries []*models.ReportDelivery
+}
+
+func (s *stubSubscriptions) ListDue(ctx context.Context, now time.Time, limit int) ([]*models.ReportSubscription, error) {
+	due := []*models.ReportSubscription{}
+	for _, sub := range s.subs {
+		if sub.Active && !sub.NextRunAt.After(now) {
+			c := *sub
+			due = append(due, &c)
+		}
+	}
+	return due, nil
+}
+
+func (s *stubSubscriptions) Advance(ctx context.Context, id string, from, next, ranAt time.Time) (bool, error) {
+	sub := s.subs[id]
+	if !sub.NextRunAt.Equal(from) {
+		return false, nil
+	}
+	sub.NextRunAt = next
+	sub.LastRunAt = &ranAt
+	return true, nil
+}
+
+func (s *stubSubscriptions) CreateDelivery(ctx context.Context, d *models.ReportDelivery) error {
+	s.deliveries = append(s.deliveries, d)
+	return nil
+}
+
+type recordingMailer struct {
+	sent []*mailer.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestNextRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		frequency models.ReportFrequency
+		from      time.Time
+		want      time.Time
+	}{
+		{"weekly from wednesday", models.ReportFrequencyWeekly, time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC), time.Date(2024, 6, 10, DeliveryHour, 0, 0, 0, time.UTC)},
+		{"weekly early monday", models.ReportFrequencyWeekly, time.Date(2024, 6, 10, 1, 0, 0, 0, time.UTC), time.Date(2024, 6, 10, DeliveryHour, 0, 0, 0, time.UTC)},
+		{"weekly at the slot", models.ReportFrequencyWeekly, time.Date(2024, 6, 10, DeliveryHour, 0, 0, 0, time.UTC), time.Date(2024, 6, 17, DeliveryHour, 0, 0, 0, time.UTC)},
+		{"monthly mid month", models.ReportFrequencyMonthly, time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, DeliveryHour, 0, 0, 0, time.UTC)},
+		{"monthly across the year", models.ReportFrequencyMonthly, time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, DeliveryHour, 0, 0, 0, time.UTC)},
+		{"monthly before the slot", models.ReportFrequencyMonthly, time.Date(2024, 7, 1, 2, 0, 0, 0, time.UTC), time.Date(2024, 7, 1, DeliveryHour, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextRun(tt.frequency, tt.from); !got.Equal(tt.want) {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	now := time.Date(2024, 6, 10, DeliveryHour, 5, 0, 0, time.UTC)
+	status := models.StatusOpen
+
+	newScheduler := func(m mailer.Mailer) (*Scheduler, *stubSubscriptions, *stubExports) {
+		subs := &stubSubscriptions{subs: map[string]*models.ReportSubscription{
+			"pdf": {ID: "pdf", UserID: "user-1", Name: "Board pack", Frequency: models.ReportFrequencyWeekly, Format: models.ReportFormatPDF,
+				Active: true, NextRunAt: now.Add(-5 * time.Minute)},
+			"csv": {ID: "csv", UserID: "user-1", Name: "Open risks", Frequency: models.ReportFrequencyMonthly, Format: models.ReportFormatCSV,
+				Filters: models.ReportFilters{Status: &status, Tag: "vendor"}, Active: true, NextRunAt: now.Add(-time.Hour)},
+			"later":    {ID: "later", UserID: "user-1", Name: "Later", Frequency: models.ReportFrequencyWeekly, Format: models.ReportFormatPDF, Active: true, NextRunAt: now.Add(time.Hour)},
+			"inactive": {ID: "inactive", UserID: "user-1", Name: "Paused", Frequency: models.ReportFrequencyWeekly, Format: models.ReportFormatPDF, NextRunAt: now.Add(-time.Hour)},
+		}}
+		exports := &stubExports{}
+		deliverer := NewDeliverer(NewGenerator(stubDashboard{}, stubAnalytics{}), exports, stubUsers{}, subs, m)
+		return NewScheduler(subs, deliverer, time.Minute), subs, exports
+	}
+
+	t.Run("sends due reports once", func(t *testing.T) {
+		m := &recordingMailer{}
+		scheduler, subs, exports := newScheduler(m)
+
+		sent, err := scheduler.RunDue(context.Background(), now)
+		if err != nil || sent != 2 {
+			t.Fatalf("expected 2 reports sent, got %d (%v)", sent, err)
+		}
+		if len(m.sent) != 2 || len(subs.deliveries) != 2 {
+			t.Fatalf("expected 2 messages and deliveries, got %d and %d", len(m.sent), len(subs.deliveries))
+		}
+		for _, msg := range m.sent {
+			if msg.To[0] != "cro@example.com" || len(msg.Attachments) != 1 || len(msg.Attachments[0].Data) == 0 {
+				t.Errorf("unexpected message: %+v", msg)
+			}
+		}
+		if exports.params == nil || *exports.params.Status != status || exports.params.Tag != "vendor" {
+			t.Errorf("expected the subscription filters to reach the export, got %+v", exports.params)
+		}
+		if next := subs.subs["pdf"].NextRunAt; !next.Equal(time.Date(2024, 6, 17, DeliveryHour, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected the weekly report to move to next monday, got %s", next)
+		}
+		if next := subs.subs["csv"].NextRunAt; !next.Equal(time.Date(2024, 7, 1, DeliveryHour, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected the monthly report to move to next month, got %s", next)
+		}
+
+		if sent, _ := scheduler.RunDue(context.Background(), now); sent != 0 {
+			t.Errorf("expected nothing due on a second run, got %d", sent)
+		}
+	})
+
+	t.Run("records failed sends", func(t *testing.T) {
+		scheduler, subs, _ := newScheduler(&recordingMailer{err: errors.New("connection refused")})
+
+		if _, err := scheduler.RunDue(context.Background(), now); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if len(subs.deliveries) != 2 {
+			t.Fatalf("expected 2 deliveries, got %d", len(subs.deliveries))
+		}
+		for _, d := range subs.deliveries {
+			if d.Status != models.ReportDeliveryFailed || d.Error == nil || !strings.Contains(*d.Error, "connection refused") {
+				t.Errorf("expected a failed delivery, got %+v", d)
+			}
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package reports
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// RiskExportColumns are the CSV and XLSX columns. The names match the import
+// field names so an export can be edited and imported again.
+var RiskExportColumns = []string{
+	"id", "title", "description", "owner_name", "owner_email", "status", "severity", "category",
+	"review_date", "tags", "mitigations", "controls", "created_at", "updated_at",
+}
+
+// exportFlushEvery is how many rows are buffered before they are flushed to
+// the client; a failed flush means the client went away and stops the query
+const exportFlushEvery = 100
+
+var exportContentTypes = map[string]string{
+	"csv":  "text/csv; charset=utf-8",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"json": "application/json; charset=utf-8",
+}
+
+// ExportContentType returns the MIME type of a risk export format, and
+// whether the format is supported
+func ExportContentType(format string) (string, bool) {
+	contentType, ok := exportContentTypes[format]
+	return contentType, ok
+}
+
+// ExportFilename is the download name for a risk export generated at t
+func ExportFilename(t time.Time, format string) string {
+	return "risks-" + t.UTC().Format("2006-01-02") + "." + format
+}
+
+// filenameSlug turns a name into lowercase words joined by dashes for use in
+// a download name
+func filenameSlug(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "export"
+	}
+	return strings.Join(words, "-")
+}
+
+// flusher is implemented by buffered writers such as bufio.Writer
+type flusher interface {
+	Flush() error
+}
+
+// riskExportEncoder writes an export in one format, one row at a time
+type riskExportEncoder interface {
+	row(row *models.RiskExportRow) error
+	// finish writes anything that has to follow the last row
+	finish() error
+}
+
+// WriteRiskExport writes every risk matching params to w as CSV, XLSX or
+// JSON. If w can be flushed it is flushed every few rows, so rows reach the
+// client while the query is still running.
+func WriteRiskExport(ctx context.Context, w io.Writer, exports database.ExportRepository, format string, params *models.RiskListParams) error {
+	var enc riskExportEncoder
+	var err error
+	switch format {
+	case "csv":
+		enc, err = newCSVRiskExport(w)
+	case "xlsx":
+		enc, err = newXLSXRiskExport(w)
+	default:
+		enc, err = newJSONRiskExport(w)
+	}
+	if closer, ok := enc.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	flush := func() error { return nil }
+	if f, ok := w.(flusher); ok {
+		flush = f.Flush
+	}
+
+	n := 0
+	err = exports.EachRisk(ctx, params, func(row *models.RiskExportRow) error {
+		if err := enc.row(row); err != nil {
+			return err
+		}
+		n++
+		if n%exportFlushEvery == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := enc.finish(); err != nil {
+		return err
+	}
+	return flush()
+}
+
+// riskExportRecord flattens a row into the CSV and XLSX columns
+func riskExportRecord(row *models.RiskExportRow) []string {
+	mitigations := make([]string, len(row.Mitigations))
+	for i, m := range row.Mitigations {
+		details := []string{string(m.Status)}
+		if m.Owner != "" {
+			details = append(details, "owner: "+m.Owner)
+		}
+		if m.DueDate != nil {
+			details = append(details, "due: "+*m.DueDate)
+		}
+		mitigations[i] = m.Description + " (" + strings.Join(details, ", ") + ")"
+	}
+	controls := make([]string, len(row.Controls))
+	for i, ctl := range row.Controls {
+		controls[i] = ctl.Framework + " " + ctl.ControlRef
+	}
+	reviewDate := ""
+	if row.ReviewDate != nil {
+		reviewDate = row.ReviewDate.Format("2006-01-02")
+	}
+
+	return []string{
+		row.ID, cellText(row.Title), cellText(row.Description), cellText(row.OwnerName), cellText(row.OwnerEmail),
+		string(row.Status), string(row.Severity), cellText(row.Category), reviewDate, cellText(strings.Join(row.Tags, "; ")),
+		cellText(strings.Join(mitigations, "\n")), cellText(strings.Join(controls, "; ")), row.CreatedAt.UTC().Format(time.RFC3339), row.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// cellText keeps user-entered text from being read as a formula when an
+// export is opened in a spreadsheet, by prefixing a quote to values that
+// start with a formula trigger
+func cellText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type csvRiskExport struct {
+	w *csv.Writer
+}
+
+func newCSVRiskExport(w io.Writer) (riskExportEncoder, error) {
+	enc := &csvRiskExport{w: csv.NewWriter(w)}
+	return enc, enc.w.Write(RiskExportColumns)
+}
+
+func (e *csvRiskExport) row(row *models.RiskExportRow) error {
+	return e.w.Write(riskExportRecord(row))
+}
+
+func (e *csvRiskExport) finish() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type jsonRiskExport struct {
+	w     io.Writer
+	enc   *json.Encoder
+	count int
+}
+
+// newJSONRiskExport writes a JSON array element by element rather than
+// marshalling a slice of every row
+func newJSONRiskExport(w io.Writer) (riskExportEncoder, error) {
+	_, err := io.WriteString(w, "[")
+	return &jsonRiskExport{w: w, enc: json.NewEncoder(w)}, err
+}
+
+func (e *jsonRiskExport) row(row *models.RiskExportRow) error {
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	return e.enc.Encode(row)
+}
+
+func (e *jsonRiskExport) finish() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+// xlsxRiskExport uses excelize's stream writer, which spills rows to a
+// temporary file instead of building the sheet in memory. The workbook itself
+// can only be written once the last row is in.
+type xlsxRiskExport struct {
+	out   io.Writer
+	file  *excelize.File
+	sheet *excelize.StreamWriter
+	next  int
+}
+
+func newXLSXRiskExport(w io.Writer) (riskExportEncoder, error) {
+	file := excelize.NewFile()
+	file.SetSheetName(file.GetSheetName(0), "Risks")
+	sheet, err := file.NewStreamWriter("Risks")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	enc := &xlsxRiskExport{out: w, file: file, sheet: sheet, next: 1}
+	header := make([]any, len(RiskExportColumns))
+	for i, col := range RiskExportColumns {
+		header[i] = col
+	}
+	return enc, enc.writeRow(header)
+}
+
+func (e *xlsxRiskExport) writeRow(values []any) error {
+	cell, err := excelize.CoordinatesToCellName(1, e.next)
+	if err != nil {
+		return err
+	}
+	e.next++
+	return e.sheet.SetRow(cell, values)
+}
+
+func (e *xlsxRiskExport) row(row *models.RiskExportRow) error {
+	record := riskExportRecord(row)
+	values := make([]any, len(record))
+	for i, v := range record {
+		values[i] = v
+	}
+	return e.writeRow(values)
+}
+
+func (e *xlsxRiskExport) finish() error {
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.out)
+}
+
+// Close removes the temporary files backing the workbook
+func (e *xlsxRiskExport) Close() error {
+	return e.file.Close()
+}
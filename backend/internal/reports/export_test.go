@@ -0,0 +1,89 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+type stubExportRepo struct {
+	rows []*models.RiskExportRow
+}
+
+func (r *stubExportRepo) EachRisk(ctx context.Context, params *models.RiskListParams, fn func(*models.RiskExportRow) error) error {
+	for _, row := range r.rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestWriteRiskExport_NeutralisesFormulas(t *testing.T) {
+	created := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &stubExportRepo{rows: []*models.RiskExportRow{{
+		ID:          "1",
+		Title:       `=HYPERLINK("http://evil.example","Click")`,
+		Description: "-2+3",
+		OwnerName:   "@admin",
+		Status:      models.StatusOpen,
+		Severity:    models.SeverityHigh,
+		Tags:        []string{"+cmd"},
+		Mitigations: []models.RiskExportMitigation{{Description: "Patch servers", Status: models.MitigationStatusPlanned}},
+		CreatedAt:   created,
+		UpdatedAt:   created,
+	}}}
+	want := map[string]string{
+		"title":       `'=HYPERLINK("http://evil.example","Click")`,
+		"description": "'-2+3",
+		"owner_name":  "'@admin",
+		"tags":        "'+cmd",
+		"mitigations": "Patch servers (planned)",
+	}
+	check := func(t *testing.T, records [][]string) {
+		t.Helper()
+		if len(records) != 2 {
+			t.Fatalf("expected header and one row, got %d rows", len(records))
+		}
+		for i, col := range records[0] {
+			if expected, ok := want[col]; ok && records[1][i] != expected {
+				t.Errorf("%s: expected %q, got %q", col, expected, records[1][i])
+			}
+		}
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteRiskExport(context.Background(), &buf, repo, "csv", &models.RiskListParams{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		check(t, records)
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteRiskExport(context.Background(), &buf, repo, "xlsx", &models.RiskListParams{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		file, err := excelize.OpenReader(&buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer file.Close()
+		records, err := file.GetRows("Risks")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		check(t, records)
+	})
+}
@@ -0,0 +1,407 @@
+package reports
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Layout in millimetres on A4 portrait
+const (
+	pageMargin   = 18.0
+	contentWidth = 210.0 - 2*pageMargin
+	rowHeight    = 7.0
+)
+
+// Known enum values are listed in a fixed order so reports read the same
+// quarter to quarter; anything unexpected is appended alphabetically
+var (
+	severityOrder = []string{"critical", "high", "medium", "low"}
+	statusOrder   = []string{"open", "mitigating", "resolved", "accepted"}
+)
+
+// pdfReport wraps the document with the drawing helpers the sections share
+type pdfReport struct {
+	pdf  *fpdf.Fpdf
+	opts Options
+	// tr converts UTF-8 text to the code page of the built-in fonts
+	tr func(string) string
+}
+
+// RenderPDF writes a report to w using only the PDF core fonts, so no font
+// files or external renderer are needed
+func RenderPDF(w io.Writer, opts Options, data *Data) error {
+	opts = opts.withDefaults()
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	pdf.SetCreationDate(opts.GeneratedAt)
+	pdf.SetModificationDate(opts.GeneratedAt)
+	pdf.SetCatalogSort(true)
+
+	r := &pdfReport{pdf: pdf, opts: opts, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	pdf.SetTitle(r.tr(opts.Title), false)
+	pdf.SetCreator("Risk Register", false)
+	if opts.PreparedBy != "" {
+		pdf.SetAuthor(r.tr(opts.PreparedBy), false)
+	}
+	pdf.SetFooterFunc(r.footer)
+
+	if opts.Cover {
+		r.cover()
+	}
+	for _, section := range opts.Sections {
+		pdf.AddPage()
+		switch section {
+		case SectionSummary:
+			r.summary(data.Summary)
+		case SectionTrends:
+			r.trends(data.Analytics)
+		case SectionTopRisks:
+			r.topRisks(data.TopRisks)
+		case SectionOverdueReviews:
+			r.overdueReviews(data.Overdue)
+		}
+	}
+	if pdf.PageCount() == 0 {
+		pdf.AddPage()
+	}
+
+	return pdf.Output(w)
+}
+
+func (r *pdfReport) cover() {
+	pdf := r.pdf
+	pdf.AddPage()
+
+	pdf.SetFillColor(31, 56, 100)
+	pdf.Rect(0, 0, 210, 8, "F")
+
+	pdf.SetY(90)
+	pdf.SetFont("Helvetica", "B", 28)
+	pdf.SetTextColor(31, 56, 100)
+	pdf.MultiCell(contentWidth, 12, r.tr(r.opts.Title), "", "L", false)
+
+	if r.opts.Subtitle != "" {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 16)
+		pdf.SetTextColor(90, 90, 90)
+		pdf.MultiCell(contentWidth, 8, r.tr(r.opts.Subtitle), "", "L", false)
+	}
+
+	pdf.Ln(10)
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.SetTextColor(60, 60, 60)
+	pdf.CellFormat(contentWidth, 6, "Generated "+r.opts.GeneratedAt.UTC().Format("2 January 2006 15:04 MST"), "", 1, "L", false, 0, "")
+	if r.opts.PreparedBy != "" {
+		pdf.CellFormat(contentWidth, 6, r.tr("Prepared by "+r.opts.PreparedBy), "", 1, "L", false, 0, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// footer numbers every page except the cover
+func (r *pdfReport) footer() {
+	pdf := r.pdf
+	page := pdf.PageNo()
+	if r.opts.Cover {
+		if page == 1 {
+			return
+		}
+		page--
+	}
+	pdf.SetY(-12)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.CellFormat(contentWidth/2, 5, r.tr(r.opts.Title), "", 0, "L", false, 0, "")
+	pdf.CellFormat(contentWidth/2, 5, "Page "+strconv.Itoa(page), "", 0, "R", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+}
+
+func (r *pdfReport) summary(summary *models.DashboardSummaryResponse) {
+	r.heading("Executive summary")
+	if summary == nil {
+		r.note("Summary data is unavailable.")
+		return
+	}
+
+	r.figures([][2]string{
+		{"Total risks", strconv.Itoa(summary.TotalRisks)},
+		{"Overdue reviews", strconv.Itoa(summary.OverdueReviews)},
+		{"Overdue control tests", strconv.Itoa(summary.OverdueControlTests)},
+		{"Overdue evidence", strconv.Itoa(summary.OverdueEvidenceRequests)},
+		{"Critical or high", strconv.Itoa(summary.BySeverity["critical"] + summary.BySeverity["high"])},
+	})
+
+	r.subheading("By severity")
+	r.distribution("Severity", orderedCounts(summary.BySeverity, severityOrder))
+
+	r.subheading("By status")
+	r.distribution("Status", orderedCounts(summary.ByStatus, statusOrder))
+
+	r.subheading("By category")
+	categories := make([]labelCount, len(summary.ByCategory))
+	for i, c := range summary.ByCategory {
+		categories[i] = labelCount{c.CategoryName, c.Count}
+	}
+	r.distribution("Category", categories)
+}
+
+func (r *pdfReport) trends(analytics *models.AnalyticsResponse) {
+	r.heading("Trends")
+	if analytics == nil {
+		r.note("Trend data is unavailable.")
+		return
+	}
+
+	period := "month"
+	if r.opts.Granularity == models.GranularityWeekly {
+		period = "week"
+	}
+
+	r.subheading("Risks created per " + period)
+	created := make([]labelCount, len(analytics.CreatedOverTime))
+	for i, p := range analytics.CreatedOverTime {
+		created[i] = labelCount{p.Period, p.Count}
+	}
+	r.distribution("Period", created)
+
+	r.subheading("Opened and closed per " + period)
+	if len(analytics.StatusOverTime) == 0 {
+		r.note("No activity in this period.")
+		return
+	}
+	rows := make([][]string, len(analytics.StatusOverTime))
+	for i, p := range analytics.StatusOverTime {
+		rows[i] = []string{p.Period, strconv.Itoa(p.Open), strconv.Itoa(p.Closed), signed(p.Open - p.Closed)}
+	}
+	r.table([]string{"Period", "Opened", "Closed", "Net change"}, []float64{70, 34, 34, 36}, rows)
+}
+
+func (r *pdfReport) topRisks(risks []models.TopRisk) {
+	r.heading("Top risks by severity")
+	if len(risks) == 0 {
+		r.note("There are no open risks.")
+		return
+	}
+
+	rows := make([][]string, len(risks))
+	for i, risk := range risks {
+		review := "-"
+		if risk.ReviewDate != nil {
+			review = risk.ReviewDate.Format("2006-01-02")
+		}
+		rows[i] = []string{risk.Title, risk.Severity, risk.Status, risk.OwnerName, risk.Category, review}
+	}
+	r.table([]string{"Risk", "Severity", "Status", "Owner", "Category", "Review"}, []float64{56, 18, 22, 28, 26, 24}, rows)
+}
+
+func (r *pdfReport) overdueReviews(risks []models.ReviewRisk) {
+	r.heading("Overdue reviews")
+	if len(risks) == 0 {
+		r.note("No reviews are overdue.")
+		return
+	}
+
+	rows := make([][]string, len(risks))
+	for i, risk := range risks {
+		days := int(r.opts.GeneratedAt.Sub(risk.ReviewDate).Hours() / 24)
+		rows[i] = []string{risk.Title, risk.Severity, risk.Status, risk.ReviewDate.Format("2006-01-02"), strconv.Itoa(days)}
+	}
+	r.table([]string{"Risk", "Severity", "Status", "Review due", "Days overdue"}, []float64{74, 22, 24, 28, 26}, rows)
+}
+
+func (r *pdfReport) heading(text string) {
+	r.pdf.SetFont("Helvetica", "B", 18)
+	r.pdf.SetTextColor(31, 56, 100)
+	r.pdf.CellFormat(contentWidth, 10, r.tr(text), "", 1, "L", false, 0, "")
+	r.pdf.SetTextColor(0, 0, 0)
+	r.pdf.Ln(2)
+}
+
+func (r *pdfReport) subheading(text string) {
+	r.ensureSpace(rowHeight * 3)
+	r.pdf.Ln(3)
+	r.pdf.SetFont("Helvetica", "B", 12)
+	r.pdf.CellFormat(contentWidth, 8, r.tr(text), "", 1, "L", false, 0, "")
+}
+
+func (r *pdfReport) note(text string) {
+	r.pdf.SetFont("Helvetica", "I", 10)
+	r.pdf.SetTextColor(100, 100, 100)
+	r.pdf.CellFormat(contentWidth, rowHeight, r.tr(text), "", 1, "L", false, 0, "")
+	r.pdf.SetTextColor(0, 0, 0)
+}
+
+// figures draws headline numbers side by side
+func (r *pdfReport) figures(items [][2]string) {
+	pdf := r.pdf
+	width := contentWidth / float64(len(items))
+	x, y := pdf.GetX(), pdf.GetY()
+	pdf.SetFillColor(238, 242, 248)
+	for i, item := range items {
+		left := x + float64(i)*width
+		pdf.Rect(left, y, width-3, 22, "F")
+		pdf.SetXY(left+3, y+3)
+		pdf.SetFont("Helvetica", "B", 18)
+		pdf.CellFormat(width-9, 9, item[1], "", 2, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(width-9, 6, r.tr(item[0]), "", 0, "L", false, 0, "")
+	}
+	pdf.SetXY(x, y+26)
+}
+
+// distribution draws a count table with a proportional bar per row
+func (r *pdfReport) distribution(label string, counts []labelCount) {
+	if len(counts) == 0 {
+		r.note("No data.")
+		return
+	}
+
+	max := 0
+	for _, c := range counts {
+		if c.count > max {
+			max = c.count
+		}
+	}
+
+	const labelWidth, countWidth = 60.0, 20.0
+	barWidth := contentWidth - labelWidth - countWidth
+	r.tableHeader([]string{label, "Risks", ""}, []float64{labelWidth, countWidth, barWidth})
+	for i, c := range counts {
+		if r.ensureSpace(rowHeight) {
+			r.tableHeader([]string{label, "Risks", ""}, []float64{labelWidth, countWidth, barWidth})
+		}
+		r.rowFill(i)
+		pdf := r.pdf
+		x, y := pdf.GetX(), pdf.GetY()
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.CellFormat(labelWidth, rowHeight, r.fit(c.label, labelWidth), "", 0, "L", true, 0, "")
+		pdf.CellFormat(countWidth, rowHeight, strconv.Itoa(c.count), "", 0, "R", true, 0, "")
+		pdf.CellFormat(barWidth, rowHeight, "", "", 1, "L", true, 0, "")
+		if max > 0 && c.count > 0 {
+			pdf.SetFillColor(70, 110, 170)
+			length := math.Max(0.5, (barWidth-6)*float64(c.count)/float64(max))
+			pdf.Rect(x+labelWidth+countWidth+3, y+1.5, length, rowHeight-3, "F")
+		}
+	}
+}
+
+// table draws rows under a header, repeating the header after page breaks
+func (r *pdfReport) table(headers []string, widths []float64, rows [][]string) {
+	r.tableHeader(headers, widths)
+	r.pdf.SetFont("Helvetica", "", 9)
+	for i, row := range rows {
+		if r.ensureSpace(rowHeight) {
+			r.tableHeader(headers, widths)
+			r.pdf.SetFont("Helvetica", "", 9)
+		}
+		r.rowFill(i)
+		for j, cell := range row {
+			align := "L"
+			if _, err := strconv.Atoi(cell); err == nil {
+				align = "R"
+			}
+			ln := 0
+			if j == len(row)-1 {
+				ln = 1
+			}
+			r.pdf.CellFormat(widths[j], rowHeight, r.fit(cell, widths[j]), "", ln, align, true, 0, "")
+		}
+	}
+}
+
+func (r *pdfReport) tableHeader(headers []string, widths []float64) {
+	r.ensureSpace(rowHeight * 2)
+	pdf := r.pdf
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(31, 56, 100)
+	pdf.SetTextColor(255, 255, 255)
+	for i, h := range headers {
+		ln := 0
+		if i == len(headers)-1 {
+			ln = 1
+		}
+		pdf.CellFormat(widths[i], rowHeight, r.tr(h), "", ln, "L", true, 0, "")
+	}
+	pdf.SetTextColor(0, 0, 0)
+}
+
+// rowFill shades alternate rows
+func (r *pdfReport) rowFill(i int) {
+	if i%2 == 0 {
+		r.pdf.SetFillColor(255, 255, 255)
+	} else {
+		r.pdf.SetFillColor(244, 246, 250)
+	}
+}
+
+// ensureSpace starts a new page if height won't fit on this one, and reports
+// whether it did
+func (r *pdfReport) ensureSpace(height float64) bool {
+	_, pageHeight := r.pdf.GetPageSize()
+	if r.pdf.GetY()+height <= pageHeight-pageMargin {
+		return false
+	}
+	r.pdf.AddPage()
+	return true
+}
+
+// fit translates text and shortens it with an ellipsis to fit a cell
+func (r *pdfReport) fit(text string, width float64) string {
+	text = r.tr(text)
+	max := width - 2*r.pdf.GetCellMargin()
+	if r.pdf.GetStringWidth(text) <= max {
+		return text
+	}
+	for len(text) > 0 && r.pdf.GetStringWidth(text+"...") > max {
+		text = text[:len(text)-1]
+	}
+	return text + "..."
+}
+
+type labelCount struct {
+	label string
+	count int
+}
+
+// orderedCounts lists counts in the given key order, then any other keys
+// alphabetically
+func orderedCounts(counts map[string]int, order []string) []labelCount {
+	result := []labelCount{}
+	seen := make(map[string]bool)
+	for _, key := range order {
+		seen[key] = true
+		result = append(result, labelCount{key, counts[key]})
+	}
+	var extra []string
+	for key := range counts {
+		if !seen[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		result = append(result, labelCount{key, counts[key]})
+	}
+	return result
+}
+
+func signed(n int) string {
+	if n > 0 {
+		return fmt.Sprintf("+%d", n)
+	}
+	return strconv.Itoa(n)
+}
+
+// ReportFilename is the download name for a report generated at t
+func ReportFilename(t time.Time) string {
+	return "risk-report-" + t.UTC().Format("2006-01-02") + ".pdf"
+}
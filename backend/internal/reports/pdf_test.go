@@ -0,0 +1,78 @@
+package reports
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"backend/internal/models"
+)
+
+func TestParseSections(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []Section
+		wantErr bool
+	}{
+		{"empty selects all", "", AllSections, false},
+		{"reordered and repeated", "overdue_reviews, summary,summary", []Section{SectionSummary, SectionOverdueReviews}, false},
+		{"unknown section", "summary,appendix", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSections(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	review := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	data := &Data{
+		Summary: &models.DashboardSummaryResponse{
+			TotalRisks:     3,
+			BySeverity:     map[string]int{"critical": 1, "high": 2},
+			ByStatus:       map[string]int{"open": 3},
+			ByCategory:     []models.CategoryCount{{CategoryID: "1", CategoryName: "Sécurité", Count: 3}},
+			OverdueReviews: 1,
+		},
+		Analytics: &models.AnalyticsResponse{
+			CreatedOverTime: []models.TimeDataPoint{{Period: "2024-01", Count: 3}},
+			StatusOverTime:  []models.StatusTimeDataPoint{{Period: "2024-01", Open: 3, Closed: 1}},
+		},
+		TopRisks: []models.TopRisk{{ID: "1", Title: "A very long risk title that will not fit in the table column at all", Severity: "critical", Status: "open", ReviewDate: &review}},
+		Overdue:  []models.ReviewRisk{{ID: "1", Title: "Vendor outage", Severity: "high", Status: "open", ReviewDate: review}},
+	}
+
+	t.Run("all sections with cover", func(t *testing.T) {
+		var buf bytes.Buffer
+		opts := Options{Cover: true, Subtitle: "Q1 board pack", PreparedBy: "cro@example.com", GeneratedAt: review.AddDate(0, 1, 0)}
+		if err := RenderPDF(&buf, opts, data); err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+			t.Error("expected a PDF document")
+		}
+	})
+
+	t.Run("empty sections", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := RenderPDF(&buf, Options{}, &Data{}); err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+			t.Error("expected a PDF document")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+// Package reports renders the risk register into documents for people who
+// don't use the app, such as the quarterly board pack.
+package reports
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+// Section is one optional part of a risk report
+type Section string
+
+const (
+	SectionSummary        Section = "summary"
+	SectionTrends         Section = "trends"
+	SectionTopRisks       Section = "top_risks"
+	SectionOverdueReviews Section = "overdue_reviews"
+)
+
+// AllSections lists every section in the order they appear in a report
+var AllSections = []Section{SectionSummary, SectionTrends, SectionTopRisks, SectionOverdueReviews}
+
+const (
+	DefaultTitle    = "Risk Report"
+	DefaultTopRisks = 10
+	MaxTopRisks     = 50
+)
+
+// Options controls what goes into a report
+type Options struct {
+	Title       string
+	Subtitle    string
+	Cover       bool
+	Sections    []Section
+	Granularity models.AnalyticsGranularity
+	TopRisks    int
+	PreparedBy  string
+	GeneratedAt time.Time
+}
+
+// withDefaults fills in anything left unset
+func (o Options) withDefaults() Options {
+	if o.Title == "" {
+		o.Title = DefaultTitle
+	}
+	if len(o.Sections) == 0 {
+		o.Sections = AllSections
+	}
+	if o.Granularity == "" {
+		o.Granularity = models.GranularityMonthly
+	}
+	if o.TopRisks <= 0 {
+		o.TopRisks = DefaultTopRisks
+	}
+	if o.GeneratedAt.IsZero() {
+		o.GeneratedAt = time.Now()
+	}
+	return o
+}
+
+func (o Options) has(section Section) bool {
+	for _, s := range o.Sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseSections reads a comma-separated section list. An empty list selects
+// every section. Sections are returned in report order, without repeats.
+func ParseSections(value string) ([]Section, error) {
+	if strings.TrimSpace(value) == "" {
+		return AllSections, nil
+	}
+
+	requested := make(map[Section]bool)
+	for _, name := range strings.Split(value, ",") {
+		section := Section(strings.TrimSpace(name))
+		known := false
+		for _, s := range AllSections {
+			if s == section {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown report section %q", section)
+		}
+		requested[section] = true
+	}
+
+	sections := []Section{}
+	for _, s := range AllSections {
+		if requested[s] {
+			sections = append(sections, s)
+		}
+	}
+	return sections, nil
+}
+
+// Data is everything a report shows. Fields for sections that were not
+// requested are left nil.
+type Data struct {
+	Summary   *models.DashboardSummaryResponse
+	Analytics *models.AnalyticsResponse
+	TopRisks  []models.TopRisk
+	Overdue   []models.ReviewRisk
+}
+
+// Generator gathers report data from the same queries that back the
+// dashboard and analytics pages, so the report always matches the app
+type Generator struct {
+	dashboard database.DashboardRepository
+	analytics database.AnalyticsRepository
+}
+
+func NewGenerator(dashboard database.DashboardRepository, analytics database.AnalyticsRepository) *Generator {
+	return &Generator{dashboard: dashboard, analytics: analytics}
+}
+
+// Collect fetches the data for the requested sections
+func (g *Generator) Collect(ctx context.Context, opts Options) (*Data, error) {
+	opts = opts.withDefaults()
+	data := &Data{}
+
+	if opts.has(SectionSummary) {
+		summary, err := g.dashboard.GetSummary(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching summary: %w", err)
+		}
+		data.Summary = summary
+	}
+	if opts.has(SectionTrends) {
+		analytics, err := g.analytics.GetAnalytics(ctx, opts.Granularity)
+		if err != nil {
+			return nil, fmt.Errorf("fetching analytics: %w", err)
+		}
+		data.Analytics = analytics
+	}
+	if opts.has(SectionTopRisks) {
+		top, err := g.dashboard.GetTopRisks(ctx, opts.TopRisks)
+		if err != nil {
+			return nil, fmt.Errorf("fetching top risks: %w", err)
+		}
+		data.TopRisks = top.Risks
+	}
+	if opts.has(SectionOverdueReviews) {
+		overdue, err := g.dashboard.GetOverdueReviews(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetching overdue reviews: %w", err)
+		}
+		data.Overdue = overdue.Risks
+	}
+	return data, nil
+}
+
+// GeneratePDF collects the report data and writes it to w as a PDF
+func (g *Generator) GeneratePDF(ctx context.Context, opts Options, w io.Writer) error {
+	data, err := g.Collect(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return RenderPDF(w, opts, data)
+}
@@ -0,0 +1,115 @@
+package reports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"backend/internal/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SoAExportColumns are the per-control columns of an SoA export
+var SoAExportColumns = []string{
+	"family", "control_ref", "title", "applicability", "justification", "implementation_status", "owner", "linked_risks",
+}
+
+// SoAFilename is the download name for an SoA export. Version 0 is the
+// working SoA, which is named by date instead.
+func SoAFilename(framework string, version int, t time.Time, format string) string {
+	name := "soa-" + filenameSlug(framework)
+	if version > 0 {
+		return name + "-v" + strconv.Itoa(version) + "." + format
+	}
+	return name + "-draft-" + t.UTC().Format("2006-01-02") + "." + format
+}
+
+// WriteSoAExport writes an SoA as CSV or XLSX. The CSV has one row per
+// control; the XLSX adds a cover sheet with the version and its sign-off.
+// The working SoA is passed as a version numbered 0 with status draft.
+func WriteSoAExport(w io.Writer, format string, soa *models.SoAVersion) error {
+	if format == "xlsx" {
+		return writeSoAXLSX(w, soa)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(SoAExportColumns); err != nil {
+		return err
+	}
+	for _, entry := range soa.Entries {
+		if err := cw.Write(soaRecord(entry)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func soaRecord(entry *models.SoAEntry) []string {
+	applicability := string(entry.Applicability)
+	if !entry.Assessed {
+		applicability = "not_assessed"
+	}
+	return []string{
+		cellText(entry.Family), cellText(entry.ControlRef), cellText(entry.Title), applicability, cellText(entry.Justification),
+		string(entry.ImplementationStatus), cellText(entry.OwnerName), strconv.Itoa(entry.LinkedRiskCount),
+	}
+}
+
+func writeSoAXLSX(w io.Writer, soa *models.SoAVersion) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	version := "working draft"
+	if soa.Version > 0 {
+		version = strconv.Itoa(soa.Version)
+	}
+	cover := [][]any{
+		{"Statement of Applicability", cellText(soa.FrameworkName)},
+		{"version", version},
+		{"title", cellText(soa.Title)},
+		{"status", string(soa.Status)},
+		{"created_at", soa.CreatedAt.UTC().Format(time.RFC3339)},
+		{"created_by", cellText(soa.CreatedByName)},
+	}
+	if soa.DecidedAt != nil {
+		cover = append(cover,
+			[]any{"decided_by", cellText(soa.DecidedByName)},
+			[]any{"decided_at", soa.DecidedAt.UTC().Format(time.RFC3339)},
+			[]any{"decision_comment", cellText(soa.DecisionComment)},
+		)
+	}
+	cover = append(cover,
+		[]any{},
+		[]any{"controls", soa.Summary.Total},
+		[]any{"applicable", soa.Summary.Applicable},
+		[]any{"excluded", soa.Summary.Excluded},
+		[]any{"not_assessed", soa.Summary.Unassessed},
+	)
+	for _, status := range []models.ImplementationStatus{
+		models.ImplementationImplemented, models.ImplementationPartial, models.ImplementationPlanned, models.ImplementationNotImplemented,
+	} {
+		cover = append(cover, []any{string(status), soa.Summary.Implementation[status]})
+	}
+
+	entries := [][]any{toRow(SoAExportColumns)}
+	for _, entry := range soa.Entries {
+		entries = append(entries, toRow(soaRecord(entry)))
+	}
+
+	file.SetSheetName(file.GetSheetName(0), "Cover")
+	if _, err := file.NewSheet("Controls"); err != nil {
+		return err
+	}
+	for sheet, rows := range map[string][][]any{"Cover": cover, "Controls": entries} {
+		for i, row := range rows {
+			if err := file.SetSheetRow(sheet, fmt.Sprintf("A%d", i+1), &row); err != nil {
+				return err
+			}
+		}
+	}
+	return file.Write(w)
+}
@@ -12,7 +12,8 @@ func (s *FiberServer) RegisterFiberRoutes() {
 	s.App.Use(cors.New(cors.Config{
 		AllowOrigins:     "*",
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
-		AllowHeaders:     "Accept,Authorization,Content-Type",
+		AllowHeaders:     "Accept,Authorization,Content-Type,If-Match,Last-Event-ID",
+		ExposeHeaders:    "ETag",
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
@@ -26,58 +27,241 @@ func (s *FiberServer) RegisterFiberRoutes() {
 	auth.Post("/register", s.auth.Register)
 	auth.Post("/login", s.auth.Login)
 
+	// Alert ingestion, authenticated with an alert source token instead of a
+	// user session. Registered before the protected group so its middleware
+	// does not run for these paths.
+	alertIngest := s.App.Group("/api/v1/alerts")
+	alertIngest.Post("/alertmanager", s.alertHandler.Alertmanager)
+	alertIngest.Post("/generic", s.alertHandler.Generic)
+
+	// Change event stream. EventSource can't send headers, so the token may
+	// also come as ?access_token=; registered before the protected group
+	// for that reason.
+	s.App.Get("/api/v1/events", middleware.QueryToken, middleware.AuthMiddleware, s.streamHandler.Stream)
+
+	// Calendar feeds. Calendar clients can't send a session token, so the
+	// feed token in the path authenticates instead.
+	s.App.Get("/api/v1/calendar/:token.ics", s.calendarHandler.Feed)
+
 	// Protected routes
 	protected := s.App.Group("/api/v1", middleware.AuthMiddleware)
 	protected.Get("/auth/me", s.auth.Me)
 
+	// The current user's notification inbox and preferences
+	notifications := protected.Group("/notifications")
+	notifications.Get("/", s.notificationHandler.List)
+	notifications.Post("/read-all", s.notificationHandler.MarkAllRead)
+	notifications.Get("/preferences", s.notificationHandler.Preferences)
+	notifications.Put("/preferences", s.notificationHandler.UpdatePreferences)
+	notifications.Post("/:id/read", s.notificationHandler.MarkRead)
+
+	// The current user's calendar feed
+	calendarFeed := protected.Group("/calendar/feed")
+	calendarFeed.Get("/", s.calendarHandler.GetFeed)
+	calendarFeed.Post("/", s.calendarHandler.CreateFeed)
+	calendarFeed.Delete("/", s.calendarHandler.DeleteFeed)
+
+	// Team routes
+	teams := protected.Group("/teams")
+	teams.Get("/", s.teamHandler.List)
+	teams.Post("/", middleware.RequireAdmin, s.teamHandler.Create)
+	teams.Get("/:id", s.teamHandler.Get)
+	teams.Put("/:id", middleware.RequireAdmin, s.teamHandler.Update)
+	teams.Delete("/:id", middleware.RequireAdmin, s.teamHandler.Delete)
+
 	// Dashboard routes
 	dashboard := protected.Group("/dashboard")
 	dashboard.Get("/summary", s.dashboardHandler.Summary)
 	dashboard.Get("/reviews/upcoming", s.dashboardHandler.UpcomingReviews)
 	dashboard.Get("/reviews/overdue", s.dashboardHandler.OverdueReviews)
+	dashboard.Get("/control-tests/overdue", s.controlTestHandler.Overdue)
+	dashboard.Get("/evidence-requests/overdue", s.evidenceRequestHandler.Overdue)
+	dashboard.Get("/risks/top", s.dashboardHandler.TopRisks)
 
 	// Analytics routes
 	protected.Get("/analytics", s.analyticsHandler.Get)
 
+	// Report routes
+	protected.Get("/reports/risk", s.reportHandler.RiskReport)
+	subscriptions := protected.Group("/report-subscriptions")
+	subscriptions.Get("/", s.reportSubscriptionHandler.List)
+	subscriptions.Post("/", s.reportSubscriptionHandler.Create)
+	subscriptions.Get("/:id", s.reportSubscriptionHandler.Get)
+	subscriptions.Put("/:id", s.reportSubscriptionHandler.Update)
+	subscriptions.Delete("/:id", s.reportSubscriptionHandler.Delete)
+	subscriptions.Get("/:id/deliveries", s.reportSubscriptionHandler.Deliveries)
+	subscriptions.Post("/:id/send", s.reportSubscriptionHandler.Send)
+
+	// Webhook routes (admin only)
+	hooks := protected.Group("/webhooks", middleware.RequireAdmin)
+	hooks.Get("/", s.webhookHandler.List)
+	hooks.Post("/", s.webhookHandler.Create)
+	hooks.Get("/event-types", s.webhookHandler.EventTypes)
+	hooks.Get("/dead-letters", s.webhookHandler.DeadLetters)
+	hooks.Post("/deliveries/:id/redeliver", s.webhookHandler.Redeliver)
+	hooks.Get("/:id", s.webhookHandler.Get)
+	hooks.Put("/:id", s.webhookHandler.Update)
+	hooks.Delete("/:id", s.webhookHandler.Delete)
+	hooks.Get("/:id/deliveries", s.webhookHandler.Deliveries)
+
+	// Alert source routes (admin only)
+	alertSources := protected.Group("/alert-sources", middleware.RequireAdmin)
+	alertSources.Get("/", s.alertHandler.ListSources)
+	alertSources.Post("/", s.alertHandler.CreateSource)
+	alertSources.Get("/:id", s.alertHandler.GetSource)
+	alertSources.Put("/:id", s.alertHandler.UpdateSource)
+	alertSources.Delete("/:id", s.alertHandler.DeleteSource)
+
+	// Chat notification channel routes (admin only)
+	chatChannels := protected.Group("/chat-channels", middleware.RequireAdmin)
+	chatChannels.Get("/", s.chatChannelHandler.List)
+	chatChannels.Post("/", s.chatChannelHandler.Create)
+	chatChannels.Get("/triggers", s.chatChannelHandler.Triggers)
+	chatChannels.Get("/:id", s.chatChannelHandler.Get)
+	chatChannels.Put("/:id", s.chatChannelHandler.Update)
+	chatChannels.Delete("/:id", s.chatChannelHandler.Delete)
+	chatChannels.Get("/:id/messages", s.chatChannelHandler.Messages)
+	chatChannels.Post("/:id/test", s.chatChannelHandler.Test)
+
 	// Category routes (admin only)
 	categories := protected.Group("/categories")
 	categories.Get("/", middleware.RequireAdmin, s.categoryHandler.List)
 	categories.Post("/", middleware.RequireAdmin, s.categoryHandler.Create)
 	categories.Put("/:id", middleware.RequireAdmin, s.categoryHandler.Update)
+	categories.Patch("/:id", middleware.RequireAdmin, s.categoryHandler.Patch)
 	categories.Delete("/:id", middleware.RequireAdmin, s.categoryHandler.Delete)
 
 	// Risk routes
 	risks := protected.Group("/risks")
 	risks.Get("/", s.riskHandler.List)
 	risks.Post("/", s.riskHandler.Create)
+	risks.Get("/trash", s.trashHandler.ListRisks)
+	risks.Post("/bulk", s.bulkHandler.Risks)
+	risks.Post("/import", s.riskImportHandler.Import)
+	risks.Get("/export", s.riskExportHandler.Export)
 	risks.Get("/:id", s.riskHandler.Get)
 	risks.Put("/:id", s.riskHandler.Update)
+	risks.Patch("/:id", s.riskHandler.Patch)
 	risks.Delete("/:id", s.riskHandler.Delete)
+	risks.Post("/:id/restore", s.trashHandler.RestoreRisk)
+	risks.Delete("/:id/purge", middleware.RequireAdmin, s.trashHandler.PurgeRisk)
 
 	// Nested mitigation routes under a specific risk
 	risks.Get("/:riskId/mitigations", s.mitigationHandler.List)
 	risks.Post("/:riskId/mitigations", s.mitigationHandler.Create)
 	risks.Put("/:riskId/mitigations/:id", s.mitigationHandler.Update)
+	risks.Patch("/:riskId/mitigations/:id", s.mitigationHandler.Patch)
 	risks.Delete("/:riskId/mitigations/:id", s.mitigationHandler.Delete)
 
+	// Evidence attached to risks and their mitigations
+	risks.Get("/:riskId/attachments", s.attachmentHandler.ListForRisk)
+	risks.Post("/:riskId/attachments", s.attachmentHandler.UploadForRisk)
+	risks.Get("/:riskId/mitigations/:id/attachments", s.attachmentHandler.ListForMitigation)
+	risks.Post("/:riskId/mitigations/:id/attachments", s.attachmentHandler.UploadForMitigation)
+
 	// Audit log routes for risks
 	risks.Get("/:riskId/audit", s.auditHandler.ListByRisk)
 
+	// Point-in-time history reconstructed from the audit log
+	risks.Get("/:id/history", s.historyHandler.RiskAsOf)
+	risks.Get("/:id/history/diff", s.historyHandler.RiskDiff)
+
 	// Framework routes (admin only)
 	protected.Get("/frameworks", middleware.RequireAdmin, s.frameworkHandler.List)
 	protected.Post("/frameworks", middleware.RequireAdmin, s.frameworkHandler.Create)
 	protected.Put("/frameworks/:id", middleware.RequireAdmin, s.frameworkHandler.Update)
 	protected.Delete("/frameworks/:id", middleware.RequireAdmin, s.frameworkHandler.Delete)
+	protected.Post("/frameworks/:id/import", middleware.RequireAdmin, s.frameworkImportHandler.Import)
+	protected.Post("/frameworks/:id/migrate", middleware.RequireAdmin, s.frameworkMigrationHandler.Migrate)
+	protected.Get("/frameworks/:id/tree", s.frameworkControlHandler.Tree)
+	protected.Get("/frameworks/:id/coverage", s.frameworkControlHandler.Coverage)
+	protected.Get("/frameworks/:id/coverage/export", s.frameworkControlHandler.CoverageExport)
 	protected.Get("/controls", s.frameworkControlHandler.List)
 	protected.Get("/controls/:id/risks", s.frameworkControlHandler.ListLinkedRisks)
 	protected.Post("/controls", middleware.RequireAdmin, s.frameworkControlHandler.Create)
 	protected.Put("/controls/:id", middleware.RequireAdmin, s.frameworkControlHandler.Update)
+	protected.Patch("/controls/:id", middleware.RequireAdmin, s.frameworkControlHandler.Patch)
 	protected.Delete("/controls/:id", middleware.RequireAdmin, s.frameworkControlHandler.Delete)
+	protected.Get("/controls/:id/crosswalk", s.controlMappingHandler.Crosswalk)
+
+	// Statement of Applicability and its signed-off versions
+	protected.Get("/frameworks/:id/soa", s.soaHandler.Get)
+	protected.Get("/frameworks/:id/soa/export", s.soaHandler.Export)
+	protected.Get("/frameworks/:id/soa/compare", s.soaHandler.Compare)
+	protected.Get("/frameworks/:id/soa/versions", s.soaHandler.ListVersions)
+	protected.Post("/frameworks/:id/soa/versions", middleware.RequireAdmin, s.soaHandler.CreateVersion)
+	protected.Put("/controls/:id/applicability", middleware.RequireAdmin, s.soaHandler.SetApplicability)
+	protected.Get("/soa-versions/:id", s.soaHandler.GetVersion)
+	protected.Get("/soa-versions/:id/export", s.soaHandler.ExportVersion)
+	protected.Post("/soa-versions/:id/approve", middleware.RequireAdmin, s.soaHandler.Approve)
+	protected.Post("/soa-versions/:id/reject", middleware.RequireAdmin, s.soaHandler.Reject)
+
+	// Scheduled control tests and their results. Testers record results for
+	// the tests assigned to them.
+	protected.Get("/control-tests", s.controlTestHandler.List)
+	protected.Post("/control-tests", middleware.RequireAdmin, s.controlTestHandler.Create)
+	protected.Get("/control-tests/:id", s.controlTestHandler.Get)
+	protected.Put("/control-tests/:id", middleware.RequireAdmin, s.controlTestHandler.Update)
+	protected.Delete("/control-tests/:id", middleware.RequireAdmin, s.controlTestHandler.Delete)
+	protected.Get("/control-tests/:id/results", s.controlTestHandler.ListResults)
+	protected.Post("/control-tests/:id/results", s.controlTestHandler.RecordResult)
+	protected.Get("/controls/:id/tests", s.controlTestHandler.ListForControl)
+
+	// Evidence requests for controls. Assignees fulfil the requests assigned
+	// to them.
+	protected.Get("/evidence-requests", s.evidenceRequestHandler.List)
+	protected.Post("/evidence-requests", middleware.RequireAdmin, s.evidenceRequestHandler.Create)
+	protected.Get("/evidence-requests/:id", s.evidenceRequestHandler.Get)
+	protected.Put("/evidence-requests/:id", middleware.RequireAdmin, s.evidenceRequestHandler.Update)
+	protected.Delete("/evidence-requests/:id", middleware.RequireAdmin, s.evidenceRequestHandler.Delete)
+	protected.Get("/evidence-requests/:id/submissions", s.evidenceRequestHandler.ListSubmissions)
+	protected.Post("/evidence-requests/:id/submissions", s.evidenceRequestHandler.Fulfil)
+	protected.Get("/controls/:id/evidence-requests", s.evidenceRequestHandler.ListForControl)
+
+	// Policy register. Owners draft and submit versions of their policies,
+	// admins approve them, and every user acknowledges the version in force.
+	protected.Get("/policies", s.policyHandler.List)
+	protected.Post("/policies", middleware.RequireAdmin, s.policyHandler.Create)
+	protected.Get("/policies/:id", s.policyHandler.Get)
+	protected.Put("/policies/:id", middleware.RequireAdmin, s.policyHandler.Update)
+	protected.Delete("/policies/:id", middleware.RequireAdmin, s.policyHandler.Delete)
+	protected.Post("/policies/:id/controls", s.policyHandler.LinkControl)
+	protected.Delete("/policies/:id/controls/:controlId", s.policyHandler.UnlinkControl)
+	protected.Get("/policies/:id/versions", s.policyHandler.ListVersions)
+	protected.Post("/policies/:id/versions", s.policyHandler.CreateVersion)
+	protected.Post("/policies/:id/acknowledge", s.policyHandler.Acknowledge)
+	protected.Get("/policies/:id/acknowledgements", s.policyHandler.ListAcknowledgements)
+	protected.Get("/policies/:id/attachments", s.attachmentHandler.ListForPolicy)
+	protected.Post("/policies/:id/attachments", s.attachmentHandler.UploadForPolicy)
+	protected.Get("/policy-versions/:id", s.policyHandler.GetVersion)
+	protected.Put("/policy-versions/:id", s.policyHandler.UpdateVersion)
+	protected.Delete("/policy-versions/:id", s.policyHandler.DeleteVersion)
+	protected.Post("/policy-versions/:id/submit", s.policyHandler.SubmitVersion)
+	protected.Post("/policy-versions/:id/approve", middleware.RequireAdmin, s.policyHandler.ApproveVersion)
+	protected.Post("/policy-versions/:id/reject", middleware.RequireAdmin, s.policyHandler.RejectVersion)
+	protected.Get("/controls/:id/policies", s.policyHandler.ListForControl)
+
+	// Evidence attached to controls, and attachments of any parent by ID.
+	// Deleting needs the same rights as uploading to the parent.
+	protected.Get("/controls/:id/attachments", s.attachmentHandler.ListForControl)
+	protected.Post("/controls/:id/attachments", middleware.RequireAdmin, s.attachmentHandler.UploadForControl)
+	protected.Get("/attachments/:id", s.attachmentHandler.Get)
+	protected.Get("/attachments/:id/download", s.attachmentHandler.Download)
+	protected.Delete("/attachments/:id", s.attachmentHandler.Delete)
+
+	// Crosswalk mappings between controls
+	protected.Get("/control-mappings", s.controlMappingHandler.List)
+	protected.Post("/control-mappings", middleware.RequireAdmin, s.controlMappingHandler.Create)
+	protected.Post("/control-mappings/import", middleware.RequireAdmin, s.controlMappingHandler.Import)
+	protected.Get("/control-mappings/:id", s.controlMappingHandler.Get)
+	protected.Put("/control-mappings/:id", middleware.RequireAdmin, s.controlMappingHandler.Update)
+	protected.Delete("/control-mappings/:id", middleware.RequireAdmin, s.controlMappingHandler.Delete)
 
 	// Nested control routes under a specific risk
 	risks.Get("/:riskId/controls", s.controlHandler.ListControls)
 	risks.Post("/:riskId/controls", s.controlHandler.LinkControl)
 	risks.Delete("/:riskId/controls/:id", s.controlHandler.UnlinkControl)
+	risks.Get("/:riskId/controls/mapped", s.controlMappingHandler.ListMappedForRisk)
 
 	// AI routes (stubbed)
 	ai := protected.Group("/ai")
@@ -89,23 +273,40 @@ func (s *FiberServer) RegisterFiberRoutes() {
 	incidentCategories.Get("/", s.incidentCategoryHandler.List)
 	incidentCategories.Post("/", middleware.RequireAdmin, s.incidentCategoryHandler.Create)
 	incidentCategories.Put("/:id", middleware.RequireAdmin, s.incidentCategoryHandler.Update)
+	incidentCategories.Patch("/:id", middleware.RequireAdmin, s.incidentCategoryHandler.Patch)
 	incidentCategories.Delete("/:id", middleware.RequireAdmin, s.incidentCategoryHandler.Delete)
 
 	// Incident routes
 	incidents := protected.Group("/incidents")
 	incidents.Get("/", s.incidentHandler.List)
 	incidents.Post("/", middleware.RequireResponder, s.incidentHandler.Create)
+	incidents.Get("/trash", middleware.RequireAdmin, s.trashHandler.ListIncidents)
+	incidents.Post("/bulk", middleware.RequireResponder, s.bulkHandler.Incidents)
 	incidents.Get("/:id", s.incidentHandler.Get)
 	incidents.Put("/:id", middleware.RequireResponder, s.incidentHandler.Update)
+	incidents.Patch("/:id", middleware.RequireResponder, s.incidentHandler.Patch)
 	incidents.Delete("/:id", middleware.RequireAdmin, s.incidentHandler.Delete)
+	incidents.Post("/:id/restore", middleware.RequireAdmin, s.trashHandler.RestoreIncident)
+	incidents.Delete("/:id/purge", middleware.RequireAdmin, s.trashHandler.PurgeIncident)
 
 	// Nested risk routes under a specific incident
 	incidents.Get("/:incidentId/risks", s.incidentRiskHandler.ListRisks)
 	incidents.Post("/:incidentId/risks", middleware.RequireResponder, s.incidentRiskHandler.LinkRisk)
 	incidents.Delete("/:incidentId/risks/:riskId", middleware.RequireResponder, s.incidentRiskHandler.UnlinkRisk)
 
+	// Alerts that opened or updated an incident
+	incidents.Get("/:incidentId/alerts", s.alertHandler.IncidentAlerts)
+
+	// Evidence attached to incidents
+	incidents.Get("/:incidentId/attachments", s.attachmentHandler.ListForIncident)
+	incidents.Post("/:incidentId/attachments", middleware.RequireResponder, s.attachmentHandler.UploadForIncident)
+
 	// Audit log routes for incidents
 	incidents.Get("/:incidentId/audit", s.auditHandler.ListByIncident)
+
+	// Point-in-time history reconstructed from the audit log
+	incidents.Get("/:id/history", s.historyHandler.IncidentAsOf)
+	incidents.Get("/:id/history/diff", s.historyHandler.IncidentDiff)
 }
 
 func (s *FiberServer) HelloWorldHandler(c *fiber.Ctx) error {
@@ -1,45 +1,86 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 
+	"backend/internal/chat"
 	"backend/internal/database"
 	"backend/internal/handlers"
+	"backend/internal/mailer"
+	"backend/internal/notifications"
+	"backend/internal/realtime"
+	"backend/internal/reports"
+	"backend/internal/storage"
+	"backend/internal/webhooks"
 )
 
 type FiberServer struct {
 	*fiber.App
-	db                      database.Service
-	rawDB                   *sql.DB
-	users                   database.UserRepository
-	risks                   database.RiskRepository
-	categories              database.CategoryRepository
-	mitigations             database.MitigationRepository
-	frameworks              database.FrameworkRepository
-	frameworkControls       database.FrameworkControlRepository
-	controls                database.RiskFrameworkControlRepository
-	audit                   database.AuditLogRepository
-	incidents               database.IncidentRepository
-	incidentCategories      database.IncidentCategoryRepository
-	incidentRisks           database.IncidentRiskRepository
-	auth                    *handlers.AuthHandler
-	riskHandler             *handlers.RiskHandler
-	categoryHandler         *handlers.CategoryHandler
-	mitigationHandler       *handlers.MitigationHandler
-	frameworkHandler        *handlers.FrameworkHandler
-	frameworkControlHandler *handlers.FrameworkControlHandler
-	controlHandler          *handlers.ControlHandler
-	dashboardHandler        *handlers.DashboardHandler
-	analyticsHandler        *handlers.AnalyticsHandler
-	aiHandler               *handlers.AIHandler
-	auditHandler            *handlers.AuditHandler
-	incidentHandler         *handlers.IncidentHandler
-	incidentCategoryHandler *handlers.IncidentCategoryHandler
-	incidentRiskHandler     *handlers.IncidentRiskHandler
+	db                        database.Service
+	rawDB                     *sql.DB
+	users                     database.UserRepository
+	risks                     database.RiskRepository
+	categories                database.CategoryRepository
+	mitigations               database.MitigationRepository
+	frameworks                database.FrameworkRepository
+	frameworkControls         database.FrameworkControlRepository
+	controls                  database.RiskFrameworkControlRepository
+	audit                     database.AuditLogRepository
+	incidents                 database.IncidentRepository
+	incidentCategories        database.IncidentCategoryRepository
+	incidentRisks             database.IncidentRiskRepository
+	auth                      *handlers.AuthHandler
+	riskHandler               *handlers.RiskHandler
+	categoryHandler           *handlers.CategoryHandler
+	mitigationHandler         *handlers.MitigationHandler
+	frameworkHandler          *handlers.FrameworkHandler
+	frameworkControlHandler   *handlers.FrameworkControlHandler
+	frameworkImportHandler    *handlers.FrameworkImportHandler
+	frameworkMigrationHandler *handlers.FrameworkMigrationHandler
+	controlMappingHandler     *handlers.ControlMappingHandler
+	soaHandler                *handlers.SoAHandler
+	controlTestHandler        *handlers.ControlTestHandler
+	evidenceRequestHandler    *handlers.EvidenceRequestHandler
+	policyHandler             *handlers.PolicyHandler
+	attachmentHandler         *handlers.AttachmentHandler
+	controlHandler            *handlers.ControlHandler
+	dashboardHandler          *handlers.DashboardHandler
+	analyticsHandler          *handlers.AnalyticsHandler
+	aiHandler                 *handlers.AIHandler
+	auditHandler              *handlers.AuditHandler
+	historyHandler            *handlers.HistoryHandler
+	trashHandler              *handlers.TrashHandler
+	incidentHandler           *handlers.IncidentHandler
+	incidentCategoryHandler   *handlers.IncidentCategoryHandler
+	incidentRiskHandler       *handlers.IncidentRiskHandler
+	bulkHandler               *handlers.BulkHandler
+	riskImportHandler         *handlers.RiskImportHandler
+	riskExportHandler         *handlers.RiskExportHandler
+	reportHandler             *handlers.ReportHandler
+	reportSubscriptionHandler *handlers.ReportSubscriptionHandler
+	reportScheduler           *reports.Scheduler
+	webhookHandler            *handlers.WebhookHandler
+	webhookDispatcher         *webhooks.Dispatcher
+	alertHandler              *handlers.AlertHandler
+	chatChannelHandler        *handlers.ChatChannelHandler
+	chatDispatcher            *chat.Dispatcher
+	notificationHandler       *handlers.NotificationHandler
+	notificationWorker        *notifications.Worker
+	streamHandler             *handlers.StreamHandler
+	streamBroker              *realtime.Broker
+	streamBackend             string
+	calendarHandler           *handlers.CalendarHandler
+	teamHandler               *handlers.TeamHandler
 }
 
 func New() *FiberServer {
@@ -52,50 +93,213 @@ func New() *FiberServer {
 	frameworks := database.NewFrameworkRepository(rawDB)
 	frameworkControls := database.NewFrameworkControlRepository(rawDB)
 	controls := database.NewRiskFrameworkControlRepository(rawDB)
-	audit := database.NewAuditLogRepository(rawDB)
+	incidents := database.NewIncidentRepository(rawDB)
+	webhookRepo := database.NewWebhookRepository(rawDB)
+	chatChannels := database.NewChatChannelRepository(rawDB)
+	notificationRepo := database.NewNotificationRepository(rawDB)
+	// Audit entries about risks, mitigations and incidents also queue
+	// webhooks and notify the users concerned, and incident entries queue
+	// chat notifications
+	audit := webhooks.NewAuditEmitter(database.NewAuditLogRepository(rawDB), webhookRepo)
+	audit = notifications.NewAuditNotifier(audit, notificationRepo, risks, mitigations, incidents, users)
+	audit = chat.NewAuditNotifier(audit, chatChannels, incidents, users, os.Getenv("APP_URL"))
+	streamBroker := realtime.NewBroker()
+	streamBackend := getEnv("REALTIME_BACKEND", "local")
+	audit = realtime.NewAuditPublisher(audit, newStreamPublisher(streamBackend, streamBroker, rawDB))
 	dashboard := database.NewDashboardRepository(rawDB)
 	analytics := database.NewAnalyticsRepository(rawDB)
-	incidents := database.NewIncidentRepository(rawDB)
 	incidentCategories := database.NewIncidentCategoryRepository(rawDB)
 	incidentRisks := database.NewIncidentRiskRepository(rawDB)
+	transactor := database.NewTransactor(rawDB)
+	attachments := database.NewAttachmentRepository(rawDB)
+	attachmentStore := newAttachmentStore()
+	attachmentCleanup := handlers.NewAttachmentCleanup(transactor, attachments, attachmentStore)
+	reportSubscriptions := database.NewReportSubscriptionRepository(rawDB)
+	reportGenerator := reports.NewGenerator(dashboard, analytics)
+	exports := database.NewExportRepository(rawDB)
+	mail := newMailer()
+	reportDeliverer := reports.NewDeliverer(reportGenerator, exports, users, reportSubscriptions, mail)
+	outbound := &http.Client{Timeout: getDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second)}
+	chatDispatcher := chat.NewDispatcher(chatChannels, outbound, getDurationEnv("CHAT_DISPATCH_INTERVAL", 10*time.Second))
+	notificationWorker := notifications.NewWorker(notificationRepo, mail, os.Getenv("APP_URL"),
+		getDurationEnv("NOTIFICATION_INTERVAL", time.Minute), getDurationEnv("MITIGATION_DUE_NOTICE", 72*time.Hour),
+		getDurationEnv("EVIDENCE_DUE_NOTICE", 7*24*time.Hour))
+	attachmentLimits := newAttachmentLimits()
 
 	server := &FiberServer{
 		App: fiber.New(fiber.Config{
 			ServerHeader: "risk-register",
 			AppName:      "Risk Register API",
+			// Leave room for the largest attachment and its multipart framing
+			BodyLimit: max(fiber.DefaultBodyLimit, int(attachmentLimits.MaxBytes)+1<<20),
 		}),
-		db:                      db,
-		rawDB:                   rawDB,
-		users:                   users,
-		risks:                   risks,
-		categories:              categories,
-		mitigations:             mitigations,
-		frameworks:              frameworks,
-		frameworkControls:       frameworkControls,
-		controls:                controls,
-		audit:                   audit,
-		incidents:               incidents,
-		incidentCategories:      incidentCategories,
-		incidentRisks:           incidentRisks,
-		auth:                    handlers.NewAuthHandler(users),
-		riskHandler:             handlers.NewRiskHandler(risks, categories, audit),
-		categoryHandler:         handlers.NewCategoryHandler(categories),
-		mitigationHandler:       handlers.NewMitigationHandler(mitigations),
-		frameworkHandler:        handlers.NewFrameworkHandler(frameworks),
-		frameworkControlHandler: handlers.NewFrameworkControlHandler(frameworkControls),
-		controlHandler:          handlers.NewControlHandler(controls),
-		dashboardHandler:        handlers.NewDashboardHandler(dashboard),
-		analyticsHandler:        handlers.NewAnalyticsHandler(analytics),
-		aiHandler:               handlers.NewAIHandler(),
-		auditHandler:            handlers.NewAuditHandler(audit),
-		incidentHandler:         handlers.NewIncidentHandler(incidents, incidentCategories, incidentRisks, audit),
-		incidentCategoryHandler: handlers.NewIncidentCategoryHandler(incidentCategories),
-		incidentRiskHandler:     handlers.NewIncidentRiskHandler(incidentRisks, audit),
+		db:                        db,
+		rawDB:                     rawDB,
+		users:                     users,
+		risks:                     risks,
+		categories:                categories,
+		mitigations:               mitigations,
+		frameworks:                frameworks,
+		frameworkControls:         frameworkControls,
+		controls:                  controls,
+		audit:                     audit,
+		incidents:                 incidents,
+		incidentCategories:        incidentCategories,
+		incidentRisks:             incidentRisks,
+		auth:                      handlers.NewAuthHandler(users),
+		riskHandler:               handlers.NewRiskHandler(risks, categories, audit),
+		categoryHandler:           handlers.NewCategoryHandler(categories),
+		mitigationHandler:         handlers.NewMitigationHandler(mitigations, audit, attachmentCleanup),
+		frameworkHandler:          handlers.NewFrameworkHandler(frameworks, attachmentCleanup),
+		frameworkControlHandler:   handlers.NewFrameworkControlHandler(transactor, frameworkControls, attachmentCleanup),
+		frameworkImportHandler:    handlers.NewFrameworkImportHandler(transactor, frameworks, frameworkControls, audit),
+		frameworkMigrationHandler: handlers.NewFrameworkMigrationHandler(transactor, frameworks, frameworkControls, database.NewFrameworkMigrationRepository(rawDB), audit),
+		controlMappingHandler:     handlers.NewControlMappingHandler(transactor, database.NewControlMappingRepository(rawDB), frameworks, frameworkControls, audit),
+		soaHandler:                handlers.NewSoAHandler(transactor, database.NewSoARepository(rawDB), audit),
+		controlTestHandler:        handlers.NewControlTestHandler(transactor, database.NewControlTestRepository(rawDB), audit),
+		evidenceRequestHandler:    handlers.NewEvidenceRequestHandler(transactor, database.NewEvidenceRequestRepository(rawDB), audit),
+		policyHandler:             handlers.NewPolicyHandler(transactor, database.NewPolicyRepository(rawDB), audit, attachmentCleanup),
+		attachmentHandler:         handlers.NewAttachmentHandler(transactor, attachments, attachmentStore, audit, attachmentLimits),
+		controlHandler:            handlers.NewControlHandler(controls),
+		dashboardHandler:          handlers.NewDashboardHandler(dashboard),
+		analyticsHandler:          handlers.NewAnalyticsHandler(analytics),
+		aiHandler:                 handlers.NewAIHandler(),
+		auditHandler:              handlers.NewAuditHandler(audit),
+		historyHandler:            handlers.NewHistoryHandler(audit),
+		trashHandler:              handlers.NewTrashHandler(risks, incidents, audit, attachmentCleanup, getDurationEnv("TRASH_GRACE_PERIOD", 30*24*time.Hour)),
+		incidentHandler:           handlers.NewIncidentHandler(incidents, incidentCategories, incidentRisks, audit),
+		incidentCategoryHandler:   handlers.NewIncidentCategoryHandler(incidentCategories),
+		incidentRiskHandler:       handlers.NewIncidentRiskHandler(incidentRisks, audit),
+		bulkHandler:               handlers.NewBulkHandler(transactor, risks, categories, incidents, incidentCategories, audit),
+		riskImportHandler:         handlers.NewRiskImportHandler(transactor, risks, users, categories, audit),
+		riskExportHandler:         handlers.NewRiskExportHandler(exports),
+		reportHandler:             handlers.NewReportHandler(reportGenerator),
+		reportSubscriptionHandler: handlers.NewReportSubscriptionHandler(reportSubscriptions, reportDeliverer),
+		reportScheduler:           reports.NewScheduler(reportSubscriptions, reportDeliverer, getDurationEnv("REPORT_SCHEDULER_INTERVAL", time.Minute)),
+		webhookHandler:            handlers.NewWebhookHandler(webhookRepo),
+		webhookDispatcher:         webhooks.NewDispatcher(webhookRepo, outbound, getDurationEnv("WEBHOOK_DISPATCH_INTERVAL", 10*time.Second)),
+		alertHandler:              handlers.NewAlertHandler(transactor, database.NewAlertRepository(rawDB), incidents, incidentCategories, audit),
+		chatChannelHandler:        handlers.NewChatChannelHandler(chatChannels, incidentCategories, chatDispatcher),
+		chatDispatcher:            chatDispatcher,
+		notificationHandler:       handlers.NewNotificationHandler(notificationRepo),
+		notificationWorker:        notificationWorker,
+		streamHandler:             handlers.NewStreamHandler(streamBroker, 25*time.Second, time.Hour),
+		streamBroker:              streamBroker,
+		streamBackend:             streamBackend,
+		calendarHandler:           handlers.NewCalendarHandler(database.NewCalendarRepository(rawDB), os.Getenv("APP_URL"), os.Getenv("API_URL")),
+		teamHandler:               handlers.NewTeamHandler(transactor, database.NewTeamRepository(rawDB), users),
 	}
 
 	return server
 }
 
+// StartBackgroundJobs runs the scheduled workers until ctx is cancelled.
+// Setting REPORT_SCHEDULER_INTERVAL, WEBHOOK_DISPATCH_INTERVAL,
+// CHAT_DISPATCH_INTERVAL or NOTIFICATION_INTERVAL to zero disables that
+// worker, e.g. on instances that should only serve requests.
+func (s *FiberServer) StartBackgroundJobs(ctx context.Context) {
+	go s.reportScheduler.Run(ctx)
+	go s.webhookDispatcher.Run(ctx)
+	go s.chatDispatcher.Run(ctx)
+	go s.notificationWorker.Run(ctx)
+	if s.streamBackend == "postgres" {
+		go realtime.Listen(ctx, buildConnStr(), s.streamBroker)
+	}
+}
+
+// CloseStreams ends every open event stream so that shutdown doesn't wait
+// on them
+func (s *FiberServer) CloseStreams() {
+	s.streamBroker.Close()
+}
+
+// newStreamPublisher picks how change events reach clients from
+// REALTIME_BACKEND: local hands them to this instance's broker, postgres
+// sends them through LISTEN/NOTIFY to every instance
+func newStreamPublisher(backend string, broker *realtime.Broker, db *sql.DB) realtime.Publisher {
+	switch backend {
+	case "postgres":
+		return realtime.NewPostgresPublisher(database.NewNotifier(db))
+	default:
+		if backend != "local" {
+			log.Printf("Unknown REALTIME_BACKEND %q, using local", backend)
+		}
+		return realtime.NewLocalPublisher(broker)
+	}
+}
+
+// newMailer picks the outgoing mail transport from MAILER: smtp for real
+// delivery, file to write .eml files to MAIL_DIR, or log (the default)
+func newMailer() mailer.Mailer {
+	from := getEnv("MAIL_FROM", "risk-register@localhost")
+	switch driver := getEnv("MAILER", "log"); driver {
+	case "smtp":
+		port, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+		if err != nil {
+			log.Printf("Invalid SMTP_PORT, using 587")
+			port = 587
+		}
+		return mailer.NewSMTPMailer(getEnv("SMTP_HOST", "localhost"), port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	case "file":
+		return mailer.NewFileMailer(getEnv("MAIL_DIR", "mail"), from)
+	default:
+		if driver != "log" {
+			log.Printf("Unknown MAILER %q, logging mail instead", driver)
+		}
+		return mailer.LogMailer{}
+	}
+}
+
+// newAttachmentStore picks where attachment files are kept from
+// ATTACHMENT_STORAGE: local (the default) writes them under ATTACHMENT_DIR,
+// s3 puts them in S3_BUCKET on S3_ENDPOINT, which can be AWS or any
+// S3-compatible service such as MinIO
+func newAttachmentStore() storage.Store {
+	switch backend := getEnv("ATTACHMENT_STORAGE", "local"); backend {
+	case "s3":
+		store, err := storage.NewS3Store(context.Background(), storage.S3Config{
+			Endpoint:  getEnv("S3_ENDPOINT", "s3.amazonaws.com"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    getEnv("S3_BUCKET", "risk-register-attachments"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    getEnv("S3_USE_SSL", "true") == "true",
+		})
+		if err != nil {
+			panic(fmt.Sprintf("failed to connect to attachment storage: %v", err))
+		}
+		return store
+	default:
+		if backend != "local" {
+			log.Printf("Unknown ATTACHMENT_STORAGE %q, using local", backend)
+		}
+		return storage.NewLocalStore(getEnv("ATTACHMENT_DIR", "data/attachments"))
+	}
+}
+
+// newAttachmentLimits reads ATTACHMENT_MAX_BYTES (10 MiB by default) and
+// ATTACHMENT_TYPES, a comma-separated list of accepted content types
+func newAttachmentLimits() handlers.AttachmentLimits {
+	limits := handlers.AttachmentLimits{MaxBytes: 10 << 20, AllowedTypes: handlers.DefaultAttachmentTypes}
+	if val := os.Getenv("ATTACHMENT_MAX_BYTES"); val != "" {
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || n <= 0 {
+			log.Printf("Invalid ATTACHMENT_MAX_BYTES %q, using %d", val, limits.MaxBytes)
+		} else {
+			limits.MaxBytes = n
+		}
+	}
+	if val := os.Getenv("ATTACHMENT_TYPES"); val != "" {
+		limits.AllowedTypes = nil
+		for _, t := range strings.Split(val, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				limits.AllowedTypes = append(limits.AllowedTypes, t)
+			}
+		}
+	}
+	return limits
+}
+
 func getRawDB() *sql.DB {
 	connStr := buildConnStr()
 	db, err := sql.Open("pgx", connStr)
@@ -123,3 +327,17 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// getDurationEnv reads a Go duration (e.g. "720h") from the environment
+func getDurationEnv(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("Invalid %s %q, using default %s", key, val, defaultVal)
+		return defaultVal
+	}
+	return d
+}
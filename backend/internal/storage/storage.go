@@ -0,0 +1,26 @@
+// Package storage keeps uploaded files. The local store writes them under a
+// directory and suits single-server installs; the S3 store works with AWS
+// S3 and compatible services such as MinIO.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// Store saves and serves objects by key. Keys are slash-separated paths
+// chosen by the caller, never taken from user input.
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any object
+	// already there
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the object stored under key; the caller closes it
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object under key. Deleting a missing object is not
+	// an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tcminio "github.com/testcontainers/testcontainers-go/modules/minio"
+)
+
+// testStore runs the behaviour every Store must share
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	data := []byte("quarterly access review export")
+
+	require.NoError(t, store.Put(ctx, "attachments/a/evidence.csv", bytes.NewReader(data), int64(len(data)), "text/csv"))
+	r, err := store.Open(ctx, "attachments/a/evidence.csv")
+	require.NoError(t, err)
+	got, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, data, got)
+
+	require.NoError(t, store.Delete(ctx, "attachments/a/evidence.csv"))
+	_, err = store.Open(ctx, "attachments/a/evidence.csv")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, store.Delete(ctx, "attachments/a/evidence.csv"), "deleting a missing object is not an error")
+}
+
+func TestLocalStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalStore(dir)
+	testStore(t, store)
+
+	t.Run("short write leaves nothing behind", func(t *testing.T) {
+		err := store.Put(context.Background(), "attachments/b", strings.NewReader("abc"), 10, "text/plain")
+		assert.Error(t, err)
+		_, err = os.Stat(filepath.Join(dir, "attachments", "b"))
+		assert.True(t, os.IsNotExist(err))
+		leftovers, _ := filepath.Glob(filepath.Join(dir, "attachments", ".upload-*"))
+		assert.Empty(t, leftovers)
+	})
+
+	t.Run("keys cannot escape the directory", func(t *testing.T) {
+		for _, key := range []string{"", "../outside", "/etc/passwd", "a/../../outside"} {
+			err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain")
+			assert.Error(t, err, key)
+		}
+	})
+}
+
+func TestS3Store(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	ctx := context.Background()
+	container, err := tcminio.Run(ctx, "minio/minio:RELEASE.2024-01-16T16-07-38Z")
+	require.NoError(t, err)
+	t.Cleanup(func() { container.Terminate(context.Background()) })
+	endpoint, err := container.ConnectionString(ctx)
+	require.NoError(t, err)
+
+	store, err := NewS3Store(ctx, S3Config{
+		Endpoint:  endpoint,
+		Bucket:    "evidence",
+		AccessKey: container.Username,
+		SecretKey: container.Password,
+	})
+	require.NoError(t, err)
+	testStore(t, store)
+
+	_, err = NewS3Store(ctx, S3Config{Endpoint: endpoint, Bucket: "evidence", AccessKey: container.Username, SecretKey: container.Password})
+	assert.NoError(t, err, "an existing bucket is reused")
+}
@@ -0,0 +1,169 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderEventID   = "X-Webhook-Event-Id"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is moved
+	// to the dead-letter view
+	MaxAttempts = 8
+	// BaseBackoff is the wait after the first failure; each later failure
+	// doubles it, up to MaxBackoff
+	BaseBackoff = 30 * time.Second
+	MaxBackoff  = 6 * time.Hour
+
+	// claimBatchSize caps how many deliveries one tick sends
+	claimBatchSize = 50
+	// responseBodyLimit is how much of a receiver's response is kept in the
+	// delivery log
+	responseBodyLimit = 1024
+)
+
+// Sign returns the signature header for body sent at t. Receivers recompute
+// HMAC-SHA256 over "<t>.<body>" with the webhook secret, compare it with v1,
+// and should reject stale timestamps to stop replays.
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the delay before retrying a delivery that has failed attempts
+// times
+func Backoff(attempts int) time.Duration {
+	delay := BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+	return delay
+}
+
+// Dispatcher posts queued deliveries to their webhooks
+type Dispatcher struct {
+	webhooks database.WebhookRepository
+	client   *http.Client
+	interval time.Duration
+}
+
+func NewDispatcher(webhooks database.WebhookRepository, client *http.Client, interval time.Duration) *Dispatcher {
+	return &Dispatcher{webhooks: webhooks, client: client, interval: interval}
+}
+
+// Run sends due deliveries every interval until ctx is cancelled. A zero
+// interval disables the dispatcher.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if d.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchDue(ctx, time.Now()); err != nil {
+			log.Printf("webhook dispatcher: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends every delivery due at now and returns how many were
+// attempted
+func (d *Dispatcher) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	// The lease outlasts the request timeout so a slow receiver isn't sent
+	// the same delivery twice
+	lease := now.Add(2*d.client.Timeout + time.Minute)
+	dispatches, err := d.webhooks.ClaimDue(ctx, now, lease, claimBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claiming deliveries: %w", err)
+	}
+
+	for _, dispatch := range dispatches {
+		attempt := d.send(ctx, dispatch)
+		if err := d.webhooks.RecordAttempt(ctx, dispatch.Delivery.ID, attempt); err != nil {
+			return 0, fmt.Errorf("recording delivery %s: %w", dispatch.Delivery.ID, err)
+		}
+	}
+	return len(dispatches), nil
+}
+
+// send posts one delivery and works out what happens to it next
+func (d *Dispatcher) send(ctx context.Context, dispatch *models.WebhookDispatch) *models.WebhookAttempt {
+	delivery := dispatch.Delivery
+	now := time.Now()
+	attempt := &models.WebhookAttempt{Status: models.WebhookDeliveryDelivered, AttemptedAt: now}
+
+	failed := func(msg string) *models.WebhookAttempt {
+		attempt.Error = &msg
+		if delivery.Attempts+1 >= MaxAttempts {
+			attempt.Status = models.WebhookDeliveryDead
+			return attempt
+		}
+		next := now.Add(Backoff(delivery.Attempts + 1))
+		attempt.Status = models.WebhookDeliveryPending
+		attempt.NextAttemptAt = &next
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return failed(err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "risk-register-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(dispatch.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return failed(err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, responseBodyLimit))
+	status := resp.StatusCode
+	attempt.ResponseStatus = &status
+	if len(body) > 0 {
+		// Postgres text can't hold invalid UTF-8 or NUL bytes
+		text := strings.ReplaceAll(strings.ToValidUTF8(string(body), "\uFFFD"), "\x00", "")
+		attempt.ResponseBody = &text
+	}
+
+	if status < 200 || status > 299 {
+		return failed("receiver responded with " + resp.Status)
+	}
+	return attempt
+}
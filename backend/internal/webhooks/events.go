@@ -0,0 +1,89 @@
+// Package webhooks notifies external systems of changes to risks,
+// mitigations and incidents. Events are raised from audit entries, queued in
+// the database alongside them, and posted with HMAC signatures by a
+// background dispatcher that retries failures with exponential backoff.
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// webhookEntities are the audit entity types that raise events
+var webhookEntities = map[string]bool{"risk": true, "mitigation": true, "incident": true}
+
+// Events returns the webhook events raised by an audit entry, if any
+func Events(entityType, entityID string, action models.AuditAction, changes map[string]any, actorID string, at time.Time) []*models.WebhookEvent {
+	if !webhookEntities[entityType] {
+		return nil
+	}
+
+	event := func(eventType string) *models.WebhookEvent {
+		return &models.WebhookEvent{
+			ID:         uuid.New().String(),
+			Type:       eventType,
+			OccurredAt: at.UTC(),
+			EntityType: entityType,
+			EntityID:   entityID,
+			ActorID:    actorID,
+			Data:       changes,
+		}
+	}
+
+	events := []*models.WebhookEvent{event(entityType + "." + string(action))}
+	if entityType == "risk" && becameCritical(action, changes) {
+		events = append(events, event(models.WebhookEventRiskCritical))
+	}
+	return events
+}
+
+// becameCritical reports whether a risk audit entry records a risk created
+// as critical or raised to critical from another severity
+func becameCritical(action models.AuditAction, changes map[string]any) bool {
+	critical := string(models.SeverityCritical)
+	switch action {
+	case models.AuditActionCreated:
+		return fmt.Sprint(changes["severity"]) == critical
+	case models.AuditActionUpdated:
+		change, ok := changes["severity"].(map[string]any)
+		return ok && fmt.Sprint(change["to"]) == critical && fmt.Sprint(change["from"]) != critical
+	}
+	return false
+}
+
+// auditEmitter records audit entries and queues the webhook events they
+// raise. Inside a transaction both writes commit or roll back together, so a
+// dry run or failed batch never notifies anyone.
+type auditEmitter struct {
+	database.AuditLogRepository
+	webhooks database.WebhookRepository
+}
+
+// NewAuditEmitter wraps audit so that every entry about a risk, mitigation
+// or incident also queues webhook deliveries
+func NewAuditEmitter(audit database.AuditLogRepository, webhooks database.WebhookRepository) database.AuditLogRepository {
+	return &auditEmitter{AuditLogRepository: audit, webhooks: webhooks}
+}
+
+func (e *auditEmitter) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	if err := e.AuditLogRepository.Create(ctx, entityType, entityID, action, changes, userID); err != nil {
+		return err
+	}
+	for _, event := range Events(entityType, entityID, action, changes, userID, time.Now()) {
+		if _, err := e.webhooks.Enqueue(ctx, event); err != nil {
+			return fmt.Errorf("queueing %s webhook: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+func (e *auditEmitter) WithTx(tx *sql.Tx) database.AuditLogRepository {
+	return &auditEmitter{AuditLogRepository: e.AuditLogRepository.WithTx(tx), webhooks: e.webhooks.WithTx(tx)}
+}
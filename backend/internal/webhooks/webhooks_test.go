@@ -0,0 +1,204 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/internal/database"
+	"backend/internal/models"
+)
+
+type stubAudit struct {
+	database.AuditLogRepository
+	entries int
+}
+
+func (s *stubAudit) Create(ctx context.Context, entityType, entityID string, action models.AuditAction, changes map[string]any, userID string) error {
+	s.entries++
+	return nil
+}
+
+func (s *stubAudit) WithTx(tx *sql.Tx) database.AuditLogRepository { return s }
+
+type stubWebhooks struct {
+	database.WebhookRepository
+	events     []*models.WebhookEvent
+	dispatches []*models.WebhookDispatch
+	attempts   map[string]*models.WebhookAttempt
+}
+
+func (s *stubWebhooks) Enqueue(ctx context.Context, event *models.WebhookEvent) (int, error) {
+	s.events = append(s.events, event)
+	return 1, nil
+}
+
+func (s *stubWebhooks) WithTx(tx *sql.Tx) database.WebhookRepository { return s }
+
+func (s *stubWebhooks) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.WebhookDispatch, error) {
+	claimed := s.dispatches
+	s.dispatches = nil
+	return claimed, nil
+}
+
+func (s *stubWebhooks) RecordAttempt(ctx context.Context, deliveryID string, attempt *models.WebhookAttempt) error {
+	s.attempts[deliveryID] = attempt
+	return nil
+}
+
+func TestEvents(t *testing.T) {
+	at := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	types := func(events []*models.WebhookEvent) string {
+		names := make([]string, len(events))
+		for i, e := range events {
+			names[i] = e.Type
+		}
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		name    string
+		entity  string
+		action  models.AuditAction
+		changes map[string]any
+		want    string
+	}{
+		{"risk created", "risk", models.AuditActionCreated, map[string]any{"severity": "high"}, "risk.created"},
+		{"critical risk created", "risk", models.AuditActionCreated, map[string]any{"severity": "critical"}, "risk.created,risk.critical"},
+		{"risk raised to critical", "risk", models.AuditActionUpdated,
+			map[string]any{"severity": map[string]any{"from": models.SeverityHigh, "to": models.SeverityCritical}}, "risk.updated,risk.critical"},
+		{"critical risk retitled", "risk", models.AuditActionUpdated,
+			map[string]any{"title": map[string]any{"from": "a", "to": "b"}}, "risk.updated"},
+		{"incident deleted", "incident", models.AuditActionDeleted, nil, "incident.deleted"},
+		{"mitigation updated", "mitigation", models.AuditActionUpdated, map[string]any{}, "mitigation.updated"},
+		{"import batch", "risk_import", models.AuditActionCreated, map[string]any{"rows": 2}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := Events(tt.entity, "entity-id", tt.action, tt.changes, "user-id", at)
+			if got := types(events); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			for _, e := range events {
+				if e.ID == "" || e.EntityID != "entity-id" || e.ActorID != "user-id" || !e.OccurredAt.Equal(at) {
+					t.Errorf("unexpected event: %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditEmitter(t *testing.T) {
+	audit := &stubAudit{}
+	hooks := &stubWebhooks{}
+	emitter := NewAuditEmitter(audit, hooks).WithTx(nil)
+
+	if err := emitter.Create(context.Background(), "risk", "risk-id", models.AuditActionCreated, map[string]any{"severity": "critical"}, "user-id"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := emitter.Create(context.Background(), "risk_import", "batch-id", models.AuditActionCreated, nil, "user-id"); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if audit.entries != 2 {
+		t.Errorf("expected both audit entries to be written, got %d", audit.entries)
+	}
+	if len(hooks.events) != 2 || hooks.events[1].Type != models.WebhookEventRiskCritical {
+		t.Errorf("expected risk.created and risk.critical, got %+v", hooks.events)
+	}
+}
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1718000000, 0)
+	body := []byte(`{"type":"risk.created"}`)
+	sig := Sign("secret", at, body)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1718000000." + string(body)))
+	want := "t=1718000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if sig != want {
+		t.Errorf("expected %s, got %s", want, sig)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if Backoff(1) != BaseBackoff || Backoff(2) != 2*BaseBackoff || Backoff(3) != 4*BaseBackoff {
+		t.Errorf("expected doubling delays, got %s %s %s", Backoff(1), Backoff(2), Backoff(3))
+	}
+	if Backoff(40) != MaxBackoff {
+		t.Errorf("expected delays to be capped at %s, got %s", MaxBackoff, Backoff(40))
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	var got []received
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, received{r.Header.Clone(), body})
+		if strings.HasSuffix(r.URL.Path, "/fail") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "try later")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	payload, _ := json.Marshal(&models.WebhookEvent{ID: "event-1", Type: "risk.created"})
+	dispatch := func(id, path string, attempts int) *models.WebhookDispatch {
+		return &models.WebhookDispatch{
+			Delivery: &models.WebhookDelivery{ID: id, EventID: "event-1", EventType: "risk.created", Payload: payload, Attempts: attempts},
+			URL:      server.URL + path,
+			Secret:   "secret",
+		}
+	}
+	hooks := &stubWebhooks{
+		dispatches: []*models.WebhookDispatch{dispatch("ok", "/ok", 0), dispatch("retry", "/fail", 2), dispatch("dead", "/fail", MaxAttempts-1)},
+		attempts:   map[string]*models.WebhookAttempt{},
+	}
+	d := NewDispatcher(hooks, server.Client(), time.Second)
+
+	now := time.Now()
+	n, err := d.DispatchDue(context.Background(), now)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 deliveries attempted, got %d (%v)", n, err)
+	}
+
+	first := got[0]
+	if first.header.Get(HeaderEvent) != "risk.created" || first.header.Get(HeaderDelivery) != "ok" || string(first.body) != string(payload) {
+		t.Errorf("unexpected request: %v %s", first.header, first.body)
+	}
+	sig := first.header.Get(HeaderSignature)
+	timestamp := strings.TrimPrefix(strings.Split(sig, ",")[0], "t=")
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(timestamp + "." + string(payload)))
+	if !strings.HasSuffix(sig, ",v1="+hex.EncodeToString(mac.Sum(nil))) {
+		t.Errorf("signature does not verify: %s", sig)
+	}
+
+	if a := hooks.attempts["ok"]; a.Status != models.WebhookDeliveryDelivered || *a.ResponseStatus != 204 {
+		t.Errorf("expected a delivered attempt, got %+v", a)
+	}
+	retry := hooks.attempts["retry"]
+	if retry.Status != models.WebhookDeliveryPending || retry.NextAttemptAt == nil || *retry.ResponseBody != "try later" {
+		t.Fatalf("expected a retry, got %+v", retry)
+	}
+	if wait := retry.NextAttemptAt.Sub(retry.AttemptedAt); wait != Backoff(3) {
+		t.Errorf("expected a %s backoff, got %s", Backoff(3), wait)
+	}
+	if a := hooks.attempts["dead"]; a.Status != models.WebhookDeliveryDead || a.NextAttemptAt != nil || a.Error == nil {
+		t.Errorf("expected the last attempt to go to the dead-letter view, got %+v", a)
+	}
+}
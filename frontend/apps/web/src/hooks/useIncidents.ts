@@ -1,6 +1,6 @@
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 
-import { api } from '@/lib/api';
+import { api, isVersionConflict } from '@/lib/api';
 import { buildQueryString } from '@/lib/utils';
 import type {
   IncidentCategory,
@@ -74,12 +74,17 @@ export function useUpdateIncident(id: string) {
   const queryClient = useQueryClient();
 
   return useMutation({
-    mutationFn: (input: UpdateIncidentInput) =>
-      api.put<Incident>(`/api/v1/incidents/${id}`, input),
+    mutationFn: ({ version, ...input }: UpdateIncidentInput & { version: number }) =>
+      api.put<Incident>(`/api/v1/incidents/${id}`, input, { version }),
     onSuccess: (updatedIncident) => {
       queryClient.setQueryData([...INCIDENTS_KEY, id], updatedIncident);
       queryClient.invalidateQueries({ queryKey: INCIDENTS_KEY });
     },
+    onError: (error) => {
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: INCIDENTS_KEY });
+      }
+    },
   });
 }
 
@@ -88,10 +93,16 @@ export function useDeleteIncident() {
   const queryClient = useQueryClient();
 
   return useMutation({
-    mutationFn: (id: string) => api.delete(`/api/v1/incidents/${id}`),
+    mutationFn: ({ id, version }: { id: string; version: number }) =>
+      api.delete(`/api/v1/incidents/${id}`, { version }),
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: INCIDENTS_KEY });
     },
+    onError: (error) => {
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: INCIDENTS_KEY });
+      }
+    },
   });
 }
 
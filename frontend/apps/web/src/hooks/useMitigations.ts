@@ -1,6 +1,6 @@
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 
-import { api } from '@/lib/api';
+import { api, isVersionConflict } from '@/lib/api';
 import type {
   CreateMitigationInput,
   Mitigation,
@@ -48,12 +48,17 @@ export function useUpdateMitigation(riskId: string, id: string) {
   const queryClient = useQueryClient();
 
   return useMutation({
-    mutationFn: (input: UpdateMitigationInput) =>
-      api.put<Mitigation>(`/api/v1/risks/${riskId}/mitigations/${id}`, input),
+    mutationFn: ({ version, ...input }: UpdateMitigationInput & { version: number }) =>
+      api.put<Mitigation>(`/api/v1/risks/${riskId}/mitigations/${id}`, input, { version }),
     onSuccess: (updatedMitigation) => {
       queryClient.setQueryData([MITIGATIONS_KEY, riskId, id], updatedMitigation);
       queryClient.invalidateQueries({ queryKey: [MITIGATIONS_KEY, riskId] });
     },
+    onError: (error) => {
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: [MITIGATIONS_KEY, riskId] });
+      }
+    },
   });
 }
 
@@ -62,10 +67,15 @@ export function useDeleteMitigation(riskId: string) {
   const queryClient = useQueryClient();
 
   return useMutation({
-    mutationFn: (id: string) =>
-      api.delete(`/api/v1/risks/${riskId}/mitigations/${id}`),
+    mutationFn: ({ id, version }: { id: string; version: number }) =>
+      api.delete(`/api/v1/risks/${riskId}/mitigations/${id}`, { version }),
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: [MITIGATIONS_KEY, riskId] });
     },
+    onError: (error) => {
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: [MITIGATIONS_KEY, riskId] });
+      }
+    },
   });
 }
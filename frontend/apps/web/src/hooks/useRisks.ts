@@ -1,6 +1,6 @@
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
 
-import { api } from '@/lib/api';
+import { api, isVersionConflict } from '@/lib/api';
 import { buildQueryString } from '@/lib/utils';
 import { DASHBOARD_KEY } from './useDashboard';
 import type {
@@ -97,13 +97,18 @@ export function useUpdateRisk(id: string) {
   const queryClient = useQueryClient();
 
   return useMutation({
-    mutationFn: (input: UpdateRiskInput) =>
-      api.put<Risk>(`/api/v1/risks/${id}`, input),
+    mutationFn: ({ version, ...input }: UpdateRiskInput & { version: number }) =>
+      api.put<Risk>(`/api/v1/risks/${id}`, input, { version }),
     onSuccess: (updatedRisk) => {
       queryClient.setQueryData([...RISKS_KEY, id], updatedRisk);
       queryClient.invalidateQueries({ queryKey: RISKS_KEY });
       queryClient.invalidateQueries({ queryKey: DASHBOARD_KEY });
     },
+    onError: (error) => {
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: RISKS_KEY });
+      }
+    },
   });
 }
 
@@ -112,11 +117,17 @@ export function useDeleteRisk() {
   const queryClient = useQueryClient();
 
   return useMutation({
-    mutationFn: (id: string) => api.delete(`/api/v1/risks/${id}`),
+    mutationFn: ({ id, version }: { id: string; version: number }) =>
+      api.delete(`/api/v1/risks/${id}`, { version }),
     onSuccess: () => {
       queryClient.invalidateQueries({ queryKey: RISKS_KEY });
       queryClient.invalidateQueries({ queryKey: DASHBOARD_KEY });
     },
+    onError: (error) => {
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: RISKS_KEY });
+      }
+    },
   });
 }
 
@@ -125,13 +136,18 @@ export function useUpdateRiskStatus() {
   const queryClient = useQueryClient();
 
   return useMutation({
-    mutationFn: ({ id, status }: { id: string; status: RiskStatus }) =>
-      api.put<Risk>(`/api/v1/risks/${id}`, { status }),
+    mutationFn: ({ id, status, version }: { id: string; status: RiskStatus; version: number }) =>
+      api.put<Risk>(`/api/v1/risks/${id}`, { status }, { version }),
     onSuccess: (updatedRisk) => {
       queryClient.setQueryData([...RISKS_KEY, updatedRisk.id], updatedRisk);
       queryClient.invalidateQueries({ queryKey: RISKS_KEY });
       queryClient.invalidateQueries({ queryKey: DASHBOARD_KEY });
     },
+    onError: (error) => {
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: RISKS_KEY });
+      }
+    },
   });
 }
 
@@ -11,6 +11,14 @@ interface ApiOptions {
   method?: 'GET' | 'POST' | 'PUT' | 'DELETE';
   body?: unknown;
   token?: string;
+  // version of the record as last read, sent as If-Match so the server
+  // rejects the write if someone else changed it in the meantime
+  version?: number;
+}
+
+interface WriteOptions {
+  token?: string;
+  version?: number;
 }
 
 class ApiClient {
@@ -26,7 +34,7 @@ class ApiClient {
   }
 
   async request<T>(path: string, options: ApiOptions = {}): Promise<T> {
-    const { method = 'GET', body, token } = options;
+    const { method = 'GET', body, token, version } = options;
 
     const headers: Record<string, string> = {
       'Content-Type': 'application/json',
@@ -36,6 +44,9 @@ class ApiClient {
     if (authToken) {
       headers['Authorization'] = `Bearer ${authToken}`;
     }
+    if (version !== undefined) {
+      headers['If-Match'] = `"${version}"`;
+    }
 
     const response = await fetch(`${this.baseUrl}${path}`, {
       method,
@@ -47,6 +58,9 @@ class ApiClient {
       const error = await response.json().catch(() => ({ error: 'Request failed' }));
       throw new ApiError(response.status, error.error || 'Request failed');
     }
+    if (response.status === 204) {
+      return undefined as T;
+    }
 
     return response.json();
   }
@@ -59,12 +73,12 @@ class ApiClient {
     return this.request<T>(path, { method: 'POST', body, token });
   }
 
-  put<T>(path: string, body: unknown, token?: string): Promise<T> {
-    return this.request<T>(path, { method: 'PUT', body, token });
+  put<T>(path: string, body: unknown, options: WriteOptions = {}): Promise<T> {
+    return this.request<T>(path, { method: 'PUT', body, ...options });
   }
 
-  delete<T>(path: string, token?: string): Promise<T> {
-    return this.request<T>(path, { method: 'DELETE', token });
+  delete<T>(path: string, options: WriteOptions = {}): Promise<T> {
+    return this.request<T>(path, { method: 'DELETE', ...options });
   }
 
   async getAndUnwrap<T>(path: string, token?: string): Promise<T> {
@@ -80,4 +94,10 @@ export class ApiError extends Error {
   }
 }
 
+// isVersionConflict reports whether a write was rejected because the record
+// changed since it was read (412) or was sent without its version (428)
+export function isVersionConflict(error: unknown): boolean {
+  return error instanceof ApiError && (error.status === 412 || error.status === 428);
+}
+
 export const api = new ApiClient(API_BASE);
@@ -91,7 +91,7 @@ function Board() {
 
     const currentRisk = risks.find((r) => r.id === riskId);
     if (currentRisk && currentRisk.status !== newStatus) {
-      updateRiskStatus.mutate({ id: riskId, status: newStatus });
+      updateRiskStatus.mutate({ id: riskId, status: newStatus, version: currentRisk.version });
     }
   };
 
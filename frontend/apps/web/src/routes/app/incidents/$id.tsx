@@ -12,6 +12,7 @@ import {
   useIncidentAuditLogs,
 } from "@/hooks/useIncidents";
 import { useRisks } from "@/hooks/useRisks";
+import { isVersionConflict } from "@/lib/api";
 import { useUsers } from "@/hooks/useUsers";
 import { Button, buttonVariants } from "@/components/ui/button";
 import {
@@ -115,8 +116,10 @@ function IncidentDetail() {
   }, [incident]);
 
   const handleSave = async () => {
+    if (!incident) return;
     try {
       await updateIncident.mutateAsync({
+        version: incident.version,
         title,
         description: description || undefined,
         status,
@@ -132,19 +135,27 @@ function IncidentDetail() {
       toast.success("Incident updated");
       setIsEditing(false);
     } catch (error) {
-      toast.error("Failed to update incident");
+      toast.error(
+        isVersionConflict(error)
+          ? "Someone else changed this incident. Review the latest version and try again."
+          : "Failed to update incident",
+      );
     }
   };
 
   const handleDelete = async () => {
-    if (!confirm("Are you sure you want to delete this incident?")) return;
+    if (!incident || !confirm("Are you sure you want to delete this incident?")) return;
 
     try {
-      await deleteIncident.mutateAsync(id);
+      await deleteIncident.mutateAsync({ id, version: incident.version });
       toast.success("Incident deleted");
       navigate({ to: "/app/incidents" });
     } catch (error) {
-      toast.error("Failed to delete incident");
+      toast.error(
+        isVersionConflict(error)
+          ? "Someone else changed this incident. Review the latest version and try again."
+          : "Failed to delete incident",
+      );
     }
   };
 
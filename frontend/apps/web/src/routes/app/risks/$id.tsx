@@ -21,7 +21,7 @@ import {
 } from "@/hooks/useControls";
 import { useSummarize, useDraftMitigation } from "@/hooks/useAI";
 import { useAuditLogs } from "@/hooks/useAudit";
-import { api } from "@/lib/api";
+import { api, isVersionConflict } from "@/lib/api";
 import { Button, buttonVariants } from "@/components/ui/button";
 import {
   Card,
@@ -174,8 +174,10 @@ function RiskDetail() {
   }, [frameworkControlId, selectableControls]);
 
   const handleSave = async () => {
+    if (!risk) return;
     try {
       await updateRisk.mutateAsync({
+        version: risk.version,
         title,
         description: description || undefined,
         status,
@@ -186,19 +188,27 @@ function RiskDetail() {
       toast.success("Risk updated");
       setIsEditing(false);
     } catch (error) {
-      toast.error("Failed to update risk");
+      toast.error(
+        isVersionConflict(error)
+          ? "Someone else changed this risk. Review the latest version and try again."
+          : "Failed to update risk",
+      );
     }
   };
 
   const handleDelete = async () => {
-    if (!confirm("Are you sure you want to delete this risk?")) return;
+    if (!risk || !confirm("Are you sure you want to delete this risk?")) return;
 
     try {
-      await deleteRisk.mutateAsync(id);
+      await deleteRisk.mutateAsync({ id, version: risk.version });
       toast.success("Risk deleted");
       navigate({ to: "/app/risks" });
     } catch (error) {
-      toast.error("Failed to delete risk");
+      toast.error(
+        isVersionConflict(error)
+          ? "Someone else changed this risk. Review the latest version and try again."
+          : "Failed to delete risk",
+      );
     }
   };
 
@@ -248,33 +258,48 @@ function RiskDetail() {
       toast.error("Description is required");
       return;
     }
+    const editing = mitigations?.find((m) => m.id === editingMitigationId);
+    if (!editing) return;
 
     try {
-      await api.put(`/api/v1/risks/${id}/mitigations/${editingMitigationId}`, {
-        description: mitigationDescription,
-        owner: mitigationOwner || undefined,
-        status: mitigationStatus,
-        due_date: mitigationDueDate || undefined,
-      });
+      await api.put(
+        `/api/v1/risks/${id}/mitigations/${editingMitigationId}`,
+        {
+          description: mitigationDescription,
+          owner: mitigationOwner || undefined,
+          status: mitigationStatus,
+          due_date: mitigationDueDate || undefined,
+        },
+        { version: editing.version },
+      );
       toast.success("Mitigation updated");
       resetMitigationForm();
       queryClient.invalidateQueries({ queryKey: ["mitigations", id] });
     } catch (error) {
-      toast.error("Failed to update mitigation");
+      if (isVersionConflict(error)) {
+        queryClient.invalidateQueries({ queryKey: ["mitigations", id] });
+        toast.error("Someone else changed this mitigation. Review the latest version and try again.");
+      } else {
+        toast.error("Failed to update mitigation");
+      }
     }
   };
 
-  const handleDeleteMitigation = async (mitigationId: string) => {
+  const handleDeleteMitigation = async (mitigation: Mitigation) => {
     if (!confirm("Are you sure you want to delete this mitigation?")) return;
 
     try {
-      await deleteMitigation.mutateAsync(mitigationId);
+      await deleteMitigation.mutateAsync({ id: mitigation.id, version: mitigation.version });
       toast.success("Mitigation deleted");
-      if (editingMitigationId === mitigationId) {
+      if (editingMitigationId === mitigation.id) {
         resetMitigationForm();
       }
     } catch (error) {
-      toast.error("Failed to delete mitigation");
+      toast.error(
+        isVersionConflict(error)
+          ? "Someone else changed this mitigation. Review the latest version and try again."
+          : "Failed to delete mitigation",
+      );
     }
   };
 
@@ -732,7 +757,7 @@ function RiskDetail() {
                     <Button
                       variant="destructive"
                       size="sm"
-                      onClick={() => handleDeleteMitigation(mitigation.id)}
+                      onClick={() => handleDeleteMitigation(mitigation)}
                       disabled={deleteMitigation.isPending}
                     >
                       Delete
@@ -38,6 +38,7 @@ export interface Incident {
   updated_at: string;
   created_by: string;
   updated_by: string;
+  version: number;
 }
 
 export interface CreateIncidentInput {
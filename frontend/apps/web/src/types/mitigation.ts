@@ -11,6 +11,7 @@ export interface Mitigation {
   updated_at: string;
   created_by: string;
   updated_by: string;
+  version: number;
 }
 
 export interface CreateMitigationInput {
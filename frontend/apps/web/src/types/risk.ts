@@ -27,6 +27,7 @@ export interface Risk {
   updated_at: string;
   created_by: string;
   updated_by: string;
+  version: number;
 }
 
 export interface CreateRiskInput {